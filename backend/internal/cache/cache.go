@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Store is one cache tier holding opaque values
+type Store interface {
+	// Get returns the value for key and whether it was present
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value for ttl; a ttl of zero means no expiry
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Broadcaster tells other replicas which keys were invalidated
+type Broadcaster interface {
+	Publish(ctx context.Context, keys []string) error
+}
+
+// Tier names reported to Options.OnLookup
+const (
+	TierLocal  = "local"
+	TierRemote = "remote"
+)
+
+// Options configures a Cache. Only the local tier is required.
+type Options struct {
+	// Remote is an optional shared tier such as Redis, consulted on local misses
+	Remote Store
+	// Broadcaster publishes invalidations to other replicas' local tiers
+	Broadcaster Broadcaster
+	// OnLookup is called for every lookup with the tier and whether it hit
+	OnLookup func(tier string, hit bool)
+	// OnError is called when a tier or the broadcaster fails; the cache
+	// falls back to loading from the source
+	OnError func(op string, err error)
+}
+
+// Cache is a read-through cache over a local tier and an optional remote
+// tier. Concurrent misses for the same key share a single load.
+type Cache struct {
+	local   *LRU
+	options Options
+	group   singleflight.Group
+
+	// generation is bumped by every invalidation so that a load which
+	// started before a write does not repopulate the cache with stale data
+	generation atomic.Uint64
+}
+
+func New(local *LRU, options Options) *Cache {
+	return &Cache{local: local, options: options}
+}
+
+// Fetch returns the cached value for key, calling load on a miss and
+// caching its result for ttl
+func (c *Cache) Fetch(ctx context.Context, key string, ttl time.Duration, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	if value, ok, _ := c.local.Get(ctx, key); ok {
+		c.lookup(TierLocal, true)
+		return value, nil
+	}
+	c.lookup(TierLocal, false)
+
+	value, err, _ := c.group.Do(key, func() (interface{}, error) {
+		generation := c.generation.Load()
+
+		if c.options.Remote != nil {
+			value, ok, err := c.options.Remote.Get(ctx, key)
+			if err != nil {
+				c.fail("remote_get", err)
+			}
+			c.lookup(TierRemote, ok)
+			if ok {
+				c.store(ctx, key, value, ttl, generation, false)
+				return value, nil
+			}
+		}
+
+		value, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.store(ctx, key, value, ttl, generation, true)
+		return value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
+
+// store caches a loaded value unless an invalidation happened meanwhile
+func (c *Cache) store(ctx context.Context, key string, value []byte, ttl time.Duration, generation uint64, remote bool) {
+	if c.generation.Load() != generation {
+		return
+	}
+	c.local.Set(ctx, key, value, ttl)
+	if remote && c.options.Remote != nil {
+		if err := c.options.Remote.Set(ctx, key, value, ttl); err != nil {
+			c.fail("remote_set", err)
+		}
+	}
+}
+
+// Invalidate drops keys from both tiers and broadcasts them to other replicas
+func (c *Cache) Invalidate(ctx context.Context, keys ...string) {
+	c.InvalidateLocal(keys...)
+	if c.options.Remote != nil {
+		if err := c.options.Remote.Delete(ctx, keys...); err != nil {
+			c.fail("remote_delete", err)
+		}
+	}
+	if c.options.Broadcaster != nil {
+		if err := c.options.Broadcaster.Publish(ctx, keys); err != nil {
+			c.fail("broadcast", err)
+		}
+	}
+}
+
+// InvalidateLocal drops keys from this replica only, e.g. on a broadcast
+// from another replica that already cleared the remote tier
+func (c *Cache) InvalidateLocal(keys ...string) {
+	c.generation.Add(1)
+	c.local.Delete(context.Background(), keys...)
+}
+
+// Purge empties the local tier, e.g. after missing broadcasts
+func (c *Cache) Purge() {
+	c.generation.Add(1)
+	c.local.Purge()
+}
+
+func (c *Cache) lookup(tier string, hit bool) {
+	if c.options.OnLookup != nil {
+		c.options.OnLookup(tier, hit)
+	}
+}
+
+func (c *Cache) fail(op string, err error) {
+	if c.options.OnError != nil {
+		c.options.OnError(op, err)
+	}
+}
@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingBroadcaster struct {
+	mu   sync.Mutex
+	keys [][]string
+}
+
+func (b *recordingBroadcaster) Publish(ctx context.Context, keys []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.keys = append(b.keys, keys)
+	return nil
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("evicts the least recently used entry", func(t *testing.T) {
+		lru := NewLRU(2)
+		lru.Set(ctx, "a", []byte("1"), 0)
+		lru.Set(ctx, "b", []byte("2"), 0)
+		lru.Get(ctx, "a")
+		lru.Set(ctx, "c", []byte("3"), 0)
+
+		_, ok, _ := lru.Get(ctx, "b")
+		assert.False(t, ok)
+		value, ok, _ := lru.Get(ctx, "a")
+		assert.True(t, ok)
+		assert.Equal(t, []byte("1"), value)
+		assert.Equal(t, 2, lru.Len())
+	})
+
+	t.Run("expires entries after their ttl", func(t *testing.T) {
+		now := time.Now()
+		lru := NewLRU(10)
+		lru.now = func() time.Time { return now }
+		lru.Set(ctx, "a", []byte("1"), time.Minute)
+
+		_, ok, _ := lru.Get(ctx, "a")
+		assert.True(t, ok)
+
+		now = now.Add(time.Minute)
+		_, ok, _ = lru.Get(ctx, "a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, lru.Len())
+	})
+
+	t.Run("delete and purge", func(t *testing.T) {
+		lru := NewLRU(10)
+		lru.Set(ctx, "a", []byte("1"), 0)
+		lru.Set(ctx, "b", []byte("2"), 0)
+		lru.Delete(ctx, "a", "missing")
+		assert.Equal(t, 1, lru.Len())
+		lru.Purge()
+		assert.Equal(t, 0, lru.Len())
+	})
+}
+
+func TestCache_Fetch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("loads once and then serves from the local tier", func(t *testing.T) {
+		var lookups []string
+		c := New(NewLRU(10), Options{OnLookup: func(tier string, hit bool) {
+			if hit {
+				lookups = append(lookups, tier+":hit")
+			} else {
+				lookups = append(lookups, tier+":miss")
+			}
+		}})
+		loads := 0
+		load := func(ctx context.Context) ([]byte, error) {
+			loads++
+			return []byte("value"), nil
+		}
+
+		for i := 0; i < 2; i++ {
+			value, err := c.Fetch(ctx, "key", time.Minute, load)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("value"), value)
+		}
+		assert.Equal(t, 1, loads)
+		assert.Equal(t, []string{"local:miss", "local:hit"}, lookups)
+	})
+
+	t.Run("load errors are not cached", func(t *testing.T) {
+		c := New(NewLRU(10), Options{})
+		_, err := c.Fetch(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+			return nil, errors.New("boom")
+		})
+		assert.EqualError(t, err, "boom")
+
+		value, err := c.Fetch(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+			return []byte("value"), nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value"), value)
+	})
+
+	t.Run("concurrent misses share one load", func(t *testing.T) {
+		c := New(NewLRU(10), Options{})
+		var loads atomic.Int32
+		release := make(chan struct{})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				value, err := c.Fetch(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+					loads.Add(1)
+					<-release
+					return []byte("value"), nil
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("value"), value)
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), loads.Load())
+	})
+
+	t.Run("remote tier fills the local tier", func(t *testing.T) {
+		remote := NewLRU(10)
+		remote.Set(ctx, "key", []byte("shared"), 0)
+		local := NewLRU(10)
+		c := New(local, Options{Remote: remote})
+
+		value, err := c.Fetch(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+			t.Fatal("load should not be called on a remote hit")
+			return nil, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("shared"), value)
+		_, ok, _ := local.Get(ctx, "key")
+		assert.True(t, ok)
+	})
+
+	t.Run("loaded values are written to the remote tier", func(t *testing.T) {
+		remote := NewLRU(10)
+		c := New(NewLRU(10), Options{Remote: remote})
+
+		_, err := c.Fetch(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+			return []byte("value"), nil
+		})
+		assert.NoError(t, err)
+		value, ok, _ := remote.Get(ctx, "key")
+		assert.True(t, ok)
+		assert.Equal(t, []byte("value"), value)
+	})
+
+	t.Run("a load racing an invalidation is not cached", func(t *testing.T) {
+		local := NewLRU(10)
+		c := New(local, Options{})
+
+		_, err := c.Fetch(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+			c.Invalidate(ctx, "key")
+			return []byte("stale"), nil
+		})
+		assert.NoError(t, err)
+		_, ok, _ := local.Get(ctx, "key")
+		assert.False(t, ok)
+	})
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	local, remote := NewLRU(10), NewLRU(10)
+	broadcaster := &recordingBroadcaster{}
+	c := New(local, Options{Remote: remote, Broadcaster: broadcaster})
+
+	local.Set(ctx, "a", []byte("1"), 0)
+	remote.Set(ctx, "a", []byte("1"), 0)
+	c.Invalidate(ctx, "a", "b")
+
+	assert.Equal(t, 0, local.Len())
+	assert.Equal(t, 0, remote.Len())
+	assert.Equal(t, [][]string{{"a", "b"}}, broadcaster.keys)
+
+	t.Run("local invalidation leaves other tiers alone", func(t *testing.T) {
+		local.Set(ctx, "a", []byte("1"), 0)
+		remote.Set(ctx, "a", []byte("1"), 0)
+		c.InvalidateLocal("a")
+
+		assert.Equal(t, 0, local.Len())
+		assert.Equal(t, 1, remote.Len())
+		assert.Len(t, broadcaster.keys, 1)
+	})
+}
+
+func TestPostgresBroadcaster_Publish(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	broadcaster := NewPostgresBroadcaster(db, "")
+
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(DefaultChannel, "a\nb").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.NoError(t, broadcaster.Publish(context.Background(), []string{"a", "b"}))
+
+	// Oversized payloads become a purge
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(DefaultChannel, purgePayload).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.NoError(t, broadcaster.Publish(context.Background(), []string{strings.Repeat("k", maxPayload+1)}))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// DefaultChannel is the Postgres channel carrying cache invalidations
+const DefaultChannel = "book_cache_invalidate"
+
+// NOTIFY payloads are limited to 8000 bytes; larger invalidations are sent
+// as a purge of the whole local tier
+const (
+	maxPayload   = 7900
+	purgePayload = "*"
+)
+
+// PostgresBroadcaster publishes invalidated keys with pg_notify, one key per
+// line
+type PostgresBroadcaster struct {
+	db      *sql.DB
+	channel string
+}
+
+func NewPostgresBroadcaster(db *sql.DB, channel string) *PostgresBroadcaster {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	return &PostgresBroadcaster{db: db, channel: channel}
+}
+
+func (b *PostgresBroadcaster) Publish(ctx context.Context, keys []string) error {
+	payload := strings.Join(keys, "\n")
+	if len(payload) > maxPayload {
+		payload = purgePayload
+	}
+	_, err := b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, b.channel, payload)
+	return err
+}
+
+// Listen applies invalidations broadcast on channel to c's local tier until
+// ctx is done. The local tier is purged whenever the connection is
+// re-established, since notifications sent while disconnected are lost.
+func Listen(ctx context.Context, dsn, channel string, c *Cache, logger *zap.Logger) {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Warn("Cache invalidation listener error", zap.String("channel", channel), zap.Error(err))
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		logger.Error("Failed to listen for cache invalidations", zap.String("channel", channel), zap.Error(err))
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-listener.Notify:
+			// A nil notification signals a reconnect
+			if notification == nil || notification.Extra == purgePayload {
+				c.Purge()
+				continue
+			}
+			c.InvalidateLocal(strings.Split(notification.Extra, "\n")...)
+		case <-time.After(90 * time.Second):
+			go listener.Ping()
+		}
+	}
+}
@@ -2,35 +2,77 @@ package config
 
 import (
 	"fmt"
-	"io/ioutil"
+	"net/url"
 	"os"
+	"reflect"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	CORS     CORSConfig     `yaml:"cors"`
-	Logging  LoggingConfig  `yaml:"logging"`
-	API      APIConfig      `yaml:"api"`
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Logging     LoggingConfig     `yaml:"logging"`
+	API         APIConfig         `yaml:"api"`
+	Tenancy     TenancyConfig     `yaml:"tenancy"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Admin       AdminConfig       `yaml:"admin"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Health      HealthConfig      `yaml:"health"`
+	Metrics     MetricsConfig     `yaml:"metrics"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Events      EventsConfig      `yaml:"events"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Stream      StreamConfig      `yaml:"stream"`
+	GraphQL     GraphQLConfig     `yaml:"graphql"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
+	Validation  ValidationConfig  `yaml:"validation"`
+	Duplicates  DuplicatesConfig  `yaml:"duplicates"`
+	Enrichment  EnrichmentConfig  `yaml:"enrichment"`
+	Covers      CoversConfig      `yaml:"covers"`
+	Ebooks      EbooksConfig      `yaml:"ebooks"`
+	OPDS        OPDSConfig        `yaml:"opds"`
+	OAI         OAIConfig         `yaml:"oai"`
+	SRU         SRUConfig         `yaml:"sru"`
+
+	overrideProblems []string
 }
 
 type ServerConfig struct {
-	Port string `yaml:"port"`
-	Host string `yaml:"host"`
+	Port              string        `yaml:"port"`
+	Host              string        `yaml:"host"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout is the grace period for draining in-flight requests
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// RequestTimeout bounds each request's context; RouteTimeouts overrides it
+	// per "METHOD /route/:template" and 0 disables the deadline
+	RequestTimeout time.Duration            `yaml:"request_timeout"`
+	RouteTimeouts  map[string]time.Duration `yaml:"route_timeouts"`
 }
 
+// DatabaseConfig describes the Postgres connection. URL, when set, takes
+// precedence over the individual fields.
 type DatabaseConfig struct {
+	URL            string `yaml:"url" secret:"true"`
 	Driver         string `yaml:"driver"`
 	Host           string `yaml:"host"`
 	Port           string `yaml:"port"`
 	User           string `yaml:"user"`
-	Password       string `yaml:"password"`
+	Password       string `yaml:"password" secret:"true"`
 	Name           string `yaml:"name"`
 	SSLMode        string `yaml:"ssl_mode"`
 	MaxConnections int    `yaml:"max_connections"`
 	MaxIdle        int    `yaml:"max_idle_connections"`
+	// AutoMigrate applies pending schema migrations on startup; without it
+	// they are applied with the migrate command
+	AutoMigrate bool `yaml:"auto_migrate"`
 }
 
 type CORSConfig struct {
@@ -50,46 +92,439 @@ type APIConfig struct {
 	SwaggerPath   string `yaml:"swagger_path"`
 }
 
+// TenancyConfig controls how requests are mapped to tenants. When disabled,
+// every request is served as DefaultTenant.
+type TenancyConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Strategies    []string      `yaml:"strategies"`
+	HeaderName    string        `yaml:"header_name"`
+	APIKeyHeader  string        `yaml:"api_key_header"`
+	BaseDomain    string        `yaml:"base_domain"`
+	DefaultTenant string        `yaml:"default_tenant"`
+	CacheTTL      time.Duration `yaml:"cache_ttl"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	RPS     int  `yaml:"rps"`
+	Burst   int  `yaml:"burst"`
+}
+
+// IdempotencyConfig controls how long Idempotency-Key responses are kept
+type IdempotencyConfig struct {
+	TTL             time.Duration `yaml:"ttl"`
+	LockTTL         time.Duration `yaml:"lock_ttl"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval"`
+	// MaxBodySize bounds the request bodies buffered to detect a reused key,
+	// in bytes; ebook imports are allowed up to ebooks.max_size
+	MaxBodySize int64 `yaml:"max_body_size"`
+}
+
+// HealthConfig controls the /livez and /readyz probes
+type HealthConfig struct {
+	// CacheTTL is how long check results are reused between probes
+	CacheTTL     time.Duration `yaml:"cache_ttl"`
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+	// PoolSaturation is the share of connections in use reported as degraded
+	PoolSaturation float64 `yaml:"pool_saturation"`
+	// ShutdownDelay keeps serving after readiness fails so load balancers
+	// stop routing traffic before connections are drained
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+}
+
+// MetricsConfig controls the Prometheus endpoint
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+	// BookCountInterval is how often books_total is recounted from the database
+	BookCountInterval time.Duration `yaml:"book_count_interval"`
+}
+
+// TracingConfig controls OpenTelemetry span export
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Exporter is "otlp" (OTLP over HTTP) or "stdout"
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// CacheConfig controls the read-through book cache. The local LRU is always
+// used when enabled; RedisURL adds a tier shared by all replicas.
+type CacheConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Size is the maximum number of entries in each replica's LRU
+	Size    int           `yaml:"size"`
+	TTL     time.Duration `yaml:"ttl"`
+	ListTTL time.Duration `yaml:"list_ttl"`
+	// RedisURL is a redis:// or rediss:// URL; empty disables the Redis tier
+	RedisURL    string `yaml:"redis_url" secret:"true"`
+	RedisPrefix string `yaml:"redis_prefix"`
+	// InvalidationChannel is the Postgres NOTIFY channel shared by replicas
+	InvalidationChannel string `yaml:"invalidation_channel"`
+}
+
+// EventsConfig controls the outbox relay that publishes book events
+type EventsConfig struct {
+	// Sinks lists where events go: "bus" (in-process), "nats", "kafka"
+	Sinks          []string      `yaml:"sinks"`
+	RelayInterval  time.Duration `yaml:"relay_interval"`
+	BatchSize      int           `yaml:"batch_size"`
+	BackoffBase    time.Duration `yaml:"backoff_base"`
+	BackoffMax     time.Duration `yaml:"backoff_max"`
+	PublishTimeout time.Duration `yaml:"publish_timeout"`
+	// Retention is how long published events stay in the outbox
+	Retention time.Duration `yaml:"retention"`
+	NATS      NATSConfig    `yaml:"nats"`
+	Kafka     KafkaConfig   `yaml:"kafka"`
+}
+
+type NATSConfig struct {
+	URL           string `yaml:"url" secret:"true"`
+	SubjectPrefix string `yaml:"subject_prefix"`
+}
+
+type KafkaConfig struct {
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+}
+
+// WebhooksConfig controls delivery of book events to tenant webhooks. The
+// deliveries are queued from the in-process bus, so the "bus" sink must be on.
+type WebhooksConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	// Timeout bounds each HTTP request to a receiver
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxAttempts is how often a delivery is tried before it is marked failed
+	MaxAttempts int           `yaml:"max_attempts"`
+	BackoffBase time.Duration `yaml:"backoff_base"`
+	BackoffMax  time.Duration `yaml:"backoff_max"`
+	// FailureThreshold disables a webhook after that many failed attempts in a row
+	FailureThreshold int `yaml:"failure_threshold"`
+	// AllowPrivateNetworks permits loopback and private receiver addresses
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
+}
+
+// StreamConfig controls GET /api/v1/books/stream. Relayed events reach every
+// replica over Postgres NOTIFY, so the "bus" sink must be on.
+type StreamConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Channel string `yaml:"channel"`
+	// ReplaySize is how many recent events each replica keeps for clients
+	// resuming with Last-Event-ID
+	ReplaySize int `yaml:"replay_size"`
+	// ClientQueue is how far a client may fall behind before it is
+	// disconnected to resume from the replay buffer
+	ClientQueue int           `yaml:"client_queue"`
+	Heartbeat   time.Duration `yaml:"heartbeat"`
+}
+
+// GraphQLConfig controls the /graphql endpoint and the queries it accepts
+type GraphQLConfig struct {
+	Enabled       bool `yaml:"enabled"`
+	MaxDepth      int  `yaml:"max_depth"`
+	MaxComplexity int  `yaml:"max_complexity"`
+	// ListCost is the number of items assumed per list field when scoring
+	// complexity
+	ListCost int `yaml:"list_cost"`
+	// PersistedQueries is "off", "auto" (automatic persisted queries) or
+	// "only", which runs nothing but the queries in PersistedQueriesFile
+	PersistedQueries     string `yaml:"persisted_queries"`
+	PersistedQueriesFile string `yaml:"persisted_queries_file"`
+	// PersistedQueriesCacheSize bounds the queries remembered in auto mode
+	PersistedQueriesCacheSize int `yaml:"persisted_queries_cache_size"`
+}
+
+// GRPCConfig controls the gRPC listener serving BookService next to the
+// HTTP API. It binds to server.host.
+type GRPCConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Port    string `yaml:"port"`
+	// Reflection lets tools such as grpcurl discover the services
+	Reflection bool `yaml:"reflection"`
+}
+
+// ValidationConfig holds the deployment's input validation rules. Tenants
+// may override them through their settings.
+type ValidationConfig struct {
+	Books BookValidationConfig `yaml:"books"`
+}
+
+// BookValidationConfig mirrors the book validation rules of the domain.
+// Year offsets are relative to the current year and take precedence over
+// the absolute bounds; zero bounds without an offset are left open.
+type BookValidationConfig struct {
+	MinYear       int            `yaml:"min_year"`
+	MaxYear       int            `yaml:"max_year"`
+	MinYearOffset *int           `yaml:"min_year_offset,omitempty"`
+	MaxYearOffset *int           `yaml:"max_year_offset,omitempty"`
+	Title         TextRuleConfig `yaml:"title"`
+	Author        TextRuleConfig `yaml:"author"`
+}
+
+// TextRuleConfig constrains a text field. Required defaults to true and
+// MaxLength to the column size of 255 characters.
+type TextRuleConfig struct {
+	Required       *bool    `yaml:"required,omitempty"`
+	MinLength      int      `yaml:"min_length"`
+	MaxLength      int      `yaml:"max_length"`
+	Pattern        string   `yaml:"pattern"`
+	ForbiddenWords []string `yaml:"forbidden_words"`
+}
+
+// DuplicatesConfig controls duplicate detection. When enabled, new books
+// resembling existing ones are rejected unless forced; the report at
+// /api/v1/books/duplicates works either way.
+type DuplicatesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Threshold is the minimum similarity, between 0 and 1, of the
+	// normalized titles and authors of duplicates
+	Threshold float64 `yaml:"threshold"`
+	// Limit bounds the possible duplicates reported for a new book
+	Limit int `yaml:"limit"`
+}
+
+// EnrichmentConfig controls book lookups in external bibliographic sources,
+// served at GET /api/v1/books/lookup and POST /api/v1/books/{id}/enrich
+type EnrichmentConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Providers lists the sources in fallback order: "openlibrary",
+	// "googlebooks" and "fixture"
+	Providers []string `yaml:"providers"`
+	// Timeout bounds each request to a source
+	Timeout   time.Duration `yaml:"timeout"`
+	UserAgent string        `yaml:"user_agent"`
+	// CacheSize bounds the lookups remembered by each replica; 0 disables
+	// the cache
+	CacheSize   int                      `yaml:"cache_size"`
+	CacheTTL    time.Duration            `yaml:"cache_ttl"`
+	OpenLibrary EnrichmentProviderConfig `yaml:"openlibrary"`
+	GoogleBooks EnrichmentProviderConfig `yaml:"googlebooks"`
+	// FixturePath is the JSON file the fixture provider answers from
+	FixturePath string `yaml:"fixture_path"`
+}
+
+// EnrichmentProviderConfig configures one HTTP bibliographic source. An
+// empty BaseURL uses the public API.
+type EnrichmentProviderConfig struct {
+	BaseURL string `yaml:"base_url"`
+	APIKey  string `yaml:"api_key" secret:"true"`
+	// RateLimit is the lookups allowed per second; 0 is unlimited
+	RateLimit float64 `yaml:"rate_limit"`
+	Burst     int     `yaml:"burst"`
+}
+
+// CoversConfig controls cover image uploads at /api/v1/books/{id}/cover and
+// the blob storage that keeps the images and their thumbnails
+type CoversConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxSize bounds uploads, in bytes
+	MaxSize int64 `yaml:"max_size"`
+	// MaxPixels bounds the width times height of uploaded images
+	MaxPixels int `yaml:"max_pixels"`
+	// Sizes maps thumbnail names to the bound of their longer side, in
+	// pixels; "original" names the uploaded image
+	Sizes map[string]int `yaml:"sizes"`
+	// Quality is the JPEG quality of the thumbnails, from 1 to 100
+	Quality int `yaml:"quality"`
+	// CacheMaxAge is how long clients may use a cover before revalidating it
+	CacheMaxAge time.Duration     `yaml:"cache_max_age"`
+	Storage     BlobStorageConfig `yaml:"storage"`
+}
+
+// EbooksConfig controls EPUB and PDF uploads at /api/v1/books/files and the
+// blob storage that keeps the files
+type EbooksConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxSize bounds uploads, in bytes
+	MaxSize int64 `yaml:"max_size"`
+	// MaxEntries bounds the entries of an EPUB archive
+	MaxEntries int `yaml:"max_entries"`
+	// MaxUncompressed bounds how far an EPUB archive, or the streams read
+	// from a PDF, may expand, in bytes
+	MaxUncompressed int64             `yaml:"max_uncompressed"`
+	Storage         BlobStorageConfig `yaml:"storage"`
+}
+
+// OPDSConfig controls the OPDS catalogue feeds at /opds and /opds/v2
+type OPDSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Title names the catalogue in e-reader apps
+	Title string `yaml:"title"`
+	// PageSize is the number of books per page of a feed
+	PageSize int `yaml:"page_size"`
+	// FacetLimit is the number of authors and subjects offered as facets
+	FacetLimit int `yaml:"facet_limit"`
+	// Thumbnail names the cover size linked as the books' thumbnail
+	Thumbnail string `yaml:"thumbnail"`
+}
+
+// OAIConfig controls the OAI-PMH endpoint at /oai
+type OAIConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	RepositoryName string `yaml:"repository_name"`
+	// RepositoryIdentifier namespaces the records' OAI identifiers; the
+	// request's host name when empty
+	RepositoryIdentifier string   `yaml:"repository_identifier"`
+	AdminEmails          []string `yaml:"admin_emails"`
+	// PageSize is the number of records per list response
+	PageSize int `yaml:"page_size"`
+}
+
+// SRUConfig controls the SRU search endpoint at /sru
+type SRUConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Title names the database in explain responses
+	Title string `yaml:"title"`
+	// DefaultRecords is the number of records of a response when the
+	// request does not say
+	DefaultRecords int `yaml:"default_records"`
+	// MaxRecords bounds the number of records of a response
+	MaxRecords int `yaml:"max_records"`
+}
+
+// BlobStorageConfig selects the "local" or "s3" blob store
+type BlobStorageConfig struct {
+	Backend string `yaml:"backend"`
+	// Path is the directory of the local store
+	Path string   `yaml:"path"`
+	S3   S3Config `yaml:"s3"`
+}
+
+// S3Config locates a bucket in Amazon S3 or a compatible service such as MinIO
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key" secret:"true"`
+	SecretKey string `yaml:"secret_key" secret:"true"`
+	// PathStyle addresses the bucket in the URL path; MinIO needs it
+	PathStyle bool `yaml:"path_style"`
+	// CreateBucket creates the bucket on startup unless it exists
+	CreateBucket bool          `yaml:"create_bucket"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+type AdminConfig struct {
+	APIKey string `yaml:"api_key" secret:"true"`
+}
+
 func (db *DatabaseConfig) GetConnectionString() string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		db.Host, db.Port, db.User, db.Password, db.Name, db.SSLMode)
+		quoteDSN(db.Host), quoteDSN(db.Port), quoteDSN(db.User), quoteDSN(db.Password), quoteDSN(db.Name), quoteDSN(db.SSLMode))
 }
 
-func Load() (*Config, error) {
-	var config Config
+// quoteDSN quotes a key/value connection string value so that passwords
+// with spaces or quotes survive
+func quoteDSN(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
 
-	// Try to load from .env.yaml first, then fallback to .env.yaml.example
-	configPaths := []string{".env.yaml", ".env.yaml.example"}
-	var configData []byte
-	var err error
+// applyURL fills the connection fields from a postgres:// URL
+func (db *DatabaseConfig) applyURL() error {
+	u, err := url.Parse(db.URL)
+	if err != nil {
+		return fmt.Errorf("database.url: %w", err)
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return fmt.Errorf("database.url: unsupported scheme %q", u.Scheme)
+	}
 
-	for _, path := range configPaths {
-		configData, err = ioutil.ReadFile(path)
-		if err == nil {
-			break
+	if host := u.Hostname(); host != "" {
+		db.Host = host
+	}
+	if port := u.Port(); port != "" {
+		db.Port = port
+	}
+	if u.User != nil {
+		db.User = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			db.Password = password
 		}
 	}
+	if name := strings.TrimPrefix(u.Path, "/"); name != "" {
+		db.Name = name
+	}
+	if sslMode := u.Query().Get("sslmode"); sslMode != "" {
+		db.SSLMode = sslMode
+	}
+	return nil
+}
+
+// DefaultPaths are tried in order when no configuration file is given
+var DefaultPaths = []string{".env.yaml", ".env.yaml.example"}
 
+// ResolvePath returns path, or the first of DefaultPaths that exists
+func ResolvePath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	for _, candidate := range DefaultPaths {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("failed to read configuration file: none of %s found", strings.Join(DefaultPaths, ", "))
+}
+
+// Load reads the configuration from path (or the first of DefaultPaths
+// when empty), applies environment overrides and validates the result,
+// reporting every problem at once
+func Load(path string) (*Config, error) {
+	config, err := Read(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
+	return config, nil
+}
 
-	err = yaml.Unmarshal(configData, &config)
+// Read is Load without validation, for tools that inspect the configuration.
+// Problems with environment overrides are reported by Validate.
+func Read(path string) (*Config, error) {
+	resolved, err := ResolvePath(path)
 	if err != nil {
+		return nil, err
+	}
+	configData, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(configData, &config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal configuration: %w", err)
 	}
 
-	// Override with environment variables if present
-	if port := os.Getenv("PORT"); port != "" {
-		config.Server.Port = port
+	// Environment overrides: legacy names first, then BYFOOD_* for any field
+	legacy := map[string]*string{
+		"PORT":          &config.Server.Port,
+		"HOST":          &config.Server.Host,
+		"ADMIN_API_KEY": &config.Admin.APIKey,
+		"DATABASE_URL":  &config.Database.URL,
 	}
-	if host := os.Getenv("HOST"); host != "" {
-		config.Server.Host = host
+	for name, field := range legacy {
+		if value := os.Getenv(name); value != "" {
+			*field = value
+		}
 	}
-	if dbURL := os.Getenv("DATABASE_URL"); dbURL != "" {
-		// Simple parsing for DATABASE_URL override
-		// In production, you might want to use url.Parse
+
+	// Unparseable overrides are reported by Validate with everything else
+	config.overrideProblems = applyEnv(reflect.ValueOf(&config).Elem(), EnvPrefix)
+
+	if config.Database.URL != "" {
+		if err := config.Database.applyURL(); err != nil {
+			config.overrideProblems = append(config.overrideProblems, err.Error())
+		}
 	}
 
 	return &config, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix namespaces environment overrides. Every field can be set with
+// the upper-cased path of its YAML keys, e.g. BYFOOD_DATABASE_MAX_CONNECTIONS
+// or BYFOOD_CORS_ALLOWED_ORIGINS. Slices are comma separated, maps are
+// comma separated key=value pairs, and a _FILE suffix reads the value from
+// a file (for mounted secrets).
+const EnvPrefix = "BYFOOD"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides the fields of v from the environment and returns a
+// problem for every value that could not be parsed
+func applyEnv(v reflect.Value, prefix string) []string {
+	var problems []string
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(key)
+
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			problems = append(problems, applyEnv(v.Field(i), name)...)
+			continue
+		}
+
+		raw, ok, err := lookupEnv(name)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(i), raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	return problems
+}
+
+// lookupEnv reads name, or the file named by name_FILE
+func lookupEnv(name string) (string, bool, error) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true, nil
+	}
+	path, ok := os.LookupEnv(name + "_FILE")
+	if !ok {
+		return "", false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+func setField(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", field.Type())
+		}
+		items := reflect.MakeSlice(field.Type(), 0, 0)
+		for _, item := range splitList(raw) {
+			items = reflect.Append(items, reflect.ValueOf(item))
+		}
+		field.Set(items)
+	case reflect.Map:
+		entries := reflect.MakeMap(field.Type())
+		for _, item := range splitList(raw) {
+			key, value, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("expected key=value, got %q", item)
+			}
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err := setField(elem, strings.TrimSpace(value)); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			entries.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)), elem)
+		}
+		field.Set(entries)
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setField(elem.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(elem)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
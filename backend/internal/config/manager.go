@@ -0,0 +1,263 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+// liveFields lists the settings applied without a restart; a change to any
+// other field is logged and ignored until the process restarts
+var liveFields = []string{"logging.level", "cors", "rate_limit", "validation"}
+
+// Applier pushes a configuration into a running component. An error rolls
+// the whole reload back.
+type Applier func(cfg *Config) error
+
+// Revision describes the active configuration
+type Revision struct {
+	Number      int64     `json:"revision"`
+	Checksum    string    `json:"checksum"`
+	Source      string    `json:"source"`
+	LoadedAt    time.Time `json:"loaded_at"`
+	LastAttempt time.Time `json:"last_attempt,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+type namedApplier struct {
+	name  string
+	apply Applier
+}
+
+type snapshot struct {
+	config   *Config
+	revision Revision
+}
+
+// Manager holds the active configuration and reloads its live fields on
+// SIGHUP or when the file changes
+type Manager struct {
+	path   string
+	logger *zap.Logger
+
+	reloadMu sync.Mutex
+	appliers []namedApplier
+	current  atomic.Pointer[snapshot]
+}
+
+func NewManager(path string, cfg *Config, logger *zap.Logger) (*Manager, error) {
+	resolved, err := ResolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manager{path: resolved, logger: logger}
+	m.current.Store(&snapshot{
+		config: cfg,
+		revision: Revision{
+			Number:   1,
+			Checksum: checksum(cfg),
+			Source:   resolved,
+			LoadedAt: time.Now(),
+		},
+	})
+	return m, nil
+}
+
+// Current returns the active configuration; callers must not modify it
+func (m *Manager) Current() *Config {
+	return m.current.Load().config
+}
+
+func (m *Manager) Revision() Revision {
+	return m.current.Load().revision
+}
+
+// OnReload registers an applier, called in registration order on reload
+func (m *Manager) OnReload(name string, apply Applier) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	m.appliers = append(m.appliers, namedApplier{name: name, apply: apply})
+}
+
+// Reload re-reads the configuration file and environment, validates the
+// result and applies the live fields. On any failure the previous
+// configuration stays active.
+func (m *Manager) Reload() error {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	active := m.current.Load()
+	next, err := Read(m.path)
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		m.fail(active, err)
+		return err
+	}
+
+	changed := diff(reflect.ValueOf(*active.config), reflect.ValueOf(*next), "")
+	if len(changed) == 0 {
+		return nil
+	}
+
+	var restart []string
+	for _, field := range changed {
+		if !isLive(field) {
+			restart = append(restart, field)
+		}
+	}
+	if len(restart) > 0 {
+		m.logger.Warn("Configuration changes require a restart and were not applied", zap.Strings("fields", restart))
+	}
+
+	// Only live fields move to the new values; everything else keeps what
+	// the process is actually running with
+	effective := *active.config
+	effective.Logging.Level = next.Logging.Level
+	effective.CORS = next.CORS
+	effective.RateLimit = next.RateLimit
+	effective.Validation = next.Validation
+
+	if reflect.DeepEqual(effective, *active.config) {
+		return nil
+	}
+
+	for i, applier := range m.appliers {
+		if err := applier.apply(&effective); err != nil {
+			err = fmt.Errorf("%s: %w", applier.name, err)
+			for _, applied := range m.appliers[:i] {
+				if rollbackErr := applied.apply(active.config); rollbackErr != nil {
+					m.logger.Error("Failed to roll back configuration", zap.String("applier", applied.name), zap.Error(rollbackErr))
+				}
+			}
+			m.fail(active, err)
+			return err
+		}
+	}
+
+	now := time.Now()
+	m.current.Store(&snapshot{
+		config: &effective,
+		revision: Revision{
+			Number:      active.revision.Number + 1,
+			Checksum:    checksum(&effective),
+			Source:      m.path,
+			LoadedAt:    now,
+			LastAttempt: now,
+		},
+	})
+	m.logger.Info("Configuration reloaded",
+		zap.Int64("revision", active.revision.Number+1),
+		zap.Strings("changed", changed),
+	)
+	return nil
+}
+
+func (m *Manager) fail(active *snapshot, err error) {
+	m.logger.Error("Configuration reload rejected, keeping the active configuration",
+		zap.Int64("revision", active.revision.Number),
+		zap.Error(err),
+	)
+	failed := *active
+	failed.revision.LastAttempt = time.Now()
+	failed.revision.LastError = err.Error()
+	m.current.Store(&failed)
+}
+
+// Watch reloads on SIGHUP and whenever the configuration file changes,
+// until ctx is done
+func (m *Manager) Watch(ctx context.Context) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	// Watch the directory so that editors and Kubernetes ConfigMaps, which
+	// replace the file rather than write to it, are picked up
+	var events <-chan fsnotify.Event
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(filepath.Dir(m.path))
+	}
+	if err != nil {
+		m.logger.Warn("Configuration file watching disabled, use SIGHUP to reload", zap.Error(err))
+	} else {
+		defer watcher.Close()
+		events = watcher.Events
+	}
+
+	// Writes arrive as bursts of events; reload once they settle
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	target := filepath.Clean(m.path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			m.logger.Info("SIGHUP received, reloading configuration")
+			m.Reload()
+		case event := <-events:
+			if filepath.Clean(event.Name) == target || filepath.Base(event.Name) == "..data" {
+				debounce.Reset(250 * time.Millisecond)
+			}
+		case <-debounce.C:
+			m.Reload()
+		}
+	}
+}
+
+func isLive(field string) bool {
+	for _, live := range liveFields {
+		if field == live || strings.HasPrefix(field, live+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// diff returns the YAML paths of the fields that differ between a and b
+func diff(a, b reflect.Value, prefix string) []string {
+	var changed []string
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if key == "" {
+			continue
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		fa, fb := a.Field(i), b.Field(i)
+		if fa.Kind() == reflect.Struct && fa.Type() != durationType {
+			changed = append(changed, diff(fa, fb, path)...)
+		} else if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+func checksum(cfg *Config) string {
+	out, _ := yaml.Marshal(cfg.Redacted())
+	sum := sha256.Sum256(out)
+	return hex.EncodeToString(sum[:8])
+}
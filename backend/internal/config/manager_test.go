@@ -0,0 +1,129 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func setupManager(t *testing.T) (*Manager, string, *observer.ObservedLogs) {
+	path := writeConfig(t, baseConfig)
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	manager, err := NewManager(path, cfg, zap.New(core))
+	assert.NoError(t, err)
+	return manager, path, logs
+}
+
+func rewrite(t *testing.T, path, old, new string) {
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Replace(baseConfig, old, new, 1)), 0o600))
+}
+
+func TestManager_Reload(t *testing.T) {
+	t.Run("live fields are applied", func(t *testing.T) {
+		manager, path, _ := setupManager(t)
+		var applied *Config
+		manager.OnReload("test", func(cfg *Config) error {
+			applied = cfg
+			return nil
+		})
+
+		rewrite(t, path, "rps: 100", "rps: 5")
+
+		assert.NoError(t, manager.Reload())
+		assert.Equal(t, 5, applied.RateLimit.RPS)
+		assert.Equal(t, 5, manager.Current().RateLimit.RPS)
+		assert.Equal(t, int64(2), manager.Revision().Number)
+	})
+
+	t.Run("validation rules are applied", func(t *testing.T) {
+		manager, path, logs := setupManager(t)
+		var applied *Config
+		manager.OnReload("test", func(cfg *Config) error {
+			applied = cfg
+			return nil
+		})
+
+		rewrite(t, path, "rate_limit:", "validation:\n  books:\n    title:\n      max_length: 100\nrate_limit:")
+
+		assert.NoError(t, manager.Reload())
+		assert.Equal(t, 100, applied.Validation.Books.Title.MaxLength)
+		assert.Equal(t, 100, manager.Current().Validation.Books.Title.MaxLength)
+		assert.Equal(t, int64(2), manager.Revision().Number)
+		assert.Empty(t, logs.FilterMessage("Configuration changes require a restart and were not applied").All())
+	})
+
+	t.Run("structural fields are reported but not applied", func(t *testing.T) {
+		manager, path, logs := setupManager(t)
+
+		rewrite(t, path, `port: "8080"`, `port: "9090"`)
+
+		assert.NoError(t, manager.Reload())
+		assert.Equal(t, "8080", manager.Current().Server.Port)
+		assert.Equal(t, int64(1), manager.Revision().Number)
+
+		warnings := logs.FilterMessage("Configuration changes require a restart and were not applied").All()
+		assert.Len(t, warnings, 1)
+		assert.Equal(t, []interface{}{"server.port"}, warnings[0].ContextMap()["fields"])
+	})
+
+	t.Run("invalid configuration is rejected", func(t *testing.T) {
+		manager, path, _ := setupManager(t)
+		calls := 0
+		manager.OnReload("test", func(cfg *Config) error {
+			calls++
+			return nil
+		})
+
+		rewrite(t, path, "rps: 100", "rps: -1")
+
+		assert.Error(t, manager.Reload())
+		assert.Equal(t, 0, calls)
+		assert.Equal(t, 100, manager.Current().RateLimit.RPS)
+		assert.Contains(t, manager.Revision().LastError, "rate_limit.rps")
+	})
+
+	t.Run("failing applier rolls back earlier appliers", func(t *testing.T) {
+		manager, path, _ := setupManager(t)
+		var seen []int
+		manager.OnReload("first", func(cfg *Config) error {
+			seen = append(seen, cfg.RateLimit.RPS)
+			return nil
+		})
+		manager.OnReload("second", func(cfg *Config) error {
+			return errors.New("cannot apply")
+		})
+
+		rewrite(t, path, "rps: 100", "rps: 5")
+
+		assert.Error(t, manager.Reload())
+		assert.Equal(t, []int{5, 100}, seen)
+		assert.Equal(t, 100, manager.Current().RateLimit.RPS)
+		assert.Equal(t, int64(1), manager.Revision().Number)
+	})
+}
+
+func TestManager_Watch(t *testing.T) {
+	manager, path, _ := setupManager(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.Watch(ctx)
+
+	// Give the watcher time to register before writing
+	time.Sleep(100 * time.Millisecond)
+	rewrite(t, path, "burst: 200", "burst: 50")
+
+	assert.Eventually(t, func() bool {
+		return manager.Current().RateLimit.Burst == 50
+	}, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v2"
+)
+
+const redactedValue = "******"
+
+// Redacted returns a copy of c with every field tagged secret:"true" masked
+func (c *Config) Redacted() *Config {
+	copied := *c
+	redact(reflect.ValueOf(&copied).Elem())
+	return &copied
+}
+
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case t.Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "":
+			field.SetString(redactedValue)
+		case field.Kind() == reflect.Struct:
+			redact(field)
+		}
+	}
+}
+
+// Print writes c as YAML, masking secrets when redacted is set
+func Print(w io.Writer, c *Config, redacted bool) error {
+	if redacted {
+		c = c.Redacted()
+	}
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
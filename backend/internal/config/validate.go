@@ -0,0 +1,299 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+var (
+	validLogLevels = map[string]bool{"": true, "debug": true, "info": true, "warn": true, "error": true}
+	validSSLModes  = map[string]bool{
+		"": true, "disable": true, "allow": true, "prefer": true,
+		"require": true, "verify-ca": true, "verify-full": true,
+	}
+	validStrategies = map[string]bool{"api_key": true, "header": true, "subdomain": true}
+	validExporters  = map[string]bool{"otlp": true, "stdout": true}
+	validSinks      = map[string]bool{"bus": true, "nats": true, "kafka": true}
+	validPersisted  = map[string]bool{"": true, "off": true, "auto": true, "only": true}
+	validProviders  = map[string]bool{"openlibrary": true, "googlebooks": true, "fixture": true}
+	validBlobStores = map[string]bool{"local": true, "s3": true}
+)
+
+// maxBookTextLength is the size of the book title and author columns
+const maxBookTextLength = 255
+
+// Validate checks the whole configuration and reports all problems together
+func (c *Config) Validate() error {
+	problems := append([]string(nil), c.overrideProblems...)
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(validPort(c.Server.Port), "server.port: %q is not a valid port", c.Server.Port)
+	for _, setting := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"server.request_timeout", c.Server.RequestTimeout},
+		{"tenancy.cache_ttl", c.Tenancy.CacheTTL},
+		{"idempotency.ttl", c.Idempotency.TTL},
+		{"idempotency.lock_ttl", c.Idempotency.LockTTL},
+		{"health.cache_ttl", c.Health.CacheTTL},
+		{"health.check_timeout", c.Health.CheckTimeout},
+		{"health.shutdown_delay", c.Health.ShutdownDelay},
+		{"cache.ttl", c.Cache.TTL},
+		{"cache.list_ttl", c.Cache.ListTTL},
+		{"events.relay_interval", c.Events.RelayInterval},
+		{"events.backoff_base", c.Events.BackoffBase},
+		{"events.backoff_max", c.Events.BackoffMax},
+		{"events.publish_timeout", c.Events.PublishTimeout},
+		{"events.retention", c.Events.Retention},
+		{"webhooks.poll_interval", c.Webhooks.PollInterval},
+		{"webhooks.timeout", c.Webhooks.Timeout},
+		{"webhooks.backoff_base", c.Webhooks.BackoffBase},
+		{"webhooks.backoff_max", c.Webhooks.BackoffMax},
+		{"stream.heartbeat", c.Stream.Heartbeat},
+		{"enrichment.timeout", c.Enrichment.Timeout},
+		{"enrichment.cache_ttl", c.Enrichment.CacheTTL},
+		{"covers.cache_max_age", c.Covers.CacheMaxAge},
+		{"covers.storage.s3.timeout", c.Covers.Storage.S3.Timeout},
+		{"ebooks.storage.s3.timeout", c.Ebooks.Storage.S3.Timeout},
+	} {
+		check(setting.value >= 0, "%s: must not be negative", setting.name)
+	}
+	routes := make([]string, 0, len(c.Server.RouteTimeouts))
+	for route := range c.Server.RouteTimeouts {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		d := c.Server.RouteTimeouts[route]
+		_, path, ok := strings.Cut(route, " ")
+		check(ok && strings.HasPrefix(path, "/"), "server.route_timeouts: %q must be \"METHOD /path\"", route)
+		check(d >= 0, "server.route_timeouts: %q must not be negative", route)
+	}
+
+	check(c.Database.Host != "", "database.host: is required")
+	check(c.Database.Name != "", "database.name: is required")
+	check(c.Database.User != "", "database.user: is required")
+	check(validPort(c.Database.Port), "database.port: %q is not a valid port", c.Database.Port)
+	check(validSSLModes[c.Database.SSLMode], "database.ssl_mode: unknown mode %q", c.Database.SSLMode)
+	check(c.Database.MaxConnections >= 0, "database.max_connections: must not be negative")
+	check(c.Database.MaxIdle >= 0, "database.max_idle_connections: must not be negative")
+	check(c.Database.MaxConnections == 0 || c.Database.MaxIdle <= c.Database.MaxConnections,
+		"database.max_idle_connections: %d exceeds max_connections %d", c.Database.MaxIdle, c.Database.MaxConnections)
+
+	check(validLogLevels[c.Logging.Level], "logging.level: unknown level %q", c.Logging.Level)
+
+	check(!c.API.EnableSwagger || strings.HasPrefix(c.API.SwaggerPath, "/"),
+		"api.swagger_path: must start with / when swagger is enabled")
+
+	for _, strategy := range c.Tenancy.Strategies {
+		check(validStrategies[strategy], "tenancy.strategies: unknown strategy %q", strategy)
+	}
+	check(!c.Tenancy.Enabled || len(c.Tenancy.Strategies) > 0 || c.Tenancy.DefaultTenant != "",
+		"tenancy: enabled without strategies or a default tenant")
+
+	if c.RateLimit.Enabled {
+		check(c.RateLimit.RPS > 0, "rate_limit.rps: must be positive when rate limiting is enabled")
+		check(c.RateLimit.Burst > 0, "rate_limit.burst: must be positive when rate limiting is enabled")
+	}
+
+	check(c.Idempotency.MaxBodySize >= 0, "idempotency.max_body_size: must not be negative")
+	check(c.Idempotency.LockTTL == 0 || c.Idempotency.TTL == 0 || c.Idempotency.LockTTL <= c.Idempotency.TTL,
+		"idempotency.lock_ttl: must not exceed ttl")
+
+	check(c.Health.PoolSaturation >= 0 && c.Health.PoolSaturation <= 1,
+		"health.pool_saturation: must be between 0 and 1")
+
+	check(!c.Metrics.Enabled || strings.HasPrefix(c.Metrics.Path, "/"),
+		"metrics.path: must start with / when metrics are enabled")
+
+	if c.Tracing.Enabled {
+		check(validExporters[c.Tracing.Exporter], "tracing.exporter: unknown exporter %q", c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1,
+		"tracing.sample_ratio: must be between 0 and 1")
+
+	check(c.Cache.Size >= 0, "cache.size: must not be negative")
+	if c.Cache.RedisURL != "" {
+		u, err := url.Parse(c.Cache.RedisURL)
+		check(err == nil && (u.Scheme == "redis" || u.Scheme == "rediss"),
+			"cache.redis_url: must be a redis:// or rediss:// URL")
+	}
+
+	check(c.Events.BatchSize >= 0, "events.batch_size: must not be negative")
+	for _, sink := range c.Events.Sinks {
+		check(validSinks[sink], "events.sinks: unknown sink %q", sink)
+		switch sink {
+		case "nats":
+			check(c.Events.NATS.URL != "", "events.nats.url: is required when the nats sink is enabled")
+		case "kafka":
+			check(len(c.Events.Kafka.Brokers) > 0, "events.kafka.brokers: are required when the kafka sink is enabled")
+			check(c.Events.Kafka.Topic != "", "events.kafka.topic: is required when the kafka sink is enabled")
+		}
+	}
+
+	check(c.Webhooks.BatchSize >= 0, "webhooks.batch_size: must not be negative")
+	check(c.Webhooks.MaxAttempts >= 0, "webhooks.max_attempts: must not be negative")
+	check(c.Webhooks.FailureThreshold >= 0, "webhooks.failure_threshold: must not be negative")
+	check(c.Stream.ReplaySize >= 0, "stream.replay_size: must not be negative")
+	check(c.Stream.ClientQueue >= 0, "stream.client_queue: must not be negative")
+	check(c.GraphQL.MaxDepth >= 0, "graphql.max_depth: must not be negative")
+	check(c.GraphQL.MaxComplexity >= 0, "graphql.max_complexity: must not be negative")
+	check(c.GraphQL.ListCost >= 0, "graphql.list_cost: must not be negative")
+	check(c.GraphQL.PersistedQueriesCacheSize >= 0, "graphql.persisted_queries_cache_size: must not be negative")
+	check(validPersisted[c.GraphQL.PersistedQueries], "graphql.persisted_queries: unknown mode %q", c.GraphQL.PersistedQueries)
+	check(c.GraphQL.PersistedQueries != "only" || c.GraphQL.PersistedQueriesFile != "",
+		"graphql.persisted_queries_file: is required when persisted_queries is \"only\"")
+	if c.GRPC.Enabled {
+		check(c.GRPC.Port == "" || validPort(c.GRPC.Port), "grpc.port: %q is not a valid port", c.GRPC.Port)
+		check(c.GRPC.Port != c.Server.Port, "grpc.port: must differ from server.port")
+	}
+
+	books := c.Validation.Books
+	check(books.MinYear == 0 || books.MaxYear == 0 || books.MinYearOffset != nil || books.MaxYearOffset != nil ||
+		books.MinYear <= books.MaxYear, "validation.books.min_year: must not be after max_year")
+	check(books.MinYearOffset == nil || books.MaxYearOffset == nil || *books.MinYearOffset <= *books.MaxYearOffset,
+		"validation.books.min_year_offset: must not be after max_year_offset")
+	for _, rule := range []struct {
+		name string
+		rule TextRuleConfig
+	}{
+		{"validation.books.title", books.Title},
+		{"validation.books.author", books.Author},
+	} {
+		max := rule.rule.MaxLength
+		if max == 0 {
+			max = maxBookTextLength
+		}
+		check(rule.rule.MinLength >= 0, "%s.min_length: must not be negative", rule.name)
+		check(max > 0 && max <= maxBookTextLength, "%s.max_length: must be between 1 and %d", rule.name, maxBookTextLength)
+		check(rule.rule.MinLength <= max, "%s.min_length: must not exceed max_length", rule.name)
+		if rule.rule.Pattern != "" {
+			_, err := regexp.Compile(rule.rule.Pattern)
+			check(err == nil, "%s.pattern: %v", rule.name, err)
+		}
+	}
+
+	check(c.Duplicates.Threshold >= 0 && c.Duplicates.Threshold <= 1, "duplicates.threshold: must be between 0 and 1")
+	check(c.Duplicates.Limit >= 0, "duplicates.limit: must not be negative")
+
+	seenProviders := map[string]bool{}
+	for _, provider := range c.Enrichment.Providers {
+		check(validProviders[provider], "enrichment.providers: unknown provider %q", provider)
+		check(!seenProviders[provider], "enrichment.providers: %q is listed twice", provider)
+		seenProviders[provider] = true
+	}
+	check(!c.Enrichment.Enabled || len(c.Enrichment.Providers) > 0, "enrichment.providers: are required when enrichment is enabled")
+	check(!seenProviders["fixture"] || c.Enrichment.FixturePath != "",
+		"enrichment.fixture_path: is required when the fixture provider is enabled")
+	check(c.Enrichment.CacheSize >= 0, "enrichment.cache_size: must not be negative")
+	for _, provider := range []struct {
+		name   string
+		config EnrichmentProviderConfig
+	}{
+		{"enrichment.openlibrary", c.Enrichment.OpenLibrary},
+		{"enrichment.googlebooks", c.Enrichment.GoogleBooks},
+	} {
+		if provider.config.BaseURL != "" {
+			u, err := url.Parse(provider.config.BaseURL)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+				"%s.base_url: %q is not an http or https URL", provider.name, provider.config.BaseURL)
+		}
+		check(provider.config.RateLimit >= 0, "%s.rate_limit: must not be negative", provider.name)
+		check(provider.config.Burst >= 0, "%s.burst: must not be negative", provider.name)
+	}
+
+	check(c.Covers.MaxSize >= 0, "covers.max_size: must not be negative")
+	check(c.Covers.MaxPixels >= 0, "covers.max_pixels: must not be negative")
+	check(c.Covers.Quality >= 0 && c.Covers.Quality <= 100, "covers.quality: must be between 1 and 100")
+	sizes := make([]string, 0, len(c.Covers.Sizes))
+	for size := range c.Covers.Sizes {
+		sizes = append(sizes, size)
+	}
+	sort.Strings(sizes)
+	for _, size := range sizes {
+		check(size != "original", "covers.sizes: %q names the uploaded image", size)
+		check(c.Covers.Sizes[size] > 0, "covers.sizes: %q must be positive", size)
+	}
+	checkStorage := func(prefix string, storage BlobStorageConfig) {
+		check(validBlobStores[storage.Backend], "%s.backend: unknown backend %q", prefix, storage.Backend)
+		check(storage.Backend != "local" || storage.Path != "", "%s.path: is required for the local backend", prefix)
+		if storage.Backend == "s3" {
+			u, err := url.Parse(storage.S3.Endpoint)
+			check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+				"%s.s3.endpoint: %q is not an http or https URL", prefix, storage.S3.Endpoint)
+			check(storage.S3.Bucket != "", "%s.s3.bucket: is required for the s3 backend", prefix)
+		}
+	}
+	if c.Covers.Enabled {
+		checkStorage("covers.storage", c.Covers.Storage)
+	}
+
+	check(c.Ebooks.MaxSize >= 0, "ebooks.max_size: must not be negative")
+	check(c.Ebooks.MaxEntries >= 0, "ebooks.max_entries: must not be negative")
+	check(c.Ebooks.MaxUncompressed >= 0, "ebooks.max_uncompressed: must not be negative")
+	if c.Ebooks.Enabled {
+		checkStorage("ebooks.storage", c.Ebooks.Storage)
+	}
+
+	check(c.OPDS.PageSize >= 0 && c.OPDS.PageSize <= 200, "opds.page_size: must be between 1 and 200")
+	check(c.OPDS.FacetLimit >= 0, "opds.facet_limit: must not be negative")
+	if c.OPDS.Thumbnail != "" && len(c.Covers.Sizes) > 0 {
+		_, ok := c.Covers.Sizes[c.OPDS.Thumbnail]
+		check(ok, "opds.thumbnail: %q is not one of covers.sizes", c.OPDS.Thumbnail)
+	}
+
+	check(c.OAI.PageSize >= 0 && c.OAI.PageSize <= 1000, "oai.page_size: must be between 1 and 1000")
+	check(!c.OAI.Enabled || len(c.OAI.AdminEmails) > 0, "oai.admin_emails: required when OAI-PMH is enabled")
+	for _, email := range c.OAI.AdminEmails {
+		check(strings.Contains(email, "@"), "oai.admin_emails: %q is not an email address", email)
+	}
+
+	check(c.SRU.MaxRecords >= 0 && c.SRU.MaxRecords <= 1000, "sru.max_records: must be between 1 and 1000")
+	check(c.SRU.DefaultRecords >= 0 && (c.SRU.MaxRecords == 0 || c.SRU.DefaultRecords <= c.SRU.MaxRecords),
+		"sru.default_records: must be between 1 and sru.max_records")
+
+	bus := false
+	for _, sink := range c.Events.Sinks {
+		bus = bus || sink == "bus"
+	}
+	check(!c.Webhooks.Enabled || bus, "webhooks.enabled: requires the bus sink in events.sinks")
+	check(!c.Stream.Enabled || bus, "stream.enabled: requires the bus sink in events.sinks")
+	// The covers of deleted books are removed by a bus subscriber
+	check(!c.Covers.Enabled || bus, "covers.enabled: requires the bus sink in events.sinks")
+	// So are the files of deleted books, and those of merged books moved
+	check(!c.Ebooks.Enabled || bus, "ebooks.enabled: requires the bus sink in events.sinks")
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
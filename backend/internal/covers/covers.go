@@ -0,0 +1,144 @@
+// Package covers turns uploaded cover images into the renditions served for
+// books: the original as uploaded and JPEG thumbnails bounded by configured
+// sizes. Images are identified by content sniffing, never by the client's
+// Content-Type, and their dimensions are checked before they are decoded so
+// that a small file declaring a huge image is refused.
+package covers
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"sort"
+
+	"byfood-library/internal/domain/entities"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Original names the uploaded image among the renditions
+const Original = "original"
+
+const (
+	DefaultMaxSize   = 5 << 20
+	DefaultMaxPixels = 40_000_000
+	DefaultQuality   = 85
+)
+
+// DefaultSizes bound the width and height of the thumbnails, in pixels
+var DefaultSizes = map[string]int{"small": 128, "medium": 320, "large": 640}
+
+// contentTypes are the sniffed types accepted for upload
+var contentTypes = map[string]bool{"image/jpeg": true, "image/png": true, "image/webp": true}
+
+// Config tunes the processor. Zero values use the defaults.
+type Config struct {
+	// MaxSize bounds the uploaded file, in bytes
+	MaxSize int64
+	// MaxPixels bounds the width times height of the uploaded image
+	MaxPixels int
+	// Sizes maps thumbnail names to the bound of their longer side
+	Sizes map[string]int
+	// Quality is the JPEG quality of the thumbnails, from 1 to 100
+	Quality int
+}
+
+// Processor checks uploads and renders thumbnails
+type Processor struct {
+	config Config
+	names  []string
+}
+
+func NewProcessor(config Config) *Processor {
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultMaxSize
+	}
+	if config.MaxPixels <= 0 {
+		config.MaxPixels = DefaultMaxPixels
+	}
+	if len(config.Sizes) == 0 {
+		config.Sizes = DefaultSizes
+	}
+	if config.Quality <= 0 || config.Quality > 100 {
+		config.Quality = DefaultQuality
+	}
+	names := make([]string, 0, len(config.Sizes))
+	for name := range config.Sizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &Processor{config: config, names: names}
+}
+
+// MaxSize is the largest upload accepted, in bytes
+func (p *Processor) MaxSize() int64 {
+	return p.config.MaxSize
+}
+
+// HasSize reports whether name is Original or a configured thumbnail
+func (p *Processor) HasSize(name string) bool {
+	_, ok := p.config.Sizes[name]
+	return ok || name == Original
+}
+
+// Rendition is one stored form of a cover
+type Rendition struct {
+	Name        string
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+}
+
+// Process checks an upload and returns the original followed by the
+// thumbnails in name order. Thumbnails keep the aspect ratio, are never
+// larger than the original and have transparency flattened onto white.
+func (p *Processor) Process(data []byte) ([]Rendition, error) {
+	if int64(len(data)) > p.config.MaxSize {
+		return nil, entities.ErrCoverTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if !contentTypes[contentType] {
+		return nil, entities.ErrInvalidCoverImage
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil, entities.ErrInvalidCoverImage
+	}
+	if config.Width*config.Height > p.config.MaxPixels {
+		return nil, entities.ErrCoverTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, entities.ErrInvalidCoverImage
+	}
+
+	renditions := []Rendition{{Name: Original, ContentType: contentType, Data: data, Width: config.Width, Height: config.Height}}
+	for _, name := range p.names {
+		width, height := fit(config.Width, config.Height, p.config.Sizes[name])
+		thumbnail := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(thumbnail, thumbnail.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), img, img.Bounds(), draw.Over, nil)
+
+		var out bytes.Buffer
+		if err := jpeg.Encode(&out, thumbnail, &jpeg.Options{Quality: p.config.Quality}); err != nil {
+			return nil, err
+		}
+		renditions = append(renditions, Rendition{Name: name, ContentType: "image/jpeg", Data: out.Bytes(), Width: width, Height: height})
+	}
+	return renditions, nil
+}
+
+// fit scales width and height so that the longer side is at most bound
+func fit(width, height, bound int) (int, int) {
+	if width <= bound && height <= bound {
+		return width, height
+	}
+	if width >= height {
+		return bound, max(1, height*bound/width)
+	}
+	return max(1, width*bound/height), bound
+}
@@ -0,0 +1,100 @@
+package covers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodePNG returns a width x height PNG, half transparent
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, A: uint8(255 * (x % 2))})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// webpPixel is a 1x1 lossless WebP
+const webpPixel = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func TestProcessor_Process(t *testing.T) {
+	processor := NewProcessor(Config{Sizes: map[string]int{"small": 100, "large": 800}})
+
+	t.Run("renders thumbnails within their bounds", func(t *testing.T) {
+		data := encodePNG(t, 300, 600)
+
+		renditions, err := processor.Process(data)
+
+		require.NoError(t, err)
+		require.Len(t, renditions, 3)
+		assert.Equal(t, Rendition{Name: Original, ContentType: "image/png", Data: data, Width: 300, Height: 600}, renditions[0])
+
+		// Thumbnails come in name order; large would upscale, so it keeps the original size
+		assert.Equal(t, "large", renditions[1].Name)
+		assert.Equal(t, [2]int{300, 600}, [2]int{renditions[1].Width, renditions[1].Height})
+		assert.Equal(t, "small", renditions[2].Name)
+		assert.Equal(t, [2]int{50, 100}, [2]int{renditions[2].Width, renditions[2].Height})
+		for _, thumbnail := range renditions[1:] {
+			assert.Equal(t, "image/jpeg", thumbnail.ContentType)
+			img, err := jpeg.Decode(bytes.NewReader(thumbnail.Data))
+			require.NoError(t, err)
+			assert.Equal(t, thumbnail.Width, img.Bounds().Dx())
+			assert.Equal(t, thumbnail.Height, img.Bounds().Dy())
+		}
+	})
+
+	t.Run("accepts JPEG and WebP", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 20, 10)), nil))
+		webp, _ := base64.StdEncoding.DecodeString(webpPixel)
+
+		for contentType, data := range map[string][]byte{"image/jpeg": buf.Bytes(), "image/webp": webp} {
+			renditions, err := processor.Process(data)
+			require.NoError(t, err, contentType)
+			assert.Equal(t, contentType, renditions[0].ContentType)
+		}
+	})
+
+	t.Run("rejects other content", func(t *testing.T) {
+		for name, data := range map[string][]byte{
+			"text":      []byte("<svg xmlns='http://www.w3.org/2000/svg'></svg>"),
+			"gif":       []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"),
+			"truncated": encodePNG(t, 10, 10)[:40],
+		} {
+			_, err := processor.Process(data)
+			assert.ErrorIs(t, err, entities.ErrInvalidCoverImage, name)
+		}
+	})
+
+	t.Run("rejects large files and images", func(t *testing.T) {
+		small := NewProcessor(Config{MaxSize: 100, MaxPixels: 50})
+
+		_, err := small.Process(encodePNG(t, 200, 200))
+		assert.ErrorIs(t, err, entities.ErrCoverTooLarge)
+
+		// Checked on the declared dimensions, before decoding
+		_, err = small.Process(encodePNG(t, 8, 8))
+		assert.ErrorIs(t, err, entities.ErrCoverTooLarge)
+	})
+}
+
+func TestProcessor_HasSize(t *testing.T) {
+	processor := NewProcessor(Config{})
+
+	assert.True(t, processor.HasSize(Original))
+	assert.True(t, processor.HasSize("medium"))
+	assert.False(t, processor.HasSize("huge"))
+}
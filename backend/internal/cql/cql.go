@@ -0,0 +1,206 @@
+// Package cql parses queries in the Contextual Query Language (CQL 1.2)
+// that SRU clients send. A query is a tree of search clauses, such as
+// `dc.title any "clean code"`, joined by the booleans and, or, not and prox,
+// each of which may carry modifiers, and it may end with sort keys:
+//
+//	dc.creator = "martin" and (dc.date >= 2000 or dc.subject == software) sortBy dc.title/sort.descending
+//
+// Parse only checks the syntax. It leaves the meaning of indexes, relations,
+// modifiers and terms to the translator that runs the query, which reports
+// what it cannot run as an *UnsupportedError.
+package cql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Identifiers of the context sets of the indexes and relations
+const (
+	ContextSetCQL = "info:srw/cql-context-set/1/cql-v1.2"
+	ContextSetDC  = "info:srw/cql-context-set/1/dc-v1.1"
+	ContextSetRec = "info:srw/cql-context-set/2/rec-1.1"
+)
+
+// Booleans joining clauses
+const (
+	And  = "and"
+	Or   = "or"
+	Not  = "not"
+	Prox = "prox"
+)
+
+// Query is a parsed CQL query
+type Query struct {
+	Root     Node
+	SortKeys []SortKey
+}
+
+// Node is a *SearchClause or a *Boolean
+type Node interface {
+	fmt.Stringer
+	node()
+}
+
+// SearchClause matches the books whose index relates to the term. A bare
+// term is a clause of the cql.serverChoice index with the = relation.
+type SearchClause struct {
+	Index    Index
+	Relation Relation
+	// Term is the search term as written, without its quotes: backslash
+	// escapes are kept, so that escaped masking characters can be told from
+	// unescaped ones
+	Term string
+}
+
+// Boolean joins two nodes
+type Boolean struct {
+	// Operator is one of And, Or, Not and Prox
+	Operator  string
+	Modifiers []Modifier
+	Left      Node
+	Right     Node
+}
+
+// Index names what a clause searches, lower-cased, such as "dc" and
+// "title" for dc.title
+type Index struct {
+	Prefix string
+	Name   string
+	// ContextSet is the identifier a prefix assignment in the query bound
+	// the prefix to; it is empty when the query did not assign the prefix
+	ContextSet string
+}
+
+// Relation relates an index to a term. Name is a comparison symbol, such as
+// "=" or "<=", or a lower-cased named relation, such as "any", "all",
+// "adj" or "within", without the cql prefix.
+type Relation struct {
+	Name      string
+	Modifiers []Modifier
+}
+
+// Modifier refines a relation, boolean or sort key, such as /respectCase or
+// /distance<=3. Name is lower-cased, and Comparison and Value are empty
+// for a modifier without a value.
+type Modifier struct {
+	Name       string
+	Comparison string
+	Value      string
+}
+
+// SortKey orders the results by an index
+type SortKey struct {
+	Index     Index
+	Modifiers []Modifier
+}
+
+func (*SearchClause) node() {}
+func (*Boolean) node()      {}
+
+// FindModifier returns the modifier of the name, with or without the
+// prefix, if the list has it
+func FindModifier(modifiers []Modifier, prefix, name string) (Modifier, bool) {
+	for _, modifier := range modifiers {
+		if modifier.Name == name || modifier.Name == prefix+"."+name {
+			return modifier, true
+		}
+	}
+	return Modifier{}, false
+}
+
+// String renders the query canonically: indexes, relations and terms are
+// explicit, terms are quoted and both sides of a boolean are parenthesized
+func (q *Query) String() string {
+	s := q.Root.String()
+	if len(q.SortKeys) > 0 {
+		keys := make([]string, len(q.SortKeys))
+		for i, key := range q.SortKeys {
+			keys[i] = key.Index.String() + modifiersString(key.Modifiers)
+		}
+		s += " sortby " + strings.Join(keys, " ")
+	}
+	return s
+}
+
+func (c *SearchClause) String() string {
+	return c.Index.String() + " " + c.Relation.Name + modifiersString(c.Relation.Modifiers) + ` "` + c.Term + `"`
+}
+
+func (b *Boolean) String() string {
+	return "(" + b.Left.String() + ") " + b.Operator + modifiersString(b.Modifiers) + " (" + b.Right.String() + ")"
+}
+
+func (i Index) String() string {
+	if i.Prefix == "" {
+		return i.Name
+	}
+	return i.Prefix + "." + i.Name
+}
+
+func modifiersString(modifiers []Modifier) string {
+	var b strings.Builder
+	for _, modifier := range modifiers {
+		b.WriteString("/" + modifier.Name)
+		if modifier.Comparison != "" {
+			b.WriteString(modifier.Comparison + quoteIfNeeded(modifier.Value))
+		}
+	}
+	return b.String()
+}
+
+func quoteIfNeeded(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n()=<>/\"") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// SyntaxError is a query that is not valid CQL
+type SyntaxError struct {
+	// Offset is the byte offset of the error in the query
+	Offset  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("CQL syntax error at offset %d: %s", e.Offset, e.Message)
+}
+
+// Feature is a part of CQL that a query uses
+type Feature string
+
+const (
+	FeatureContextSet        Feature = "context set"
+	FeatureIndex             Feature = "index"
+	FeatureRelation          Feature = "relation"
+	FeatureRelationModifier  Feature = "relation modifier"
+	FeatureRelationForIndex  Feature = "relation for index"
+	FeatureTerm              Feature = "term"
+	FeatureEmptyTerm         Feature = "empty term"
+	FeatureAnchoring         Feature = "anchoring"
+	FeatureBooleanModifier   Feature = "boolean modifier"
+	FeatureBooleans          Feature = "number of booleans"
+	FeatureProximity         Feature = "proximity"
+	FeatureProximityRelation Feature = "proximity relation"
+	FeatureProximityDistance Feature = "proximity distance"
+	FeatureProximityUnit     Feature = "proximity unit"
+	FeatureSortIndex         Feature = "sort index"
+	FeatureSortModifier      Feature = "sort modifier"
+)
+
+// UnsupportedError is a valid query that uses a feature, such as an index
+// or a relation, that cannot be run
+type UnsupportedError struct {
+	Feature Feature
+	// Value is the unsupported value as written, such as the index name
+	Value string
+}
+
+func Unsupported(feature Feature, value string) *UnsupportedError {
+	return &UnsupportedError{Feature: feature, Value: value}
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("unsupported %s: %s", e.Feature, e.Value)
+}
@@ -0,0 +1,209 @@
+package cql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"bare word", `clean`, `cql.serverchoice = "clean"`},
+		{"bare quoted term", `"clean code"`, `cql.serverchoice = "clean code"`},
+		{"index and relation", `dc.title = clean`, `dc.title = "clean"`},
+		{"no spaces around the relation", `dc.title="clean code"`, `dc.title = "clean code"`},
+		{"unprefixed index", `title any "clean code"`, `title any "clean code"`},
+		{"names are case-insensitive", `DC.Title ANY Clean`, `dc.title any "Clean"`},
+		{"prefixed named relation", `dc.title cql.all "clean code"`, `dc.title all "clean code"`},
+		{"two-character comparisons", `dc.date >= 2000 and dc.date <> 2005 and dc.title == x`, `((dc.date >= "2000") and (dc.date <> "2005")) and (dc.title == "x")`},
+		{"one-character comparisons", `dc.date<2000 or dc.date>2010`, `(dc.date < "2000") or (dc.date > "2010")`},
+		{"within", `dc.date within "1990 2000"`, `dc.date within "1990 2000"`},
+		{"booleans are left-associative", `a or b and c`, `((cql.serverchoice = "a") or (cql.serverchoice = "b")) and (cql.serverchoice = "c")`},
+		{"parentheses group", `a or (b and c)`, `(cql.serverchoice = "a") or ((cql.serverchoice = "b") and (cql.serverchoice = "c"))`},
+		{"nested parentheses", `((a))`, `cql.serverchoice = "a"`},
+		{"not", `dc.title = code NOT dc.creator = martin`, `(dc.title = "code") not (dc.creator = "martin")`},
+		{"relation modifiers", `dc.title =/respectCase/cql.unmasked "C*"`, `dc.title =/respectcase/cql.unmasked "C*"`},
+		{"proximity with modifiers", `clean prox/unit=word/distance<=2/ordered code`, `(cql.serverchoice = "clean") prox/unit=word/distance<=2/ordered (cql.serverchoice = "code")`},
+		{"quoted modifier value", `a prox/unit="word" b`, `(cql.serverchoice = "a") prox/unit=word (cql.serverchoice = "b")`},
+		{"escaped quote", `dc.title = "say \"hi\""`, `dc.title = "say \"hi\""`},
+		{"escapes are kept", `dc.title = c\*de`, `dc.title = "c\*de"`},
+		{"keyword as a quoted term", `"and"`, `cql.serverchoice = "and"`},
+		{"sort keys", `dc.title = code sortBy dc.date/sort.descending dc.title`, `dc.title = "code" sortby dc.date/sort.descending dc.title`},
+		{"sort after a boolean", `a and b sortby dc.title`, `(cql.serverchoice = "a") and (cql.serverchoice = "b") sortby dc.title`},
+		{"unicode", `dc.creator = "Gabriel García Márquez"`, `dc.creator = "Gabriel García Márquez"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := Parse(tt.query)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, query.String())
+		})
+	}
+}
+
+func TestParse_PrefixAssignments(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []Index
+	}{
+		{
+			"a prefix",
+			`> x = "info:srw/cql-context-set/1/dc-v1.1" x.title = code`,
+			[]Index{{Prefix: "x", Name: "title", ContextSet: ContextSetDC}},
+		},
+		{
+			"the default context set",
+			`> "info:srw/cql-context-set/1/dc-v1.1" title = code`,
+			[]Index{{Name: "title", ContextSet: ContextSetDC}},
+		},
+		{
+			"assignments are scoped",
+			`(> x = "urn:a" x.title = a) and x.title = b`,
+			[]Index{{Prefix: "x", Name: "title", ContextSet: "urn:a"}, {Prefix: "x", Name: "title"}},
+		},
+		{
+			"inner assignments win",
+			`> x = "urn:a" (> x = "urn:b" x.title = a) or x.title = b`,
+			[]Index{{Prefix: "x", Name: "title", ContextSet: "urn:b"}, {Prefix: "x", Name: "title", ContextSet: "urn:a"}},
+		},
+		{
+			"unassigned prefixes",
+			`dc.title = a`,
+			[]Index{{Prefix: "dc", Name: "title"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := Parse(tt.query)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, indexes(query.Root))
+		})
+	}
+}
+
+// indexes lists the indexes of the clauses under node, left to right
+func indexes(node Node) []Index {
+	switch n := node.(type) {
+	case *SearchClause:
+		return []Index{n.Index}
+	case *Boolean:
+		return append(indexes(n.Left), indexes(n.Right)...)
+	}
+	return nil
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		offset  int
+		message string
+	}{
+		{"empty", ``, 0, "the query is empty"},
+		{"blank", `   `, 0, "the query is empty"},
+		{"unterminated string", `dc.title = "clean`, 11, "unterminated quoted string"},
+		{"trailing backslash", `clean\`, 5, "backslash at the end of the query"},
+		{"missing term", `dc.title =`, 10, "expected a search term, found the end of the query"},
+		{"missing right operand", `a and`, 5, "expected a search clause, found the end of the query"},
+		{"unclosed parenthesis", `(a and b`, 8, `expected ")", found the end of the query`},
+		{"stray parenthesis", `a)`, 1, `expected a boolean or the end of the query, found ")"`},
+		{"quoted index", `"dc.title" = a`, 0, "an index must not be quoted"},
+		{"two clauses without a boolean", `a b c d`, 6, `expected a boolean or the end of the query, found "d"`},
+		{"relation without index", `= a`, 0, `expected a search clause, found "="`},
+		{"modifier without name", `a prox/ (b)`, 8, `expected a modifier, found "("`},
+		{"modifier without value", `dc.title =/x= a`, 15, `expected a search term, found the end of the query`},
+		{"empty sort", `a sortby`, 8, "expected a sort key, found the end of the query"},
+		{"sort inside parentheses", `(a sortby dc.title)`, 3, `expected ")", found "sortby"`},
+		{"prefix without identifier", `> x = ) a`, 6, `expected a context set identifier, found ")"`},
+		{"deep nesting", strings.Repeat("(", 40) + "a" + strings.Repeat(")", 40), 32, "the query is nested too deeply"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.query)
+
+			var syntaxErr *SyntaxError
+			require.ErrorAs(t, err, &syntaxErr)
+			assert.Equal(t, tt.offset, syntaxErr.Offset)
+			assert.Equal(t, tt.message, syntaxErr.Message)
+		})
+	}
+}
+
+func TestParse_TooManyBooleans(t *testing.T) {
+	_, err := Parse("a" + strings.Repeat(" or a", MaxBooleans+1))
+
+	var unsupported *UnsupportedError
+	require.ErrorAs(t, err, &unsupported)
+	assert.Equal(t, FeatureBooleans, unsupported.Feature)
+}
+
+func TestParse_Tree(t *testing.T) {
+	query, err := Parse(`dc.title any/respectCase "clean code" prox/distance<3 dc.title = martin`)
+	require.NoError(t, err)
+
+	assert.Equal(t, &Boolean{
+		Operator:  Prox,
+		Modifiers: []Modifier{{Name: "distance", Comparison: "<", Value: "3"}},
+		Left: &SearchClause{
+			Index:    Index{Prefix: "dc", Name: "title"},
+			Relation: Relation{Name: "any", Modifiers: []Modifier{{Name: "respectcase"}}},
+			Term:     "clean code",
+		},
+		Right: &SearchClause{Index: Index{Prefix: "dc", Name: "title"}, Relation: Relation{Name: "="}, Term: "martin"},
+	}, query.Root)
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		name     string
+		term     string
+		chars    []Char
+		unescape string
+		words    []string
+	}{
+		{"plain", "ab", []Char{{Rune: 'a'}, {Rune: 'b'}}, "ab", []string{"ab"}},
+		{"masks", "a*?", []Char{{Rune: 'a'}, {Rune: '*'}, {Rune: '?'}}, "a*?", []string{"a*?"}},
+		{"escaped mask", `a\*`, []Char{{Rune: 'a'}, {Rune: '*', Escaped: true}}, "a*", []string{`a\*`}},
+		{"escaped backslash", `\\*`, []Char{{Rune: '\\', Escaped: true}, {Rune: '*'}}, `\*`, []string{`\\*`}},
+		{"escaped quote", `\"`, []Char{{Rune: '"', Escaped: true}}, `"`, []string{`\"`}},
+		{"words", "  clean   code ", nil, "  clean   code ", []string{"clean", "code"}},
+		{"escaped space", `clean\ code`, nil, "clean code", []string{`clean\ code`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.chars != nil {
+				assert.Equal(t, tt.chars, Chars(tt.term))
+			}
+			assert.Equal(t, tt.unescape, Unescape(tt.term))
+			assert.Equal(t, tt.words, Words(tt.term))
+		})
+	}
+}
+
+func TestFindModifier(t *testing.T) {
+	modifiers := []Modifier{{Name: "sort.descending"}, {Name: "distance", Comparison: "<=", Value: "2"}}
+
+	modifier, ok := FindModifier(modifiers, "sort", "descending")
+	assert.True(t, ok)
+	assert.Equal(t, "sort.descending", modifier.Name)
+
+	modifier, ok = FindModifier(modifiers, "prox", "distance")
+	assert.True(t, ok)
+	assert.Equal(t, "2", modifier.Value)
+
+	_, ok = FindModifier(modifiers, "sort", "ascending")
+	assert.False(t, ok)
+}
@@ -0,0 +1,391 @@
+package cql
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxDepth bounds the nesting of parentheses
+	maxDepth = 32
+	// MaxBooleans bounds the booleans of a query, each of which adds to the
+	// query it is translated into
+	MaxBooleans = 64
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	// tokenWord is an unquoted string
+	tokenWord
+	// tokenString is a quoted string, without its quotes
+	tokenString
+	tokenLeft
+	tokenRight
+	tokenSlash
+	// tokenComparison is one of = == < > <= >= <>
+	tokenComparison
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+// lex splits the query into tokens, ending with tokenEOF
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLeft, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRight, ")", i})
+			i++
+		case c == '/':
+			tokens = append(tokens, token{tokenSlash, "/", i})
+			i++
+		case c == '=' || c == '<' || c == '>':
+			n := 1
+			if i+1 < len(input) {
+				switch input[i : i+2] {
+				case "==", "<=", ">=", "<>":
+					n = 2
+				}
+			}
+			tokens = append(tokens, token{tokenComparison, input[i : i+n], i})
+			i += n
+		case c == '"':
+			start := i
+			i++
+			for ; i < len(input) && input[i] != '"'; i++ {
+				if input[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(input) {
+				return nil, &SyntaxError{Offset: start, Message: "unterminated quoted string"}
+			}
+			tokens = append(tokens, token{tokenString, input[start+1 : i], start})
+			i++
+		default:
+			start := i
+			for ; i < len(input) && !strings.ContainsRune(" \t\n\r()/=<>\"", rune(input[i])); i++ {
+				if input[i] == '\\' {
+					i++
+				}
+			}
+			if i > len(input) {
+				return nil, &SyntaxError{Offset: len(input) - 1, Message: "backslash at the end of the query"}
+			}
+			tokens = append(tokens, token{tokenWord, input[start:i], start})
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(input)}), nil
+}
+
+type parser struct {
+	tokens   []token
+	next     int
+	depth    int
+	booleans int
+	// scopes hold the prefix assignments in force, innermost last; the
+	// empty prefix names the default context set
+	scopes []map[string]string
+}
+
+// Parse parses a CQL query
+func Parse(input string) (*Query, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, &SyntaxError{Offset: 0, Message: "the query is empty"}
+	}
+	p := &parser{tokens: tokens}
+	query := &Query{}
+	if query.Root, err = p.query(query); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.unexpected(t, "a boolean or the end of the query")
+	}
+	return query, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+func (p *parser) unexpected(t token, expected string) error {
+	found := `"` + t.text + `"`
+	if t.kind == tokenEOF {
+		found = "the end of the query"
+	}
+	return &SyntaxError{Offset: t.offset, Message: "expected " + expected + ", found " + found}
+}
+
+// isKeyword tells whether the token is the unquoted keyword
+func isKeyword(t token, keywords ...string) bool {
+	if t.kind != tokenWord {
+		return false
+	}
+	for _, keyword := range keywords {
+		if strings.EqualFold(t.text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// query parses prefix assignments and the clauses they scope, and the sort
+// keys that end the whole query when top is set
+func (p *parser) query(top *Query) (Node, error) {
+	if t := p.peek(); t.kind == tokenComparison && t.text == ">" {
+		p.advance()
+		prefix, uri, err := p.prefixAssignment()
+		if err != nil {
+			return nil, err
+		}
+		p.scopes = append(p.scopes, map[string]string{prefix: uri})
+		defer func() { p.scopes = p.scopes[:len(p.scopes)-1] }()
+		return p.query(top)
+	}
+
+	node, err := p.scopedClause()
+	if err != nil {
+		return nil, err
+	}
+	if top != nil && isKeyword(p.peek(), "sortby") {
+		p.advance()
+		if top.SortKeys, err = p.sortKeys(); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// prefixAssignment parses `prefix = "uri"` or `"uri"` after the >
+func (p *parser) prefixAssignment() (string, string, error) {
+	first := p.advance()
+	if first.kind != tokenWord && first.kind != tokenString {
+		return "", "", p.unexpected(first, "a context set identifier")
+	}
+	if t := p.peek(); t.kind == tokenComparison && t.text == "=" {
+		if first.kind != tokenWord {
+			return "", "", p.unexpected(first, "a prefix")
+		}
+		p.advance()
+		uri := p.advance()
+		if uri.kind != tokenWord && uri.kind != tokenString {
+			return "", "", p.unexpected(uri, "a context set identifier")
+		}
+		return strings.ToLower(first.text), uri.text, nil
+	}
+	return "", first.text, nil
+}
+
+func (p *parser) scopedClause() (Node, error) {
+	left, err := p.searchClause()
+	if err != nil {
+		return nil, err
+	}
+	for isKeyword(p.peek(), And, Or, Not, Prox) {
+		operator := p.advance()
+		if p.booleans++; p.booleans > MaxBooleans {
+			return nil, Unsupported(FeatureBooleans, "more than "+strconv.Itoa(MaxBooleans))
+		}
+		modifiers, err := p.modifiers()
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.searchClause()
+		if err != nil {
+			return nil, err
+		}
+		left = &Boolean{Operator: strings.ToLower(operator.text), Modifiers: modifiers, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) searchClause() (Node, error) {
+	t := p.advance()
+	switch t.kind {
+	case tokenLeft:
+		if p.depth++; p.depth > maxDepth {
+			return nil, &SyntaxError{Offset: t.offset, Message: "the query is nested too deeply"}
+		}
+		node, err := p.query(nil)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenRight {
+			return nil, p.unexpected(closing, `")"`)
+		}
+		p.depth--
+		return node, nil
+	case tokenWord, tokenString:
+	default:
+		return nil, p.unexpected(t, "a search clause")
+	}
+
+	// A word or a comparison after the first string makes it an index
+	next := p.peek()
+	isRelation := next.kind == tokenComparison ||
+		(next.kind == tokenWord && !isKeyword(next, And, Or, Not, Prox, "sortby"))
+	if !isRelation {
+		return &SearchClause{Index: p.index("cql.serverchoice"), Relation: Relation{Name: "="}, Term: t.text}, nil
+	}
+	if t.kind != tokenWord {
+		return nil, &SyntaxError{Offset: t.offset, Message: "an index must not be quoted"}
+	}
+
+	relation := Relation{Name: strings.TrimPrefix(strings.ToLower(p.advance().text), "cql.")}
+	var err error
+	if relation.Modifiers, err = p.modifiers(); err != nil {
+		return nil, err
+	}
+	term := p.advance()
+	if term.kind != tokenWord && term.kind != tokenString {
+		return nil, p.unexpected(term, "a search term")
+	}
+	return &SearchClause{Index: p.index(t.text), Relation: relation, Term: term.text}, nil
+}
+
+// index splits a prefixed name and resolves its prefix against the prefix
+// assignments in force
+func (p *parser) index(name string) Index {
+	name = strings.ToLower(name)
+	var index Index
+	if prefix, rest, ok := strings.Cut(name, "."); ok {
+		index.Prefix, index.Name = prefix, rest
+	} else {
+		index.Name = name
+	}
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if uri, ok := p.scopes[i][index.Prefix]; ok {
+			index.ContextSet = uri
+			break
+		}
+	}
+	return index
+}
+
+// modifiers parses a possibly empty list of modifiers
+func (p *parser) modifiers() ([]Modifier, error) {
+	var modifiers []Modifier
+	for p.peek().kind == tokenSlash {
+		p.advance()
+		name := p.advance()
+		if name.kind != tokenWord {
+			return nil, p.unexpected(name, "a modifier")
+		}
+		modifier := Modifier{Name: strings.ToLower(name.text)}
+		if t := p.peek(); t.kind == tokenComparison {
+			p.advance()
+			value := p.advance()
+			if value.kind != tokenWord && value.kind != tokenString {
+				return nil, p.unexpected(value, "a modifier value")
+			}
+			modifier.Comparison, modifier.Value = t.text, value.text
+		}
+		modifiers = append(modifiers, modifier)
+	}
+	return modifiers, nil
+}
+
+func (p *parser) sortKeys() ([]SortKey, error) {
+	var keys []SortKey
+	for p.peek().kind == tokenWord {
+		key := SortKey{Index: p.index(p.advance().text)}
+		var err error
+		if key.Modifiers, err = p.modifiers(); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, p.unexpected(p.peek(), "a sort key")
+	}
+	return keys, nil
+}
+
+// Char is a character of a search term
+type Char struct {
+	Rune rune
+	// Escaped characters are literal: an escaped * is an asterisk rather
+	// than a mask
+	Escaped bool
+}
+
+// Mask and anchor characters, special in terms unless escaped
+const (
+	MaskAny  = '*'
+	MaskOne  = '?'
+	Anchor   = '^'
+	escaping = '\\'
+)
+
+// Chars splits a term into its characters, resolving backslash escapes
+func Chars(term string) []Char {
+	var chars []Char
+	escaped := false
+	for _, r := range term {
+		if !escaped && r == escaping {
+			escaped = true
+			continue
+		}
+		chars = append(chars, Char{Rune: r, Escaped: escaped})
+		escaped = false
+	}
+	return chars
+}
+
+// Unescape returns the term with its backslash escapes resolved, treating
+// masks and anchors as the characters they are
+func Unescape(term string) string {
+	var b strings.Builder
+	for _, c := range Chars(term) {
+		b.WriteRune(c.Rune)
+	}
+	return b.String()
+}
+
+// Words splits a term into its whitespace-separated words, keeping their
+// escapes
+func Words(term string) []string {
+	var words []string
+	var word strings.Builder
+	escaped := false
+	for _, r := range term {
+		if !escaped && unicode.IsSpace(r) {
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			continue
+		}
+		word.WriteRune(r)
+		escaped = !escaped && r == escaping
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
@@ -0,0 +1,237 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/middleware"
+	"byfood-library/internal/tenancy"
+	"byfood-library/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const requestIDKey = "x-request-id"
+
+// Interceptor wraps one gRPC call, unary or streaming. method is the full
+// method name, e.g. /byfood.library.book.v1.BookService/GetBook.
+type Interceptor func(ctx context.Context, method string, next func(context.Context) error) error
+
+// Unary adapts interceptors to a unary server interceptor; the first one is
+// the outermost
+func Unary(interceptors ...Interceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var resp interface{}
+		err := chain(interceptors, info.FullMethod)(ctx, func(ctx context.Context) error {
+			var err error
+			resp, err = handler(ctx, req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+// Stream adapts interceptors to a stream server interceptor; the first one
+// is the outermost
+func Stream(interceptors ...Interceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return chain(interceptors, info.FullMethod)(ss.Context(), func(ctx context.Context) error {
+			return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		})
+	}
+}
+
+func chain(interceptors []Interceptor, method string) func(context.Context, func(context.Context) error) error {
+	return func(ctx context.Context, handler func(context.Context) error) error {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context) error {
+				return interceptor(ctx, method, inner)
+			}
+		}
+		return next(ctx)
+	}
+}
+
+// contextStream hands the context built by the interceptors to stream handlers
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// Logger stores a logger enriched with the request ID and method in the
+// call context and writes one access log line per call. The request ID is
+// taken from the x-request-id metadata or generated, and echoed back in the
+// response header.
+func Logger(base *zap.Logger) Interceptor {
+	return func(ctx context.Context, method string, next func(context.Context) error) error {
+		start := time.Now()
+
+		requestID := first(ctx, requestIDKey)
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+
+		logger := base.With(zap.String("request_id", requestID), zap.String("method", method))
+		ctx = logging.WithLogger(ctx, logger)
+		err := next(ctx)
+
+		code := status.Code(err)
+		fields := []zap.Field{
+			zap.String("code", code.String()),
+			zap.Duration("latency", time.Since(start)),
+		}
+		if err != nil {
+			fields = append(fields, zap.Error(err))
+		}
+		switch code {
+		case codes.OK:
+			logger.Info("gRPC request", fields...)
+		case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+			logger.Error("gRPC request", fields...)
+		default:
+			logger.Warn("gRPC request", fields...)
+		}
+		return err
+	}
+}
+
+// Recover turns a panic in a handler into an INTERNAL error
+func Recover(logger *zap.Logger) Interceptor {
+	return func(ctx context.Context, method string, next func(context.Context) error) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logging.FromContext(ctx, logger).Error("Panic recovered", zap.Any("panic", r))
+				err = status.Error(codes.Internal, "internal server error occurred")
+			}
+		}()
+		return next(ctx)
+	}
+}
+
+// Metrics records the count and latency of calls by method and code
+func Metrics() Interceptor {
+	return func(ctx context.Context, method string, next func(context.Context) error) error {
+		start := time.Now()
+		err := next(ctx)
+		middleware.RecordGRPCRequest(method, status.Code(err).String(), time.Since(start))
+		return err
+	}
+}
+
+// Tracing starts a server span per call, continuing the caller's trace when
+// the metadata carries a W3C traceparent
+func Tracing() Interceptor {
+	return func(ctx context.Context, method string, next func(context.Context) error) error {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+		ctx, span := tracing.Start(ctx, strings.TrimPrefix(method, "/"),
+			trace.WithSpanKind(trace.SpanKindServer),
+		)
+		defer span.End()
+
+		err := next(ctx)
+		if code := status.Code(err); code != codes.OK {
+			span.SetStatus(otelcodes.Error, code.String())
+			span.RecordError(err)
+		}
+		return err
+	}
+}
+
+// Tenant resolves the caller's tenant from the x-api-key and x-tenant-id
+// metadata or the :authority, exactly as the HTTP tenant middleware does
+// from headers and Host. Methods under skipPrefixes, such as health checks
+// and reflection, run without a tenant.
+func Tenant(resolver middleware.TenantResolver, apiKeyHeader string, logger *zap.Logger, skipPrefixes ...string) Interceptor {
+	return func(ctx context.Context, method string, next func(context.Context) error) error {
+		for _, prefix := range skipPrefixes {
+			if strings.HasPrefix(method, prefix) {
+				return next(ctx)
+			}
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		req := &http.Request{Header: http.Header{}, Host: first(ctx, ":authority")}
+		for key, values := range md {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+
+		tenant, err := resolver.Resolve(ctx, req)
+		if err != nil {
+			logging.FromContext(ctx, logger).Warn("Failed to resolve tenant",
+				zap.String("host", req.Host),
+				zap.Error(err),
+			)
+			switch {
+			case errors.Is(err, entities.ErrTenantNotFound):
+				return status.Error(codes.Unauthenticated, "unknown tenant")
+			case errors.Is(err, entities.ErrTenantSuspended):
+				return status.Error(codes.PermissionDenied, "tenant is suspended")
+			case errors.Is(err, entities.ErrTenantRequired):
+				return status.Error(codes.Unauthenticated, "tenant could not be determined")
+			default:
+				return status.Error(codes.Internal, "failed to resolve tenant")
+			}
+		}
+
+		principal := "anonymous"
+		if apiKeyHeader != "" && req.Header.Get(apiKeyHeader) != "" {
+			principal = "tenant:" + tenant.Slug
+		}
+		ctx = tenancy.WithTenant(ctx, tenant)
+		ctx = logging.With(ctx, logger, zap.String("tenant", tenant.Slug), zap.String("principal", principal))
+		return next(ctx)
+	}
+}
+
+func first(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// metadataCarrier lets the OpenTelemetry propagator read incoming metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
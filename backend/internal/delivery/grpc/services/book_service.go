@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/middleware"
+	"byfood-library/internal/stream"
+	"byfood-library/internal/tenancy"
+	"byfood-library/internal/usecases"
+	bookv1 "byfood-library/proto/book/v1"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type bookService struct {
+	bookv1.UnimplementedBookServiceServer
+	bookUseCase    usecases.BookUseCase
+	catalogUseCase usecases.CatalogUseCase
+	hub            *stream.Hub
+	logger         *zap.Logger
+}
+
+// NewBookService serves BookService from bookUseCase, listing books through
+// catalogUseCase. hub feeds WatchBooks and is nil when the live book stream
+// is disabled.
+func NewBookService(bookUseCase usecases.BookUseCase, catalogUseCase usecases.CatalogUseCase, hub *stream.Hub, logger *zap.Logger) bookv1.BookServiceServer {
+	return &bookService{
+		bookUseCase:    bookUseCase,
+		catalogUseCase: catalogUseCase,
+		hub:            hub,
+		logger:         logger,
+	}
+}
+
+func parseID(id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, entities.ErrInvalidUUID.Error())
+	}
+	return parsed, nil
+}
+
+func toProto(book *entities.Book) *bookv1.Book {
+	return &bookv1.Book{
+		Id:         book.ID.String(),
+		Title:      book.Title,
+		Author:     book.Author,
+		Year:       int32(book.Year),
+		Isbn:       book.ISBN,
+		Metadata:   metadataToProto(book.BookMetadata),
+		CoverEtag:  book.CoverETag,
+		CreateTime: timestamppb.New(book.CreatedAt),
+		UpdateTime: timestamppb.New(book.UpdatedAt),
+	}
+}
+
+func metadataToProto(metadata entities.BookMetadata) *bookv1.BookMetadata {
+	return &bookv1.BookMetadata{
+		Publisher:   metadata.Publisher,
+		PageCount:   int32(metadata.PageCount),
+		Subjects:    metadata.Subjects,
+		Description: metadata.Description,
+		CoverUrl:    metadata.CoverURL,
+	}
+}
+
+func metadataFromProto(metadata *bookv1.BookMetadata) entities.BookMetadata {
+	return entities.BookMetadata{
+		Publisher:   metadata.GetPublisher(),
+		PageCount:   int(metadata.GetPageCount()),
+		Subjects:    metadata.GetSubjects(),
+		Description: metadata.GetDescription(),
+		CoverURL:    metadata.GetCoverUrl(),
+	}
+}
+
+func (s *bookService) GetBook(ctx context.Context, req *bookv1.GetBookRequest) (*bookv1.Book, error) {
+	id, err := parseID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	book, err := s.bookUseCase.GetBookByID(ctx, id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(book), nil
+}
+
+// encodeCursor makes a page token of the position of a book
+func encodeCursor(cursor *entities.BookCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(cursor.CreatedAt.UnixNano(), 10) + "/" + cursor.ID.String()))
+}
+
+func decodeCursor(token string) (*entities.BookCursor, error) {
+	invalid := status.Error(codes.InvalidArgument, "invalid page_token")
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, invalid
+	}
+	nanos, id, ok := strings.Cut(string(raw), "/")
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if !ok || err != nil {
+		return nil, invalid
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, invalid
+	}
+	return &entities.BookCursor{CreatedAt: time.Unix(0, n), ID: parsed}, nil
+}
+
+// ListBooks pages over the catalogue, newest first, reading one page from
+// the database at a time. Tokens hold a position rather than an offset, so
+// books created between pages do not shift later pages.
+func (s *bookService) ListBooks(ctx context.Context, req *bookv1.ListBooksRequest) (*bookv1.ListBooksResponse, error) {
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	// One book past the page tells whether another page follows
+	query := entities.BookQuery{Limit: pageSize + 1}
+	if req.GetPageToken() != "" {
+		cursor, err := decodeCursor(req.GetPageToken())
+		if err != nil {
+			return nil, err
+		}
+		query.After = cursor
+	}
+
+	page, err := s.catalogUseCase.Search(ctx, query)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	books := page.Books
+	more := len(books) > pageSize
+	if more {
+		books = books[:pageSize]
+	}
+
+	response := &bookv1.ListBooksResponse{TotalSize: int32(page.Total)}
+	for _, book := range books {
+		response.Books = append(response.Books, toProto(book))
+	}
+	if more {
+		response.NextPageToken = encodeCursor(books[len(books)-1].Cursor())
+	}
+	return response, nil
+}
+
+func (s *bookService) CreateBook(ctx context.Context, req *bookv1.CreateBookRequest) (*bookv1.Book, error) {
+	book, err := s.bookUseCase.CreateBook(ctx, &entities.CreateBookDTO{
+		Title:        req.GetTitle(),
+		Author:       req.GetAuthor(),
+		Year:         int(req.GetYear()),
+		ISBN:         req.GetIsbn(),
+		Force:        req.GetForce(),
+		BookMetadata: metadataFromProto(req.GetMetadata()),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(book), nil
+}
+
+func (s *bookService) UpdateBook(ctx context.Context, req *bookv1.UpdateBookRequest) (*bookv1.Book, error) {
+	id, err := parseID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	dto := &entities.UpdateBookDTO{
+		Title:  req.GetTitle(),
+		Author: req.GetAuthor(),
+		Year:   int(req.GetYear()),
+	}
+	// The metadata message replaces the stored metadata as a whole, and
+	// leaving it out keeps it
+	if req.Metadata != nil {
+		metadata := metadataFromProto(req.GetMetadata())
+		subjects := []string(metadata.Subjects)
+		dto.BookMetadataUpdate = entities.BookMetadataUpdate{
+			Publisher:   &metadata.Publisher,
+			PageCount:   &metadata.PageCount,
+			Subjects:    &subjects,
+			Description: &metadata.Description,
+			CoverURL:    &metadata.CoverURL,
+		}
+	}
+	// proto3 strings cannot be told apart from unset ones, so an empty
+	// ISBN keeps the stored one
+	if isbn := req.GetIsbn(); isbn != "" {
+		dto.ISBN = &isbn
+	}
+	book, err := s.bookUseCase.UpdateBook(ctx, id, dto)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(book), nil
+}
+
+func (s *bookService) DeleteBook(ctx context.Context, req *bookv1.DeleteBookRequest) (*bookv1.DeleteBookResponse, error) {
+	id, err := parseID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.bookUseCase.DeleteBook(ctx, id); err != nil {
+		return nil, statusError(err)
+	}
+	return &bookv1.DeleteBookResponse{}, nil
+}
+
+var eventTypes = map[string]bookv1.BookEvent_Type{
+	events.BookCreated: bookv1.BookEvent_TYPE_CREATED,
+	events.BookUpdated: bookv1.BookEvent_TYPE_UPDATED,
+	events.BookDeleted: bookv1.BookEvent_TYPE_DELETED,
+}
+
+func toEvent(msg *stream.Message) *bookv1.BookEvent {
+	evt := msg.Event
+	out := &bookv1.BookEvent{
+		Id:        msg.ID,
+		Type:      eventTypes[evt.Type],
+		BookId:    evt.AggregateID.String(),
+		OccurTime: timestamppb.New(evt.OccurredAt),
+	}
+	if evt.Type != events.BookDeleted && len(evt.Data) > 0 {
+		var book entities.Book
+		if json.Unmarshal(evt.Data, &book) == nil {
+			out.Book = toProto(&book)
+		}
+	}
+	return out
+}
+
+// WatchBooks follows the same hub as the SSE stream. The stream ends with
+// UNAVAILABLE when the client falls behind or the server shuts down; clients
+// should reconnect with the last event ID they received.
+func (s *bookService) WatchBooks(req *bookv1.WatchBooksRequest, srv grpc.ServerStreamingServer[bookv1.BookEvent]) error {
+	if s.hub == nil {
+		return status.Error(codes.Unimplemented, "the live book stream is disabled")
+	}
+	ctx := srv.Context()
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return statusError(err)
+	}
+
+	filter := stream.AllOf(stream.AuthorFilter(req.GetAuthors()), stream.GenreFilter(req.GetGenres()))
+	sub, replay, reset := s.hub.Subscribe(tenantID, req.GetLastEventId(), filter)
+	defer s.hub.Unsubscribe(sub)
+	middleware.AddStreamConnections(1)
+	defer middleware.AddStreamConnections(-1)
+
+	if reset {
+		if err := srv.Send(&bookv1.BookEvent{Type: bookv1.BookEvent_TYPE_RESET}); err != nil {
+			return err
+		}
+	}
+	for _, msg := range replay {
+		if err := srv.Send(toEvent(msg)); err != nil {
+			return err
+		}
+	}
+	logging.FromContext(ctx, s.logger).Debug("Book watch opened", zap.Int("replayed", len(replay)), zap.Bool("reset", reset))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.Unavailable, "stream interrupted; resume with last_event_id")
+			}
+			if err := srv.Send(toEvent(msg)); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"byfood-library/internal/domain/entities"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// kindCodes maps domain error kinds to gRPC codes, as the problem package
+// maps them to HTTP statuses
+var kindCodes = map[entities.ErrorKind]codes.Code{
+	entities.KindNotFound:    codes.NotFound,
+	entities.KindInvalid:     codes.InvalidArgument,
+	entities.KindConflict:    codes.FailedPrecondition,
+	entities.KindForbidden:   codes.PermissionDenied,
+	entities.KindTimeout:     codes.DeadlineExceeded,
+	entities.KindUnavailable: codes.Unavailable,
+}
+
+// statusError converts a use case error to a gRPC status error; errors that
+// already carry a status are returned unchanged. Internal errors are not
+// described to the client.
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, entities.ErrTenantSlugTaken), errors.Is(err, entities.ErrPossibleDuplicate):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, entities.ErrIdempotencyKeyInFlight):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	if code, ok := kindCodes[entities.KindOf(err)]; ok {
+		return status.Error(code, err.Error())
+	}
+	return status.Error(codes.Internal, "internal server error occurred")
+}
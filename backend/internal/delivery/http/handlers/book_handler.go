@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
 	"byfood-library/internal/domain/entities"
-	"byfood-library/internal/middleware"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/problem"
 	"byfood-library/internal/usecases"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -23,44 +25,43 @@ func NewBookHandler(bookUseCase usecases.BookUseCase, logger *zap.Logger) BookHa
 	}
 }
 
+// log returns the request-scoped logger
+func (h *bookHandler) log(c echo.Context) *zap.Logger {
+	return logging.FromContext(c.Request().Context(), h.logger)
+}
+
 // @Summary Get all books
 // @Description Get all books from the library with UUID and timestamps
 // @Tags books
 // @Accept json
-// @Produce json
+// @Produce json,application/ld+json,text/csv,application/xml,text/turtle
 // @Success 200 {array} entities.Book
-// @Failure 500 {object} ErrorResponse
+// @Failure 500 {object} problem.Problem
 // @Router /books [get]
 func (h *bookHandler) GetBooks(c echo.Context) error {
 	ctx := c.Request().Context()
-	requestID := middleware.GetRequestID(c)
-	ctx = middleware.WithRequestID(ctx, requestID)
-	
-	h.logger.Info("Getting all books", zap.String("request_id", requestID))
-	
+
+	h.log(c).Info("Getting all books")
+
 	books, err := h.bookUseCase.GetAllBooks(ctx)
 	if err != nil {
-		h.logger.Error("Failed to get all books", zap.String("request_id", requestID), zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Failed to retrieve books",
-			Message: err.Error(),
-		})
+		return respondError(c, h.logger, err, "Failed to get all books")
 	}
 
-	h.logger.Info("Successfully retrieved all books", zap.String("request_id", requestID), zap.Int("count", len(books)))
-	return c.JSON(http.StatusOK, books)
+	h.log(c).Info("Successfully retrieved all books", zap.Int("count", len(books)))
+	return respond(c, http.StatusOK, books)
 }
 
 // @Summary Get book by ID
 // @Description Get a single book by its UUID
 // @Tags books
 // @Accept json
-// @Produce json
+// @Produce json,application/ld+json,text/csv,application/xml,text/turtle
 // @Param id path string true "Book UUID"
 // @Success 200 {object} entities.Book
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /books/{id} [get]
 func (h *bookHandler) GetBook(c echo.Context) error {
 	ctx := c.Request().Context()
@@ -68,70 +69,51 @@ func (h *bookHandler) GetBook(c echo.Context) error {
 
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		h.logger.Error("Invalid UUID format", zap.String("id", idParam), zap.Error(err))
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid UUID format",
-			Message: err.Error(),
-		})
+		h.log(c).Warn("Invalid UUID format", zap.String("id", idParam), zap.Error(err))
+		return invalidUUID(c)
 	}
 
 	book, err := h.bookUseCase.GetBookByID(ctx, id)
 	if err != nil {
-		if err == entities.ErrBookNotFound {
-			return c.JSON(http.StatusNotFound, ErrorResponse{
-				Error:   "Book not found",
-				Message: err.Error(),
-			})
-		}
-		h.logger.Error("Failed to get book", zap.String("id", id.String()), zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Failed to retrieve book",
-			Message: err.Error(),
-		})
+		return respondError(c, h.logger, err, "Failed to get book", zap.String("id", id.String()))
 	}
 
-	return c.JSON(http.StatusOK, book)
+	return respond(c, http.StatusOK, book)
 }
 
 // @Summary Create a new book
-// @Description Create a new book with title, author, and year
+// @Description Create a new book with title, author, year and optional ISBN. A book resembling existing ones is rejected with 409 and the possible duplicates unless force is true.
 // @Tags books
 // @Accept json
 // @Produce json
 // @Param book body entities.CreateBookDTO true "Book to create"
+// @Param force query bool false "Create the book even if it looks like a duplicate"
 // @Success 201 {object} entities.Book
-// @Failure 400 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /books [post]
 func (h *bookHandler) CreateBook(c echo.Context) error {
 	ctx := c.Request().Context()
 	var dto entities.CreateBookDTO
 
 	if err := c.Bind(&dto); err != nil {
-		h.logger.Error("Failed to bind request body", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+		return invalidBody(c, h.logger, err)
+	}
+	if raw := c.QueryParam("force"); raw != "" {
+		force, err := strconv.ParseBool(raw)
+		if err != nil {
+			return problem.Write(c, problem.BadRequest("force must be true or false"))
+		}
+		dto.Force = force
 	}
 
 	book, err := h.bookUseCase.CreateBook(ctx, &dto)
 	if err != nil {
-		// Check for validation errors
-		if err == entities.ErrInvalidTitle || err == entities.ErrInvalidAuthor || err == entities.ErrInvalidYear {
-			return c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error:   "Validation failed",
-				Message: err.Error(),
-			})
-		}
-		h.logger.Error("Failed to create book", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Failed to create book",
-			Message: err.Error(),
-		})
+		return respondError(c, h.logger, err, "Failed to create book")
 	}
 
-	return c.JSON(http.StatusCreated, book)
+	return respond(c, http.StatusCreated, book)
 }
 
 // @Summary Update a book
@@ -142,9 +124,9 @@ func (h *bookHandler) CreateBook(c echo.Context) error {
 // @Param id path string true "Book UUID"
 // @Param book body entities.UpdateBookDTO true "Book data to update"
 // @Success 200 {object} entities.Book
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /books/{id} [put]
 func (h *bookHandler) UpdateBook(c echo.Context) error {
 	ctx := c.Request().Context()
@@ -152,45 +134,21 @@ func (h *bookHandler) UpdateBook(c echo.Context) error {
 
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		h.logger.Error("Invalid UUID format", zap.String("id", idParam), zap.Error(err))
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid UUID format",
-			Message: err.Error(),
-		})
+		h.log(c).Warn("Invalid UUID format", zap.String("id", idParam), zap.Error(err))
+		return invalidUUID(c)
 	}
 
 	var dto entities.UpdateBookDTO
 	if err := c.Bind(&dto); err != nil {
-		h.logger.Error("Failed to bind request body", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid request body",
-			Message: err.Error(),
-		})
+		return invalidBody(c, h.logger, err)
 	}
 
 	book, err := h.bookUseCase.UpdateBook(ctx, id, &dto)
 	if err != nil {
-		if err == entities.ErrBookNotFound {
-			return c.JSON(http.StatusNotFound, ErrorResponse{
-				Error:   "Book not found",
-				Message: err.Error(),
-			})
-		}
-		// Check for validation errors
-		if err == entities.ErrInvalidTitle || err == entities.ErrInvalidAuthor || err == entities.ErrInvalidYear {
-			return c.JSON(http.StatusBadRequest, ErrorResponse{
-				Error:   "Validation failed",
-				Message: err.Error(),
-			})
-		}
-		h.logger.Error("Failed to update book", zap.String("id", id.String()), zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Failed to update book",
-			Message: err.Error(),
-		})
+		return respondError(c, h.logger, err, "Failed to update book", zap.String("id", id.String()))
 	}
 
-	return c.JSON(http.StatusOK, book)
+	return respond(c, http.StatusOK, book)
 }
 
 // @Summary Delete a book
@@ -200,9 +158,9 @@ func (h *bookHandler) UpdateBook(c echo.Context) error {
 // @Produce json
 // @Param id path string true "Book UUID"
 // @Success 200 {object} SuccessResponse
-// @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /books/{id} [delete]
 func (h *bookHandler) DeleteBook(c echo.Context) error {
 	ctx := c.Request().Context()
@@ -210,29 +168,55 @@ func (h *bookHandler) DeleteBook(c echo.Context) error {
 
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		h.logger.Error("Invalid UUID format", zap.String("id", idParam), zap.Error(err))
-		return c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "Invalid UUID format",
-			Message: err.Error(),
-		})
+		h.log(c).Warn("Invalid UUID format", zap.String("id", idParam), zap.Error(err))
+		return invalidUUID(c)
 	}
 
 	err = h.bookUseCase.DeleteBook(ctx, id)
 	if err != nil {
-		if err == entities.ErrBookNotFound {
-			return c.JSON(http.StatusNotFound, ErrorResponse{
-				Error:   "Book not found",
-				Message: err.Error(),
-			})
-		}
-		h.logger.Error("Failed to delete book", zap.String("id", id.String()), zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "Failed to delete book",
-			Message: err.Error(),
-		})
+		return respondError(c, h.logger, err, "Failed to delete book", zap.String("id", id.String()))
 	}
 
-	return c.JSON(http.StatusOK, SuccessResponse{
+	return respond(c, http.StatusOK, SuccessResponse{
 		Message: "Book deleted successfully",
 	})
-}
\ No newline at end of file
+}
+
+// @Summary Report duplicate books
+// @Description Groups of books that appear to describe the same work: the same ISBN, or similar normalized titles and authors
+// @Tags books
+// @Produce json
+// @Success 200 {array} entities.DuplicateGroup
+// @Failure 500 {object} problem.Problem
+// @Router /books/duplicates [get]
+func (h *bookHandler) GetDuplicates(c echo.Context) error {
+	groups, err := h.bookUseCase.FindDuplicates(c.Request().Context())
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to find duplicate books")
+	}
+	return respond(c, http.StatusOK, groups)
+}
+
+// @Summary Merge duplicate books
+// @Description Delete the duplicates in favour of the survivor, which keeps its ID and takes the ISBN and metadata it lacks from the duplicates, the first listed first. Each duplicate's book.deleted event names the survivor in merged_into.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param merge body entities.MergeBooksDTO true "Survivor and duplicates"
+// @Success 200 {object} entities.Book
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /books/merge [post]
+func (h *bookHandler) MergeBooks(c echo.Context) error {
+	var dto entities.MergeBooksDTO
+	if err := c.Bind(&dto); err != nil {
+		return invalidBody(c, h.logger, err)
+	}
+
+	book, err := h.bookUseCase.MergeBooks(c.Request().Context(), &dto)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to merge books", zap.String("id", dto.SurvivorID.String()))
+	}
+	return respond(c, http.StatusOK, book)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/tenancy"
+	"github.com/labstack/echo/v4"
+)
+
+// JSONSchema is the subset of JSON Schema (draft 2020-12) needed to describe
+// book input. Patterns are RE2, which agrees with ECMAScript for the common
+// syntax; forbidden words are listed under the x-forbiddenWords extension
+// and match whole words, ignoring case.
+type JSONSchema struct {
+	Schema         string                 `json:"$schema,omitempty"`
+	Title          string                 `json:"title,omitempty"`
+	Type           string                 `json:"type"`
+	Required       []string               `json:"required,omitempty"`
+	Properties     map[string]*JSONSchema `json:"properties,omitempty"`
+	MinLength      *int                   `json:"minLength,omitempty"`
+	MaxLength      *int                   `json:"maxLength,omitempty"`
+	Pattern        string                 `json:"pattern,omitempty"`
+	Minimum        *int                   `json:"minimum,omitempty"`
+	Maximum        *int                   `json:"maximum,omitempty"`
+	ForbiddenWords []string               `json:"x-forbiddenWords,omitempty"`
+}
+
+// @Summary Get the book input schema
+// @Description JSON Schema of the book create and update bodies with the validation rules in effect for the tenant, so that clients can validate before submitting
+// @Tags books
+// @Produce json
+// @Success 200 {object} JSONSchema
+// @Router /books/schema [get]
+func (h *bookHandler) GetBookSchema(c echo.Context) error {
+	rules := tenancy.BookValidationRules(c.Request().Context())
+	return respond(c, http.StatusOK, bookSchema(rules, time.Now()))
+}
+
+func bookSchema(rules entities.BookValidationRules, now time.Time) *JSONSchema {
+	schema := &JSONSchema{
+		Schema:   "https://json-schema.org/draft/2020-12/schema",
+		Title:    "Book",
+		Type:     "object",
+		Required: []string{"year"},
+		Properties: map[string]*JSONSchema{
+			"title":  textSchema(rules.Title),
+			"author": textSchema(rules.Author),
+			"year":   {Type: "integer"},
+		},
+	}
+	if rules.Title.IsRequired() {
+		schema.Required = append(schema.Required, "title")
+	}
+	if rules.Author.IsRequired() {
+		schema.Required = append(schema.Required, "author")
+	}
+
+	min, max := rules.YearBounds(now)
+	if min != math.MinInt {
+		schema.Properties["year"].Minimum = &min
+	}
+	if max != math.MaxInt {
+		schema.Properties["year"].Maximum = &max
+	}
+	return schema
+}
+
+func textSchema(rule entities.TextRule) *JSONSchema {
+	max := rule.EffectiveMaxLength()
+	schema := &JSONSchema{
+		Type:           "string",
+		MaxLength:      &max,
+		Pattern:        rule.Pattern,
+		ForbiddenWords: rule.ForbiddenWords,
+	}
+	min := rule.MinLength
+	if rule.IsRequired() && min < 1 {
+		min = 1
+	}
+	if min > 0 {
+		schema.MinLength = &min
+	}
+	return schema
+}
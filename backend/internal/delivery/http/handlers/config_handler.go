@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"byfood-library/internal/config"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ConfigProvider exposes the active configuration and its revision
+type ConfigProvider interface {
+	Current() *config.Config
+	Revision() config.Revision
+}
+
+type configHandler struct {
+	provider ConfigProvider
+	logger   *zap.Logger
+}
+
+func NewConfigHandler(provider ConfigProvider, logger *zap.Logger) ConfigHandlerInterface {
+	return &configHandler{
+		provider: provider,
+		logger:   logger,
+	}
+}
+
+// @Summary Show active configuration
+// @Description Revision of the active configuration; format=yaml returns the configuration itself with secrets masked
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param format query string false "yaml to return the redacted configuration"
+// @Success 200 {object} config.Revision
+// @Failure 401 {object} problem.Problem
+// @Router /admin/config [get]
+func (h *configHandler) GetConfig(c echo.Context) error {
+	if c.QueryParam("format") == "yaml" {
+		c.Response().Header().Set(echo.HeaderContentType, "application/yaml")
+		c.Response().Header().Set("X-Config-Revision", strconv.FormatInt(h.provider.Revision().Number, 10))
+		c.Response().WriteHeader(http.StatusOK)
+		return config.Print(c.Response(), h.provider.Current(), true)
+	}
+	return respond(c, http.StatusOK, h.provider.Revision())
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"byfood-library/internal/covers"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/problem"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type coverHandler struct {
+	coverUseCase usecases.CoverUseCase
+	maxSize      int64
+	maxAge       time.Duration
+	logger       *zap.Logger
+}
+
+// NewCoverHandler accepts uploads of up to maxSize bytes and lets clients
+// cache covers for maxAge before revalidating them by ETag
+func NewCoverHandler(coverUseCase usecases.CoverUseCase, maxSize int64, maxAge time.Duration, logger *zap.Logger) CoverHandlerInterface {
+	if maxSize <= 0 {
+		maxSize = covers.DefaultMaxSize
+	}
+	return &coverHandler{
+		coverUseCase: coverUseCase,
+		maxSize:      maxSize,
+		maxAge:       maxAge,
+		logger:       logger,
+	}
+}
+
+// log returns the request-scoped logger
+func (h *coverHandler) log(c echo.Context) *zap.Logger {
+	return logging.FromContext(c.Request().Context(), h.logger)
+}
+
+// @Summary Upload a book cover
+// @Description Upload a JPEG, PNG or WebP image as the request body to replace the book's cover. The type is detected from the image itself; thumbnails are rendered in the configured sizes. The book's cover_etag changes with every new image.
+// @Tags books
+// @Accept image/jpeg,image/png,image/webp
+// @Produce json
+// @Param id path string true "Book UUID"
+// @Success 200 {object} entities.Book
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Router /books/{id}/cover [put]
+func (h *coverHandler) UploadCover(c echo.Context) error {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		h.log(c).Warn("Invalid UUID format", zap.String("id", idParam), zap.Error(err))
+		return invalidUUID(c)
+	}
+
+	tooLarge := problem.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("cover image must be at most %d bytes", h.maxSize))
+	if c.Request().ContentLength > h.maxSize {
+		return problem.Write(c, tooLarge)
+	}
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body, h.maxSize+1))
+	if err != nil {
+		h.log(c).Warn("Failed to read cover upload", zap.Error(err))
+		return problem.Write(c, problem.BadRequest("request body could not be read"))
+	}
+	if int64(len(data)) > h.maxSize {
+		return problem.Write(c, tooLarge)
+	}
+	if len(data) == 0 {
+		return problem.Write(c, problem.BadRequest("request body must be a JPEG, PNG or WebP image"))
+	}
+
+	book, err := h.coverUseCase.UploadCover(c.Request().Context(), id, data)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to upload cover", zap.String("id", id.String()))
+	}
+	return respond(c, http.StatusOK, book)
+}
+
+// @Summary Get a book cover
+// @Description Get the book's uploaded cover, or one of its JPEG thumbnails. Responses carry an ETag; send it back in If-None-Match to get 304 Not Modified while the cover is unchanged.
+// @Tags books
+// @Produce image/jpeg,image/png,image/webp
+// @Param id path string true "Book UUID"
+// @Param size query string false "original (default) or a thumbnail size such as small, medium or large"
+// @Success 200 {file} binary
+// @Success 304 "Cover unchanged"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /books/{id}/cover [get]
+func (h *coverHandler) GetCover(c echo.Context) error {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		h.log(c).Warn("Invalid UUID format", zap.String("id", idParam), zap.Error(err))
+		return invalidUUID(c)
+	}
+	size := c.QueryParam("size")
+	if size == "" {
+		size = covers.Original
+	}
+
+	cover, err := h.coverUseCase.GetCover(c.Request().Context(), id, size)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to get cover", zap.String("id", id.String()), zap.String("size", size))
+	}
+	defer cover.Body.Close()
+
+	// Covers are tenant data, so only the client may cache them
+	etag := `"` + cover.ETag + `"`
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(h.maxAge.Seconds())))
+	if !cover.ModTime.IsZero() {
+		header.Set("Last-Modified", cover.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	header.Set("X-Content-Type-Options", "nosniff")
+	if cover.Size > 0 {
+		header.Set(echo.HeaderContentLength, strconv.FormatInt(cover.Size, 10))
+	}
+	return c.Stream(http.StatusOK, cover.ContentType, cover.Body)
+}
+
+// etagMatches implements the weak comparison If-None-Match asks for
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// @Summary Delete a book cover
+// @Description Remove the book's uploaded cover and its thumbnails
+// @Tags books
+// @Produce json
+// @Param id path string true "Book UUID"
+// @Success 200 {object} entities.Book
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /books/{id}/cover [delete]
+func (h *coverHandler) DeleteCover(c echo.Context) error {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		h.log(c).Warn("Invalid UUID format", zap.String("id", idParam), zap.Error(err))
+		return invalidUUID(c)
+	}
+
+	book, err := h.coverUseCase.DeleteCover(c.Request().Context(), id)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to delete cover", zap.String("id", id.String()))
+	}
+	return respond(c, http.StatusOK, book)
+}
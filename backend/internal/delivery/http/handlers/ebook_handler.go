@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"byfood-library/internal/ebooks"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/problem"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type ebookHandler struct {
+	ebookUseCase usecases.EbookUseCase
+	maxSize      int64
+	logger       *zap.Logger
+}
+
+// NewEbookHandler accepts uploads of up to maxSize bytes
+func NewEbookHandler(ebookUseCase usecases.EbookUseCase, maxSize int64, logger *zap.Logger) EbookHandlerInterface {
+	if maxSize <= 0 {
+		maxSize = ebooks.DefaultMaxSize
+	}
+	return &ebookHandler{
+		ebookUseCase: ebookUseCase,
+		maxSize:      maxSize,
+		logger:       logger,
+	}
+}
+
+// log returns the request-scoped logger
+func (h *ebookHandler) log(c echo.Context) *zap.Logger {
+	return logging.FromContext(c.Request().Context(), h.logger)
+}
+
+// @Summary Import an ebook file
+// @Description Upload an EPUB or PDF file as the request body, naming it in a Content-Disposition header. The title, authors, year, ISBN, publisher, subjects and description are read from the file. The file is attached to the book given by book_id, or else to the book with the file's ISBN, or else to a new book created from the file; a new book is checked for duplicates unless force is true. Metadata an existing book lacks is filled in, and an EPUB's cover becomes the book's cover if it has none. A file already in the library is not stored again: the response then reports it with duplicate set.
+// @Tags books
+// @Accept application/epub+zip,application/pdf
+// @Produce json
+// @Param Content-Disposition header string false "attachment; filename=clean-code.epub"
+// @Param book_id query string false "UUID of the book the file belongs to"
+// @Param force query bool false "Create the book even if it looks like a duplicate"
+// @Success 200 {object} entities.BookFileImport
+// @Success 201 {object} entities.BookFileImport
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Router /books/files [post]
+func (h *ebookHandler) ImportFile(c echo.Context) error {
+	bookID := uuid.Nil
+	if raw := c.QueryParam("book_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			h.log(c).Warn("Invalid UUID format", zap.String("book_id", raw), zap.Error(err))
+			return invalidUUID(c)
+		}
+		bookID = id
+	}
+	force := false
+	if raw := c.QueryParam("force"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return problem.Write(c, problem.BadRequest("force must be true or false"))
+		}
+		force = parsed
+	}
+	filename := ""
+	if _, params, err := mime.ParseMediaType(c.Request().Header.Get("Content-Disposition")); err == nil {
+		filename = params["filename"]
+	}
+
+	tooLarge := problem.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("file must be at most %d bytes", h.maxSize))
+	if c.Request().ContentLength > h.maxSize {
+		return problem.Write(c, tooLarge)
+	}
+	data, err := io.ReadAll(io.LimitReader(c.Request().Body, h.maxSize+1))
+	if err != nil {
+		h.log(c).Warn("Failed to read file upload", zap.Error(err))
+		return problem.Write(c, problem.BadRequest("request body could not be read"))
+	}
+	if int64(len(data)) > h.maxSize {
+		return problem.Write(c, tooLarge)
+	}
+	if len(data) == 0 {
+		return problem.Write(c, problem.BadRequest("request body must be an EPUB or PDF file"))
+	}
+
+	result, err := h.ebookUseCase.ImportFile(c.Request().Context(), data, filename, bookID, force)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to import book file", zap.String("filename", filename))
+	}
+	if result.Created {
+		return respond(c, http.StatusCreated, result)
+	}
+	return respond(c, http.StatusOK, result)
+}
+
+// @Summary List a book's files
+// @Description List the ebook files of a book, oldest first
+// @Tags books
+// @Produce json
+// @Param id path string true "Book UUID"
+// @Success 200 {array} entities.BookFile
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /books/{id}/files [get]
+func (h *ebookHandler) ListFiles(c echo.Context) error {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		h.log(c).Warn("Invalid UUID format", zap.String("id", idParam), zap.Error(err))
+		return invalidUUID(c)
+	}
+
+	files, err := h.ebookUseCase.ListFiles(c.Request().Context(), id)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to list book files", zap.String("id", id.String()))
+	}
+	return respond(c, http.StatusOK, files)
+}
+
+// @Summary Download a book file
+// @Description Download an ebook file as an attachment. The ETag is the file's SHA-256; send it back in If-None-Match to get 304 Not Modified.
+// @Tags books
+// @Produce application/epub+zip,application/pdf
+// @Param id path string true "Book UUID"
+// @Param fileId path string true "File UUID"
+// @Success 200 {file} binary
+// @Success 304 "File unchanged"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /books/{id}/files/{fileId} [get]
+func (h *ebookHandler) DownloadFile(c echo.Context) error {
+	id, fileID, err := fileParams(c)
+	if err != nil {
+		return invalidUUID(c)
+	}
+
+	file, err := h.ebookUseCase.OpenFile(c.Request().Context(), id, fileID)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to open book file", zap.String("id", id.String()), zap.String("file_id", fileID.String()))
+	}
+	defer file.Body.Close()
+
+	// A file's content never changes, so its checksum is a strong ETag
+	etag := `"` + file.File.Checksum + `"`
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", "private, no-cache")
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	header.Set("X-Content-Type-Options", "nosniff")
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": file.File.Filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	header.Set("Content-Disposition", disposition)
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(file.File.Size, 10))
+	return c.Stream(http.StatusOK, file.File.ContentType, file.Body)
+}
+
+// @Summary Delete a book file
+// @Description Remove an ebook file from a book and from storage
+// @Tags books
+// @Param id path string true "Book UUID"
+// @Param fileId path string true "File UUID"
+// @Success 204 "File deleted"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /books/{id}/files/{fileId} [delete]
+func (h *ebookHandler) DeleteFile(c echo.Context) error {
+	id, fileID, err := fileParams(c)
+	if err != nil {
+		return invalidUUID(c)
+	}
+
+	if err := h.ebookUseCase.DeleteFile(c.Request().Context(), id, fileID); err != nil {
+		return respondError(c, h.logger, err, "Failed to delete book file", zap.String("id", id.String()), zap.String("file_id", fileID.String()))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func fileParams(c echo.Context) (uuid.UUID, uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	fileID, err := uuid.Parse(c.Param("fileId"))
+	return id, fileID, err
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type enrichmentHandler struct {
+	enrichmentUseCase usecases.EnrichmentUseCase
+	logger            *zap.Logger
+}
+
+func NewEnrichmentHandler(enrichmentUseCase usecases.EnrichmentUseCase, logger *zap.Logger) EnrichmentHandlerInterface {
+	return &enrichmentHandler{
+		enrichmentUseCase: enrichmentUseCase,
+		logger:            logger,
+	}
+}
+
+// log returns the request-scoped logger
+func (h *enrichmentHandler) log(c echo.Context) *zap.Logger {
+	return logging.FromContext(c.Request().Context(), h.logger)
+}
+
+// @Summary Look up a book before creating it
+// @Description Ask the bibliographic sources about a book by ISBN, or by title and optionally author, and return what they know as a create body to review and submit
+// @Tags books
+// @Produce json
+// @Param isbn query string false "ISBN-10 or ISBN-13"
+// @Param title query string false "Title, required without an ISBN"
+// @Param author query string false "Author, to narrow a title lookup"
+// @Success 200 {object} entities.BookLookup
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 502 {object} problem.Problem
+// @Router /books/lookup [get]
+func (h *enrichmentHandler) LookupBook(c echo.Context) error {
+	query := entities.MetadataQuery{
+		ISBN:   c.QueryParam("isbn"),
+		Title:  c.QueryParam("title"),
+		Author: c.QueryParam("author"),
+	}
+
+	lookup, err := h.enrichmentUseCase.LookupBook(c.Request().Context(), query)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to look up book")
+	}
+	return respond(c, http.StatusOK, lookup)
+}
+
+// @Summary Enrich a book
+// @Description Fill the ISBN, publisher, page count, subjects, description and cover URL the book lacks from the bibliographic sources, looking it up by ISBN or else by title and author. Fields already set are kept.
+// @Tags books
+// @Produce json
+// @Param id path string true "Book UUID"
+// @Success 200 {object} entities.Book
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 502 {object} problem.Problem
+// @Router /books/{id}/enrich [post]
+func (h *enrichmentHandler) EnrichBook(c echo.Context) error {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		h.log(c).Warn("Invalid UUID format", zap.String("id", idParam), zap.Error(err))
+		return invalidUUID(c)
+	}
+
+	book, err := h.enrichmentUseCase.EnrichBook(c.Request().Context(), id)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to enrich book", zap.String("id", id.String()))
+	}
+	return respond(c, http.StatusOK, book)
+}
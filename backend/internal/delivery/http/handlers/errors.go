@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/problem"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// respondError writes err as problem details. Errors the client cannot fix
+// are logged under message first, since their detail is not exposed.
+func respondError(c echo.Context, logger *zap.Logger, err error, message string, fields ...zap.Field) error {
+	if kind := entities.KindOf(err); kind == entities.KindInternal || kind == entities.KindTimeout {
+		logging.FromContext(c.Request().Context(), logger).Error(message, append(fields, zap.Error(err))...)
+	}
+	return problem.Respond(c, err)
+}
+
+// invalidBody reports a request body that could not be bound
+func invalidBody(c echo.Context, logger *zap.Logger, err error) error {
+	logging.FromContext(c.Request().Context(), logger).Warn("Failed to bind request body", zap.Error(err))
+	detail := "request body must be a JSON object"
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		detail = fmt.Sprint(httpErr.Message)
+	}
+	return problem.Write(c, problem.BadRequest(detail))
+}
+
+// invalidUUID reports a path parameter that is not a UUID
+func invalidUUID(c echo.Context) error {
+	return problem.Write(c, problem.BadRequest(entities.ErrInvalidUUID.Error()))
+}
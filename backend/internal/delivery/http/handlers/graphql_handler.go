@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"byfood-library/internal/gql"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/problem"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type graphQLHandler struct {
+	server *gql.Server
+	logger *zap.Logger
+}
+
+func NewGraphQLHandler(server *gql.Server, logger *zap.Logger) GraphQLHandlerInterface {
+	return &graphQLHandler{
+		server: server,
+		logger: logger,
+	}
+}
+
+// @Summary GraphQL endpoint
+// @Description Runs a GraphQL query or mutation over books. POST takes {"query", "operationName", "variables", "extensions"}; GET takes the same as query parameters, with variables and extensions JSON-encoded, and only runs queries. Requests rejected before execution (parse, validation, depth or complexity limits, persisted queries) get a 400; execution errors are reported in the errors array with a 200.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param request body gql.Request true "GraphQL request"
+// @Success 200 {object} object
+// @Failure 400 {object} object
+// @Router /graphql [post]
+func (h *graphQLHandler) Query(c echo.Context) error {
+	logger := logging.FromContext(c.Request().Context(), h.logger)
+
+	var req gql.Request
+	readOnly := c.Request().Method == http.MethodGet
+	if readOnly {
+		req.Query = c.QueryParam("query")
+		req.OperationName = c.QueryParam("operationName")
+		for param, target := range map[string]interface{}{
+			"variables":  &req.Variables,
+			"extensions": &req.Extensions,
+		} {
+			if value := c.QueryParam(param); value != "" {
+				if err := json.Unmarshal([]byte(value), target); err != nil {
+					return problem.Write(c, problem.BadRequest(param+" must be JSON"))
+				}
+			}
+		}
+	} else if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode GraphQL request", zap.Error(err))
+		return problem.Write(c, problem.BadRequest("body must be a JSON GraphQL request"))
+	}
+
+	result, executed := h.server.Execute(c.Request().Context(), &req, readOnly)
+	if !executed {
+		return c.JSON(http.StatusBadRequest, result)
+	}
+	return c.JSON(http.StatusOK, result)
+}
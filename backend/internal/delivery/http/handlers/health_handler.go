@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"byfood-library/internal/health"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// HealthRegistry runs the registered dependency checks
+type HealthRegistry interface {
+	Ready(ctx context.Context) (bool, *health.Report)
+	ShuttingDown() bool
+}
+
+type healthHandler struct {
+	registry HealthRegistry
+	logger   *zap.Logger
+}
+
+func NewHealthHandler(registry HealthRegistry, logger *zap.Logger) HealthHandlerInterface {
+	return &healthHandler{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+// LivenessResponse reports that the process is running
+type LivenessResponse struct {
+	Status health.Status `json:"status"`
+}
+
+// @Summary Liveness probe
+// @Description Reports whether the process is running; does not check dependencies
+// @Tags health
+// @Produce json
+// @Success 200 {object} LivenessResponse
+// @Router /livez [get]
+func (h *healthHandler) Liveness(c echo.Context) error {
+	return respond(c, http.StatusOK, LivenessResponse{Status: health.StatusUp})
+}
+
+// @Summary Readiness probe
+// @Description Reports whether the instance can serve traffic, with per-check status and latency
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *healthHandler) Readiness(c echo.Context) error {
+	ready, report := h.registry.Ready(c.Request().Context())
+	if !ready {
+		if h.registry.ShuttingDown() {
+			report.Status = health.StatusDown
+		} else {
+			h.logger.Warn("Readiness check failed", zap.Any("checks", report.Checks))
+		}
+		return respond(c, http.StatusServiceUnavailable, report)
+	}
+	return respond(c, http.StatusOK, report)
+}
@@ -9,6 +9,59 @@ type BookHandlerInterface interface {
 	CreateBook(c echo.Context) error
 	UpdateBook(c echo.Context) error
 	DeleteBook(c echo.Context) error
+	GetBookSchema(c echo.Context) error
+	GetDuplicates(c echo.Context) error
+	MergeBooks(c echo.Context) error
+}
+
+// EnrichmentHandlerInterface for looking books up in bibliographic sources
+type EnrichmentHandlerInterface interface {
+	LookupBook(c echo.Context) error
+	EnrichBook(c echo.Context) error
+}
+
+// CoverHandlerInterface for uploading and serving book cover images
+type CoverHandlerInterface interface {
+	UploadCover(c echo.Context) error
+	GetCover(c echo.Context) error
+	DeleteCover(c echo.Context) error
+}
+
+// OPDSHandlerInterface for the OPDS catalogue feeds
+type OPDSHandlerInterface interface {
+	Navigation(c echo.Context) error
+	Books(c echo.Context) error
+	Authors(c echo.Context) error
+	Subjects(c echo.Context) error
+	OpenSearch(c echo.Context) error
+}
+
+// OAIHandlerInterface for the OAI-PMH endpoint
+type OAIHandlerInterface interface {
+	Handle(c echo.Context) error
+}
+
+// SRUHandlerInterface for the SRU endpoint
+type SRUHandlerInterface interface {
+	Handle(c echo.Context) error
+}
+
+// EbookHandlerInterface for importing and serving ebook files
+type EbookHandlerInterface interface {
+	ImportFile(c echo.Context) error
+	ListFiles(c echo.Context) error
+	DownloadFile(c echo.Context) error
+	DeleteFile(c echo.Context) error
+}
+
+// StreamHandlerInterface for the live book change stream
+type StreamHandlerInterface interface {
+	StreamBooks(c echo.Context) error
+}
+
+// GraphQLHandlerInterface for the GraphQL endpoint
+type GraphQLHandlerInterface interface {
+	Query(c echo.Context) error
 }
 
 // URLHandlerInterface for URL processing functionality
@@ -16,10 +69,37 @@ type URLHandlerInterface interface {
 	ProcessURL(c echo.Context) error
 }
 
-// ErrorResponse for consistent error handling
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+// TenantHandlerInterface for the tenant admin API
+type TenantHandlerInterface interface {
+	GetTenants(c echo.Context) error
+	GetTenant(c echo.Context) error
+	CreateTenant(c echo.Context) error
+	SuspendTenant(c echo.Context) error
+	ActivateTenant(c echo.Context) error
+	UpdateTenantSettings(c echo.Context) error
+}
+
+// WebhookHandlerInterface for webhook subscriptions and their delivery log
+type WebhookHandlerInterface interface {
+	GetWebhooks(c echo.Context) error
+	GetWebhook(c echo.Context) error
+	CreateWebhook(c echo.Context) error
+	UpdateWebhook(c echo.Context) error
+	DeleteWebhook(c echo.Context) error
+	GetDeliveries(c echo.Context) error
+	GetDelivery(c echo.Context) error
+	Redeliver(c echo.Context) error
+}
+
+// HealthHandlerInterface for liveness and readiness probes
+type HealthHandlerInterface interface {
+	Liveness(c echo.Context) error
+	Readiness(c echo.Context) error
+}
+
+// ConfigHandlerInterface for the admin configuration endpoint
+type ConfigHandlerInterface interface {
+	GetConfig(c echo.Context) error
 }
 
 // SuccessResponse for consistent success responses
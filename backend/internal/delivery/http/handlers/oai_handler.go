@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+
+	"byfood-library/internal/oai"
+	"byfood-library/internal/problem"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type oaiHandler struct {
+	provider *oai.Provider
+	logger   *zap.Logger
+}
+
+// NewOAIHandler serves the OAI-PMH endpoint at /oai
+func NewOAIHandler(provider *oai.Provider, logger *zap.Logger) OAIHandlerInterface {
+	return &oaiHandler{
+		provider: provider,
+		logger:   logger,
+	}
+}
+
+// @Summary OAI-PMH
+// @Description Answers the six OAI-PMH 2.0 verbs for metadata harvesters, with the books as oai_dc or marcxml records. Lists are selected by from and until against the books' last change, include deleted books, and are paged with resumption tokens. Protocol errors are reported in the response, with status 200. POST requests send the arguments form encoded.
+// @Tags oai
+// @Accept x-www-form-urlencoded
+// @Produce text/xml
+// @Param verb query string true "Identify, ListMetadataFormats, ListSets, GetRecord, ListIdentifiers or ListRecords"
+// @Param identifier query string false "OAI identifier of a record"
+// @Param metadataPrefix query string false "oai_dc or marcxml"
+// @Param from query string false "Lower bound of the datestamps, as YYYY-MM-DD or YYYY-MM-DDThh:mm:ssZ"
+// @Param until query string false "Upper bound of the datestamps, as YYYY-MM-DD or YYYY-MM-DDThh:mm:ssZ"
+// @Param set query string false "Set; the repository has none"
+// @Param resumptionToken query string false "Continues an incomplete list"
+// @Success 200 {string} string "OAI-PMH response"
+// @Failure 500 {object} problem.Problem
+// @Router /oai [get]
+// @Router /oai [post]
+func (h *oaiHandler) Handle(c echo.Context) error {
+	args := c.QueryParams()
+	if c.Request().Method == http.MethodPost {
+		if err := c.Request().ParseForm(); err != nil {
+			return problem.Write(c, problem.BadRequest("request body must be form encoded"))
+		}
+		args = c.Request().PostForm
+	}
+
+	baseURL := c.Scheme() + "://" + c.Request().Host + "/oai"
+	response, err := h.provider.Handle(c.Request().Context(), baseURL, args)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to answer OAI-PMH request", zap.String("verb", args.Get("verb")))
+	}
+	data, err := xml.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, oai.ContentType, append([]byte(xml.Header), data...))
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/opds"
+	"byfood-library/internal/problem"
+	"byfood-library/internal/usecases"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// maxOPDSPage bounds the page of an acquisition feed, so that its offset
+// stays reasonable
+const maxOPDSPage = 100_000
+
+type opdsHandler struct {
+	catalogUseCase usecases.CatalogUseCase
+	config         opds.Config
+	logger         *zap.Logger
+}
+
+// NewOPDSHandler serves the OPDS 1.2 feeds under /opds and the OPDS 2.0
+// feeds under /opds/v2 with the same methods
+func NewOPDSHandler(catalogUseCase usecases.CatalogUseCase, config opds.Config, logger *zap.Logger) OPDSHandlerInterface {
+	return &opdsHandler{
+		catalogUseCase: catalogUseCase,
+		config:         config,
+		logger:         logger,
+	}
+}
+
+// feeds returns the feeds of the catalogue at the request's host
+func (h *opdsHandler) feeds(c echo.Context) *opds.Feeds {
+	return opds.New(h.config, c.Scheme()+"://"+c.Request().Host, time.Now())
+}
+
+// isOPDS2 tells whether the request is for an OPDS 2.0 feed
+func isOPDS2(c echo.Context) bool {
+	return strings.HasPrefix(c.Path(), "/opds/v2")
+}
+
+// writeOPDSXML writes v as an XML document of the content type
+func writeOPDSXML(c echo.Context, contentType string, v interface{}) error {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, contentType, append([]byte(xml.Header), data...))
+}
+
+// writeOPDSJSON writes v as an OPDS 2.0 feed
+func writeOPDSJSON(c echo.Context, v interface{}) error {
+	c.Response().Header().Set(echo.HeaderContentType, opds.JSONFeed)
+	return c.JSON(http.StatusOK, v)
+}
+
+// @Summary OPDS catalogue root
+// @Description The navigation feed e-reader apps start from, leading to all books, to the books by author and to the books by subject. /opds serves an OPDS 1.2 Atom feed and /opds/v2 an OPDS 2.0 JSON feed.
+// @Tags opds
+// @Produce application/atom+xml,application/opds+json
+// @Success 200 {string} string "Navigation feed"
+// @Router /opds [get]
+// @Router /opds/v2 [get]
+func (h *opdsHandler) Navigation(c echo.Context) error {
+	feeds := h.feeds(c)
+	if isOPDS2(c) {
+		return writeOPDSJSON(c, feeds.JSONNavigation())
+	}
+	return writeOPDSXML(c, opds.AtomNavigation, feeds.Navigation())
+}
+
+// @Summary OPDS acquisition feed
+// @Description A page of books, newest first, with links to download their ebook files and covers, to the other pages and to the most common authors and subjects as facets. q matches every word in the title or author; author and subject match exactly.
+// @Tags opds
+// @Produce application/atom+xml,application/opds+json
+// @Param q query string false "Search words"
+// @Param author query string false "Author"
+// @Param subject query string false "Subject or genre"
+// @Param page query int false "Page, counting from 1"
+// @Success 200 {string} string "Acquisition feed"
+// @Failure 400 {object} problem.Problem
+// @Router /opds/books [get]
+// @Router /opds/v2/books [get]
+func (h *opdsHandler) Books(c echo.Context) error {
+	page := 1
+	if raw := c.QueryParam("page"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxOPDSPage {
+			return problem.Write(c, problem.BadRequest("page must be an integer from 1 to "+strconv.Itoa(maxOPDSPage)))
+		}
+		page = parsed
+	}
+
+	feeds := h.feeds(c)
+	ctx := c.Request().Context()
+	query := feeds.Query(c.QueryParam("q"), c.QueryParam("author"), c.QueryParam("subject"), page)
+	result, err := h.catalogUseCase.Search(ctx, query)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to search the catalogue")
+	}
+	listing := &opds.Listing{Query: query, Page: page, Books: result.Books, Total: result.Total, Files: result.Files}
+	if listing.Authors, err = h.catalogUseCase.FacetValues(ctx, entities.FacetAuthor, feeds.FacetLimit); err != nil {
+		return respondError(c, h.logger, err, "Failed to read facet values")
+	}
+	if listing.Subjects, err = h.catalogUseCase.FacetValues(ctx, entities.FacetSubject, feeds.FacetLimit); err != nil {
+		return respondError(c, h.logger, err, "Failed to read facet values")
+	}
+
+	if isOPDS2(c) {
+		return writeOPDSJSON(c, feeds.JSONPublications(listing))
+	}
+	return writeOPDSXML(c, opds.AtomAcquisition, feeds.Acquisition(listing))
+}
+
+// @Summary OPDS authors
+// @Description A navigation feed of the authors in the catalogue, most prolific first, each leading to their books
+// @Tags opds
+// @Produce application/atom+xml,application/opds+json
+// @Success 200 {string} string "Navigation feed"
+// @Router /opds/authors [get]
+// @Router /opds/v2/authors [get]
+func (h *opdsHandler) Authors(c echo.Context) error {
+	return h.browse(c, entities.FacetAuthor)
+}
+
+// @Summary OPDS subjects
+// @Description A navigation feed of the subjects and genres in the catalogue, most common first, each leading to their books
+// @Tags opds
+// @Produce application/atom+xml,application/opds+json
+// @Success 200 {string} string "Navigation feed"
+// @Router /opds/subjects [get]
+// @Router /opds/v2/subjects [get]
+func (h *opdsHandler) Subjects(c echo.Context) error {
+	return h.browse(c, entities.FacetSubject)
+}
+
+func (h *opdsHandler) browse(c echo.Context, facet entities.Facet) error {
+	values, err := h.catalogUseCase.FacetValues(c.Request().Context(), facet, opds.BrowseLimit)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to read facet values", zap.String("facet", string(facet)))
+	}
+	feeds := h.feeds(c)
+	if isOPDS2(c) {
+		return writeOPDSJSON(c, feeds.JSONBrowse(facet, values))
+	}
+	return writeOPDSXML(c, opds.AtomNavigation, feeds.Browse(facet, values))
+}
+
+// @Summary OpenSearch description
+// @Description Describes how e-reader apps search the catalogue, for the OPDS 1.2 and 2.0 feeds
+// @Tags opds
+// @Produce application/opensearchdescription+xml
+// @Success 200 {string} string "OpenSearch description"
+// @Router /opds/opensearch.xml [get]
+func (h *opdsHandler) OpenSearch(c echo.Context) error {
+	return writeOPDSXML(c, opds.OpenSearchType, h.feeds(c).OpenSearch())
+}
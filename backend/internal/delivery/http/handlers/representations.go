@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/render"
+	"byfood-library/internal/schemaorg"
+	"github.com/labstack/echo/v4"
+)
+
+// representations writes every handler's responses: JSON for any value,
+// and for books and lists of books also schema.org JSON-LD, CSV, XML and
+// Turtle, as the client's Accept header prefers
+var representations = newRepresentations()
+
+func newRepresentations() *render.Registry {
+	registry := render.NewRegistry()
+	registry.RegisterAny(echo.MIMEApplicationJSONCharsetUTF8, render.JSON)
+
+	render.Register(registry, render.MediaTypeJSONLD, schemaorg.WriteBook)
+	render.Register(registry, render.MediaTypeJSONLD, schemaorg.WriteBooks)
+	render.Register(registry, render.MediaTypeCSV+"; charset=utf-8; header=present", func(w io.Writer, book *entities.Book) error {
+		return writeBooksCSV(w, []*entities.Book{book})
+	})
+	render.Register(registry, render.MediaTypeCSV+"; charset=utf-8; header=present", writeBooksCSV)
+	render.Register(registry, render.MediaTypeXML+"; charset=utf-8", func(w io.Writer, book *entities.Book) error {
+		return writeXML(w, bookXMLOf(book))
+	})
+	render.Register(registry, render.MediaTypeXML+"; charset=utf-8", func(w io.Writer, books []*entities.Book) error {
+		list := &booksXML{Books: make([]*bookXML, len(books))}
+		for i, book := range books {
+			list.Books[i] = bookXMLOf(book)
+		}
+		return writeXML(w, list)
+	})
+	render.Register(registry, render.MediaTypeTurtle+"; charset=utf-8", func(w io.Writer, book *entities.Book) error {
+		return schemaorg.WriteTurtle(w, []*entities.Book{book})
+	})
+	render.Register(registry, render.MediaTypeTurtle+"; charset=utf-8", schemaorg.WriteTurtle)
+	return registry
+}
+
+// respond writes value with status in the representation the request
+// prefers, or a 406 problem when it accepts none of value's
+func respond(c echo.Context, status int, value interface{}) error {
+	return representations.Respond(c, status, value)
+}
+
+// bookCSVHeader names the columns of the CSV representation after the
+// fields of the JSON one
+var bookCSVHeader = []string{
+	"id", "title", "author", "year", "isbn", "publisher", "page_count",
+	"subjects", "description", "cover_url", "created_at", "updated_at",
+}
+
+// writeBooksCSV writes a header and a row per book. Subjects are joined
+// with "; ", and text that a spreadsheet would run as a formula is
+// prefixed with an apostrophe.
+func writeBooksCSV(w io.Writer, books []*entities.Book) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(bookCSVHeader); err != nil {
+		return err
+	}
+	for _, book := range books {
+		pageCount := ""
+		if book.PageCount > 0 {
+			pageCount = strconv.Itoa(book.PageCount)
+		}
+		err := writer.Write([]string{
+			book.ID.String(),
+			csvText(book.Title),
+			csvText(book.Author),
+			strconv.Itoa(book.Year),
+			book.ISBN,
+			csvText(book.Publisher),
+			pageCount,
+			csvText(strings.Join(book.Subjects, "; ")),
+			csvText(book.Description),
+			csvText(book.CoverURL),
+			book.CreatedAt.UTC().Format(time.RFC3339),
+			book.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// bookXML is the XML representation of a book, with the element names of
+// the JSON fields
+type bookXML struct {
+	XMLName     xml.Name  `xml:"book"`
+	ID          string    `xml:"id,attr"`
+	Title       string    `xml:"title"`
+	Author      string    `xml:"author"`
+	Year        int       `xml:"year"`
+	ISBN        string    `xml:"isbn,omitempty"`
+	Publisher   string    `xml:"publisher,omitempty"`
+	PageCount   int       `xml:"page_count,omitempty"`
+	Subjects    []string  `xml:"subjects>subject,omitempty"`
+	Description string    `xml:"description,omitempty"`
+	CoverURL    string    `xml:"cover_url,omitempty"`
+	CoverETag   string    `xml:"cover_etag,omitempty"`
+	CreatedAt   time.Time `xml:"created_at"`
+	UpdatedAt   time.Time `xml:"updated_at"`
+}
+
+type booksXML struct {
+	XMLName xml.Name   `xml:"books"`
+	Books   []*bookXML `xml:"book"`
+}
+
+func bookXMLOf(book *entities.Book) *bookXML {
+	return &bookXML{
+		ID:          book.ID.String(),
+		Title:       book.Title,
+		Author:      book.Author,
+		Year:        book.Year,
+		ISBN:        book.ISBN,
+		Publisher:   book.Publisher,
+		PageCount:   book.PageCount,
+		Subjects:    book.Subjects,
+		Description: book.Description,
+		CoverURL:    book.CoverURL,
+		CoverETag:   book.CoverETag,
+		CreatedAt:   book.CreatedAt,
+		UpdatedAt:   book.UpdatedAt,
+	}
+}
+
+func writeXML(w io.Writer, value interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+
+	"byfood-library/internal/problem"
+	"byfood-library/internal/sru"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type sruHandler struct {
+	provider *sru.Provider
+	logger   *zap.Logger
+}
+
+// NewSRUHandler serves the SRU endpoint at /sru
+func NewSRUHandler(provider *sru.Provider, logger *zap.Logger) SRUHandlerInterface {
+	return &sruHandler{
+		provider: provider,
+		logger:   logger,
+	}
+}
+
+// @Summary SRU
+// @Description Answers SRU 2.0 searchRetrieve requests, whose CQL queries search the books by dc.title, dc.creator, dc.publisher, dc.description, dc.subject, dc.date, dc.identifier, rec.identifier or cql.serverChoice with booleans, proximity and relation modifiers, with the matching books as Dublin Core or MARCXML records. A request without a query is answered with an explain record describing the indexes and schemas. What cannot be answered is reported as diagnostics in the response, with status 200. POST requests send the parameters form encoded.
+// @Tags sru
+// @Accept x-www-form-urlencoded
+// @Produce application/sru+xml
+// @Param query query string false "CQL query; an explain request without it"
+// @Param startRecord query int false "Position of the first record, from 1"
+// @Param maximumRecords query int false "Number of records"
+// @Param recordSchema query string false "dc or marcxml, or their identifiers"
+// @Param recordXMLEscaping query string false "xml or string"
+// @Param queryType query string false "cql"
+// @Param operation query string false "explain or searchRetrieve, for SRU 1.x clients"
+// @Success 200 {string} string "SRU response"
+// @Failure 500 {object} problem.Problem
+// @Router /sru [get]
+// @Router /sru [post]
+func (h *sruHandler) Handle(c echo.Context) error {
+	args := c.QueryParams()
+	if c.Request().Method == http.MethodPost {
+		if err := c.Request().ParseForm(); err != nil {
+			return problem.Write(c, problem.BadRequest("request body must be form encoded"))
+		}
+		args = c.Request().PostForm
+	}
+
+	baseURL := c.Scheme() + "://" + c.Request().Host + "/sru"
+	response, err := h.provider.Handle(c.Request().Context(), baseURL, args)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to answer SRU request", zap.String("query", args.Get("query")))
+	}
+	data, err := xml.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, sru.ContentType, append([]byte(xml.Header), data...))
+}
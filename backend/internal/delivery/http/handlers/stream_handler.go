@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"byfood-library/internal/logging"
+	"byfood-library/internal/middleware"
+	"byfood-library/internal/problem"
+	"byfood-library/internal/stream"
+	"byfood-library/internal/tenancy"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// streamRetry is the reconnect delay suggested to EventSource clients
+const streamRetry = 3 * time.Second
+
+type streamHandler struct {
+	hub       *stream.Hub
+	heartbeat time.Duration
+	logger    *zap.Logger
+}
+
+func NewStreamHandler(hub *stream.Hub, heartbeat time.Duration, logger *zap.Logger) StreamHandlerInterface {
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	return &streamHandler{
+		hub:       hub,
+		heartbeat: heartbeat,
+		logger:    logger,
+	}
+}
+
+// @Summary Stream book changes
+// @Description Server-Sent Events stream of book.created, book.updated and book.deleted. Event IDs can be sent back as Last-Event-ID to resume; a "reset" event means the client must reload the list. Deletions are sent regardless of the author and genre filters.
+// @Tags books
+// @Produce text/event-stream
+// @Param author query []string false "Only books by these authors (case-insensitive)" collectionFormat(multi)
+// @Param genre query []string false "Only books with one of these subjects (case-insensitive)" collectionFormat(multi)
+// @Param last_event_id query string false "Resume after this event, for clients that cannot send Last-Event-ID"
+// @Param Last-Event-ID header string false "Resume after this event"
+// @Success 200 {string} string "text/event-stream"
+// @Failure 400 {object} problem.Problem
+// @Router /books/stream [get]
+func (h *streamHandler) StreamBooks(c echo.Context) error {
+	ctx := c.Request().Context()
+	logger := logging.FromContext(ctx, h.logger)
+
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return problem.Respond(c, err)
+	}
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.QueryParam("last_event_id")
+	}
+	filter := stream.AllOf(stream.AuthorFilter(c.QueryParams()["author"]), stream.GenreFilter(c.QueryParams()["genre"]))
+	sub, replay, reset := h.hub.Subscribe(tenantID, lastEventID, filter)
+	defer h.hub.Unsubscribe(sub)
+	middleware.AddStreamConnections(1)
+	defer middleware.AddStreamConnections(-1)
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	// The server's write timeout would otherwise cut the stream off
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	if reset {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, msg := range replay {
+		if err := writeStreamMessage(w, msg); err != nil {
+			return nil
+		}
+	}
+	w.Flush()
+	logger.Debug("Book stream opened", zap.Int("replayed", len(replay)), zap.Bool("reset", reset))
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-sub.C:
+			// Closed when the client fell behind or the hub reset; the
+			// client reconnects and resumes from its last event
+			if !ok {
+				return nil
+			}
+			if err := writeStreamMessage(w, msg); err != nil {
+				return nil
+			}
+			w.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			w.Flush()
+		}
+	}
+}
+
+func writeStreamMessage(w io.Writer, msg *stream.Message) error {
+	data, err := json.Marshal(msg.Event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", msg.ID, msg.Event.Type, data)
+	return err
+}
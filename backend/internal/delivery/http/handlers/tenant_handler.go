@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type tenantHandler struct {
+	tenantUseCase usecases.TenantUseCase
+	logger        *zap.Logger
+}
+
+func NewTenantHandler(tenantUseCase usecases.TenantUseCase, logger *zap.Logger) TenantHandlerInterface {
+	return &tenantHandler{
+		tenantUseCase: tenantUseCase,
+		logger:        logger,
+	}
+}
+
+// @Summary List tenants
+// @Description List all tenants hosted by this deployment
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} entities.Tenant
+// @Failure 401 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /admin/tenants [get]
+func (h *tenantHandler) GetTenants(c echo.Context) error {
+	tenants, err := h.tenantUseCase.GetAllTenants(c.Request().Context())
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to retrieve tenants")
+	}
+	return respond(c, http.StatusOK, tenants)
+}
+
+// @Summary Get tenant by ID
+// @Description Get a single tenant by its UUID
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Tenant UUID"
+// @Success 200 {object} entities.Tenant
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /admin/tenants/{id} [get]
+func (h *tenantHandler) GetTenant(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return invalidUUID(c)
+	}
+
+	tenant, err := h.tenantUseCase.GetTenantByID(c.Request().Context(), id)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to retrieve tenant")
+	}
+	return respond(c, http.StatusOK, tenant)
+}
+
+// @Summary Create a tenant
+// @Description Create a tenant; the returned API key is shown only once
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param tenant body entities.CreateTenantDTO true "Tenant to create"
+// @Success 201 {object} entities.TenantWithAPIKey
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Router /admin/tenants [post]
+func (h *tenantHandler) CreateTenant(c echo.Context) error {
+	var dto entities.CreateTenantDTO
+	if err := c.Bind(&dto); err != nil {
+		return invalidBody(c, h.logger, err)
+	}
+
+	tenant, err := h.tenantUseCase.CreateTenant(c.Request().Context(), &dto)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to create tenant")
+	}
+	return respond(c, http.StatusCreated, tenant)
+}
+
+// @Summary Suspend a tenant
+// @Description Suspend a tenant; its requests are rejected until reactivated
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Tenant UUID"
+// @Success 200 {object} entities.Tenant
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /admin/tenants/{id}/suspend [post]
+func (h *tenantHandler) SuspendTenant(c echo.Context) error {
+	return h.changeStatus(c, h.tenantUseCase.SuspendTenant)
+}
+
+// @Summary Activate a tenant
+// @Description Reactivate a suspended tenant
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Tenant UUID"
+// @Success 200 {object} entities.Tenant
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /admin/tenants/{id}/activate [post]
+func (h *tenantHandler) ActivateTenant(c echo.Context) error {
+	return h.changeStatus(c, h.tenantUseCase.ActivateTenant)
+}
+
+// @Summary Update tenant settings
+// @Description Replace the tenant's configuration overrides
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path string true "Tenant UUID"
+// @Param settings body entities.UpdateTenantSettingsDTO true "Tenant settings"
+// @Success 200 {object} entities.Tenant
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /admin/tenants/{id}/settings [put]
+func (h *tenantHandler) UpdateTenantSettings(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return invalidUUID(c)
+	}
+
+	var dto entities.UpdateTenantSettingsDTO
+	if err := c.Bind(&dto); err != nil {
+		return invalidBody(c, h.logger, err)
+	}
+
+	tenant, err := h.tenantUseCase.UpdateTenantSettings(c.Request().Context(), id, &dto)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to update tenant settings")
+	}
+	return respond(c, http.StatusOK, tenant)
+}
+
+func (h *tenantHandler) changeStatus(c echo.Context, change func(ctx context.Context, id uuid.UUID) (*entities.Tenant, error)) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return invalidUUID(c)
+	}
+
+	tenant, err := change(c.Request().Context(), id)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to update tenant status")
+	}
+	return respond(c, http.StatusOK, tenant)
+}
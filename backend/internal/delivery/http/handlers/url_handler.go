@@ -23,12 +23,12 @@ func NewURLHandler(logger *zap.Logger) URLHandlerInterface {
 // @Accept json
 // @Produce json
 // @Success 200 {object} SuccessResponse
-// @Failure 500 {object} ErrorResponse
+// @Failure 500 {object} problem.Problem
 // @Router /process-url [post]
 func (h *urlHandler) ProcessURL(c echo.Context) error {
 	// This is a placeholder implementation
 	// Add actual URL processing logic as needed
-	return c.JSON(http.StatusOK, SuccessResponse{
+	return respond(c, http.StatusOK, SuccessResponse{
 		Message: "URL processed successfully",
 	})
 }
\ No newline at end of file
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/problem"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+type webhookHandler struct {
+	webhookUseCase usecases.WebhookUseCase
+	logger         *zap.Logger
+}
+
+func NewWebhookHandler(webhookUseCase usecases.WebhookUseCase, logger *zap.Logger) WebhookHandlerInterface {
+	return &webhookHandler{
+		webhookUseCase: webhookUseCase,
+		logger:         logger,
+	}
+}
+
+// @Summary List webhooks
+// @Description List the tenant's webhook subscriptions
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} entities.Webhook
+// @Failure 500 {object} problem.Problem
+// @Router /webhooks [get]
+func (h *webhookHandler) GetWebhooks(c echo.Context) error {
+	webhooks, err := h.webhookUseCase.GetAllWebhooks(c.Request().Context())
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to retrieve webhooks")
+	}
+	return respond(c, http.StatusOK, webhooks)
+}
+
+// @Summary Get webhook by ID
+// @Description Get a single webhook subscription by its UUID
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook UUID"
+// @Success 200 {object} entities.Webhook
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /webhooks/{id} [get]
+func (h *webhookHandler) GetWebhook(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return invalidUUID(c)
+	}
+
+	webhook, err := h.webhookUseCase.GetWebhookByID(c.Request().Context(), id)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to retrieve webhook")
+	}
+	return respond(c, http.StatusOK, webhook)
+}
+
+// @Summary Create a webhook
+// @Description Subscribe a URL to book events; the returned signing secret is shown only once
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body entities.CreateWebhookDTO true "Webhook to create"
+// @Success 201 {object} entities.WebhookWithSecret
+// @Failure 400 {object} problem.Problem
+// @Router /webhooks [post]
+func (h *webhookHandler) CreateWebhook(c echo.Context) error {
+	var dto entities.CreateWebhookDTO
+	if err := c.Bind(&dto); err != nil {
+		return invalidBody(c, h.logger, err)
+	}
+
+	webhook, err := h.webhookUseCase.CreateWebhook(c.Request().Context(), &dto)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to create webhook")
+	}
+	return respond(c, http.StatusCreated, webhook)
+}
+
+// @Summary Update a webhook
+// @Description Replace a webhook's URL and event types; set active to true to re-enable a disabled webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook UUID"
+// @Param webhook body entities.UpdateWebhookDTO true "Webhook changes"
+// @Success 200 {object} entities.Webhook
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /webhooks/{id} [put]
+func (h *webhookHandler) UpdateWebhook(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return invalidUUID(c)
+	}
+
+	var dto entities.UpdateWebhookDTO
+	if err := c.Bind(&dto); err != nil {
+		return invalidBody(c, h.logger, err)
+	}
+
+	webhook, err := h.webhookUseCase.UpdateWebhook(c.Request().Context(), id, &dto)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to update webhook")
+	}
+	return respond(c, http.StatusOK, webhook)
+}
+
+// @Summary Delete a webhook
+// @Description Delete a webhook subscription and its delivery log
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook UUID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /webhooks/{id} [delete]
+func (h *webhookHandler) DeleteWebhook(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return invalidUUID(c)
+	}
+
+	if err := h.webhookUseCase.DeleteWebhook(c.Request().Context(), id); err != nil {
+		return respondError(c, h.logger, err, "Failed to delete webhook")
+	}
+	return respond(c, http.StatusOK, SuccessResponse{
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// @Summary List webhook deliveries
+// @Description List a webhook's most recent deliveries, newest first, with request headers and response snippets
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook UUID"
+// @Param limit query int false "Maximum deliveries to return (default 50, max 200)"
+// @Success 200 {array} entities.WebhookDelivery
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /webhooks/{id}/deliveries [get]
+func (h *webhookHandler) GetDeliveries(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return invalidUUID(c)
+	}
+
+	limit := defaultDeliveryLimit
+	if raw := c.QueryParam("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxDeliveryLimit {
+			return problem.Write(c, problem.BadRequest("limit must be between 1 and "+strconv.Itoa(maxDeliveryLimit)))
+		}
+	}
+
+	deliveries, err := h.webhookUseCase.ListDeliveries(c.Request().Context(), id, limit)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to retrieve webhook deliveries")
+	}
+	return respond(c, http.StatusOK, deliveries)
+}
+
+// @Summary Get a webhook delivery
+// @Description Get one delivery from a webhook's log
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook UUID"
+// @Param deliveryId path string true "Delivery UUID"
+// @Success 200 {object} entities.WebhookDelivery
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /webhooks/{id}/deliveries/{deliveryId} [get]
+func (h *webhookHandler) GetDelivery(c echo.Context) error {
+	id, deliveryID, err := deliveryParams(c)
+	if err != nil {
+		return invalidUUID(c)
+	}
+
+	delivery, err := h.webhookUseCase.GetDelivery(c.Request().Context(), id, deliveryID)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to retrieve webhook delivery")
+	}
+	return respond(c, http.StatusOK, delivery)
+}
+
+// @Summary Redeliver a webhook delivery
+// @Description Queue the delivery's payload to be sent again; the new delivery is returned
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook UUID"
+// @Param deliveryId path string true "Delivery UUID"
+// @Success 202 {object} entities.WebhookDelivery
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Router /webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *webhookHandler) Redeliver(c echo.Context) error {
+	id, deliveryID, err := deliveryParams(c)
+	if err != nil {
+		return invalidUUID(c)
+	}
+
+	delivery, err := h.webhookUseCase.Redeliver(c.Request().Context(), id, deliveryID)
+	if err != nil {
+		return respondError(c, h.logger, err, "Failed to redeliver webhook delivery")
+	}
+	return respond(c, http.StatusAccepted, delivery)
+}
+
+func deliveryParams(c echo.Context) (uuid.UUID, uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	deliveryID, err := uuid.Parse(c.Param("deliveryId"))
+	return id, deliveryID, err
+}
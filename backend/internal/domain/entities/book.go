@@ -7,11 +7,19 @@ import (
 	"github.com/google/uuid"
 )
 
+// Book is a catalogue record. ISBN is a normalized ISBN-13, or empty when
+// unknown.
 type Book struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Title     string    `json:"title" db:"title"`
-	Author    string    `json:"author" db:"author"`
-	Year      int       `json:"year" db:"year"`
+	ID       uuid.UUID `json:"id" db:"id"`
+	TenantID uuid.UUID `json:"-" db:"tenant_id"`
+	Title    string    `json:"title" db:"title"`
+	Author   string    `json:"author" db:"author"`
+	Year     int       `json:"year" db:"year"`
+	ISBN     string    `json:"isbn,omitempty" db:"isbn"`
+	BookMetadata
+	// CoverETag identifies the uploaded cover served at
+	// /api/v1/books/{id}/cover, and changes with it; empty without one
+	CoverETag string    `json:"cover_etag,omitempty" db:"cover_etag"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -19,40 +27,57 @@ type Book struct {
 type CreateBookDTO struct {
 	Title  string `json:"title" validate:"required"`
 	Author string `json:"author" validate:"required"`
-	Year   int    `json:"year" validate:"required,min=1000,max=2034"`
+	Year   int    `json:"year" validate:"required"`
+	// ISBN may be an ISBN-10 or ISBN-13, with or without hyphens
+	ISBN string `json:"isbn,omitempty"`
+	BookMetadata
+	// Force creates the book even if it looks like a duplicate
+	Force bool `json:"-"`
 }
 
+// UpdateBookDTO sets a book's title, author and year. The ISBN and metadata
+// are optional: a field left out keeps its stored value, and an empty one
+// clears it.
 type UpdateBookDTO struct {
-	Title  string `json:"title" validate:"required"`
-	Author string `json:"author" validate:"required"`
-	Year   int    `json:"year" validate:"required,min=1000,max=2034"`
+	Title  string  `json:"title" validate:"required"`
+	Author string  `json:"author" validate:"required"`
+	Year   int     `json:"year" validate:"required"`
+	ISBN   *string `json:"isbn,omitempty" swaggertype:"string"`
+	BookMetadataUpdate
 }
 
 // Domain validation methods
 func (dto *CreateBookDTO) Validate() error {
-	if strings.TrimSpace(dto.Title) == "" {
-		return ErrInvalidTitle
-	}
-	if strings.TrimSpace(dto.Author) == "" {
-		return ErrInvalidAuthor
-	}
-	if dto.Year < 1000 || dto.Year > 2034 {
-		return ErrInvalidYear
-	}
-	return nil
+	return dto.ValidateWith(DefaultBookValidationRules)
+}
+
+// ValidateWith checks the book against rules and reports every invalid
+// field as a *ValidationError
+func (dto *CreateBookDTO) ValidateWith(rules BookValidationRules) error {
+	return validateBook(dto.Title, dto.Author, dto.ISBN, dto.Year, dto.BookMetadata, rules)
 }
 
 func (dto *UpdateBookDTO) Validate() error {
-	if strings.TrimSpace(dto.Title) == "" {
-		return ErrInvalidTitle
-	}
-	if strings.TrimSpace(dto.Author) == "" {
-		return ErrInvalidAuthor
+	return dto.ValidateWith(DefaultBookValidationRules)
+}
+
+func (dto *UpdateBookDTO) ValidateWith(rules BookValidationRules) error {
+	var isbn string
+	if dto.ISBN != nil {
+		isbn = *dto.ISBN
 	}
-	if dto.Year < 1000 || dto.Year > 2034 {
-		return ErrInvalidYear
+	return validateBook(dto.Title, dto.Author, isbn, dto.Year, dto.BookMetadataUpdate.values(), rules)
+}
+
+func validateBook(title, author, isbn string, year int, metadata BookMetadata, rules BookValidationRules) error {
+	problems := rules.check(title, author, year, time.Now())
+	if strings.TrimSpace(isbn) != "" {
+		if _, err := NormalizeISBN(isbn); err != nil {
+			problems.add("/isbn", ErrInvalidISBN, "")
+		}
 	}
-	return nil
+	metadata.check(&problems)
+	return problems.err()
 }
 
 func (dto *CreateBookDTO) ToBook() *Book {
@@ -61,19 +86,32 @@ func (dto *CreateBookDTO) ToBook() *Book {
 		Title:  strings.TrimSpace(dto.Title),
 		Author: strings.TrimSpace(dto.Author),
 		Year:   dto.Year,
+		ISBN:   normalizedISBN(dto.ISBN),
+
+		BookMetadata: dto.BookMetadata.normalized(),
+	}
+}
+
+// ApplyTo brings the stored book to its new state, keeping the optional
+// fields the update leaves out
+func (dto *UpdateBookDTO) ApplyTo(book *Book) {
+	book.Title = strings.TrimSpace(dto.Title)
+	book.Author = strings.TrimSpace(dto.Author)
+	book.Year = dto.Year
+	if dto.ISBN != nil {
+		book.ISBN = normalizedISBN(*dto.ISBN)
 	}
+	dto.BookMetadataUpdate.applyTo(&book.BookMetadata)
+}
+
+// normalizedISBN is for ISBNs that passed validation; anything else is
+// dropped
+func normalizedISBN(isbn string) string {
+	normalized, _ := NormalizeISBN(isbn)
+	return normalized
 }
 
 // ValidateBookData validates book data before persistence
 func (b *Book) ValidateBookData() error {
-	if b.Title == "" {
-		return ErrInvalidTitle
-	}
-	if b.Author == "" {
-		return ErrInvalidAuthor
-	}
-	if b.Year < 1000 || b.Year > 2034 {
-		return ErrInvalidYear
-	}
-	return nil
-}
\ No newline at end of file
+	return validateBook(b.Title, b.Author, b.ISBN, b.Year, b.BookMetadata, DefaultBookValidationRules)
+}
@@ -0,0 +1,36 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookFile is an ebook file of a book, kept in blob storage. Checksum is the
+// file's SHA-256, by which a tenant stores any one file only once.
+type BookFile struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	TenantID    uuid.UUID `json:"-" db:"tenant_id"`
+	BookID      uuid.UUID `json:"book_id" db:"book_id"`
+	Format      string    `json:"format" db:"format"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Filename    string    `json:"filename" db:"filename"`
+	Size        int64     `json:"size" db:"size"`
+	Checksum    string    `json:"checksum" db:"checksum"`
+	// StorageKey locates the file in blob storage. After a merge it points
+	// under the merged book until the file is moved under the survivor.
+	StorageKey string    `json:"-" db:"storage_key"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// BookFileImport is the outcome of importing an ebook file: the book it was
+// attached to and the stored file
+type BookFileImport struct {
+	Book *Book     `json:"book"`
+	File *BookFile `json:"file"`
+	// Created is set when the book was created from the file's metadata
+	Created bool `json:"created"`
+	// Duplicate is set when the file was already in the library, in which
+	// case nothing changed
+	Duplicate bool `json:"duplicate"`
+}
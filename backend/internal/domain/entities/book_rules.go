@@ -0,0 +1,236 @@
+package entities
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxBookTextLength is the size of the title and author columns. Rules may
+// lower it but never raise it, so that long values are rejected as invalid
+// input rather than failing in the database.
+const MaxBookTextLength = 255
+
+// TextRule constrains a text field of a book. Lengths count characters of
+// the trimmed value, Pattern is an RE2 expression the value must match and
+// ForbiddenWords are matched as whole words, ignoring case.
+type TextRule struct {
+	// Required defaults to true when unset
+	Required       *bool    `json:"required,omitempty"`
+	MinLength      int      `json:"min_length,omitempty"`
+	MaxLength      int      `json:"max_length,omitempty"`
+	Pattern        string   `json:"pattern,omitempty"`
+	ForbiddenWords []string `json:"forbidden_words,omitempty"`
+}
+
+// BookValidationRules bounds the values accepted for a book. The deployment
+// configures the defaults and tenants may override them through their
+// settings; see Merge.
+//
+// Year bounds are either absolute (MinYear, MaxYear) or relative to the
+// current year (MinYearOffset, MaxYearOffset), the offset taking precedence.
+// A zero bound with no offset leaves that side open.
+type BookValidationRules struct {
+	MinYear       int      `json:"min_year,omitempty"`
+	MaxYear       int      `json:"max_year,omitempty"`
+	MinYearOffset *int     `json:"min_year_offset,omitempty"`
+	MaxYearOffset *int     `json:"max_year_offset,omitempty"`
+	Title         TextRule `json:"title"`
+	Author        TextRule `json:"author"`
+}
+
+var DefaultBookValidationRules = BookValidationRules{MinYear: 1000, MaxYear: 2034}
+
+// Merge returns r with every field set in override replacing its own.
+// Forbidden words add to r's rather than replacing them, so a tenant cannot
+// lift a word the deployment bans.
+func (r BookValidationRules) Merge(override BookValidationRules) BookValidationRules {
+	if override.MinYear != 0 || override.MinYearOffset != nil {
+		r.MinYear, r.MinYearOffset = override.MinYear, override.MinYearOffset
+	}
+	if override.MaxYear != 0 || override.MaxYearOffset != nil {
+		r.MaxYear, r.MaxYearOffset = override.MaxYear, override.MaxYearOffset
+	}
+	r.Title = r.Title.merge(override.Title)
+	r.Author = r.Author.merge(override.Author)
+	return r
+}
+
+func (t TextRule) merge(override TextRule) TextRule {
+	if override.Required != nil {
+		t.Required = override.Required
+	}
+	if override.MinLength != 0 {
+		t.MinLength = override.MinLength
+	}
+	if override.MaxLength != 0 {
+		t.MaxLength = override.MaxLength
+	}
+	if override.Pattern != "" {
+		t.Pattern = override.Pattern
+	}
+	if len(override.ForbiddenWords) > 0 {
+		t.ForbiddenWords = append(append([]string(nil), t.ForbiddenWords...), override.ForbiddenWords...)
+	}
+	return t
+}
+
+// YearBounds returns the inclusive range of accepted years at now. Open
+// sides are math.MinInt and math.MaxInt.
+func (r BookValidationRules) YearBounds(now time.Time) (min, max int) {
+	min, max = math.MinInt, math.MaxInt
+	switch {
+	case r.MinYearOffset != nil:
+		min = now.Year() + *r.MinYearOffset
+	case r.MinYear != 0:
+		min = r.MinYear
+	}
+	switch {
+	case r.MaxYearOffset != nil:
+		max = now.Year() + *r.MaxYearOffset
+	case r.MaxYear != 0:
+		max = r.MaxYear
+	}
+	return min, max
+}
+
+// IsRequired reports whether the field must be present
+func (t TextRule) IsRequired() bool {
+	return t.Required == nil || *t.Required
+}
+
+// EffectiveMaxLength is MaxLength capped to what the column can store
+func (t TextRule) EffectiveMaxLength() int {
+	if t.MaxLength <= 0 || t.MaxLength > MaxBookTextLength {
+		return MaxBookTextLength
+	}
+	return t.MaxLength
+}
+
+// ValidateBook checks a book's fields against the rules at now and reports
+// every invalid field as a *ValidationError. It is the single place book
+// input is validated, whichever way it reaches the use case.
+func (r BookValidationRules) ValidateBook(title, author string, year int, now time.Time) error {
+	return r.check(title, author, year, now).err()
+}
+
+func (r BookValidationRules) check(title, author string, year int, now time.Time) fieldErrors {
+	var problems fieldErrors
+	r.Title.check(&problems, "/title", "title", title, ErrInvalidTitle)
+	r.Author.check(&problems, "/author", "author", author, ErrInvalidAuthor)
+	if min, max := r.YearBounds(now); year < min || year > max {
+		problems.add("/year", ErrInvalidYear, yearDetail(min, max))
+	}
+	return problems
+}
+
+func yearDetail(min, max int) string {
+	switch {
+	case min == math.MinInt:
+		return fmt.Sprintf("year must not be after %d", max)
+	case max == math.MaxInt:
+		return fmt.Sprintf("year must not be before %d", min)
+	}
+	return fmt.Sprintf("year must be between %d and %d", min, max)
+}
+
+// check records at most one failure for the field, the first rule it breaks
+func (t TextRule) check(problems *fieldErrors, pointer, name, value string, sentinel error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if t.IsRequired() {
+			problems.add(pointer, sentinel, name+" cannot be empty")
+		}
+		return
+	}
+
+	length := utf8.RuneCountInString(value)
+	switch {
+	case length < t.MinLength:
+		problems.add(pointer, sentinel, fmt.Sprintf("%s must be at least %d characters", name, t.MinLength))
+	case length > t.EffectiveMaxLength():
+		problems.add(pointer, sentinel, fmt.Sprintf("%s must be at most %d characters", name, t.EffectiveMaxLength()))
+	case t.Pattern != "" && !mustCompile(t.Pattern).MatchString(value):
+		problems.add(pointer, sentinel, fmt.Sprintf("%s must match the pattern %s", name, t.Pattern))
+	default:
+		for _, word := range t.ForbiddenWords {
+			if mustCompile(forbiddenWordPattern(word)).MatchString(value) {
+				problems.add(pointer, sentinel, fmt.Sprintf("%s contains the forbidden word %q", name, word))
+				return
+			}
+		}
+	}
+}
+
+// Validate checks the rules themselves, e.g. tenant settings before they
+// are stored. Pointers are relative to prefix.
+func (r BookValidationRules) Validate(prefix string) []FieldError {
+	var problems fieldErrors
+	if r.MinYearOffset == nil && r.MaxYearOffset == nil && r.MinYear != 0 && r.MaxYear != 0 && r.MinYear > r.MaxYear {
+		problems.add(prefix+"/min_year", ErrInvalidTenantSettings, "min_year must not be after max_year")
+	}
+	if r.MinYearOffset != nil && r.MaxYearOffset != nil && *r.MinYearOffset > *r.MaxYearOffset {
+		problems.add(prefix+"/min_year_offset", ErrInvalidTenantSettings, "min_year_offset must not be after max_year_offset")
+	}
+	r.Title.validate(&problems, prefix+"/title")
+	r.Author.validate(&problems, prefix+"/author")
+	return problems
+}
+
+func (t TextRule) validate(problems *fieldErrors, prefix string) {
+	if t.MinLength < 0 {
+		problems.add(prefix+"/min_length", ErrInvalidTenantSettings, "min_length must not be negative")
+	}
+	if t.MaxLength < 0 || t.MaxLength > MaxBookTextLength {
+		problems.add(prefix+"/max_length", ErrInvalidTenantSettings, fmt.Sprintf("max_length must be between 0 and %d", MaxBookTextLength))
+	}
+	if t.MinLength > t.EffectiveMaxLength() {
+		problems.add(prefix+"/min_length", ErrInvalidTenantSettings, "min_length must not exceed max_length")
+	}
+	if t.Pattern != "" {
+		if _, err := compile(t.Pattern); err != nil {
+			problems.add(prefix+"/pattern", ErrInvalidTenantSettings, fmt.Sprintf("pattern is not a valid regular expression: %v", err))
+		}
+	}
+	for i, word := range t.ForbiddenWords {
+		if strings.TrimSpace(word) == "" {
+			problems.add(fmt.Sprintf("%s/forbidden_words/%d", prefix, i), ErrInvalidTenantSettings, "forbidden words cannot be empty")
+		}
+	}
+}
+
+func forbiddenWordPattern(word string) string {
+	return `(?i)\b` + regexp.QuoteMeta(strings.TrimSpace(word)) + `\b`
+}
+
+// patterns caches compiled expressions; rules are checked on every write
+// and only a handful of distinct patterns exist per deployment
+var patterns sync.Map
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// mustCompile is for patterns that passed Validate. One that did not, e.g.
+// set directly in the database, matches nothing rather than panicking.
+func mustCompile(pattern string) *regexp.Regexp {
+	re, err := compile(pattern)
+	if err != nil {
+		return neverMatches
+	}
+	return re
+}
+
+var neverMatches = regexp.MustCompile(`[^\s\S]`)
@@ -0,0 +1,165 @@
+package entities
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestBookValidationRules_ValidateBook(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	optional := false
+
+	tests := []struct {
+		name    string
+		rules   BookValidationRules
+		title   string
+		author  string
+		year    int
+		pointer string
+		detail  string
+	}{
+		{
+			name:  "defaults accept a valid book",
+			rules: DefaultBookValidationRules,
+			title: "Dune", author: "Frank Herbert", year: 1965,
+		},
+		{
+			name:  "title longer than the column",
+			rules: DefaultBookValidationRules,
+			title: strings.Repeat("a", 256), author: "Frank Herbert", year: 1965,
+			pointer: "/title", detail: "title must be at most 255 characters",
+		},
+		{
+			name:  "lengths count characters, not bytes",
+			rules: DefaultBookValidationRules,
+			title: strings.Repeat("é", 255), author: "Frank Herbert", year: 1965,
+		},
+		{
+			name:  "configured max length",
+			rules: BookValidationRules{Author: TextRule{MaxLength: 5}},
+			title: "Dune", author: "Frank Herbert", year: 1965,
+			pointer: "/author", detail: "author must be at most 5 characters",
+		},
+		{
+			name:  "configured max length is capped to the column",
+			rules: BookValidationRules{Title: TextRule{MaxLength: 1000}},
+			title: strings.Repeat("a", 256), author: "Frank Herbert", year: 1965,
+			pointer: "/title", detail: "title must be at most 255 characters",
+		},
+		{
+			name:  "min length",
+			rules: BookValidationRules{Title: TextRule{MinLength: 5}},
+			title: " Dune ", author: "Frank Herbert", year: 1965,
+			pointer: "/title", detail: "title must be at least 5 characters",
+		},
+		{
+			name:  "optional author may be empty",
+			rules: BookValidationRules{Author: TextRule{Required: &optional}},
+			title: "Beowulf", author: "", year: 1000,
+		},
+		{
+			name:  "pattern",
+			rules: BookValidationRules{Title: TextRule{Pattern: `^[A-Z]`}},
+			title: "dune", author: "Frank Herbert", year: 1965,
+			pointer: "/title", detail: "title must match the pattern ^[A-Z]",
+		},
+		{
+			name:  "forbidden word ignores case",
+			rules: BookValidationRules{Title: TextRule{ForbiddenWords: []string{"draft"}}},
+			title: "Dune (DRAFT)", author: "Frank Herbert", year: 1965,
+			pointer: "/title", detail: `title contains the forbidden word "draft"`,
+		},
+		{
+			name:  "forbidden words match whole words only",
+			rules: BookValidationRules{Title: TextRule{ForbiddenWords: []string{"draft"}}},
+			title: "Draftsman", author: "Frank Herbert", year: 1965,
+		},
+		{
+			name:  "max year relative to the current year",
+			rules: BookValidationRules{MinYear: 1000, MaxYearOffset: intPtr(1)},
+			title: "Dune", author: "Frank Herbert", year: 2028,
+			pointer: "/year", detail: "year must be between 1000 and 2027",
+		},
+		{
+			name:  "offset takes precedence over the absolute bound",
+			rules: BookValidationRules{MinYear: 1000, MinYearOffset: intPtr(-10), MaxYear: 2100},
+			title: "Dune", author: "Frank Herbert", year: 2000,
+			pointer: "/year", detail: "year must be between 2016 and 2100",
+		},
+		{
+			name:  "open lower bound",
+			rules: BookValidationRules{MaxYear: 2000},
+			title: "Dune", author: "Frank Herbert", year: 2001,
+			pointer: "/year", detail: "year must not be after 2000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.ValidateBook(tt.title, tt.author, tt.year, now)
+
+			if tt.pointer == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var validation *ValidationError
+			if assert.True(t, errors.As(err, &validation)) {
+				assert.Equal(t, []FieldError{{Pointer: tt.pointer, Detail: tt.detail, Err: validation.Fields[0].Err}}, validation.Fields)
+			}
+		})
+	}
+}
+
+func TestBookValidationRules_Merge(t *testing.T) {
+	optional := false
+	base := BookValidationRules{
+		MinYear:       1000,
+		MaxYearOffset: intPtr(1),
+		Title:         TextRule{MaxLength: 200, ForbiddenWords: []string{"draft"}},
+	}
+
+	merged := base.Merge(BookValidationRules{
+		MaxYear: 2100,
+		Title:   TextRule{Pattern: `^\S`, ForbiddenWords: []string{"untitled"}},
+		Author:  TextRule{Required: &optional},
+	})
+
+	assert.Equal(t, 1000, merged.MinYear)
+	assert.Equal(t, 2100, merged.MaxYear)
+	assert.Nil(t, merged.MaxYearOffset, "an absolute bound replaces the inherited offset")
+	assert.Equal(t, 200, merged.Title.MaxLength)
+	assert.Equal(t, `^\S`, merged.Title.Pattern)
+	assert.Equal(t, []string{"draft", "untitled"}, merged.Title.ForbiddenWords)
+	assert.False(t, merged.Author.IsRequired())
+	assert.Equal(t, []string{"draft"}, base.Title.ForbiddenWords, "the base rules are not modified")
+}
+
+func TestBookValidationRules_Validate(t *testing.T) {
+	rules := BookValidationRules{
+		MinYear: 2000,
+		MaxYear: 1900,
+		Title:   TextRule{MaxLength: 300, Pattern: "[a-z"},
+		Author:  TextRule{MinLength: 10, MaxLength: 5, ForbiddenWords: []string{" "}},
+	}
+
+	var pointers []string
+	for _, field := range rules.Validate("/settings/validation") {
+		assert.ErrorIs(t, field.Err, ErrInvalidTenantSettings)
+		pointers = append(pointers, field.Pointer)
+	}
+
+	assert.Equal(t, []string{
+		"/settings/validation/min_year",
+		"/settings/validation/title/max_length",
+		"/settings/validation/title/pattern",
+		"/settings/validation/author/min_length",
+		"/settings/validation/author/forbidden_words/0",
+	}, pointers)
+	assert.Empty(t, DefaultBookValidationRules.Validate(""))
+}
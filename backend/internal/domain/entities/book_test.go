@@ -69,7 +69,7 @@ func TestCreateBookDTO_Validate(t *testing.T) {
 			err := tt.dto.Validate()
 			if tt.wantErr {
 				assert.Error(t, err)
-				assert.Equal(t, tt.errType, err)
+				assert.ErrorIs(t, err, tt.errType)
 			} else {
 				assert.NoError(t, err)
 			}
@@ -145,7 +145,7 @@ func TestBook_ValidateBookData(t *testing.T) {
 			err := tt.book.ValidateBookData()
 			if tt.wantErr {
 				assert.Error(t, err)
-				assert.Equal(t, tt.errType, err)
+				assert.ErrorIs(t, err, tt.errType)
 			} else {
 				assert.NoError(t, err)
 			}
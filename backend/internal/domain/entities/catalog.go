@@ -0,0 +1,57 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookQuery selects a page of the catalogue, newest books first. Filters
+// left empty match every book.
+type BookQuery struct {
+	// Search matches books whose title or author contains every word,
+	// ignoring case
+	Search string
+	// Author matches the author exactly
+	Author string
+	// Subject matches books with the subject among theirs
+	Subject string
+	// After continues the list past the book at the cursor, counting Offset
+	// from there
+	After  *BookCursor
+	Offset int
+	Limit  int
+}
+
+// BookCursor is the position of a book in the catalogue: books are ordered
+// by creation time, then ID, both descending
+type BookCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Cursor is the position of the book in the catalogue
+func (b *Book) Cursor() *BookCursor {
+	return &BookCursor{CreatedAt: b.CreatedAt, ID: b.ID}
+}
+
+// BookPage is a page of the books a BookQuery matches, and how many it
+// matches in all
+type BookPage struct {
+	Books []*Book
+	Total int
+}
+
+// Facet names a field the catalogue can be browsed by
+type Facet string
+
+const (
+	FacetAuthor  Facet = "author"
+	FacetSubject Facet = "subject"
+)
+
+// FacetValue is a value of a facet and the number of books that have it
+type FacetValue struct {
+	Value string `db:"value"`
+	Count int    `db:"count"`
+}
@@ -0,0 +1,76 @@
+package entities
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Reasons two books are considered duplicates
+const (
+	DuplicateReasonISBN    = "isbn"
+	DuplicateReasonSimilar = "similar"
+)
+
+// DuplicateCandidate is an existing book that a new one may duplicate.
+// Score is 1 for an ISBN match, otherwise the similarity of the normalized
+// titles and authors.
+type DuplicateCandidate struct {
+	Book   *Book   `json:"book"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// DuplicateMatch links two books of a DuplicateGroup
+type DuplicateMatch struct {
+	BookID      uuid.UUID `json:"book_id"`
+	DuplicateID uuid.UUID `json:"duplicate_id"`
+	Score       float64   `json:"score"`
+	Reason      string    `json:"reason"`
+}
+
+// DuplicateGroup is a set of books that appear to describe the same work,
+// oldest first, with the matches that connect them
+type DuplicateGroup struct {
+	Books   []*Book          `json:"books"`
+	Matches []DuplicateMatch `json:"matches"`
+}
+
+// DuplicateError rejects a new book that resembles existing ones unless the
+// caller forces its creation. errors.Is matches ErrPossibleDuplicate.
+type DuplicateError struct {
+	Candidates []DuplicateCandidate
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("book may duplicate %d existing book(s)", len(e.Candidates))
+}
+
+func (e *DuplicateError) Unwrap() error {
+	return ErrPossibleDuplicate
+}
+
+// MergeBooksDTO folds the duplicates into the survivor, which keeps its ID
+// and fields; it only takes an ISBN from a duplicate when it has none
+type MergeBooksDTO struct {
+	SurvivorID   uuid.UUID   `json:"survivor_id"`
+	DuplicateIDs []uuid.UUID `json:"duplicate_ids"`
+}
+
+func (dto *MergeBooksDTO) Validate() error {
+	var problems fieldErrors
+	if dto.SurvivorID == uuid.Nil {
+		problems.add("/survivor_id", ErrInvalidMerge, "survivor_id is required")
+	}
+	if len(dto.DuplicateIDs) == 0 {
+		problems.add("/duplicate_ids", ErrInvalidMerge, "duplicate_ids must list at least one book")
+	}
+	seen := map[uuid.UUID]bool{dto.SurvivorID: true}
+	for i, id := range dto.DuplicateIDs {
+		if seen[id] {
+			problems.add(fmt.Sprintf("/duplicate_ids/%d", i), ErrInvalidMerge, "duplicate_ids must be distinct and exclude the survivor")
+		}
+		seen[id] = true
+	}
+	return problems.err()
+}
@@ -1,12 +1,148 @@
 package entities
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
+
+// ErrorKind classifies domain errors by how a caller can react to them. The
+// delivery layers map kinds to HTTP statuses and gRPC codes, so a new error
+// only needs the right kind to be reported correctly everywhere.
+type ErrorKind string
+
+const (
+	KindInternal  ErrorKind = "internal"
+	KindNotFound  ErrorKind = "not_found"
+	KindInvalid   ErrorKind = "invalid"
+	KindConflict  ErrorKind = "conflict"
+	KindForbidden ErrorKind = "forbidden"
+	KindTimeout   ErrorKind = "timeout"
+	// KindUnavailable is for failures of an external service the request
+	// depends on, such as a bibliographic source
+	KindUnavailable ErrorKind = "unavailable"
+)
+
+// Error is a domain error of a known kind. The sentinels below are *Error
+// values; match them with errors.Is and their kind with KindOf.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(kind ErrorKind, message string) error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// KindOf returns the kind of the first domain error in err's tree, or
+// KindInternal for errors the domain does not know
+func KindOf(err error) ErrorKind {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Kind
+	}
+	return KindInternal
+}
 
 var (
-	ErrBookNotFound   = errors.New("book not found")
-	ErrInvalidTitle   = errors.New("title cannot be empty")
-	ErrInvalidAuthor  = errors.New("author cannot be empty")
-	ErrInvalidYear    = errors.New("year must be between 1000 and 2034")
-	ErrDatabaseError  = errors.New("database operation failed")
-	ErrInvalidUUID    = errors.New("invalid UUID format")
-)
\ No newline at end of file
+	ErrBookNotFound   = newError(KindNotFound, "book not found")
+	ErrInvalidTitle   = newError(KindInvalid, "title cannot be empty")
+	ErrInvalidAuthor  = newError(KindInvalid, "author cannot be empty")
+	ErrInvalidYear    = newError(KindInvalid, "year is out of range")
+	ErrDatabaseError  = newError(KindInternal, "database operation failed")
+	ErrInvalidUUID    = newError(KindInvalid, "invalid UUID format")
+	ErrRequestTimeout = newError(KindTimeout, "request deadline exceeded")
+	ErrInvalidISBN    = newError(KindInvalid, "isbn must be a valid ISBN-10 or ISBN-13")
+
+	ErrPossibleDuplicate = newError(KindConflict, "book may duplicate an existing book")
+	ErrInvalidMerge      = newError(KindInvalid, "merge needs a survivor and distinct duplicates")
+
+	ErrInvalidBookMetadata = newError(KindInvalid, "book metadata is invalid")
+	ErrInvalidLookup       = newError(KindInvalid, "lookup needs an isbn or a title")
+	ErrMetadataNotFound    = newError(KindNotFound, "no bibliographic record found for the book")
+	ErrMetadataUnavailable = newError(KindUnavailable, "bibliographic sources are unavailable")
+
+	ErrCoverNotFound     = newError(KindNotFound, "book has no cover")
+	ErrInvalidCoverImage = newError(KindInvalid, "cover must be a JPEG, PNG or WebP image")
+	ErrCoverTooLarge     = newError(KindInvalid, "cover image is too large")
+	ErrUnknownCoverSize  = newError(KindInvalid, "unknown cover size")
+
+	ErrBookFileNotFound    = newError(KindNotFound, "book file not found")
+	ErrUnsupportedBookFile = newError(KindInvalid, "file must be an EPUB or PDF")
+	ErrMalformedBookFile   = newError(KindInvalid, "file is malformed")
+	ErrBookFileTooLarge    = newError(KindInvalid, "file is too large")
+	ErrDuplicateBookFile   = newError(KindConflict, "file is already in the library")
+
+	ErrTenantNotFound        = newError(KindNotFound, "tenant not found")
+	ErrTenantSuspended       = newError(KindForbidden, "tenant is suspended")
+	ErrTenantRequired        = newError(KindInvalid, "tenant could not be resolved for request")
+	ErrTenantSlugTaken       = newError(KindConflict, "tenant slug already in use")
+	ErrInvalidTenantSlug     = newError(KindInvalid, "tenant slug must be lowercase letters, digits or dashes")
+	ErrInvalidTenantName     = newError(KindInvalid, "tenant name cannot be empty")
+	ErrInvalidTenantSettings = newError(KindInvalid, "tenant settings are invalid")
+	ErrDefaultTenantLocked   = newError(KindConflict, "default tenant cannot be suspended")
+
+	ErrIdempotencyKeyInFlight = newError(KindConflict, "a request with this idempotency key is in progress")
+	ErrIdempotencyKeyReused   = newError(KindConflict, "idempotency key was used with a different request")
+	ErrIdempotencyKeyLost     = newError(KindConflict, "idempotency key was taken over by another request")
+
+	ErrWebhookNotFound         = newError(KindNotFound, "webhook not found")
+	ErrWebhookDeliveryNotFound = newError(KindNotFound, "webhook delivery not found")
+	ErrWebhookDisabled         = newError(KindConflict, "webhook is disabled")
+	ErrInvalidWebhookURL       = newError(KindInvalid, "webhook url must be an absolute http or https url without credentials")
+	ErrInvalidWebhookEvents    = newError(KindInvalid, "webhook event_types must list book.created, book.updated or book.deleted")
+)
+
+// FieldError is one invalid field of an input. Pointer is the RFC 6901 JSON
+// pointer to the field in the request body, e.g. /settings/rate_limit_rps.
+type FieldError struct {
+	Pointer string `json:"pointer"`
+	Detail  string `json:"detail"`
+	// Err is the sentinel the field failed with
+	Err error `json:"-"`
+}
+
+// ValidationError lists every invalid field of an input rather than only
+// the first. errors.Is matches the sentinel of any of its fields, and its
+// kind is KindInvalid.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	details := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		details[i] = field.Detail
+	}
+	return strings.Join(details, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, field := range e.Fields {
+		errs[i] = field.Err
+	}
+	return errs
+}
+
+// fieldErrors accumulates the failures of one validation pass
+type fieldErrors []FieldError
+
+// add records that the field at pointer failed with err; detail defaults to
+// the error's message
+func (f *fieldErrors) add(pointer string, err error, detail string) {
+	if detail == "" {
+		detail = err.Error()
+	}
+	*f = append(*f, FieldError{Pointer: pointer, Detail: detail, Err: err})
+}
+
+func (f fieldErrors) err() error {
+	if len(f) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: f}
+}
@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HarvestQuery selects the records changed within [From, Before), oldest
+// first, for metadata harvesting. Zero times leave the range open.
+type HarvestQuery struct {
+	From   time.Time
+	Before time.Time
+	// After continues a harvest past the last record it returned
+	After *HarvestCursor
+	Limit int
+}
+
+// HarvestCursor is the position of a record in a harvest: records are
+// ordered by datestamp, then ID
+type HarvestCursor struct {
+	Datestamp time.Time
+	ID        uuid.UUID
+}
+
+// HarvestRecord is a book as harvested: its last change, and the book
+// itself unless it has been deleted
+type HarvestRecord struct {
+	ID uuid.UUID
+	// Datestamp is when the book was last updated, or deleted
+	Datestamp time.Time
+	Deleted   bool
+	// Book is nil for a deleted book
+	Book *Book
+}
+
+// Cursor is the position of the record in a harvest
+func (r *HarvestRecord) Cursor() *HarvestCursor {
+	return &HarvestCursor{Datestamp: r.Datestamp, ID: r.ID}
+}
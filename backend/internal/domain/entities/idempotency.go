@@ -0,0 +1,61 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type IdempotencyStatus string
+
+const (
+	IdempotencyStatusInFlight  IdempotencyStatus = "in_flight"
+	IdempotencyStatusCompleted IdempotencyStatus = "completed"
+)
+
+// IdempotencyRecord stores the fingerprint of a request made with an
+// Idempotency-Key and, once completed, the response to replay on retries
+type IdempotencyRecord struct {
+	TenantID    uuid.UUID `db:"tenant_id"`
+	Key         string    `db:"idempotency_key"`
+	Method      string    `db:"method"`
+	Path        string    `db:"path"`
+	RequestHash string    `db:"request_hash"`
+	// LockID identifies the request holding the key, so that one whose
+	// claim lapsed cannot complete or release the key of the next
+	LockID          uuid.UUID         `db:"lock_id"`
+	Status          IdempotencyStatus `db:"status"`
+	ResponseStatus  *int              `db:"response_status"`
+	ResponseHeaders StoredHeaders     `db:"response_headers"`
+	ResponseBody    []byte            `db:"response_body"`
+	CreatedAt       time.Time         `db:"created_at"`
+	ExpiresAt       time.Time         `db:"expires_at"`
+}
+
+// StoredHeaders persists response headers as JSONB
+type StoredHeaders http.Header
+
+func (h StoredHeaders) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	return json.Marshal(h)
+}
+
+func (h *StoredHeaders) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	default:
+		return fmt.Errorf("unsupported type %T for stored headers", src)
+	}
+}
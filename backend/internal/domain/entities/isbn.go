@@ -0,0 +1,71 @@
+package entities
+
+import "strings"
+
+// NormalizeISBN returns isbn as the 13 digits of an ISBN-13. ISBN-10s are
+// converted, and hyphens and spaces are ignored. The check digit must be
+// valid.
+func NormalizeISBN(isbn string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch {
+		case r == '-' || r == ' ':
+			return -1
+		case r == 'x':
+			return 'X'
+		}
+		return r
+	}, isbn)
+
+	switch len(digits) {
+	case 10:
+		if !validISBN10(digits) {
+			return "", ErrInvalidISBN
+		}
+		isbn13 := "978" + digits[:9]
+		return isbn13 + isbn13CheckDigit(isbn13), nil
+	case 13:
+		if !allDigits(digits) || isbn13CheckDigit(digits[:12]) != digits[12:] {
+			return "", ErrInvalidISBN
+		}
+		return digits, nil
+	}
+	return "", ErrInvalidISBN
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func validISBN10(digits string) bool {
+	if !allDigits(digits[:9]) {
+		return false
+	}
+	sum := 0
+	for i, r := range digits {
+		value := int(r - '0')
+		if r == 'X' && i == 9 {
+			value = 10
+		} else if r < '0' || r > '9' {
+			return false
+		}
+		sum += value * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+func isbn13CheckDigit(first12 string) string {
+	sum := 0
+	for i, r := range first12 {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(r-'0') * weight
+	}
+	return string(rune('0' + (10-sum%10)%10))
+}
@@ -0,0 +1,206 @@
+package entities
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// MaxBookSubjects bounds the subjects kept for a book
+const MaxBookSubjects = 50
+
+// BookMetadata is the descriptive part of a book that librarians rarely
+// type in themselves; it is usually filled from a bibliographic source.
+// Every field is optional.
+type BookMetadata struct {
+	Publisher   string         `json:"publisher,omitempty" db:"publisher"`
+	PageCount   int            `json:"page_count,omitempty" db:"page_count"`
+	Subjects    pq.StringArray `json:"subjects,omitempty" db:"subjects" swaggertype:"array,string"`
+	Description string         `json:"description,omitempty" db:"description"`
+	CoverURL    string         `json:"cover_url,omitempty" db:"cover_url"`
+}
+
+// BookMetadataUpdate is the metadata of an update; the fields left out
+// (nil) keep their stored values
+type BookMetadataUpdate struct {
+	Publisher   *string   `json:"publisher,omitempty" swaggertype:"string"`
+	PageCount   *int      `json:"page_count,omitempty" swaggertype:"integer"`
+	Subjects    *[]string `json:"subjects,omitempty" swaggertype:"array,string"`
+	Description *string   `json:"description,omitempty" swaggertype:"string"`
+	CoverURL    *string   `json:"cover_url,omitempty" swaggertype:"string"`
+}
+
+// values is the update as metadata, with the fields left out empty
+func (u BookMetadataUpdate) values() BookMetadata {
+	var m BookMetadata
+	if u.Publisher != nil {
+		m.Publisher = *u.Publisher
+	}
+	if u.PageCount != nil {
+		m.PageCount = *u.PageCount
+	}
+	if u.Subjects != nil {
+		m.Subjects = *u.Subjects
+	}
+	if u.Description != nil {
+		m.Description = *u.Description
+	}
+	if u.CoverURL != nil {
+		m.CoverURL = *u.CoverURL
+	}
+	return m
+}
+
+// applyTo sets the fields of m that the update carries, normalized
+func (u BookMetadataUpdate) applyTo(m *BookMetadata) {
+	values := u.values().normalized()
+	if u.Publisher != nil {
+		m.Publisher = values.Publisher
+	}
+	if u.PageCount != nil {
+		m.PageCount = values.PageCount
+	}
+	if u.Subjects != nil {
+		m.Subjects = values.Subjects
+	}
+	if u.Description != nil {
+		m.Description = values.Description
+	}
+	if u.CoverURL != nil {
+		m.CoverURL = values.CoverURL
+	}
+}
+
+// normalized trims the fields and drops blank and repeated subjects
+func (m BookMetadata) normalized() BookMetadata {
+	m.Publisher = strings.TrimSpace(m.Publisher)
+	m.Description = strings.TrimSpace(m.Description)
+	m.CoverURL = strings.TrimSpace(m.CoverURL)
+
+	var subjects pq.StringArray
+	seen := map[string]bool{}
+	for _, subject := range m.Subjects {
+		subject = strings.TrimSpace(subject)
+		if subject == "" || seen[strings.ToLower(subject)] {
+			continue
+		}
+		seen[strings.ToLower(subject)] = true
+		subjects = append(subjects, subject)
+	}
+	m.Subjects = subjects
+	return m
+}
+
+func (m BookMetadata) check(problems *fieldErrors) {
+	if utf8.RuneCountInString(strings.TrimSpace(m.Publisher)) > MaxBookTextLength {
+		problems.add("/publisher", ErrInvalidBookMetadata, fmt.Sprintf("publisher must be at most %d characters", MaxBookTextLength))
+	}
+	if m.PageCount < 0 {
+		problems.add("/page_count", ErrInvalidBookMetadata, "page_count must not be negative")
+	}
+	if len(m.Subjects) > MaxBookSubjects {
+		problems.add("/subjects", ErrInvalidBookMetadata, fmt.Sprintf("subjects must list at most %d subjects", MaxBookSubjects))
+	}
+	for i, subject := range m.Subjects {
+		if utf8.RuneCountInString(strings.TrimSpace(subject)) > MaxBookTextLength {
+			problems.add(fmt.Sprintf("/subjects/%d", i), ErrInvalidBookMetadata, fmt.Sprintf("subjects must be at most %d characters", MaxBookTextLength))
+		}
+	}
+	if cover := strings.TrimSpace(m.CoverURL); cover != "" && !webURL(cover) {
+		problems.add("/cover_url", ErrInvalidBookMetadata, "cover_url must be an absolute http or https url")
+	}
+}
+
+// Sanitized returns m normalized and cut down until it passes validation,
+// for metadata from sources the library does not control: long texts and
+// subject lists are truncated and an unusable cover URL is dropped
+func (m BookMetadata) Sanitized() BookMetadata {
+	m = m.normalized()
+	m.Publisher = truncate(m.Publisher, MaxBookTextLength)
+	if m.PageCount < 0 {
+		m.PageCount = 0
+	}
+	if len(m.Subjects) > MaxBookSubjects {
+		m.Subjects = m.Subjects[:MaxBookSubjects]
+	}
+	for i, subject := range m.Subjects {
+		m.Subjects[i] = truncate(subject, MaxBookTextLength)
+	}
+	if m.CoverURL != "" && !webURL(m.CoverURL) {
+		m.CoverURL = ""
+	}
+	return m
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:max]))
+}
+
+// FillMetadata sets the ISBN and metadata fields the book lacks from a
+// bibliographic source, leaving what the library entered alone. It reports
+// whether anything changed.
+func (b *Book) FillMetadata(isbn string, metadata BookMetadata) bool {
+	changed := false
+	fill := func(field *string, value string) {
+		if *field == "" && value != "" {
+			*field, changed = value, true
+		}
+	}
+	fill(&b.ISBN, isbn)
+	fill(&b.Publisher, metadata.Publisher)
+	fill(&b.Description, metadata.Description)
+	fill(&b.CoverURL, metadata.CoverURL)
+	if b.PageCount == 0 && metadata.PageCount > 0 {
+		b.PageCount, changed = metadata.PageCount, true
+	}
+	if len(b.Subjects) == 0 && len(metadata.Subjects) > 0 {
+		b.Subjects, changed = metadata.Subjects, true
+	}
+	return changed
+}
+
+func webURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// MetadataQuery asks bibliographic sources about a book, by ISBN when it is
+// known and otherwise by title and, optionally, author
+type MetadataQuery struct {
+	ISBN   string `json:"isbn,omitempty" query:"isbn"`
+	Title  string `json:"title,omitempty" query:"title"`
+	Author string `json:"author,omitempty" query:"author"`
+}
+
+// Validate checks that the query can be answered and normalizes its ISBN
+func (q *MetadataQuery) Validate() error {
+	var problems fieldErrors
+	q.ISBN, q.Title, q.Author = strings.TrimSpace(q.ISBN), strings.TrimSpace(q.Title), strings.TrimSpace(q.Author)
+	switch {
+	case q.ISBN != "":
+		isbn, err := NormalizeISBN(q.ISBN)
+		if err != nil {
+			problems.add("/isbn", ErrInvalidISBN, "")
+		}
+		q.ISBN = isbn
+	case q.Title == "":
+		problems.add("/title", ErrInvalidLookup, "")
+	}
+	return problems.err()
+}
+
+// BookLookup is what the bibliographic sources know about a book that is
+// not in the library yet, as a CreateBookDTO to review and submit. Covers
+// holds the cover image URLs by size ("small", "medium", "large").
+type BookLookup struct {
+	Book   CreateBookDTO     `json:"book"`
+	Covers map[string]string `json:"covers,omitempty"`
+	// Sources names the providers the data came from, e.g. "openlibrary"
+	Sources []string `json:"sources"`
+}
@@ -0,0 +1,134 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TenantStatus string
+
+const (
+	TenantStatusActive    TenantStatus = "active"
+	TenantStatusSuspended TenantStatus = "suspended"
+)
+
+// DefaultTenantID is the tenant seeded by the migrations and used when multi-tenancy is disabled
+var DefaultTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+type Tenant struct {
+	ID         uuid.UUID      `json:"id" db:"id"`
+	Slug       string         `json:"slug" db:"slug"`
+	Name       string         `json:"name" db:"name"`
+	Status     TenantStatus   `json:"status" db:"status"`
+	APIKeyHash string         `json:"-" db:"api_key_hash"`
+	Settings   TenantSettings `json:"settings" db:"settings"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// TenantSettings holds per-tenant overrides of the deployment configuration.
+// Zero values mean "use the deployment default".
+type TenantSettings struct {
+	CORSOrigins    []string             `json:"cors_origins,omitempty"`
+	RateLimitRPS   int                  `json:"rate_limit_rps,omitempty"`
+	RateLimitBurst int                  `json:"rate_limit_burst,omitempty"`
+	Validation     *BookValidationRules `json:"validation,omitempty"`
+}
+
+// Value stores the settings as JSONB
+func (s TenantSettings) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan reads the settings from a JSONB column
+func (s *TenantSettings) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = TenantSettings{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("unsupported type %T for tenant settings", src)
+	}
+}
+
+type CreateTenantDTO struct {
+	Slug     string         `json:"slug" validate:"required"`
+	Name     string         `json:"name" validate:"required"`
+	Settings TenantSettings `json:"settings"`
+}
+
+type UpdateTenantSettingsDTO struct {
+	Settings TenantSettings `json:"settings"`
+}
+
+// TenantWithAPIKey is returned once on tenant creation; the plain key is never stored
+type TenantWithAPIKey struct {
+	*Tenant
+	APIKey string `json:"api_key"`
+}
+
+func (dto *CreateTenantDTO) Validate() error {
+	var problems fieldErrors
+	if !tenantSlugPattern.MatchString(strings.TrimSpace(dto.Slug)) {
+		problems.add("/slug", ErrInvalidTenantSlug, "")
+	}
+	if strings.TrimSpace(dto.Name) == "" {
+		problems.add("/name", ErrInvalidTenantName, "")
+	}
+	dto.Settings.validate(&problems)
+	return problems.err()
+}
+
+func (s *TenantSettings) Validate() error {
+	var problems fieldErrors
+	s.validate(&problems)
+	return problems.err()
+}
+
+// validate records invalid settings; pointers are relative to the request
+// bodies, which carry the settings under "settings"
+func (s *TenantSettings) validate(problems *fieldErrors) {
+	if s.RateLimitRPS < 0 {
+		problems.add("/settings/rate_limit_rps", ErrInvalidTenantSettings, "rate_limit_rps must not be negative")
+	}
+	if s.RateLimitBurst < 0 {
+		problems.add("/settings/rate_limit_burst", ErrInvalidTenantSettings, "rate_limit_burst must not be negative")
+	}
+	if s.Validation != nil {
+		*problems = append(*problems, s.Validation.Validate("/settings/validation")...)
+	}
+}
+
+func (dto *CreateTenantDTO) ToTenant() *Tenant {
+	return &Tenant{
+		ID:       uuid.New(),
+		Slug:     strings.TrimSpace(dto.Slug),
+		Name:     strings.TrimSpace(dto.Name),
+		Status:   TenantStatusActive,
+		Settings: dto.Settings,
+	}
+}
+
+func (t *Tenant) IsActive() bool {
+	return t.Status == TenantStatusActive
+}
+
+// HashAPIKey returns the hex encoded SHA-256 digest stored for tenant API keys
+func HashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,168 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// WebhookEventTypes lists the events a webhook may subscribe to
+var WebhookEventTypes = []string{"book.created", "book.updated", "book.deleted"}
+
+// Webhook is a partner endpoint notified of catalogue changes. DisabledAt is
+// set once deliveries have failed too many times in a row.
+type Webhook struct {
+	ID                  uuid.UUID      `json:"id" db:"id"`
+	TenantID            uuid.UUID      `json:"-" db:"tenant_id"`
+	URL                 string         `json:"url" db:"url"`
+	EventTypes          pq.StringArray `json:"event_types" db:"event_types" swaggertype:"array,string"`
+	Secret              string         `json:"-" db:"secret"`
+	Active              bool           `json:"active" db:"active"`
+	ConsecutiveFailures int            `json:"consecutive_failures" db:"consecutive_failures"`
+	DisabledAt          *time.Time     `json:"disabled_at,omitempty" db:"disabled_at"`
+	CreatedAt           time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// Subscribes reports whether the webhook wants events of eventType
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookWithSecret is returned once on creation; the secret signs deliveries
+type WebhookWithSecret struct {
+	*Webhook
+	Secret string `json:"secret"`
+}
+
+type CreateWebhookDTO struct {
+	URL        string   `json:"url" validate:"required"`
+	EventTypes []string `json:"event_types" validate:"required"`
+}
+
+// UpdateWebhookDTO replaces the URL and event types; setting Active to true
+// re-enables a webhook that was disabled after repeated failures
+type UpdateWebhookDTO struct {
+	URL        string   `json:"url" validate:"required"`
+	EventTypes []string `json:"event_types" validate:"required"`
+	Active     *bool    `json:"active,omitempty"`
+}
+
+func (dto *CreateWebhookDTO) Validate() error {
+	return validateWebhook(dto.URL, dto.EventTypes)
+}
+
+func (dto *UpdateWebhookDTO) Validate() error {
+	return validateWebhook(dto.URL, dto.EventTypes)
+}
+
+func validateWebhook(rawURL string, eventTypes []string) error {
+	var problems fieldErrors
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		problems.add("/url", ErrInvalidWebhookURL, "")
+	}
+	if len(eventTypes) == 0 {
+		problems.add("/event_types", ErrInvalidWebhookEvents, "")
+	}
+	for i, eventType := range eventTypes {
+		if !isWebhookEventType(eventType) {
+			problems.add(fmt.Sprintf("/event_types/%d", i), ErrInvalidWebhookEvents,
+				fmt.Sprintf("%q is not one of book.created, book.updated or book.deleted", eventType))
+		}
+	}
+	return problems.err()
+}
+
+func isWebhookEventType(eventType string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent (or to be sent) to a webhook, with the
+// outcome of its latest attempt. Pending deliveries are retried at
+// NextAttemptAt until they succeed or run out of attempts.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id" db:"id"`
+	WebhookID      uuid.UUID             `json:"webhook_id" db:"webhook_id"`
+	TenantID       uuid.UUID             `json:"-" db:"tenant_id"`
+	EventID        uuid.UUID             `json:"event_id" db:"event_id"`
+	EventType      string                `json:"event_type" db:"event_type"`
+	Payload        json.RawMessage       `json:"payload" db:"payload" swaggertype:"object"`
+	RequestHeaders WebhookHeaders        `json:"request_headers,omitempty" db:"request_headers"`
+	Status         WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts       int                   `json:"attempts" db:"attempts"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	ResponseStatus *int                  `json:"response_status,omitempty" db:"response_status"`
+	ResponseBody   *string               `json:"response_body,omitempty" db:"response_body"`
+	Error          *string               `json:"error,omitempty" db:"error"`
+	DurationMS     *int64                `json:"duration_ms,omitempty" db:"duration_ms"`
+	// RedeliveryOf points at the delivery this one repeats on request
+	RedeliveryOf *uuid.UUID `json:"redelivery_of,omitempty" db:"redelivery_of"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// WebhookHeaders are the headers sent with the latest attempt, kept for the
+// delivery log
+type WebhookHeaders map[string]string
+
+// Value stores the headers in a JSONB column
+func (h WebhookHeaders) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	return json.Marshal(h)
+}
+
+// Scan reads the headers from a JSONB column
+func (h *WebhookHeaders) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	default:
+		return fmt.Errorf("unsupported type %T for webhook headers", src)
+	}
+}
+
+// WebhookAttempt is the result of one HTTP attempt of a delivery
+type WebhookAttempt struct {
+	RequestHeaders WebhookHeaders
+	ResponseStatus int
+	ResponseBody   string
+	Error          string
+	Duration       time.Duration
+}
+
+// Succeeded reports whether the receiver answered with a 2xx status
+func (a *WebhookAttempt) Succeeded() bool {
+	return a.Error == "" && a.ResponseStatus >= 200 && a.ResponseStatus < 300
+}
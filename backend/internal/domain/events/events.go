@@ -0,0 +1,86 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+// Event types published for book changes
+const (
+	BookCreated = "book.created"
+	BookUpdated = "book.updated"
+	BookDeleted = "book.deleted"
+)
+
+const AggregateBook = "book"
+
+// Event is a domain event as stored in the outbox and delivered to sinks.
+// Consumers must tolerate duplicates, which they can detect by ID.
+type Event struct {
+	ID            uuid.UUID       `json:"id" db:"id"`
+	Type          string          `json:"type" db:"event_type"`
+	AggregateType string          `json:"aggregate_type" db:"aggregate_type"`
+	AggregateID   uuid.UUID       `json:"aggregate_id" db:"aggregate_id"`
+	TenantID      uuid.UUID       `json:"tenant_id" db:"tenant_id"`
+	OccurredAt    time.Time       `json:"occurred_at" db:"occurred_at"`
+	Data          json.RawMessage `json:"data" db:"payload"`
+
+	// Sequence orders events in the outbox; Attempts counts failed deliveries
+	Sequence int64 `json:"sequence" db:"sequence"`
+	Attempts int   `json:"-" db:"attempts"`
+}
+
+// BookDeletedData is the payload of BookDeleted. MergedInto is set when the
+// book was merged into another, which consumers should now refer to.
+type BookDeletedData struct {
+	ID         uuid.UUID  `json:"id"`
+	MergedInto *uuid.UUID `json:"merged_into,omitempty"`
+}
+
+// newEvent wraps data, a book or a payload type from this package; neither
+// can fail to marshal
+func newEvent(eventType string, aggregateID, tenantID uuid.UUID, data interface{}) *Event {
+	payload, _ := json.Marshal(data)
+	return &Event{
+		ID:            uuid.New(),
+		Type:          eventType,
+		AggregateType: AggregateBook,
+		AggregateID:   aggregateID,
+		TenantID:      tenantID,
+		OccurredAt:    time.Now().UTC(),
+		Data:          payload,
+	}
+}
+
+// NewBookCreated records a new book; the payload is the book as returned by the API
+func NewBookCreated(book *entities.Book) *Event {
+	return newEvent(BookCreated, book.ID, book.TenantID, book)
+}
+
+// NewBookUpdated records the book's state after an update
+func NewBookUpdated(book *entities.Book) *Event {
+	return newEvent(BookUpdated, book.ID, book.TenantID, book)
+}
+
+// NewBookDeleted records that a book no longer exists
+func NewBookDeleted(tenantID, id uuid.UUID) *Event {
+	return newEvent(BookDeleted, id, tenantID, BookDeletedData{ID: id})
+}
+
+// NewBookMerged records that a book was merged into survivorID and no
+// longer exists
+func NewBookMerged(tenantID, id, survivorID uuid.UUID) *Event {
+	return newEvent(BookDeleted, id, tenantID, BookDeletedData{ID: id, MergedInto: &survivorID})
+}
+
+// Outcome is the result of one delivery attempt. Failed events are retried
+// after RetryAfter.
+type Outcome struct {
+	EventID    uuid.UUID
+	Published  bool
+	Error      string
+	RetryAfter time.Duration
+}
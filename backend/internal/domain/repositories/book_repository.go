@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"time"
 
+	"byfood-library/internal/cql"
 	"byfood-library/internal/domain/entities"
 	"github.com/google/uuid"
 )
@@ -10,7 +12,61 @@ import (
 type BookRepository interface {
 	Create(ctx context.Context, book *entities.Book) (*entities.Book, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*entities.Book, error)
+	// GetByIDForUpdate reads the book from the database, never a cache, and
+	// locks it until the transaction in ctx ends, so that a read-modify-write
+	// cannot lose a concurrent change
+	GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entities.Book, error)
+	// GetByIDs returns the books among ids, in no particular order; missing
+	// books are left out rather than reported
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error)
+	// GetByIDsForUpdate is GetByIDs with the locks of GetByIDForUpdate,
+	// taken in id order so that concurrent callers cannot deadlock
+	GetByIDsForUpdate(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error)
 	GetAll(ctx context.Context) ([]*entities.Book, error)
 	Update(ctx context.Context, id uuid.UUID, book *entities.Book) (*entities.Book, error)
+	// SetCover records the version of the book's uploaded cover image, or
+	// that it has none when etag is empty
+	SetCover(ctx context.Context, id uuid.UUID, etag string) (*entities.Book, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	Count(ctx context.Context) (int, error)
+	// FindSimilar returns up to limit books, other than book itself, that
+	// share its ISBN or have a trigram-similar title, most similar first.
+	// They are candidates for the caller to score.
+	FindSimilar(ctx context.Context, book *entities.Book, limit int) ([]*entities.Book, error)
+	// FindSimilarPairs returns up to limit such candidate pairs across the
+	// tenant's catalogue, the older book of each pair first
+	FindSimilarPairs(ctx context.Context, limit int) ([][2]*entities.Book, error)
+	// Search returns the page of books the query selects
+	Search(ctx context.Context, query entities.BookQuery) (*entities.BookPage, error)
+	// SearchCQL returns the page of books a CQL query selects, or a
+	// *cql.UnsupportedError if the query uses what cannot be searched
+	SearchCQL(ctx context.Context, query *cql.Query, offset, limit int) (*entities.BookPage, error)
+	// FacetValues returns up to limit values of the facet, most common first
+	FacetValues(ctx context.Context, facet entities.Facet, limit int) ([]entities.FacetValue, error)
+	// Harvest returns up to Limit records, books and deleted books, in
+	// harvest order
+	Harvest(ctx context.Context, query entities.HarvestQuery) ([]*entities.HarvestRecord, error)
+	// GetHarvestRecord returns the book's record, deleted or not, or
+	// ErrBookNotFound if there never was such a book
+	GetHarvestRecord(ctx context.Context, id uuid.UUID) (*entities.HarvestRecord, error)
+	// EarliestDatestamp returns the oldest datestamp of any record, or the
+	// zero time without any
+	EarliestDatestamp(ctx context.Context) (time.Time, error)
+
+	// AddFile records an ebook file of a book; a file with the same checksum
+	// fails with ErrDuplicateBookFile
+	AddFile(ctx context.Context, file *entities.BookFile) (*entities.BookFile, error)
+	// ListFiles returns the book's files, oldest first
+	ListFiles(ctx context.Context, bookID uuid.UUID) ([]*entities.BookFile, error)
+	// ListFilesOf returns the files of all the given books, oldest first
+	ListFilesOf(ctx context.Context, bookIDs []uuid.UUID) ([]*entities.BookFile, error)
+	GetFile(ctx context.Context, bookID, fileID uuid.UUID) (*entities.BookFile, error)
+	// FindFileByChecksum returns the tenant's file with the given SHA-256, or
+	// ErrBookFileNotFound
+	FindFileByChecksum(ctx context.Context, checksum string) (*entities.BookFile, error)
+	DeleteFile(ctx context.Context, bookID, fileID uuid.UUID) error
+	// MoveFiles attaches the files of the books in from to the book to
+	MoveFiles(ctx context.Context, from []uuid.UUID, to uuid.UUID) error
+	// SetFileStorageKey records where the file's content has been moved
+	SetFileStorageKey(ctx context.Context, fileID uuid.UUID, key string) error
 }
\ No newline at end of file
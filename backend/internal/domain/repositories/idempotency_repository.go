@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+type IdempotencyRepository interface {
+	// Acquire claims the key for record. It returns (record, true) when the
+	// caller now owns the key, or the existing unexpired record and false.
+	// The in-flight claim lapses at record.ExpiresAt so a crashed request
+	// does not block retries until the full TTL.
+	Acquire(ctx context.Context, record *entities.IdempotencyRecord) (*entities.IdempotencyRecord, bool, error)
+	// Complete and Release act only on the in-flight claim made with
+	// lockID; once it has lapsed and another request took the key over,
+	// they return ErrIdempotencyKeyLost and leave the key alone.
+	Complete(ctx context.Context, tenantID uuid.UUID, key string, lockID uuid.UUID, status int, headers http.Header, body []byte, expiresAt time.Time) error
+	Release(ctx context.Context, tenantID uuid.UUID, key string, lockID uuid.UUID) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"byfood-library/internal/domain/events"
+)
+
+// Transactor runs fn in one database transaction, bound to the tenant from
+// ctx. Repository calls made with the ctx passed to fn join the transaction.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type OutboxRepository interface {
+	// Append stores events for the tenant in ctx; called within WithinTx
+	// so that events commit or roll back with the change they describe
+	Append(ctx context.Context, evts ...*events.Event) error
+	// Relay locks the outbox against other relays, loads up to limit due
+	// events in sequence order and saves the outcomes returned by deliver.
+	// Events queued behind an aggregate's failed event are not loaded until
+	// it is delivered. ok is false when another relay holds the lock.
+	Relay(ctx context.Context, limit int, deliver func(ctx context.Context, batch []*events.Event) []events.Outcome) (ok bool, err error)
+	// DeletePublished removes events published more than olderThan ago
+	DeletePublished(ctx context.Context, olderThan time.Duration) (int64, error)
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+type TenantRepository interface {
+	Create(ctx context.Context, tenant *entities.Tenant) (*entities.Tenant, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.Tenant, error)
+	GetBySlug(ctx context.Context, slug string) (*entities.Tenant, error)
+	GetByAPIKeyHash(ctx context.Context, hash string) (*entities.Tenant, error)
+	GetAll(ctx context.Context) ([]*entities.Tenant, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status entities.TenantStatus) (*entities.Tenant, error)
+	UpdateSettings(ctx context.Context, id uuid.UUID, settings entities.TenantSettings) (*entities.Tenant, error)
+}
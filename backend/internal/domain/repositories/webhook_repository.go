@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+// WebhookRepository stores webhooks and their delivery log. Webhook and log
+// methods are scoped to the tenant in ctx; ClaimDue and the Record methods
+// serve the dispatcher across tenants.
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *entities.Webhook) (*entities.Webhook, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*entities.Webhook, error)
+	GetAll(ctx context.Context) ([]*entities.Webhook, error)
+	Update(ctx context.Context, webhook *entities.Webhook) (*entities.Webhook, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	// ListSubscribed returns the tenant's active webhooks for eventType
+	ListSubscribed(ctx context.Context, eventType string) ([]*entities.Webhook, error)
+
+	// Enqueue queues deliveries; an event already queued for a webhook is
+	// skipped unless the delivery is a redelivery
+	Enqueue(ctx context.Context, deliveries ...*entities.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*entities.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, webhookID, id uuid.UUID) (*entities.WebhookDelivery, error)
+
+	// ClaimDue returns up to limit pending deliveries of active webhooks that
+	// are due, and pushes their next attempt lease into the future so other
+	// dispatchers skip them while they are in flight
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*entities.WebhookDelivery, error)
+	// RecordAttempt saves the outcome of an attempt. A nil retryAt ends the
+	// delivery as succeeded or failed; otherwise it stays pending until then.
+	RecordAttempt(ctx context.Context, id uuid.UUID, attempt *entities.WebhookAttempt, retryAt *time.Time) error
+	// RecordResult resets or increments the webhook's consecutive failures
+	// and disables it once they reach disableAfter. It reports whether the
+	// webhook was disabled by this call.
+	RecordResult(ctx context.Context, webhookID uuid.UUID, succeeded bool, disableAfter int) (disabled bool, err error)
+}
@@ -0,0 +1,220 @@
+// Package duplicates decides whether book records describe the same work.
+// Candidates are found by the repository (same ISBN or trigram-similar
+// title); this package scores them on normalized titles and authors so that
+// "Clean Code / Robert Martin" and "Clean code / Robert C. Martin" match.
+package duplicates
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+// Config tunes the detector. Zero values use the defaults.
+type Config struct {
+	// Threshold is the minimum score, between 0 and 1, of a duplicate
+	Threshold float64
+	// Limit bounds the candidates reported for a new book
+	Limit int
+}
+
+const (
+	DefaultThreshold = 0.8
+	DefaultLimit     = 5
+
+	// titleWeight is the share of the title in the score; the author makes
+	// up the rest
+	titleWeight = 0.7
+)
+
+// Detector scores possible duplicates
+type Detector struct {
+	config Config
+}
+
+func NewDetector(config Config) *Detector {
+	if config.Threshold <= 0 {
+		config.Threshold = DefaultThreshold
+	}
+	if config.Limit <= 0 {
+		config.Limit = DefaultLimit
+	}
+	return &Detector{config: config}
+}
+
+// Limit is the number of candidates worth fetching for a new book
+func (d *Detector) Limit() int {
+	return d.config.Limit
+}
+
+// Match scores b against a. Books with the same ISBN always match, books
+// with different ISBNs are different editions and never do, and otherwise
+// the normalized title and author similarity must reach the threshold.
+func (d *Detector) Match(a, b *entities.Book) (score float64, reason string, ok bool) {
+	if a.ISBN != "" && b.ISBN != "" {
+		if a.ISBN == b.ISBN {
+			return 1, entities.DuplicateReasonISBN, true
+		}
+		return 0, "", false
+	}
+
+	score = titleWeight*Similarity(NormalizeTitle(a.Title), NormalizeTitle(b.Title)) +
+		(1-titleWeight)*Similarity(NormalizeAuthor(a.Author), NormalizeAuthor(b.Author))
+	return score, entities.DuplicateReasonSimilar, score >= d.config.Threshold
+}
+
+// Candidates keeps the books among existing that book may duplicate, best
+// first and at most Limit of them. An ISBN match outranks an equally scored
+// similar one.
+func (d *Detector) Candidates(book *entities.Book, existing []*entities.Book) []entities.DuplicateCandidate {
+	var candidates []entities.DuplicateCandidate
+	for _, other := range existing {
+		if other.ID == book.ID {
+			continue
+		}
+		if score, reason, ok := d.Match(book, other); ok {
+			candidates = append(candidates, entities.DuplicateCandidate{Book: other, Score: score, Reason: reason})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Reason == entities.DuplicateReasonISBN && candidates[j].Reason != entities.DuplicateReasonISBN
+	})
+	if len(candidates) > d.config.Limit {
+		candidates = candidates[:d.config.Limit]
+	}
+	return candidates
+}
+
+// Group scores the candidate pairs and joins the matching ones into groups,
+// so that A~B and B~C report A, B and C together. Groups are ordered by
+// their oldest book.
+func (d *Detector) Group(pairs [][2]*entities.Book) []entities.DuplicateGroup {
+	parent := map[uuid.UUID]uuid.UUID{}
+	var find func(id uuid.UUID) uuid.UUID
+	find = func(id uuid.UUID) uuid.UUID {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+
+	books := map[uuid.UUID]*entities.Book{}
+	var matches []entities.DuplicateMatch
+	for _, pair := range pairs {
+		score, reason, ok := d.Match(pair[0], pair[1])
+		if !ok {
+			continue
+		}
+		for _, book := range pair {
+			if _, seen := books[book.ID]; !seen {
+				books[book.ID] = book
+				parent[book.ID] = book.ID
+			}
+		}
+		parent[find(pair[1].ID)] = find(pair[0].ID)
+		matches = append(matches, entities.DuplicateMatch{
+			BookID: pair[0].ID, DuplicateID: pair[1].ID, Score: score, Reason: reason,
+		})
+	}
+
+	byRoot := map[uuid.UUID]*entities.DuplicateGroup{}
+	for id, book := range books {
+		root := find(id)
+		if byRoot[root] == nil {
+			byRoot[root] = &entities.DuplicateGroup{}
+		}
+		byRoot[root].Books = append(byRoot[root].Books, book)
+	}
+	for _, match := range matches {
+		group := byRoot[find(match.BookID)]
+		group.Matches = append(group.Matches, match)
+	}
+
+	groups := make([]entities.DuplicateGroup, 0, len(byRoot))
+	for _, group := range byRoot {
+		sort.Slice(group.Books, func(i, j int) bool {
+			return older(group.Books[i], group.Books[j])
+		})
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return older(groups[i].Books[0], groups[j].Books[0])
+	})
+	return groups
+}
+
+func older(a, b *entities.Book) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+// articles are dropped from the start of titles
+var articles = map[string]bool{"the": true, "a": true, "an": true}
+
+// NormalizeTitle lower-cases title, drops punctuation and a leading article
+func NormalizeTitle(title string) string {
+	words := words(title)
+	if len(words) > 1 && articles[words[0]] {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// NormalizeAuthor lower-cases author, drops punctuation and initials, and
+// puts "Last, First" names in reading order
+func NormalizeAuthor(author string) string {
+	if last, first, ok := strings.Cut(author, ","); ok {
+		author = first + " " + last
+	}
+	var kept []string
+	for _, word := range words(author) {
+		if len([]rune(word)) > 1 {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Similarity is the trigram similarity of a and b as computed by Postgres'
+// pg_trgm: the shared trigrams of the padded words over all their trigrams
+func Similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for trigram := range ta {
+		if tb[trigram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range words(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,106 @@
+package duplicates
+
+import (
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func book(title, author, isbn string, created string) *entities.Book {
+	createdAt, _ := time.Parse(time.RFC3339, created)
+	return &entities.Book{ID: uuid.New(), Title: title, Author: author, ISBN: isbn, CreatedAt: createdAt}
+}
+
+func TestDetector_Match(t *testing.T) {
+	detector := NewDetector(Config{})
+
+	tests := []struct {
+		name   string
+		a, b   *entities.Book
+		reason string
+		match  bool
+	}{
+		{
+			name:   "case and middle initials",
+			a:      book("Clean Code", "Robert Martin", "", ""),
+			b:      book("Clean code", "Robert C. Martin", "", ""),
+			reason: entities.DuplicateReasonSimilar, match: true,
+		},
+		{
+			name:   "leading article and last name first",
+			a:      book("The Pragmatic Programmer", "Andrew Hunt", "", ""),
+			b:      book("Pragmatic Programmer", "Hunt, Andrew", "", ""),
+			reason: entities.DuplicateReasonSimilar, match: true,
+		},
+		{
+			name:   "different title by the same author",
+			a:      book("Clean Code", "Robert Martin", "", ""),
+			b:      book("Clean Architecture", "Robert C. Martin", "", ""),
+			reason: entities.DuplicateReasonSimilar,
+		},
+		{
+			name:   "same ISBN whatever the title",
+			a:      book("Clean Code", "Robert Martin", "9780132350884", ""),
+			b:      book("Clean Code: A Handbook of Agile Software Craftsmanship", "Martin", "9780132350884", ""),
+			reason: entities.DuplicateReasonISBN, match: true,
+		},
+		{
+			name: "different ISBNs are different editions",
+			a:    book("Clean Code", "Robert Martin", "9780132350884", ""),
+			b:    book("Clean Code", "Robert Martin", "9780136083238", ""),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reason, ok := detector.Match(tt.a, tt.b)
+
+			assert.Equal(t, tt.match, ok, "score %.2f", score)
+			assert.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestDetector_Candidates(t *testing.T) {
+	detector := NewDetector(Config{Limit: 1})
+	newBook := book("Clean code", "Robert C. Martin", "9780132350884", "")
+	sameISBN := book("Clean Code (2nd printing)", "R. Martin", "9780132350884", "")
+	similar := book("Clean Code", "Robert Martin", "", "")
+	other := book("Refactoring", "Martin Fowler", "", "")
+
+	candidates := detector.Candidates(newBook, []*entities.Book{newBook, similar, other, sameISBN})
+
+	if assert.Len(t, candidates, 1) {
+		assert.Equal(t, sameISBN, candidates[0].Book)
+		assert.Equal(t, entities.DuplicateReasonISBN, candidates[0].Reason)
+	}
+}
+
+func TestDetector_Group(t *testing.T) {
+	detector := NewDetector(Config{})
+	a := book("Clean Code", "Robert Martin", "", "2024-01-01T00:00:00Z")
+	b := book("Clean code", "Robert C. Martin", "", "2024-01-02T00:00:00Z")
+	c := book("Clean Code", "Martin, Robert", "", "2024-01-03T00:00:00Z")
+	d := book("Dune", "Frank Herbert", "", "2023-01-01T00:00:00Z")
+	e := book("Dune", "Frank Herbert", "", "2023-06-01T00:00:00Z")
+	unrelated := book("Clean Architecture", "Robert Martin", "", "2024-01-04T00:00:00Z")
+
+	groups := detector.Group([][2]*entities.Book{{b, c}, {a, b}, {d, e}, {a, unrelated}})
+
+	if assert.Len(t, groups, 2) {
+		assert.Equal(t, []*entities.Book{d, e}, groups[0].Books)
+		assert.Equal(t, []*entities.Book{a, b, c}, groups[1].Books)
+		assert.Len(t, groups[1].Matches, 2)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "pragmatic programmer", NormalizeTitle("The Pragmatic Programmer!"))
+	assert.Equal(t, "a", NormalizeTitle("A"))
+	assert.Equal(t, "robert martin", NormalizeAuthor("Martin, Robert C."))
+	assert.Equal(t, 1.0, Similarity("dune", "dune"))
+	assert.Zero(t, Similarity("", "dune"))
+}
@@ -0,0 +1,191 @@
+// Package ebooks reads the bibliographic metadata of uploaded EPUB and PDF
+// files: the OPF package document of an EPUB, and the document information
+// dictionary and XMP packet of a PDF. Uploads are untrusted, so archives are
+// checked for unsafe entry names and for expanding beyond a limit before
+// anything is read from them, and no read is allowed to grow unbounded.
+package ebooks
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/lib/pq"
+)
+
+// File formats
+const (
+	FormatEPUB = "epub"
+	FormatPDF  = "pdf"
+)
+
+// Content types of the formats
+const (
+	ContentTypeEPUB = "application/epub+zip"
+	ContentTypePDF  = "application/pdf"
+)
+
+// Defaults applied to zero Config fields
+const (
+	DefaultMaxSize         = 100 << 20
+	DefaultMaxEntries      = 10000
+	DefaultMaxUncompressed = 1 << 30
+)
+
+// maxPartSize bounds every single part read out of a file: an archive entry,
+// a decompressed PDF stream or an XMP packet
+const maxPartSize = 16 << 20
+
+type Config struct {
+	// MaxSize bounds uploaded files, in bytes
+	MaxSize int64
+	// MaxEntries bounds the entries of an EPUB archive
+	MaxEntries int
+	// MaxUncompressed bounds the declared uncompressed size of an EPUB
+	// archive, and the PDF streams decompressed while reading one, in bytes
+	MaxUncompressed int64
+}
+
+// Parser extracts metadata from EPUB and PDF files within its limits
+type Parser struct {
+	config Config
+}
+
+func NewParser(config Config) *Parser {
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultMaxSize
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultMaxEntries
+	}
+	if config.MaxUncompressed <= 0 {
+		config.MaxUncompressed = DefaultMaxUncompressed
+	}
+	return &Parser{config: config}
+}
+
+// MaxSize is the largest file Parse accepts, in bytes
+func (p *Parser) MaxSize() int64 {
+	return p.config.MaxSize
+}
+
+// Metadata is what a file says about the book it holds. Fields the file
+// does not provide are left empty.
+type Metadata struct {
+	Format      string
+	ContentType string
+	Title       string
+	Authors     []string
+	// Year is the year of publication, or, for a PDF without one, of the
+	// file's creation
+	Year int
+	// ISBN is the first identifier that is a valid ISBN, normalized to
+	// ISBN-13
+	ISBN        string
+	Identifiers []string
+	Publisher   string
+	Description string
+	Subjects    []string
+	Language    string
+	// PageCount is only known for PDFs
+	PageCount int
+	// Cover is the image an EPUB names as its cover, nil without one
+	Cover []byte
+}
+
+// Parse detects the format of data and reads its metadata. It fails with
+// entities.ErrUnsupportedBookFile for other content, ErrMalformedBookFile
+// for files it cannot read or will not trust, and ErrBookFileTooLarge
+// beyond the limits.
+func (p *Parser) Parse(data []byte) (*Metadata, error) {
+	if int64(len(data)) > p.config.MaxSize {
+		return nil, entities.ErrBookFileTooLarge
+	}
+	var metadata *Metadata
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		metadata, err = p.parseEPUB(data)
+	case bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")):
+		metadata, err = p.parsePDF(data)
+	default:
+		return nil, entities.ErrUnsupportedBookFile
+	}
+	if err != nil {
+		return nil, err
+	}
+	metadata.ISBN = firstISBN(metadata.Identifiers)
+	return metadata, nil
+}
+
+// CreateBookDTO is the book the file describes, with the metadata cut down
+// to what passes validation
+func (m *Metadata) CreateBookDTO() entities.CreateBookDTO {
+	return entities.CreateBookDTO{
+		Title:  m.Title,
+		Author: strings.Join(m.Authors, ", "),
+		Year:   m.Year,
+		ISBN:   m.ISBN,
+		BookMetadata: entities.BookMetadata{
+			Publisher:   m.Publisher,
+			PageCount:   m.PageCount,
+			Subjects:    pq.StringArray(m.Subjects),
+			Description: m.Description,
+		}.Sanitized(),
+	}
+}
+
+func malformed(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", entities.ErrMalformedBookFile, fmt.Sprintf(format, args...))
+}
+
+// firstISBN finds the first identifier that is an ISBN, such as
+// "urn:isbn:9780132350884" or "ISBN 0-13-235088-2"
+func firstISBN(identifiers []string) string {
+	for _, identifier := range identifiers {
+		candidate := strings.ToLower(strings.TrimSpace(identifier))
+		candidate = strings.TrimPrefix(candidate, "urn:")
+		candidate = strings.TrimPrefix(candidate, "isbn")
+		candidate = strings.TrimLeft(candidate, ": ")
+		if isbn, err := entities.NormalizeISBN(candidate); err == nil {
+			return isbn
+		}
+	}
+	return ""
+}
+
+// year reads the year from the start of a date such as "2008-08-01",
+// "D:20080801120000Z" or "2008"
+func year(date string) int {
+	date = strings.TrimPrefix(strings.TrimSpace(date), "D:")
+	if len(date) < 4 {
+		return 0
+	}
+	y := 0
+	for _, r := range date[:4] {
+		if r < '0' || r > '9' {
+			return 0
+		}
+		y = y*10 + int(r-'0')
+	}
+	return y
+}
+
+// appendText adds the trimmed value unless it is blank or already there
+func appendText(values []string, value string) []string {
+	value = collapseSpace(value)
+	if value == "" {
+		return values
+	}
+	for _, existing := range values {
+		if strings.EqualFold(existing, value) {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,290 @@
+package ebooks
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type zipEntry struct {
+	name string
+	body string
+}
+
+func buildZip(t *testing.T, entries ...zipEntry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := zw.Create(entry.name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(entry.body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+const container = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`
+
+const epub2OPF = `<?xml version="1.0"?>
+<package version="2.0" xmlns="http://www.idpf.org/2007/opf" unique-identifier="id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:title>Clean   Code</dc:title>
+    <dc:creator opf:role="aut">Robert C. Martin</dc:creator>
+    <dc:creator opf:role="edt">An Editor</dc:creator>
+    <dc:date opf:event="modification">2020-01-01</dc:date>
+    <dc:date opf:event="publication">2008-08-01</dc:date>
+    <dc:identifier id="id" opf:scheme="UUID">urn:uuid:0b6f8f0e-4a5e-4a53-9d0c-5d2b4c8f6a11</dc:identifier>
+    <dc:identifier opf:scheme="ISBN">urn:isbn:0-13-235088-2</dc:identifier>
+    <dc:subject>Software</dc:subject>
+    <dc:subject>software</dc:subject>
+    <dc:subject>Agile</dc:subject>
+    <dc:publisher>Prentice Hall</dc:publisher>
+    <dc:description>&lt;p&gt;Even bad code can &lt;b&gt;function&lt;/b&gt;.&lt;/p&gt;</dc:description>
+    <dc:language>en</dc:language>
+    <meta name="cover" content="cover-img"/>
+  </metadata>
+  <manifest>
+    <item id="cover-img" href="images/cover%20art.png" media-type="image/png"/>
+  </manifest>
+</package>`
+
+const epub3OPF = `<?xml version="1.0"?>
+<package version="3.0" xmlns="http://www.idpf.org/2007/opf">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>The Go Programming Language</dc:title>
+    <dc:creator id="c1">Alan Donovan</dc:creator>
+    <meta refines="#c1" property="role">aut</meta>
+    <dc:creator id="c2">Brian Kernighan</dc:creator>
+    <meta refines="#c2" property="role">aut</meta>
+    <dc:creator id="c3">Someone Else</dc:creator>
+    <meta refines="#c3" property="role">ill</meta>
+    <dc:date>2015</dc:date>
+  </metadata>
+  <manifest>
+    <item id="c" href="../../escape.png" media-type="image/png" properties="cover-image"/>
+  </manifest>
+</package>`
+
+func TestParser_EPUB(t *testing.T) {
+	parser := NewParser(Config{})
+
+	t.Run("reads EPUB 2 metadata and cover", func(t *testing.T) {
+		data := buildZip(t,
+			zipEntry{"mimetype", ContentTypeEPUB},
+			zipEntry{containerPath, container},
+			zipEntry{"OEBPS/content.opf", epub2OPF},
+			zipEntry{"OEBPS/images/cover art.png", "PNGDATA"},
+		)
+
+		metadata, err := parser.Parse(data)
+
+		require.NoError(t, err)
+		assert.Equal(t, &Metadata{
+			Format:      FormatEPUB,
+			ContentType: ContentTypeEPUB,
+			Title:       "Clean Code",
+			Authors:     []string{"Robert C. Martin"},
+			Year:        2008,
+			ISBN:        "9780132350884",
+			Identifiers: []string{"urn:uuid:0b6f8f0e-4a5e-4a53-9d0c-5d2b4c8f6a11", "urn:isbn:0-13-235088-2"},
+			Publisher:   "Prentice Hall",
+			Description: "Even bad code can function.",
+			Subjects:    []string{"Software", "Agile"},
+			Language:    "en",
+			Cover:       []byte("PNGDATA"),
+		}, metadata)
+
+		dto := metadata.CreateBookDTO()
+		assert.Equal(t, "Robert C. Martin", dto.Author)
+		assert.NoError(t, dto.Validate())
+	})
+
+	t.Run("reads EPUB 3 roles and ignores covers outside the archive", func(t *testing.T) {
+		data := buildZip(t,
+			zipEntry{"mimetype", ContentTypeEPUB},
+			zipEntry{containerPath, container},
+			zipEntry{"OEBPS/content.opf", epub3OPF},
+		)
+
+		metadata, err := parser.Parse(data)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alan Donovan", "Brian Kernighan"}, metadata.Authors)
+		assert.Equal(t, 2015, metadata.Year)
+		assert.Nil(t, metadata.Cover)
+		assert.Equal(t, "Alan Donovan, Brian Kernighan", metadata.CreateBookDTO().Author)
+	})
+
+	t.Run("rejects other archives", func(t *testing.T) {
+		_, err := parser.Parse(buildZip(t, zipEntry{"word/document.xml", "<w/>"}))
+		assert.ErrorIs(t, err, entities.ErrUnsupportedBookFile)
+	})
+
+	t.Run("rejects unsafe entry names", func(t *testing.T) {
+		for _, name := range []string{"../evil", "/etc/passwd", "OEBPS/../../evil", `OEBPS\..\evil`, "C:/evil", "a/./b"} {
+			data := buildZip(t, zipEntry{"mimetype", ContentTypeEPUB}, zipEntry{name, "x"})
+			_, err := parser.Parse(data)
+			assert.ErrorIs(t, err, entities.ErrMalformedBookFile, name)
+		}
+	})
+
+	t.Run("rejects archives that expand too far", func(t *testing.T) {
+		small := NewParser(Config{MaxUncompressed: 1 << 10, MaxEntries: 3})
+		bomb := buildZip(t,
+			zipEntry{"mimetype", ContentTypeEPUB},
+			zipEntry{"OEBPS/zeros", strings.Repeat("0", 1<<20)},
+		)
+		assert.Less(t, len(bomb), 1<<12)
+
+		_, err := small.Parse(bomb)
+		assert.ErrorIs(t, err, entities.ErrMalformedBookFile)
+
+		_, err = small.Parse(buildZip(t,
+			zipEntry{"mimetype", ContentTypeEPUB}, zipEntry{"a", ""}, zipEntry{"b", ""}, zipEntry{"c", ""},
+		))
+		assert.ErrorIs(t, err, entities.ErrMalformedBookFile)
+	})
+
+	t.Run("reports a missing package document", func(t *testing.T) {
+		_, err := parser.Parse(buildZip(t, zipEntry{"mimetype", ContentTypeEPUB}))
+		assert.ErrorIs(t, err, entities.ErrMalformedBookFile)
+	})
+}
+
+// buildPDF lays out objects as "n 0 obj ... endobj" after a header; it
+// does not bother with a cross-reference table, which the parser ignores
+func buildPDF(trailer string, objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	for i, object := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	fmt.Fprintf(&buf, "trailer\n%s\n%%%%EOF\n", trailer)
+	return buf.Bytes()
+}
+
+func flate(t *testing.T, data string) string {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, err := zw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.String()
+}
+
+const xmp = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+  <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+    <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/"
+        xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:prism="http://prismstandard.org/namespaces/basic/2.0/"
+        xmp:CreateDate="2019-05-01T10:00:00Z">
+      <dc:title><rdf:Alt><rdf:li xml:lang="x-default">Design Patterns</rdf:li></rdf:Alt></dc:title>
+      <dc:creator><rdf:Seq><rdf:li>Erich Gamma</rdf:li><rdf:li>Richard Helm</rdf:li></rdf:Seq></dc:creator>
+      <dc:subject><rdf:Bag><rdf:li>Patterns</rdf:li></rdf:Bag></dc:subject>
+      <dc:date><rdf:Seq><rdf:li>1994</rdf:li></rdf:Seq></dc:date>
+      <prism:isbn>978-0-201-63361-0</prism:isbn>
+    </rdf:Description>
+  </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+func TestParser_PDF(t *testing.T) {
+	parser := NewParser(Config{})
+
+	t.Run("reads the information dictionary", func(t *testing.T) {
+		data := buildPDF("<< /Root 1 0 R /Info 3 0 R /Size 4 >>",
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [] /Count 464 >>",
+			`<< /Title (Clean Code\072 A Handbook \(2nd\)) /Author <FEFF0052006F0062006500720074>
+			   /Keywords (software; craftsmanship) /Subject (Agile software craftsmanship)
+			   /CreationDate (D:20080801120000Z) /Producer (Tool) >>`,
+		)
+
+		metadata, err := parser.Parse(data)
+
+		require.NoError(t, err)
+		assert.Equal(t, &Metadata{
+			Format:      FormatPDF,
+			ContentType: ContentTypePDF,
+			Title:       "Clean Code: A Handbook (2nd)",
+			Authors:     []string{"Robert"},
+			Year:        2008,
+			Description: "Agile software craftsmanship",
+			Subjects:    []string{"software", "craftsmanship"},
+			PageCount:   464,
+		}, metadata)
+	})
+
+	t.Run("prefers XMP and reads object streams", func(t *testing.T) {
+		// Objects 4 (Pages) and 5 (Info) are packed into object stream 3
+		packed := "4 0 5 40 " + "<< /Type /Pages /Count 12 >>" + strings.Repeat(" ", 12) + "<< /Title (Old Title) /Author (Someone) >>"
+		compressed := flate(t, packed)
+		data := buildPDF("<< /Root 1 0 R /Info 5 0 R >>",
+			"<< /Type /Catalog /Pages 4 0 R /Metadata 2 0 R >>",
+			fmt.Sprintf("<< /Type /Metadata /Subtype /XML /Length %d >>\nstream\n%s\nendstream", len(xmp), xmp),
+			fmt.Sprintf("<< /Type /ObjStm /N 2 /First 9 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream", len(compressed), compressed),
+		)
+
+		metadata, err := parser.Parse(data)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Design Patterns", metadata.Title)
+		assert.Equal(t, []string{"Erich Gamma", "Richard Helm"}, metadata.Authors)
+		assert.Equal(t, 1994, metadata.Year)
+		assert.Equal(t, "9780201633610", metadata.ISBN)
+		assert.Equal(t, []string{"Patterns"}, metadata.Subjects)
+		assert.Equal(t, 12, metadata.PageCount)
+	})
+
+	t.Run("object streams are decompressed within the budget", func(t *testing.T) {
+		small := NewParser(Config{MaxUncompressed: 1 << 10})
+		packed := "1 0 " + "<< /Title (Huge) >>" + strings.Repeat(" ", 1<<20)
+		compressed := flate(t, packed)
+		data := buildPDF("<< /Info 1 0 R >>",
+			fmt.Sprintf("<< /Type /ObjStm /N 1 /First 4 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream", len(compressed), compressed),
+		)
+
+		metadata, err := small.Parse(data)
+
+		require.NoError(t, err)
+		assert.Empty(t, metadata.Title)
+	})
+
+	t.Run("encrypted information dictionaries are skipped", func(t *testing.T) {
+		data := buildPDF("<< /Info 1 0 R /Encrypt 2 0 R >>", "<< /Title <A1B2C3> >>", "<< /Filter /Standard >>")
+
+		metadata, err := parser.Parse(data)
+
+		require.NoError(t, err)
+		assert.Empty(t, metadata.Title)
+	})
+
+	t.Run("rejects malformed files", func(t *testing.T) {
+		_, err := parser.Parse([]byte("%PDF-1.4\nnothing here"))
+		assert.ErrorIs(t, err, entities.ErrMalformedBookFile)
+
+		deep := buildPDF("<< /Info 1 0 R >>", "<< /Title "+strings.Repeat("[", 100)+" >>")
+		metadata, err := parser.Parse(deep)
+		require.NoError(t, err)
+		assert.Empty(t, metadata.Title)
+	})
+}
+
+func TestParser_Parse(t *testing.T) {
+	_, err := NewParser(Config{}).Parse([]byte("plain text"))
+	assert.ErrorIs(t, err, entities.ErrUnsupportedBookFile)
+
+	_, err = NewParser(Config{MaxSize: 4}).Parse([]byte("%PDF-1.4"))
+	assert.ErrorIs(t, err, entities.ErrBookFileTooLarge)
+}
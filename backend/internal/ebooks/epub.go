@@ -0,0 +1,301 @@
+package ebooks
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"byfood-library/internal/domain/entities"
+)
+
+// containerPath is where every EPUB names its package document
+const containerPath = "META-INF/container.xml"
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// opfPackage is the part of an OPF package document the parser reads. Both
+// EPUB 2 and EPUB 3 are covered: elements and attributes are matched by
+// local name, whatever their namespace prefix.
+type opfPackage struct {
+	Metadata struct {
+		Titles       []string     `xml:"title"`
+		Creators     []opfCreator `xml:"creator"`
+		Dates        []opfDate    `xml:"date"`
+		Identifiers  []string     `xml:"identifier"`
+		Subjects     []string     `xml:"subject"`
+		Publishers   []string     `xml:"publisher"`
+		Descriptions []string     `xml:"description"`
+		Languages    []string     `xml:"language"`
+		Metas        []opfMeta    `xml:"meta"`
+	} `xml:"metadata"`
+	Manifest []opfItem `xml:"manifest>item"`
+}
+
+type opfCreator struct {
+	ID    string `xml:"id,attr"`
+	Role  string `xml:"role,attr"`
+	Value string `xml:",chardata"`
+}
+
+type opfDate struct {
+	Event string `xml:"event,attr"`
+	Value string `xml:",chardata"`
+}
+
+type opfMeta struct {
+	Name     string `xml:"name,attr"`
+	Content  string `xml:"content,attr"`
+	Property string `xml:"property,attr"`
+	Refines  string `xml:"refines,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type opfItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+// epubArchive reads entries of a checked archive by name
+type epubArchive map[string]*zip.File
+
+func (p *Parser) parseEPUB(data []byte) (*Metadata, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, malformed("not a readable zip archive")
+	}
+	archive, err := p.checkArchive(zr.File)
+	if err != nil {
+		return nil, err
+	}
+
+	mimetype, err := archive.read("mimetype")
+	if err != nil || strings.TrimSpace(string(mimetype)) != ContentTypeEPUB {
+		return nil, entities.ErrUnsupportedBookFile
+	}
+
+	raw, err := archive.read(containerPath)
+	if err != nil {
+		return nil, err
+	}
+	var container epubContainer
+	if err := xml.Unmarshal(raw, &container); err != nil {
+		return nil, malformed("%s is not valid XML", containerPath)
+	}
+	opfPath := ""
+	for _, rootfile := range container.Rootfiles {
+		if rootfile.MediaType == "" || rootfile.MediaType == "application/oebps-package+xml" {
+			opfPath = rootfile.FullPath
+			break
+		}
+	}
+	if opfPath == "" {
+		return nil, malformed("%s names no package document", containerPath)
+	}
+
+	raw, err = archive.read(opfPath)
+	if err != nil {
+		return nil, err
+	}
+	var pkg opfPackage
+	if err := xml.Unmarshal(raw, &pkg); err != nil {
+		return nil, malformed("package document is not valid XML")
+	}
+
+	metadata := readOPF(&pkg)
+	if href := coverHref(&pkg); href != "" {
+		// A missing or unreadable cover does not make the book unusable
+		if name, ok := resolveHref(opfPath, href); ok {
+			metadata.Cover, _ = archive.read(name)
+		}
+	}
+	return metadata, nil
+}
+
+// checkArchive rejects archives with more entries than allowed, with entry
+// names that could escape the directory they are extracted to, or that
+// declare more uncompressed data than allowed. Declared sizes are only a
+// first line of defence; read enforces them while decompressing.
+func (p *Parser) checkArchive(files []*zip.File) (epubArchive, error) {
+	if len(files) > p.config.MaxEntries {
+		return nil, malformed("archive has more than %d entries", p.config.MaxEntries)
+	}
+	archive := make(epubArchive, len(files))
+	var total uint64
+	for _, file := range files {
+		if !safeEntryName(file.Name) {
+			return nil, malformed("archive entry %q has an unsafe name", file.Name)
+		}
+		total += file.UncompressedSize64
+		if total > uint64(p.config.MaxUncompressed) {
+			return nil, malformed("archive expands to more than %d bytes", p.config.MaxUncompressed)
+		}
+		archive[strings.TrimSuffix(file.Name, "/")] = file
+	}
+	return archive, nil
+}
+
+// safeEntryName accepts relative, clean, slash-separated names only
+func safeEntryName(name string) bool {
+	name = strings.TrimSuffix(name, "/")
+	if name == "" || strings.ContainsAny(name, "\\\x00") || strings.HasPrefix(name, "/") {
+		return false
+	}
+	if len(name) >= 2 && name[1] == ':' {
+		return false
+	}
+	if path.Clean(name) != name {
+		return false
+	}
+	for _, element := range strings.Split(name, "/") {
+		if element == ".." || element == "." {
+			return false
+		}
+	}
+	return true
+}
+
+// read decompresses an entry, at most maxPartSize bytes of it
+func (a epubArchive) read(name string) ([]byte, error) {
+	file, ok := a[name]
+	if !ok {
+		return nil, malformed("archive has no %s", name)
+	}
+	if file.UncompressedSize64 > maxPartSize {
+		return nil, malformed("%s is larger than %d bytes", name, maxPartSize)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, malformed("%s cannot be read", name)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxPartSize+1))
+	if err != nil {
+		return nil, malformed("%s cannot be read", name)
+	}
+	if len(data) > maxPartSize {
+		return nil, malformed("%s is larger than %d bytes", name, maxPartSize)
+	}
+	return data, nil
+}
+
+// resolveHref resolves a manifest href against the package document's
+// directory, refusing anything that leaves the archive
+func resolveHref(opfPath, href string) (string, bool) {
+	unescaped, err := url.PathUnescape(strings.SplitN(href, "#", 2)[0])
+	if err != nil || strings.Contains(unescaped, ":") {
+		return "", false
+	}
+	name := path.Join(path.Dir(opfPath), unescaped)
+	return name, safeEntryName(name)
+}
+
+func readOPF(pkg *opfPackage) *Metadata {
+	m := &pkg.Metadata
+	metadata := &Metadata{Format: FormatEPUB, ContentType: ContentTypeEPUB}
+
+	if len(m.Titles) > 0 {
+		metadata.Title = collapseSpace(m.Titles[0])
+	}
+
+	// EPUB 2 gives creator roles as attributes, EPUB 3 in refining metas;
+	// only authors are kept unless no creator has a role
+	roles := map[string]string{}
+	for _, meta := range m.Metas {
+		if meta.Property == "role" && strings.HasPrefix(meta.Refines, "#") {
+			roles[strings.TrimPrefix(meta.Refines, "#")] = strings.TrimSpace(meta.Value)
+		}
+	}
+	var authors, others []string
+	for _, creator := range m.Creators {
+		role := creator.Role
+		if role == "" {
+			role = roles[creator.ID]
+		}
+		if role == "" || role == "aut" {
+			authors = appendText(authors, creator.Value)
+		} else {
+			others = appendText(others, creator.Value)
+		}
+	}
+	if len(authors) == 0 {
+		authors = others
+	}
+	metadata.Authors = authors
+
+	for _, date := range m.Dates {
+		if date.Event == "" || date.Event == "publication" {
+			if metadata.Year = year(date.Value); metadata.Year != 0 {
+				break
+			}
+		}
+	}
+	for _, identifier := range m.Identifiers {
+		metadata.Identifiers = appendText(metadata.Identifiers, identifier)
+	}
+	for _, subject := range m.Subjects {
+		metadata.Subjects = appendText(metadata.Subjects, subject)
+	}
+	if len(m.Publishers) > 0 {
+		metadata.Publisher = collapseSpace(m.Publishers[0])
+	}
+	if len(m.Descriptions) > 0 {
+		metadata.Description = plainText(m.Descriptions[0])
+	}
+	if len(m.Languages) > 0 {
+		metadata.Language = strings.TrimSpace(m.Languages[0])
+	}
+	return metadata
+}
+
+// coverHref finds the cover image: the EPUB 3 cover-image item, else the
+// item an EPUB 2 "cover" meta points at
+func coverHref(pkg *opfPackage) string {
+	for _, item := range pkg.Manifest {
+		for _, property := range strings.Fields(item.Properties) {
+			if property == "cover-image" {
+				return item.Href
+			}
+		}
+	}
+	for _, meta := range pkg.Metadata.Metas {
+		if meta.Name != "cover" {
+			continue
+		}
+		for _, item := range pkg.Manifest {
+			if item.ID == meta.Content && strings.HasPrefix(item.MediaType, "image/") {
+				return item.Href
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	markupTag = regexp.MustCompile(`<[^>]*>`)
+	inlineTag = regexp.MustCompile(`^</?(a|abbr|b|cite|code|em|i|q|small|span|strong|sub|sup|u)\b`)
+)
+
+// plainText strips the HTML that descriptions often carry. Other tags than
+// inline ones separate words.
+func plainText(s string) string {
+	return collapseSpace(html.UnescapeString(markupTag.ReplaceAllStringFunc(s, func(tag string) string {
+		if inlineTag.MatchString(strings.ToLower(tag)) {
+			return ""
+		}
+		return " "
+	})))
+}
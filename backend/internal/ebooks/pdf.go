@@ -0,0 +1,623 @@
+package ebooks
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxPDFDepth bounds the nesting of arrays and dictionaries
+const maxPDFDepth = 32
+
+// pdfRef is an indirect reference, "12 0 R"
+type pdfRef int
+
+// pdfName is a name object such as /Title, without the slash
+type pdfName string
+
+type pdfDict map[pdfName]interface{}
+
+var (
+	objectHeader = regexp.MustCompile(`(?:^|[\s>\]])(\d+)\s+\d+\s+obj\b`)
+	infoRef      = regexp.MustCompile(`/Info\s+(\d+)\s+\d+\s+R`)
+	rootRef      = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R`)
+	xmpPacket    = regexp.MustCompile(`(?s)<x:xmpmeta.*?</x:xmpmeta>`)
+)
+
+// pdfDocument reads objects out of a PDF without its cross-reference
+// table, which is often damaged: objects are found by scanning for their
+// headers, the last one winning as incremental updates append. Objects
+// packed into object streams are found by decompressing those, within the
+// parser's budget.
+type pdfDocument struct {
+	data    []byte
+	offsets map[int]int
+	// packed holds the objects of the object streams read so far
+	packed  map[int]interface{}
+	streams []int
+	budget  int64
+}
+
+func (p *Parser) parsePDF(data []byte) (*Metadata, error) {
+	doc := &pdfDocument{data: data, offsets: map[int]int{}, budget: p.config.MaxUncompressed}
+	for _, match := range objectHeader.FindAllSubmatchIndex(data, -1) {
+		num, err := strconv.Atoi(string(data[match[2]:match[3]]))
+		if err == nil {
+			doc.offsets[num] = match[1]
+		}
+	}
+	if len(doc.offsets) == 0 {
+		return nil, malformed("no objects found in PDF")
+	}
+
+	metadata := &Metadata{Format: FormatPDF, ContentType: ContentTypePDF}
+	encrypted := bytes.Contains(data, []byte("/Encrypt"))
+
+	// XMP is preferred: it is richer and, unlike the information
+	// dictionary, usually kept current by the tools that edit a file
+	var catalog pdfDict
+	if match := lastSubmatch(rootRef, data); match != "" {
+		num, _ := strconv.Atoi(match)
+		catalog, _ = doc.object(num).(pdfDict)
+	}
+	if packet := doc.xmp(catalog); packet != nil {
+		readXMP(packet, metadata)
+	}
+	if catalog != nil {
+		if pages, ok := doc.resolve(catalog["Pages"]).(pdfDict); ok {
+			if count, ok := pages["Count"].(int); ok && count > 0 {
+				metadata.PageCount = count
+			}
+		}
+	}
+
+	// Strings in the information dictionary of an encrypted file are
+	// encrypted too
+	if match := lastSubmatch(infoRef, data); match != "" && !encrypted {
+		num, _ := strconv.Atoi(match)
+		if info, ok := doc.object(num).(pdfDict); ok {
+			doc.readInfo(info, metadata)
+		}
+	}
+	return metadata, nil
+}
+
+func lastSubmatch(re *regexp.Regexp, data []byte) string {
+	matches := re.FindAllSubmatch(data, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return string(matches[len(matches)-1][1])
+}
+
+// readInfo fills what XMP left empty from the information dictionary
+func (doc *pdfDocument) readInfo(info pdfDict, metadata *Metadata) {
+	text := func(key pdfName) string {
+		s, _ := doc.resolve(info[key]).(string)
+		return collapseSpace(s)
+	}
+	if metadata.Title == "" {
+		metadata.Title = text("Title")
+	}
+	if len(metadata.Authors) == 0 {
+		metadata.Authors = appendText(nil, text("Author"))
+	}
+	if metadata.Description == "" {
+		metadata.Description = text("Subject")
+	}
+	if len(metadata.Subjects) == 0 {
+		for _, keyword := range strings.FieldsFunc(text("Keywords"), func(r rune) bool { return r == ',' || r == ';' }) {
+			metadata.Subjects = appendText(metadata.Subjects, keyword)
+		}
+	}
+	if metadata.Year == 0 {
+		metadata.Year = year(text("CreationDate"))
+	}
+}
+
+// xmp returns the catalog's metadata stream, or else any XMP packet left
+// uncompressed in the file
+func (doc *pdfDocument) xmp(catalog pdfDict) []byte {
+	if ref, ok := catalog["Metadata"].(pdfRef); ok {
+		if offset, ok := doc.offsets[int(ref)]; ok {
+			if stream := doc.stream(offset); stream != nil {
+				return stream
+			}
+		}
+	}
+	packets := xmpPacket.FindAll(doc.data, -1)
+	if len(packets) == 0 || len(packets[len(packets)-1]) > maxPartSize {
+		return nil
+	}
+	return packets[len(packets)-1]
+}
+
+// object returns the value of object num, or nil if it cannot be read
+func (doc *pdfDocument) object(num int) interface{} {
+	if offset, ok := doc.offsets[num]; ok {
+		parser := &pdfParser{data: doc.data, pos: offset}
+		value, err := parser.value(0)
+		if err == nil {
+			return value
+		}
+	}
+	if value, ok := doc.packed[num]; ok {
+		return value
+	}
+	doc.unpack()
+	return doc.packed[num]
+}
+
+// resolve follows v if it is a reference
+func (doc *pdfDocument) resolve(v interface{}) interface{} {
+	if ref, ok := v.(pdfRef); ok {
+		return doc.object(int(ref))
+	}
+	return v
+}
+
+// unpack reads every object stream once
+func (doc *pdfDocument) unpack() {
+	if doc.packed != nil {
+		return
+	}
+	doc.packed = map[int]interface{}{}
+	for _, offset := range doc.offsets {
+		parser := &pdfParser{data: doc.data, pos: offset}
+		dict, ok := mustDict(parser.value(0))
+		if !ok || dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		n, _ := dict["N"].(int)
+		first, _ := dict["First"].(int)
+		data := doc.stream(offset)
+		if data == nil || first <= 0 || first > len(data) {
+			continue
+		}
+		header := &pdfParser{data: data[:first]}
+		for i := 0; i < n; i++ {
+			num, err1 := header.value(0)
+			rel, err2 := header.value(0)
+			numInt, ok1 := num.(int)
+			relInt, ok2 := rel.(int)
+			if err1 != nil || err2 != nil || !ok1 || !ok2 || first+relInt >= len(data) {
+				break
+			}
+			if value, err := (&pdfParser{data: data, pos: first + relInt}).value(0); err == nil {
+				doc.packed[numInt] = value
+			}
+		}
+	}
+}
+
+func mustDict(v interface{}, err error) (pdfDict, bool) {
+	dict, ok := v.(pdfDict)
+	return dict, ok && err == nil
+}
+
+// stream returns the decoded data of the stream object at offset, or nil
+// if it cannot be read or the budget is spent. Only uncompressed and
+// FlateDecode streams are read; metadata seldom uses anything else.
+func (doc *pdfDocument) stream(offset int) []byte {
+	parser := &pdfParser{data: doc.data, pos: offset}
+	dict, ok := mustDict(parser.value(0))
+	if !ok {
+		return nil
+	}
+	parser.skipSpace()
+	if !bytes.HasPrefix(doc.data[parser.pos:], []byte("stream")) {
+		return nil
+	}
+	start := parser.pos + len("stream")
+	if bytes.HasPrefix(doc.data[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(doc.data) && doc.data[start] == '\n' {
+		start++
+	}
+
+	end := -1
+	if length, ok := doc.resolve(dict["Length"]).(int); ok && length >= 0 && start+length <= len(doc.data) {
+		end = start + length
+	} else if i := bytes.Index(doc.data[start:], []byte("endstream")); i >= 0 {
+		end = start + i
+	}
+	if end < 0 {
+		return nil
+	}
+	raw := doc.data[start:end]
+
+	switch filter := dict["Filter"].(type) {
+	case nil:
+		return raw
+	case pdfName:
+		if filter != "FlateDecode" {
+			return nil
+		}
+	case []interface{}:
+		if len(filter) != 1 || filter[0] != pdfName("FlateDecode") {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if _, ok := dict["DecodeParms"]; ok {
+		// Predictors are used for images and cross-reference streams only
+		return nil
+	}
+
+	limit := min(int64(maxPartSize), doc.budget)
+	if limit <= 0 {
+		return nil
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+	decoded, err := io.ReadAll(io.LimitReader(zr, limit+1))
+	doc.budget -= int64(len(decoded))
+	if int64(len(decoded)) > limit || (err != nil && len(decoded) == 0) {
+		return nil
+	}
+	return decoded
+}
+
+// pdfParser reads PDF values: dictionaries, arrays, names, strings,
+// numbers, booleans, null and references. Strings are returned decoded to
+// UTF-8; other numbers than integers are kept as float64.
+type pdfParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		case isPDFSpace(c):
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return isPDFSpace(c) || strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (p *pdfParser) value(depth int) (interface{}, error) {
+	if depth > maxPDFDepth {
+		return nil, malformed("PDF objects nest too deeply")
+	}
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, malformed("unexpected end of PDF")
+	}
+	switch c := p.data[p.pos]; {
+	case bytes.HasPrefix(p.data[p.pos:], []byte("<<")):
+		return p.dict(depth)
+	case c == '<':
+		return p.hexString()
+	case c == '(':
+		return p.literalString()
+	case c == '[':
+		return p.array(depth)
+	case c == '/':
+		return p.name(), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.number()
+	default:
+		word := p.word()
+		switch word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return nil, malformed("unexpected %q in PDF", word)
+	}
+}
+
+func (p *pdfParser) word() string {
+	start := p.pos
+	for p.pos < len(p.data) && !isPDFDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+func (p *pdfParser) dict(depth int) (pdfDict, error) {
+	p.pos += 2
+	dict := pdfDict{}
+	for {
+		p.skipSpace()
+		if bytes.HasPrefix(p.data[p.pos:], []byte(">>")) {
+			p.pos += 2
+			return dict, nil
+		}
+		if p.pos >= len(p.data) || p.data[p.pos] != '/' {
+			return nil, malformed("PDF dictionary key is not a name")
+		}
+		key := p.name()
+		value, err := p.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = value
+	}
+}
+
+func (p *pdfParser) array(depth int) ([]interface{}, error) {
+	p.pos++
+	var values []interface{}
+	for {
+		p.skipSpace()
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			p.pos++
+			return values, nil
+		}
+		value, err := p.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+}
+
+func (p *pdfParser) name() pdfName {
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.data) && !isPDFDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	raw := string(p.data[start:p.pos])
+	if !strings.Contains(raw, "#") {
+		return pdfName(raw)
+	}
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if c, err := strconv.ParseUint(raw[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(raw[i])
+	}
+	return pdfName(b.String())
+}
+
+// number reads a number, or a reference when two integers are followed by R
+func (p *pdfParser) number() (interface{}, error) {
+	word := p.word()
+	n, err := strconv.Atoi(word)
+	if err != nil {
+		f, err := strconv.ParseFloat(word, 64)
+		if err != nil {
+			return nil, malformed("invalid number %q in PDF", word)
+		}
+		return f, nil
+	}
+
+	save := p.pos
+	p.skipSpace()
+	genStart := p.pos
+	for p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos > genStart {
+		p.skipSpace()
+		if p.pos < len(p.data) && p.data[p.pos] == 'R' && (p.pos+1 == len(p.data) || isPDFDelimiter(p.data[p.pos+1])) {
+			p.pos++
+			return pdfRef(n), nil
+		}
+	}
+	p.pos = save
+	return n, nil
+}
+
+func (p *pdfParser) hexString() (string, error) {
+	p.pos++
+	var digits []byte
+	for p.pos < len(p.data) && p.data[p.pos] != '>' {
+		if c := p.data[p.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.data) {
+		return "", malformed("unterminated PDF string")
+	}
+	p.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	raw := make([]byte, len(digits)/2)
+	for i := range raw {
+		c, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return "", malformed("invalid PDF hex string")
+		}
+		raw[i] = byte(c)
+	}
+	return decodeText(raw), nil
+}
+
+func (p *pdfParser) literalString() (string, error) {
+	p.pos++
+	var raw []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return decodeText(raw), nil
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				continue
+			}
+			c = p.data[p.pos]
+			p.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					octal := int(c - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						octal = octal*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(octal)
+				}
+			}
+		}
+		raw = append(raw, c)
+	}
+	return "", malformed("unterminated PDF string")
+}
+
+// decodeText decodes a PDF text string: UTF-16BE with a byte order mark,
+// UTF-8 with one (PDF 2.0), or else PDFDocEncoding, which is read as
+// Latin-1
+func decodeText(raw []byte) string {
+	switch {
+	case bytes.HasPrefix(raw, []byte{0xFE, 0xFF}):
+		raw = raw[2:]
+		units := make([]uint16, len(raw)/2)
+		for i := range units {
+			units[i] = uint16(raw[2*i])<<8 | uint16(raw[2*i+1])
+		}
+		return string(utf16.Decode(units))
+	case bytes.HasPrefix(raw, []byte{0xEF, 0xBB, 0xBF}):
+		return strings.ToValidUTF8(string(raw[3:]), "")
+	}
+	runes := make([]rune, len(raw))
+	for i, c := range raw {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// XMP namespaces the parser reads
+const (
+	nsDC    = "http://purl.org/dc/elements/1.1/"
+	nsXMP   = "http://ns.adobe.com/xap/1.0/"
+	nsPDF   = "http://ns.adobe.com/pdf/1.3/"
+	nsRDF   = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsPRISM = "http://prismstandard.org/namespaces/"
+)
+
+// readXMP fills metadata from an XMP packet. Properties are collected by
+// namespace and name whether they are written as elements, with their
+// values in rdf:li items for lists, or as attributes.
+func readXMP(packet []byte, metadata *Metadata) {
+	properties := map[string][]string{}
+	key := func(name xml.Name) string {
+		switch {
+		case name.Space == nsDC:
+			return "dc:" + name.Local
+		case name.Space == nsXMP:
+			return "xmp:" + name.Local
+		case name.Space == nsPDF:
+			return "pdf:" + name.Local
+		case strings.HasPrefix(name.Space, nsPRISM):
+			return "prism:" + name.Local
+		}
+		return ""
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(packet))
+	var open []string
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			property := key(t.Name)
+			if property == "" && t.Name.Space == nsRDF && len(open) > 0 {
+				property = open[len(open)-1]
+			}
+			open = append(open, property)
+			for _, attr := range t.Attr {
+				if k := key(attr.Name); k != "" {
+					properties[k] = appendText(properties[k], attr.Value)
+				}
+			}
+		case xml.EndElement:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case xml.CharData:
+			if len(open) > 0 && open[len(open)-1] != "" {
+				k := open[len(open)-1]
+				properties[k] = appendText(properties[k], string(t))
+			}
+		}
+	}
+
+	first := func(k string) string {
+		if values := properties[k]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	metadata.Title = first("dc:title")
+	metadata.Authors = properties["dc:creator"]
+	metadata.Subjects = properties["dc:subject"]
+	metadata.Description = plainText(first("dc:description"))
+	metadata.Publisher = first("dc:publisher")
+	metadata.Language = first("dc:language")
+	metadata.Identifiers = append(append([]string(nil), properties["prism:isbn"]...), properties["dc:identifier"]...)
+	for _, date := range append(properties["dc:date"], properties["xmp:CreateDate"]...) {
+		if metadata.Year = year(date); metadata.Year != 0 {
+			break
+		}
+	}
+	if len(metadata.Subjects) == 0 {
+		for _, keyword := range strings.FieldsFunc(first("pdf:Keywords"), func(r rune) bool { return r == ',' || r == ';' }) {
+			metadata.Subjects = appendText(metadata.Subjects, keyword)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+)
+
+// maxResponseSize bounds the upstream responses read
+const maxResponseSize = 1 << 20
+
+// Client fetches JSON from the bibliographic APIs
+type Client struct {
+	http      *http.Client
+	userAgent string
+}
+
+// NewClient returns a Client whose requests time out after timeout and
+// identify themselves with userAgent, as Open Library asks API users to do
+func NewClient(timeout time.Duration, userAgent string) *Client {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &Client{http: &http.Client{Timeout: timeout}, userAgent: userAgent}
+}
+
+// getJSON decodes the response to a GET of url into target. A 404 is
+// reported as entities.ErrMetadataNotFound.
+func (c *Client) getJSON(ctx context.Context, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return entities.ErrMetadataNotFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, req.URL.Host)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(target); err != nil {
+		return fmt.Errorf("decoding response from %s: %w", req.URL.Host, err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"byfood-library/internal/cache"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/duplicates"
+	"byfood-library/internal/middleware"
+	"golang.org/x/time/rate"
+)
+
+// notFound is the cached answer for books the provider does not know, so
+// that repeated lookups of them do not reach the upstream APIs either
+var notFound = []byte("null")
+
+// Cached remembers a provider's answers, including "not found", for a TTL.
+// Failures are not cached.
+type Cached struct {
+	provider MetadataProvider
+	cache    *cache.Cache
+	ttl      time.Duration
+}
+
+func NewCached(provider MetadataProvider, c *cache.Cache, ttl time.Duration) *Cached {
+	return &Cached{provider: provider, cache: c, ttl: ttl}
+}
+
+func (c *Cached) Name() string {
+	return c.provider.Name()
+}
+
+func (c *Cached) Lookup(ctx context.Context, query entities.MetadataQuery) (*Metadata, error) {
+	data, err := c.cache.Fetch(ctx, cacheKey(query), c.ttl, func(ctx context.Context) ([]byte, error) {
+		metadata, err := c.provider.Lookup(ctx, query)
+		if errors.Is(err, entities.ErrMetadataNotFound) {
+			return notFound, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(metadata)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata *Metadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+	if metadata == nil {
+		return nil, entities.ErrMetadataNotFound
+	}
+	return metadata, nil
+}
+
+// cacheKey identifies a query; titles and authors are normalized so that
+// spelling variants share an entry
+func cacheKey(query entities.MetadataQuery) string {
+	if query.ISBN != "" {
+		return "metadata:isbn:" + query.ISBN
+	}
+	return "metadata:title:" + duplicates.NormalizeTitle(query.Title) + ":" + duplicates.NormalizeAuthor(query.Author)
+}
+
+// RateLimited keeps a provider within the request rate its API allows.
+// Lookups wait for their turn until the context is done.
+type RateLimited struct {
+	provider MetadataProvider
+	limiter  *rate.Limiter
+}
+
+// NewRateLimited allows perSecond lookups a second with bursts of burst
+func NewRateLimited(provider MetadataProvider, perSecond float64, burst int) *RateLimited {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &RateLimited{provider: provider, limiter: rate.NewLimiter(rate.Limit(perSecond), burst)}
+}
+
+func (r *RateLimited) Name() string {
+	return r.provider.Name()
+}
+
+func (r *RateLimited) Lookup(ctx context.Context, query entities.MetadataQuery) (*Metadata, error) {
+	if err := r.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return r.provider.Lookup(ctx, query)
+}
+
+// Instrumented records the outcome and duration of a provider's lookups
+type Instrumented struct {
+	provider MetadataProvider
+}
+
+func NewInstrumented(provider MetadataProvider) *Instrumented {
+	return &Instrumented{provider: provider}
+}
+
+func (i *Instrumented) Name() string {
+	return i.provider.Name()
+}
+
+func (i *Instrumented) Lookup(ctx context.Context, query entities.MetadataQuery) (*Metadata, error) {
+	start := time.Now()
+	metadata, err := i.provider.Lookup(ctx, query)
+	result := "found"
+	switch {
+	case errors.Is(err, entities.ErrMetadataNotFound):
+		result = "not_found"
+	case err != nil:
+		result = "error"
+	}
+	middleware.RecordEnrichmentLookup(i.provider.Name(), result, time.Since(start))
+	return metadata, err
+}
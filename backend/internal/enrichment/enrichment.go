@@ -0,0 +1,170 @@
+// Package enrichment looks books up in external bibliographic sources such
+// as Open Library and Google Books. Each source is a MetadataProvider; a
+// Chain asks them in order and fills the gaps one leaves from the next, and
+// Cached and RateLimited wrap providers to spare the upstream APIs.
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/logging"
+	"go.uber.org/zap"
+)
+
+// Cover sizes used as keys of Metadata.Covers
+const (
+	CoverSmall  = "small"
+	CoverMedium = "medium"
+	CoverLarge  = "large"
+)
+
+// Metadata is what a source knows about a book
+type Metadata struct {
+	ISBN   string `json:"isbn,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Author string `json:"author,omitempty"`
+	Year   int    `json:"year,omitempty"`
+	entities.BookMetadata
+	// Covers holds cover image URLs by size
+	Covers map[string]string `json:"covers,omitempty"`
+	// Sources names the providers that contributed, in order
+	Sources []string `json:"sources,omitempty"`
+}
+
+// MetadataProvider is one bibliographic source. Lookup answers a validated
+// query and returns entities.ErrMetadataNotFound when the source has no
+// record of the book; any other error means the source could not be asked.
+type MetadataProvider interface {
+	Name() string
+	Lookup(ctx context.Context, query entities.MetadataQuery) (*Metadata, error)
+}
+
+// Chain asks its providers in order. Later providers only fill the fields
+// earlier ones left empty, and are skipped once every field is known.
+type Chain []MetadataProvider
+
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, provider := range c {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Lookup returns the merged metadata, entities.ErrMetadataNotFound when no
+// provider knows the book, or entities.ErrMetadataUnavailable when none
+// knows it and at least one failed. A provider that found the book's ISBN
+// by title lets the following ones look it up by ISBN.
+func (c Chain) Lookup(ctx context.Context, query entities.MetadataQuery) (*Metadata, error) {
+	logger := logging.FromContext(ctx, zap.NewNop())
+
+	var result *Metadata
+	failed := false
+	for _, provider := range c {
+		found, err := provider.Lookup(ctx, query)
+		if errors.Is(err, entities.ErrMetadataNotFound) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Warn("Bibliographic source failed", zap.String("provider", provider.Name()), zap.Error(err))
+			failed = true
+			continue
+		}
+
+		if result == nil {
+			result = &Metadata{}
+		}
+		result.merge(found, provider.Name())
+		if query.ISBN == "" && result.ISBN != "" {
+			query = entities.MetadataQuery{ISBN: result.ISBN}
+		}
+		if result.complete() {
+			break
+		}
+	}
+
+	switch {
+	case result != nil:
+		return result, nil
+	case failed:
+		return nil, entities.ErrMetadataUnavailable
+	}
+	return nil, entities.ErrMetadataNotFound
+}
+
+// merge copies the fields of other that m lacks
+func (m *Metadata) merge(other *Metadata, source string) {
+	fill(&m.ISBN, other.ISBN)
+	fill(&m.Title, other.Title)
+	fill(&m.Author, other.Author)
+	fill(&m.Publisher, other.Publisher)
+	fill(&m.Description, other.Description)
+	fill(&m.CoverURL, other.CoverURL)
+	if m.Year == 0 {
+		m.Year = other.Year
+	}
+	if m.PageCount == 0 {
+		m.PageCount = other.PageCount
+	}
+	if len(m.Subjects) == 0 {
+		m.Subjects = other.Subjects
+	}
+	for size, url := range other.Covers {
+		if m.Covers == nil {
+			m.Covers = map[string]string{}
+		}
+		if m.Covers[size] == "" {
+			m.Covers[size] = url
+		}
+	}
+	m.Sources = append(m.Sources, source)
+}
+
+func fill(field *string, value string) {
+	if *field == "" {
+		*field = strings.TrimSpace(value)
+	}
+}
+
+func (m *Metadata) complete() bool {
+	return m.ISBN != "" && m.Title != "" && m.Author != "" && m.Year != 0 &&
+		m.Publisher != "" && m.PageCount != 0 && len(m.Subjects) > 0 &&
+		m.Description != "" && m.CoverURL != ""
+}
+
+// bestCover picks the largest of covers for CoverURL
+func bestCover(covers map[string]string) string {
+	for _, size := range []string{CoverLarge, CoverMedium, CoverSmall} {
+		if covers[size] != "" {
+			return covers[size]
+		}
+	}
+	return ""
+}
+
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+// parseYear finds the year in a free-form publication date such as
+// "October 26, 2015" or "2015-10-26"
+func parseYear(date string) int {
+	year, _ := strconv.Atoi(yearPattern.FindString(date))
+	return year
+}
+
+// firstISBN returns the first valid ISBN of candidates, normalized
+func firstISBN(candidates ...string) string {
+	for _, candidate := range candidates {
+		if isbn, err := entities.NormalizeISBN(candidate); err == nil && isbn != "" {
+			return isbn
+		}
+	}
+	return ""
+}
@@ -0,0 +1,272 @@
+package enrichment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"byfood-library/internal/cache"
+	"byfood-library/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider answers every lookup with metadata or err and records the
+// queries it was asked
+type stubProvider struct {
+	name     string
+	metadata *Metadata
+	err      error
+	queries  []entities.MetadataQuery
+}
+
+func (s *stubProvider) Name() string {
+	return s.name
+}
+
+func (s *stubProvider) Lookup(ctx context.Context, query entities.MetadataQuery) (*Metadata, error) {
+	s.queries = append(s.queries, query)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return copyMetadata(s.metadata), nil
+}
+
+func serve(t *testing.T, handler http.HandlerFunc) string {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestOpenLibrary_Lookup(t *testing.T) {
+	var userAgent string
+	url := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		switch {
+		case r.URL.Path == "/api/books" && r.URL.Query().Get("bibkeys") == "ISBN:9780134190440":
+			w.Write([]byte(`{"ISBN:9780134190440": {
+				"title": "The Go Programming Language",
+				"authors": [{"name": "Alan A. A. Donovan"}, {"name": "Brian W. Kernighan"}],
+				"publishers": [{"name": "Addison-Wesley"}],
+				"publish_date": "October 26, 2015",
+				"number_of_pages": 380,
+				"subjects": [{"name": "Go (Computer program language)"}],
+				"notes": {"type": "/type/text", "value": "Includes index."},
+				"cover": {"small": "https://covers.example/s.jpg", "large": "https://covers.example/l.jpg"},
+				"identifiers": {"isbn_10": ["0134190440"]}
+			}}`))
+		case r.URL.Path == "/api/books":
+			w.Write([]byte(`{}`))
+		case r.URL.Path == "/search.json" && r.URL.Query().Get("title") == "Clean Code":
+			assert.Equal(t, "Robert Martin", r.URL.Query().Get("author"))
+			w.Write([]byte(`{"docs": [{"title": "Clean Code", "author_name": ["Robert C. Martin"],
+				"first_publish_year": 2008, "isbn": ["not-an-isbn", "0132350882"], "cover_i": 42}]}`))
+		default:
+			w.Write([]byte(`{"docs": []}`))
+		}
+	})
+	provider := NewOpenLibrary(NewClient(time.Second, "byfood-library-test"), url)
+
+	t.Run("by ISBN", func(t *testing.T) {
+		metadata, err := provider.Lookup(context.Background(), entities.MetadataQuery{ISBN: "9780134190440"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "9780134190440", metadata.ISBN)
+		assert.Equal(t, "Alan A. A. Donovan", metadata.Author)
+		assert.Equal(t, 2015, metadata.Year)
+		assert.Equal(t, "Addison-Wesley", metadata.Publisher)
+		assert.Equal(t, 380, metadata.PageCount)
+		assert.Equal(t, []string{"Go (Computer program language)"}, []string(metadata.Subjects))
+		assert.Equal(t, "Includes index.", metadata.Description)
+		assert.Equal(t, "https://covers.example/l.jpg", metadata.CoverURL)
+		assert.Equal(t, "byfood-library-test", userAgent)
+	})
+
+	t.Run("by title and author", func(t *testing.T) {
+		metadata, err := provider.Lookup(context.Background(), entities.MetadataQuery{Title: "Clean Code", Author: "Robert Martin"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "9780132350884", metadata.ISBN)
+		assert.Equal(t, 2008, metadata.Year)
+		assert.Equal(t, "https://covers.openlibrary.org/b/id/42-M.jpg", metadata.Covers[CoverMedium])
+		assert.Equal(t, "https://covers.openlibrary.org/b/id/42-L.jpg", metadata.CoverURL)
+	})
+
+	t.Run("unknown book", func(t *testing.T) {
+		_, err := provider.Lookup(context.Background(), entities.MetadataQuery{ISBN: "9780201633610"})
+		assert.ErrorIs(t, err, entities.ErrMetadataNotFound)
+
+		_, err = provider.Lookup(context.Background(), entities.MetadataQuery{Title: "Nothing"})
+		assert.ErrorIs(t, err, entities.ErrMetadataNotFound)
+	})
+}
+
+func TestGoogleBooks_Lookup(t *testing.T) {
+	url := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/books/v1/volumes", r.URL.Path)
+		assert.Equal(t, "secret", r.URL.Query().Get("key"))
+		switch r.URL.Query().Get("q") {
+		case "isbn:9780201633610":
+			w.Write([]byte(`{"totalItems": 1, "items": [{"volumeInfo": {
+				"title": "Design Patterns",
+				"authors": ["Erich Gamma", "Richard Helm"],
+				"publisher": "Addison-Wesley",
+				"publishedDate": "1994-10-31",
+				"description": "Capturing a wealth of experience.",
+				"pageCount": 395,
+				"categories": ["Computers"],
+				"industryIdentifiers": [{"type": "ISBN_10", "identifier": "0201633612"}],
+				"imageLinks": {"smallThumbnail": "http://books.example/s", "thumbnail": "http://books.example/m"}
+			}}]}`))
+		case "intitle:Dune inauthor:Frank Herbert":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"totalItems": 0}`))
+		}
+	})
+	provider := NewGoogleBooks(NewClient(time.Second, ""), url, "secret")
+
+	metadata, err := provider.Lookup(context.Background(), entities.MetadataQuery{ISBN: "9780201633610"})
+	require.NoError(t, err)
+	assert.Equal(t, "9780201633610", metadata.ISBN)
+	assert.Equal(t, "Erich Gamma", metadata.Author)
+	assert.Equal(t, 1994, metadata.Year)
+	assert.Equal(t, 395, metadata.PageCount)
+	assert.Equal(t, "Capturing a wealth of experience.", metadata.Description)
+	assert.Equal(t, "https://books.example/m", metadata.CoverURL)
+	assert.Equal(t, "https://books.example/s", metadata.Covers[CoverSmall])
+
+	_, err = provider.Lookup(context.Background(), entities.MetadataQuery{Title: "Nothing"})
+	assert.ErrorIs(t, err, entities.ErrMetadataNotFound)
+
+	_, err = provider.Lookup(context.Background(), entities.MetadataQuery{Title: "Dune", Author: "Frank Herbert"})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, entities.ErrMetadataNotFound)
+}
+
+func TestFixture_Lookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"isbn": "0-13-235088-2", "title": "Clean Code", "author": "Robert C. Martin", "publisher": "Prentice Hall"},
+		{"title": "The Pragmatic Programmer", "author": "Andrew Hunt", "page_count": 352}
+	]`), 0o600))
+	fixture, err := LoadFixture(path)
+	require.NoError(t, err)
+
+	metadata, err := fixture.Lookup(context.Background(), entities.MetadataQuery{ISBN: "9780132350884"})
+	require.NoError(t, err)
+	assert.Equal(t, "Prentice Hall", metadata.Publisher)
+
+	metadata, err = fixture.Lookup(context.Background(), entities.MetadataQuery{Title: "pragmatic programmer", Author: "Hunt, Andrew"})
+	require.NoError(t, err)
+	assert.Equal(t, 352, metadata.PageCount)
+
+	_, err = fixture.Lookup(context.Background(), entities.MetadataQuery{Title: "Clean Code", Author: "Someone Else"})
+	assert.ErrorIs(t, err, entities.ErrMetadataNotFound)
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"isbn": "123"}]`), 0o600))
+	_, err = LoadFixture(path)
+	assert.ErrorIs(t, err, entities.ErrInvalidISBN)
+}
+
+func TestChain_Lookup(t *testing.T) {
+	query := entities.MetadataQuery{Title: "Clean Code"}
+
+	t.Run("fills gaps from later providers", func(t *testing.T) {
+		first := &stubProvider{name: "first", metadata: &Metadata{Title: "Clean Code", ISBN: "9780132350884"}}
+		second := &stubProvider{name: "second", metadata: &Metadata{
+			Title:        "Clean Code: A Handbook",
+			BookMetadata: entities.BookMetadata{Publisher: "Prentice Hall"},
+		}}
+
+		metadata, err := Chain{first, second}.Lookup(context.Background(), query)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Clean Code", metadata.Title)
+		assert.Equal(t, "Prentice Hall", metadata.Publisher)
+		assert.Equal(t, []string{"first", "second"}, metadata.Sources)
+		assert.Equal(t, entities.MetadataQuery{ISBN: "9780132350884"}, second.queries[0])
+	})
+
+	t.Run("stops once every field is known", func(t *testing.T) {
+		first := &stubProvider{name: "first", metadata: &Metadata{
+			ISBN: "9780132350884", Title: "Clean Code", Author: "Robert C. Martin", Year: 2008,
+			BookMetadata: entities.BookMetadata{
+				Publisher: "Prentice Hall", PageCount: 464, Subjects: []string{"Software"},
+				Description: "Even bad code can function.", CoverURL: "https://covers.example/l.jpg",
+			},
+		}}
+		second := &stubProvider{name: "second", err: errors.New("unreachable")}
+
+		_, err := Chain{first, second}.Lookup(context.Background(), query)
+
+		require.NoError(t, err)
+		assert.Empty(t, second.queries)
+	})
+
+	t.Run("falls back past failures", func(t *testing.T) {
+		failing := &stubProvider{name: "failing", err: errors.New("connection refused")}
+		working := &stubProvider{name: "working", metadata: &Metadata{Title: "Clean Code"}}
+
+		metadata, err := Chain{failing, working}.Lookup(context.Background(), query)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"working"}, metadata.Sources)
+	})
+
+	t.Run("not found or unavailable", func(t *testing.T) {
+		missing := &stubProvider{name: "missing", err: entities.ErrMetadataNotFound}
+		failing := &stubProvider{name: "failing", err: errors.New("connection refused")}
+
+		_, err := Chain{missing, missing}.Lookup(context.Background(), query)
+		assert.ErrorIs(t, err, entities.ErrMetadataNotFound)
+
+		_, err = Chain{missing, failing}.Lookup(context.Background(), query)
+		assert.ErrorIs(t, err, entities.ErrMetadataUnavailable)
+	})
+}
+
+func TestCached_Lookup(t *testing.T) {
+	provider := &stubProvider{name: "stub", metadata: &Metadata{Title: "Clean Code"}}
+	cached := NewCached(provider, cache.New(cache.NewLRU(10), cache.Options{}), time.Minute)
+
+	for _, title := range []string{"Clean Code", "clean code!"} {
+		metadata, err := cached.Lookup(context.Background(), entities.MetadataQuery{Title: title})
+		require.NoError(t, err)
+		assert.Equal(t, "Clean Code", metadata.Title)
+	}
+	assert.Len(t, provider.queries, 1)
+
+	provider.err = entities.ErrMetadataNotFound
+	for i := 0; i < 2; i++ {
+		_, err := cached.Lookup(context.Background(), entities.MetadataQuery{ISBN: "9780132350884"})
+		assert.ErrorIs(t, err, entities.ErrMetadataNotFound)
+	}
+	assert.Len(t, provider.queries, 2)
+
+	provider.err = errors.New("connection refused")
+	for i := 0; i < 2; i++ {
+		_, err := cached.Lookup(context.Background(), entities.MetadataQuery{Title: "Dune"})
+		assert.Error(t, err)
+	}
+	assert.Len(t, provider.queries, 4, "failures are not cached")
+}
+
+func TestRateLimited_Lookup(t *testing.T) {
+	stub := &stubProvider{name: "stub", metadata: &Metadata{}}
+	provider := NewRateLimited(stub, 0.001, 1)
+
+	_, err := provider.Lookup(context.Background(), entities.MetadataQuery{Title: "Dune"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = provider.Lookup(ctx, entities.MetadataQuery{Title: "Dune"})
+	assert.Error(t, err, "the second lookup would wait far beyond the deadline")
+	assert.Len(t, stub.queries, 1)
+}
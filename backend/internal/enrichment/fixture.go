@@ -0,0 +1,80 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/duplicates"
+)
+
+// Fixture answers lookups from a JSON file holding an array of Metadata
+// records, for development, tests and air-gapped installations. Records
+// match on ISBN, or on normalized title and, when the query names one,
+// author.
+type Fixture struct {
+	records []*Metadata
+}
+
+// LoadFixture reads the records in path
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var records []*Metadata
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("parsing metadata fixture %s: %w", path, err)
+	}
+	for i, record := range records {
+		if record.ISBN == "" {
+			continue
+		}
+		if record.ISBN, err = entities.NormalizeISBN(record.ISBN); err != nil {
+			return nil, fmt.Errorf("metadata fixture %s: record %d: %w", path, i, err)
+		}
+	}
+	return NewFixture(records), nil
+}
+
+func NewFixture(records []*Metadata) *Fixture {
+	return &Fixture{records: records}
+}
+
+func (f *Fixture) Name() string {
+	return "fixture"
+}
+
+func (f *Fixture) Lookup(ctx context.Context, query entities.MetadataQuery) (*Metadata, error) {
+	for _, record := range f.records {
+		if query.ISBN != "" {
+			if record.ISBN == query.ISBN {
+				return copyMetadata(record), nil
+			}
+			continue
+		}
+		if duplicates.NormalizeTitle(record.Title) != duplicates.NormalizeTitle(query.Title) {
+			continue
+		}
+		if query.Author == "" || duplicates.NormalizeAuthor(record.Author) == duplicates.NormalizeAuthor(query.Author) {
+			return copyMetadata(record), nil
+		}
+	}
+	return nil, entities.ErrMetadataNotFound
+}
+
+// copyMetadata keeps callers from changing the fixture through a result
+func copyMetadata(m *Metadata) *Metadata {
+	c := *m
+	c.Subjects = append(c.Subjects[:0:0], m.Subjects...)
+	c.Sources = nil
+	if m.Covers != nil {
+		c.Covers = make(map[string]string, len(m.Covers))
+		for size, url := range m.Covers {
+			c.Covers[size] = url
+		}
+	}
+	return &c
+}
@@ -0,0 +1,105 @@
+package enrichment
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"byfood-library/internal/domain/entities"
+)
+
+// DefaultGoogleBooksURL is the public Google Books API
+const DefaultGoogleBooksURL = "https://www.googleapis.com"
+
+// GoogleBooks looks books up in the Google Books volumes API. The API key
+// is optional; without one requests share an anonymous quota.
+type GoogleBooks struct {
+	client  *Client
+	baseURL string
+	apiKey  string
+}
+
+func NewGoogleBooks(client *Client, baseURL, apiKey string) *GoogleBooks {
+	if baseURL == "" {
+		baseURL = DefaultGoogleBooksURL
+	}
+	return &GoogleBooks{client: client, baseURL: strings.TrimSuffix(baseURL, "/"), apiKey: apiKey}
+}
+
+func (g *GoogleBooks) Name() string {
+	return "googlebooks"
+}
+
+type googleVolumes struct {
+	Items []struct {
+		VolumeInfo struct {
+			Title               string   `json:"title"`
+			Authors             []string `json:"authors"`
+			Publisher           string   `json:"publisher"`
+			PublishedDate       string   `json:"publishedDate"`
+			Description         string   `json:"description"`
+			PageCount           int      `json:"pageCount"`
+			Categories          []string `json:"categories"`
+			IndustryIdentifiers []struct {
+				Type       string `json:"type"`
+				Identifier string `json:"identifier"`
+			} `json:"industryIdentifiers"`
+			ImageLinks map[string]string `json:"imageLinks"`
+		} `json:"volumeInfo"`
+	} `json:"items"`
+}
+
+func (g *GoogleBooks) Lookup(ctx context.Context, query entities.MetadataQuery) (*Metadata, error) {
+	terms := "isbn:" + query.ISBN
+	if query.ISBN == "" {
+		terms = "intitle:" + query.Title
+		if query.Author != "" {
+			terms += " inauthor:" + query.Author
+		}
+	}
+	params := url.Values{"q": {terms}, "maxResults": {"1"}}
+	if g.apiKey != "" {
+		params.Set("key", g.apiKey)
+	}
+
+	var volumes googleVolumes
+	if err := g.client.getJSON(ctx, g.baseURL+"/books/v1/volumes?"+params.Encode(), &volumes); err != nil {
+		return nil, err
+	}
+	if len(volumes.Items) == 0 {
+		return nil, entities.ErrMetadataNotFound
+	}
+
+	info := volumes.Items[0].VolumeInfo
+	var isbns []string
+	for _, id := range info.IndustryIdentifiers {
+		if id.Type == "ISBN_13" || id.Type == "ISBN_10" {
+			isbns = append(isbns, id.Identifier)
+		}
+	}
+	metadata := &Metadata{
+		ISBN:  firstISBN(isbns...),
+		Title: info.Title,
+		Year:  parseYear(info.PublishedDate),
+	}
+	if len(info.Authors) > 0 {
+		metadata.Author = info.Authors[0]
+	}
+	metadata.Publisher = info.Publisher
+	metadata.PageCount = info.PageCount
+	metadata.Subjects = info.Categories
+	metadata.Description = info.Description
+
+	// Thumbnails come as http URLs; the same images are served over https
+	covers := map[string]string{}
+	for size, key := range map[string]string{CoverSmall: "smallThumbnail", CoverMedium: "thumbnail"} {
+		if link := info.ImageLinks[key]; link != "" {
+			covers[size] = strings.Replace(link, "http://", "https://", 1)
+		}
+	}
+	if len(covers) > 0 {
+		metadata.Covers = covers
+		metadata.CoverURL = bestCover(covers)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,181 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"byfood-library/internal/domain/entities"
+)
+
+const (
+	// DefaultOpenLibraryURL is the public Open Library API
+	DefaultOpenLibraryURL = "https://openlibrary.org"
+
+	openLibraryCoversURL = "https://covers.openlibrary.org/b/id/"
+)
+
+// OpenLibrary looks books up in Open Library: by ISBN through the Books
+// API and by title and author through the search API
+type OpenLibrary struct {
+	client  *Client
+	baseURL string
+}
+
+func NewOpenLibrary(client *Client, baseURL string) *OpenLibrary {
+	if baseURL == "" {
+		baseURL = DefaultOpenLibraryURL
+	}
+	return &OpenLibrary{client: client, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (o *OpenLibrary) Name() string {
+	return "openlibrary"
+}
+
+func (o *OpenLibrary) Lookup(ctx context.Context, query entities.MetadataQuery) (*Metadata, error) {
+	if query.ISBN != "" {
+		return o.lookupISBN(ctx, query.ISBN)
+	}
+	return o.search(ctx, query)
+}
+
+type openLibraryName struct {
+	Name string `json:"name"`
+}
+
+// openLibraryText is a description or note, which Open Library gives
+// either as a string or as {"type": "/type/text", "value": "..."}
+type openLibraryText string
+
+func (t *openLibraryText) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*t = openLibraryText(text)
+		return nil
+	}
+	var typed struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return err
+	}
+	*t = openLibraryText(typed.Value)
+	return nil
+}
+
+type openLibraryBook struct {
+	Title         string            `json:"title"`
+	Authors       []openLibraryName `json:"authors"`
+	Publishers    []openLibraryName `json:"publishers"`
+	PublishDate   string            `json:"publish_date"`
+	NumberOfPages int               `json:"number_of_pages"`
+	Subjects      []openLibraryName `json:"subjects"`
+	Notes         openLibraryText   `json:"notes"`
+	Cover         map[string]string `json:"cover"`
+	Identifiers   struct {
+		ISBN13 []string `json:"isbn_13"`
+		ISBN10 []string `json:"isbn_10"`
+	} `json:"identifiers"`
+}
+
+func (o *OpenLibrary) lookupISBN(ctx context.Context, isbn string) (*Metadata, error) {
+	key := "ISBN:" + isbn
+	var books map[string]openLibraryBook
+	endpoint := o.baseURL + "/api/books?" + url.Values{
+		"bibkeys": {key},
+		"format":  {"json"},
+		"jscmd":   {"data"},
+	}.Encode()
+	if err := o.client.getJSON(ctx, endpoint, &books); err != nil {
+		return nil, err
+	}
+	book, ok := books[key]
+	if !ok {
+		return nil, entities.ErrMetadataNotFound
+	}
+
+	metadata := &Metadata{
+		ISBN:  firstISBN(append(book.Identifiers.ISBN13, append(book.Identifiers.ISBN10, isbn)...)...),
+		Title: book.Title,
+		Year:  parseYear(book.PublishDate),
+	}
+	if len(book.Authors) > 0 {
+		metadata.Author = book.Authors[0].Name
+	}
+	if len(book.Publishers) > 0 {
+		metadata.Publisher = book.Publishers[0].Name
+	}
+	metadata.PageCount = book.NumberOfPages
+	for _, subject := range book.Subjects {
+		metadata.Subjects = append(metadata.Subjects, subject.Name)
+	}
+	metadata.Description = string(book.Notes)
+	if len(book.Cover) > 0 {
+		metadata.Covers = map[string]string{}
+		for _, size := range []string{CoverSmall, CoverMedium, CoverLarge} {
+			if book.Cover[size] != "" {
+				metadata.Covers[size] = book.Cover[size]
+			}
+		}
+		metadata.CoverURL = bestCover(metadata.Covers)
+	}
+	return metadata, nil
+}
+
+type openLibrarySearch struct {
+	Docs []struct {
+		Title            string   `json:"title"`
+		AuthorName       []string `json:"author_name"`
+		FirstPublishYear int      `json:"first_publish_year"`
+		ISBN             []string `json:"isbn"`
+		Publisher        []string `json:"publisher"`
+		Pages            int      `json:"number_of_pages_median"`
+		Subject          []string `json:"subject"`
+		CoverID          int      `json:"cover_i"`
+	} `json:"docs"`
+}
+
+func (o *OpenLibrary) search(ctx context.Context, query entities.MetadataQuery) (*Metadata, error) {
+	params := url.Values{
+		"title":  {query.Title},
+		"limit":  {"1"},
+		"fields": {"title,author_name,first_publish_year,isbn,publisher,number_of_pages_median,subject,cover_i"},
+	}
+	if query.Author != "" {
+		params.Set("author", query.Author)
+	}
+	var result openLibrarySearch
+	if err := o.client.getJSON(ctx, o.baseURL+"/search.json?"+params.Encode(), &result); err != nil {
+		return nil, err
+	}
+	if len(result.Docs) == 0 {
+		return nil, entities.ErrMetadataNotFound
+	}
+
+	doc := result.Docs[0]
+	metadata := &Metadata{
+		ISBN:  firstISBN(doc.ISBN...),
+		Title: doc.Title,
+		Year:  doc.FirstPublishYear,
+	}
+	if len(doc.AuthorName) > 0 {
+		metadata.Author = doc.AuthorName[0]
+	}
+	if len(doc.Publisher) > 0 {
+		metadata.Publisher = doc.Publisher[0]
+	}
+	metadata.PageCount = doc.Pages
+	metadata.Subjects = doc.Subject
+	if doc.CoverID > 0 {
+		metadata.Covers = map[string]string{
+			CoverSmall:  fmt.Sprintf("%s%d-S.jpg", openLibraryCoversURL, doc.CoverID),
+			CoverMedium: fmt.Sprintf("%s%d-M.jpg", openLibraryCoversURL, doc.CoverID),
+			CoverLarge:  fmt.Sprintf("%s%d-L.jpg", openLibraryCoversURL, doc.CoverID),
+		}
+		metadata.CoverURL = metadata.Covers[CoverLarge]
+	}
+	return metadata, nil
+}
@@ -0,0 +1,114 @@
+package gql
+
+import (
+	"errors"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// Codes reported in an error's extensions.code, following the names Apollo
+// clients already understand
+const (
+	CodeParseFailed                = "GRAPHQL_PARSE_FAILED"
+	CodeValidationFailed           = "GRAPHQL_VALIDATION_FAILED"
+	CodeBadRequest                 = "BAD_REQUEST"
+	CodeBadUserInput               = "BAD_USER_INPUT"
+	CodeNotFound                   = "NOT_FOUND"
+	CodeConflict                   = "CONFLICT"
+	CodeQueryTooDeep               = "QUERY_TOO_DEEP"
+	CodeQueryTooComplex            = "QUERY_TOO_COMPLEX"
+	CodePersistedQueryNotFound     = "PERSISTED_QUERY_NOT_FOUND"
+	CodePersistedQueryNotSupported = "PERSISTED_QUERY_NOT_SUPPORTED"
+	CodePersistedQueryRequired     = "PERSISTED_QUERY_REQUIRED"
+	CodeTimeout                    = "TIMEOUT"
+	CodeUnavailable                = "SERVICE_UNAVAILABLE"
+	CodeInternal                   = "INTERNAL_SERVER_ERROR"
+)
+
+// requestError rejects a request before it is executed
+func requestError(code, message string) gqlerrors.FormattedError {
+	err := gqlerrors.NewFormattedError(message)
+	err.Extensions = map[string]interface{}{"code": code}
+	return err
+}
+
+func withCode(errs []gqlerrors.FormattedError, code string) []gqlerrors.FormattedError {
+	for i := range errs {
+		errs[i].Extensions = map[string]interface{}{"code": code}
+	}
+	return errs
+}
+
+// codeFor classifies errors returned by the use case by their domain kind;
+// errors from outside the domain get no code
+func codeFor(err error) string {
+	var domainErr *entities.Error
+	if !errors.As(err, &domainErr) {
+		return ""
+	}
+	if errors.Is(err, entities.ErrTenantRequired) {
+		return CodeBadRequest
+	}
+	switch domainErr.Kind {
+	case entities.KindNotFound:
+		return CodeNotFound
+	case entities.KindInvalid:
+		return CodeBadUserInput
+	case entities.KindConflict:
+		return CodeConflict
+	case entities.KindTimeout:
+		return CodeTimeout
+	case entities.KindUnavailable:
+		return CodeUnavailable
+	}
+	return CodeInternal
+}
+
+// originalError digs the resolver's error out of the wrappers the executor
+// adds around it
+func originalError(err error) error {
+	for {
+		switch wrapped := err.(type) {
+		case gqlerrors.FormattedError:
+			if wrapped.OriginalError() == nil {
+				return err
+			}
+			err = wrapped.OriginalError()
+		case *gqlerrors.Error:
+			if wrapped.OriginalError == nil {
+				return err
+			}
+			err = wrapped.OriginalError
+		default:
+			return err
+		}
+	}
+}
+
+// classify adds codes to execution errors raised by the use case
+func classify(errs []gqlerrors.FormattedError) {
+	for i := range errs {
+		if errs[i].Extensions != nil {
+			continue
+		}
+		err := originalError(errs[i])
+		code := codeFor(err)
+		if code == "" {
+			continue
+		}
+		errs[i].Extensions = map[string]interface{}{"code": code}
+
+		var validation *entities.ValidationError
+		var duplicate *entities.DuplicateError
+		switch {
+		case errors.As(err, &validation):
+			// Pointers are relative to the mutation's input argument
+			errs[i].Extensions["errors"] = validation.Fields
+		case errors.As(err, &duplicate):
+			errs[i].Extensions["duplicates"] = duplicate.Candidates
+		case code == CodeInternal:
+			errs[i].Message = "internal server error"
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// memoryBooks is a BookUseCase over a map that records batched lookups
+type memoryBooks struct {
+	books   map[uuid.UUID]*entities.Book
+	batches [][]uuid.UUID
+}
+
+func newMemoryBooks(books ...*entities.Book) *memoryBooks {
+	m := &memoryBooks{books: map[uuid.UUID]*entities.Book{}}
+	for _, book := range books {
+		m.books[book.ID] = book
+	}
+	return m
+}
+
+func (m *memoryBooks) CreateBook(ctx context.Context, dto *entities.CreateBookDTO) (*entities.Book, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+	book := dto.ToBook()
+	for _, existing := range m.books {
+		if existing.Title == book.Title && !dto.Force {
+			return nil, &entities.DuplicateError{Candidates: []entities.DuplicateCandidate{
+				{Book: existing, Score: 1, Reason: entities.DuplicateReasonSimilar},
+			}}
+		}
+	}
+	m.books[book.ID] = book
+	return book, nil
+}
+
+func (m *memoryBooks) GetBookByID(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	if book, ok := m.books[id]; ok {
+		return book, nil
+	}
+	return nil, entities.ErrBookNotFound
+}
+
+func (m *memoryBooks) GetBooksByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	m.batches = append(m.batches, ids)
+	var books []*entities.Book
+	for _, id := range ids {
+		if book, ok := m.books[id]; ok {
+			books = append(books, book)
+		}
+	}
+	return books, nil
+}
+
+func (m *memoryBooks) GetAllBooks(ctx context.Context) ([]*entities.Book, error) {
+	var books []*entities.Book
+	for _, book := range m.books {
+		books = append(books, book)
+	}
+	return books, nil
+}
+
+func (m *memoryBooks) UpdateBook(ctx context.Context, id uuid.UUID, dto *entities.UpdateBookDTO) (*entities.Book, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+	book, ok := m.books[id]
+	if !ok {
+		return nil, entities.ErrBookNotFound
+	}
+	dto.ApplyTo(book)
+	return book, nil
+}
+
+func (m *memoryBooks) DeleteBook(ctx context.Context, id uuid.UUID) error {
+	if _, ok := m.books[id]; !ok {
+		return entities.ErrBookNotFound
+	}
+	delete(m.books, id)
+	return nil
+}
+
+func (m *memoryBooks) FindDuplicates(ctx context.Context) ([]entities.DuplicateGroup, error) {
+	return nil, nil
+}
+
+func (m *memoryBooks) MergeBooks(ctx context.Context, dto *entities.MergeBooksDTO) (*entities.Book, error) {
+	return nil, entities.ErrBookNotFound
+}
+
+func testBook(title string) *entities.Book {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &entities.Book{ID: uuid.New(), TenantID: uuid.New(), Title: title, Author: "Author", Year: 2020, CreatedAt: now, UpdatedAt: now}
+}
+
+func newTestServer(t *testing.T, books *memoryBooks, config Config) *Server {
+	server, err := NewServer(books, config, zap.NewNop())
+	require.NoError(t, err)
+	return server
+}
+
+// data round-trips a result through JSON as a client would see it
+func data(t *testing.T, result *graphql.Result) map[string]interface{} {
+	encoded, err := json.Marshal(result.Data)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	return decoded
+}
+
+func codes(result *graphql.Result) []string {
+	var out []string
+	for _, err := range result.Errors {
+		code, _ := err.Extensions["code"].(string)
+		out = append(out, code)
+	}
+	return out
+}
+
+func TestNewSchema(t *testing.T) {
+	server := newTestServer(t, newMemoryBooks(), Config{})
+
+	fields := server.Schema().Type("Book").(*graphql.Object).Fields()
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{
+		"author", "coverEtag", "coverUrl", "createdAt", "description", "id", "isbn", "pageCount", "publisher", "subjects", "title", "updatedAt", "year",
+	}, names)
+	assert.Equal(t, "ID!", fields["id"].Type.String())
+	assert.Equal(t, "DateTime!", fields["createdAt"].Type.String())
+	assert.Equal(t, "String", fields["isbn"].Type.String())
+	assert.Equal(t, "[String!]", fields["subjects"].Type.String())
+
+	input := server.Schema().Type("CreateBookInput").(*graphql.InputObject).Fields()
+	assert.Len(t, input, 9)
+	assert.Equal(t, "Int!", input["year"].Type.String())
+}
+
+func TestServer_Execute(t *testing.T) {
+	first, second := testBook("First"), testBook("Second")
+
+	t.Run("batches book lookups", func(t *testing.T) {
+		books := newMemoryBooks(first, second)
+		server := newTestServer(t, books, Config{})
+		missing := uuid.New()
+
+		result, executed := server.Execute(context.Background(), &Request{
+			Query: `query($a: ID!, $b: ID!, $c: ID!) {
+				a: book(id: $a) { title createdAt }
+				b: book(id: $b) { title }
+				again: book(id: $a) { author }
+				c: book(id: $c) { title }
+			}`,
+			Variables: map[string]interface{}{"a": first.ID.String(), "b": second.ID.String(), "c": missing.String()},
+		}, true)
+
+		assert.True(t, executed)
+		assert.Empty(t, result.Errors)
+		got := data(t, result)
+		assert.Equal(t, map[string]interface{}{"title": "First", "createdAt": "2024-01-02T03:04:05Z"}, got["a"])
+		assert.Equal(t, "Second", got["b"].(map[string]interface{})["title"])
+		assert.Equal(t, "Author", got["again"].(map[string]interface{})["author"])
+		assert.Nil(t, got["c"])
+		// the executor resolves sibling fields in no particular order
+		if assert.Len(t, books.batches, 1) {
+			assert.ElementsMatch(t, []uuid.UUID{first.ID, second.ID, missing}, books.batches[0])
+		}
+	})
+
+	t.Run("reuses books already listed", func(t *testing.T) {
+		books := newMemoryBooks(first)
+		server := newTestServer(t, books, Config{})
+
+		result, _ := server.Execute(context.Background(), &Request{
+			Query: `{ books { id } book(id: "` + first.ID.String() + `") { title } }`,
+		}, true)
+
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, "First", data(t, result)["book"].(map[string]interface{})["title"])
+		assert.Empty(t, books.batches)
+	})
+
+	t.Run("runs mutations through the use case", func(t *testing.T) {
+		books := newMemoryBooks(first)
+		server := newTestServer(t, books, Config{})
+
+		result, _ := server.Execute(context.Background(), &Request{
+			Query: `mutation {
+				created: createBook(input: {title: "New", author: "Writer", year: 2001, subjects: ["Fiction"]}) { id title subjects }
+				updated: updateBook(id: "` + first.ID.String() + `", input: {title: "Renamed", author: "Author", year: 2020}) { title }
+			}`,
+		}, false)
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, []interface{}{"Fiction"}, data(t, result)["created"].(map[string]interface{})["subjects"])
+		assert.Equal(t, "Renamed", data(t, result)["updated"].(map[string]interface{})["title"])
+		assert.Len(t, books.books, 2)
+
+		result, _ = server.Execute(context.Background(), &Request{
+			Query: `mutation { deleteBook(id: "` + first.ID.String() + `") }`,
+		}, false)
+		assert.Empty(t, result.Errors)
+		assert.Equal(t, first.ID.String(), data(t, result)["deleteBook"])
+	})
+
+	t.Run("reports use case errors with codes", func(t *testing.T) {
+		server := newTestServer(t, newMemoryBooks(), Config{})
+
+		result, executed := server.Execute(context.Background(), &Request{
+			Query: `mutation { createBook(input: {title: "New", author: "Writer", year: 3000}) { id } }`,
+		}, false)
+		assert.True(t, executed)
+		assert.Equal(t, []string{CodeBadUserInput}, codes(result))
+		assert.Equal(t, "year must be between 1000 and 2034", result.Errors[0].Message)
+
+		result, _ = server.Execute(context.Background(), &Request{
+			Query: `mutation { deleteBook(id: "` + uuid.NewString() + `") }`,
+		}, false)
+		assert.Equal(t, []string{CodeNotFound}, codes(result))
+
+		result, _ = server.Execute(context.Background(), &Request{Query: `{ book(id: "nope") { id } }`}, true)
+		assert.Equal(t, []string{CodeBadUserInput}, codes(result))
+	})
+
+	t.Run("rejects bad requests before running them", func(t *testing.T) {
+		server := newTestServer(t, newMemoryBooks(), Config{})
+
+		for name, tc := range map[string]struct {
+			query string
+			code  string
+		}{
+			"parse error":      {`{ books {`, CodeParseFailed},
+			"unknown field":    {`{ books { tenant } }`, CodeValidationFailed},
+			"mutation via GET": {`mutation { deleteBook(id: "x") }`, CodeBadRequest},
+		} {
+			result, executed := server.Execute(context.Background(), &Request{Query: tc.query}, true)
+			assert.False(t, executed, name)
+			assert.Equal(t, []string{tc.code}, codes(result), name)
+		}
+	})
+}
+
+func TestServer_Limits(t *testing.T) {
+	query := `query { books { id title ...more } } fragment more on Book { author year }`
+
+	t.Run("scores list fields by the assumed list size", func(t *testing.T) {
+		server := newTestServer(t, newMemoryBooks(), Config{})
+		doc, op := parse(t, query)
+
+		assert.Equal(t, cost{depth: 2, complexity: 41}, measure(server.Schema(), doc, op, 10))
+	})
+
+	t.Run("ignores introspection", func(t *testing.T) {
+		server := newTestServer(t, newMemoryBooks(), Config{MaxDepth: 1})
+
+		result, executed := server.Execute(context.Background(), &Request{
+			Query: `{ __schema { types { name fields { name type { name ofType { name } } } } } }`,
+		}, true)
+		assert.True(t, executed)
+		assert.Empty(t, result.Errors)
+	})
+
+	t.Run("scores each fragment once however often it is spread", func(t *testing.T) {
+		server := newTestServer(t, newMemoryBooks(), Config{})
+		var fanOut strings.Builder
+		fanOut.WriteString(`query { books { ...f0 } }`)
+		for i := 0; i < 30; i++ {
+			fmt.Fprintf(&fanOut, ` fragment f%d on Book { id ...f%d ...f%d }`, i, i+1, i+1)
+		}
+		fanOut.WriteString(` fragment f30 on Book { title }`)
+		doc, op := parse(t, fanOut.String())
+
+		start := time.Now()
+		c := measure(server.Schema(), doc, op, 10)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, cost{depth: 2, complexity: (1<<31-1)*10 + 1}, c)
+
+		result, executed := server.Execute(context.Background(), &Request{Query: fanOut.String()}, true)
+		assert.False(t, executed)
+		assert.Equal(t, []string{CodeQueryTooComplex}, codes(result))
+	})
+
+	for name, tc := range map[string]struct {
+		config Config
+		code   string
+	}{
+		"depth":      {Config{MaxDepth: 1}, CodeQueryTooDeep},
+		"complexity": {Config{MaxComplexity: 40}, CodeQueryTooComplex},
+	} {
+		t.Run("rejects queries over the "+name+" limit", func(t *testing.T) {
+			server := newTestServer(t, newMemoryBooks(), tc.config)
+
+			result, executed := server.Execute(context.Background(), &Request{Query: query}, true)
+			assert.False(t, executed)
+			assert.Equal(t, []string{tc.code}, codes(result))
+		})
+	}
+}
+
+func TestServer_PersistedQueries(t *testing.T) {
+	book := testBook("First")
+	query := `{ books { title } }`
+	hashed := func(hash, query string) *Request {
+		return &Request{Query: query, Extensions: RequestExtensions{
+			PersistedQuery: &PersistedQuery{Version: 1, SHA256Hash: hash},
+		}}
+	}
+
+	t.Run("auto registers unknown queries", func(t *testing.T) {
+		server := newTestServer(t, newMemoryBooks(book), Config{PersistedQueries: PersistedQueriesAuto})
+
+		result, _ := server.Execute(context.Background(), hashed(HashQuery(query), ""), true)
+		assert.Equal(t, []string{CodePersistedQueryNotFound}, codes(result))
+		assert.Equal(t, "PersistedQueryNotFound", result.Errors[0].Message)
+
+		result, _ = server.Execute(context.Background(), hashed(HashQuery("{ other }"), query), true)
+		assert.Equal(t, []string{CodeBadRequest}, codes(result))
+
+		result, executed := server.Execute(context.Background(), hashed(HashQuery(query), query), true)
+		assert.True(t, executed)
+		assert.Empty(t, result.Errors)
+
+		result, executed = server.Execute(context.Background(), hashed(HashQuery(query), ""), true)
+		assert.True(t, executed)
+		assert.Len(t, data(t, result)["books"], 1)
+	})
+
+	t.Run("only runs allowlisted queries", func(t *testing.T) {
+		server := newTestServer(t, newMemoryBooks(book), Config{
+			PersistedQueries: PersistedQueriesOnly,
+			AllowedQueries:   map[string]string{HashQuery(query): query},
+		})
+
+		_, executed := server.Execute(context.Background(), hashed(HashQuery(query), ""), true)
+		assert.True(t, executed)
+
+		result, _ := server.Execute(context.Background(), &Request{Query: query}, true)
+		assert.Equal(t, []string{CodePersistedQueryRequired}, codes(result))
+
+		result, _ = server.Execute(context.Background(), hashed(HashQuery("{ books { id } }"), "{ books { id } }"), true)
+		assert.Equal(t, []string{CodePersistedQueryNotFound}, codes(result))
+	})
+
+	t.Run("off refuses hashes", func(t *testing.T) {
+		server := newTestServer(t, newMemoryBooks(book), Config{})
+
+		result, _ := server.Execute(context.Background(), hashed(HashQuery(query), query), true)
+		assert.Equal(t, []string{CodePersistedQueryNotSupported}, codes(result))
+	})
+}
+
+func parse(t *testing.T, query string) (*ast.Document, *ast.OperationDefinition) {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	require.NoError(t, err)
+	return doc, operation(doc, "")
+}
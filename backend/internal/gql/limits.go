@@ -0,0 +1,139 @@
+package gql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// cost is what an operation would take to run: how deeply its fields nest
+// and how many fields it would resolve
+type cost struct {
+	depth      int
+	complexity int
+}
+
+// measure scores op. Every field costs one, and fields returning lists
+// multiply the cost of their selections by listCost, the number of items
+// assumed per list. Introspection fields are free, so tools can load the
+// schema whatever the limits. Validation must already have passed, which
+// rules out fragment cycles. Each fragment is scored once per parent type,
+// so fragments spreading one another many times cannot make scoring itself
+// the expensive part.
+func measure(schema *graphql.Schema, doc *ast.Document, op *ast.OperationDefinition, listCost int) cost {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	var root *graphql.Object
+	switch op.Operation {
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	default:
+		root = schema.QueryType()
+	}
+
+	type fragmentKey struct {
+		name   string
+		parent *graphql.Object
+	}
+	scored := map[fragmentKey]cost{}
+
+	var walk func(set *ast.SelectionSet, parent *graphql.Object) cost
+	walk = func(set *ast.SelectionSet, parent *graphql.Object) cost {
+		var total cost
+		if set == nil {
+			return total
+		}
+		add := func(c cost) {
+			total.complexity += c.complexity
+			if c.depth > total.depth {
+				total.depth = c.depth
+			}
+		}
+		for _, selection := range set.Selections {
+			switch selection := selection.(type) {
+			case *ast.Field:
+				if strings.HasPrefix(selection.Name.Value, "__") {
+					continue
+				}
+				child, list := fieldType(parent, selection.Name.Value)
+				c := walk(selection.SelectionSet, child)
+				if list {
+					c.complexity *= listCost
+				}
+				add(cost{depth: c.depth + 1, complexity: c.complexity + 1})
+			case *ast.InlineFragment:
+				add(walk(selection.SelectionSet, typeCondition(schema, selection.TypeCondition, parent)))
+			case *ast.FragmentSpread:
+				fragment, ok := fragments[selection.Name.Value]
+				if !ok {
+					continue
+				}
+				key := fragmentKey{name: fragment.Name.Value, parent: parent}
+				c, ok := scored[key]
+				if !ok {
+					c = walk(fragment.SelectionSet, typeCondition(schema, fragment.TypeCondition, parent))
+					scored[key] = c
+				}
+				add(c)
+			}
+		}
+		return total
+	}
+	return walk(op.SelectionSet, root)
+}
+
+// fieldType returns the object a field resolves to, if any, and whether it
+// is a list
+func fieldType(parent *graphql.Object, name string) (*graphql.Object, bool) {
+	if parent == nil {
+		return nil, false
+	}
+	field, ok := parent.Fields()[name]
+	if !ok {
+		return nil, false
+	}
+
+	var list bool
+	t := field.Type
+	for {
+		if nonNull, ok := t.(*graphql.NonNull); ok {
+			t = nonNull.OfType
+			continue
+		}
+		if of, ok := t.(*graphql.List); ok {
+			list = true
+			t = of.OfType
+			continue
+		}
+		break
+	}
+	object, _ := t.(*graphql.Object)
+	return object, list
+}
+
+func typeCondition(schema *graphql.Schema, condition *ast.Named, parent *graphql.Object) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+	object, _ := schema.Type(condition.Name.Value).(*graphql.Object)
+	return object
+}
+
+// checkLimits rejects operations deeper or costlier than allowed
+func (s *Server) checkLimits(doc *ast.Document, op *ast.OperationDefinition) error {
+	c := measure(&s.schema, doc, op, s.config.ListCost)
+	if c.depth > s.config.MaxDepth {
+		return requestError(CodeQueryTooDeep, fmt.Sprintf("query depth %d exceeds the limit of %d", c.depth, s.config.MaxDepth))
+	}
+	if c.complexity > s.config.MaxComplexity {
+		return requestError(CodeQueryTooComplex, fmt.Sprintf("query complexity %d exceeds the limit of %d", c.complexity, s.config.MaxComplexity))
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package gql
+
+import (
+	"context"
+	"sync"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+)
+
+type loaderKey struct{}
+
+// bookLoader collects the books requested while one level of a query is
+// resolved and fetches them with a single GetBooksByIDs call when the first
+// result is needed. Loaded books are remembered for the rest of the request.
+type bookLoader struct {
+	books usecases.BookUseCase
+
+	mu      sync.Mutex
+	pending []uuid.UUID
+	queued  map[uuid.UUID]bool
+	loaded  map[uuid.UUID]loadResult
+}
+
+type loadResult struct {
+	book *entities.Book
+	err  error
+}
+
+func newBookLoader(books usecases.BookUseCase) *bookLoader {
+	return &bookLoader{
+		books:  books,
+		queued: make(map[uuid.UUID]bool),
+		loaded: make(map[uuid.UUID]loadResult),
+	}
+}
+
+func withLoader(ctx context.Context, loader *bookLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, loader)
+}
+
+// loaderFromContext returns the request's loader, or an unshared one when
+// resolvers run outside Server.Execute
+func loaderFromContext(ctx context.Context, books usecases.BookUseCase) *bookLoader {
+	if loader, ok := ctx.Value(loaderKey{}).(*bookLoader); ok {
+		return loader
+	}
+	return newBookLoader(books)
+}
+
+// Load queues id and returns a thunk resolving to its book, or to nil if it
+// does not exist. The executor runs thunks once the surrounding level has
+// been resolved, by which time all of its ids are queued.
+func (l *bookLoader) Load(ctx context.Context, id uuid.UUID) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.loaded[id]; !ok && !l.queued[id] {
+		l.queued[id] = true
+		l.pending = append(l.pending, id)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		result, ok := l.loaded[id]
+		if !ok {
+			l.dispatch(ctx)
+			result = l.loaded[id]
+		}
+		if result.err != nil || result.book == nil {
+			return nil, result.err
+		}
+		return result.book, nil
+	}
+}
+
+// Prime records books fetched by other resolvers so later lookups reuse them
+func (l *bookLoader) Prime(books ...*entities.Book) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, book := range books {
+		l.loaded[book.ID] = loadResult{book: book}
+	}
+}
+
+// Forget drops a deleted book
+func (l *bookLoader) Forget(id uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loaded[id] = loadResult{}
+}
+
+// dispatch fetches every queued id; l.mu must be held
+func (l *bookLoader) dispatch(ctx context.Context) {
+	ids := l.pending
+	l.pending = nil
+	l.queued = make(map[uuid.UUID]bool)
+
+	books, err := l.books.GetBooksByIDs(ctx, ids)
+	for _, id := range ids {
+		l.loaded[id] = loadResult{err: err}
+	}
+	if err != nil {
+		return
+	}
+	for _, book := range books {
+		l.loaded[book.ID] = loadResult{book: book}
+	}
+}
@@ -0,0 +1,106 @@
+package gql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"byfood-library/internal/cache"
+)
+
+// Persisted query modes
+const (
+	// PersistedQueriesOff runs query text only
+	PersistedQueriesOff = "off"
+	// PersistedQueriesAuto follows Apollo's automatic persisted queries:
+	// clients send a hash, and the full query only when the hash is unknown
+	PersistedQueriesAuto = "auto"
+	// PersistedQueriesOnly runs nothing but the queries in an allowlist
+	PersistedQueriesOnly = "only"
+)
+
+// PersistedQuery is the persistedQuery request extension
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// HashQuery returns the key a persisted query is stored under
+func HashQuery(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadPersistedQueries reads an allowlist for PersistedQueriesOnly mode: a
+// JSON object mapping each query's SHA-256 hash to its text
+func LoadPersistedQueries(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var queries map[string]string
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	allowed := make(map[string]string, len(queries))
+	for hash, query := range queries {
+		hash = strings.ToLower(hash)
+		if HashQuery(query) != hash {
+			return nil, fmt.Errorf("%s: hash %s does not match its query", path, hash)
+		}
+		allowed[hash] = query
+	}
+	return allowed, nil
+}
+
+// persistedQueries finds the query text of a request according to mode
+type persistedQueries struct {
+	mode string
+	// registered holds queries sent in auto mode
+	registered *cache.LRU
+	allowed    map[string]string
+}
+
+func (p *persistedQueries) resolve(ctx context.Context, req *Request) (string, error) {
+	extension := req.Extensions.PersistedQuery
+	if extension == nil {
+		if p.mode == PersistedQueriesOnly {
+			return "", requestError(CodePersistedQueryRequired, "only persisted queries are accepted")
+		}
+		return req.Query, nil
+	}
+
+	if p.mode == PersistedQueriesOff {
+		return "", requestError(CodePersistedQueryNotSupported, "PersistedQueryNotSupported")
+	}
+	if extension.Version != 1 {
+		return "", requestError(CodeBadRequest, "unsupported persisted query version")
+	}
+	hash := strings.ToLower(extension.SHA256Hash)
+
+	if p.mode == PersistedQueriesOnly {
+		query, ok := p.allowed[hash]
+		if !ok {
+			return "", requestError(CodePersistedQueryNotFound, "PersistedQueryNotFound")
+		}
+		return query, nil
+	}
+
+	if req.Query == "" {
+		query, ok, _ := p.registered.Get(ctx, hash)
+		if !ok {
+			return "", requestError(CodePersistedQueryNotFound, "PersistedQueryNotFound")
+		}
+		return string(query), nil
+	}
+	if HashQuery(req.Query) != hash {
+		return "", requestError(CodeBadRequest, "provided sha256Hash does not match the query")
+	}
+	p.registered.Set(ctx, hash, []byte(req.Query), 0)
+	return req.Query, nil
+}
@@ -0,0 +1,89 @@
+package gql
+
+import (
+	"fmt"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+type resolver struct {
+	books usecases.BookUseCase
+}
+
+func idArgument(p graphql.ResolveParams) (uuid.UUID, error) {
+	id, err := uuid.Parse(fmt.Sprint(p.Args["id"]))
+	if err != nil {
+		return uuid.Nil, entities.ErrInvalidUUID
+	}
+	return id, nil
+}
+
+func inputArgument(p graphql.ResolveParams, target interface{}) error {
+	input, _ := p.Args["input"].(map[string]interface{})
+	return decodeInput(input, target)
+}
+
+// book defers to the request's loader, so every book looked up at the same
+// depth of a query is fetched with one call
+func (r *resolver) book(p graphql.ResolveParams) (interface{}, error) {
+	id, err := idArgument(p)
+	if err != nil {
+		return nil, err
+	}
+	return loaderFromContext(p.Context, r.books).Load(p.Context, id), nil
+}
+
+func (r *resolver) allBooks(p graphql.ResolveParams) (interface{}, error) {
+	books, err := r.books.GetAllBooks(p.Context)
+	if err != nil {
+		return nil, err
+	}
+	loaderFromContext(p.Context, r.books).Prime(books...)
+	return books, nil
+}
+
+func (r *resolver) createBook(p graphql.ResolveParams) (interface{}, error) {
+	var dto entities.CreateBookDTO
+	if err := inputArgument(p, &dto); err != nil {
+		return nil, err
+	}
+	dto.Force, _ = p.Args["force"].(bool)
+	book, err := r.books.CreateBook(p.Context, &dto)
+	if err != nil {
+		return nil, err
+	}
+	loaderFromContext(p.Context, r.books).Prime(book)
+	return book, nil
+}
+
+func (r *resolver) updateBook(p graphql.ResolveParams) (interface{}, error) {
+	id, err := idArgument(p)
+	if err != nil {
+		return nil, err
+	}
+	var dto entities.UpdateBookDTO
+	if err := inputArgument(p, &dto); err != nil {
+		return nil, err
+	}
+	book, err := r.books.UpdateBook(p.Context, id, &dto)
+	if err != nil {
+		return nil, err
+	}
+	loaderFromContext(p.Context, r.books).Prime(book)
+	return book, nil
+}
+
+func (r *resolver) deleteBook(p graphql.ResolveParams) (interface{}, error) {
+	id, err := idArgument(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.books.DeleteBook(p.Context, id); err != nil {
+		return nil, err
+	}
+	loaderFromContext(p.Context, r.books).Forget(id)
+	return id.String(), nil
+}
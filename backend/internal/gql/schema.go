@@ -0,0 +1,303 @@
+// Package gql serves the book domain over GraphQL. Object and input types
+// are derived from the entities and DTOs, and resolvers go through
+// BookUseCase so validation, tenancy and events behave as in the REST API.
+package gql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+var (
+	uuidType = reflect.TypeOf(uuid.UUID{})
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// structField is a JSON-visible field of an entity as exposed in the schema.
+// Pointers and fields tagged omitempty are nullable, and an empty omitempty
+// field resolves to null as it is left out of the JSON. A slice of scalars
+// is a list of non-null scalars.
+type structField struct {
+	name      string
+	index     []int
+	goType    reflect.Type
+	scalar    *graphql.Scalar
+	list      bool
+	nullable  bool
+	omitEmpty bool
+}
+
+// fieldName turns a JSON name such as created_at into createdAt
+func fieldName(jsonName string) string {
+	parts := strings.Split(jsonName, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func scalarFor(t reflect.Type) *graphql.Scalar {
+	switch {
+	case t == uuidType:
+		return graphql.ID
+	case t == timeType:
+		return graphql.DateTime
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return graphql.String
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return graphql.Int
+	case reflect.Float32, reflect.Float64:
+		return graphql.Float
+	case reflect.Bool:
+		return graphql.Boolean
+	}
+	return nil
+}
+
+// structFields lists the fields of struct type t that appear in its JSON
+// form; fields tagged json:"-" (such as a book's tenant) stay hidden and
+// the fields of an embedded struct are promoted, as encoding/json does
+func structFields(t reflect.Type) ([]structField, error) {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		jsonName, options, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && jsonName == "" && f.Type.Kind() == reflect.Struct {
+			embedded, err := structFields(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range embedded {
+				e.index = append([]int{i}, e.index...)
+				fields = append(fields, e)
+			}
+			continue
+		}
+		if !f.IsExported() || jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+
+		goType, nullable, list := f.Type, false, false
+		if goType.Kind() == reflect.Ptr {
+			goType, nullable = goType.Elem(), true
+		}
+		scalar := scalarFor(goType)
+		if scalar == nil && goType.Kind() == reflect.Slice {
+			scalar, list = scalarFor(goType.Elem()), true
+		}
+		if scalar == nil {
+			return nil, fmt.Errorf("gql: %s.%s has unsupported type %s", t.Name(), f.Name, f.Type)
+		}
+		omitEmpty := options == "omitempty"
+		fields = append(fields, structField{
+			name:      fieldName(jsonName),
+			index:     f.Index,
+			goType:    goType,
+			scalar:    scalar,
+			list:      list,
+			nullable:  nullable || omitEmpty,
+			omitEmpty: omitEmpty,
+		})
+	}
+	return fields, nil
+}
+
+func (f structField) outputType() graphql.Output {
+	var output graphql.Output = f.scalar
+	if f.list {
+		output = graphql.NewList(graphql.NewNonNull(f.scalar))
+	}
+	if f.nullable {
+		return output
+	}
+	return graphql.NewNonNull(output)
+}
+
+// objectType derives an object type from the entity value
+func objectType(name, description string, value interface{}) (*graphql.Object, error) {
+	fields, err := structFields(reflect.TypeOf(value))
+	if err != nil {
+		return nil, err
+	}
+
+	objectFields := graphql.Fields{}
+	for _, f := range fields {
+		f := f
+		objectFields[f.name] = &graphql.Field{
+			Type: f.outputType(),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				value := reflect.Indirect(reflect.ValueOf(p.Source)).FieldByIndex(f.index)
+				if f.omitEmpty && value.IsZero() {
+					return nil, nil
+				}
+				if value.Kind() == reflect.Ptr {
+					if value.IsNil() {
+						return nil, nil
+					}
+					value = value.Elem()
+				}
+				if id, ok := value.Interface().(uuid.UUID); ok {
+					return id.String(), nil
+				}
+				return value.Interface(), nil
+			},
+		}
+	}
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        name,
+		Description: description,
+		Fields:      objectFields,
+	}), nil
+}
+
+// inputType derives an input object type from the DTO value
+func inputType(name string, value interface{}) (*graphql.InputObject, error) {
+	fields, err := structFields(reflect.TypeOf(value))
+	if err != nil {
+		return nil, err
+	}
+
+	inputFields := graphql.InputObjectConfigFieldMap{}
+	for _, f := range fields {
+		inputFields[f.name] = &graphql.InputObjectFieldConfig{Type: f.outputType().(graphql.Input)}
+	}
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:   name,
+		Fields: inputFields,
+	}), nil
+}
+
+// decodeInput copies an input object argument onto the DTO target points to
+func decodeInput(input map[string]interface{}, target interface{}) error {
+	dst := reflect.ValueOf(target).Elem()
+	fields, err := structFields(dst.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		raw, ok := input[f.name]
+		if !ok || raw == nil {
+			continue
+		}
+		value := reflect.ValueOf(raw)
+		switch {
+		case f.goType == uuidType:
+			id, err := uuid.Parse(fmt.Sprint(raw))
+			if err != nil {
+				return entities.ErrInvalidUUID
+			}
+			value = reflect.ValueOf(id)
+		case f.list:
+			items, ok := raw.([]interface{})
+			if !ok {
+				return fmt.Errorf("gql: cannot use %T for %s", raw, f.name)
+			}
+			value = reflect.MakeSlice(f.goType, len(items), len(items))
+			for i, item := range items {
+				element := reflect.ValueOf(item)
+				if !element.IsValid() || !element.Type().ConvertibleTo(f.goType.Elem()) {
+					return fmt.Errorf("gql: cannot use %T for %s", item, f.name)
+				}
+				value.Index(i).Set(element.Convert(f.goType.Elem()))
+			}
+		case value.Type().ConvertibleTo(f.goType):
+			value = value.Convert(f.goType)
+		default:
+			return fmt.Errorf("gql: cannot use %T for %s", raw, f.name)
+		}
+
+		field := dst.FieldByIndex(f.index)
+		if field.Kind() == reflect.Ptr {
+			ptr := reflect.New(f.goType)
+			ptr.Elem().Set(value)
+			value = ptr
+		}
+		field.Set(value)
+	}
+	return nil
+}
+
+// NewSchema builds the book schema with resolvers calling books
+func NewSchema(books usecases.BookUseCase) (graphql.Schema, error) {
+	bookType, err := objectType("Book", "A book in the tenant's library", entities.Book{})
+	if err != nil {
+		return graphql.Schema{}, err
+	}
+	createInput, err := inputType("CreateBookInput", entities.CreateBookDTO{})
+	if err != nil {
+		return graphql.Schema{}, err
+	}
+	updateInput, err := inputType("UpdateBookInput", entities.UpdateBookDTO{})
+	if err != nil {
+		return graphql.Schema{}, err
+	}
+
+	r := &resolver{books: books}
+	idArgument := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"book": &graphql.Field{
+				Type:        bookType,
+				Description: "A book by ID, or null if it does not exist. Lookups in one query are loaded together.",
+				Args:        graphql.FieldConfigArgument{"id": idArgument},
+				Resolve:     r.book,
+			},
+			"books": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bookType))),
+				Description: "All books, newest first",
+				Resolve:     r.allBooks,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createBook": &graphql.Field{
+				Type:        graphql.NewNonNull(bookType),
+				Description: "Creates a book. One resembling existing books fails with CONFLICT and the possible duplicates unless force is true.",
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createInput)},
+					"force": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: r.createBook,
+			},
+			"updateBook": &graphql.Field{
+				Type: graphql.NewNonNull(bookType),
+				Args: graphql.FieldConfigArgument{
+					"id":    idArgument,
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateInput)},
+				},
+				Resolve: r.updateBook,
+			},
+			"deleteBook": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "Deletes a book and returns its ID",
+				Args:        graphql.FieldConfigArgument{"id": idArgument},
+				Resolve:     r.deleteBook,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
@@ -0,0 +1,164 @@
+package gql
+
+import (
+	"context"
+
+	"byfood-library/internal/cache"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tracing"
+	"byfood-library/internal/usecases"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// Config bounds the queries a Server accepts
+type Config struct {
+	MaxDepth      int
+	MaxComplexity int
+	// ListCost is the number of items assumed per list when scoring complexity
+	ListCost int
+	// PersistedQueries is one of the PersistedQueries* modes
+	PersistedQueries string
+	// AllowedQueries maps hashes to queries for PersistedQueriesOnly
+	AllowedQueries map[string]string
+	// CacheSize bounds the queries remembered in PersistedQueriesAuto mode
+	CacheSize int
+}
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    RequestExtensions      `json:"extensions"`
+}
+
+type RequestExtensions struct {
+	PersistedQuery *PersistedQuery `json:"persistedQuery,omitempty"`
+}
+
+// Server executes GraphQL requests against the book schema
+type Server struct {
+	schema    graphql.Schema
+	books     usecases.BookUseCase
+	config    Config
+	persisted *persistedQueries
+	logger    *zap.Logger
+}
+
+func NewServer(books usecases.BookUseCase, config Config, logger *zap.Logger) (*Server, error) {
+	if config.MaxDepth <= 0 {
+		config.MaxDepth = 10
+	}
+	if config.MaxComplexity <= 0 {
+		config.MaxComplexity = 1000
+	}
+	if config.ListCost <= 0 {
+		config.ListCost = 10
+	}
+	if config.PersistedQueries == "" {
+		config.PersistedQueries = PersistedQueriesOff
+	}
+
+	schema, err := NewSchema(books)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		schema: schema,
+		books:  books,
+		config: config,
+		persisted: &persistedQueries{
+			mode:       config.PersistedQueries,
+			registered: cache.NewLRU(config.CacheSize),
+			allowed:    config.AllowedQueries,
+		},
+		logger: logger,
+	}, nil
+}
+
+// Schema returns the executable schema
+func (s *Server) Schema() *graphql.Schema {
+	return &s.schema
+}
+
+// Execute runs req. executed is false when the request was rejected without
+// running: it did not parse or validate, exceeded the limits, named an
+// unknown persisted query, or is a mutation while readOnly is set, as it is
+// for GET requests.
+func (s *Server) Execute(ctx context.Context, req *Request, readOnly bool) (result *graphql.Result, executed bool) {
+	ctx, span := tracing.Start(ctx, "GraphQL.Execute")
+	defer span.End()
+	logger := logging.FromContext(ctx, s.logger)
+
+	reject := func(errs ...gqlerrors.FormattedError) (*graphql.Result, bool) {
+		logger.Warn("GraphQL request rejected", zap.String("operation", req.OperationName), zap.String("error", errs[0].Message))
+		span.SetAttributes(attribute.String("graphql.error", errs[0].Message))
+		return &graphql.Result{Errors: errs}, false
+	}
+
+	query, err := s.persisted.resolve(ctx, req)
+	if err != nil {
+		return reject(gqlerrors.FormatError(err))
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return reject(withCode(gqlerrors.FormatErrors(err), CodeParseFailed)...)
+	}
+	if validation := graphql.ValidateDocument(&s.schema, doc, nil); !validation.IsValid {
+		return reject(withCode(validation.Errors, CodeValidationFailed)...)
+	}
+
+	op := operation(doc, req.OperationName)
+	if op == nil {
+		return reject(requestError(CodeBadRequest, "operation not found; operationName is required when a document has several"))
+	}
+	span.SetAttributes(
+		attribute.String("graphql.operation.type", op.Operation),
+		attribute.String("graphql.operation.name", req.OperationName),
+	)
+	if readOnly && op.Operation != ast.OperationTypeQuery {
+		return reject(requestError(CodeBadRequest, "only queries can be sent with GET; use POST for mutations"))
+	}
+	if err := s.checkLimits(doc, op); err != nil {
+		return reject(gqlerrors.FormatError(err))
+	}
+
+	result = graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withLoader(ctx, newBookLoader(s.books)),
+	})
+	classify(result.Errors)
+	return result, true
+}
+
+// operation picks the operation to run, as the executor will
+func operation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		op, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = op
+		} else if op.Name != nil && op.Name.Value == name {
+			return op
+		}
+	}
+	return found
+}
@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CheckFunc adapts a function to the Checker interface
+type CheckFunc struct {
+	CheckName string
+	Fn        func(ctx context.Context) (map[string]interface{}, error)
+}
+
+func (f CheckFunc) Name() string { return f.CheckName }
+
+func (f CheckFunc) Check(ctx context.Context) (map[string]interface{}, error) {
+	return f.Fn(ctx)
+}
+
+// DatabasePing verifies that Postgres answers within the check timeout
+func DatabasePing(db *sqlx.DB) Checker {
+	return CheckFunc{
+		CheckName: "database",
+		Fn: func(ctx context.Context) (map[string]interface{}, error) {
+			if err := db.PingContext(ctx); err != nil {
+				return nil, fmt.Errorf("ping failed: %w", err)
+			}
+			return nil, nil
+		},
+	}
+}
+
+// PoolSaturation reports the connection pool as degraded once the share of
+// connections in use reaches threshold, or when callers had to wait for one
+func PoolSaturation(db *sqlx.DB, threshold float64) Checker {
+	var lastWaitCount int64
+	return CheckFunc{
+		CheckName: "database_pool",
+		Fn: func(ctx context.Context) (map[string]interface{}, error) {
+			stats := db.Stats()
+			details := map[string]interface{}{
+				"open":          stats.OpenConnections,
+				"in_use":        stats.InUse,
+				"idle":          stats.Idle,
+				"max_open":      stats.MaxOpenConnections,
+				"wait_count":    stats.WaitCount,
+				"wait_duration": stats.WaitDuration.String(),
+			}
+
+			newWaits := stats.WaitCount - lastWaitCount
+			lastWaitCount = stats.WaitCount
+
+			// An unlimited pool cannot saturate
+			if stats.MaxOpenConnections <= 0 {
+				return details, nil
+			}
+
+			usage := float64(stats.InUse) / float64(stats.MaxOpenConnections)
+			details["usage"] = usage
+			if threshold > 0 && usage >= threshold {
+				return details, Degraded(fmt.Errorf("%d of %d connections in use", stats.InUse, stats.MaxOpenConnections))
+			}
+			if newWaits > 0 {
+				return details, Degraded(fmt.Errorf("%d requests waited for a connection", newWaits))
+			}
+			return details, nil
+		},
+	}
+}
+
+// Migrations verifies that the database has had the migrations this binary
+// ships applied, up to version expected
+func Migrations(db *sqlx.DB, expected int) Checker {
+	return CheckFunc{
+		CheckName: "migrations",
+		Fn: func(ctx context.Context) (map[string]interface{}, error) {
+			var current sql.NullInt64
+			if err := db.GetContext(ctx, &current, `SELECT MAX(version) FROM schema_migrations`); err != nil {
+				return nil, fmt.Errorf("failed to read schema version: %w", err)
+			}
+
+			details := map[string]interface{}{
+				"current":  current.Int64,
+				"expected": expected,
+			}
+			if !current.Valid || current.Int64 < int64(expected) {
+				return details, fmt.Errorf("schema version %d is behind expected %d", current.Int64, expected)
+			}
+			return details, nil
+		},
+	}
+}
@@ -0,0 +1,192 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Checker probes a single dependency. Returning an error wrapped with
+// Degraded reports the dependency as degraded rather than down.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) (map[string]interface{}, error)
+}
+
+type degradedError struct{ err error }
+
+func (d degradedError) Error() string { return d.err.Error() }
+func (d degradedError) Unwrap() error { return d.err }
+
+// Degraded marks a check failure as non-fatal for readiness
+func Degraded(err error) error {
+	return degradedError{err: err}
+}
+
+type Result struct {
+	Name      string                 `json:"name"`
+	Status    Status                 `json:"status"`
+	Critical  bool                   `json:"critical"`
+	LatencyMS float64                `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+type Report struct {
+	Status    Status    `json:"status"`
+	Checks    []Result  `json:"checks"`
+	CheckedAt time.Time `json:"checked_at"`
+	Cached    bool      `json:"cached"`
+}
+
+type Config struct {
+	// CacheTTL bounds how often the checks actually run
+	CacheTTL time.Duration
+	// Timeout applies to each check
+	Timeout time.Duration
+}
+
+type registration struct {
+	checker  Checker
+	critical bool
+}
+
+// Registry runs registered checks and caches the combined report so that
+// frequent probes do not hammer dependencies
+type Registry struct {
+	config Config
+
+	mu     sync.Mutex
+	checks []registration
+	report *Report
+
+	running      sync.Mutex
+	shuttingDown atomic.Bool
+	now          func() time.Time
+}
+
+func NewRegistry(config Config) *Registry {
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Second
+	}
+	return &Registry{config: config, now: time.Now}
+}
+
+// Register adds a check; critical checks that are down fail readiness
+func (r *Registry) Register(checker Checker, critical bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, registration{checker: checker, critical: critical})
+	r.report = nil
+}
+
+// SetShuttingDown makes readiness fail so load balancers stop routing traffic
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+func (r *Registry) ShuttingDown() bool {
+	return r.shuttingDown.Load()
+}
+
+// Ready reports whether the instance should receive traffic
+func (r *Registry) Ready(ctx context.Context) (bool, *Report) {
+	report := r.Report(ctx)
+	if r.ShuttingDown() {
+		return false, report
+	}
+	return report.Status != StatusDown, report
+}
+
+// Report returns the cached report or runs all checks concurrently
+func (r *Registry) Report(ctx context.Context) *Report {
+	if report := r.cached(); report != nil {
+		return report
+	}
+
+	// Only one caller refreshes; the others wait and reuse its result
+	r.running.Lock()
+	defer r.running.Unlock()
+	if report := r.cached(); report != nil {
+		return report
+	}
+
+	r.mu.Lock()
+	checks := append([]registration(nil), r.checks...)
+	r.mu.Unlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, reg := range checks {
+		wg.Add(1)
+		go func(i int, reg registration) {
+			defer wg.Done()
+			results[i] = r.run(ctx, reg)
+		}(i, reg)
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusUp, Checks: results, CheckedAt: r.now()}
+	for _, result := range results {
+		switch {
+		case result.Status == StatusDown && result.Critical:
+			report.Status = StatusDown
+		case result.Status != StatusUp && report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+
+	r.mu.Lock()
+	r.report = report
+	r.mu.Unlock()
+
+	fresh := *report
+	return &fresh
+}
+
+func (r *Registry) cached() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.report == nil || r.now().Sub(r.report.CheckedAt) >= r.config.CacheTTL {
+		return nil
+	}
+	cached := *r.report
+	cached.Cached = true
+	return &cached
+}
+
+func (r *Registry) run(ctx context.Context, reg registration) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+
+	start := r.now()
+	details, err := reg.checker.Check(ctx)
+	result := Result{
+		Name:      reg.checker.Name(),
+		Status:    StatusUp,
+		Critical:  reg.critical,
+		LatencyMS: float64(r.now().Sub(start).Microseconds()) / 1000,
+		Details:   details,
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		var degraded degradedError
+		if errors.As(err, &degraded) {
+			result.Status = StatusDegraded
+		} else {
+			result.Status = StatusDown
+		}
+	}
+	return result
+}
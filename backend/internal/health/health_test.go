@@ -0,0 +1,131 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func countingCheck(name string, calls *int, err error) Checker {
+	return CheckFunc{
+		CheckName: name,
+		Fn: func(ctx context.Context) (map[string]interface{}, error) {
+			*calls++
+			return nil, err
+		},
+	}
+}
+
+func TestRegistry_Report(t *testing.T) {
+	t.Run("results are cached", func(t *testing.T) {
+		calls := 0
+		registry := NewRegistry(Config{CacheTTL: time.Minute})
+		registry.Register(countingCheck("database", &calls, nil), true)
+
+		first := registry.Report(context.Background())
+		second := registry.Report(context.Background())
+
+		assert.Equal(t, 1, calls)
+		assert.False(t, first.Cached)
+		assert.True(t, second.Cached)
+		assert.Equal(t, StatusUp, second.Status)
+	})
+
+	t.Run("cache expires", func(t *testing.T) {
+		calls := 0
+		now := time.Now()
+		registry := NewRegistry(Config{CacheTTL: time.Second})
+		registry.now = func() time.Time { return now }
+		registry.Register(countingCheck("database", &calls, nil), true)
+
+		registry.Report(context.Background())
+		now = now.Add(2 * time.Second)
+		registry.Report(context.Background())
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("critical failure is down", func(t *testing.T) {
+		calls := 0
+		registry := NewRegistry(Config{})
+		registry.Register(countingCheck("database", &calls, errors.New("connection refused")), true)
+
+		ready, report := registry.Ready(context.Background())
+
+		assert.False(t, ready)
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, "connection refused", report.Checks[0].Error)
+	})
+
+	t.Run("non-critical failure is degraded but ready", func(t *testing.T) {
+		dbCalls, poolCalls := 0, 0
+		registry := NewRegistry(Config{})
+		registry.Register(countingCheck("database", &dbCalls, nil), true)
+		registry.Register(countingCheck("database_pool", &poolCalls, Degraded(errors.New("saturated"))), false)
+
+		ready, report := registry.Ready(context.Background())
+
+		assert.True(t, ready)
+		assert.Equal(t, StatusDegraded, report.Status)
+		assert.Equal(t, StatusDegraded, report.Checks[1].Status)
+	})
+
+	t.Run("shutting down fails readiness", func(t *testing.T) {
+		calls := 0
+		registry := NewRegistry(Config{})
+		registry.Register(countingCheck("database", &calls, nil), true)
+		registry.SetShuttingDown()
+
+		ready, _ := registry.Ready(context.Background())
+
+		assert.False(t, ready)
+	})
+
+	t.Run("slow check times out", func(t *testing.T) {
+		registry := NewRegistry(Config{Timeout: 10 * time.Millisecond})
+		registry.Register(CheckFunc{
+			CheckName: "database",
+			Fn: func(ctx context.Context) (map[string]interface{}, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		}, true)
+
+		report := registry.Report(context.Background())
+
+		assert.Equal(t, StatusDown, report.Status)
+	})
+}
+
+func TestMigrations(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+	db := sqlx.NewDb(mockDB, "sqlmock")
+
+	t.Run("up to date", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT MAX\(version\) FROM schema_migrations`).
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(3))
+
+		details, err := Migrations(db, 3).Check(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), details["current"])
+	})
+
+	t.Run("behind", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT MAX\(version\) FROM schema_migrations`).
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(2))
+
+		_, err := Migrations(db, 3).Check(context.Background())
+
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -7,6 +7,10 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// SchemaVersion is the schema_migrations version this build expects, the
+// version of the latest file in migrations
+const SchemaVersion = 14
+
 func InitDBWithConfig(connectionString string) (*sqlx.DB, error) {
 	db, err := sqlx.Connect("postgres", connectionString)
 	if err != nil {
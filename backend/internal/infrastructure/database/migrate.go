@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// migrationLock is the advisory lock key that serializes migrations, so
+// replicas starting together apply each version once
+const migrationLock = 0x6d696772617465
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is one numbered schema change, read from migrations/NNNN_name.sql
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrations returns the embedded migrations in version order
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s is not named NNNN_name.sql", entry.Name())
+		}
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %d (%s) is out of sequence, expected version %d", migration.Version, migration.Name, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// Migrate applies the migrations the database has not seen yet, each in its
+// own transaction, and returns the versions it applied. Databases created
+// before schema_migrations existed hold the version 1 schema and are
+// recorded as such.
+func Migrate(ctx context.Context, db *sqlx.DB) ([]int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []int
+	for _, migration := range migrations {
+		ok, err := apply(ctx, db, migration)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		if ok {
+			applied = append(applied, migration.Version)
+		}
+	}
+	return applied, nil
+}
+
+func apply(ctx context.Context, db *sqlx.DB, migration Migration) (bool, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLock); err != nil {
+		return false, err
+	}
+	current, err := currentVersion(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+	if migration.Version <= current {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, migration.Version); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// currentVersion returns the latest applied version, creating
+// schema_migrations on first use
+func currentVersion(ctx context.Context, tx *sqlx.Tx) (int, error) {
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, `SELECT to_regclass('schema_migrations') IS NOT NULL`); err != nil {
+		return 0, err
+	}
+	if !exists {
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE schema_migrations (
+			version INTEGER PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+			return 0, err
+		}
+
+		var baseline bool
+		if err := tx.GetContext(ctx, &baseline, `SELECT to_regclass('books') IS NOT NULL`); err != nil {
+			return 0, err
+		}
+		if baseline {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES (1)`); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	var current int
+	if err := tx.GetContext(ctx, &current, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`); err != nil {
+		return 0, err
+	}
+	return current, nil
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, SchemaVersion, migrations[len(migrations)-1].Version)
+	for _, migration := range migrations {
+		assert.NotEmpty(t, migration.SQL, "migration %d", migration.Version)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	migrations, err := Migrations()
+	require.NoError(t, err)
+
+	t.Run("adopts a database created before migrations", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+		db := sqlx.NewDb(mockDB, "sqlmock")
+
+		for i, migration := range migrations {
+			mock.ExpectBegin()
+			mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WithArgs(migrationLock).WillReturnResult(sqlmock.NewResult(0, 0))
+			if i == 0 {
+				mock.ExpectQuery(`SELECT to_regclass\('schema_migrations'\)`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec(`CREATE TABLE schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(`SELECT to_regclass\('books'\)`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectExec(`INSERT INTO schema_migrations \(version\) VALUES \(1\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+			} else {
+				mock.ExpectQuery(`SELECT to_regclass\('schema_migrations'\)`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			}
+			current := 1
+			if i > 0 {
+				current = migration.Version - 1
+			}
+			mock.ExpectQuery(`SELECT COALESCE\(MAX\(version\), 0\) FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(current))
+			if migration.Version <= current {
+				mock.ExpectRollback()
+				continue
+			}
+			mock.ExpectExec(regexp.QuoteMeta(migration.SQL)).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`INSERT INTO schema_migrations \(version\) VALUES \(\$1\)`).WithArgs(migration.Version).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		}
+
+		applied, err := Migrate(context.Background(), db)
+
+		assert.NoError(t, err)
+		expected := []int(nil)
+		for _, migration := range migrations[1:] {
+			expected = append(expected, migration.Version)
+		}
+		assert.Equal(t, expected, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stops at the first failure", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+		db := sqlx.NewDb(mockDB, "sqlmock")
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WithArgs(migrationLock).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT to_regclass\('schema_migrations'\)`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT COALESCE\(MAX\(version\), 0\) FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(0))
+		mock.ExpectExec(regexp.QuoteMeta(migrations[0].SQL)).WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		applied, err := Migrate(context.Background(), db)
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Empty(t, applied)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
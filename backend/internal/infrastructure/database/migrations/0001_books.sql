@@ -29,4 +29,4 @@ CREATE TRIGGER update_books_updated_at
 INSERT INTO books (title, author, year) VALUES
     ('The Go Programming Language', 'Alan Donovan', 2015),
     ('Clean Code', 'Robert Martin', 2008),
-    ('Design Patterns', 'Gang of Four', 1994);
\ No newline at end of file
+    ('Design Patterns', 'Gang of Four', 1994);
@@ -0,0 +1,32 @@
+-- Tenants (partner libraries) sharing this deployment
+CREATE TABLE tenants (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended')),
+    api_key_hash CHAR(64) UNIQUE,
+    settings JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Default tenant used when multi-tenancy is disabled
+INSERT INTO tenants (id, slug, name) VALUES
+    ('00000000-0000-0000-0000-000000000001', 'default', 'Default Library');
+
+-- Books that predate tenancy belong to the default tenant
+ALTER TABLE books ADD COLUMN tenant_id UUID REFERENCES tenants(id);
+UPDATE books SET tenant_id = '00000000-0000-0000-0000-000000000001';
+ALTER TABLE books ALTER COLUMN tenant_id SET NOT NULL;
+
+CREATE INDEX idx_books_tenant_created_at ON books (tenant_id, created_at DESC);
+
+-- Row-level security as defence in depth: the application scopes every query
+-- by tenant_id and also sets app.tenant_id per transaction. Note that
+-- superusers bypass RLS, so production should connect as a non-superuser role.
+ALTER TABLE books ENABLE ROW LEVEL SECURITY;
+ALTER TABLE books FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY books_tenant_isolation ON books
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
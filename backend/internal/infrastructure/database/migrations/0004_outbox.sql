@@ -0,0 +1,21 @@
+-- Transactional outbox: domain events are written in the same transaction as
+-- the change they describe and published by the relay. Not subject to RLS
+-- because the relay reads across tenants.
+CREATE TABLE outbox (
+    id UUID PRIMARY KEY,
+    sequence BIGSERIAL NOT NULL UNIQUE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    aggregate_type VARCHAR(64) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (sequence) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_pending_aggregate ON outbox (aggregate_type, aggregate_id, sequence) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_published_at ON outbox (published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Trigram similarity for duplicate detection
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Normalized ISBN-13; empty when unknown
+ALTER TABLE books ADD COLUMN isbn VARCHAR(13) NOT NULL DEFAULT '';
+
+-- Duplicate detection looks books up by ISBN and by trigram-similar title.
+-- ISBNs are deliberately not unique: a library may hold several records of
+-- one edition until they are merged.
+CREATE INDEX idx_books_tenant_isbn ON books (tenant_id, isbn) WHERE isbn <> '';
+CREATE INDEX idx_books_title_trgm ON books USING GIN (lower(title) gin_trgm_ops);
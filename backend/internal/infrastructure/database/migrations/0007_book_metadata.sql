@@ -0,0 +1,7 @@
+-- Descriptive metadata, usually filled from bibliographic sources
+ALTER TABLE books
+    ADD COLUMN publisher VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN page_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN subjects TEXT[],
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN cover_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Version of the uploaded cover image in blob storage; empty without one
+ALTER TABLE books ADD COLUMN cover_etag VARCHAR(64) NOT NULL DEFAULT '';
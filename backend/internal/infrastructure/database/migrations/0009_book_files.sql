@@ -0,0 +1,25 @@
+-- Ebook files of books, kept in blob storage under storage_key. A tenant
+-- stores a file once, found again by its SHA-256 checksum; the files of
+-- merged books move to the survivor.
+CREATE TABLE book_files (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    format VARCHAR(16) NOT NULL,
+    content_type VARCHAR(127) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    storage_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, checksum)
+);
+
+CREATE INDEX idx_book_files_book ON book_files (book_id, created_at);
+
+ALTER TABLE book_files ENABLE ROW LEVEL SECURITY;
+ALTER TABLE book_files FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY book_files_tenant_isolation ON book_files
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
@@ -0,0 +1,5 @@
+-- Catalogue feeds search titles and authors by substring, and filter and
+-- count books by author and subject
+CREATE INDEX idx_books_author_trgm ON books USING GIN (lower(author) gin_trgm_ops);
+CREATE INDEX idx_books_tenant_author ON books (tenant_id, author);
+CREATE INDEX idx_books_subjects ON books USING GIN (subjects);
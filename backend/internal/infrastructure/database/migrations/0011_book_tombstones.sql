@@ -0,0 +1,35 @@
+-- Deleted books, kept so that harvesters learn of deletions. The trigger
+-- below records every deletion, merged books included, and the rows are
+-- never purged: the repository declares its deleted records persistent.
+CREATE TABLE book_tombstones (
+    book_id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_book_tombstones_tenant_deleted_at ON book_tombstones (tenant_id, deleted_at, book_id);
+
+ALTER TABLE book_tombstones ENABLE ROW LEVEL SECURITY;
+ALTER TABLE book_tombstones FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY book_tombstones_tenant_isolation ON book_tombstones
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+-- Metadata harvesting pages through books by (updated_at, id)
+CREATE INDEX idx_books_tenant_updated_at ON books (tenant_id, updated_at, id);
+
+-- Trigger to record a tombstone for every deleted book
+CREATE OR REPLACE FUNCTION record_book_tombstone()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO book_tombstones (book_id, tenant_id) VALUES (OLD.id, OLD.tenant_id)
+        ON CONFLICT (book_id) DO NOTHING;
+    RETURN OLD;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER record_books_tombstone
+    AFTER DELETE ON books
+    FOR EACH ROW
+    EXECUTE FUNCTION record_book_tombstone();
@@ -0,0 +1,5 @@
+-- Idempotency key times are set by the application, so they carry their
+-- time zone; stored values were written in UTC
+ALTER TABLE idempotency_keys
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at AT TIME ZONE 'UTC';
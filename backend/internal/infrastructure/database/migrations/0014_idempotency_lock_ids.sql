@@ -0,0 +1,4 @@
+-- Each claim on an idempotency key carries the id of the request holding
+-- it, so that a request whose claim lapsed cannot complete or release the
+-- key once another has taken it over. Stored responses keep no claim.
+ALTER TABLE idempotency_keys ADD COLUMN lock_id UUID;
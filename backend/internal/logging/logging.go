@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"context"
+
+	"byfood-library/internal/tracing"
+	"go.uber.org/zap"
+)
+
+type loggerKey struct{}
+
+// WithLogger stores logger as the request-scoped logger in ctx
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// With enriches the logger in ctx with fields, e.g. once the tenant is known
+func With(ctx context.Context, fallback *zap.Logger, fields ...zap.Field) context.Context {
+	return WithLogger(ctx, fromContext(ctx, fallback).With(fields...))
+}
+
+// FromContext returns the request-scoped logger carried by ctx, or fallback
+// outside a request. The trace and span IDs of the active span are added so
+// that every line can be joined with its trace.
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	return tracing.Logger(ctx, fromContext(ctx, fallback))
+}
+
+func fromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	if fallback == nil {
+		return zap.NewNop()
+	}
+	return fallback
+}
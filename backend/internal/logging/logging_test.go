@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Run("falls back outside a request", func(t *testing.T) {
+		core, logs := observer.New(zap.InfoLevel)
+		fallback := zap.New(core)
+
+		FromContext(context.Background(), fallback).Info("background")
+
+		assert.Equal(t, 1, logs.Len())
+	})
+
+	t.Run("nil fallback is a no-op logger", func(t *testing.T) {
+		assert.NotPanics(t, func() {
+			FromContext(context.Background(), nil).Info("dropped")
+		})
+	})
+
+	t.Run("enrichment accumulates", func(t *testing.T) {
+		core, logs := observer.New(zap.InfoLevel)
+		ctx := WithLogger(context.Background(), zap.New(core).With(zap.String("request_id", "req-1")))
+		ctx = With(ctx, nil, zap.String("tenant", "north"))
+
+		FromContext(ctx, zap.NewNop()).Info("hello")
+
+		fields := logs.All()[0].ContextMap()
+		assert.Equal(t, "req-1", fields["request_id"])
+		assert.Equal(t, "north", fields["tenant"])
+	})
+}
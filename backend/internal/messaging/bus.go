@@ -0,0 +1,68 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"byfood-library/internal/domain/events"
+)
+
+// Sink is a destination the relay publishes outbox events to. Publish must
+// only return nil once the event is durably accepted; events are retried
+// until it does, so sinks see duplicates after failures.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, evt *events.Event) error
+}
+
+// Handler consumes events from the Bus
+type Handler func(ctx context.Context, evt *events.Event) error
+
+// Bus is an in-process Sink that fans events out to subscribers, so other
+// parts of the service can react to committed changes
+type Bus struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[int]Handler)}
+}
+
+func (b *Bus) Name() string { return "bus" }
+
+// Subscribe registers handler and returns a function that removes it
+func (b *Bus) Subscribe(handler Handler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish calls every subscriber in turn. If any fails the event is
+// redelivered to all of them, so handlers must be idempotent.
+func (b *Bus) Publish(ctx context.Context, evt *events.Event) error {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, evt); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
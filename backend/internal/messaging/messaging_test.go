@@ -0,0 +1,220 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/events"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryOutbox hands its pending events to the relay as one batch
+type memoryOutbox struct {
+	pending  []*events.Event
+	outcomes []events.Outcome
+	locked   bool
+}
+
+func (o *memoryOutbox) Append(ctx context.Context, evts ...*events.Event) error {
+	o.pending = append(o.pending, evts...)
+	return nil
+}
+
+func (o *memoryOutbox) Relay(ctx context.Context, limit int, deliver func(ctx context.Context, batch []*events.Event) []events.Outcome) (bool, error) {
+	if o.locked {
+		return false, nil
+	}
+	batch := o.pending
+	if len(batch) > limit {
+		batch = batch[:limit]
+	}
+	o.outcomes = deliver(ctx, batch)
+	return true, nil
+}
+
+func (o *memoryOutbox) DeletePublished(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return 0, nil
+}
+
+// recordingSink fails events for the aggregates in failFor
+type recordingSink struct {
+	published []*events.Event
+	failFor   map[uuid.UUID]bool
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Publish(ctx context.Context, evt *events.Event) error {
+	if s.failFor[evt.AggregateID] {
+		return errors.New("sink unavailable")
+	}
+	s.published = append(s.published, evt)
+	return nil
+}
+
+func testEvent(eventType string, aggregateID uuid.UUID) *events.Event {
+	return &events.Event{ID: uuid.New(), Type: eventType, AggregateType: events.AggregateBook, AggregateID: aggregateID, TenantID: uuid.New()}
+}
+
+func TestRelay_RelayOnce(t *testing.T) {
+	ctx := context.Background()
+	bookA, bookB := uuid.New(), uuid.New()
+
+	t.Run("publishes the batch in order", func(t *testing.T) {
+		outbox := &memoryOutbox{}
+		outbox.Append(ctx, testEvent(events.BookCreated, bookA), testEvent(events.BookUpdated, bookA))
+		sink := &recordingSink{}
+		relay := NewRelay(outbox, []Sink{sink}, RelayConfig{}, zap.NewNop())
+
+		attempted, err := relay.RelayOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, attempted)
+		assert.Equal(t, outbox.pending, sink.published)
+		for _, outcome := range outbox.outcomes {
+			assert.True(t, outcome.Published)
+		}
+	})
+
+	t.Run("a failure holds back later events for the same aggregate only", func(t *testing.T) {
+		outbox := &memoryOutbox{}
+		failed, heldBack, other := testEvent(events.BookCreated, bookA), testEvent(events.BookUpdated, bookA), testEvent(events.BookCreated, bookB)
+		failed.Attempts = 2
+		outbox.Append(ctx, failed, heldBack, other)
+		sink := &recordingSink{failFor: map[uuid.UUID]bool{bookA: true}}
+		relay := NewRelay(outbox, []Sink{sink}, RelayConfig{BackoffBase: time.Second, BackoffMax: time.Minute}, zap.NewNop())
+
+		_, err := relay.RelayOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*events.Event{other}, sink.published)
+		assert.Len(t, outbox.outcomes, 2)
+		assert.Equal(t, failed.ID, outbox.outcomes[0].EventID)
+		assert.False(t, outbox.outcomes[0].Published)
+		assert.Equal(t, "sink unavailable", outbox.outcomes[0].Error)
+		// Third attempt: 4s less up to 20% jitter
+		assert.InDelta(t, 3.6, outbox.outcomes[0].RetryAfter.Seconds(), 0.41)
+		assert.Equal(t, other.ID, outbox.outcomes[1].EventID)
+		assert.True(t, outbox.outcomes[1].Published)
+	})
+
+	t.Run("an event is only published once every sink accepts it", func(t *testing.T) {
+		outbox := &memoryOutbox{}
+		outbox.Append(ctx, testEvent(events.BookCreated, bookA))
+		first, second := &recordingSink{}, &recordingSink{failFor: map[uuid.UUID]bool{bookA: true}}
+		relay := NewRelay(outbox, []Sink{first, second}, RelayConfig{}, zap.NewNop())
+
+		relay.RelayOnce(ctx)
+
+		assert.Len(t, first.published, 1)
+		assert.False(t, outbox.outcomes[0].Published)
+	})
+
+	t.Run("another relay holds the lock", func(t *testing.T) {
+		outbox := &memoryOutbox{locked: true}
+		outbox.Append(ctx, testEvent(events.BookCreated, bookA))
+		relay := NewRelay(outbox, []Sink{&recordingSink{}}, RelayConfig{}, zap.NewNop())
+
+		attempted, err := relay.RelayOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, attempted)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{20, time.Minute},
+	} {
+		got := Backoff(tc.attempts, time.Second, time.Minute)
+		assert.LessOrEqual(t, got, tc.want)
+		assert.GreaterOrEqual(t, got, tc.want*4/5)
+	}
+}
+
+func TestBus(t *testing.T) {
+	ctx := context.Background()
+	bus := NewBus()
+	var received []string
+	unsubscribe := bus.Subscribe(func(ctx context.Context, evt *events.Event) error {
+		received = append(received, evt.Type)
+		return nil
+	})
+	bus.Subscribe(func(ctx context.Context, evt *events.Event) error {
+		if evt.Type == events.BookDeleted {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+
+	assert.NoError(t, bus.Publish(ctx, testEvent(events.BookCreated, uuid.New())))
+	assert.EqualError(t, bus.Publish(ctx, testEvent(events.BookDeleted, uuid.New())), "handler failed")
+	unsubscribe()
+	assert.NoError(t, bus.Publish(ctx, testEvent(events.BookUpdated, uuid.New())))
+
+	assert.Equal(t, []string{events.BookCreated, events.BookDeleted}, received)
+}
+
+// fakeJetStream stands in for a JetStream connection
+type fakeJetStream struct {
+	subjects []string
+	payloads [][]byte
+}
+
+func (f *fakeJetStream) Publish(ctx context.Context, subject string, payload []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	f.subjects = append(f.subjects, subject)
+	f.payloads = append(f.payloads, payload)
+	return &jetstream.PubAck{Stream: "BOOKS"}, nil
+}
+
+func TestNATSSink(t *testing.T) {
+	js := &fakeJetStream{}
+	sink := NewNATSSink(js, "")
+	evt := testEvent(events.BookCreated, uuid.New())
+
+	assert.NoError(t, sink.Publish(context.Background(), evt))
+
+	assert.Equal(t, []string{"byfood.events." + evt.TenantID.String() + ".book.created"}, js.subjects)
+	var decoded events.Event
+	assert.NoError(t, json.Unmarshal(js.payloads[0], &decoded))
+	assert.Equal(t, evt.ID, decoded.ID)
+}
+
+// fakeKafkaWriter stands in for a Kafka producer
+type fakeKafkaWriter struct {
+	messages []kafka.Message
+}
+
+func (f *fakeKafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	f.messages = append(f.messages, msgs...)
+	return nil
+}
+
+func TestKafkaSink(t *testing.T) {
+	writer := &fakeKafkaWriter{}
+	sink := NewKafkaSink(writer)
+	evt := testEvent(events.BookUpdated, uuid.New())
+
+	assert.NoError(t, sink.Publish(context.Background(), evt))
+
+	assert.Len(t, writer.messages, 1)
+	message := writer.messages[0]
+	assert.Equal(t, evt.AggregateID.String(), string(message.Key))
+	assert.Contains(t, message.Headers, kafka.Header{Key: "event-type", Value: []byte(events.BookUpdated)})
+	var decoded events.Event
+	assert.NoError(t, json.Unmarshal(message.Value, &decoded))
+	assert.Equal(t, evt.ID, decoded.ID)
+}
@@ -0,0 +1,177 @@
+package messaging
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/middleware"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RelayConfig controls how often the outbox is polled and how failed
+// deliveries are retried
+type RelayConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	// Failed events are retried after BackoffBase * 2^(attempts-1), with
+	// jitter, capped at BackoffMax
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// PublishTimeout bounds each sink's Publish call
+	PublishTimeout time.Duration
+	// Retention is how long published events are kept; 0 keeps them forever
+	Retention       time.Duration
+	CleanupInterval time.Duration
+}
+
+// Relay publishes outbox events to every sink. Delivery is at least once:
+// an event is retried until all sinks accept it, and an aggregate's later
+// events wait until its earlier ones are delivered.
+type Relay struct {
+	outbox repositories.OutboxRepository
+	sinks  []Sink
+	config RelayConfig
+	logger *zap.Logger
+}
+
+func NewRelay(outbox repositories.OutboxRepository, sinks []Sink, config RelayConfig, logger *zap.Logger) *Relay {
+	if config.Interval <= 0 {
+		config.Interval = time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.BackoffBase <= 0 {
+		config.BackoffBase = time.Second
+	}
+	if config.BackoffMax <= 0 {
+		config.BackoffMax = 10 * time.Minute
+	}
+	if config.PublishTimeout <= 0 {
+		config.PublishTimeout = 10 * time.Second
+	}
+	if config.CleanupInterval <= 0 {
+		config.CleanupInterval = time.Hour
+	}
+	return &Relay{
+		outbox: outbox,
+		sinks:  sinks,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Run relays events every Interval until ctx is done, draining full
+// batches back to back
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(r.config.CleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		for {
+			delivered, err := r.RelayOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				r.logger.Error("Failed to relay outbox events", zap.Error(err))
+			}
+			if err != nil || delivered < r.config.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-cleanup.C:
+			r.purge(ctx)
+		}
+	}
+}
+
+// RelayOnce delivers one batch and returns the number of events attempted.
+// It returns 0 when another replica is relaying.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	attempted := 0
+	_, err := r.outbox.Relay(ctx, r.config.BatchSize, func(ctx context.Context, batch []*events.Event) []events.Outcome {
+		outcomes := r.deliver(ctx, batch)
+		attempted = len(batch)
+		return outcomes
+	})
+	return attempted, err
+}
+
+// deliver publishes the batch in order. Once an event fails, later events
+// for the same aggregate are left in the outbox untouched so they cannot
+// overtake it.
+func (r *Relay) deliver(ctx context.Context, batch []*events.Event) []events.Outcome {
+	outcomes := make([]events.Outcome, 0, len(batch))
+	blocked := make(map[uuid.UUID]bool)
+
+	for _, evt := range batch {
+		if blocked[evt.AggregateID] {
+			continue
+		}
+
+		if err := r.publish(ctx, evt); err != nil {
+			blocked[evt.AggregateID] = true
+			retryAfter := Backoff(evt.Attempts+1, r.config.BackoffBase, r.config.BackoffMax)
+			r.logger.Warn("Failed to publish event, will retry",
+				zap.String("event_id", evt.ID.String()),
+				zap.String("event_type", evt.Type),
+				zap.Int("attempts", evt.Attempts+1),
+				zap.Duration("retry_after", retryAfter),
+				zap.Error(err))
+			outcomes = append(outcomes, events.Outcome{EventID: evt.ID, Error: err.Error(), RetryAfter: retryAfter})
+			continue
+		}
+		outcomes = append(outcomes, events.Outcome{EventID: evt.ID, Published: true})
+	}
+	return outcomes
+}
+
+func (r *Relay) publish(ctx context.Context, evt *events.Event) error {
+	for _, sink := range r.sinks {
+		sinkCtx, cancel := context.WithTimeout(ctx, r.config.PublishTimeout)
+		err := sink.Publish(sinkCtx, evt)
+		cancel()
+		middleware.RecordEventPublish(sink.Name(), evt.Type, err == nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Relay) purge(ctx context.Context) {
+	if r.config.Retention <= 0 {
+		return
+	}
+	deleted, err := r.outbox.DeletePublished(ctx, r.config.Retention)
+	if err != nil {
+		r.logger.Error("Failed to purge published outbox events", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		r.logger.Info("Purged published outbox events", zap.Int64("count", deleted))
+	}
+}
+
+// Backoff returns the delay before retry number attempts: exponential from
+// base, capped at max, with up to 20% jitter so that retries spread out
+func Backoff(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay - jitter
+}
@@ -0,0 +1,108 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"byfood-library/internal/domain/events"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/segmentio/kafka-go"
+)
+
+// NATSPublisher is the part of jetstream.JetStream used by NATSSink
+type NATSPublisher interface {
+	Publish(ctx context.Context, subject string, payload []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// NATSSink publishes events to JetStream as "<prefix>.<tenant>.<type>",
+// e.g. byfood.events.<tenant id>.book.created. The event ID is sent as the
+// message ID so that JetStream drops redeliveries within its dedupe window.
+type NATSSink struct {
+	js     NATSPublisher
+	prefix string
+}
+
+func NewNATSSink(js NATSPublisher, prefix string) *NATSSink {
+	if prefix == "" {
+		prefix = "byfood.events"
+	}
+	return &NATSSink{js: js, prefix: prefix}
+}
+
+// ConnectNATS connects to the NATS server at url and returns a sink and a
+// function closing the connection
+func ConnectNATS(url, prefix string) (*NATSSink, func(), error) {
+	conn, err := nats.Connect(url, nats.Name("byfood-library outbox relay"))
+	if err != nil {
+		return nil, nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return NewNATSSink(js, prefix), conn.Close, nil
+}
+
+func (s *NATSSink) Name() string { return "nats" }
+
+func (s *NATSSink) Subject(evt *events.Event) string {
+	return s.prefix + "." + evt.TenantID.String() + "." + evt.Type
+}
+
+func (s *NATSSink) Publish(ctx context.Context, evt *events.Event) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	_, err = s.js.Publish(ctx, s.Subject(evt), payload, jetstream.WithMsgID(evt.ID.String()))
+	return err
+}
+
+// KafkaWriter is the part of kafka.Writer used by KafkaSink
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// KafkaSink writes events keyed by aggregate ID, so that all events for a
+// book land in the same partition and stay in order
+type KafkaSink struct {
+	writer KafkaWriter
+}
+
+func NewKafkaSink(writer KafkaWriter) *KafkaSink {
+	return &KafkaSink{writer: writer}
+}
+
+// NewKafkaWriter returns a synchronous writer that waits for all in-sync
+// replicas to acknowledge each batch
+func NewKafkaWriter(brokers []string, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 10 * time.Millisecond,
+	}
+}
+
+func (s *KafkaSink) Name() string { return "kafka" }
+
+func (s *KafkaSink) Publish(ctx context.Context, evt *events.Event) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	return s.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(evt.AggregateID.String()),
+		Value: payload,
+		Headers: []kafka.Header{
+			{Key: "event-id", Value: []byte(evt.ID.String())},
+			{Key: "event-type", Value: []byte(evt.Type)},
+			{Key: "tenant-id", Value: []byte(evt.TenantID.String())},
+		},
+		Time: evt.OccurredAt,
+	})
+}
@@ -3,7 +3,7 @@ package middleware
 import (
 	"net/http"
 
-	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/problem"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
@@ -18,78 +18,10 @@ func NewErrorHandler(logger *zap.Logger) *ErrorHandler {
 	}
 }
 
-type ErrorResponse struct {
-	Error     string `json:"error"`
-	Message   string `json:"message"`
-	RequestID string `json:"request_id,omitempty"`
-	Code      int    `json:"code"`
-}
-
-// CustomHTTPErrorHandler handles errors in a centralized way
+// CustomHTTPErrorHandler renders every error that reaches Echo, whether
+// returned by middleware, a handler or the router, as problem details
 func (eh *ErrorHandler) CustomHTTPErrorHandler(err error, c echo.Context) {
 	requestID := GetRequestID(c)
-	
-	var code int
-	var message string
-	var errorType string
-
-	// Handle different error types
-	switch err {
-	case entities.ErrBookNotFound:
-		code = http.StatusNotFound
-		errorType = "NOT_FOUND"
-		message = "The requested book could not be found"
-	case entities.ErrInvalidTitle:
-		code = http.StatusBadRequest
-		errorType = "VALIDATION_ERROR"
-		message = "Book title is required and cannot be empty"
-	case entities.ErrInvalidAuthor:
-		code = http.StatusBadRequest
-		errorType = "VALIDATION_ERROR"
-		message = "Book author is required and cannot be empty"
-	case entities.ErrInvalidYear:
-		code = http.StatusBadRequest
-		errorType = "VALIDATION_ERROR"
-		message = "Publication year must be between 1000 and 2034"
-	case entities.ErrInvalidUUID:
-		code = http.StatusBadRequest
-		errorType = "VALIDATION_ERROR"
-		message = "Invalid UUID format provided"
-	case entities.ErrDatabaseError:
-		code = http.StatusInternalServerError
-		errorType = "INTERNAL_ERROR"
-		message = "A database error occurred. Please try again later"
-	default:
-		// Handle Echo HTTP errors
-		if he, ok := err.(*echo.HTTPError); ok {
-			code = he.Code
-			errorType = "HTTP_ERROR"
-			message = he.Message.(string)
-		} else {
-			// Unknown error
-			code = http.StatusInternalServerError
-			errorType = "INTERNAL_ERROR"
-			message = "An unexpected error occurred. Please try again later"
-		}
-	}
-
-	// Log the error with context
-	eh.logger.Error("HTTP error occurred",
-		zap.String("request_id", requestID),
-		zap.Int("status_code", code),
-		zap.String("error_type", errorType),
-		zap.String("path", c.Request().URL.Path),
-		zap.String("method", c.Request().Method),
-		zap.Error(err),
-	)
-
-	// Send structured error response
-	errorResponse := ErrorResponse{
-		Error:     errorType,
-		Message:   message,
-		RequestID: requestID,
-		Code:      code,
-	}
 
 	// If response has already been committed, just log the error
 	if c.Response().Committed {
@@ -100,8 +32,23 @@ func (eh *ErrorHandler) CustomHTTPErrorHandler(err error, c echo.Context) {
 		return
 	}
 
-	// Send the error response
-	if err := c.JSON(code, errorResponse); err != nil {
+	p := problem.FromError(err)
+	if p.Status >= http.StatusInternalServerError {
+		eh.logger.Error("HTTP error occurred",
+			zap.String("request_id", requestID),
+			zap.Int("status_code", p.Status),
+			zap.String("path", c.Request().URL.Path),
+			zap.String("method", c.Request().Method),
+			zap.Error(err),
+		)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(p.Status)
+	} else {
+		err = problem.Write(c, p)
+	}
+	if err != nil {
 		eh.logger.Error("Failed to send error response",
 			zap.String("request_id", requestID),
 			zap.Error(err),
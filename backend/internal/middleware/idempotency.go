@@ -0,0 +1,263 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tenancy"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	defaultIdempotencyTTL     = 24 * time.Hour
+	defaultIdempotencyLockTTL = time.Minute
+	defaultIdempotencyMaxBody = 1 << 20
+)
+
+// Response headers that describe the original exchange rather than the
+// resource and must not be replayed
+var idempotencySkippedHeaders = map[string]bool{
+	echo.HeaderXRequestID:               true,
+	echo.HeaderContentLength:            true,
+	"Date":                              true,
+	IdempotentReplayedHeader:            true,
+	echo.HeaderAccessControlAllowOrigin: true,
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long completed responses are replayed
+	TTL time.Duration
+	// LockTTL bounds how long an in-flight request holds its key
+	LockTTL time.Duration
+	// CleanupInterval is how often expired keys are purged
+	CleanupInterval time.Duration
+	// MaxBodySize bounds the request bodies read to fingerprint the payload,
+	// in bytes; BodyLimits overrides it per method and route template as in
+	// TimeoutConfig
+	MaxBodySize int64
+	BodyLimits  map[string]int64
+}
+
+type IdempotencyMiddleware struct {
+	repo   repositories.IdempotencyRepository
+	config IdempotencyConfig
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewIdempotencyMiddleware(repo repositories.IdempotencyRepository, config IdempotencyConfig, logger *zap.Logger) *IdempotencyMiddleware {
+	if config.TTL <= 0 {
+		config.TTL = defaultIdempotencyTTL
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = defaultIdempotencyLockTTL
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultIdempotencyMaxBody
+	}
+	return &IdempotencyMiddleware{
+		repo:   repo,
+		config: config,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// idempotencyRecorder tees the response body so it can be stored for replay
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *idempotencyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Handler honors the Idempotency-Key header on POST and PATCH requests.
+// A retry replays the stored response, a concurrent duplicate gets 409 and
+// reusing a key for a different payload gets 422.
+func (im *IdempotencyMiddleware) Handler() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			key := req.Header.Get(IdempotencyKeyHeader)
+			if key == "" || (req.Method != http.MethodPost && req.Method != http.MethodPatch) {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return echo.NewHTTPError(http.StatusBadRequest, "Idempotency-Key is too long")
+			}
+
+			// The body is buffered for the fingerprint before the handler
+			// can apply its own limit, so it is bounded here
+			limit := im.config.MaxBodySize
+			if routeLimit, ok := im.config.BodyLimits[req.Method+" "+c.Path()]; ok {
+				limit = routeLimit
+			}
+			tooLarge := echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must be at most %d bytes", limit))
+			if req.ContentLength > limit {
+				return tooLarge
+			}
+			body, err := io.ReadAll(http.MaxBytesReader(c.Response(), req.Body, limit))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					return tooLarge
+				}
+				return echo.NewHTTPError(http.StatusBadRequest, "Failed to read request body")
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			tenantID := uuid.Nil
+			if tenant, ok := tenancy.FromContext(req.Context()); ok {
+				tenantID = tenant.ID
+			}
+
+			record := &entities.IdempotencyRecord{
+				TenantID:    tenantID,
+				Key:         key,
+				Method:      req.Method,
+				Path:        req.URL.Path,
+				RequestHash: fingerprint(req.Method, req.URL.Path, body),
+				LockID:      uuid.New(),
+				ExpiresAt:   im.now().Add(im.config.LockTTL),
+			}
+
+			stored, acquired, err := im.repo.Acquire(req.Context(), record)
+			if err != nil {
+				if errors.Is(err, entities.ErrIdempotencyKeyInFlight) {
+					return echo.NewHTTPError(http.StatusConflict, err.Error())
+				}
+				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to process Idempotency-Key")
+			}
+
+			if !acquired {
+				return im.replay(c, record, stored)
+			}
+
+			recorder := &idempotencyRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			err = next(c)
+
+			// Errors and server failures are not stored so that the client can retry
+			status := c.Response().Status
+			if err != nil || !c.Response().Committed || status >= http.StatusInternalServerError {
+				im.release(req.Context(), record)
+				return err
+			}
+
+			headers := http.Header{}
+			for name, values := range c.Response().Header() {
+				if !idempotencySkippedHeaders[http.CanonicalHeaderKey(name)] {
+					headers[name] = values
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), 5*time.Second)
+			defer cancel()
+			err = im.repo.Complete(ctx, tenantID, key, record.LockID, status, headers, recorder.body.Bytes(), im.now().Add(im.config.TTL))
+			if errors.Is(err, entities.ErrIdempotencyKeyLost) {
+				// The claim lapsed during the request; the response of the
+				// request that took the key over is the one kept
+				logging.FromContext(ctx, im.logger).Warn("Idempotency key was taken over before the response was stored",
+					zap.String("idempotency_key", key),
+				)
+			} else if err != nil {
+				logging.FromContext(ctx, im.logger).Error("Failed to store idempotent response",
+					zap.String("idempotency_key", key),
+					zap.Error(err),
+				)
+			}
+			return nil
+		}
+	}
+}
+
+func (im *IdempotencyMiddleware) replay(c echo.Context, request, stored *entities.IdempotencyRecord) error {
+	if stored.RequestHash != request.RequestHash {
+		logging.FromContext(c.Request().Context(), im.logger).Warn("Idempotency key reused with a different payload",
+			zap.String("idempotency_key", request.Key),
+		)
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, entities.ErrIdempotencyKeyReused.Error())
+	}
+
+	if stored.Status != entities.IdempotencyStatusCompleted || stored.ResponseStatus == nil {
+		return echo.NewHTTPError(http.StatusConflict, entities.ErrIdempotencyKeyInFlight.Error())
+	}
+
+	for name, values := range stored.ResponseHeaders {
+		for _, value := range values {
+			c.Response().Header().Add(name, value)
+		}
+	}
+	c.Response().Header().Set(IdempotentReplayedHeader, "true")
+	c.Response().Header().Set(echo.HeaderContentLength, strconv.Itoa(len(stored.ResponseBody)))
+	c.Response().WriteHeader(*stored.ResponseStatus)
+	_, err := c.Response().Write(stored.ResponseBody)
+	return err
+}
+
+// release gives up the claim of record, unless another request has taken
+// the key over since
+func (im *IdempotencyMiddleware) release(reqCtx context.Context, record *entities.IdempotencyRecord) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(reqCtx), 5*time.Second)
+	defer cancel()
+	err := im.repo.Release(ctx, record.TenantID, record.Key, record.LockID)
+	if err != nil && !errors.Is(err, entities.ErrIdempotencyKeyLost) {
+		logging.FromContext(ctx, im.logger).Error("Failed to release idempotency key", zap.String("idempotency_key", record.Key), zap.Error(err))
+	}
+}
+
+// RunCleanup purges expired keys every CleanupInterval until ctx is done
+func (im *IdempotencyMiddleware) RunCleanup(ctx context.Context) {
+	interval := im.config.CleanupInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := im.repo.DeleteExpired(ctx)
+			if err != nil {
+				im.logger.Error("Failed to purge expired idempotency keys", zap.Error(err))
+				continue
+			}
+			if deleted > 0 {
+				im.logger.Info("Purged expired idempotency keys", zap.Int64("count", deleted))
+			}
+		}
+	}
+}
+
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
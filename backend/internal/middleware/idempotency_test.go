@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryIdempotencyRepository is an in-memory stand-in for the Postgres store
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]*entities.IdempotencyRecord
+}
+
+func newMemoryIdempotencyRepository() *memoryIdempotencyRepository {
+	return &memoryIdempotencyRepository{records: make(map[string]*entities.IdempotencyRecord)}
+}
+
+func (m *memoryIdempotencyRepository) Acquire(ctx context.Context, record *entities.IdempotencyRecord) (*entities.IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := record.TenantID.String() + "/" + record.Key
+	if existing, ok := m.records[id]; ok && existing.ExpiresAt.After(time.Now()) {
+		copied := *existing
+		return &copied, false, nil
+	}
+	stored := *record
+	stored.Status = entities.IdempotencyStatusInFlight
+	m.records[id] = &stored
+	return &stored, true, nil
+}
+
+func (m *memoryIdempotencyRepository) Complete(ctx context.Context, tenantID uuid.UUID, key string, lockID uuid.UUID, status int, headers http.Header, body []byte, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[tenantID.String()+"/"+key]
+	if !ok || record.Status != entities.IdempotencyStatusInFlight || record.LockID != lockID {
+		return entities.ErrIdempotencyKeyLost
+	}
+	record.Status = entities.IdempotencyStatusCompleted
+	record.ResponseStatus = &status
+	record.ResponseHeaders = entities.StoredHeaders(headers)
+	record.ResponseBody = append([]byte(nil), body...)
+	record.ExpiresAt = expiresAt
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) Release(ctx context.Context, tenantID uuid.UUID, key string, lockID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := tenantID.String() + "/" + key
+	record, ok := m.records[id]
+	if !ok || record.Status != entities.IdempotencyStatusInFlight || record.LockID != lockID {
+		return entities.ErrIdempotencyKeyLost
+	}
+	delete(m.records, id)
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func setupIdempotencyTest(handler echo.HandlerFunc) (*echo.Echo, *memoryIdempotencyRepository) {
+	repo := newMemoryIdempotencyRepository()
+	im := NewIdempotencyMiddleware(repo, IdempotencyConfig{}, zap.NewNop())
+
+	e := echo.New()
+	e.Use(im.Handler())
+	e.POST("/api/v1/books", handler)
+	return e, repo
+}
+
+func postWithKey(e *echo.Echo, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/books", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(IdempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	t.Run("retry replays stored response", func(t *testing.T) {
+		calls := 0
+		e, _ := setupIdempotencyTest(func(c echo.Context) error {
+			calls++
+			c.Response().Header().Set("Location", "/api/v1/books/1")
+			return c.JSON(http.StatusCreated, map[string]int{"call": calls})
+		})
+
+		first := postWithKey(e, "key-1", `{"title":"Clean Code"}`)
+		second := postWithKey(e, "key-1", `{"title":"Clean Code"}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusCreated, second.Code)
+		assert.Equal(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, "/api/v1/books/1", second.Header().Get("Location"))
+		assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	})
+
+	t.Run("different payload is rejected", func(t *testing.T) {
+		e, _ := setupIdempotencyTest(func(c echo.Context) error {
+			return c.JSON(http.StatusCreated, map[string]string{"status": "created"})
+		})
+
+		postWithKey(e, "key-2", `{"title":"Clean Code"}`)
+		rec := postWithKey(e, "key-2", `{"title":"Refactoring"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+
+	t.Run("concurrent duplicate gets conflict", func(t *testing.T) {
+		e, repo := setupIdempotencyTest(func(c echo.Context) error {
+			return c.JSON(http.StatusCreated, map[string]string{"status": "created"})
+		})
+		repo.records[uuid.Nil.String()+"/key-3"] = &entities.IdempotencyRecord{
+			Key:         "key-3",
+			RequestHash: fingerprint(http.MethodPost, "/api/v1/books", []byte(`{"title":"Clean Code"}`)),
+			Status:      entities.IdempotencyStatusInFlight,
+			ExpiresAt:   time.Now().Add(time.Minute),
+		}
+
+		rec := postWithKey(e, "key-3", `{"title":"Clean Code"}`)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("server errors are not stored", func(t *testing.T) {
+		calls := 0
+		e, _ := setupIdempotencyTest(func(c echo.Context) error {
+			calls++
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "boom"})
+		})
+
+		postWithKey(e, "key-4", `{}`)
+		postWithKey(e, "key-4", `{}`)
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("a request whose claim lapsed leaves the new owner's key alone", func(t *testing.T) {
+		var e *echo.Echo
+		var repo *memoryIdempotencyRepository
+		takeover := &entities.IdempotencyRecord{
+			Key:         "key-8",
+			RequestHash: fingerprint(http.MethodPost, "/api/v1/books", []byte(`{}`)),
+			LockID:      uuid.New(),
+			Status:      entities.IdempotencyStatusInFlight,
+			ExpiresAt:   time.Now().Add(time.Minute),
+		}
+		e, repo = setupIdempotencyTest(func(c echo.Context) error {
+			repo.mu.Lock()
+			repo.records[uuid.Nil.String()+"/key-8"] = takeover
+			repo.mu.Unlock()
+			return c.JSON(http.StatusCreated, map[string]string{"status": "created"})
+		})
+
+		rec := postWithKey(e, "key-8", `{}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		stored := repo.records[uuid.Nil.String()+"/key-8"]
+		assert.Equal(t, entities.IdempotencyStatusInFlight, stored.Status)
+		assert.Nil(t, stored.ResponseStatus)
+	})
+
+	t.Run("oversized body is rejected before it is read", func(t *testing.T) {
+		repo := newMemoryIdempotencyRepository()
+		im := NewIdempotencyMiddleware(repo, IdempotencyConfig{
+			MaxBodySize: 16,
+			BodyLimits:  map[string]int64{"POST /api/v1/books/files": 64},
+		}, zap.NewNop())
+		calls := 0
+		handler := func(c echo.Context) error {
+			calls++
+			return c.JSON(http.StatusCreated, map[string]string{"status": "created"})
+		}
+		e := echo.New()
+		e.Use(im.Handler())
+		e.POST("/api/v1/books", handler)
+		e.POST("/api/v1/books/files", handler)
+
+		body := strings.Repeat("x", 32)
+		rec := postWithKey(e, "key-5", body)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+		// Without a Content-Length the limit applies while reading
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/books", io.NopCloser(strings.NewReader(body)))
+		req.ContentLength = -1
+		req.Header.Set(IdempotencyKeyHeader, "key-6")
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+		assert.Equal(t, 0, calls)
+		assert.Empty(t, repo.records)
+
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/books/files", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, "key-7")
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+}
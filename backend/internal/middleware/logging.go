@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"byfood-library/internal/logging"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// RequestLogger stores a logger enriched with the request ID and route in
+// the request context and writes one structured access log line per
+// request. Paths under skipPrefixes (probes, metrics) are not access logged.
+func RequestLogger(base *zap.Logger, skipPrefixes ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			req := c.Request()
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			logger := base.With(
+				zap.String("request_id", GetRequestID(c)),
+				zap.String("method", req.Method),
+				zap.String("route", route),
+			)
+			c.SetRequest(req.WithContext(logging.WithLogger(req.Context(), logger)))
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			for _, prefix := range skipPrefixes {
+				if strings.HasPrefix(req.URL.Path, prefix) {
+					return nil
+				}
+			}
+
+			res := c.Response()
+			fields := []zap.Field{
+				zap.String("path", req.URL.Path),
+				zap.Int("status", res.Status),
+				zap.Duration("latency", time.Since(start)),
+				zap.String("remote_ip", c.RealIP()),
+				zap.String("user_agent", req.UserAgent()),
+				zap.Int64("bytes_in", req.ContentLength),
+				zap.Int64("bytes_out", res.Size),
+			}
+			if err != nil {
+				fields = append(fields, zap.Error(err))
+			}
+
+			// The handler chain may have enriched the logger (tenant, principal)
+			logger = logging.FromContext(c.Request().Context(), logger)
+			switch {
+			case res.Status >= http.StatusInternalServerError:
+				logger.Error("HTTP request", fields...)
+			case res.Status >= http.StatusBadRequest:
+				logger.Warn("HTTP request", fields...)
+			default:
+				logger.Info("HTTP request", fields...)
+			}
+			return nil
+		}
+	}
+}
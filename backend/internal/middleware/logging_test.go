@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"byfood-library/internal/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	e := echo.New()
+	e.Use(DefaultMiddleware())
+	e.Use(RequestLogger(zap.New(core), "/livez"))
+	e.GET("/api/v1/books/:id", func(c echo.Context) error {
+		ctx := logging.With(c.Request().Context(), nil, zap.String("tenant", "north"))
+		c.SetRequest(c.Request().WithContext(ctx))
+		logging.FromContext(ctx, nil).Info("Getting book")
+		return echo.NewHTTPError(http.StatusNotFound, "Book not found")
+	})
+	e.GET("/livez", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	t.Run("handler and access logs share request fields", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/books/123", nil)
+		req.Header.Set(echo.HeaderXRequestID, "req-42")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+
+		entries := logs.TakeAll()
+		assert.Len(t, entries, 2)
+
+		handlerLog := entries[0].ContextMap()
+		assert.Equal(t, "req-42", handlerLog["request_id"])
+		assert.Equal(t, "/api/v1/books/:id", handlerLog["route"])
+
+		access := entries[1]
+		assert.Equal(t, "HTTP request", access.Message)
+		assert.Equal(t, zapcore.WarnLevel, access.Level)
+		assert.Equal(t, "north", access.ContextMap()["tenant"])
+		assert.Equal(t, int64(http.StatusNotFound), access.ContextMap()["status"])
+	})
+
+	t.Run("probes are not access logged", func(t *testing.T) {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+		assert.Equal(t, 0, logs.Len())
+	})
+}
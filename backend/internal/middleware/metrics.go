@@ -1,12 +1,18 @@
 package middleware
 
 import (
+	"context"
+	"database/sql"
 	"strconv"
 	"time"
 
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/tenancy"
 	"github.com/labstack/echo/v4"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
 )
 
 var (
@@ -63,6 +69,101 @@ var (
 		},
 		[]string{"status"},
 	)
+
+	// Read-through cache lookups per tier
+	cacheLookupsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_lookups_total",
+			Help: "Total number of cache lookups by tier and result",
+		},
+		[]string{"cache", "tier", "result"},
+	)
+
+	cacheErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_errors_total",
+			Help: "Total number of failed cache tier or broadcast operations",
+		},
+		[]string{"cache", "operation"},
+	)
+
+	// Outbox relay deliveries per sink
+	eventsPublishedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "events_published_total",
+			Help: "Total number of domain event deliveries to sinks",
+		},
+		[]string{"sink", "event_type", "status"},
+	)
+
+	// Webhook delivery attempts and auto-disables
+	webhookDeliveriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_deliveries_total",
+			Help: "Total number of webhook delivery attempts by outcome",
+		},
+		[]string{"event_type", "status"},
+	)
+
+	webhookDeliveryDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "webhook_delivery_duration_seconds",
+			Help:    "Duration of webhook delivery attempts in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"event_type"},
+	)
+
+	webhooksDisabledTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "webhooks_disabled_total",
+			Help: "Total number of webhooks disabled after repeated delivery failures",
+		},
+	)
+
+	// Open Server-Sent Events connections
+	streamConnections = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "book_stream_connections",
+			Help: "Number of open book event streams",
+		},
+	)
+
+	// gRPC calls by method and status code
+	grpcRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_requests_total",
+			Help: "Total number of gRPC calls by method and status code",
+		},
+		[]string{"method", "code"},
+	)
+
+	grpcRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_request_duration_seconds",
+			Help:    "Duration of gRPC calls in seconds, including whole streams",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method"},
+	)
+
+	// Lookups in external bibliographic sources
+	enrichmentLookupsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "enrichment_lookups_total",
+			Help: "Total number of bibliographic source lookups by provider and result",
+		},
+		[]string{"provider", "result"},
+	)
+
+	enrichmentLookupDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "enrichment_lookup_duration_seconds",
+			Help:    "Duration of bibliographic source lookups in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"provider"},
+	)
 )
 
 // PrometheusMetrics middleware collects HTTP metrics
@@ -75,29 +176,39 @@ func PrometheusMetrics() echo.MiddlewareFunc {
 			activeConnections.Inc()
 			defer activeConnections.Dec()
 
-			// Process request
+			// Process request; errors are rendered here so the recorded
+			// status matches what the client receives
 			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
 			
 			// Calculate request duration
 			duration := time.Since(start).Seconds()
 			
 			// Get response status code
 			statusCode := strconv.Itoa(c.Response().Status)
+
+			// Label by route template so IDs in the URL don't create new series
+			path := c.Path()
+			if path == "" {
+				path = "unmatched"
+			}
 			
 			// Record metrics
 			httpRequestDuration.WithLabelValues(
 				c.Request().Method,
-				c.Path(),
+				path,
 				statusCode,
 			).Observe(duration)
 			
 			httpRequestsTotal.WithLabelValues(
 				c.Request().Method,
-				c.Path(),
+				path,
 				statusCode,
 			).Inc()
 
-			return err
+			return nil
 		}
 	}
 }
@@ -117,4 +228,112 @@ func RecordDatabaseOperation(operation, table string, duration time.Duration, su
 func UpdateBookMetrics(totalBooks int, activeBooks int) {
 	booksTotal.WithLabelValues("total").Set(float64(totalBooks))
 	booksTotal.WithLabelValues("active").Set(float64(activeBooks))
-}
\ No newline at end of file
+}
+
+// AddBookCount adjusts books_total after a create or delete
+func AddBookCount(delta int) {
+	booksTotal.WithLabelValues("total").Add(float64(delta))
+	booksTotal.WithLabelValues("active").Add(float64(delta))
+}
+
+// RecordCacheLookup records a hit or miss in one tier of the named cache
+func RecordCacheLookup(cache, tier string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookupsTotal.WithLabelValues(cache, tier, result).Inc()
+}
+
+// RecordCacheError records a failed cache operation, after which the cache
+// falls back to the database
+func RecordCacheError(cache, operation string) {
+	cacheErrorsTotal.WithLabelValues(cache, operation).Inc()
+}
+
+// RecordEventPublish records one attempt to publish an outbox event to sink
+func RecordEventPublish(sink, eventType string, success bool) {
+	status := "success"
+	if !success {
+		status = "error"
+	}
+	eventsPublishedTotal.WithLabelValues(sink, eventType, status).Inc()
+}
+
+// RecordWebhookDelivery records one webhook delivery attempt; status is
+// "succeeded", "retrying" or "failed"
+func RecordWebhookDelivery(eventType, status string, duration time.Duration) {
+	webhookDeliveriesTotal.WithLabelValues(eventType, status).Inc()
+	webhookDeliveryDuration.WithLabelValues(eventType).Observe(duration.Seconds())
+}
+
+// RecordWebhookDisabled records a webhook disabled after repeated failures
+func RecordWebhookDisabled() {
+	webhooksDisabledTotal.Inc()
+}
+
+// AddStreamConnections adjusts book_stream_connections as streams open and close
+func AddStreamConnections(delta int) {
+	streamConnections.Add(float64(delta))
+}
+
+// RecordGRPCRequest records one finished gRPC call
+func RecordGRPCRequest(method, code string, duration time.Duration) {
+	grpcRequestsTotal.WithLabelValues(method, code).Inc()
+	grpcRequestDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// RecordEnrichmentLookup records one lookup in a bibliographic source;
+// result is "found", "not_found" or "error"
+func RecordEnrichmentLookup(provider, result string, duration time.Duration) {
+	enrichmentLookupsTotal.WithLabelValues(provider, result).Inc()
+	enrichmentLookupDuration.WithLabelValues(provider).Observe(duration.Seconds())
+}
+
+// RegisterDatabaseMetrics exports connection pool statistics for db
+func RegisterDatabaseMetrics(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// RunBookMetrics recounts books across all tenants every interval until ctx
+// is done, correcting drift from writes made by other instances
+func RunBookMetrics(ctx context.Context, tenants repositories.TenantRepository, books repositories.BookRepository, interval time.Duration, logger *zap.Logger) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if total, err := countBooks(ctx, tenants, books); err != nil {
+			if ctx.Err() == nil {
+				logger.Error("Failed to refresh book metrics", zap.Error(err))
+			}
+		} else {
+			UpdateBookMetrics(total, total)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func countBooks(ctx context.Context, tenants repositories.TenantRepository, books repositories.BookRepository) (int, error) {
+	all, err := tenants.GetAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, tenant := range all {
+		count, err := books.Count(tenancy.WithTenant(ctx, tenant))
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	e := echo.New()
+	e.Use(PrometheusMetrics())
+	e.GET("/api/v1/books/:id", func(c echo.Context) error {
+		if c.Param("id") == "missing" {
+			return echo.NewHTTPError(http.StatusNotFound, "Book not found")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	t.Run("labels use the route template", func(t *testing.T) {
+		before := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("GET", "/api/v1/books/:id", "200"))
+
+		for _, id := range []string{"a", "b", "c"} {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/books/"+id, nil))
+		}
+
+		after := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("GET", "/api/v1/books/:id", "200"))
+		assert.Equal(t, 3.0, after-before)
+	})
+
+	t.Run("handler errors record the rendered status", func(t *testing.T) {
+		before := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("GET", "/api/v1/books/:id", "404"))
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/books/missing", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		after := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("GET", "/api/v1/books/:id", "404"))
+		assert.Equal(t, 1.0, after-before)
+	})
+}
+
+func TestAddBookCount(t *testing.T) {
+	UpdateBookMetrics(5, 5)
+	AddBookCount(1)
+	AddBookCount(-2)
+
+	assert.Equal(t, 4.0, testutil.ToFloat64(booksTotal.WithLabelValues("total")))
+}
@@ -0,0 +1,267 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tenancy"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const TenantIDKey = "tenant_id"
+
+// tenantResolutionKey keeps the outcome of resolving the request's tenant,
+// which CORS needs before Resolve runs
+const tenantResolutionKey = "tenant_resolution"
+
+type tenantResolution struct {
+	tenant *entities.Tenant
+	err    error
+}
+
+// TenantResolver maps a request to its tenant
+type TenantResolver interface {
+	Resolve(ctx context.Context, req *http.Request) (*entities.Tenant, error)
+}
+
+// TenantConfig holds the deployment-wide defaults that tenants may override
+type TenantConfig struct {
+	CORS            middleware.CORSConfig
+	EnableRateLimit bool
+	RateLimitRPS    int
+	RateLimitBurst  int
+	// SkipPrefixes lists paths served without a tenant (health, admin, docs)
+	SkipPrefixes []string
+	// APIKeyHeader identifies requests authenticated by a tenant API key;
+	// empty when API key resolution is disabled
+	APIKeyHeader string
+}
+
+type tenantLimiter struct {
+	limiter *rate.Limiter
+	rps     int
+	burst   int
+}
+
+type TenantMiddleware struct {
+	resolver TenantResolver
+	logger   *zap.Logger
+
+	// config may be replaced at runtime by UpdateDefaults
+	configMu sync.RWMutex
+	config   TenantConfig
+
+	corsMu sync.Mutex
+	cors   map[string]echo.MiddlewareFunc
+
+	limitersMu sync.Mutex
+	limiters   map[uuid.UUID]*tenantLimiter
+}
+
+func NewTenantMiddleware(resolver TenantResolver, config TenantConfig, logger *zap.Logger) *TenantMiddleware {
+	return &TenantMiddleware{
+		resolver: resolver,
+		config:   config,
+		logger:   logger,
+		cors:     make(map[string]echo.MiddlewareFunc),
+		limiters: make(map[uuid.UUID]*tenantLimiter),
+	}
+}
+
+// UpdateDefaults replaces the deployment-wide CORS and rate limit defaults,
+// e.g. after a configuration reload. Tenant overrides still take precedence.
+func (tm *TenantMiddleware) UpdateDefaults(cors middleware.CORSConfig, enableRateLimit bool, rps, burst int) {
+	tm.configMu.Lock()
+	tm.config.CORS = cors
+	tm.config.EnableRateLimit = enableRateLimit
+	tm.config.RateLimitRPS = rps
+	tm.config.RateLimitBurst = burst
+	tm.configMu.Unlock()
+
+	// Cached CORS handlers were built from the previous defaults
+	tm.corsMu.Lock()
+	tm.cors = make(map[string]echo.MiddlewareFunc)
+	tm.corsMu.Unlock()
+}
+
+func (tm *TenantMiddleware) settings() TenantConfig {
+	tm.configMu.RLock()
+	defer tm.configMu.RUnlock()
+	return tm.config
+}
+
+func (tm *TenantMiddleware) skip(c echo.Context) bool {
+	path := c.Request().URL.Path
+	for _, prefix := range tm.settings().SkipPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve resolves the request's tenant once per request
+func (tm *TenantMiddleware) resolve(c echo.Context) (*entities.Tenant, error) {
+	if resolution, ok := c.Get(tenantResolutionKey).(tenantResolution); ok {
+		return resolution.tenant, resolution.err
+	}
+	tenant, err := tm.resolver.Resolve(c.Request().Context(), c.Request())
+	c.Set(tenantResolutionKey, tenantResolution{tenant: tenant, err: err})
+	return tenant, err
+}
+
+// Resolve attaches the request's tenant to the Echo and Go contexts. It
+// runs after CORS, so that its errors reach browsers as readable responses.
+func (tm *TenantMiddleware) Resolve() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if tm.skip(c) {
+				return next(c)
+			}
+
+			tenant, err := tm.resolve(c)
+			if err != nil && c.Request().Method == http.MethodOptions {
+				// Preflights carry no credentials; fall back to default CORS
+				return next(c)
+			}
+			if err != nil {
+				logging.FromContext(c.Request().Context(), tm.logger).Warn("Failed to resolve tenant",
+					zap.String("host", c.Request().Host),
+					zap.String("path", c.Request().URL.Path),
+					zap.Error(err),
+				)
+				switch {
+				case errors.Is(err, entities.ErrTenantNotFound):
+					return echo.NewHTTPError(http.StatusNotFound, "Unknown tenant")
+				case errors.Is(err, entities.ErrTenantSuspended):
+					return echo.NewHTTPError(http.StatusForbidden, "Tenant is suspended")
+				case errors.Is(err, entities.ErrTenantRequired):
+					return echo.NewHTTPError(http.StatusBadRequest, "Tenant could not be determined")
+				default:
+					return echo.NewHTTPError(http.StatusInternalServerError, "Failed to resolve tenant")
+				}
+			}
+
+			principal := "anonymous"
+			if header := tm.settings().APIKeyHeader; header != "" && c.Request().Header.Get(header) != "" {
+				principal = "tenant:" + tenant.Slug
+			}
+
+			c.Set(TenantIDKey, tenant.ID.String())
+			ctx := tenancy.WithTenant(c.Request().Context(), tenant)
+			ctx = logging.With(ctx, tm.logger, zap.String("tenant", tenant.Slug), zap.String("principal", principal))
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// CORS applies the tenant's allowed origins, falling back to the deployment
+// defaults when the request has no tenant or an unknown one. One Echo CORS
+// middleware is built per distinct CORS policy.
+func (tm *TenantMiddleware) CORS() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			settings := tm.settings()
+			origins := settings.CORS.AllowOrigins
+			if !tm.skip(c) {
+				if tenant, err := tm.resolve(c); err == nil && len(tenant.Settings.CORSOrigins) > 0 {
+					origins = tenant.Settings.CORSOrigins
+				}
+			}
+			return tm.corsFor(settings.CORS, origins)(next)(c)
+		}
+	}
+}
+
+func (tm *TenantMiddleware) corsFor(defaults middleware.CORSConfig, origins []string) echo.MiddlewareFunc {
+	key := strings.Join(origins, ",") + "|" + strings.Join(defaults.AllowMethods, ",") + "|" + strings.Join(defaults.AllowHeaders, ",")
+
+	tm.corsMu.Lock()
+	defer tm.corsMu.Unlock()
+
+	if mw, ok := tm.cors[key]; ok {
+		return mw
+	}
+	cfg := defaults
+	cfg.AllowOrigins = origins
+	mw := middleware.CORSWithConfig(cfg)
+	tm.cors[key] = mw
+	return mw
+}
+
+// RateLimiter enforces a token bucket per tenant using the tenant's limits
+// or the deployment defaults
+func (tm *TenantMiddleware) RateLimiter() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			tenant, ok := tenancy.FromContext(c.Request().Context())
+			if !tm.settings().EnableRateLimit || !ok {
+				return next(c)
+			}
+
+			if !tm.limiterFor(tenant).Allow() {
+				logging.FromContext(c.Request().Context(), tm.logger).Warn("Tenant rate limit exceeded",
+					zap.String("remote_addr", c.Request().RemoteAddr),
+				)
+				return echo.NewHTTPError(http.StatusTooManyRequests, "Rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
+
+func (tm *TenantMiddleware) limiterFor(tenant *entities.Tenant) *rate.Limiter {
+	settings := tm.settings()
+	rps, burst := settings.RateLimitRPS, settings.RateLimitBurst
+	if tenant.Settings.RateLimitRPS > 0 {
+		rps = tenant.Settings.RateLimitRPS
+	}
+	if tenant.Settings.RateLimitBurst > 0 {
+		burst = tenant.Settings.RateLimitBurst
+	}
+
+	tm.limitersMu.Lock()
+	defer tm.limitersMu.Unlock()
+
+	l, ok := tm.limiters[tenant.ID]
+	if !ok || l.rps != rps || l.burst != burst {
+		l = &tenantLimiter{limiter: rate.NewLimiter(rate.Limit(rps), burst), rps: rps, burst: burst}
+		tm.limiters[tenant.ID] = l
+	}
+	return l.limiter
+}
+
+// AdminAuth protects the admin API with a shared key sent in X-Admin-Key.
+// The admin API is disabled when no key is configured.
+func AdminAuth(adminKey string, logger *zap.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if adminKey == "" {
+				return echo.NewHTTPError(http.StatusNotFound, "Admin API is disabled")
+			}
+
+			provided := c.Request().Header.Get("X-Admin-Key")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(adminKey)) != 1 {
+				logging.FromContext(c.Request().Context(), logger).Warn("Invalid admin key",
+					zap.String("remote_addr", c.Request().RemoteAddr),
+				)
+				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid admin key")
+			}
+
+			ctx := logging.With(c.Request().Context(), logger, zap.String("principal", "admin"))
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
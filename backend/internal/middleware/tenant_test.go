@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type stubResolver struct {
+	tenants map[string]*entities.Tenant
+	calls   int
+}
+
+func (r *stubResolver) Resolve(ctx context.Context, req *http.Request) (*entities.Tenant, error) {
+	r.calls++
+	tenant, ok := r.tenants[req.Header.Get("X-Tenant-ID")]
+	if !ok {
+		return nil, entities.ErrTenantNotFound
+	}
+	return tenant, nil
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	north := &entities.Tenant{ID: uuid.New(), Slug: "north", Status: entities.TenantStatusActive,
+		Settings: entities.TenantSettings{CORSOrigins: []string{"https://north.example.com"}}}
+	resolver := &stubResolver{tenants: map[string]*entities.Tenant{"north": north}}
+	tm := NewTenantMiddleware(resolver, TenantConfig{
+		CORS: middleware.CORSConfig{AllowOrigins: []string{"https://app.example.com"}},
+	}, zap.NewNop())
+
+	e := echo.New()
+	e.Use(tm.CORS())
+	e.Use(tm.Resolve())
+	e.GET("/books", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Get(TenantIDKey).(string))
+	})
+
+	t.Run("tenant origins apply and the tenant is resolved once", func(t *testing.T) {
+		resolver.calls = 0
+		req := httptest.NewRequest(http.MethodGet, "/books", nil)
+		req.Header.Set("X-Tenant-ID", "north")
+		req.Header.Set(echo.HeaderOrigin, "https://north.example.com")
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, north.ID.String(), rec.Body.String())
+		assert.Equal(t, "https://north.example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+		assert.Equal(t, 1, resolver.calls)
+	})
+
+	t.Run("unknown tenant errors carry the default CORS headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/books", nil)
+		req.Header.Set("X-Tenant-ID", "bogus")
+		req.Header.Set(echo.HeaderOrigin, "https://app.example.com")
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "https://app.example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	})
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TimeoutConfig sets request deadlines. Routes are keyed by method and route
+// template, e.g. "GET /api/v1/books/:id"; a zero duration disables the
+// deadline for that route (useful for streaming endpoints).
+type TimeoutConfig struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// RequestTimeout bounds the context handed to handlers so that use cases and
+// repositories stop working on requests the server has given up on
+func RequestTimeout(config TimeoutConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			timeout := config.Default
+			if routeTimeout, ok := config.Routes[c.Request().Method+" "+c.Path()]; ok {
+				timeout = routeTimeout
+			}
+			if timeout <= 0 {
+				return next(c)
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
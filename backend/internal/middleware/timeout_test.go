@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	e := echo.New()
+	e.Use(RequestTimeout(TimeoutConfig{
+		Default: 10 * time.Second,
+		Routes: map[string]time.Duration{
+			"GET /books/:id":    time.Second,
+			"GET /books/stream": 0,
+		},
+	}))
+
+	var remaining time.Duration
+	var hasDeadline bool
+	handler := func(c echo.Context) error {
+		var deadline time.Time
+		deadline, hasDeadline = c.Request().Context().Deadline()
+		remaining = time.Until(deadline)
+		return c.NoContent(http.StatusOK)
+	}
+	e.GET("/books", handler)
+	e.GET("/books/:id", handler)
+	e.GET("/books/stream", handler)
+
+	tests := []struct {
+		name        string
+		path        string
+		hasDeadline bool
+		max         time.Duration
+	}{
+		{name: "default deadline", path: "/books", hasDeadline: true, max: 10 * time.Second},
+		{name: "route template override", path: "/books/42", hasDeadline: true, max: time.Second},
+		{name: "disabled for route", path: "/books/stream", hasDeadline: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.hasDeadline, hasDeadline)
+			if tt.hasDeadline {
+				assert.LessOrEqual(t, remaining, tt.max)
+				assert.Greater(t, remaining, tt.max-time.Second)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"byfood-library/internal/tracing"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupTracingTest(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	_, err := tracing.Setup(context.Background(), tracing.Config{})
+	assert.NoError(t, err)
+	return recorder
+}
+
+func TestTracing(t *testing.T) {
+	recorder := setupTracingTest(t)
+
+	e := echo.New()
+	e.Use(Tracing())
+	e.GET("/api/v1/books/:id", func(c echo.Context) error {
+		_, span := tracing.Start(c.Request().Context(), "BookUseCase.GetBookByID")
+		span.End()
+		if c.Param("id") == "broken" {
+			return echo.NewHTTPError(http.StatusInternalServerError, "boom")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	t.Run("continues the caller's trace", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/books/123", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		child, server := spans[len(spans)-2], spans[len(spans)-1]
+
+		assert.Equal(t, "GET /api/v1/books/:id", server.Name())
+		assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+		assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+		assert.Contains(t, server.Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+	})
+
+	t.Run("server errors mark the span failed", func(t *testing.T) {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/books/broken", nil))
+
+		spans := recorder.Ended()
+		server := spans[len(spans)-1]
+
+		assert.Equal(t, codes.Error, server.Status().Code)
+		assert.False(t, server.Parent().IsValid())
+	})
+}
@@ -0,0 +1,211 @@
+package oai
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+
+	"byfood-library/internal/domain/entities"
+)
+
+// Prefixes of the metadata formats
+const (
+	PrefixDublinCore = "oai_dc"
+	PrefixMARC       = "marcxml"
+)
+
+// Namespaces and schemas of the metadata formats
+const (
+	NamespaceDublinCore = "http://www.openarchives.org/OAI/2.0/oai_dc/"
+	SchemaDublinCore    = "http://www.openarchives.org/OAI/2.0/oai_dc.xsd"
+	NamespaceMARC       = "http://www.loc.gov/MARC21/slim"
+	SchemaMARC          = "http://www.loc.gov/standards/marcxml/schema/MARC21slim.xsd"
+
+	// NamespaceDCElements is the namespace of the simple Dublin Core
+	// elements, which the dc prefix of DCElements names
+	NamespaceDCElements = "http://purl.org/dc/elements/1.1/"
+)
+
+var metadataFormats = []MetadataFormat{
+	{Prefix: PrefixDublinCore, Schema: SchemaDublinCore, Namespace: NamespaceDublinCore},
+	{Prefix: PrefixMARC, Schema: SchemaMARC, Namespace: NamespaceMARC},
+}
+
+func supportsFormat(prefix string) bool {
+	for _, format := range metadataFormats {
+		if format.Prefix == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+func metadataOf(book *entities.Book, prefix string) *Metadata {
+	if prefix == PrefixMARC {
+		return &Metadata{MARC: MARCOf(book)}
+	}
+	return &Metadata{DublinCore: DublinCoreOf(book)}
+}
+
+// DublinCore is a book as an oai_dc record of simple Dublin Core elements.
+// It declares its namespaces, so that it can be embedded in any document.
+type DublinCore struct {
+	XMLName        xml.Name `xml:"oai_dc:dc"`
+	XmlnsOAIDC     string   `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string   `xml:"xmlns:dc,attr"`
+	XmlnsXSI       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	DCElements
+}
+
+// DCElements are the simple Dublin Core elements of a book, for records
+// that wrap them in their own element, such as oai_dc:dc
+type DCElements struct {
+	Title       string   `xml:"dc:title"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Subjects    []string `xml:"dc:subject"`
+	Description string   `xml:"dc:description,omitempty"`
+	Publisher   string   `xml:"dc:publisher,omitempty"`
+	Date        string   `xml:"dc:date,omitempty"`
+	Type        string   `xml:"dc:type"`
+	Identifiers []string `xml:"dc:identifier"`
+}
+
+// DublinCoreOf maps a book to an oai_dc record
+func DublinCoreOf(book *entities.Book) *DublinCore {
+	return &DublinCore{
+		XmlnsOAIDC:     NamespaceDublinCore,
+		XmlnsDC:        NamespaceDCElements,
+		XmlnsXSI:       nsXSI,
+		SchemaLocation: NamespaceDublinCore + " " + SchemaDublinCore,
+		DCElements:     DCElementsOf(book),
+	}
+}
+
+// DCElementsOf maps a book to Dublin Core
+func DCElementsOf(book *entities.Book) DCElements {
+	dc := DCElements{
+		Title:       book.Title,
+		Creator:     book.Author,
+		Subjects:    book.Subjects,
+		Description: book.Description,
+		Publisher:   book.Publisher,
+		Type:        "Text",
+	}
+	if book.Year > 0 {
+		dc.Date = strconv.Itoa(book.Year)
+	}
+	if book.ISBN != "" {
+		dc.Identifiers = append(dc.Identifiers, "urn:isbn:"+book.ISBN)
+	}
+	return dc
+}
+
+// MARCRecord is a book as a MARC 21 bibliographic record in MARCXML. It
+// declares its namespaces, so that it can be embedded in any document.
+type MARCRecord struct {
+	XMLName        xml.Name `xml:"marc:record"`
+	XmlnsMARC      string   `xml:"xmlns:marc,attr"`
+	XmlnsXSI       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+
+	Leader        string         `xml:"marc:leader"`
+	ControlFields []ControlField `xml:"marc:controlfield"`
+	DataFields    []DataField    `xml:"marc:datafield"`
+}
+
+type ControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type DataField struct {
+	Tag       string     `xml:"tag,attr"`
+	Ind1      string     `xml:"ind1,attr"`
+	Ind2      string     `xml:"ind2,attr"`
+	Subfields []Subfield `xml:"marc:subfield"`
+}
+
+type Subfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// marcLeader describes a new record of a monograph of language material,
+// encoded in Unicode at full level without ISBD punctuation; the lengths
+// are left for binary MARC to fill in
+const marcLeader = "00000nam a2200000   4500"
+
+// MARCOf maps a book to MARC 21: its ID and last change as the 001 and 005
+// control fields, the fixed-length 008 data, and the ISBN (020), author
+// (100), title (245), publication (264), extent (300), summary (520) and
+// subjects (650)
+func MARCOf(book *entities.Book) *MARCRecord {
+	record := &MARCRecord{
+		XmlnsMARC:      NamespaceMARC,
+		XmlnsXSI:       nsXSI,
+		SchemaLocation: NamespaceMARC + " " + SchemaMARC,
+		Leader:         marcLeader,
+		ControlFields: []ControlField{
+			{Tag: "001", Value: book.ID.String()},
+			{Tag: "005", Value: book.UpdatedAt.UTC().Format("20060102150405") + ".0"},
+			{Tag: "008", Value: marcFixedData(book)},
+		},
+	}
+	field := func(tag, ind1, ind2 string, subfields ...Subfield) {
+		record.DataFields = append(record.DataFields, DataField{Tag: tag, Ind1: ind1, Ind2: ind2, Subfields: subfields})
+	}
+
+	if book.ISBN != "" {
+		field("020", " ", " ", Subfield{Code: "a", Value: book.ISBN})
+	}
+	// The title is an added entry when the record has a main entry
+	titleAdded := "0"
+	if book.Author != "" {
+		field("100", "1", " ", Subfield{Code: "a", Value: book.Author})
+		titleAdded = "1"
+	}
+	title := []Subfield{{Code: "a", Value: book.Title}}
+	if book.Author != "" {
+		title = append(title, Subfield{Code: "c", Value: book.Author})
+	}
+	field("245", titleAdded, "0", title...)
+
+	var publication []Subfield
+	if book.Publisher != "" {
+		publication = append(publication, Subfield{Code: "b", Value: book.Publisher})
+	}
+	if book.Year > 0 {
+		publication = append(publication, Subfield{Code: "c", Value: strconv.Itoa(book.Year)})
+	}
+	if len(publication) > 0 {
+		field("264", " ", "1", publication...)
+	}
+	if book.PageCount > 0 {
+		field("300", " ", " ", Subfield{Code: "a", Value: strconv.Itoa(book.PageCount) + " pages"})
+	}
+	if book.Description != "" {
+		field("520", " ", " ", Subfield{Code: "a", Value: book.Description})
+	}
+	for _, subject := range book.Subjects {
+		// Second indicator 4: the subject is not from a controlled vocabulary
+		field("650", " ", "4", Subfield{Code: "a", Value: subject})
+	}
+	return record
+}
+
+// marcFixedData is the 40 characters of the 008 field: when the record was
+// entered, the publication date, an unknown place and language, and fill
+// characters for the elements the catalogue does not record
+func marcFixedData(book *entities.Book) string {
+	entered := book.CreatedAt
+	if entered.IsZero() {
+		entered = book.UpdatedAt
+	}
+	dates := "nuuuu"
+	if book.Year > 0 && book.Year <= 9999 {
+		dates = fmt.Sprintf("s%04d", book.Year)
+	}
+	fill := "|||||||||||||||||"
+	return entered.UTC().Format("060102") + dates + "    " + "xx " + fill + "und" + " " + "d"
+}
@@ -0,0 +1,389 @@
+// Package oai is an OAI-PMH 2.0 data provider for the catalogue. It answers
+// the six protocol verbs with the books as oai_dc (Dublin Core) or MARCXML
+// records, harvested selectively by the datestamp of their last change.
+// Deleted books stay harvestable as deleted records, and long lists are
+// paged with resumption tokens that carry the position of the last record
+// sent, so a page never repeats or skips records when books change between
+// requests; a book updated mid-harvest is simply harvested again later.
+package oai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultRepositoryName = "Library"
+	DefaultPageSize       = 100
+	MaxPageSize           = 1000
+)
+
+// Config describes the repository. Zero values use the defaults.
+type Config struct {
+	// RepositoryName is the human readable name of the repository
+	RepositoryName string
+	// RepositoryIdentifier is the namespace of the records' OAI identifiers,
+	// such as "library.example.com"; the host name of the base URL when
+	// empty
+	RepositoryIdentifier string
+	// AdminEmails are the addresses of the repository's administrators
+	AdminEmails []string
+	// PageSize is the number of headers or records per list response
+	PageSize int
+}
+
+func (c Config) withDefaults() Config {
+	if c.RepositoryName == "" {
+		c.RepositoryName = DefaultRepositoryName
+	}
+	if c.PageSize <= 0 {
+		c.PageSize = DefaultPageSize
+	}
+	if c.PageSize > MaxPageSize {
+		c.PageSize = MaxPageSize
+	}
+	return c
+}
+
+// Provider answers OAI-PMH requests
+type Provider struct {
+	harvest usecases.HarvestUseCase
+	config  Config
+	now     func() time.Time
+}
+
+func NewProvider(harvest usecases.HarvestUseCase, config Config) *Provider {
+	return &Provider{
+		harvest: harvest,
+		config:  config.withDefaults(),
+		now:     time.Now,
+	}
+}
+
+// verb lists the arguments of a verb besides the verb itself
+type verb struct {
+	required []string
+	optional []string
+	// resumable verbs also take a resumptionToken, exclusive of the others
+	resumable bool
+}
+
+var verbs = map[string]verb{
+	"Identify":            {},
+	"ListMetadataFormats": {optional: []string{"identifier"}},
+	"ListSets":            {resumable: true},
+	"GetRecord":           {required: []string{"identifier", "metadataPrefix"}},
+	"ListIdentifiers":     {required: []string{"metadataPrefix"}, optional: []string{"from", "until", "set"}, resumable: true},
+	"ListRecords":         {required: []string{"metadataPrefix"}, optional: []string{"from", "until", "set"}, resumable: true},
+}
+
+func (v verb) allows(argument string) bool {
+	if argument == "resumptionToken" {
+		return v.resumable
+	}
+	for _, names := range [][]string{v.required, v.optional} {
+		for _, name := range names {
+			if argument == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Handle answers the request whose arguments are args, made to the
+// repository at baseURL. Protocol errors are reported in the response;
+// an error is returned only when the catalogue cannot be read.
+func (p *Provider) Handle(ctx context.Context, baseURL string, args url.Values) (*Response, error) {
+	response := newResponse(p.now(), baseURL)
+	err := p.dispatch(ctx, baseURL, args, response)
+
+	var protocolErr *Error
+	if errors.As(err, &protocolErr) {
+		// The request element echoes the arguments only when they are valid
+		if protocolErr.Code == ErrBadVerb || protocolErr.Code == ErrBadArgument {
+			response.Request = Request{URL: baseURL}
+		}
+		response.Errors = append(response.Errors, *protocolErr)
+		return response, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (p *Provider) dispatch(ctx context.Context, baseURL string, args url.Values, response *Response) error {
+	if len(args["verb"]) != 1 {
+		return newError(ErrBadVerb, "the verb argument must be given exactly once")
+	}
+	name := args.Get("verb")
+	spec, ok := verbs[name]
+	if !ok {
+		return newError(ErrBadVerb, "%q is not an OAI-PMH verb", name)
+	}
+	if err := checkArguments(spec, args); err != nil {
+		return err
+	}
+	response.Request = Request{
+		URL:             baseURL,
+		Verb:            name,
+		Identifier:      args.Get("identifier"),
+		MetadataPrefix:  args.Get("metadataPrefix"),
+		From:            args.Get("from"),
+		Until:           args.Get("until"),
+		Set:             args.Get("set"),
+		ResumptionToken: args.Get("resumptionToken"),
+	}
+
+	switch name {
+	case "Identify":
+		return p.identify(ctx, baseURL, response)
+	case "ListMetadataFormats":
+		return p.listMetadataFormats(ctx, baseURL, args, response)
+	case "ListSets":
+		return newError(ErrNoSetHierarchy, "the repository does not support sets")
+	case "GetRecord":
+		return p.getRecord(ctx, baseURL, args, response)
+	case "ListIdentifiers":
+		return p.list(ctx, baseURL, args, response, false)
+	default:
+		return p.list(ctx, baseURL, args, response, true)
+	}
+}
+
+// checkArguments reports illegal, repeated and missing arguments
+func checkArguments(spec verb, args url.Values) error {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "verb" {
+			continue
+		}
+		if !spec.allows(name) {
+			return newError(ErrBadArgument, "%q is not an argument of this verb", name)
+		}
+		if len(args[name]) > 1 {
+			return newError(ErrBadArgument, "the %s argument is repeated", name)
+		}
+	}
+	if _, ok := args["resumptionToken"]; ok {
+		if len(args) > 2 {
+			return newError(ErrBadArgument, "resumptionToken is an exclusive argument")
+		}
+		return nil
+	}
+	for _, name := range spec.required {
+		if args.Get(name) == "" {
+			return newError(ErrBadArgument, "the %s argument is required", name)
+		}
+	}
+	return nil
+}
+
+func (p *Provider) identify(ctx context.Context, baseURL string, response *Response) error {
+	earliest, err := p.harvest.EarliestDatestamp(ctx)
+	if err != nil {
+		return err
+	}
+	if earliest.IsZero() {
+		// No record precedes the response of an empty repository
+		earliest = p.now()
+	}
+	repositoryID := p.repositoryIdentifier(baseURL)
+	response.Identify = &Identify{
+		RepositoryName:    p.config.RepositoryName,
+		BaseURL:           baseURL,
+		ProtocolVersion:   "2.0",
+		AdminEmails:       p.config.AdminEmails,
+		EarliestDatestamp: formatDatestamp(earliest),
+		DeletedRecord:     "persistent",
+		Granularity:       "YYYY-MM-DDThh:mm:ssZ",
+		Description: &Description{OAIIdentifier: OAIIdentifier{
+			Xmlns:                nsOAIIdentifier,
+			XmlnsXSI:             nsXSI,
+			SchemaLocation:       nsOAIIdentifier + " http://www.openarchives.org/OAI/2.0/oai-identifier.xsd",
+			Scheme:               "oai",
+			RepositoryIdentifier: repositoryID,
+			Delimiter:            ":",
+			SampleIdentifier:     identifier(repositoryID, uuid.Nil),
+		}},
+	}
+	return nil
+}
+
+func (p *Provider) listMetadataFormats(ctx context.Context, baseURL string, args url.Values, response *Response) error {
+	if raw := args.Get("identifier"); raw != "" {
+		if _, err := p.lookup(ctx, raw, p.repositoryIdentifier(baseURL)); err != nil {
+			return err
+		}
+	}
+	// Every record, deleted ones included, is available in every format
+	response.ListMetadataFormats = &ListMetadataFormats{Formats: metadataFormats}
+	return nil
+}
+
+func (p *Provider) getRecord(ctx context.Context, baseURL string, args url.Values, response *Response) error {
+	repositoryID := p.repositoryIdentifier(baseURL)
+	record, err := p.lookup(ctx, args.Get("identifier"), repositoryID)
+	if err != nil {
+		return err
+	}
+	prefix := args.Get("metadataPrefix")
+	if !supportsFormat(prefix) {
+		return newError(ErrCannotDisseminateFormat, "the %q metadata format is not supported", prefix)
+	}
+	response.GetRecord = &GetRecord{Record: recordOf(record, repositoryID, prefix)}
+	return nil
+}
+
+// lookup returns the record of an OAI identifier
+func (p *Provider) lookup(ctx context.Context, raw, repositoryID string) (*entities.HarvestRecord, error) {
+	id, ok := parseIdentifier(raw, repositoryID)
+	if !ok {
+		return nil, newError(ErrIDDoesNotExist, "%q is not an identifier of this repository", raw)
+	}
+	record, err := p.harvest.GetRecord(ctx, id)
+	if entities.KindOf(err) == entities.KindNotFound {
+		return nil, newError(ErrIDDoesNotExist, "%q is unknown or illegal in this repository", raw)
+	}
+	return record, err
+}
+
+// list answers ListRecords, or ListIdentifiers without the records'
+// metadata
+func (p *Provider) list(ctx context.Context, baseURL string, args url.Values, response *Response, withMetadata bool) error {
+	var state resumption
+	resumed := args.Get("resumptionToken") != ""
+	if resumed {
+		var err error
+		if state, err = decodeResumption(args.Get("resumptionToken")); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if state, err = newResumption(args); err != nil {
+			return err
+		}
+	}
+
+	query := entities.HarvestQuery{From: state.From, Before: state.Before, Limit: p.config.PageSize + 1}
+	if state.ID != uuid.Nil {
+		query.After = &entities.HarvestCursor{Datestamp: state.Datestamp, ID: state.ID}
+	}
+	records, err := p.harvest.Harvest(ctx, query)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return newError(ErrNoRecordsMatch, "no records match the request")
+	}
+
+	var token *ResumptionToken
+	if len(records) > p.config.PageSize {
+		records = records[:p.config.PageSize]
+		next := state.after(records[len(records)-1], len(records))
+		token = &ResumptionToken{Cursor: state.Cursor, Value: next.encode()}
+	} else if resumed {
+		// An empty token marks the last page of a resumed list
+		token = &ResumptionToken{Cursor: state.Cursor}
+	}
+
+	repositoryID := p.repositoryIdentifier(baseURL)
+	if !withMetadata {
+		headers := make([]Header, 0, len(records))
+		for _, record := range records {
+			headers = append(headers, headerOf(record, repositoryID))
+		}
+		response.ListIdentifiers = &ListIdentifiers{Headers: headers, ResumptionToken: token}
+		return nil
+	}
+	list := make([]Record, 0, len(records))
+	for _, record := range records {
+		list = append(list, recordOf(record, repositoryID, state.Prefix))
+	}
+	response.ListRecords = &ListRecords{Records: list, ResumptionToken: token}
+	return nil
+}
+
+// repositoryIdentifier is the configured repository identifier, or the host
+// name of the base URL
+func (p *Provider) repositoryIdentifier(baseURL string) string {
+	if p.config.RepositoryIdentifier != "" {
+		return p.config.RepositoryIdentifier
+	}
+	if parsed, err := url.Parse(baseURL); err == nil && parsed.Hostname() != "" {
+		return parsed.Hostname()
+	}
+	return "localhost"
+}
+
+// identifier is the OAI identifier of a book, such as
+// "oai:library.example.com:3f0c…"
+func identifier(repositoryID string, id uuid.UUID) string {
+	return "oai:" + repositoryID + ":" + id.String()
+}
+
+func parseIdentifier(raw, repositoryID string) (uuid.UUID, bool) {
+	rest, ok := strings.CutPrefix(raw, "oai:"+repositoryID+":")
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(rest)
+	return id, err == nil
+}
+
+func headerOf(record *entities.HarvestRecord, repositoryID string) Header {
+	header := Header{Identifier: identifier(repositoryID, record.ID), Datestamp: formatDatestamp(record.Datestamp)}
+	if record.Deleted {
+		header.Status = "deleted"
+	}
+	return header
+}
+
+// recordOf is the record in the metadata format; deleted records have a
+// header only
+func recordOf(record *entities.HarvestRecord, repositoryID, prefix string) Record {
+	result := Record{Header: headerOf(record, repositoryID)}
+	if !record.Deleted && record.Book != nil {
+		result.Metadata = metadataOf(record.Book, prefix)
+	}
+	return result
+}
+
+// Error codes of OAI-PMH
+const (
+	ErrBadArgument             = "badArgument"
+	ErrBadResumptionToken      = "badResumptionToken"
+	ErrBadVerb                 = "badVerb"
+	ErrCannotDisseminateFormat = "cannotDisseminateFormat"
+	ErrIDDoesNotExist          = "idDoesNotExist"
+	ErrNoRecordsMatch          = "noRecordsMatch"
+	ErrNoSetHierarchy          = "noSetHierarchy"
+)
+
+// Error is an OAI-PMH error, reported in the response
+type Error struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+func newError(code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
@@ -0,0 +1,259 @@
+package oai
+
+import (
+	"context"
+	"encoding/xml"
+	"net/url"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseURL = "https://library.test/oai"
+
+var (
+	now     = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	bookID  = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	goneID  = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	book    = newBook()
+	records = []*entities.HarvestRecord{
+		{ID: bookID, Datestamp: book.UpdatedAt, Book: book},
+		{ID: goneID, Datestamp: now.Add(-time.Hour), Deleted: true},
+	}
+)
+
+func newBook() *entities.Book {
+	return &entities.Book{
+		ID:     bookID,
+		Title:  "Clean Code",
+		Author: "Robert C. Martin",
+		Year:   2008,
+		ISBN:   "9780132350884",
+		BookMetadata: entities.BookMetadata{
+			Publisher:   "Prentice Hall",
+			PageCount:   464,
+			Subjects:    pq.StringArray{"Software"},
+			Description: "A handbook of agile software craftsmanship",
+		},
+		CreatedAt: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		UpdatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+// fakeHarvest serves records, in harvest order, and remembers the queries
+// it was asked
+type fakeHarvest struct {
+	records []*entities.HarvestRecord
+	queries []entities.HarvestQuery
+}
+
+func (f *fakeHarvest) Harvest(ctx context.Context, query entities.HarvestQuery) ([]*entities.HarvestRecord, error) {
+	f.queries = append(f.queries, query)
+	var result []*entities.HarvestRecord
+	for _, record := range f.records {
+		if len(result) == query.Limit {
+			break
+		}
+		if query.After != nil && !record.Datestamp.After(query.After.Datestamp) {
+			continue
+		}
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+func (f *fakeHarvest) GetRecord(ctx context.Context, id uuid.UUID) (*entities.HarvestRecord, error) {
+	for _, record := range f.records {
+		if record.ID == id {
+			return record, nil
+		}
+	}
+	return nil, entities.ErrBookNotFound
+}
+
+func (f *fakeHarvest) EarliestDatestamp(ctx context.Context) (time.Time, error) {
+	if len(f.records) == 0 {
+		return time.Time{}, nil
+	}
+	return f.records[0].Datestamp, nil
+}
+
+func newProvider(config Config, records ...*entities.HarvestRecord) (*Provider, *fakeHarvest) {
+	harvest := &fakeHarvest{records: records}
+	provider := NewProvider(harvest, config)
+	provider.now = func() time.Time { return now }
+	return provider, harvest
+}
+
+// handle answers the query and returns the response as XML
+func handle(t *testing.T, provider *Provider, query string) (*Response, string) {
+	t.Helper()
+	args, err := url.ParseQuery(query)
+	require.NoError(t, err)
+	response, err := provider.Handle(context.Background(), baseURL, args)
+	require.NoError(t, err)
+	data, err := xml.Marshal(response)
+	require.NoError(t, err)
+	return response, string(data)
+}
+
+func TestIdentify(t *testing.T) {
+	provider, _ := newProvider(Config{AdminEmails: []string{"admin@library.test"}}, records...)
+
+	_, body := handle(t, provider, "verb=Identify")
+
+	assert.Contains(t, body, `<responseDate>2026-03-01T12:00:00Z</responseDate><request verb="Identify">https://library.test/oai</request>`)
+	assert.Contains(t, body, `<repositoryName>Library</repositoryName>`)
+	assert.Contains(t, body, `<adminEmail>admin@library.test</adminEmail>`)
+	assert.Contains(t, body, `<earliestDatestamp>2025-01-02T03:04:05Z</earliestDatestamp>`)
+	assert.Contains(t, body, `<deletedRecord>persistent</deletedRecord>`)
+	assert.Contains(t, body, `<repositoryIdentifier>library.test</repositoryIdentifier>`)
+	assert.Contains(t, body, `<sampleIdentifier>oai:library.test:00000000-0000-0000-0000-000000000000</sampleIdentifier>`)
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		code    string
+		request Request
+	}{
+		{"missing verb", "", ErrBadVerb, Request{URL: baseURL}},
+		{"illegal verb", "verb=ListAll", ErrBadVerb, Request{URL: baseURL}},
+		{"repeated verb", "verb=Identify&verb=Identify", ErrBadVerb, Request{URL: baseURL}},
+		{"illegal argument", "verb=Identify&metadataPrefix=oai_dc", ErrBadArgument, Request{URL: baseURL}},
+		{"repeated argument", "verb=ListRecords&metadataPrefix=oai_dc&metadataPrefix=marcxml", ErrBadArgument, Request{URL: baseURL}},
+		{"missing argument", "verb=GetRecord&metadataPrefix=oai_dc", ErrBadArgument, Request{URL: baseURL}},
+		{"resumption token with other arguments", "verb=ListRecords&metadataPrefix=oai_dc&resumptionToken=abc", ErrBadArgument, Request{URL: baseURL}},
+		{"invalid date", "verb=ListRecords&metadataPrefix=oai_dc&from=yesterday", ErrBadArgument, Request{URL: baseURL}},
+		{"mixed granularities", "verb=ListRecords&metadataPrefix=oai_dc&from=2025-01-01&until=2025-01-02T00:00:00Z", ErrBadArgument, Request{URL: baseURL}},
+		{"from after until", "verb=ListRecords&metadataPrefix=oai_dc&from=2025-01-02&until=2025-01-01", ErrBadArgument, Request{URL: baseURL}},
+		{
+			"unknown format", "verb=ListRecords&metadataPrefix=mods", ErrCannotDisseminateFormat,
+			Request{URL: baseURL, Verb: "ListRecords", MetadataPrefix: "mods"},
+		},
+		{
+			"sets", "verb=ListIdentifiers&metadataPrefix=oai_dc&set=fiction", ErrNoSetHierarchy,
+			Request{URL: baseURL, Verb: "ListIdentifiers", MetadataPrefix: "oai_dc", Set: "fiction"},
+		},
+		{"no sets", "verb=ListSets", ErrNoSetHierarchy, Request{URL: baseURL, Verb: "ListSets"}},
+		{
+			"foreign identifier", "verb=GetRecord&metadataPrefix=oai_dc&identifier=oai:elsewhere.test:" + bookID.String(), ErrIDDoesNotExist,
+			Request{URL: baseURL, Verb: "GetRecord", MetadataPrefix: "oai_dc", Identifier: "oai:elsewhere.test:" + bookID.String()},
+		},
+		{
+			"unknown identifier", "verb=ListMetadataFormats&identifier=oai:library.test:" + uuid.Nil.String(), ErrIDDoesNotExist,
+			Request{URL: baseURL, Verb: "ListMetadataFormats", Identifier: "oai:library.test:" + uuid.Nil.String()},
+		},
+		{
+			"invalid resumption token", "verb=ListRecords&resumptionToken=abc", ErrBadResumptionToken,
+			Request{URL: baseURL, Verb: "ListRecords", ResumptionToken: "abc"},
+		},
+		{
+			"no records", "verb=ListRecords&metadataPrefix=oai_dc&from=2030-01-01", ErrNoRecordsMatch,
+			Request{URL: baseURL, Verb: "ListRecords", MetadataPrefix: "oai_dc", From: "2030-01-01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, _ := newProvider(Config{})
+			if tt.code != ErrNoRecordsMatch {
+				provider.harvest = &fakeHarvest{records: records}
+			}
+
+			response, _ := handle(t, provider, tt.query)
+
+			require.Len(t, response.Errors, 1)
+			assert.Equal(t, tt.code, response.Errors[0].Code)
+			assert.Equal(t, tt.request, response.Request)
+		})
+	}
+}
+
+func TestGetRecord(t *testing.T) {
+	provider, _ := newProvider(Config{}, records...)
+
+	t.Run("Dublin Core", func(t *testing.T) {
+		_, body := handle(t, provider, "verb=GetRecord&metadataPrefix=oai_dc&identifier=oai:library.test:"+bookID.String())
+
+		assert.Contains(t, body, `<header><identifier>oai:library.test:`+bookID.String()+`</identifier><datestamp>2025-01-02T03:04:05Z</datestamp></header>`)
+		assert.Contains(t, body, `<oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/"`)
+		assert.Contains(t, body, `<dc:title>Clean Code</dc:title><dc:creator>Robert C. Martin</dc:creator><dc:subject>Software</dc:subject>`)
+		assert.Contains(t, body, `<dc:date>2008</dc:date><dc:type>Text</dc:type><dc:identifier>urn:isbn:9780132350884</dc:identifier>`)
+	})
+
+	t.Run("deleted record", func(t *testing.T) {
+		_, body := handle(t, provider, "verb=GetRecord&metadataPrefix=marcxml&identifier=oai:library.test:"+goneID.String())
+
+		assert.Contains(t, body, `<header status="deleted"><identifier>oai:library.test:`+goneID.String()+`</identifier><datestamp>2026-03-01T11:00:00Z</datestamp></header></record>`)
+		assert.NotContains(t, body, `<metadata>`)
+	})
+}
+
+func TestMARCOf(t *testing.T) {
+	record := MARCOf(newBook())
+
+	assert.Equal(t, "00000nam a2200000   4500", record.Leader)
+	assert.Len(t, record.Leader, 24)
+	require.Len(t, record.ControlFields, 3)
+	assert.Equal(t, ControlField{Tag: "005", Value: "20250102030405.0"}, record.ControlFields[1])
+	assert.Equal(t, "240506s2008    xx |||||||||||||||||und d", record.ControlFields[2].Value)
+	assert.Len(t, record.ControlFields[2].Value, 40)
+
+	tags := make([]string, 0, len(record.DataFields))
+	for _, field := range record.DataFields {
+		tags = append(tags, field.Tag)
+	}
+	assert.Equal(t, []string{"020", "100", "245", "264", "300", "520", "650"}, tags)
+	assert.Equal(t, DataField{Tag: "245", Ind1: "1", Ind2: "0", Subfields: []Subfield{{Code: "a", Value: "Clean Code"}, {Code: "c", Value: "Robert C. Martin"}}}, record.DataFields[2])
+	assert.Equal(t, DataField{Tag: "264", Ind1: " ", Ind2: "1", Subfields: []Subfield{{Code: "b", Value: "Prentice Hall"}, {Code: "c", Value: "2008"}}}, record.DataFields[3])
+
+	data, err := xml.Marshal(record)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `<marc:datafield tag="650" ind1=" " ind2="4"><marc:subfield code="a">Software</marc:subfield></marc:datafield>`)
+}
+
+func TestListRecords_ResumptionTokens(t *testing.T) {
+	var many []*entities.HarvestRecord
+	for i := 0; i < 5; i++ {
+		many = append(many, &entities.HarvestRecord{ID: uuid.New(), Datestamp: now.Add(time.Duration(i) * time.Minute), Deleted: true})
+	}
+	provider, harvest := newProvider(Config{PageSize: 2}, many...)
+
+	response, _ := handle(t, provider, "verb=ListIdentifiers&metadataPrefix=marcxml&from=2026-03-01&until=2026-03-01")
+	require.NotNil(t, response.ListIdentifiers)
+	assert.Len(t, response.ListIdentifiers.Headers, 2)
+	first := response.ListIdentifiers.ResumptionToken
+	require.NotNil(t, first)
+	assert.Equal(t, 0, first.Cursor)
+	assert.Equal(t, entities.HarvestQuery{From: now.Truncate(24 * time.Hour), Before: now.Truncate(24 * time.Hour).Add(24 * time.Hour), Limit: 3}, harvest.queries[0])
+
+	response, _ = handle(t, provider, "verb=ListIdentifiers&resumptionToken="+first.Value)
+	second := response.ListIdentifiers.ResumptionToken
+	require.NotNil(t, second)
+	assert.Equal(t, 2, second.Cursor)
+	assert.Equal(t, many[1].Cursor(), harvest.queries[1].After)
+	assert.Equal(t, harvest.queries[0].Before, harvest.queries[1].Before)
+
+	response, _ = handle(t, provider, "verb=ListIdentifiers&resumptionToken="+second.Value)
+	assert.Len(t, response.ListIdentifiers.Headers, 1)
+	assert.Equal(t, &ResumptionToken{Cursor: 4}, response.ListIdentifiers.ResumptionToken)
+	assert.Equal(t, "deleted", response.ListIdentifiers.Headers[0].Status)
+}
+
+func TestListRecords_CompleteList(t *testing.T) {
+	provider, _ := newProvider(Config{}, records...)
+
+	response, body := handle(t, provider, "verb=ListRecords&metadataPrefix=marcxml&until=2026-03-01T11:00:00Z")
+
+	require.NotNil(t, response.ListRecords)
+	assert.Len(t, response.ListRecords.Records, 2)
+	assert.Nil(t, response.ListRecords.ResumptionToken)
+	assert.Contains(t, body, `<metadata><marc:record xmlns:marc="http://www.loc.gov/MARC21/slim"`)
+}
@@ -0,0 +1,131 @@
+package oai
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Namespaces of the OAI-PMH responses
+const (
+	nsOAI           = "http://www.openarchives.org/OAI/2.0/"
+	nsOAIIdentifier = "http://www.openarchives.org/OAI/2.0/oai-identifier"
+	nsXSI           = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// ContentType is the media type of the responses
+const ContentType = "text/xml; charset=utf-8"
+
+// Response is an OAI-PMH response: the answer to one verb, or errors
+type Response struct {
+	XMLName        xml.Name `xml:"OAI-PMH"`
+	Xmlns          string   `xml:"xmlns,attr"`
+	XmlnsXSI       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+
+	ResponseDate        string               `xml:"responseDate"`
+	Request             Request              `xml:"request"`
+	Errors              []Error              `xml:"error"`
+	Identify            *Identify            `xml:"Identify,omitempty"`
+	ListMetadataFormats *ListMetadataFormats `xml:"ListMetadataFormats,omitempty"`
+	GetRecord           *GetRecord           `xml:"GetRecord,omitempty"`
+	ListIdentifiers     *ListIdentifiers     `xml:"ListIdentifiers,omitempty"`
+	ListRecords         *ListRecords         `xml:"ListRecords,omitempty"`
+}
+
+func newResponse(now time.Time, baseURL string) *Response {
+	return &Response{
+		Xmlns:          nsOAI,
+		XmlnsXSI:       nsXSI,
+		SchemaLocation: nsOAI + " http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd",
+		ResponseDate:   formatDatestamp(now),
+		Request:        Request{URL: baseURL},
+	}
+}
+
+// Request echoes the base URL and the arguments of the request
+type Request struct {
+	Verb            string `xml:"verb,attr,omitempty"`
+	Identifier      string `xml:"identifier,attr,omitempty"`
+	MetadataPrefix  string `xml:"metadataPrefix,attr,omitempty"`
+	From            string `xml:"from,attr,omitempty"`
+	Until           string `xml:"until,attr,omitempty"`
+	Set             string `xml:"set,attr,omitempty"`
+	ResumptionToken string `xml:"resumptionToken,attr,omitempty"`
+	URL             string `xml:",chardata"`
+}
+
+// Identify describes the repository
+type Identify struct {
+	RepositoryName    string       `xml:"repositoryName"`
+	BaseURL           string       `xml:"baseURL"`
+	ProtocolVersion   string       `xml:"protocolVersion"`
+	AdminEmails       []string     `xml:"adminEmail"`
+	EarliestDatestamp string       `xml:"earliestDatestamp"`
+	DeletedRecord     string       `xml:"deletedRecord"`
+	Granularity       string       `xml:"granularity"`
+	Description       *Description `xml:"description,omitempty"`
+}
+
+type Description struct {
+	OAIIdentifier OAIIdentifier `xml:"oai-identifier"`
+}
+
+// OAIIdentifier describes the repository's identifiers in the oai scheme
+type OAIIdentifier struct {
+	Xmlns                string `xml:"xmlns,attr"`
+	XmlnsXSI             string `xml:"xmlns:xsi,attr"`
+	SchemaLocation       string `xml:"xsi:schemaLocation,attr"`
+	Scheme               string `xml:"scheme"`
+	RepositoryIdentifier string `xml:"repositoryIdentifier"`
+	Delimiter            string `xml:"delimiter"`
+	SampleIdentifier     string `xml:"sampleIdentifier"`
+}
+
+type ListMetadataFormats struct {
+	Formats []MetadataFormat `xml:"metadataFormat"`
+}
+
+type MetadataFormat struct {
+	Prefix    string `xml:"metadataPrefix"`
+	Schema    string `xml:"schema"`
+	Namespace string `xml:"metadataNamespace"`
+}
+
+type GetRecord struct {
+	Record Record `xml:"record"`
+}
+
+type ListIdentifiers struct {
+	Headers         []Header         `xml:"header"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
+
+type ListRecords struct {
+	Records         []Record         `xml:"record"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
+
+// Record is a header and, unless the record is deleted, its metadata
+type Record struct {
+	Header   Header    `xml:"header"`
+	Metadata *Metadata `xml:"metadata,omitempty"`
+}
+
+type Header struct {
+	Status     string `xml:"status,attr,omitempty"`
+	Identifier string `xml:"identifier"`
+	Datestamp  string `xml:"datestamp"`
+}
+
+// Metadata holds a record in one of the metadata formats
+type Metadata struct {
+	DublinCore *DublinCore
+	MARC       *MARCRecord
+}
+
+// ResumptionToken continues an incomplete list. Cursor counts the records
+// sent before this page; the last page of a list has an empty token.
+type ResumptionToken struct {
+	Cursor int    `xml:"cursor,attr"`
+	Value  string `xml:",chardata"`
+}
@@ -0,0 +1,107 @@
+package oai
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+const (
+	dayGranularity     = "2006-01-02"
+	secondsGranularity = "2006-01-02T15:04:05Z"
+)
+
+// formatDatestamp formats a datestamp at the repository's granularity
+func formatDatestamp(t time.Time) string {
+	return t.UTC().Format(secondsGranularity)
+}
+
+// parseDatestamp parses a from or until argument at either granularity,
+// returning the length of the period it names
+func parseDatestamp(value string) (time.Time, time.Duration, bool) {
+	if t, err := time.Parse(secondsGranularity, value); err == nil {
+		return t, time.Second, true
+	}
+	if t, err := time.Parse(dayGranularity, value); err == nil {
+		return t, 24 * time.Hour, true
+	}
+	return time.Time{}, 0, false
+}
+
+// resumption is the state of a list carried by its resumption tokens: the
+// request's format and range, the last record sent and the number of
+// records sent
+type resumption struct {
+	Prefix string    `json:"p"`
+	From   time.Time `json:"f"`
+	// Before is the exclusive end of the range, just after until
+	Before    time.Time `json:"b"`
+	Datestamp time.Time `json:"d"`
+	ID        uuid.UUID `json:"i"`
+	Cursor    int       `json:"c"`
+}
+
+// newResumption starts a list from the request's arguments
+func newResumption(args url.Values) (resumption, error) {
+	state := resumption{Prefix: args.Get("metadataPrefix")}
+	var fromStep, untilStep time.Duration
+	if raw := args.Get("from"); raw != "" {
+		from, step, ok := parseDatestamp(raw)
+		if !ok {
+			return state, newError(ErrBadArgument, "from must be a date or a UTC date and time")
+		}
+		state.From, fromStep = from, step
+	}
+	if raw := args.Get("until"); raw != "" {
+		until, step, ok := parseDatestamp(raw)
+		if !ok {
+			return state, newError(ErrBadArgument, "until must be a date or a UTC date and time")
+		}
+		// until includes the whole day or second it names
+		state.Before, untilStep = until.Add(step), step
+	}
+	if fromStep != 0 && untilStep != 0 {
+		if fromStep != untilStep {
+			return state, newError(ErrBadArgument, "from and until must have the same granularity")
+		}
+		if !state.From.Before(state.Before) {
+			return state, newError(ErrBadArgument, "from must not be later than until")
+		}
+	}
+	if args.Get("set") != "" {
+		return state, newError(ErrNoSetHierarchy, "the repository does not support sets")
+	}
+	if !supportsFormat(state.Prefix) {
+		return state, newError(ErrCannotDisseminateFormat, "the %q metadata format is not supported", state.Prefix)
+	}
+	return state, nil
+}
+
+// after is the state of the list once count more records, up to last, are
+// sent
+func (r resumption) after(last *entities.HarvestRecord, count int) resumption {
+	r.Datestamp, r.ID = last.Datestamp, last.ID
+	r.Cursor += count
+	return r
+}
+
+func (r resumption) encode() string {
+	data, _ := json.Marshal(r)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeResumption(token string) (resumption, error) {
+	var state resumption
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil || state.ID == uuid.Nil || state.Cursor <= 0 || !supportsFormat(state.Prefix) {
+		return resumption{}, newError(ErrBadResumptionToken, "the resumption token is invalid")
+	}
+	return state, nil
+}
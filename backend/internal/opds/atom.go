@@ -0,0 +1,280 @@
+package opds
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+)
+
+// Namespaces of the OPDS 1.2 Atom feeds
+const (
+	nsAtom       = "http://www.w3.org/2005/Atom"
+	nsDC         = "http://purl.org/dc/terms/"
+	nsOPDS       = "http://opds-spec.org/2010/catalog"
+	nsOpenSearch = "http://a9.com/-/spec/opensearch/1.1/"
+	nsThread     = "http://purl.org/syndication/thread/1.0"
+)
+
+// Feed is an OPDS 1.2 Atom feed
+type Feed struct {
+	XMLName         xml.Name `xml:"feed"`
+	Xmlns           string   `xml:"xmlns,attr"`
+	XmlnsDC         string   `xml:"xmlns:dc,attr"`
+	XmlnsOPDS       string   `xml:"xmlns:opds,attr"`
+	XmlnsOpenSearch string   `xml:"xmlns:opensearch,attr"`
+	XmlnsThread     string   `xml:"xmlns:thr,attr"`
+
+	ID      string  `xml:"id"`
+	Title   string  `xml:"title"`
+	Updated string  `xml:"updated"`
+	Author  *Person `xml:"author,omitempty"`
+	// TotalResults, ItemsPerPage and StartIndex describe the page of an
+	// acquisition feed
+	TotalResults *int    `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage *int    `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex   *int    `xml:"opensearch:startIndex,omitempty"`
+	Links        []Link  `xml:"link"`
+	Entries      []Entry `xml:"entry"`
+}
+
+// Person is the author of a feed or entry
+type Person struct {
+	Name string `xml:"name"`
+}
+
+// Link is an Atom link with the OPDS facet and thread count attributes
+type Link struct {
+	Rel         string `xml:"rel,attr,omitempty"`
+	Href        string `xml:"href,attr"`
+	Type        string `xml:"type,attr,omitempty"`
+	Title       string `xml:"title,attr,omitempty"`
+	Length      int64  `xml:"length,attr,omitempty"`
+	FacetGroup  string `xml:"opds:facetGroup,attr,omitempty"`
+	ActiveFacet bool   `xml:"opds:activeFacet,attr,omitempty"`
+	Count       int    `xml:"thr:count,attr,omitempty"`
+}
+
+// Entry is a book in an acquisition feed or a subsection in a navigation
+// feed
+type Entry struct {
+	Title       string     `xml:"title"`
+	ID          string     `xml:"id"`
+	Updated     string     `xml:"updated"`
+	Authors     []Person   `xml:"author"`
+	Identifiers []string   `xml:"dc:identifier"`
+	Issued      string     `xml:"dc:issued,omitempty"`
+	Publisher   string     `xml:"dc:publisher,omitempty"`
+	Categories  []Category `xml:"category"`
+	Summary     *Text      `xml:"summary,omitempty"`
+	Content     *Text      `xml:"content,omitempty"`
+	Links       []Link     `xml:"link"`
+}
+
+// Category is a subject of a book
+type Category struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+// Text is plain text content
+type Text struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func (f *Feeds) feed(id, title string, updated time.Time) *Feed {
+	return &Feed{
+		Xmlns:           nsAtom,
+		XmlnsDC:         nsDC,
+		XmlnsOPDS:       nsOPDS,
+		XmlnsOpenSearch: nsOpenSearch,
+		XmlnsThread:     nsThread,
+		ID:              id,
+		Title:           title,
+		Updated:         updated.Format(time.RFC3339),
+		Author:          &Person{Name: f.Title},
+	}
+}
+
+// commonLinks link a feed to itself, the start of the catalogue and search
+func (f *Feeds) commonLinks(self, selfType string) []Link {
+	return []Link{
+		{Rel: "self", Href: self, Type: selfType},
+		{Rel: "start", Href: f.Root, Type: AtomNavigation, Title: f.Title},
+		{Rel: "search", Href: f.Root + "/opensearch.xml", Type: OpenSearchType},
+	}
+}
+
+// Navigation is the root feed, leading to all books and to the books by
+// author and by subject
+func (f *Feeds) Navigation() *Feed {
+	feed := f.feed(f.Root, f.Title, f.Updated)
+	feed.Links = f.commonLinks(f.Root, AtomNavigation)
+	sections := []struct{ path, rel, title, summary, kind string }{
+		{"/books", "http://opds-spec.org/sort/new", "All books", "Every book in the catalogue, newest first", AtomAcquisition},
+		{"/authors", "subsection", "By author", "Browse the catalogue by author", AtomNavigation},
+		{"/subjects", "subsection", "By subject", "Browse the catalogue by subject or genre", AtomNavigation},
+	}
+	for _, section := range sections {
+		feed.Entries = append(feed.Entries, Entry{
+			Title:   section.title,
+			ID:      f.Root + section.path,
+			Updated: feed.Updated,
+			Content: &Text{Type: "text", Body: section.summary},
+			Links:   []Link{{Rel: section.rel, Href: f.Root + section.path, Type: section.kind}},
+		})
+	}
+	return feed
+}
+
+// Browse is a navigation feed of the values of a facet, each leading to
+// the books that have it
+func (f *Feeds) Browse(facet entities.Facet, values []entities.FacetValue) *Feed {
+	path, title := "/authors", "Authors"
+	if facet == entities.FacetSubject {
+		path, title = "/subjects", "Subjects"
+	}
+	self := f.Root + path
+	feed := f.feed(self, title, f.Updated)
+	feed.Links = append(f.commonLinks(self, AtomNavigation), Link{Rel: "up", Href: f.Root, Type: AtomNavigation})
+	for _, value := range values {
+		books := href(f.Root, "/books", (&Listing{}).params(1, facet, value.Value))
+		feed.Entries = append(feed.Entries, Entry{
+			Title:   value.Value,
+			ID:      books,
+			Updated: feed.Updated,
+			Content: &Text{Type: "text", Body: countLabel(value.Count)},
+			Links:   []Link{{Rel: "subsection", Href: books, Type: AtomAcquisition, Count: value.Count}},
+		})
+	}
+	return feed
+}
+
+// Acquisition is a page of books with links to their files, other pages and
+// facets
+func (f *Feeds) Acquisition(l *Listing) *Feed {
+	self := href(f.Root, "/books", l.params(l.Page, "", ""))
+	feed := f.feed(self, f.title(l), f.updated(l))
+	total, perPage, start := l.Total, f.PageSize, l.Query.Offset+1
+	feed.TotalResults, feed.ItemsPerPage, feed.StartIndex = &total, &perPage, &start
+
+	feed.Links = append(f.commonLinks(self, AtomAcquisition), Link{Rel: "up", Href: f.Root, Type: AtomNavigation})
+	for _, page := range f.pageLinks(l) {
+		feed.Links = append(feed.Links, Link{Rel: page.rel, Href: href(f.Root, "/books", l.params(page.number, "", "")), Type: AtomAcquisition})
+	}
+	for _, group := range f.facetGroups(l) {
+		for _, facet := range group.values {
+			feed.Links = append(feed.Links, Link{
+				Rel:         relFacet,
+				Href:        href(f.Root, "/books", l.params(1, group.facet, facet.Value)),
+				Type:        AtomAcquisition,
+				Title:       facet.Value,
+				FacetGroup:  group.title,
+				ActiveFacet: facet.active,
+				Count:       facet.Count,
+			})
+		}
+	}
+
+	for _, book := range l.Books {
+		feed.Entries = append(feed.Entries, f.entry(book, l.Files[book.ID]))
+	}
+	return feed
+}
+
+func (f *Feeds) entry(book *entities.Book, files []*entities.BookFile) Entry {
+	entry := Entry{
+		Title:       book.Title,
+		ID:          "urn:uuid:" + book.ID.String(),
+		Updated:     book.UpdatedAt.UTC().Format(time.RFC3339),
+		Identifiers: []string{bookIdentifier(book)},
+		Publisher:   book.Publisher,
+	}
+	if book.Author != "" {
+		entry.Authors = []Person{{Name: book.Author}}
+	}
+	if book.Year > 0 {
+		entry.Issued = strconv.Itoa(book.Year)
+	}
+	for _, subject := range book.Subjects {
+		entry.Categories = append(entry.Categories, Category{Term: subject, Label: subject})
+	}
+	if book.Description != "" {
+		entry.Summary = &Text{Type: "text", Body: book.Description}
+	}
+
+	entry.Links = append(entry.Links, Link{Rel: "alternate", Href: f.API + "/books/" + book.ID.String(), Type: "application/json"})
+	if image, thumbnail := f.cover(book); image != "" {
+		entry.Links = append(entry.Links,
+			Link{Rel: relImage, Href: image},
+			Link{Rel: relThumbnail, Href: thumbnail})
+	}
+	for _, file := range files {
+		entry.Links = append(entry.Links, Link{
+			Rel:    relAcquisition,
+			Href:   f.fileHref(file),
+			Type:   file.ContentType,
+			Title:  file.Filename,
+			Length: file.Size,
+		})
+	}
+	return entry
+}
+
+type pageLink struct {
+	rel    string
+	number int
+}
+
+// pageLinks are the first, previous, next and last pages around the
+// listing's page
+func (f *Feeds) pageLinks(l *Listing) []pageLink {
+	last := f.lastPage(l)
+	links := []pageLink{{"first", 1}}
+	if l.Page > 1 {
+		links = append(links, pageLink{"previous", min(l.Page-1, last)})
+	}
+	if l.Page < last {
+		links = append(links, pageLink{"next", l.Page + 1})
+	}
+	return append(links, pageLink{"last", last})
+}
+
+type facetGroup struct {
+	facet  entities.Facet
+	title  string
+	values []facetValue
+}
+
+type facetValue struct {
+	entities.FacetValue
+	active bool
+}
+
+// facetGroups are the authors and subjects offered as facets, marking the
+// listing's own
+func (f *Feeds) facetGroups(l *Listing) []facetGroup {
+	groups := []facetGroup{
+		{facet: entities.FacetAuthor, title: "Author"},
+		{facet: entities.FacetSubject, title: "Subject"},
+	}
+	for i, source := range [][]entities.FacetValue{l.Authors, l.Subjects} {
+		active := l.Query.Author
+		if groups[i].facet == entities.FacetSubject {
+			active = l.Query.Subject
+		}
+		for _, value := range source {
+			groups[i].values = append(groups[i].values, facetValue{FacetValue: value, active: value.Value == active})
+		}
+	}
+	return groups
+}
+
+func countLabel(count int) string {
+	if count == 1 {
+		return "1 book"
+	}
+	return strconv.Itoa(count) + " books"
+}
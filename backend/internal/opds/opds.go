@@ -0,0 +1,216 @@
+// Package opds renders the catalogue as OPDS feeds for e-reader apps: Atom
+// feeds for OPDS 1.2, JSON feeds for OPDS 2.0 and the OpenSearch description
+// both point their search at. A navigation feed leads to acquisition feeds of
+// books, which page through the catalogue, offer facets by author and
+// subject, and link to the books' ebook files and covers.
+package opds
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+// Media types of the feeds and of the documents they link to
+const (
+	AtomNavigation  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	AtomAcquisition = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	JSONFeed        = "application/opds+json"
+	OpenSearchType  = "application/opensearchdescription+xml"
+)
+
+// Link relations defined by OPDS
+const (
+	relAcquisition = "http://opds-spec.org/acquisition"
+	relImage       = "http://opds-spec.org/image"
+	relThumbnail   = "http://opds-spec.org/image/thumbnail"
+	relFacet       = "http://opds-spec.org/facet"
+)
+
+const (
+	DefaultTitle      = "Library"
+	DefaultPageSize   = 50
+	MaxPageSize       = 200
+	DefaultFacetLimit = 10
+	// BrowseLimit bounds the authors and subjects listed by the browse feeds
+	BrowseLimit = 500
+)
+
+// Config tunes the feeds. Zero values use the defaults.
+type Config struct {
+	// Title names the catalogue in e-reader apps
+	Title string
+	// PageSize is the number of books per page of an acquisition feed
+	PageSize int
+	// FacetLimit is the number of authors and subjects offered as facets
+	FacetLimit int
+	// Covers links the books' uploaded covers, which are served only when
+	// cover uploads are enabled; cover URLs are linked regardless
+	Covers bool
+	// Thumbnail names the cover size linked as the books' thumbnail; the
+	// whole cover is linked when empty
+	Thumbnail string
+}
+
+func (c Config) withDefaults() Config {
+	if c.Title == "" {
+		c.Title = DefaultTitle
+	}
+	if c.PageSize <= 0 {
+		c.PageSize = DefaultPageSize
+	}
+	if c.PageSize > MaxPageSize {
+		c.PageSize = MaxPageSize
+	}
+	if c.FacetLimit <= 0 {
+		c.FacetLimit = DefaultFacetLimit
+	}
+	return c
+}
+
+// Feeds renders the feeds of a catalogue whose OPDS routes are under Root
+// and whose API, which serves files and covers, is under API
+type Feeds struct {
+	Config
+	Root string
+	API  string
+	// Updated dates the navigation feeds and empty acquisition feeds
+	Updated time.Time
+}
+
+// New returns the feeds of the catalogue served at base, such as
+// "https://library.example.com"
+func New(config Config, base string, updated time.Time) *Feeds {
+	return &Feeds{
+		Config:  config.withDefaults(),
+		Root:    base + "/opds",
+		API:     base + "/api/v1",
+		Updated: updated.UTC(),
+	}
+}
+
+// Query returns the query for a page of an acquisition feed, counting pages
+// from 1
+func (f *Feeds) Query(search, author, subject string, page int) entities.BookQuery {
+	if page < 1 {
+		page = 1
+	}
+	return entities.BookQuery{
+		Search:  search,
+		Author:  author,
+		Subject: subject,
+		Offset:  (page - 1) * f.PageSize,
+		Limit:   f.PageSize,
+	}
+}
+
+// Listing is a page of an acquisition feed
+type Listing struct {
+	Query entities.BookQuery
+	Page  int
+	Books []*entities.Book
+	Total int
+	// Files holds the ebook files of the books by book ID
+	Files map[uuid.UUID][]*entities.BookFile
+	// Authors and Subjects are offered as facets
+	Authors  []entities.FacetValue
+	Subjects []entities.FacetValue
+}
+
+// lastPage is the number of the listing's last page, at least 1
+func (f *Feeds) lastPage(l *Listing) int {
+	if l.Total <= 0 {
+		return 1
+	}
+	return (l.Total + f.PageSize - 1) / f.PageSize
+}
+
+// params returns the query string of a page of the listing; facet replaces
+// the listing's author or subject when set
+func (l *Listing) params(page int, facet entities.Facet, value string) url.Values {
+	params := url.Values{}
+	author, subject := l.Query.Author, l.Query.Subject
+	switch facet {
+	case entities.FacetAuthor:
+		author = value
+	case entities.FacetSubject:
+		subject = value
+	}
+	if l.Query.Search != "" {
+		params.Set("q", l.Query.Search)
+	}
+	if author != "" {
+		params.Set("author", author)
+	}
+	if subject != "" {
+		params.Set("subject", subject)
+	}
+	if page > 1 {
+		params.Set("page", strconv.Itoa(page))
+	}
+	return params
+}
+
+// title names the listing after its filters
+func (f *Feeds) title(l *Listing) string {
+	switch {
+	case l.Query.Search != "":
+		return "Search results for “" + l.Query.Search + "”"
+	case l.Query.Author != "":
+		return "Books by " + l.Query.Author
+	case l.Query.Subject != "":
+		return "Books about " + l.Query.Subject
+	}
+	return "All books"
+}
+
+// updated dates the listing by its most recently updated book
+func (f *Feeds) updated(l *Listing) time.Time {
+	var updated time.Time
+	for _, book := range l.Books {
+		if book.UpdatedAt.After(updated) {
+			updated = book.UpdatedAt
+		}
+	}
+	if updated.IsZero() {
+		return f.Updated
+	}
+	return updated.UTC()
+}
+
+// href joins path and params onto base
+func href(base, path string, params url.Values) string {
+	if encoded := params.Encode(); encoded != "" {
+		return base + path + "?" + encoded
+	}
+	return base + path
+}
+
+// bookIdentifier is the URN of a book, by ISBN when it has one
+func bookIdentifier(book *entities.Book) string {
+	if book.ISBN != "" {
+		return "urn:isbn:" + book.ISBN
+	}
+	return "urn:uuid:" + book.ID.String()
+}
+
+// cover returns the URLs of the book's cover and thumbnail, preferring an
+// uploaded cover to a linked one
+func (f *Feeds) cover(book *entities.Book) (image, thumbnail string) {
+	if book.CoverETag == "" || !f.Covers {
+		return book.CoverURL, book.CoverURL
+	}
+	image = f.API + "/books/" + book.ID.String() + "/cover"
+	if f.Thumbnail == "" {
+		return image, image
+	}
+	return image, image + "?size=" + url.QueryEscape(f.Thumbnail)
+}
+
+// fileHref is the download URL of an ebook file
+func (f *Feeds) fileHref(file *entities.BookFile) string {
+	return f.API + "/books/" + file.BookID.String() + "/files/" + file.ID.String()
+}
@@ -0,0 +1,219 @@
+package opds
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+)
+
+// JSONCatalog is an OPDS 2.0 feed: navigation, publications or both
+type JSONCatalog struct {
+	Metadata     JSONMetadata      `json:"metadata"`
+	Links        []JSONLink        `json:"links"`
+	Navigation   []JSONLink        `json:"navigation,omitempty"`
+	Facets       []JSONFacetGroup  `json:"facets,omitempty"`
+	Publications []JSONPublication `json:"publications,omitempty"`
+}
+
+// JSONMetadata describes an OPDS 2.0 feed and the page it holds
+type JSONMetadata struct {
+	Title         string `json:"title"`
+	Modified      string `json:"modified,omitempty"`
+	NumberOfItems *int   `json:"numberOfItems,omitempty"`
+	ItemsPerPage  int    `json:"itemsPerPage,omitempty"`
+	CurrentPage   int    `json:"currentPage,omitempty"`
+}
+
+// JSONLink is a Readium web publication link
+type JSONLink struct {
+	Href       string          `json:"href"`
+	Type       string          `json:"type,omitempty"`
+	Rel        string          `json:"rel,omitempty"`
+	Title      string          `json:"title,omitempty"`
+	Templated  bool            `json:"templated,omitempty"`
+	Length     int64           `json:"length,omitempty"`
+	Properties *JSONProperties `json:"properties,omitempty"`
+}
+
+// JSONProperties qualify a link
+type JSONProperties struct {
+	NumberOfItems int `json:"numberOfItems,omitempty"`
+}
+
+// JSONFacetGroup is a group of facet links
+type JSONFacetGroup struct {
+	Metadata JSONMetadata `json:"metadata"`
+	Links    []JSONLink   `json:"links"`
+}
+
+// JSONPublication is a book with links to its files and covers
+type JSONPublication struct {
+	Metadata JSONBook   `json:"metadata"`
+	Links    []JSONLink `json:"links"`
+	Images   []JSONLink `json:"images,omitempty"`
+}
+
+// JSONBook is the metadata of a publication
+type JSONBook struct {
+	Type          string        `json:"@type"`
+	Title         string        `json:"title"`
+	Identifier    string        `json:"identifier"`
+	Author        []JSONSubject `json:"author,omitempty"`
+	Publisher     string        `json:"publisher,omitempty"`
+	Published     string        `json:"published,omitempty"`
+	Modified      string        `json:"modified"`
+	Description   string        `json:"description,omitempty"`
+	Subject       []JSONSubject `json:"subject,omitempty"`
+	NumberOfPages int           `json:"numberOfPages,omitempty"`
+}
+
+// JSONSubject names a contributor or subject, linking to its books
+type JSONSubject struct {
+	Name  string     `json:"name"`
+	Links []JSONLink `json:"links,omitempty"`
+}
+
+// jsonRoot is the root of the OPDS 2.0 feeds
+func (f *Feeds) jsonRoot() string {
+	return f.Root + "/v2"
+}
+
+// jsonLinks link a feed to itself, the start of the catalogue and search
+func (f *Feeds) jsonLinks(self string) []JSONLink {
+	root := f.jsonRoot()
+	return []JSONLink{
+		{Rel: "self", Href: self, Type: JSONFeed},
+		{Rel: "start", Href: root, Type: JSONFeed, Title: f.Title},
+		{Rel: "search", Href: root + "/books{?q}", Type: JSONFeed, Templated: true},
+	}
+}
+
+// JSONNavigation is the OPDS 2.0 root feed
+func (f *Feeds) JSONNavigation() *JSONCatalog {
+	root := f.jsonRoot()
+	return &JSONCatalog{
+		Metadata: JSONMetadata{Title: f.Title, Modified: f.Updated.Format(time.RFC3339)},
+		Links:    f.jsonLinks(root),
+		Navigation: []JSONLink{
+			{Href: root + "/books", Type: JSONFeed, Rel: "http://opds-spec.org/sort/new", Title: "All books"},
+			{Href: root + "/authors", Type: JSONFeed, Rel: "subsection", Title: "By author"},
+			{Href: root + "/subjects", Type: JSONFeed, Rel: "subsection", Title: "By subject"},
+		},
+	}
+}
+
+// JSONBrowse is an OPDS 2.0 navigation feed of the values of a facet
+func (f *Feeds) JSONBrowse(facet entities.Facet, values []entities.FacetValue) *JSONCatalog {
+	path, title := "/authors", "Authors"
+	if facet == entities.FacetSubject {
+		path, title = "/subjects", "Subjects"
+	}
+	root := f.jsonRoot()
+	catalog := &JSONCatalog{
+		Metadata: JSONMetadata{Title: title, Modified: f.Updated.Format(time.RFC3339)},
+		Links:    append(f.jsonLinks(root+path), JSONLink{Rel: "up", Href: root, Type: JSONFeed}),
+	}
+	for _, value := range values {
+		catalog.Navigation = append(catalog.Navigation, JSONLink{
+			Href:       href(root, "/books", (&Listing{}).params(1, facet, value.Value)),
+			Type:       JSONFeed,
+			Title:      value.Value,
+			Properties: &JSONProperties{NumberOfItems: value.Count},
+		})
+	}
+	return catalog
+}
+
+// JSONPublications is an OPDS 2.0 page of books with facets
+func (f *Feeds) JSONPublications(l *Listing) *JSONCatalog {
+	root := f.jsonRoot()
+	total := l.Total
+	catalog := &JSONCatalog{
+		Metadata: JSONMetadata{
+			Title:         f.title(l),
+			Modified:      f.updated(l).Format(time.RFC3339),
+			NumberOfItems: &total,
+			ItemsPerPage:  f.PageSize,
+			CurrentPage:   l.Page,
+		},
+		Links: append(f.jsonLinks(href(root, "/books", l.params(l.Page, "", ""))), JSONLink{Rel: "up", Href: root, Type: JSONFeed}),
+	}
+	for _, page := range f.pageLinks(l) {
+		catalog.Links = append(catalog.Links, JSONLink{Rel: page.rel, Href: href(root, "/books", l.params(page.number, "", "")), Type: JSONFeed})
+	}
+	for _, group := range f.facetGroups(l) {
+		if len(group.values) == 0 {
+			continue
+		}
+		jsonGroup := JSONFacetGroup{Metadata: JSONMetadata{Title: group.title}}
+		for _, facet := range group.values {
+			link := JSONLink{
+				Href:       href(root, "/books", l.params(1, group.facet, facet.Value)),
+				Type:       JSONFeed,
+				Title:      facet.Value,
+				Properties: &JSONProperties{NumberOfItems: facet.Count},
+			}
+			if facet.active {
+				link.Rel = "self"
+			}
+			jsonGroup.Links = append(jsonGroup.Links, link)
+		}
+		catalog.Facets = append(catalog.Facets, jsonGroup)
+	}
+
+	for _, book := range l.Books {
+		catalog.Publications = append(catalog.Publications, f.publication(book, l.Files[book.ID]))
+	}
+	return catalog
+}
+
+func (f *Feeds) publication(book *entities.Book, files []*entities.BookFile) JSONPublication {
+	root := f.jsonRoot()
+	metadata := JSONBook{
+		Type:          "http://schema.org/Book",
+		Title:         book.Title,
+		Identifier:    bookIdentifier(book),
+		Publisher:     book.Publisher,
+		Modified:      book.UpdatedAt.UTC().Format(time.RFC3339),
+		Description:   book.Description,
+		NumberOfPages: book.PageCount,
+	}
+	if book.Author != "" {
+		metadata.Author = []JSONSubject{{
+			Name:  book.Author,
+			Links: []JSONLink{{Href: href(root, "/books", url.Values{"author": {book.Author}}), Type: JSONFeed}},
+		}}
+	}
+	if book.Year > 0 {
+		metadata.Published = strconv.Itoa(book.Year)
+	}
+	for _, subject := range book.Subjects {
+		metadata.Subject = append(metadata.Subject, JSONSubject{
+			Name:  subject,
+			Links: []JSONLink{{Href: href(root, "/books", url.Values{"subject": {subject}}), Type: JSONFeed}},
+		})
+	}
+
+	publication := JSONPublication{
+		Metadata: metadata,
+		Links:    []JSONLink{{Rel: "alternate", Href: f.API + "/books/" + book.ID.String(), Type: "application/json"}},
+	}
+	for _, file := range files {
+		publication.Links = append(publication.Links, JSONLink{
+			Rel:    relAcquisition,
+			Href:   f.fileHref(file),
+			Type:   file.ContentType,
+			Title:  file.Filename,
+			Length: file.Size,
+		})
+	}
+	if image, thumbnail := f.cover(book); image != "" {
+		publication.Images = []JSONLink{{Href: image}}
+		if thumbnail != image {
+			publication.Images = append(publication.Images, JSONLink{Href: thumbnail, Type: "image/jpeg"})
+		}
+	}
+	return publication
+}
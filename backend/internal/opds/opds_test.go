@@ -0,0 +1,203 @@
+package opds
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updated = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newFeeds() *Feeds {
+	return New(Config{Title: "Test Library", PageSize: 2, Covers: true, Thumbnail: "small"}, "https://library.test", updated)
+}
+
+// newListing is the second of three pages of five books by an author, with
+// a file for its first book
+func newListing(feeds *Feeds) *Listing {
+	book := &entities.Book{
+		ID:     uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+		Title:  "Clean Code",
+		Author: "Robert C. Martin",
+		Year:   2008,
+		ISBN:   "9780132350884",
+		BookMetadata: entities.BookMetadata{
+			Publisher:   "Prentice Hall",
+			Subjects:    pq.StringArray{"Software"},
+			Description: "A handbook of agile software craftsmanship",
+		},
+		CoverETag: "abc",
+		UpdatedAt: updated.Add(time.Hour),
+	}
+	other := &entities.Book{ID: uuid.MustParse("22222222-2222-2222-2222-222222222222"), Title: "The Clean Coder", Author: "Robert C. Martin", UpdatedAt: updated}
+	file := &entities.BookFile{ID: uuid.MustParse("33333333-3333-3333-3333-333333333333"), BookID: book.ID, ContentType: "application/epub+zip", Filename: "clean-code.epub", Size: 1024}
+	return &Listing{
+		Query:    feeds.Query("", "Robert C. Martin", "", 2),
+		Page:     2,
+		Books:    []*entities.Book{book, other},
+		Total:    5,
+		Files:    map[uuid.UUID][]*entities.BookFile{book.ID: {file}},
+		Authors:  []entities.FacetValue{{Value: "Robert C. Martin", Count: 5}, {Value: "Kent Beck", Count: 2}},
+		Subjects: []entities.FacetValue{{Value: "Software", Count: 4}},
+	}
+}
+
+func findLink(links []Link, rel string) *Link {
+	for i := range links {
+		if links[i].Rel == rel {
+			return &links[i]
+		}
+	}
+	return nil
+}
+
+func TestFeeds_Query(t *testing.T) {
+	feeds := New(Config{PageSize: 1000}, "", updated)
+
+	assert.Equal(t, MaxPageSize, feeds.PageSize)
+	assert.Equal(t, DefaultTitle, feeds.Title)
+	assert.Equal(t, entities.BookQuery{Search: "go", Offset: 2 * MaxPageSize, Limit: MaxPageSize}, feeds.Query("go", "", "", 3))
+	assert.Equal(t, 0, feeds.Query("", "", "", 0).Offset)
+}
+
+func TestFeeds_Navigation(t *testing.T) {
+	feed := newFeeds().Navigation()
+
+	data, err := xml.Marshal(feed)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/terms/"`)
+	assert.Equal(t, "2026-03-01T12:00:00Z", feed.Updated)
+	assert.Equal(t, "https://library.test/opds/opensearch.xml", findLink(feed.Links, "search").Href)
+	require.Len(t, feed.Entries, 3)
+	assert.Equal(t, Link{Rel: "http://opds-spec.org/sort/new", Href: "https://library.test/opds/books", Type: AtomAcquisition}, feed.Entries[0].Links[0])
+	assert.Equal(t, "https://library.test/opds/subjects", feed.Entries[2].Links[0].Href)
+}
+
+func TestFeeds_Browse(t *testing.T) {
+	feed := newFeeds().Browse(entities.FacetSubject, []entities.FacetValue{{Value: "Science Fiction", Count: 3}})
+
+	assert.Equal(t, "https://library.test/opds/subjects", feed.ID)
+	require.Len(t, feed.Entries, 1)
+	assert.Equal(t, "3 books", feed.Entries[0].Content.Body)
+	assert.Equal(t, Link{Rel: "subsection", Href: "https://library.test/opds/books?subject=Science+Fiction", Type: AtomAcquisition, Count: 3}, feed.Entries[0].Links[0])
+}
+
+func TestFeeds_Acquisition(t *testing.T) {
+	feeds := newFeeds()
+	feed := feeds.Acquisition(newListing(feeds))
+
+	t.Run("describes the page", func(t *testing.T) {
+		assert.Equal(t, "Books by Robert C. Martin", feed.Title)
+		assert.Equal(t, "2026-03-01T13:00:00Z", feed.Updated)
+		assert.Equal(t, 5, *feed.TotalResults)
+		assert.Equal(t, 2, *feed.ItemsPerPage)
+		assert.Equal(t, 3, *feed.StartIndex)
+		assert.Equal(t, "https://library.test/opds/books?author=Robert+C.+Martin&page=2", findLink(feed.Links, "self").Href)
+	})
+
+	t.Run("links the other pages", func(t *testing.T) {
+		assert.Equal(t, "https://library.test/opds/books?author=Robert+C.+Martin", findLink(feed.Links, "first").Href)
+		assert.Equal(t, "https://library.test/opds/books?author=Robert+C.+Martin", findLink(feed.Links, "previous").Href)
+		assert.Equal(t, "https://library.test/opds/books?author=Robert+C.+Martin&page=3", findLink(feed.Links, "next").Href)
+		assert.Equal(t, "https://library.test/opds/books?author=Robert+C.+Martin&page=3", findLink(feed.Links, "last").Href)
+	})
+
+	t.Run("offers facets, marking the active one", func(t *testing.T) {
+		var facets []Link
+		for _, link := range feed.Links {
+			if link.Rel == relFacet {
+				facets = append(facets, link)
+			}
+		}
+		require.Len(t, facets, 3)
+		assert.Equal(t, Link{
+			Rel: relFacet, Href: "https://library.test/opds/books?author=Robert+C.+Martin", Type: AtomAcquisition,
+			Title: "Robert C. Martin", FacetGroup: "Author", ActiveFacet: true, Count: 5,
+		}, facets[0])
+		assert.False(t, facets[1].ActiveFacet)
+		assert.Equal(t, "https://library.test/opds/books?author=Robert+C.+Martin&subject=Software", facets[2].Href)
+		assert.Equal(t, "Subject", facets[2].FacetGroup)
+	})
+
+	t.Run("describes the books and links their files and covers", func(t *testing.T) {
+		require.Len(t, feed.Entries, 2)
+		entry := feed.Entries[0]
+		assert.Equal(t, "urn:uuid:11111111-1111-1111-1111-111111111111", entry.ID)
+		assert.Equal(t, []string{"urn:isbn:9780132350884"}, entry.Identifiers)
+		assert.Equal(t, "2008", entry.Issued)
+		assert.Equal(t, []Category{{Term: "Software", Label: "Software"}}, entry.Categories)
+		assert.Equal(t, &Link{
+			Rel: relAcquisition, Href: "https://library.test/api/v1/books/11111111-1111-1111-1111-111111111111/files/33333333-3333-3333-3333-333333333333",
+			Type: "application/epub+zip", Title: "clean-code.epub", Length: 1024,
+		}, findLink(entry.Links, relAcquisition))
+		assert.Equal(t, "https://library.test/api/v1/books/11111111-1111-1111-1111-111111111111/cover", findLink(entry.Links, relImage).Href)
+		assert.Equal(t, "https://library.test/api/v1/books/11111111-1111-1111-1111-111111111111/cover?size=small", findLink(entry.Links, relThumbnail).Href)
+
+		// A book without files or cover has no acquisition or image links
+		assert.Nil(t, findLink(feed.Entries[1].Links, relAcquisition))
+		assert.Nil(t, findLink(feed.Entries[1].Links, relImage))
+	})
+
+	t.Run("marshals the prefixed elements", func(t *testing.T) {
+		data, err := xml.Marshal(feed)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `<opensearch:totalResults>5</opensearch:totalResults>`)
+		assert.Contains(t, string(data), `<dc:identifier>urn:isbn:9780132350884</dc:identifier>`)
+		assert.Contains(t, string(data), `opds:facetGroup="Author" opds:activeFacet="true" thr:count="5"`)
+	})
+}
+
+func TestFeeds_JSONPublications(t *testing.T) {
+	feeds := newFeeds()
+	catalog := feeds.JSONPublications(newListing(feeds))
+
+	data, err := json.Marshal(catalog)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"metadata":{"title":"Books by Robert C. Martin","modified":"2026-03-01T13:00:00Z","numberOfItems":5,"itemsPerPage":2,"currentPage":2}`)
+	assert.Contains(t, string(data), `{"href":"https://library.test/opds/v2/books{?q}","type":"application/opds+json","rel":"search","templated":true}`)
+	assert.Contains(t, string(data), `{"href":"https://library.test/opds/v2/books?author=Robert+C.+Martin\u0026page=3","type":"application/opds+json","rel":"next"}`)
+
+	require.Len(t, catalog.Facets, 2)
+	assert.Equal(t, "self", catalog.Facets[0].Links[0].Rel)
+	assert.Equal(t, 4, catalog.Facets[1].Links[0].Properties.NumberOfItems)
+
+	require.Len(t, catalog.Publications, 2)
+	publication := catalog.Publications[0]
+	assert.Equal(t, "urn:isbn:9780132350884", publication.Metadata.Identifier)
+	assert.Equal(t, "https://library.test/opds/v2/books?subject=Software", publication.Metadata.Subject[0].Links[0].Href)
+	assert.Equal(t, relAcquisition, publication.Links[1].Rel)
+	assert.Equal(t, int64(1024), publication.Links[1].Length)
+	assert.Equal(t, []JSONLink{
+		{Href: "https://library.test/api/v1/books/11111111-1111-1111-1111-111111111111/cover"},
+		{Href: "https://library.test/api/v1/books/11111111-1111-1111-1111-111111111111/cover?size=small", Type: "image/jpeg"},
+	}, publication.Images)
+	assert.Equal(t, "urn:uuid:22222222-2222-2222-2222-222222222222", catalog.Publications[1].Metadata.Identifier)
+}
+
+func TestFeeds_JSONBrowse(t *testing.T) {
+	catalog := newFeeds().JSONBrowse(entities.FacetAuthor, []entities.FacetValue{{Value: "Kent Beck", Count: 2}})
+
+	assert.Equal(t, "Authors", catalog.Metadata.Title)
+	assert.Equal(t, []JSONLink{{
+		Href: "https://library.test/opds/v2/books?author=Kent+Beck", Type: JSONFeed, Title: "Kent Beck",
+		Properties: &JSONProperties{NumberOfItems: 2},
+	}}, catalog.Navigation)
+}
+
+func TestFeeds_OpenSearch(t *testing.T) {
+	feeds := New(Config{Title: "The Municipal Library of Springfield"}, "https://library.test", updated)
+
+	description := feeds.OpenSearch()
+
+	assert.Equal(t, "The Municipal Li", description.ShortName)
+	data, err := xml.Marshal(description)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `<Url type="application/atom+xml;profile=opds-catalog;kind=acquisition" template="https://library.test/opds/books?q={searchTerms}&amp;page={startPage?}"></Url>`)
+}
@@ -0,0 +1,45 @@
+package opds
+
+import (
+	"encoding/xml"
+	"unicode/utf8"
+)
+
+// OpenSearchDescription tells e-reader apps how to search the catalogue
+type OpenSearchDescription struct {
+	XMLName        xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns          string          `xml:"xmlns,attr"`
+	ShortName      string          `xml:"ShortName"`
+	Description    string          `xml:"Description"`
+	InputEncoding  string          `xml:"InputEncoding"`
+	OutputEncoding string          `xml:"OutputEncoding"`
+	URLs           []OpenSearchURL `xml:"Url"`
+}
+
+// OpenSearchURL is a search URL template
+type OpenSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// maxShortName bounds an OpenSearch ShortName, in characters
+const maxShortName = 16
+
+// OpenSearch describes the search of the acquisition feeds
+func (f *Feeds) OpenSearch() *OpenSearchDescription {
+	shortName := f.Title
+	if utf8.RuneCountInString(shortName) > maxShortName {
+		shortName = string([]rune(shortName)[:maxShortName])
+	}
+	return &OpenSearchDescription{
+		Xmlns:          nsOpenSearch,
+		ShortName:      shortName,
+		Description:    "Search " + f.Title + " by title or author",
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		URLs: []OpenSearchURL{
+			{Type: AtomAcquisition, Template: f.Root + "/books?q={searchTerms}&page={startPage?}"},
+			{Type: JSONFeed, Template: f.jsonRoot() + "/books?q={searchTerms}&page={startPage?}"},
+		},
+	}
+}
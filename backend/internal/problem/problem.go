@@ -0,0 +1,125 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/labstack/echo/v4"
+)
+
+// ContentType is the media type of problem details (RFC 7807)
+const ContentType = "application/problem+json"
+
+// Problem types. They identify a class of problem and are documented in the
+// README; "about:blank" is used for plain HTTP errors such as 404 routes.
+const (
+	TypeBlank       = "about:blank"
+	TypeValidation  = "/problems/validation-error"
+	TypeBadRequest  = "/problems/bad-request"
+	TypeNotFound    = "/problems/not-found"
+	TypeConflict    = "/problems/conflict"
+	TypeDuplicate   = "/problems/possible-duplicate"
+	TypeForbidden   = "/problems/forbidden"
+	TypeTimeout     = "/problems/timeout"
+	TypeUnavailable = "/problems/upstream-unavailable"
+	TypeInternal    = "/problems/internal-error"
+)
+
+// Problem is an RFC 7807 problem details object. Instance is the request
+// ID, so a report can be matched with the server's logs and traces.
+type Problem struct {
+	Type     string                `json:"type"`
+	Title    string                `json:"title"`
+	Status   int                   `json:"status"`
+	Detail   string                `json:"detail,omitempty"`
+	Instance string                `json:"instance,omitempty"`
+	Errors   []entities.FieldError `json:"errors,omitempty"`
+	// Duplicates lists the existing books a new one may duplicate
+	Duplicates []entities.DuplicateCandidate `json:"duplicates,omitempty"`
+}
+
+// New returns a plain HTTP problem for status
+func New(status int, detail string) *Problem {
+	return &Problem{Type: TypeBlank, Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// BadRequest is for requests that cannot be read at all, such as malformed
+// JSON or path parameters
+func BadRequest(detail string) *Problem {
+	return &Problem{Type: TypeBadRequest, Title: "Bad request", Status: http.StatusBadRequest, Detail: detail}
+}
+
+var kinds = map[entities.ErrorKind]Problem{
+	entities.KindInvalid:     {Type: TypeBadRequest, Title: "Bad request", Status: http.StatusBadRequest},
+	entities.KindNotFound:    {Type: TypeNotFound, Title: "Resource not found", Status: http.StatusNotFound},
+	entities.KindConflict:    {Type: TypeConflict, Title: "Conflict with the current state", Status: http.StatusConflict},
+	entities.KindForbidden:   {Type: TypeForbidden, Title: "Forbidden", Status: http.StatusForbidden},
+	entities.KindTimeout:     {Type: TypeTimeout, Title: "Request timed out", Status: http.StatusServiceUnavailable},
+	entities.KindUnavailable: {Type: TypeUnavailable, Title: "Upstream service unavailable", Status: http.StatusBadGateway},
+}
+
+// FromError describes err by its domain kind. Validation errors list their
+// fields; internal errors get a generic detail so that nothing about the
+// server's internals reaches the client.
+func FromError(err error) *Problem {
+	var validation *entities.ValidationError
+	if errors.As(err, &validation) {
+		return &Problem{
+			Type:   TypeValidation,
+			Title:  "Validation failed",
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("%d field(s) are invalid", len(validation.Fields)),
+			Errors: validation.Fields,
+		}
+	}
+
+	var duplicate *entities.DuplicateError
+	if errors.As(err, &duplicate) {
+		return &Problem{
+			Type:       TypeDuplicate,
+			Title:      "Possible duplicate",
+			Status:     http.StatusConflict,
+			Detail:     duplicate.Error() + "; repeat the request with force=true to create it anyway",
+			Duplicates: duplicate.Candidates,
+		}
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.Code >= http.StatusInternalServerError {
+			return New(httpErr.Code, "")
+		}
+		return New(httpErr.Code, fmt.Sprint(httpErr.Message))
+	}
+
+	if p, ok := kinds[entities.KindOf(err)]; ok {
+		p.Detail = err.Error()
+		return &p
+	}
+	return &Problem{
+		Type:   TypeInternal,
+		Title:  "Internal server error",
+		Status: http.StatusInternalServerError,
+		Detail: "An unexpected error occurred. Please try again later",
+	}
+}
+
+// Write sends p, filling Instance with the request ID
+func Write(c echo.Context, p *Problem) error {
+	if p.Instance == "" {
+		p.Instance = c.Response().Header().Get(echo.HeaderXRequestID)
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return c.Blob(p.Status, ContentType, body)
+}
+
+// Respond writes err as a problem
+func Respond(c echo.Context, err error) error {
+	return Write(c, FromError(err))
+}
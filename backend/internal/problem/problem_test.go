@@ -0,0 +1,88 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		typ    string
+		detail string
+	}{
+		{"not found", entities.ErrBookNotFound, http.StatusNotFound, TypeNotFound, "book not found"},
+		{"wrapped conflict", fmt.Errorf("create: %w", entities.ErrTenantSlugTaken), http.StatusConflict, TypeConflict, "create: tenant slug already in use"},
+		{"forbidden", entities.ErrTenantSuspended, http.StatusForbidden, TypeForbidden, "tenant is suspended"},
+		{"timeout", entities.ErrRequestTimeout, http.StatusServiceUnavailable, TypeTimeout, "request deadline exceeded"},
+		{"unavailable upstream", entities.ErrMetadataUnavailable, http.StatusBadGateway, TypeUnavailable, "bibliographic sources are unavailable"},
+		{"internal domain error", entities.ErrDatabaseError, http.StatusInternalServerError, TypeInternal, "An unexpected error occurred. Please try again later"},
+		{"unknown error", errors.New("pq: connection refused"), http.StatusInternalServerError, TypeInternal, "An unexpected error occurred. Please try again later"},
+		{"echo error", echo.NewHTTPError(http.StatusTooManyRequests, "Rate limit exceeded"), http.StatusTooManyRequests, TypeBlank, "Rate limit exceeded"},
+		{"echo server error", echo.NewHTTPError(http.StatusInternalServerError, "pq: connection refused"), http.StatusInternalServerError, TypeBlank, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := FromError(tt.err)
+
+			assert.Equal(t, tt.status, p.Status)
+			assert.Equal(t, tt.typ, p.Type)
+			assert.Equal(t, tt.detail, p.Detail)
+			assert.NotEmpty(t, p.Title)
+		})
+	}
+
+	t.Run("validation errors list their fields", func(t *testing.T) {
+		dto := entities.CreateBookDTO{Year: 2015}
+
+		p := FromError(dto.Validate())
+
+		assert.Equal(t, TypeValidation, p.Type)
+		assert.Equal(t, http.StatusBadRequest, p.Status)
+		assert.Len(t, p.Errors, 2)
+	})
+
+	t.Run("possible duplicates list the candidates", func(t *testing.T) {
+		book := &entities.Book{Title: "Clean Code", Author: "Robert Martin", Year: 2008}
+		err := &entities.DuplicateError{Candidates: []entities.DuplicateCandidate{
+			{Book: book, Score: 0.92, Reason: entities.DuplicateReasonSimilar},
+		}}
+
+		p := FromError(err)
+
+		assert.Equal(t, TypeDuplicate, p.Type)
+		assert.Equal(t, http.StatusConflict, p.Status)
+		assert.Equal(t, err.Candidates, p.Duplicates)
+	})
+}
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/books/1", nil), rec)
+	c.Response().Header().Set(echo.HeaderXRequestID, "req-1")
+
+	assert.NoError(t, Respond(c, entities.ErrBookNotFound))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get(echo.HeaderContentType))
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, map[string]interface{}{
+		"type":     TypeNotFound,
+		"title":    "Resource not found",
+		"status":   float64(http.StatusNotFound),
+		"detail":   "book not found",
+		"instance": "req-1",
+	}, body)
+}
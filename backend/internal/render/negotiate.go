@@ -0,0 +1,82 @@
+package render
+
+import (
+	"strconv"
+	"strings"
+)
+
+// mediaRange is a media range of an Accept header with its quality
+type mediaRange struct {
+	typ, subtype string
+	quality      float64
+}
+
+// parseAccept reads the media ranges of an Accept header. Malformed
+// ranges are skipped and malformed qualities count as 1, as clients mean
+// them to be acceptable.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(fields[0])), "/")
+		if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			name, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(strings.ToLower(name)) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && q >= 0 && q <= 1 {
+				quality = q
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, quality: quality})
+	}
+	return ranges
+}
+
+// specificity ranks how closely a range matches a media type: 3 for the
+// type itself, 2 for type/*, 1 for */* and 0 when it does not match
+func (m mediaRange) specificity(mediaType string) int {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	switch {
+	case m.typ == typ && m.subtype == subtype:
+		return 3
+	case m.typ == typ && m.subtype == "*":
+		return 2
+	case m.typ == "*":
+		return 1
+	}
+	return 0
+}
+
+// Negotiate returns the index of the offered media type an Accept header
+// prefers (RFC 9110, section 12.5.1). Each offer takes the quality of the
+// most specific range matching it, and ties go to the earlier offer. A
+// header that is empty, or has no valid range, accepts anything, so the
+// first offer is chosen.
+func Negotiate(accept string, offers []string) (int, bool) {
+	if len(offers) == 0 {
+		return 0, false
+	}
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return 0, true
+	}
+
+	best, bestQuality := -1, 0.0
+	for i, offer := range offers {
+		specificity, quality := 0, 0.0
+		for _, r := range ranges {
+			if s := r.specificity(offer); s > specificity {
+				specificity, quality = s, r.quality
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = i, quality
+		}
+	}
+	return best, best >= 0
+}
@@ -0,0 +1,150 @@
+// Package render writes response bodies in the representation the client
+// asks for with the Accept header. A Registry holds encoders by media type
+// and by the Go type of the value they encode, so a handler writes any
+// value with Respond and the client may have it in every representation
+// registered for its type. Representations registered for any value, such
+// as JSON, take precedence, so the first of them is the default for
+// clients that accept anything.
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"byfood-library/internal/problem"
+	"github.com/labstack/echo/v4"
+)
+
+// Media types of the representations
+const (
+	MediaTypeJSON   = "application/json"
+	MediaTypeJSONLD = "application/ld+json"
+	MediaTypeCSV    = "text/csv"
+	MediaTypeXML    = "application/xml"
+	MediaTypeTurtle = "text/turtle"
+)
+
+// Encoder writes a value in a representation
+type Encoder func(w io.Writer, value interface{}) error
+
+type representation struct {
+	// mediaType is matched against Accept; contentType, which may add
+	// parameters such as the charset, is sent
+	mediaType   string
+	contentType string
+	// valueType is the type of the values encoded, nil for any value
+	valueType reflect.Type
+	encode    Encoder
+}
+
+// Registry holds the representations that responses can be written in
+type Registry struct {
+	representations []representation
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// RegisterAny adds a representation of every value, such as JSON. The
+// media type of contentType is what Accept is matched against.
+func (r *Registry) RegisterAny(contentType string, encode Encoder) {
+	r.representations = append(r.representations, representation{
+		mediaType:   mediaTypeOf(contentType),
+		contentType: contentType,
+		encode:      encode,
+	})
+}
+
+// Register adds a representation of the values of type T
+func Register[T any](r *Registry, contentType string, encode func(w io.Writer, value T) error) {
+	r.representations = append(r.representations, representation{
+		mediaType:   mediaTypeOf(contentType),
+		contentType: contentType,
+		valueType:   reflect.TypeOf((*T)(nil)).Elem(),
+		encode: func(w io.Writer, value interface{}) error {
+			return encode(w, value.(T))
+		},
+	})
+}
+
+// offers returns the representations of the value in order of precedence:
+// those of any value, then those of its type, in order of registration.
+// A representation of the type takes the place of one of any value with
+// its media type.
+func (r *Registry) offers(value interface{}) []representation {
+	valueType := reflect.TypeOf(value)
+	typed := map[string]representation{}
+	for _, rep := range r.representations {
+		if rep.valueType != nil && rep.valueType == valueType {
+			if _, ok := typed[rep.mediaType]; !ok {
+				typed[rep.mediaType] = rep
+			}
+		}
+	}
+
+	var offers []representation
+	seen := map[string]bool{}
+	add := func(rep representation) {
+		if !seen[rep.mediaType] {
+			offers = append(offers, rep)
+			seen[rep.mediaType] = true
+		}
+	}
+	for _, rep := range r.representations {
+		if rep.valueType == nil {
+			if replacement, ok := typed[rep.mediaType]; ok {
+				rep = replacement
+			}
+			add(rep)
+		}
+	}
+	for _, rep := range r.representations {
+		if rep.valueType != nil && rep.valueType == valueType {
+			add(rep)
+		}
+	}
+	return offers
+}
+
+// Respond writes the value with the status in the representation the
+// request prefers, or a 406 problem when it accepts none of them
+func (r *Registry) Respond(c echo.Context, status int, value interface{}) error {
+	offers := r.offers(value)
+	mediaTypes := make([]string, len(offers))
+	for i, offer := range offers {
+		mediaTypes[i] = offer.mediaType
+	}
+	// Caches must not serve one representation for another
+	if len(offers) > 1 {
+		c.Response().Header().Add(echo.HeaderVary, "Accept")
+	}
+
+	chosen, ok := Negotiate(c.Request().Header.Get("Accept"), mediaTypes)
+	if !ok {
+		return problem.Write(c, problem.New(http.StatusNotAcceptable,
+			"acceptable representations are "+strings.Join(mediaTypes, ", ")))
+	}
+	offer := offers[chosen]
+
+	// Encode first, so that a failure can still be reported
+	var body bytes.Buffer
+	if err := offer.encode(&body, value); err != nil {
+		return err
+	}
+	return c.Blob(status, offer.contentType, body.Bytes())
+}
+
+// JSON encodes a value as JSON, as echo does
+func JSON(w io.Writer, value interface{}) error {
+	return json.NewEncoder(w).Encode(value)
+}
+
+func mediaTypeOf(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
@@ -0,0 +1,141 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"byfood-library/internal/problem"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "application/ld+json", "text/csv"}
+
+	tests := []struct {
+		name   string
+		accept string
+		want   int
+		ok     bool
+	}{
+		{"no header takes the first offer", "", 0, true},
+		{"anything takes the first offer", "*/*", 0, true},
+		{"exact type", "text/csv", 2, true},
+		{"case insensitive", "Application/LD+JSON", 1, true},
+		{"highest quality wins", "application/json;q=0.5, text/csv;q=0.9", 2, true},
+		{"ties go to the earlier offer", "text/csv, application/ld+json", 1, true},
+		{"type wildcard", "text/*", 2, true},
+		{"specific range overrides wildcard", "application/*;q=0.8, application/json;q=0.1", 1, true},
+		{"zero quality excludes", "application/json;q=0, */*;q=0.1", 1, true},
+		{"malformed quality counts as one", "text/csv;q=high", 2, true},
+		{"nothing acceptable", "image/png", -1, false},
+		{"only malformed ranges", "nonsense", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Negotiate(tt.accept, offers)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+
+	t.Run("no offers", func(t *testing.T) {
+		_, ok := Negotiate("*/*", nil)
+		assert.False(t, ok)
+	})
+}
+
+type note struct {
+	Text string `json:"text"`
+}
+
+func newTestRegistry() *Registry {
+	registry := NewRegistry()
+	registry.RegisterAny(MediaTypeJSON, JSON)
+	Register(registry, "text/plain; charset=utf-8", func(w io.Writer, n *note) error {
+		_, err := io.WriteString(w, n.Text)
+		return err
+	})
+	return registry
+}
+
+func respond(t *testing.T, registry *Registry, accept string, value interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	require.NoError(t, registry.Respond(echo.New().NewContext(req, rec), http.StatusOK, value))
+	return rec
+}
+
+func TestRegistry_Respond(t *testing.T) {
+	registry := newTestRegistry()
+
+	t.Run("JSON by default", func(t *testing.T) {
+		rec := respond(t, registry, "", &note{Text: "hello"})
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, MediaTypeJSON, rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "Accept", rec.Header().Get(echo.HeaderVary))
+		assert.JSONEq(t, `{"text":"hello"}`, rec.Body.String())
+	})
+
+	t.Run("representation of the type", func(t *testing.T) {
+		rec := respond(t, registry, "text/plain", &note{Text: "hello"})
+
+		assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "hello", rec.Body.String())
+	})
+
+	t.Run("other types only have JSON", func(t *testing.T) {
+		rec := respond(t, registry, "", map[string]int{"n": 1})
+
+		assert.Equal(t, MediaTypeJSON, rec.Header().Get(echo.HeaderContentType))
+		assert.Empty(t, rec.Header().Get(echo.HeaderVary))
+
+		rec = respond(t, registry, "text/plain", map[string]int{"n": 1})
+		assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		rec := respond(t, registry, "image/png", &note{Text: "hello"})
+
+		assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+		assert.Equal(t, problem.ContentType, rec.Header().Get(echo.HeaderContentType))
+		var body problem.Problem
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "acceptable representations are application/json, text/plain", body.Detail)
+	})
+
+	t.Run("representation of the type replaces one of any value", func(t *testing.T) {
+		Register(registry, MediaTypeJSON, func(w io.Writer, n *note) error {
+			_, err := fmt.Fprintf(w, "%q\n", n.Text)
+			return err
+		})
+		rec := respond(t, registry, "", &note{Text: "hello"})
+
+		assert.Equal(t, "\"hello\"\n", rec.Body.String())
+	})
+}
+
+func TestRegistry_Respond_EncodeError(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterAny(MediaTypeJSON, JSON)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	err := registry.Respond(echo.New().NewContext(req, rec), http.StatusOK, make(chan int))
+
+	assert.Error(t, err)
+	assert.Empty(t, rec.Body.String())
+}
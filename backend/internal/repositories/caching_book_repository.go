@@ -0,0 +1,268 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"time"
+
+	"byfood-library/internal/cache"
+	"byfood-library/internal/cql"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tenancy"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// BookCacheConfig sets how long books stay cached. The list of all books
+// changes on every write, so it usually gets a shorter TTL than single books.
+type BookCacheConfig struct {
+	TTL     time.Duration
+	ListTTL time.Duration
+}
+
+// cachingBookRepository serves GetByID and GetAll from a read-through cache
+// keyed by tenant and drops affected entries on every successful write
+type cachingBookRepository struct {
+	next   repositories.BookRepository
+	cache  *cache.Cache
+	config BookCacheConfig
+	logger *zap.Logger
+}
+
+func NewCachingBookRepository(next repositories.BookRepository, c *cache.Cache, config BookCacheConfig, logger *zap.Logger) repositories.BookRepository {
+	if config.TTL <= 0 {
+		config.TTL = 5 * time.Minute
+	}
+	if config.ListTTL <= 0 {
+		config.ListTTL = config.TTL
+	}
+	return &cachingBookRepository{
+		next:   next,
+		cache:  c,
+		config: config,
+		logger: logger,
+	}
+}
+
+func bookKey(tenantID, id uuid.UUID) string {
+	return "books:" + tenantID.String() + ":" + id.String()
+}
+
+func bookListKey(tenantID uuid.UUID) string {
+	return "books:" + tenantID.String() + ":all"
+}
+
+func (r *cachingBookRepository) Create(ctx context.Context, book *entities.Book) (*entities.Book, error) {
+	created, err := r.next.Create(ctx, book)
+	if err == nil {
+		r.invalidate(ctx)
+	}
+	return created, err
+}
+
+func (r *cachingBookRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return r.next.GetByID(ctx, id)
+	}
+
+	value, err := r.cache.Fetch(ctx, bookKey(tenantID, id), r.config.TTL, func(ctx context.Context) ([]byte, error) {
+		book, err := r.next.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return encodeBooks(book)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var book entities.Book
+	if err := r.decode(ctx, value, &book); err != nil {
+		return r.next.GetByID(ctx, id)
+	}
+	return &book, nil
+}
+
+// GetByIDForUpdate is not cached: the caller is about to write the book
+// and needs its current state
+func (r *cachingBookRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	return r.next.GetByIDForUpdate(ctx, id)
+}
+
+// GetByIDs is not cached; batches come from GraphQL dataloaders, which
+// already collapse repeated reads within a request
+func (r *cachingBookRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	return r.next.GetByIDs(ctx, ids)
+}
+
+// GetByIDsForUpdate is not cached, for the reason GetByIDForUpdate is not
+func (r *cachingBookRepository) GetByIDsForUpdate(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	return r.next.GetByIDsForUpdate(ctx, ids)
+}
+
+func (r *cachingBookRepository) GetAll(ctx context.Context) ([]*entities.Book, error) {
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return r.next.GetAll(ctx)
+	}
+
+	value, err := r.cache.Fetch(ctx, bookListKey(tenantID), r.config.ListTTL, func(ctx context.Context) ([]byte, error) {
+		books, err := r.next.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return encodeBooks(books)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var books []*entities.Book
+	if err := r.decode(ctx, value, &books); err != nil {
+		return r.next.GetAll(ctx)
+	}
+	return books, nil
+}
+
+func (r *cachingBookRepository) Update(ctx context.Context, id uuid.UUID, book *entities.Book) (*entities.Book, error) {
+	updated, err := r.next.Update(ctx, id, book)
+	if err == nil {
+		r.invalidate(ctx, id)
+	}
+	return updated, err
+}
+
+func (r *cachingBookRepository) SetCover(ctx context.Context, id uuid.UUID, etag string) (*entities.Book, error) {
+	updated, err := r.next.SetCover(ctx, id, etag)
+	if err == nil {
+		r.invalidate(ctx, id)
+	}
+	return updated, err
+}
+
+func (r *cachingBookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	err := r.next.Delete(ctx, id)
+	if err == nil {
+		r.invalidate(ctx, id)
+	}
+	return err
+}
+
+// Count is not cached; it is only used for periodic metrics
+func (r *cachingBookRepository) Count(ctx context.Context) (int, error) {
+	return r.next.Count(ctx)
+}
+
+// FindSimilar is not cached; duplicates must be checked against the
+// current catalogue
+func (r *cachingBookRepository) FindSimilar(ctx context.Context, book *entities.Book, limit int) ([]*entities.Book, error) {
+	return r.next.FindSimilar(ctx, book, limit)
+}
+
+func (r *cachingBookRepository) FindSimilarPairs(ctx context.Context, limit int) ([][2]*entities.Book, error) {
+	return r.next.FindSimilarPairs(ctx, limit)
+}
+
+// Searches and FacetValues are not cached; feeds built from them are paged
+// and filtered too many ways for entries to be reused
+
+func (r *cachingBookRepository) Search(ctx context.Context, query entities.BookQuery) (*entities.BookPage, error) {
+	return r.next.Search(ctx, query)
+}
+
+func (r *cachingBookRepository) SearchCQL(ctx context.Context, query *cql.Query, offset, limit int) (*entities.BookPage, error) {
+	return r.next.SearchCQL(ctx, query, offset, limit)
+}
+
+func (r *cachingBookRepository) FacetValues(ctx context.Context, facet entities.Facet, limit int) ([]entities.FacetValue, error) {
+	return r.next.FacetValues(ctx, facet, limit)
+}
+
+// Harvests read past the cache, since harvesters must see every change and
+// deletion as soon as it is made
+
+func (r *cachingBookRepository) Harvest(ctx context.Context, query entities.HarvestQuery) ([]*entities.HarvestRecord, error) {
+	return r.next.Harvest(ctx, query)
+}
+
+func (r *cachingBookRepository) GetHarvestRecord(ctx context.Context, id uuid.UUID) (*entities.HarvestRecord, error) {
+	return r.next.GetHarvestRecord(ctx, id)
+}
+
+func (r *cachingBookRepository) EarliestDatestamp(ctx context.Context) (time.Time, error) {
+	return r.next.EarliestDatestamp(ctx)
+}
+
+// Files are not part of the cached books and are not cached themselves;
+// they are read far less often than books
+
+func (r *cachingBookRepository) AddFile(ctx context.Context, file *entities.BookFile) (*entities.BookFile, error) {
+	return r.next.AddFile(ctx, file)
+}
+
+func (r *cachingBookRepository) ListFiles(ctx context.Context, bookID uuid.UUID) ([]*entities.BookFile, error) {
+	return r.next.ListFiles(ctx, bookID)
+}
+
+func (r *cachingBookRepository) ListFilesOf(ctx context.Context, bookIDs []uuid.UUID) ([]*entities.BookFile, error) {
+	return r.next.ListFilesOf(ctx, bookIDs)
+}
+
+func (r *cachingBookRepository) GetFile(ctx context.Context, bookID, fileID uuid.UUID) (*entities.BookFile, error) {
+	return r.next.GetFile(ctx, bookID, fileID)
+}
+
+func (r *cachingBookRepository) FindFileByChecksum(ctx context.Context, checksum string) (*entities.BookFile, error) {
+	return r.next.FindFileByChecksum(ctx, checksum)
+}
+
+func (r *cachingBookRepository) DeleteFile(ctx context.Context, bookID, fileID uuid.UUID) error {
+	return r.next.DeleteFile(ctx, bookID, fileID)
+}
+
+func (r *cachingBookRepository) MoveFiles(ctx context.Context, from []uuid.UUID, to uuid.UUID) error {
+	return r.next.MoveFiles(ctx, from, to)
+}
+
+func (r *cachingBookRepository) SetFileStorageKey(ctx context.Context, fileID uuid.UUID, key string) error {
+	return r.next.SetFileStorageKey(ctx, fileID, key)
+}
+
+// invalidate drops the current tenant's book list and the given books once
+// the write has committed, so a failed broadcast only leaves other replicas
+// stale until their entries expire
+func (r *cachingBookRepository) invalidate(ctx context.Context, ids ...uuid.UUID) {
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return
+	}
+	keys := []string{bookListKey(tenantID)}
+	for _, id := range ids {
+		keys = append(keys, bookKey(tenantID, id))
+	}
+	afterCommit(ctx, func() {
+		r.cache.Invalidate(ctx, keys...)
+	})
+}
+
+// Books are stored with gob rather than JSON because the JSON form of a
+// book omits its tenant
+func encodeBooks(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, entities.ErrDatabaseError
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *cachingBookRepository) decode(ctx context.Context, value []byte, target interface{}) error {
+	err := gob.NewDecoder(bytes.NewReader(value)).Decode(target)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Warn("Discarding unreadable cached books", zap.Error(err))
+	}
+	return err
+}
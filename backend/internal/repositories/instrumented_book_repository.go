@@ -0,0 +1,229 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"byfood-library/internal/cql"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/middleware"
+	"github.com/google/uuid"
+)
+
+const (
+	booksTable     = "books"
+	bookFilesTable = "book_files"
+)
+
+// instrumentedBookRepository records Prometheus metrics around another
+// BookRepository and keeps books_total in step with creates and deletes
+type instrumentedBookRepository struct {
+	next repositories.BookRepository
+}
+
+func NewInstrumentedBookRepository(next repositories.BookRepository) repositories.BookRepository {
+	return &instrumentedBookRepository{next: next}
+}
+
+// observe records one operation; a missing book is a valid answer rather
+// than a failed query
+func observe(operation string, start time.Time, err error) {
+	success := err == nil || errors.Is(err, entities.ErrBookNotFound)
+	middleware.RecordDatabaseOperation(operation, booksTable, time.Since(start), success)
+}
+
+// observeFiles records one operation on the files of books
+func observeFiles(operation string, start time.Time, err error) {
+	success := err == nil || errors.Is(err, entities.ErrBookNotFound) || errors.Is(err, entities.ErrBookFileNotFound)
+	middleware.RecordDatabaseOperation(operation, bookFilesTable, time.Since(start), success)
+}
+
+func (r *instrumentedBookRepository) Create(ctx context.Context, book *entities.Book) (*entities.Book, error) {
+	start := time.Now()
+	created, err := r.next.Create(ctx, book)
+	observe("create", start, err)
+	if err == nil {
+		afterCommit(ctx, func() { middleware.AddBookCount(1) })
+	}
+	return created, err
+}
+
+func (r *instrumentedBookRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	start := time.Now()
+	book, err := r.next.GetByID(ctx, id)
+	observe("get_by_id", start, err)
+	return book, err
+}
+
+func (r *instrumentedBookRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	start := time.Now()
+	book, err := r.next.GetByIDForUpdate(ctx, id)
+	observe("get_by_id_for_update", start, err)
+	return book, err
+}
+
+func (r *instrumentedBookRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	start := time.Now()
+	books, err := r.next.GetByIDs(ctx, ids)
+	observe("get_by_ids", start, err)
+	return books, err
+}
+
+func (r *instrumentedBookRepository) GetByIDsForUpdate(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	start := time.Now()
+	books, err := r.next.GetByIDsForUpdate(ctx, ids)
+	observe("get_by_ids_for_update", start, err)
+	return books, err
+}
+
+func (r *instrumentedBookRepository) GetAll(ctx context.Context) ([]*entities.Book, error) {
+	start := time.Now()
+	books, err := r.next.GetAll(ctx)
+	observe("get_all", start, err)
+	return books, err
+}
+
+func (r *instrumentedBookRepository) Update(ctx context.Context, id uuid.UUID, book *entities.Book) (*entities.Book, error) {
+	start := time.Now()
+	updated, err := r.next.Update(ctx, id, book)
+	observe("update", start, err)
+	return updated, err
+}
+
+func (r *instrumentedBookRepository) SetCover(ctx context.Context, id uuid.UUID, etag string) (*entities.Book, error) {
+	start := time.Now()
+	updated, err := r.next.SetCover(ctx, id, etag)
+	observe("set_cover", start, err)
+	return updated, err
+}
+
+func (r *instrumentedBookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	observe("delete", start, err)
+	if err == nil {
+		afterCommit(ctx, func() { middleware.AddBookCount(-1) })
+	}
+	return err
+}
+
+func (r *instrumentedBookRepository) Count(ctx context.Context) (int, error) {
+	start := time.Now()
+	count, err := r.next.Count(ctx)
+	observe("count", start, err)
+	return count, err
+}
+
+func (r *instrumentedBookRepository) FindSimilar(ctx context.Context, book *entities.Book, limit int) ([]*entities.Book, error) {
+	start := time.Now()
+	books, err := r.next.FindSimilar(ctx, book, limit)
+	observe("find_similar", start, err)
+	return books, err
+}
+
+func (r *instrumentedBookRepository) FindSimilarPairs(ctx context.Context, limit int) ([][2]*entities.Book, error) {
+	start := time.Now()
+	pairs, err := r.next.FindSimilarPairs(ctx, limit)
+	observe("find_similar_pairs", start, err)
+	return pairs, err
+}
+
+func (r *instrumentedBookRepository) Search(ctx context.Context, query entities.BookQuery) (*entities.BookPage, error) {
+	start := time.Now()
+	page, err := r.next.Search(ctx, query)
+	observe("search", start, err)
+	return page, err
+}
+
+func (r *instrumentedBookRepository) SearchCQL(ctx context.Context, query *cql.Query, offset, limit int) (*entities.BookPage, error) {
+	start := time.Now()
+	page, err := r.next.SearchCQL(ctx, query, offset, limit)
+	observe("search_cql", start, err)
+	return page, err
+}
+
+func (r *instrumentedBookRepository) FacetValues(ctx context.Context, facet entities.Facet, limit int) ([]entities.FacetValue, error) {
+	start := time.Now()
+	values, err := r.next.FacetValues(ctx, facet, limit)
+	observe("facet_values", start, err)
+	return values, err
+}
+
+func (r *instrumentedBookRepository) Harvest(ctx context.Context, query entities.HarvestQuery) ([]*entities.HarvestRecord, error) {
+	start := time.Now()
+	records, err := r.next.Harvest(ctx, query)
+	observe("harvest", start, err)
+	return records, err
+}
+
+func (r *instrumentedBookRepository) GetHarvestRecord(ctx context.Context, id uuid.UUID) (*entities.HarvestRecord, error) {
+	start := time.Now()
+	record, err := r.next.GetHarvestRecord(ctx, id)
+	observe("get_harvest_record", start, err)
+	return record, err
+}
+
+func (r *instrumentedBookRepository) EarliestDatestamp(ctx context.Context) (time.Time, error) {
+	start := time.Now()
+	earliest, err := r.next.EarliestDatestamp(ctx)
+	observe("earliest_datestamp", start, err)
+	return earliest, err
+}
+
+func (r *instrumentedBookRepository) AddFile(ctx context.Context, file *entities.BookFile) (*entities.BookFile, error) {
+	start := time.Now()
+	created, err := r.next.AddFile(ctx, file)
+	observeFiles("create", start, err)
+	return created, err
+}
+
+func (r *instrumentedBookRepository) ListFiles(ctx context.Context, bookID uuid.UUID) ([]*entities.BookFile, error) {
+	start := time.Now()
+	files, err := r.next.ListFiles(ctx, bookID)
+	observeFiles("list", start, err)
+	return files, err
+}
+
+func (r *instrumentedBookRepository) ListFilesOf(ctx context.Context, bookIDs []uuid.UUID) ([]*entities.BookFile, error) {
+	start := time.Now()
+	files, err := r.next.ListFilesOf(ctx, bookIDs)
+	observeFiles("list", start, err)
+	return files, err
+}
+
+func (r *instrumentedBookRepository) GetFile(ctx context.Context, bookID, fileID uuid.UUID) (*entities.BookFile, error) {
+	start := time.Now()
+	file, err := r.next.GetFile(ctx, bookID, fileID)
+	observeFiles("get_by_id", start, err)
+	return file, err
+}
+
+func (r *instrumentedBookRepository) FindFileByChecksum(ctx context.Context, checksum string) (*entities.BookFile, error) {
+	start := time.Now()
+	file, err := r.next.FindFileByChecksum(ctx, checksum)
+	observeFiles("get_by_checksum", start, err)
+	return file, err
+}
+
+func (r *instrumentedBookRepository) DeleteFile(ctx context.Context, bookID, fileID uuid.UUID) error {
+	start := time.Now()
+	err := r.next.DeleteFile(ctx, bookID, fileID)
+	observeFiles("delete", start, err)
+	return err
+}
+
+func (r *instrumentedBookRepository) MoveFiles(ctx context.Context, from []uuid.UUID, to uuid.UUID) error {
+	start := time.Now()
+	err := r.next.MoveFiles(ctx, from, to)
+	observeFiles("move", start, err)
+	return err
+}
+
+func (r *instrumentedBookRepository) SetFileStorageKey(ctx context.Context, fileID uuid.UUID, key string) error {
+	start := time.Now()
+	err := r.next.SetFileStorageKey(ctx, fileID, key)
+	observeFiles("update", start, err)
+	return err
+}
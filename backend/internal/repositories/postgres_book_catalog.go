@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// maxSearchWords bounds the words of a search, each of which adds a
+// condition to the query
+const maxSearchWords = 8
+
+// likeEscaper escapes the LIKE wildcards in search words
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search matches words against lower(title), which the trigram index
+// covers, and lower(author). A cursor seeks past its book by
+// (created_at, id), so that paging on from it reads no more than the page.
+func (r *postgresBookRepository) Search(ctx context.Context, query entities.BookQuery) (*entities.BookPage, error) {
+	where := []string{"tenant_id = $1"}
+	args := []interface{}{nil}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	words := strings.Fields(strings.ToLower(query.Search))
+	if len(words) > maxSearchWords {
+		words = words[:maxSearchWords]
+	}
+	for _, word := range words {
+		pattern := arg("%" + likeEscaper.Replace(word) + "%")
+		where = append(where, fmt.Sprintf("(lower(title) LIKE %s OR lower(author) LIKE %s)", pattern, pattern))
+	}
+	if query.Author != "" {
+		where = append(where, "author = "+arg(query.Author))
+	}
+	if query.Subject != "" {
+		where = append(where, "subjects @> "+arg(pq.StringArray{query.Subject}))
+	}
+	conditions := strings.Join(where, " AND ")
+	counted := len(args)
+	if query.After != nil {
+		where = append(where, fmt.Sprintf("(created_at, id) < (%s, %s)", arg(query.After.CreatedAt.UTC()), arg(query.After.ID)))
+	}
+	return r.searchPage(ctx, conditions, strings.Join(where, " AND "), "created_at DESC, id DESC", args, counted, query.Offset, query.Limit)
+}
+
+// searchPage counts the books matching the conditions and reads a page of
+// those matching pageConditions in order, in one transaction, so that the
+// total agrees with the page. The conditions take the first counted
+// arguments, the first of which is left for the tenant ID.
+func (r *postgresBookRepository) searchPage(ctx context.Context, conditions, pageConditions, order string, args []interface{}, counted, offset, limit int) (*entities.BookPage, error) {
+	paged := len(args)
+	args = append(args, limit, offset)
+	countQuery := `SELECT COUNT(*) FROM books WHERE ` + conditions
+	pageQuery := `SELECT ` + bookColumns + ` FROM books WHERE ` + pageConditions +
+		fmt.Sprintf(` ORDER BY %s LIMIT $%d OFFSET $%d`, order, paged+1, paged+2)
+
+	var page entities.BookPage
+	var books []entities.Book
+	err := r.withTenant(ctx, pageQuery, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		args[0] = tenantID
+		if err := tx.GetContext(ctx, &page.Total, countQuery, args[:counted]...); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error counting books", zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		if err := tx.SelectContext(ctx, &books, pageQuery, args...); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error searching books", zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page.Books = make([]*entities.Book, len(books))
+	for i := range books {
+		page.Books[i] = &books[i]
+	}
+	return &page, nil
+}
+
+// facetQueries read the values of each facet, most common first
+var facetQueries = map[entities.Facet]string{
+	entities.FacetAuthor: `SELECT author AS value, COUNT(*) AS count FROM books
+              WHERE tenant_id = $1 AND author <> ''
+              GROUP BY author ORDER BY count DESC, value LIMIT $2`,
+	entities.FacetSubject: `SELECT subject AS value, COUNT(*) AS count FROM books, unnest(subjects) AS subject
+              WHERE tenant_id = $1
+              GROUP BY subject ORDER BY count DESC, value LIMIT $2`,
+}
+
+func (r *postgresBookRepository) FacetValues(ctx context.Context, facet entities.Facet, limit int) ([]entities.FacetValue, error) {
+	query, ok := facetQueries[facet]
+	if !ok {
+		return nil, fmt.Errorf("unknown facet %q", facet)
+	}
+
+	var values []entities.FacetValue
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		if err := tx.SelectContext(ctx, &values, query, tenantID, limit); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error reading facet values", zap.String("facet", string(facet)), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
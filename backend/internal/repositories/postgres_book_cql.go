@@ -0,0 +1,551 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"byfood-library/internal/cql"
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+// cqlIndexKind tells how an index is searched
+type cqlIndexKind int
+
+const (
+	// cqlText indexes match words and phrases in text columns
+	cqlText cqlIndexKind = iota
+	// cqlSubject matches the subjects as text
+	cqlSubject
+	// cqlYear compares the publication year
+	cqlYear
+	// cqlIdentifier matches an ISBN or a urn:uuid: book ID
+	cqlIdentifier
+	// cqlID matches the book ID
+	cqlID
+)
+
+type cqlIndex struct {
+	kind    cqlIndexKind
+	columns []string
+	// sort is the expression the index sorts by, if it is sortable
+	sort string
+}
+
+// cqlIndexes are the indexes a CQL query can search, by context set and name
+var cqlIndexes = map[string]cqlIndex{
+	"cql.serverchoice": {kind: cqlText, columns: []string{"title", "author"}},
+	"dc.title":         {kind: cqlText, columns: []string{"title"}, sort: "lower(title)"},
+	"dc.creator":       {kind: cqlText, columns: []string{"author"}, sort: "lower(author)"},
+	"dc.publisher":     {kind: cqlText, columns: []string{"publisher"}, sort: "lower(publisher)"},
+	"dc.description":   {kind: cqlText, columns: []string{"description"}},
+	"dc.subject":       {kind: cqlSubject},
+	"dc.date":          {kind: cqlYear, sort: "year"},
+	"dc.identifier":    {kind: cqlIdentifier},
+	"rec.identifier":   {kind: cqlID},
+}
+
+// cqlContextSets maps the context set identifiers to the prefixes of
+// cqlIndexes
+var cqlContextSets = map[string]string{
+	cql.ContextSetCQL: "cql",
+	cql.ContextSetDC:  "dc",
+	cql.ContextSetRec: "rec",
+}
+
+// cqlContextSetPrefixes are the prefixes of cqlIndexes
+var cqlContextSetPrefixes = map[string]bool{"cql": true, "dc": true, "rec": true}
+
+const (
+	// maxProximityDistance bounds the distance of prox, each step of which
+	// adds to the text query
+	maxProximityDistance = 10
+	// uuidURN prefixes book IDs in dc.identifier
+	uuidURN = "urn:uuid:"
+	// isbnURN prefixes ISBNs in dc.identifier
+	isbnURN = "urn:isbn:"
+)
+
+// SearchCQL translates the query into parameterized conditions over the
+// books and reads the page it selects. Parts of the query that cannot be
+// translated are reported as a *cql.UnsupportedError before the database
+// is queried.
+func (r *postgresBookRepository) SearchCQL(ctx context.Context, query *cql.Query, offset, limit int) (*entities.BookPage, error) {
+	t := &cqlTranslator{args: []interface{}{nil}}
+	condition, err := t.node(query.Root)
+	if err != nil {
+		return nil, err
+	}
+	order, err := cqlOrder(query.SortKeys)
+	if err != nil {
+		return nil, err
+	}
+	conditions := "tenant_id = $1 AND " + condition
+	return r.searchPage(ctx, conditions, conditions, order, t.args, len(t.args), offset, limit)
+}
+
+// cqlTranslator builds the conditions of a query and collects their
+// arguments; the first argument is left for the tenant ID
+type cqlTranslator struct {
+	args []interface{}
+}
+
+func (t *cqlTranslator) arg(value interface{}) string {
+	t.args = append(t.args, value)
+	return fmt.Sprintf("$%d", len(t.args))
+}
+
+func (t *cqlTranslator) node(node cql.Node) (string, error) {
+	switch n := node.(type) {
+	case *cql.SearchClause:
+		return t.clause(n)
+	case *cql.Boolean:
+		if n.Operator == cql.Prox {
+			return t.proximity(n)
+		}
+		if len(n.Modifiers) > 0 {
+			return "", cql.Unsupported(cql.FeatureBooleanModifier, n.Modifiers[0].Name)
+		}
+		left, err := t.node(n.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := t.node(n.Right)
+		if err != nil {
+			return "", err
+		}
+		switch n.Operator {
+		case cql.And:
+			return "(" + left + " AND " + right + ")", nil
+		case cql.Or:
+			return "(" + left + " OR " + right + ")", nil
+		default:
+			return "(" + left + " AND NOT " + right + ")", nil
+		}
+	}
+	return "", fmt.Errorf("unexpected CQL node %T", node)
+}
+
+// resolveIndex finds the index a clause or sort key names. Unprefixed
+// indexes are Dublin Core ones, unless the query assigned a default context
+// set.
+func resolveIndex(index cql.Index) (cqlIndex, string, error) {
+	prefix := index.Prefix
+	if index.ContextSet != "" {
+		var ok bool
+		if prefix, ok = cqlContextSets[index.ContextSet]; !ok {
+			return cqlIndex{}, "", cql.Unsupported(cql.FeatureContextSet, index.ContextSet)
+		}
+	} else if prefix == "" {
+		prefix = "dc"
+	} else if _, ok := cqlContextSetPrefixes[prefix]; !ok {
+		return cqlIndex{}, "", cql.Unsupported(cql.FeatureContextSet, prefix)
+	}
+	name := prefix + "." + index.Name
+	resolved, ok := cqlIndexes[name]
+	if !ok {
+		return cqlIndex{}, "", cql.Unsupported(cql.FeatureIndex, index.String())
+	}
+	return resolved, name, nil
+}
+
+func (t *cqlTranslator) clause(clause *cql.SearchClause) (string, error) {
+	index, _, err := resolveIndex(clause.Index)
+	if err != nil {
+		return "", err
+	}
+	switch index.kind {
+	case cqlYear:
+		return t.year(clause)
+	case cqlIdentifier, cqlID:
+		return t.identifier(clause, index.kind)
+	}
+
+	relation := clause.Relation
+	masked, respectCase, err := textModifiers(relation.Modifiers)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(clause.Term) == "" {
+		return "", cql.Unsupported(cql.FeatureEmptyTerm, clause.String())
+	}
+
+	var patterns []string
+	negate, whole, join := false, false, " AND "
+	switch relation.Name {
+	case "=", "adj", "scr":
+		patterns = []string{strings.Join(cql.Words(clause.Term), " ")}
+	case "all", "any":
+		patterns = cql.Words(clause.Term)
+		if relation.Name == "any" {
+			join = " OR "
+		}
+	case "==", "exact":
+		patterns, whole = []string{clause.Term}, true
+	case "<>":
+		patterns, whole, negate = []string{clause.Term}, true, true
+	case "<", ">", "<=", ">=", "within", "encloses":
+		return "", cql.Unsupported(cql.FeatureRelationForIndex, relation.Name+" for "+clause.Index.String())
+	default:
+		return "", cql.Unsupported(cql.FeatureRelation, relation.Name)
+	}
+	if len(patterns) > maxSearchWords {
+		return "", cql.Unsupported(cql.FeatureTerm, "more than "+strconv.Itoa(maxSearchWords)+" words")
+	}
+
+	conditions := make([]string, len(patterns))
+	for i, term := range patterns {
+		pattern, err := likePattern(term, masked, whole)
+		if err != nil {
+			return "", err
+		}
+		conditions[i] = t.like(index, pattern, respectCase)
+	}
+	condition := "(" + strings.Join(conditions, join) + ")"
+	if negate {
+		condition = "NOT " + condition
+	}
+	return condition, nil
+}
+
+// like matches the pattern against the index's columns, or its subjects
+func (t *cqlTranslator) like(index cqlIndex, pattern string, respectCase bool) string {
+	expression := func(column string) string {
+		if respectCase {
+			return column
+		}
+		return "lower(" + column + ")"
+	}
+	if !respectCase {
+		pattern = strings.ToLower(pattern)
+	}
+	placeholder := t.arg(pattern)
+	if index.kind == cqlSubject {
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM unnest(subjects) AS subject WHERE %s LIKE %s)", expression("subject"), placeholder)
+	}
+	conditions := make([]string, len(index.columns))
+	for i, column := range index.columns {
+		conditions[i] = expression(column) + " LIKE " + placeholder
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+// textModifiers reads the relation modifiers of a text index: terms are
+// masked and matched regardless of case unless the modifiers say otherwise
+func textModifiers(modifiers []cql.Modifier) (masked, respectCase bool, err error) {
+	masked = true
+	for _, modifier := range modifiers {
+		if modifier.Comparison != "" {
+			return false, false, cql.Unsupported(cql.FeatureRelationModifier, modifier.Name)
+		}
+		switch strings.TrimPrefix(modifier.Name, "cql.") {
+		case "respectcase":
+			respectCase = true
+		case "ignorecase":
+			respectCase = false
+		case "masked":
+			masked = true
+		case "unmasked":
+			masked = false
+		default:
+			return false, false, cql.Unsupported(cql.FeatureRelationModifier, modifier.Name)
+		}
+	}
+	return masked, respectCase, nil
+}
+
+// likePattern turns a term into a LIKE pattern. Unescaped masks become
+// wildcards when the term is masked, and unescaped anchors at either end tie
+// the term to that end of the value; without anchors the term matches
+// anywhere, unless whole is set.
+func likePattern(term string, masked, whole bool) (string, error) {
+	chars := cql.Chars(term)
+	anchoredStart, anchoredEnd := whole, whole
+	if len(chars) > 0 && !chars[0].Escaped && chars[0].Rune == cql.Anchor {
+		chars, anchoredStart = chars[1:], true
+	}
+	if len(chars) > 0 && !chars[len(chars)-1].Escaped && chars[len(chars)-1].Rune == cql.Anchor {
+		chars, anchoredEnd = chars[:len(chars)-1], true
+	}
+
+	var b strings.Builder
+	if !anchoredStart {
+		b.WriteString("%")
+	}
+	for _, c := range chars {
+		switch {
+		case !c.Escaped && c.Rune == cql.Anchor:
+			return "", cql.Unsupported(cql.FeatureAnchoring, term)
+		case masked && !c.Escaped && c.Rune == cql.MaskAny:
+			b.WriteString("%")
+		case masked && !c.Escaped && c.Rune == cql.MaskOne:
+			b.WriteString("_")
+		default:
+			b.WriteString(likeEscaper.Replace(string(c.Rune)))
+		}
+	}
+	if !anchoredEnd {
+		b.WriteString("%")
+	}
+	return b.String(), nil
+}
+
+// year compares the publication year with the whole number the term holds,
+// or with the two of a within range
+func (t *cqlTranslator) year(clause *cql.SearchClause) (string, error) {
+	relation := clause.Relation
+	if len(relation.Modifiers) > 0 {
+		return "", cql.Unsupported(cql.FeatureRelationModifier, relation.Modifiers[0].Name)
+	}
+	parse := func(term string) (int, error) {
+		year, err := strconv.Atoi(strings.TrimSpace(cql.Unescape(term)))
+		if err != nil {
+			return 0, cql.Unsupported(cql.FeatureTerm, term)
+		}
+		return year, nil
+	}
+
+	switch relation.Name {
+	case "=", "==", "<>", "<", ">", "<=", ">=":
+		year, err := parse(clause.Term)
+		if err != nil {
+			return "", err
+		}
+		operator := relation.Name
+		if operator == "==" {
+			operator = "="
+		}
+		return "year " + operator + " " + t.arg(year), nil
+	case "within":
+		bounds := cql.Words(clause.Term)
+		if len(bounds) != 2 {
+			return "", cql.Unsupported(cql.FeatureTerm, clause.Term)
+		}
+		low, err := parse(bounds[0])
+		if err != nil {
+			return "", err
+		}
+		high, err := parse(bounds[1])
+		if err != nil {
+			return "", err
+		}
+		return "year BETWEEN " + t.arg(low) + " AND " + t.arg(high), nil
+	case "adj", "all", "any", "exact", "scr", "encloses":
+		return "", cql.Unsupported(cql.FeatureRelationForIndex, relation.Name+" for "+clause.Index.String())
+	}
+	return "", cql.Unsupported(cql.FeatureRelation, relation.Name)
+}
+
+// identifier matches a book by its ID, or by its ISBN for dc.identifier
+// terms that are not urn:uuid: ones
+func (t *cqlTranslator) identifier(clause *cql.SearchClause, kind cqlIndexKind) (string, error) {
+	relation := clause.Relation
+	if len(relation.Modifiers) > 0 {
+		return "", cql.Unsupported(cql.FeatureRelationModifier, relation.Modifiers[0].Name)
+	}
+	negate := false
+	switch relation.Name {
+	case "=", "==", "exact":
+	case "<>":
+		negate = true
+	case "<", ">", "<=", ">=", "adj", "all", "any", "within", "encloses", "scr":
+		return "", cql.Unsupported(cql.FeatureRelationForIndex, relation.Name+" for "+clause.Index.String())
+	default:
+		return "", cql.Unsupported(cql.FeatureRelation, relation.Name)
+	}
+
+	term := strings.TrimSpace(cql.Unescape(clause.Term))
+	var condition string
+	switch lower := strings.ToLower(term); {
+	case kind == cqlID || strings.HasPrefix(lower, uuidURN):
+		id, err := uuid.Parse(strings.TrimPrefix(lower, uuidURN))
+		if err != nil {
+			return "", cql.Unsupported(cql.FeatureTerm, clause.Term)
+		}
+		condition = "id = " + t.arg(id)
+	default:
+		isbn, err := entities.NormalizeISBN(strings.TrimPrefix(lower, isbnURN))
+		if err != nil {
+			return "", cql.Unsupported(cql.FeatureTerm, clause.Term)
+		}
+		condition = "isbn = " + t.arg(isbn)
+	}
+	if negate {
+		condition = "NOT " + condition
+	}
+	return condition, nil
+}
+
+// proximity matches two words or phrases of the same text index within a
+// distance of each other, with full-text search
+func (t *cqlTranslator) proximity(b *cql.Boolean) (string, error) {
+	left, lok := b.Left.(*cql.SearchClause)
+	right, rok := b.Right.(*cql.SearchClause)
+	if !lok || !rok {
+		return "", cql.Unsupported(cql.FeatureProximity, "prox between booleans")
+	}
+	index, name, err := resolveIndex(left.Index)
+	if err != nil {
+		return "", err
+	}
+	_, rightName, err := resolveIndex(right.Index)
+	if err != nil {
+		return "", err
+	}
+	if index.kind != cqlText || rightName != name {
+		return "", cql.Unsupported(cql.FeatureProximity, "prox across "+left.Index.String()+" and "+right.Index.String())
+	}
+
+	distances, ordered, err := proximityModifiers(b.Modifiers)
+	if err != nil {
+		return "", err
+	}
+	leftQuery, err := tsPhrase(left)
+	if err != nil {
+		return "", err
+	}
+	rightQuery, err := tsPhrase(right)
+	if err != nil {
+		return "", err
+	}
+
+	var alternatives []string
+	for _, distance := range distances {
+		operator := fmt.Sprintf(" <%d> ", distance)
+		alternatives = append(alternatives, "("+leftQuery+")"+operator+"("+rightQuery+")")
+		if !ordered {
+			alternatives = append(alternatives, "("+rightQuery+")"+operator+"("+leftQuery+")")
+		}
+	}
+	placeholder := t.arg(strings.Join(alternatives, " | "))
+	conditions := make([]string, len(index.columns))
+	for i, column := range index.columns {
+		conditions[i] = fmt.Sprintf("to_tsvector('simple', %s) @@ to_tsquery('simple', %s)", column, placeholder)
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", nil
+}
+
+// proximityModifiers reads the modifiers of prox into the word distances
+// it allows and whether the left side must come first. By default the sides
+// are adjacent, in either order.
+func proximityModifiers(modifiers []cql.Modifier) ([]int, bool, error) {
+	distances, ordered := []int{1}, false
+	for _, modifier := range modifiers {
+		switch strings.TrimPrefix(modifier.Name, "prox.") {
+		case "unit":
+			if !strings.EqualFold(modifier.Value, "word") {
+				return nil, false, cql.Unsupported(cql.FeatureProximityUnit, modifier.Value)
+			}
+		case "distance":
+			distance, err := strconv.Atoi(modifier.Value)
+			if err != nil || distance < 1 || distance > maxProximityDistance {
+				return nil, false, cql.Unsupported(cql.FeatureProximityDistance, modifier.Value)
+			}
+			switch modifier.Comparison {
+			case "=":
+				distances = []int{distance}
+			case "<":
+				if distance == 1 {
+					return nil, false, cql.Unsupported(cql.FeatureProximityDistance, modifier.Value)
+				}
+				distances = upTo(distance - 1)
+			case "<=":
+				distances = upTo(distance)
+			default:
+				return nil, false, cql.Unsupported(cql.FeatureProximityRelation, modifier.Comparison)
+			}
+		case "ordered":
+			ordered = true
+		case "unordered":
+			ordered = false
+		default:
+			return nil, false, cql.Unsupported(cql.FeatureBooleanModifier, modifier.Name)
+		}
+	}
+	return distances, ordered, nil
+}
+
+func upTo(n int) []int {
+	numbers := make([]int, n)
+	for i := range numbers {
+		numbers[i] = i + 1
+	}
+	return numbers
+}
+
+// tsPhrase turns a side of prox into a tsquery phrase of its words. Words
+// are reduced to letters and digits and quoted, and a trailing mask matches
+// prefixes.
+func tsPhrase(clause *cql.SearchClause) (string, error) {
+	switch clause.Relation.Name {
+	case "=", "adj", "scr":
+	default:
+		return "", cql.Unsupported(cql.FeatureProximity, clause.Relation.Name+" under prox")
+	}
+	if _, _, err := textModifiers(clause.Relation.Modifiers); err != nil {
+		return "", err
+	}
+
+	var lexemes []string
+	for _, word := range cql.Words(clause.Term) {
+		var b strings.Builder
+		prefix := false
+		chars := cql.Chars(word)
+		for i, c := range chars {
+			switch {
+			case !c.Escaped && c.Rune == cql.MaskAny && i == len(chars)-1:
+				prefix = true
+			case !c.Escaped && (c.Rune == cql.MaskAny || c.Rune == cql.MaskOne || c.Rune == cql.Anchor):
+				return "", cql.Unsupported(cql.FeatureProximity, "masks under prox")
+			case unicode.IsLetter(c.Rune) || unicode.IsDigit(c.Rune):
+				b.WriteRune(unicode.ToLower(c.Rune))
+			}
+		}
+		if b.Len() == 0 {
+			continue
+		}
+		lexeme := "'" + b.String() + "'"
+		if prefix {
+			lexeme += ":*"
+		}
+		lexemes = append(lexemes, lexeme)
+	}
+	if len(lexemes) == 0 {
+		return "", cql.Unsupported(cql.FeatureEmptyTerm, clause.String())
+	}
+	if len(lexemes) > maxSearchWords {
+		return "", cql.Unsupported(cql.FeatureTerm, "more than "+strconv.Itoa(maxSearchWords)+" words")
+	}
+	return strings.Join(lexemes, " <-> "), nil
+}
+
+// cqlOrder translates the sort keys into an ORDER BY list, newest books
+// first without them. The ID breaks ties, so that pages do not overlap.
+func cqlOrder(keys []cql.SortKey) (string, error) {
+	if len(keys) == 0 {
+		return "created_at DESC, id DESC", nil
+	}
+	order := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		index, _, err := resolveIndex(key.Index)
+		if err != nil || index.sort == "" {
+			return "", cql.Unsupported(cql.FeatureSortIndex, key.Index.String())
+		}
+		direction := "ASC"
+		for _, modifier := range key.Modifiers {
+			switch modifier.Name {
+			case "sort.ascending", "ascending":
+				direction = "ASC"
+			case "sort.descending", "descending":
+				direction = "DESC"
+			default:
+				return "", cql.Unsupported(cql.FeatureSortModifier, modifier.Name)
+			}
+		}
+		order = append(order, index.sort+" "+direction)
+	}
+	return strings.Join(append(order, "id"), ", "), nil
+}
@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// bookFileColumns are the columns scanned into entities.BookFile
+const bookFileColumns = `id, tenant_id, book_id, format, content_type, filename, size, checksum, storage_key, created_at`
+
+// AddFile inserts the file, reporting a checksum the tenant already has as
+// ErrDuplicateBookFile
+func (r *postgresBookRepository) AddFile(ctx context.Context, file *entities.BookFile) (*entities.BookFile, error) {
+	query := `INSERT INTO book_files (id, tenant_id, book_id, format, content_type, filename, size, checksum, storage_key)
+              VALUES (:id, :tenant_id, :book_id, :format, :content_type, :filename, :size, :checksum, :storage_key)
+              RETURNING ` + bookFileColumns
+
+	var created entities.BookFile
+	err := r.withTenantOn(ctx, "book_files", query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		file.TenantID = tenantID
+		if file.ID == uuid.Nil {
+			file.ID = uuid.New()
+		}
+
+		rows, err := sqlx.NamedQueryContext(ctx, tx, query, file)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				return entities.ErrDuplicateBookFile
+			}
+			if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+				return entities.ErrBookNotFound
+			}
+			logging.FromContext(ctx, r.logger).Error("Database error adding book file", zap.String("book_id", file.BookID.String()), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		defer rows.Close()
+
+		if rows.Next() {
+			if err := rows.StructScan(&created); err != nil {
+				return entities.ErrDatabaseError
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (r *postgresBookRepository) ListFiles(ctx context.Context, bookID uuid.UUID) ([]*entities.BookFile, error) {
+	query := `SELECT ` + bookFileColumns + ` FROM book_files WHERE tenant_id = $1 AND book_id = $2 ORDER BY created_at, id`
+
+	var files []*entities.BookFile
+	err := r.withTenantOn(ctx, "book_files", query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		if err := tx.SelectContext(ctx, &files, query, tenantID, bookID); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error listing book files", zap.String("book_id", bookID.String()), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (r *postgresBookRepository) ListFilesOf(ctx context.Context, bookIDs []uuid.UUID) ([]*entities.BookFile, error) {
+	query := `SELECT ` + bookFileColumns + ` FROM book_files WHERE tenant_id = $1 AND book_id = ANY($2::uuid[]) ORDER BY created_at, id`
+
+	keys := make([]string, len(bookIDs))
+	for i, id := range bookIDs {
+		keys[i] = id.String()
+	}
+	var files []*entities.BookFile
+	err := r.withTenantOn(ctx, "book_files", query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		if err := tx.SelectContext(ctx, &files, query, tenantID, pq.StringArray(keys)); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error listing book files", zap.Int("books", len(bookIDs)), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (r *postgresBookRepository) GetFile(ctx context.Context, bookID, fileID uuid.UUID) (*entities.BookFile, error) {
+	query := `SELECT ` + bookFileColumns + ` FROM book_files WHERE tenant_id = $1 AND book_id = $2 AND id = $3`
+	return r.getFile(ctx, query, bookID, fileID)
+}
+
+func (r *postgresBookRepository) FindFileByChecksum(ctx context.Context, checksum string) (*entities.BookFile, error) {
+	query := `SELECT ` + bookFileColumns + ` FROM book_files WHERE tenant_id = $1 AND checksum = $2`
+	return r.getFile(ctx, query, checksum)
+}
+
+// getFile runs a query for one file whose first parameter is the tenant
+func (r *postgresBookRepository) getFile(ctx context.Context, query string, args ...interface{}) (*entities.BookFile, error) {
+	var file entities.BookFile
+	err := r.withTenantOn(ctx, "book_files", query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		err := tx.GetContext(ctx, &file, query, append([]interface{}{tenantID}, args...)...)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return entities.ErrBookFileNotFound
+			}
+			logging.FromContext(ctx, r.logger).Error("Database error getting book file", zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (r *postgresBookRepository) DeleteFile(ctx context.Context, bookID, fileID uuid.UUID) error {
+	query := `DELETE FROM book_files WHERE tenant_id = $1 AND book_id = $2 AND id = $3`
+
+	return r.withTenantOn(ctx, "book_files", query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		result, err := tx.ExecContext(ctx, query, tenantID, bookID, fileID)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error deleting book file", zap.String("file_id", fileID.String()), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return entities.ErrDatabaseError
+		}
+		if rowsAffected == 0 {
+			return entities.ErrBookFileNotFound
+		}
+		return nil
+	})
+}
+
+func (r *postgresBookRepository) MoveFiles(ctx context.Context, from []uuid.UUID, to uuid.UUID) error {
+	query := `UPDATE book_files SET book_id = $3 WHERE tenant_id = $1 AND book_id = ANY($2::uuid[])`
+
+	keys := make([]string, len(from))
+	for i, id := range from {
+		keys[i] = id.String()
+	}
+	return r.withTenantOn(ctx, "book_files", query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		if _, err := tx.ExecContext(ctx, query, tenantID, pq.StringArray(keys), to); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error moving book files", zap.String("book_id", to.String()), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
+}
+
+func (r *postgresBookRepository) SetFileStorageKey(ctx context.Context, fileID uuid.UUID, key string) error {
+	query := `UPDATE book_files SET storage_key = $3 WHERE tenant_id = $1 AND id = $2`
+
+	return r.withTenantOn(ctx, "book_files", query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		result, err := tx.ExecContext(ctx, query, tenantID, fileID, key)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error updating book file", zap.String("file_id", fileID.String()), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return entities.ErrDatabaseError
+		}
+		if rowsAffected == 0 {
+			return entities.ErrBookFileNotFound
+		}
+		return nil
+	})
+}
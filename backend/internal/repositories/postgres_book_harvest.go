@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// tombstone is a row of book_tombstones, which a trigger fills as books are
+// deleted, merged books included
+type tombstone struct {
+	BookID    uuid.UUID `db:"book_id"`
+	DeletedAt time.Time `db:"deleted_at"`
+}
+
+func (t *tombstone) record() *entities.HarvestRecord {
+	return &entities.HarvestRecord{ID: t.BookID, Datestamp: t.DeletedAt, Deleted: true}
+}
+
+func bookRecord(book *entities.Book) *entities.HarvestRecord {
+	return &entities.HarvestRecord{ID: book.ID, Datestamp: book.UpdatedAt, Book: book}
+}
+
+// harvestConditions returns the conditions and arguments selecting the
+// query's rows of a table, given its datestamp and ID columns. The first
+// argument is left for the tenant ID.
+func harvestConditions(query entities.HarvestQuery, datestamp, id string) (string, []interface{}) {
+	where := []string{"tenant_id = $1"}
+	args := []interface{}{nil}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if !query.From.IsZero() {
+		where = append(where, datestamp+" >= "+arg(query.From.UTC()))
+	}
+	if !query.Before.IsZero() {
+		where = append(where, datestamp+" < "+arg(query.Before.UTC()))
+	}
+	if query.After != nil {
+		where = append(where, fmt.Sprintf("(%s, %s) > (%s, %s)", datestamp, id, arg(query.After.Datestamp.UTC()), arg(query.After.ID)))
+	}
+	return strings.Join(where, " AND "), args
+}
+
+// Harvest reads up to Limit books and up to Limit tombstones past the
+// cursor, each in harvest order, in one transaction, and keeps the first
+// Limit of them merged
+func (r *postgresBookRepository) Harvest(ctx context.Context, query entities.HarvestQuery) ([]*entities.HarvestRecord, error) {
+	conditions, bookArgs := harvestConditions(query, "updated_at", "id")
+	booksQuery := `SELECT ` + bookColumns + ` FROM books WHERE ` + conditions +
+		fmt.Sprintf(` ORDER BY updated_at, id LIMIT %d`, query.Limit)
+	conditions, tombstoneArgs := harvestConditions(query, "deleted_at", "book_id")
+	tombstonesQuery := `SELECT book_id, deleted_at FROM book_tombstones WHERE ` + conditions +
+		fmt.Sprintf(` ORDER BY deleted_at, book_id LIMIT %d`, query.Limit)
+
+	var books []entities.Book
+	var tombstones []tombstone
+	err := r.withTenant(ctx, booksQuery, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		bookArgs[0], tombstoneArgs[0] = tenantID, tenantID
+		if err := tx.SelectContext(ctx, &books, booksQuery, bookArgs...); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error harvesting books", zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		if err := tx.SelectContext(ctx, &tombstones, tombstonesQuery, tombstoneArgs...); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error harvesting deleted books", zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*entities.HarvestRecord, 0, len(books)+len(tombstones))
+	for i := range books {
+		records = append(records, bookRecord(&books[i]))
+	}
+	for i := range tombstones {
+		records = append(records, tombstones[i].record())
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].Datestamp.Equal(records[j].Datestamp) {
+			return records[i].Datestamp.Before(records[j].Datestamp)
+		}
+		return records[i].ID.String() < records[j].ID.String()
+	})
+	if len(records) > query.Limit {
+		records = records[:query.Limit]
+	}
+	return records, nil
+}
+
+// GetHarvestRecord returns the book, or its tombstone if it was deleted
+func (r *postgresBookRepository) GetHarvestRecord(ctx context.Context, id uuid.UUID) (*entities.HarvestRecord, error) {
+	bookQuery := `SELECT ` + bookColumns + ` FROM books WHERE tenant_id = $1 AND id = $2`
+	tombstoneQuery := `SELECT book_id, deleted_at FROM book_tombstones WHERE tenant_id = $1 AND book_id = $2`
+
+	var record *entities.HarvestRecord
+	err := r.withTenant(ctx, bookQuery, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		var book entities.Book
+		err := tx.GetContext(ctx, &book, bookQuery, tenantID, id)
+		if err == nil {
+			record = bookRecord(&book)
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			logging.FromContext(ctx, r.logger).Error("Database error getting book for harvest", zap.String("id", id.String()), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+
+		var deleted tombstone
+		if err := tx.GetContext(ctx, &deleted, tombstoneQuery, tenantID, id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return entities.ErrBookNotFound
+			}
+			logging.FromContext(ctx, r.logger).Error("Database error getting deleted book", zap.String("id", id.String()), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		record = deleted.record()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// EarliestDatestamp returns the datestamp of the tenant's oldest record, or
+// the zero time without any
+func (r *postgresBookRepository) EarliestDatestamp(ctx context.Context) (time.Time, error) {
+	query := `SELECT LEAST((SELECT MIN(updated_at) FROM books WHERE tenant_id = $1),
+                          (SELECT MIN(deleted_at) FROM book_tombstones WHERE tenant_id = $1))`
+
+	var earliest sql.NullTime
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		if err := tx.GetContext(ctx, &earliest, query, tenantID); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error reading earliest datestamp", zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return earliest.Time, nil
+}
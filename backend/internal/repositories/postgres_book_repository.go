@@ -3,14 +3,24 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 
 	"byfood-library/internal/domain/entities"
 	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tenancy"
+	"byfood-library/internal/tracing"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
+// bookColumns are the columns scanned into entities.Book
+const bookColumns = `id, tenant_id, title, author, year, isbn, publisher, page_count, subjects, description, cover_url, cover_etag, created_at, updated_at`
+
 type postgresBookRepository struct {
 	db     *sqlx.DB
 	logger *zap.Logger
@@ -23,51 +33,207 @@ func NewPostgresBookRepository(db *sqlx.DB, logger *zap.Logger) repositories.Boo
 	}
 }
 
+// withTenant runs fn in a transaction bound to the tenant from ctx and
+// traces it as query, joining the caller's transaction when ctx carries
+// one. Queries filter on tenant_id explicitly; app.tenant_id additionally
+// feeds the row-level security policy on books as defence in depth.
+func (r *postgresBookRepository) withTenant(ctx context.Context, query string, fn func(tx *sqlx.Tx, tenantID uuid.UUID) error) error {
+	return r.withTenantOn(ctx, "books", query, fn)
+}
+
+// withTenantOn is withTenant for a query against another table of the
+// aggregate, such as book_files
+func (r *postgresBookRepository) withTenantOn(ctx context.Context, table, query string, fn func(tx *sqlx.Tx, tenantID uuid.UUID) error) (err error) {
+	ctx, span := startQuerySpan(ctx, table, query)
+	defer func() {
+		if err != nil && !errors.Is(err, entities.ErrBookNotFound) && !errors.Is(err, entities.ErrBookFileNotFound) {
+			tracing.Fail(span, err)
+		}
+		span.End()
+	}()
+
+	if state, ok := txFromContext(ctx); ok {
+		return contextError(ctx, fn(state.tx, state.tenantID))
+	}
+
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := beginTenantTx(ctx, r.db, r.logger, tenantID)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx, tenantID); err != nil {
+		return contextError(ctx, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to commit transaction", zap.Error(err))
+		return contextError(ctx, entities.ErrDatabaseError)
+	}
+	return nil
+}
+
+// contextError reports database failures caused by the request deadline or
+// cancellation as ErrRequestTimeout rather than a generic database error
+func contextError(ctx context.Context, err error) error {
+	if errors.Is(err, entities.ErrDatabaseError) && ctx.Err() != nil {
+		return entities.ErrRequestTimeout
+	}
+	return err
+}
+
 // Create using named parameters and struct scanning
 func (r *postgresBookRepository) Create(ctx context.Context, book *entities.Book) (*entities.Book, error) {
-	query := `INSERT INTO books (title, author, year) VALUES (:title, :author, :year) 
-              RETURNING id, title, author, year, created_at, updated_at`
+	query := `INSERT INTO books (tenant_id, title, author, year, isbn, publisher, page_count, subjects, description, cover_url)
+              VALUES (:tenant_id, :title, :author, :year, :isbn, :publisher, :page_count, :subjects, :description, :cover_url)
+              RETURNING ` + bookColumns
 
 	var createdBook entities.Book
-	rows, err := r.db.NamedQueryContext(ctx, query, book)
-	if err != nil {
-		return nil, entities.ErrDatabaseError
-	}
-	defer rows.Close()
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		book.TenantID = tenantID
 
-	if rows.Next() {
-		err = rows.StructScan(&createdBook)
+		rows, err := sqlx.NamedQueryContext(ctx, tx, query, book)
 		if err != nil {
-			return nil, entities.ErrDatabaseError
+			logging.FromContext(ctx, r.logger).Error("Database error creating book", zap.Error(err))
+			return entities.ErrDatabaseError
 		}
+		defer rows.Close()
+
+		if rows.Next() {
+			if err := rows.StructScan(&createdBook); err != nil {
+				return entities.ErrDatabaseError
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+
 	return &createdBook, nil
 }
 
 // GetByID using Get for single row retrieval
 func (r *postgresBookRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
-	query := `SELECT id, title, author, year, created_at, updated_at FROM books WHERE id = $1`
+	query := `SELECT ` + bookColumns + ` FROM books WHERE tenant_id = $1 AND id = $2`
 
 	var book entities.Book
-	err := r.db.GetContext(ctx, &book, query, id)
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		err := tx.GetContext(ctx, &book, query, tenantID, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return entities.ErrBookNotFound
+			}
+			logging.FromContext(ctx, r.logger).Error("Database error getting book by ID", zap.String("id", id.String()), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, entities.ErrBookNotFound
+		return nil, err
+	}
+	return &book, nil
+}
+
+// GetByIDForUpdate is GetByID with a row lock, held until the surrounding
+// transaction ends
+func (r *postgresBookRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	query := `SELECT ` + bookColumns + ` FROM books WHERE tenant_id = $1 AND id = $2 FOR UPDATE`
+
+	var book entities.Book
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		err := tx.GetContext(ctx, &book, query, tenantID, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return entities.ErrBookNotFound
+			}
+			logging.FromContext(ctx, r.logger).Error("Database error locking book", zap.String("id", id.String()), zap.Error(err))
+			return entities.ErrDatabaseError
 		}
-		r.logger.Error("Database error getting book by ID", zap.String("id", id.String()), zap.Error(err))
-		return nil, entities.ErrDatabaseError
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return &book, nil
 }
 
+// GetByIDs loads a batch of books with a single query
+func (r *postgresBookRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	query := `SELECT ` + bookColumns + ` FROM books WHERE tenant_id = $1 AND id = ANY($2::uuid[])`
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.String()
+	}
+
+	var books []entities.Book
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		if err := tx.SelectContext(ctx, &books, query, tenantID, pq.StringArray(keys)); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error getting books by ID", zap.Int("count", len(ids)), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bookPointers := make([]*entities.Book, len(books))
+	for i := range books {
+		bookPointers[i] = &books[i]
+	}
+	return bookPointers, nil
+}
+
+// GetByIDsForUpdate is GetByIDs with row locks, taken in id order and held
+// until the surrounding transaction ends
+func (r *postgresBookRepository) GetByIDsForUpdate(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	query := `SELECT ` + bookColumns + ` FROM books WHERE tenant_id = $1 AND id = ANY($2::uuid[]) ORDER BY id FOR UPDATE`
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.String()
+	}
+
+	var books []entities.Book
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		if err := tx.SelectContext(ctx, &books, query, tenantID, pq.StringArray(keys)); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error locking books", zap.Int("count", len(ids)), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bookPointers := make([]*entities.Book, len(books))
+	for i := range books {
+		bookPointers[i] = &books[i]
+	}
+	return bookPointers, nil
+}
+
 // GetAll using Select for automatic slice population
 func (r *postgresBookRepository) GetAll(ctx context.Context) ([]*entities.Book, error) {
-	query := `SELECT id, title, author, year, created_at, updated_at FROM books ORDER BY created_at DESC`
+	query := `SELECT ` + bookColumns + ` FROM books WHERE tenant_id = $1 ORDER BY created_at DESC`
 
 	var books []entities.Book
-	err := r.db.SelectContext(ctx, &books, query)
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		if err := tx.SelectContext(ctx, &books, query, tenantID); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error getting all books", zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, entities.ErrDatabaseError
+		return nil, err
 	}
 
 	// Convert []entities.Book to []*entities.Book
@@ -80,46 +246,169 @@ func (r *postgresBookRepository) GetAll(ctx context.Context) ([]*entities.Book,
 
 // Update using named parameters
 func (r *postgresBookRepository) Update(ctx context.Context, id uuid.UUID, book *entities.Book) (*entities.Book, error) {
-	query := `UPDATE books SET title = :title, author = :author, year = :year 
-              WHERE id = :id RETURNING id, title, author, year, created_at, updated_at`
-
-	// Set the ID for the named query
-	book.ID = id
+	query := `UPDATE books SET title = :title, author = :author, year = :year, isbn = :isbn, publisher = :publisher,
+                  page_count = :page_count, subjects = :subjects, description = :description, cover_url = :cover_url
+              WHERE tenant_id = :tenant_id AND id = :id RETURNING ` + bookColumns
 
 	var updatedBook entities.Book
-	rows, err := r.db.NamedQueryContext(ctx, query, book)
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		// Set the IDs for the named query
+		book.ID = id
+		book.TenantID = tenantID
+
+		rows, err := sqlx.NamedQueryContext(ctx, tx, query, book)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error updating book", zap.String("id", id.String()), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		defer rows.Close()
+
+		if !rows.Next() {
+			return entities.ErrBookNotFound
+		}
+		if err := rows.StructScan(&updatedBook); err != nil {
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, entities.ErrDatabaseError
+		return nil, err
 	}
-	defer rows.Close()
 
-	if rows.Next() {
-		err = rows.StructScan(&updatedBook)
+	return &updatedBook, nil
+}
+
+// SetCover records the cover version; the update leaves the rest of the
+// book alone, so that it never races with edits of its fields
+func (r *postgresBookRepository) SetCover(ctx context.Context, id uuid.UUID, etag string) (*entities.Book, error) {
+	query := `UPDATE books SET cover_etag = $3 WHERE tenant_id = $1 AND id = $2 RETURNING ` + bookColumns
+
+	var book entities.Book
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		err := tx.GetContext(ctx, &book, query, tenantID, id, etag)
 		if err != nil {
-			return nil, entities.ErrDatabaseError
+			if errors.Is(err, sql.ErrNoRows) {
+				return entities.ErrBookNotFound
+			}
+			logging.FromContext(ctx, r.logger).Error("Database error setting book cover", zap.String("id", id.String()), zap.Error(err))
+			return entities.ErrDatabaseError
 		}
-		return &updatedBook, nil
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil, entities.ErrBookNotFound
+	return &book, nil
 }
 
 // Delete using Exec
 func (r *postgresBookRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM books WHERE id = $1`
+	query := `DELETE FROM books WHERE tenant_id = $1 AND id = $2`
+
+	return r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		result, err := tx.ExecContext(ctx, query, tenantID, id)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error deleting book", zap.String("id", id.String()), zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return entities.ErrDatabaseError
+		}
 
-	result, err := r.db.ExecContext(ctx, query, id)
+		if rowsAffected == 0 {
+			return entities.ErrBookNotFound
+		}
+		return nil
+	})
+}
+
+// Count returns the number of books owned by the tenant
+func (r *postgresBookRepository) Count(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*) FROM books WHERE tenant_id = $1`
+
+	var count int
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		if err := tx.GetContext(ctx, &count, query, tenantID); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error counting books", zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
 	if err != nil {
-		return entities.ErrDatabaseError
+		return 0, err
 	}
+	return count, nil
+}
+
+// FindSimilar matches titles with pg_trgm's % operator, which uses the GIN
+// index on lower(title) and the pg_trgm.similarity_threshold setting (0.3
+// by default), so that the caller's stricter scoring sees every plausible
+// candidate
+func (r *postgresBookRepository) FindSimilar(ctx context.Context, book *entities.Book, limit int) ([]*entities.Book, error) {
+	query := `SELECT ` + bookColumns + ` FROM books
+              WHERE tenant_id = $1 AND id <> $2 AND ((isbn <> '' AND isbn = $3) OR lower(title) % lower($4))
+              ORDER BY (isbn <> '' AND isbn = $3) DESC, similarity(lower(title), lower($4)) DESC LIMIT $5`
 
-	rowsAffected, err := result.RowsAffected()
+	var books []entities.Book
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		if err := tx.SelectContext(ctx, &books, query, tenantID, book.ID, book.ISBN, book.Title, limit); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error finding similar books", zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
 	if err != nil {
-		return entities.ErrDatabaseError
+		return nil, err
 	}
 
-	if rowsAffected == 0 {
-		return entities.ErrBookNotFound
+	bookPointers := make([]*entities.Book, len(books))
+	for i := range books {
+		bookPointers[i] = &books[i]
 	}
+	return bookPointers, nil
+}
 
-	return nil
-}
\ No newline at end of file
+// aliasedColumns selects bookColumns of the table aliased as alias under
+// names such as "a.title", which sqlx scans into the field tagged db:"a"
+func aliasedColumns(alias string) string {
+	columns := strings.Split(bookColumns, ", ")
+	for i, column := range columns {
+		columns[i] = fmt.Sprintf(`%s.%s AS "%s.%s"`, alias, column, alias, column)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// bookPair scans the two sides of a self-join
+type bookPair struct {
+	A entities.Book `db:"a"`
+	B entities.Book `db:"b"`
+}
+
+// FindSimilarPairs self-joins books on the same conditions as FindSimilar
+func (r *postgresBookRepository) FindSimilarPairs(ctx context.Context, limit int) ([][2]*entities.Book, error) {
+	query := `SELECT ` + aliasedColumns("a") + `, ` + aliasedColumns("b") + `
+              FROM books a JOIN books b ON b.tenant_id = a.tenant_id
+                   AND (b.created_at, b.id) > (a.created_at, a.id)
+                   AND ((a.isbn <> '' AND a.isbn = b.isbn) OR lower(a.title) % lower(b.title))
+              WHERE a.tenant_id = $1 ORDER BY a.created_at, a.id LIMIT $2`
+
+	var rows []bookPair
+	err := r.withTenant(ctx, query, func(tx *sqlx.Tx, tenantID uuid.UUID) error {
+		if err := tx.SelectContext(ctx, &rows, query, tenantID, limit); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error finding similar book pairs", zap.Error(err))
+			return entities.ErrDatabaseError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([][2]*entities.Book, len(rows))
+	for i := range rows {
+		pairs[i] = [2]*entities.Book{&rows[i].A, &rows[i].B}
+	}
+	return pairs, nil
+}
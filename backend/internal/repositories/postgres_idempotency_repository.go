@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+const idempotencyColumns = `tenant_id, idempotency_key, method, path, request_hash, lock_id, status,
+              response_status, response_headers, response_body, created_at, expires_at`
+
+type postgresIdempotencyRepository struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewPostgresIdempotencyRepository(db *sqlx.DB, logger *zap.Logger) repositories.IdempotencyRepository {
+	return &postgresIdempotencyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Acquire inserts an in-flight record, taking over the key only if the
+// previous record has expired. Conflicts leave the existing row untouched.
+func (r *postgresIdempotencyRepository) Acquire(ctx context.Context, record *entities.IdempotencyRecord) (*entities.IdempotencyRecord, bool, error) {
+	query := `INSERT INTO idempotency_keys (tenant_id, idempotency_key, method, path, request_hash, lock_id, status, expires_at)
+              VALUES (:tenant_id, :idempotency_key, :method, :path, :request_hash, :lock_id, 'in_flight', :expires_at)
+              ON CONFLICT (tenant_id, idempotency_key) DO UPDATE
+              SET method = EXCLUDED.method, path = EXCLUDED.path, request_hash = EXCLUDED.request_hash, lock_id = EXCLUDED.lock_id,
+                  status = 'in_flight', response_status = NULL, response_headers = NULL, response_body = NULL,
+                  created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+              WHERE idempotency_keys.expires_at < CURRENT_TIMESTAMP
+              RETURNING ` + idempotencyColumns
+
+	rows, err := r.db.NamedQueryContext(ctx, query, record)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error acquiring idempotency key", zap.String("key", record.Key), zap.Error(err))
+		return nil, false, entities.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var acquired entities.IdempotencyRecord
+		if err := rows.StructScan(&acquired); err != nil {
+			return nil, false, entities.ErrDatabaseError
+		}
+		return &acquired, true, nil
+	}
+	rows.Close()
+
+	var existing entities.IdempotencyRecord
+	err = r.db.GetContext(ctx, &existing,
+		`SELECT `+idempotencyColumns+` FROM idempotency_keys WHERE tenant_id = $1 AND idempotency_key = $2`,
+		record.TenantID, record.Key)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released between our insert attempt and the read; let the client retry
+		return nil, false, entities.ErrIdempotencyKeyInFlight
+	}
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error reading idempotency key", zap.String("key", record.Key), zap.Error(err))
+		return nil, false, entities.ErrDatabaseError
+	}
+	return &existing, false, nil
+}
+
+func (r *postgresIdempotencyRepository) Complete(ctx context.Context, tenantID uuid.UUID, key string, lockID uuid.UUID, status int, headers http.Header, body []byte, expiresAt time.Time) error {
+	query := `UPDATE idempotency_keys
+              SET status = 'completed', response_status = $4, response_headers = $5, response_body = $6, expires_at = $7
+              WHERE tenant_id = $1 AND idempotency_key = $2 AND status = 'in_flight' AND lock_id = $3`
+
+	result, err := r.db.ExecContext(ctx, query, tenantID, key, lockID, status, entities.StoredHeaders(headers), body, expiresAt)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error completing idempotency key", zap.String("key", key), zap.Error(err))
+		return entities.ErrDatabaseError
+	}
+	return r.owned(result)
+}
+
+func (r *postgresIdempotencyRepository) Release(ctx context.Context, tenantID uuid.UUID, key string, lockID uuid.UUID) error {
+	query := `DELETE FROM idempotency_keys WHERE tenant_id = $1 AND idempotency_key = $2 AND status = 'in_flight' AND lock_id = $3`
+
+	result, err := r.db.ExecContext(ctx, query, tenantID, key, lockID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error releasing idempotency key", zap.String("key", key), zap.Error(err))
+		return entities.ErrDatabaseError
+	}
+	return r.owned(result)
+}
+
+// owned reports ErrIdempotencyKeyLost when a statement matched no claim
+func (r *postgresIdempotencyRepository) owned(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return entities.ErrDatabaseError
+	}
+	if affected == 0 {
+		return entities.ErrIdempotencyKeyLost
+	}
+	return nil
+}
+
+func (r *postgresIdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < CURRENT_TIMESTAMP`)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error deleting expired idempotency keys", zap.Error(err))
+		return 0, entities.ErrDatabaseError
+	}
+	return result.RowsAffected()
+}
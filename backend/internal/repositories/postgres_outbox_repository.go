@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tenancy"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// outboxRelayLock is the advisory lock key that elects one relay at a time,
+// which keeps events for an aggregate in order across replicas
+const outboxRelayLock = 0x6f7574626f78
+
+const outboxColumns = `id, sequence, tenant_id, aggregate_type, aggregate_id, event_type, payload, occurred_at, attempts`
+
+type postgresOutboxRepository struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewPostgresOutboxRepository(db *sqlx.DB, logger *zap.Logger) repositories.OutboxRepository {
+	return &postgresOutboxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Append inserts events using the transaction in ctx when there is one
+func (r *postgresOutboxRepository) Append(ctx context.Context, evts ...*events.Event) error {
+	query := `INSERT INTO outbox (id, tenant_id, aggregate_type, aggregate_id, event_type, payload, occurred_at)
+              VALUES (:id, :tenant_id, :aggregate_type, :aggregate_id, :event_type, :payload, :occurred_at)`
+
+	var exec sqlx.ExtContext = r.db
+	if state, ok := txFromContext(ctx); ok {
+		exec = state.tx
+	}
+
+	for _, evt := range evts {
+		if evt.TenantID == uuid.Nil {
+			tenantID, err := tenancy.IDFromContext(ctx)
+			if err != nil {
+				return err
+			}
+			evt.TenantID = tenantID
+		}
+		if _, err := sqlx.NamedExecContext(ctx, exec, query, evt); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error appending event to outbox",
+				zap.String("event_type", evt.Type), zap.String("aggregate_id", evt.AggregateID.String()), zap.Error(err))
+			return contextError(ctx, entities.ErrDatabaseError)
+		}
+	}
+	return nil
+}
+
+// Relay holds a transaction-scoped advisory lock while delivering, so the
+// lock is released even if the relay dies mid-batch
+func (r *postgresOutboxRepository) Relay(ctx context.Context, limit int, deliver func(ctx context.Context, batch []*events.Event) []events.Outcome) (bool, error) {
+	logger := logging.FromContext(ctx, r.logger)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.Error("Failed to begin outbox transaction", zap.Error(err))
+		return false, entities.ErrDatabaseError
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.GetContext(ctx, &locked, `SELECT pg_try_advisory_xact_lock($1)`, outboxRelayLock); err != nil {
+		logger.Error("Failed to lock outbox", zap.Error(err))
+		return false, entities.ErrDatabaseError
+	}
+	if !locked {
+		return false, nil
+	}
+
+	// An event is held back while an earlier event for the same aggregate is
+	// waiting to be retried; earlier events that are due sort first
+	query := `SELECT ` + outboxColumns + ` FROM outbox o
+              WHERE o.published_at IS NULL AND o.next_attempt_at <= CURRENT_TIMESTAMP
+                AND NOT EXISTS (
+                  SELECT 1 FROM outbox p
+                  WHERE p.aggregate_type = o.aggregate_type AND p.aggregate_id = o.aggregate_id
+                    AND p.published_at IS NULL AND p.sequence < o.sequence
+                    AND p.next_attempt_at > CURRENT_TIMESTAMP)
+              ORDER BY o.sequence
+              LIMIT $1`
+
+	var batch []*events.Event
+	if err := tx.SelectContext(ctx, &batch, query, limit); err != nil {
+		logger.Error("Database error loading outbox events", zap.Error(err))
+		return true, entities.ErrDatabaseError
+	}
+	if len(batch) == 0 {
+		return true, nil
+	}
+
+	for _, outcome := range deliver(ctx, batch) {
+		var err error
+		if outcome.Published {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE outbox SET published_at = CURRENT_TIMESTAMP, attempts = attempts + 1, last_error = NULL WHERE id = $1`,
+				outcome.EventID)
+		} else {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE outbox SET attempts = attempts + 1, last_error = $2,
+                 next_attempt_at = CURRENT_TIMESTAMP + $3 * INTERVAL '1 millisecond' WHERE id = $1`,
+				outcome.EventID, outcome.Error, outcome.RetryAfter.Milliseconds())
+		}
+		if err != nil {
+			logger.Error("Database error recording outbox delivery", zap.String("event_id", outcome.EventID.String()), zap.Error(err))
+			return true, entities.ErrDatabaseError
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("Failed to commit outbox deliveries", zap.Error(err))
+		return true, entities.ErrDatabaseError
+	}
+	return true, nil
+}
+
+func (r *postgresOutboxRepository) DeletePublished(ctx context.Context, olderThan time.Duration) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM outbox WHERE published_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 millisecond'`,
+		olderThan.Milliseconds())
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error deleting published outbox events", zap.Error(err))
+		return 0, entities.ErrDatabaseError
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const tenantColumns = `id, slug, name, status, api_key_hash, settings, created_at, updated_at`
+
+type postgresTenantRepository struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewPostgresTenantRepository(db *sqlx.DB, logger *zap.Logger) repositories.TenantRepository {
+	return &postgresTenantRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *postgresTenantRepository) Create(ctx context.Context, tenant *entities.Tenant) (*entities.Tenant, error) {
+	query := `INSERT INTO tenants (id, slug, name, status, api_key_hash, settings)
+              VALUES (:id, :slug, :name, :status, :api_key_hash, :settings)
+              RETURNING ` + tenantColumns
+
+	rows, err := r.db.NamedQueryContext(ctx, query, tenant)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, entities.ErrTenantSlugTaken
+		}
+		logging.FromContext(ctx, r.logger).Error("Database error creating tenant", zap.String("slug", tenant.Slug), zap.Error(err))
+		return nil, entities.ErrDatabaseError
+	}
+	defer rows.Close()
+
+	var created entities.Tenant
+	if rows.Next() {
+		if err := rows.StructScan(&created); err != nil {
+			return nil, entities.ErrDatabaseError
+		}
+	}
+	return &created, nil
+}
+
+func (r *postgresTenantRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Tenant, error) {
+	return r.getOne(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE id = $1`, id)
+}
+
+func (r *postgresTenantRepository) GetBySlug(ctx context.Context, slug string) (*entities.Tenant, error) {
+	return r.getOne(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE slug = $1`, slug)
+}
+
+func (r *postgresTenantRepository) GetByAPIKeyHash(ctx context.Context, hash string) (*entities.Tenant, error) {
+	return r.getOne(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE api_key_hash = $1`, hash)
+}
+
+func (r *postgresTenantRepository) GetAll(ctx context.Context) ([]*entities.Tenant, error) {
+	query := `SELECT ` + tenantColumns + ` FROM tenants ORDER BY created_at`
+
+	var tenants []*entities.Tenant
+	if err := r.db.SelectContext(ctx, &tenants, query); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error listing tenants", zap.Error(err))
+		return nil, entities.ErrDatabaseError
+	}
+	return tenants, nil
+}
+
+func (r *postgresTenantRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.TenantStatus) (*entities.Tenant, error) {
+	query := `UPDATE tenants SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING ` + tenantColumns
+	return r.getOne(ctx, query, id, status)
+}
+
+func (r *postgresTenantRepository) UpdateSettings(ctx context.Context, id uuid.UUID, settings entities.TenantSettings) (*entities.Tenant, error) {
+	query := `UPDATE tenants SET settings = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING ` + tenantColumns
+	return r.getOne(ctx, query, id, settings)
+}
+
+func (r *postgresTenantRepository) getOne(ctx context.Context, query string, args ...interface{}) (*entities.Tenant, error) {
+	var tenant entities.Tenant
+	if err := r.db.GetContext(ctx, &tenant, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entities.ErrTenantNotFound
+		}
+		logging.FromContext(ctx, r.logger).Error("Database error getting tenant", zap.Error(err))
+		return nil, entities.ErrDatabaseError
+	}
+	return &tenant, nil
+}
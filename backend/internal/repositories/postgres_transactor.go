@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tenancy"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type txKey struct{}
+
+// txState is the transaction shared by repository calls within WithinTx
+type txState struct {
+	tx          *sqlx.Tx
+	tenantID    uuid.UUID
+	afterCommit []func()
+}
+
+func txFromContext(ctx context.Context) (*txState, bool) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	return state, ok
+}
+
+// afterCommit runs fn once the transaction in ctx has committed, or
+// immediately when there is none. Hooks are dropped on rollback.
+func afterCommit(ctx context.Context, fn func()) {
+	if state, ok := txFromContext(ctx); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// beginTenantTx starts a transaction with app.tenant_id set for the
+// row-level security policies
+func beginTenantTx(ctx context.Context, db *sqlx.DB, logger *zap.Logger, tenantID uuid.UUID) (*sqlx.Tx, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		logging.FromContext(ctx, logger).Error("Failed to begin transaction", zap.Error(err))
+		return nil, contextError(ctx, entities.ErrDatabaseError)
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('app.tenant_id', $1, true)`, tenantID.String()); err != nil {
+		tx.Rollback()
+		logging.FromContext(ctx, logger).Error("Failed to set tenant for transaction", zap.String("tenant_id", tenantID.String()), zap.Error(err))
+		return nil, contextError(ctx, entities.ErrDatabaseError)
+	}
+	return tx, nil
+}
+
+type postgresTransactor struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewPostgresTransactor(db *sqlx.DB, logger *zap.Logger) repositories.Transactor {
+	return &postgresTransactor{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// WithinTx runs fn in a tenant-bound transaction. Nested calls join the
+// outer transaction.
+func (t *postgresTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := txFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := beginTenantTx(ctx, t.db, t.logger, tenantID)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	state := &txState{tx: tx, tenantID: tenantID}
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(ctx, t.logger).Error("Failed to commit transaction", zap.Error(err))
+		return contextError(ctx, entities.ErrDatabaseError)
+	}
+
+	for _, hook := range state.afterCommit {
+		hook()
+	}
+	return nil
+}
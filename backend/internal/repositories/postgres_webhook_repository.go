@@ -0,0 +1,287 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tenancy"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+const webhookColumns = `id, tenant_id, url, event_types, secret, active, consecutive_failures, disabled_at, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, webhook_id, tenant_id, event_id, event_type, payload, request_headers, status, attempts,
+              next_attempt_at, response_status, response_body, error, duration_ms, redelivery_of, created_at, delivered_at`
+
+type postgresWebhookRepository struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewPostgresWebhookRepository(db *sqlx.DB, logger *zap.Logger) repositories.WebhookRepository {
+	return &postgresWebhookRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *postgresWebhookRepository) Create(ctx context.Context, webhook *entities.Webhook) (*entities.Webhook, error) {
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	webhook.TenantID = tenantID
+
+	query := `INSERT INTO webhooks (tenant_id, url, event_types, secret, active)
+              VALUES (:tenant_id, :url, :event_types, :secret, :active)
+              RETURNING ` + webhookColumns
+
+	rows, err := r.db.NamedQueryContext(ctx, query, webhook)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error creating webhook", zap.Error(err))
+		return nil, contextError(ctx, entities.ErrDatabaseError)
+	}
+	defer rows.Close()
+
+	var created entities.Webhook
+	if rows.Next() {
+		if err := rows.StructScan(&created); err != nil {
+			return nil, entities.ErrDatabaseError
+		}
+	}
+	return &created, nil
+}
+
+func (r *postgresWebhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Webhook, error) {
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.getOne(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+}
+
+func (r *postgresWebhookRepository) GetAll(ctx context.Context) ([]*entities.Webhook, error) {
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.list(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE tenant_id = $1 ORDER BY created_at`, tenantID)
+}
+
+func (r *postgresWebhookRepository) ListSubscribed(ctx context.Context, eventType string) ([]*entities.Webhook, error) {
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + webhookColumns + ` FROM webhooks
+              WHERE tenant_id = $1 AND active AND $2 = ANY(event_types)
+              ORDER BY created_at`
+	return r.list(ctx, query, tenantID, eventType)
+}
+
+func (r *postgresWebhookRepository) Update(ctx context.Context, webhook *entities.Webhook) (*entities.Webhook, error) {
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := `UPDATE webhooks
+              SET url = $3, event_types = $4, active = $5, consecutive_failures = $6, disabled_at = $7,
+                  updated_at = CURRENT_TIMESTAMP
+              WHERE id = $1 AND tenant_id = $2
+              RETURNING ` + webhookColumns
+	return r.getOne(ctx, query, webhook.ID, tenantID, webhook.URL, webhook.EventTypes, webhook.Active,
+		webhook.ConsecutiveFailures, webhook.DisabledAt)
+}
+
+func (r *postgresWebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error deleting webhook", zap.String("webhook_id", id.String()), zap.Error(err))
+		return contextError(ctx, entities.ErrDatabaseError)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return entities.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *postgresWebhookRepository) Enqueue(ctx context.Context, deliveries ...*entities.WebhookDelivery) error {
+	query := `INSERT INTO webhook_deliveries (id, webhook_id, tenant_id, event_id, event_type, payload, redelivery_of)
+              VALUES (:id, :webhook_id, :tenant_id, :event_id, :event_type, :payload, :redelivery_of)
+              ON CONFLICT (webhook_id, event_id) WHERE redelivery_of IS NULL DO NOTHING`
+
+	for _, delivery := range deliveries {
+		if delivery.ID == uuid.Nil {
+			delivery.ID = uuid.New()
+		}
+		if delivery.TenantID == uuid.Nil {
+			tenantID, err := tenancy.IDFromContext(ctx)
+			if err != nil {
+				return err
+			}
+			delivery.TenantID = tenantID
+		}
+		if _, err := r.db.NamedExecContext(ctx, query, delivery); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error queueing webhook delivery",
+				zap.String("webhook_id", delivery.WebhookID.String()), zap.String("event_id", delivery.EventID.String()), zap.Error(err))
+			return contextError(ctx, entities.ErrDatabaseError)
+		}
+	}
+	return nil
+}
+
+func (r *postgresWebhookRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*entities.WebhookDelivery, error) {
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+              WHERE webhook_id = $1 AND tenant_id = $2
+              ORDER BY created_at DESC
+              LIMIT $3`
+
+	var deliveries []*entities.WebhookDelivery
+	if err := r.db.SelectContext(ctx, &deliveries, query, webhookID, tenantID, limit); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error listing webhook deliveries", zap.String("webhook_id", webhookID.String()), zap.Error(err))
+		return nil, contextError(ctx, entities.ErrDatabaseError)
+	}
+	return deliveries, nil
+}
+
+func (r *postgresWebhookRepository) GetDelivery(ctx context.Context, webhookID, id uuid.UUID) (*entities.WebhookDelivery, error) {
+	tenantID, err := tenancy.IDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+              WHERE id = $1 AND webhook_id = $2 AND tenant_id = $3`
+
+	var delivery entities.WebhookDelivery
+	if err := r.db.GetContext(ctx, &delivery, query, id, webhookID, tenantID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entities.ErrWebhookDeliveryNotFound
+		}
+		logging.FromContext(ctx, r.logger).Error("Database error getting webhook delivery", zap.String("delivery_id", id.String()), zap.Error(err))
+		return nil, contextError(ctx, entities.ErrDatabaseError)
+	}
+	return &delivery, nil
+}
+
+// ClaimDue leases deliveries with SKIP LOCKED so that dispatchers on other
+// replicas claim different rows; a dispatcher that dies mid-delivery leaves
+// the row to be retried once the lease runs out
+func (r *postgresWebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*entities.WebhookDelivery, error) {
+	query := `UPDATE webhook_deliveries
+              SET next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond'
+              WHERE id IN (
+                SELECT d.id FROM webhook_deliveries d
+                JOIN webhooks w ON w.id = d.webhook_id
+                WHERE d.status = 'pending' AND d.next_attempt_at <= CURRENT_TIMESTAMP AND w.active
+                ORDER BY d.next_attempt_at
+                LIMIT $1
+                FOR UPDATE OF d SKIP LOCKED)
+              RETURNING ` + webhookDeliveryColumns
+
+	var deliveries []*entities.WebhookDelivery
+	if err := r.db.SelectContext(ctx, &deliveries, query, limit, lease.Milliseconds()); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error claiming webhook deliveries", zap.Error(err))
+		return nil, contextError(ctx, entities.ErrDatabaseError)
+	}
+	return deliveries, nil
+}
+
+func (r *postgresWebhookRepository) RecordAttempt(ctx context.Context, id uuid.UUID, attempt *entities.WebhookAttempt, retryAt *time.Time) error {
+	status := entities.WebhookDeliveryPending
+	if retryAt == nil {
+		status = entities.WebhookDeliveryFailed
+		if attempt.Succeeded() {
+			status = entities.WebhookDeliverySucceeded
+		}
+	}
+	var responseStatus *int
+	if attempt.ResponseStatus != 0 {
+		responseStatus = &attempt.ResponseStatus
+	}
+	var attemptError *string
+	if attempt.Error != "" {
+		attemptError = &attempt.Error
+	}
+
+	query := `UPDATE webhook_deliveries
+              SET attempts = attempts + 1, status = $2, next_attempt_at = $3, request_headers = $4,
+                  response_status = $5, response_body = $6, error = $7, duration_ms = $8,
+                  delivered_at = CASE WHEN $9 THEN CURRENT_TIMESTAMP ELSE delivered_at END
+              WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id, status, retryAt, attempt.RequestHeaders,
+		responseStatus, attempt.ResponseBody, attemptError, attempt.Duration.Milliseconds(),
+		status == entities.WebhookDeliverySucceeded)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error recording webhook attempt", zap.String("delivery_id", id.String()), zap.Error(err))
+		return contextError(ctx, entities.ErrDatabaseError)
+	}
+	return nil
+}
+
+func (r *postgresWebhookRepository) RecordResult(ctx context.Context, webhookID uuid.UUID, succeeded bool, disableAfter int) (bool, error) {
+	if succeeded {
+		_, err := r.db.ExecContext(ctx,
+			`UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures <> 0`, webhookID)
+		if err != nil {
+			logging.FromContext(ctx, r.logger).Error("Database error resetting webhook failures", zap.String("webhook_id", webhookID.String()), zap.Error(err))
+			return false, contextError(ctx, entities.ErrDatabaseError)
+		}
+		return false, nil
+	}
+
+	// Only the failure that reaches the threshold disables the webhook, so
+	// the caller can log and count each disable once
+	query := `UPDATE webhooks
+              SET consecutive_failures = consecutive_failures + 1,
+                  active = active AND NOT ($2::int > 0 AND consecutive_failures + 1 >= $2::int),
+                  disabled_at = CASE WHEN $2::int > 0 AND consecutive_failures + 1 = $2::int
+                                     THEN CURRENT_TIMESTAMP ELSE disabled_at END
+              WHERE id = $1
+              RETURNING $2::int > 0 AND consecutive_failures = $2::int`
+
+	var disabled bool
+	if err := r.db.GetContext(ctx, &disabled, query, webhookID, disableAfter); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, entities.ErrWebhookNotFound
+		}
+		logging.FromContext(ctx, r.logger).Error("Database error recording webhook failure", zap.String("webhook_id", webhookID.String()), zap.Error(err))
+		return false, contextError(ctx, entities.ErrDatabaseError)
+	}
+	return disabled, nil
+}
+
+func (r *postgresWebhookRepository) getOne(ctx context.Context, query string, args ...interface{}) (*entities.Webhook, error) {
+	var webhook entities.Webhook
+	if err := r.db.GetContext(ctx, &webhook, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entities.ErrWebhookNotFound
+		}
+		logging.FromContext(ctx, r.logger).Error("Database error getting webhook", zap.Error(err))
+		return nil, contextError(ctx, entities.ErrDatabaseError)
+	}
+	return &webhook, nil
+}
+
+func (r *postgresWebhookRepository) list(ctx context.Context, query string, args ...interface{}) ([]*entities.Webhook, error) {
+	var webhooks []*entities.Webhook
+	if err := r.db.SelectContext(ctx, &webhooks, query, args...); err != nil {
+		logging.FromContext(ctx, r.logger).Error("Database error listing webhooks", zap.Error(err))
+		return nil, contextError(ctx, entities.ErrDatabaseError)
+	}
+	return webhooks, nil
+}
@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+
+	"byfood-library/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startQuerySpan starts a client span for a query against table. Only the
+// sanitized statement is recorded, never bound values.
+func startQuerySpan(ctx context.Context, table, query string) (context.Context, trace.Span) {
+	operation := "QUERY"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	return tracing.Start(ctx, operation+" "+table,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBCollectionName(table),
+			semconv.DBQueryText(tracing.SanitizeSQL(query)),
+		),
+	)
+}
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"byfood-library/internal/config"
+	"byfood-library/internal/delivery/grpc/interceptors"
+	"byfood-library/internal/middleware"
+	bookv1 "byfood-library/proto/book/v1"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type GRPCServices struct {
+	BookService bookv1.BookServiceServer
+}
+
+// SetupGRPC builds the gRPC server. Calls are traced, logged, recovered and
+// counted like HTTP requests, and resolve their tenant from metadata; the
+// health and reflection services are exempt from tenant resolution. The
+// returned health server reports SERVING until shutdown begins.
+func SetupGRPC(cfg *config.Config, s *GRPCServices, resolver middleware.TenantResolver, apiKeyHeader string, logger *zap.Logger) (*grpc.Server, *health.Server) {
+	chain := []interceptors.Interceptor{
+		interceptors.Tracing(),
+		interceptors.Logger(logger),
+		interceptors.Recover(logger),
+		interceptors.Metrics(),
+		interceptors.Tenant(resolver, apiKeyHeader, logger, "/grpc.health.", "/grpc.reflection."),
+	}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(interceptors.Unary(chain...)),
+		grpc.ChainStreamInterceptor(interceptors.Stream(chain...)),
+	)
+
+	bookv1.RegisterBookServiceServer(server, s.BookService)
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	healthServer.SetServingStatus(bookv1.BookService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	if cfg.GRPC.Reflection {
+		reflection.Register(server)
+	}
+	return server, healthServer
+}
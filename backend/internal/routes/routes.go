@@ -6,27 +6,128 @@ import (
 	"byfood-library/internal/middleware"
 
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	echoSwagger "github.com/swaggo/echo-swagger"
+	"go.uber.org/zap"
 )
 
 type Handlers struct {
-	BookHandler handlers.BookHandlerInterface
-	URLHandler  handlers.URLHandlerInterface
+	BookHandler   handlers.BookHandlerInterface
+	URLHandler    handlers.URLHandlerInterface
+	TenantHandler handlers.TenantHandlerInterface
+	HealthHandler handlers.HealthHandlerInterface
+	ConfigHandler handlers.ConfigHandlerInterface
+	// WebhookHandler is nil when webhooks are disabled
+	WebhookHandler handlers.WebhookHandlerInterface
+	// StreamHandler is nil when the book stream is disabled
+	StreamHandler handlers.StreamHandlerInterface
+	// GraphQLHandler is nil when GraphQL is disabled
+	GraphQLHandler handlers.GraphQLHandlerInterface
+	// EnrichmentHandler is nil when metadata enrichment is disabled
+	EnrichmentHandler handlers.EnrichmentHandlerInterface
+	// CoverHandler is nil when cover uploads are disabled
+	CoverHandler handlers.CoverHandlerInterface
+	// EbookHandler is nil when ebook files are disabled
+	EbookHandler handlers.EbookHandlerInterface
+	// OPDSHandler is nil when the OPDS catalogue is disabled
+	OPDSHandler handlers.OPDSHandlerInterface
+	// OAIHandler is nil when the OAI-PMH endpoint is disabled
+	OAIHandler handlers.OAIHandlerInterface
+	// SRUHandler is nil when the SRU endpoint is disabled
+	SRUHandler handlers.SRUHandlerInterface
 }
 
-func SetupRoutes(e *echo.Echo, cfg *config.Config, h *Handlers) {
-	// Request ID middleware applied first
-	e.Use(middleware.DefaultMiddleware())
+type Middleware struct {
+	Tenant      *middleware.TenantMiddleware
+	Idempotency *middleware.IdempotencyMiddleware
+}
+
+func SetupRoutes(e *echo.Echo, cfg *config.Config, h *Handlers, m *Middleware, logger *zap.Logger) {
+	// Tenant-aware CORS first, so that tenant resolution errors carry CORS
+	// headers, then tenant resolution and rate limiting
+	e.Use(m.Tenant.CORS())
+	e.Use(m.Tenant.Resolve())
+	e.Use(m.Tenant.RateLimiter())
+
+	// Replay responses for retried POST/PATCH requests carrying an Idempotency-Key
+	e.Use(m.Idempotency.Handler())
 
 	// API version group - new versioned endpoints
 	v1 := e.Group("/api/v1")
 	booksGroup := v1.Group("/books")
 	booksGroup.GET("", h.BookHandler.GetBooks)
 	booksGroup.POST("", h.BookHandler.CreateBook)
+	if h.StreamHandler != nil {
+		booksGroup.GET("/stream", h.StreamHandler.StreamBooks)
+	}
+	booksGroup.GET("/schema", h.BookHandler.GetBookSchema)
+	booksGroup.GET("/duplicates", h.BookHandler.GetDuplicates)
+	booksGroup.POST("/merge", h.BookHandler.MergeBooks)
+	if h.EbookHandler != nil {
+		booksGroup.POST("/files", h.EbookHandler.ImportFile)
+		booksGroup.GET("/:id/files", h.EbookHandler.ListFiles)
+		booksGroup.GET("/:id/files/:fileId", h.EbookHandler.DownloadFile)
+		booksGroup.DELETE("/:id/files/:fileId", h.EbookHandler.DeleteFile)
+	}
+	if h.EnrichmentHandler != nil {
+		booksGroup.GET("/lookup", h.EnrichmentHandler.LookupBook)
+		booksGroup.POST("/:id/enrich", h.EnrichmentHandler.EnrichBook)
+	}
+	if h.CoverHandler != nil {
+		booksGroup.GET("/:id/cover", h.CoverHandler.GetCover)
+		booksGroup.PUT("/:id/cover", h.CoverHandler.UploadCover)
+		booksGroup.DELETE("/:id/cover", h.CoverHandler.DeleteCover)
+	}
 	booksGroup.GET("/:id", h.BookHandler.GetBook)
 	booksGroup.PUT("/:id", h.BookHandler.UpdateBook)
 	booksGroup.DELETE("/:id", h.BookHandler.DeleteBook)
 
+	// Tenant webhook subscriptions and their delivery log
+	if h.WebhookHandler != nil {
+		webhooksGroup := v1.Group("/webhooks")
+		webhooksGroup.GET("", h.WebhookHandler.GetWebhooks)
+		webhooksGroup.POST("", h.WebhookHandler.CreateWebhook)
+		webhooksGroup.GET("/:id", h.WebhookHandler.GetWebhook)
+		webhooksGroup.PUT("/:id", h.WebhookHandler.UpdateWebhook)
+		webhooksGroup.DELETE("/:id", h.WebhookHandler.DeleteWebhook)
+		webhooksGroup.GET("/:id/deliveries", h.WebhookHandler.GetDeliveries)
+		webhooksGroup.GET("/:id/deliveries/:deliveryId", h.WebhookHandler.GetDelivery)
+		webhooksGroup.POST("/:id/deliveries/:deliveryId/redeliver", h.WebhookHandler.Redeliver)
+	}
+
+	// GraphQL over the same book use cases; GET only runs queries
+	if h.GraphQLHandler != nil {
+		e.GET("/graphql", h.GraphQLHandler.Query)
+		e.POST("/graphql", h.GraphQLHandler.Query)
+	}
+
+	// OPDS catalogue feeds for e-reader apps: Atom (OPDS 1.2) and JSON
+	// (OPDS 2.0) served by the same handlers
+	if h.OPDSHandler != nil {
+		for _, prefix := range []string{"/opds", "/opds/v2"} {
+			opdsGroup := e.Group(prefix)
+			opdsGroup.GET("", h.OPDSHandler.Navigation)
+			opdsGroup.GET("/books", h.OPDSHandler.Books)
+			opdsGroup.GET("/authors", h.OPDSHandler.Authors)
+			opdsGroup.GET("/subjects", h.OPDSHandler.Subjects)
+		}
+		e.GET("/opds/opensearch.xml", h.OPDSHandler.OpenSearch)
+	}
+
+	// OAI-PMH for metadata harvesters, which may send the verb and its
+	// arguments either way
+	if h.OAIHandler != nil {
+		e.GET("/oai", h.OAIHandler.Handle)
+		e.POST("/oai", h.OAIHandler.Handle)
+	}
+
+	// SRU for library search clients, which likewise may send the
+	// parameters either way
+	if h.SRUHandler != nil {
+		e.GET("/sru", h.SRUHandler.Handle)
+		e.POST("/sru", h.SRUHandler.Handle)
+	}
+
 	// Legacy routes for backward compatibility with existing frontend
 	e.GET("/books", h.BookHandler.GetBooks)
 	e.POST("/books", h.BookHandler.CreateBook)
@@ -34,6 +135,17 @@ func SetupRoutes(e *echo.Echo, cfg *config.Config, h *Handlers) {
 	e.PUT("/books/:id", h.BookHandler.UpdateBook)
 	e.DELETE("/books/:id", h.BookHandler.DeleteBook)
 
+	// Admin API for managing tenants and inspecting the active configuration
+	admin := e.Group("/admin", middleware.AdminAuth(cfg.Admin.APIKey, logger))
+	tenantsGroup := admin.Group("/tenants")
+	tenantsGroup.GET("", h.TenantHandler.GetTenants)
+	tenantsGroup.POST("", h.TenantHandler.CreateTenant)
+	tenantsGroup.GET("/:id", h.TenantHandler.GetTenant)
+	tenantsGroup.POST("/:id/suspend", h.TenantHandler.SuspendTenant)
+	tenantsGroup.POST("/:id/activate", h.TenantHandler.ActivateTenant)
+	tenantsGroup.PUT("/:id/settings", h.TenantHandler.UpdateTenantSettings)
+	admin.GET("/config", h.ConfigHandler.GetConfig)
+
 	// Swagger documentation with configurable paths
 	if cfg.API.EnableSwagger {
 		e.GET(cfg.API.SwaggerPath+"/*", echoSwagger.WrapHandler)
@@ -43,8 +155,13 @@ func SetupRoutes(e *echo.Echo, cfg *config.Config, h *Handlers) {
 		})
 	}
 
-	// Health check endpoint
-	e.GET("/health", func(c echo.Context) error {
-		return c.JSON(200, map[string]string{"status": "OK"})
-	})
-}
\ No newline at end of file
+	// Prometheus scrape endpoint
+	if cfg.Metrics.Enabled && cfg.Metrics.Path != "" {
+		e.GET(cfg.Metrics.Path, echo.WrapHandler(promhttp.Handler()))
+	}
+
+	// Health probes; /health is kept as an alias of readiness
+	e.GET("/livez", h.HealthHandler.Liveness)
+	e.GET("/readyz", h.HealthHandler.Readiness)
+	e.GET("/health", h.HealthHandler.Readiness)
+}
@@ -0,0 +1,113 @@
+// Package schemaorg describes books with the schema.org vocabulary, for
+// search engines and linked-data consumers: as JSON-LD, where a book is a
+// Book whose author is a Person and a list of books is an ItemList, and as
+// the same statements in Turtle. Books are identified by urn:uuid: IRIs of
+// their IDs, so that the descriptions do not depend on where they are
+// served.
+package schemaorg
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+)
+
+// Context is the JSON-LD context of the descriptions
+const Context = "https://schema.org"
+
+// Book is a book as a schema.org Book in JSON-LD
+type Book struct {
+	Context       string        `json:"@context,omitempty"`
+	Type          string        `json:"@type"`
+	ID            string        `json:"@id"`
+	Name          string        `json:"name"`
+	Author        *Person       `json:"author,omitempty"`
+	DatePublished string        `json:"datePublished,omitempty"`
+	ISBN          string        `json:"isbn,omitempty"`
+	Publisher     *Organization `json:"publisher,omitempty"`
+	NumberOfPages int           `json:"numberOfPages,omitempty"`
+	Keywords      []string      `json:"keywords,omitempty"`
+	Description   string        `json:"description,omitempty"`
+	Image         string        `json:"image,omitempty"`
+	DateCreated   string        `json:"dateCreated"`
+	DateModified  string        `json:"dateModified"`
+}
+
+type Person struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+type Organization struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// ItemList is a list of books in JSON-LD
+type ItemList struct {
+	Context         string     `json:"@context"`
+	Type            string     `json:"@type"`
+	NumberOfItems   int        `json:"numberOfItems"`
+	ItemListElement []ListItem `json:"itemListElement"`
+}
+
+type ListItem struct {
+	Type     string `json:"@type"`
+	Position int    `json:"position"`
+	Item     *Book  `json:"item"`
+}
+
+// IRI identifies a book in the descriptions
+func IRI(book *entities.Book) string {
+	return "urn:uuid:" + book.ID.String()
+}
+
+// BookOf describes a book, without a context so that it can be nested
+func BookOf(book *entities.Book) *Book {
+	described := &Book{
+		Type:          "Book",
+		ID:            IRI(book),
+		Name:          book.Title,
+		ISBN:          book.ISBN,
+		NumberOfPages: book.PageCount,
+		Keywords:      book.Subjects,
+		Description:   book.Description,
+		Image:         book.CoverURL,
+		DateCreated:   book.CreatedAt.UTC().Format(time.RFC3339),
+		DateModified:  book.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if book.Author != "" {
+		described.Author = &Person{Type: "Person", Name: book.Author}
+	}
+	if book.Publisher != "" {
+		described.Publisher = &Organization{Type: "Organization", Name: book.Publisher}
+	}
+	if book.Year > 0 {
+		described.DatePublished = strconv.Itoa(book.Year)
+	}
+	return described
+}
+
+// WriteBook writes a book as a JSON-LD document
+func WriteBook(w io.Writer, book *entities.Book) error {
+	described := BookOf(book)
+	described.Context = Context
+	return json.NewEncoder(w).Encode(described)
+}
+
+// WriteBooks writes books as a JSON-LD document of an ItemList
+func WriteBooks(w io.Writer, books []*entities.Book) error {
+	list := &ItemList{
+		Context:         Context,
+		Type:            "ItemList",
+		NumberOfItems:   len(books),
+		ItemListElement: make([]ListItem, len(books)),
+	}
+	for i, book := range books {
+		list.ItemListElement[i] = ListItem{Type: "ListItem", Position: i + 1, Item: BookOf(book)}
+	}
+	return json.NewEncoder(w).Encode(list)
+}
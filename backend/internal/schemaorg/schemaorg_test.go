@@ -0,0 +1,112 @@
+package schemaorg
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var book = &entities.Book{
+	ID:     uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+	Title:  "Clean \"Code\"",
+	Author: "Robert C. Martin",
+	Year:   2008,
+	ISBN:   "9780132350884",
+	BookMetadata: entities.BookMetadata{
+		Publisher: "Prentice Hall",
+		PageCount: 464,
+		Subjects:  pq.StringArray{"Software", "Agile"},
+		CoverURL:  "https://covers.test/clean code.jpg",
+	},
+	CreatedAt: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+	UpdatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+func TestWriteBook(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WriteBook(&out, book))
+
+	assert.JSONEq(t, `{
+		"@context": "https://schema.org",
+		"@type": "Book",
+		"@id": "urn:uuid:11111111-1111-1111-1111-111111111111",
+		"name": "Clean \"Code\"",
+		"author": {"@type": "Person", "name": "Robert C. Martin"},
+		"datePublished": "2008",
+		"isbn": "9780132350884",
+		"publisher": {"@type": "Organization", "name": "Prentice Hall"},
+		"numberOfPages": 464,
+		"keywords": ["Software", "Agile"],
+		"image": "https://covers.test/clean code.jpg",
+		"dateCreated": "2024-05-06T07:08:09Z",
+		"dateModified": "2025-01-02T03:04:05Z"
+	}`, out.String())
+}
+
+func TestWriteBooks(t *testing.T) {
+	bare := &entities.Book{ID: uuid.MustParse("22222222-2222-2222-2222-222222222222"), Title: "Untitled"}
+
+	var out bytes.Buffer
+	require.NoError(t, WriteBooks(&out, []*entities.Book{book, bare}))
+
+	assert.JSONEq(t, `{
+		"@context": "https://schema.org",
+		"@type": "ItemList",
+		"numberOfItems": 2,
+		"itemListElement": [
+			{"@type": "ListItem", "position": 1, "item": {
+				"@type": "Book",
+				"@id": "urn:uuid:11111111-1111-1111-1111-111111111111",
+				"name": "Clean \"Code\"",
+				"author": {"@type": "Person", "name": "Robert C. Martin"},
+				"datePublished": "2008",
+				"isbn": "9780132350884",
+				"publisher": {"@type": "Organization", "name": "Prentice Hall"},
+				"numberOfPages": 464,
+				"keywords": ["Software", "Agile"],
+				"image": "https://covers.test/clean code.jpg",
+				"dateCreated": "2024-05-06T07:08:09Z",
+				"dateModified": "2025-01-02T03:04:05Z"
+			}},
+			{"@type": "ListItem", "position": 2, "item": {
+				"@type": "Book",
+				"@id": "urn:uuid:22222222-2222-2222-2222-222222222222",
+				"name": "Untitled",
+				"dateCreated": "0001-01-01T00:00:00Z",
+				"dateModified": "0001-01-01T00:00:00Z"
+			}}
+		]
+	}`, out.String())
+}
+
+func TestWriteTurtle(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WriteTurtle(&out, []*entities.Book{book}))
+
+	assert.Equal(t, `@prefix schema: <https://schema.org/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+<urn:uuid:11111111-1111-1111-1111-111111111111> a schema:Book ;
+    schema:name "Clean \"Code\"" ;
+    schema:author [ a schema:Person ; schema:name "Robert C. Martin" ] ;
+    schema:datePublished "2008"^^xsd:gYear ;
+    schema:isbn "9780132350884" ;
+    schema:publisher [ a schema:Organization ; schema:name "Prentice Hall" ] ;
+    schema:numberOfPages 464 ;
+    schema:keywords "Software" ;
+    schema:keywords "Agile" ;
+    schema:image <https://covers.test/clean%20code.jpg> ;
+    schema:dateCreated "2024-05-06T07:08:09Z"^^xsd:dateTime ;
+    schema:dateModified "2025-01-02T03:04:05Z"^^xsd:dateTime .
+`, out.String())
+}
+
+func TestTurtleString(t *testing.T) {
+	assert.Equal(t, `"a \"b\"\n\\c"`, turtleString("a \"b\"\n\\c"))
+}
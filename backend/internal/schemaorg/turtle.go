@@ -0,0 +1,80 @@
+package schemaorg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+)
+
+const turtlePrefixes = `@prefix schema: <https://schema.org/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+`
+
+// WriteTurtle writes the books as Turtle, with the statements of their
+// JSON-LD descriptions; the authors and publishers are blank nodes
+func WriteTurtle(w io.Writer, books []*entities.Book) error {
+	b := bufio.NewWriter(w)
+	b.WriteString(turtlePrefixes)
+	for _, book := range books {
+		b.WriteString("\n<" + IRI(book) + "> a schema:Book")
+		statement := func(predicate, object string) {
+			b.WriteString(" ;\n    schema:" + predicate + " " + object)
+		}
+
+		statement("name", turtleString(book.Title))
+		if book.Author != "" {
+			statement("author", "[ a schema:Person ; schema:name "+turtleString(book.Author)+" ]")
+		}
+		if book.Year > 0 {
+			statement("datePublished", turtleString(strconv.Itoa(book.Year))+"^^xsd:gYear")
+		}
+		if book.ISBN != "" {
+			statement("isbn", turtleString(book.ISBN))
+		}
+		if book.Publisher != "" {
+			statement("publisher", "[ a schema:Organization ; schema:name "+turtleString(book.Publisher)+" ]")
+		}
+		if book.PageCount > 0 {
+			statement("numberOfPages", strconv.Itoa(book.PageCount))
+		}
+		for _, subject := range book.Subjects {
+			statement("keywords", turtleString(subject))
+		}
+		if book.Description != "" {
+			statement("description", turtleString(book.Description))
+		}
+		if book.CoverURL != "" {
+			statement("image", "<"+turtleIRI(book.CoverURL)+">")
+		}
+		statement("dateCreated", turtleString(book.CreatedAt.UTC().Format(time.RFC3339))+"^^xsd:dateTime")
+		statement("dateModified", turtleString(book.UpdatedAt.UTC().Format(time.RFC3339))+"^^xsd:dateTime")
+		b.WriteString(" .\n")
+	}
+	return b.Flush()
+}
+
+// turtleEscaper escapes the characters a quoted Turtle string must not hold
+var turtleEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+func turtleString(s string) string {
+	return `"` + turtleEscaper.Replace(s) + `"`
+}
+
+// turtleIRI escapes the characters an IRI reference must not hold, which a
+// URL accepted as a cover URL should not have anyway
+func turtleIRI(iri string) string {
+	var b strings.Builder
+	for _, r := range iri {
+		if r <= 0x20 || strings.ContainsRune(`<>"{}|^`+"`"+`\`, r) {
+			fmt.Fprintf(&b, "%%%02X", r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,125 @@
+package sru
+
+import (
+	"fmt"
+
+	"byfood-library/internal/cql"
+)
+
+// Diagnostic reports why a request could not be answered as asked
+type Diagnostic struct {
+	// URI identifies the condition in the SRU diagnostics list
+	URI string `xml:"diag:uri"`
+	// Details is the offending part of the request, such as a parameter
+	// name or an index
+	Details string `xml:"diag:details,omitempty"`
+	Message string `xml:"diag:message,omitempty"`
+}
+
+// Codes of the SRU diagnostics list
+const (
+	DiagGeneralError           = 1
+	DiagUnsupportedOperation   = 4
+	DiagUnsupportedVersion     = 5
+	DiagUnsupportedValue       = 6
+	DiagMissingParameter       = 7
+	DiagUnsupportedParameter   = 8
+	DiagQuerySyntax            = 10
+	DiagContextSet             = 15
+	DiagIndex                  = 16
+	DiagRelation               = 19
+	DiagRelationModifier       = 20
+	DiagRelationForIndex       = 22
+	DiagEmptyTerm              = 27
+	DiagAnchoring              = 32
+	DiagTermFormat             = 36
+	DiagTooManyBooleans        = 38
+	DiagProximity              = 39
+	DiagProximityRelation      = 40
+	DiagProximityDistance      = 41
+	DiagProximityUnit          = 42
+	DiagBooleanModifier        = 46
+	DiagQueryFeature           = 48
+	DiagStartRecordOutOfRange  = 61
+	DiagUnknownSchema          = 66
+	DiagUnsupportedXMLEscaping = 71
+)
+
+var diagnosticMessages = map[int]string{
+	DiagGeneralError:           "General system error",
+	DiagUnsupportedOperation:   "Unsupported operation",
+	DiagUnsupportedVersion:     "Unsupported version",
+	DiagUnsupportedValue:       "Unsupported parameter value",
+	DiagMissingParameter:       "Mandatory parameter not supplied",
+	DiagUnsupportedParameter:   "Unsupported parameter",
+	DiagQuerySyntax:            "Query syntax error",
+	DiagContextSet:             "Unsupported context set",
+	DiagIndex:                  "Unsupported index",
+	DiagRelation:               "Unsupported relation",
+	DiagRelationModifier:       "Unsupported relation modifier",
+	DiagRelationForIndex:       "Unsupported combination of relation and index",
+	DiagEmptyTerm:              "Empty term unsupported",
+	DiagAnchoring:              "Anchoring character in unsupported position",
+	DiagTermFormat:             "Term in invalid format for index or relation",
+	DiagTooManyBooleans:        "Too many boolean operators in query",
+	DiagProximity:              "Proximity not supported",
+	DiagProximityRelation:      "Unsupported proximity relation",
+	DiagProximityDistance:      "Unsupported proximity distance",
+	DiagProximityUnit:          "Unsupported proximity unit",
+	DiagBooleanModifier:        "Unsupported boolean modifier",
+	DiagQueryFeature:           "Query feature unsupported",
+	DiagStartRecordOutOfRange:  "First record position out of range",
+	DiagUnknownSchema:          "Unknown schema for retrieval",
+	DiagUnsupportedXMLEscaping: "Unsupported record packing",
+}
+
+// featureDiagnostics maps what a query cannot use to its diagnostic
+var featureDiagnostics = map[cql.Feature]int{
+	cql.FeatureContextSet:        DiagContextSet,
+	cql.FeatureIndex:             DiagIndex,
+	cql.FeatureRelation:          DiagRelation,
+	cql.FeatureRelationModifier:  DiagRelationModifier,
+	cql.FeatureRelationForIndex:  DiagRelationForIndex,
+	cql.FeatureTerm:              DiagTermFormat,
+	cql.FeatureEmptyTerm:         DiagEmptyTerm,
+	cql.FeatureAnchoring:         DiagAnchoring,
+	cql.FeatureBooleanModifier:   DiagBooleanModifier,
+	cql.FeatureBooleans:          DiagTooManyBooleans,
+	cql.FeatureProximity:         DiagProximity,
+	cql.FeatureProximityRelation: DiagProximityRelation,
+	cql.FeatureProximityDistance: DiagProximityDistance,
+	cql.FeatureProximityUnit:     DiagProximityUnit,
+	cql.FeatureSortIndex:         DiagIndex,
+	cql.FeatureSortModifier:      DiagQueryFeature,
+}
+
+// diagnosticError is a request that is answered with a fatal diagnostic
+type diagnosticError struct {
+	code    int
+	details string
+}
+
+func diagnostic(code int, details string) *diagnosticError {
+	return &diagnosticError{code: code, details: details}
+}
+
+func (e *diagnosticError) Error() string {
+	return fmt.Sprintf("SRU diagnostic %d: %s", e.code, e.details)
+}
+
+func (e *diagnosticError) diagnostic() Diagnostic {
+	return Diagnostic{
+		URI:     fmt.Sprintf("info:srw/diagnostic/1/%d", e.code),
+		Details: e.details,
+		Message: diagnosticMessages[e.code],
+	}
+}
+
+// unsupportedDiagnostic maps what a query cannot use to its diagnostic
+func unsupportedDiagnostic(err *cql.UnsupportedError) *diagnosticError {
+	code, ok := featureDiagnostics[err.Feature]
+	if !ok {
+		code = DiagQueryFeature
+	}
+	return diagnostic(code, err.Value)
+}
@@ -0,0 +1,157 @@
+package sru
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"byfood-library/internal/cql"
+)
+
+// Explain is a ZeeRex record describing the service: where it is, what it
+// holds, which indexes a query can search and the schemas of its records
+type Explain struct {
+	XMLName xml.Name `xml:"zr:explain"`
+	XmlnsZR string   `xml:"xmlns:zr,attr"`
+
+	ServerInfo   ServerInfo   `xml:"zr:serverInfo"`
+	DatabaseInfo DatabaseInfo `xml:"zr:databaseInfo"`
+	IndexInfo    IndexInfo    `xml:"zr:indexInfo"`
+	SchemaInfo   SchemaInfo   `xml:"zr:schemaInfo"`
+	ConfigInfo   ConfigInfo   `xml:"zr:configInfo"`
+}
+
+type ServerInfo struct {
+	Protocol  string `xml:"protocol,attr"`
+	Version   string `xml:"version,attr"`
+	Transport string `xml:"transport,attr"`
+	Host      string `xml:"zr:host"`
+	Port      int    `xml:"zr:port"`
+	Database  string `xml:"zr:database"`
+}
+
+type DatabaseInfo struct {
+	Title Text `xml:"zr:title"`
+}
+
+// Text is human readable text in a language
+type Text struct {
+	Lang    string `xml:"lang,attr"`
+	Primary bool   `xml:"primary,attr"`
+	Value   string `xml:",chardata"`
+}
+
+type IndexInfo struct {
+	Sets    []Set   `xml:"zr:set"`
+	Indexes []Index `xml:"zr:index"`
+}
+
+// Set binds a prefix of the index names to its context set
+type Set struct {
+	Name       string `xml:"name,attr"`
+	Identifier string `xml:"identifier,attr"`
+}
+
+type Index struct {
+	Search bool   `xml:"search,attr"`
+	Sort   bool   `xml:"sort,attr"`
+	Title  string `xml:"zr:title"`
+	Map    struct {
+		Name IndexName `xml:"zr:name"`
+	} `xml:"zr:map"`
+}
+
+type IndexName struct {
+	Set  string `xml:"set,attr"`
+	Name string `xml:",chardata"`
+}
+
+type SchemaInfo struct {
+	Schemas []Schema `xml:"zr:schema"`
+}
+
+type Schema struct {
+	Identifier string `xml:"identifier,attr"`
+	Name       string `xml:"name,attr"`
+	Title      string `xml:"zr:title"`
+}
+
+type ConfigInfo struct {
+	Defaults []Setting `xml:"zr:default"`
+	Settings []Setting `xml:"zr:setting"`
+}
+
+type Setting struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// explainSets are the context sets of the indexes
+var explainSets = []Set{
+	{Name: "cql", Identifier: cql.ContextSetCQL},
+	{Name: "dc", Identifier: cql.ContextSetDC},
+	{Name: "rec", Identifier: cql.ContextSetRec},
+}
+
+// explainIndexes are the indexes the catalogue searches, as set, name,
+// title and whether it sorts by them
+var explainIndexes = []struct {
+	set, name, title string
+	sort             bool
+}{
+	{"cql", "serverChoice", "title or author", false},
+	{"dc", "title", "title", true},
+	{"dc", "creator", "author", true},
+	{"dc", "publisher", "publisher", true},
+	{"dc", "description", "description", false},
+	{"dc", "subject", "subject", false},
+	{"dc", "date", "year of publication", true},
+	{"dc", "identifier", "ISBN, or urn:uuid: book ID", false},
+	{"rec", "identifier", "book ID", false},
+}
+
+// explainRecord describes the service at baseURL
+func (p *Provider) explainRecord(baseURL string) *Record {
+	explain := &Explain{
+		XmlnsZR:      nsExplain,
+		ServerInfo:   serverInfo(baseURL),
+		DatabaseInfo: DatabaseInfo{Title: Text{Lang: "en", Primary: true, Value: p.config.Title}},
+		IndexInfo:    IndexInfo{Sets: explainSets},
+		SchemaInfo: SchemaInfo{Schemas: []Schema{
+			{Identifier: SchemaDublinCore, Name: "dc", Title: "Dublin Core"},
+			{Identifier: SchemaMARC, Name: "marcxml", Title: "MARC 21 in MARCXML"},
+		}},
+		ConfigInfo: ConfigInfo{
+			Defaults: []Setting{{Type: "numberOfRecords", Value: strconv.Itoa(p.config.DefaultRecords)}},
+			Settings: []Setting{{Type: "maximumRecords", Value: strconv.Itoa(p.config.MaxRecords)}},
+		},
+	}
+	for _, index := range explainIndexes {
+		entry := Index{Search: true, Sort: index.sort, Title: index.title}
+		entry.Map.Name = IndexName{Set: index.set, Name: index.name}
+		explain.IndexInfo.Indexes = append(explain.IndexInfo.Indexes, entry)
+	}
+	return &Record{Schema: nsExplain, XMLEscaping: xmlEscapingXML, Data: RecordData{XML: explain}}
+}
+
+// serverInfo splits the base URL into the host, port and path that ZeeRex
+// describes the server by
+func serverInfo(baseURL string) ServerInfo {
+	info := ServerInfo{Protocol: "SRU", Version: supportedVersion, Transport: "http"}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return info
+	}
+	info.Transport = u.Scheme
+	info.Host = u.Hostname()
+	info.Port, _ = strconv.Atoi(u.Port())
+	if info.Port == 0 {
+		info.Port = 80
+		if u.Scheme == "https" {
+			info.Port = 443
+		}
+	}
+	info.Database = strings.TrimPrefix(u.Path, "/")
+	return info
+}
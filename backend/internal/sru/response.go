@@ -0,0 +1,86 @@
+package sru
+
+import (
+	"encoding/xml"
+
+	"byfood-library/internal/oai"
+)
+
+// Namespaces of the SRU responses and their records
+const (
+	nsResponse   = "http://docs.oasis-open.org/ns/search-ws/sruResponse"
+	nsDiagnostic = "http://docs.oasis-open.org/ns/search-ws/diagnostic"
+	nsDCSchema   = "info:srw/schema/1/dc-schema"
+	nsExplain    = "http://explain.z3950.org/dtd/2.0/"
+)
+
+// ContentType is the media type of the responses
+const ContentType = "application/sru+xml; charset=utf-8"
+
+// resultCountPrecisionExact says numberOfRecords is the exact count
+const resultCountPrecisionExact = "info:srw/vocabulary/resultCountPrecision/1/exact"
+
+// Response is a *SearchRetrieveResponse or an *ExplainResponse
+type Response interface {
+	response()
+}
+
+// SearchRetrieveResponse answers a searchRetrieve request: the number of
+// matching books and a page of them as records, or fatal diagnostics
+type SearchRetrieveResponse struct {
+	XMLName   xml.Name `xml:"sru:searchRetrieveResponse"`
+	XmlnsSRU  string   `xml:"xmlns:sru,attr"`
+	XmlnsDiag string   `xml:"xmlns:diag,attr"`
+
+	NumberOfRecords      int          `xml:"sru:numberOfRecords"`
+	Records              *Records     `xml:"sru:records,omitempty"`
+	NextRecordPosition   int          `xml:"sru:nextRecordPosition,omitempty"`
+	Diagnostics          *Diagnostics `xml:"sru:diagnostics,omitempty"`
+	ResultCountPrecision string       `xml:"sru:resultCountPrecision,omitempty"`
+}
+
+// ExplainResponse answers an explain request with a ZeeRex record that
+// describes the service
+type ExplainResponse struct {
+	XMLName   xml.Name `xml:"sru:explainResponse"`
+	XmlnsSRU  string   `xml:"xmlns:sru,attr"`
+	XmlnsDiag string   `xml:"xmlns:diag,attr"`
+
+	Record      *Record      `xml:"sru:record,omitempty"`
+	Diagnostics *Diagnostics `xml:"sru:diagnostics,omitempty"`
+}
+
+func (*SearchRetrieveResponse) response() {}
+func (*ExplainResponse) response()        {}
+
+type Records struct {
+	Records []Record `xml:"sru:record"`
+}
+
+// Record is a book, or the description of the service, in a record schema
+type Record struct {
+	Schema      string `xml:"sru:recordSchema"`
+	XMLEscaping string `xml:"sru:recordXMLEscaping"`
+	Data        RecordData
+	Position    int `xml:"sru:recordPosition,omitempty"`
+}
+
+// RecordData holds the record as XML, or as an escaped string of XML
+type RecordData struct {
+	XMLName xml.Name    `xml:"sru:recordData"`
+	XML     interface{} `xml:",omitempty"`
+	String  string      `xml:",chardata"`
+}
+
+type Diagnostics struct {
+	Diagnostics []Diagnostic `xml:"diag:diagnostic"`
+}
+
+// DublinCore is a book in the SRU Dublin Core schema. It declares its
+// namespaces, so that it can be embedded in any document.
+type DublinCore struct {
+	XMLName xml.Name `xml:"srw_dc:dc"`
+	XmlnsDC string   `xml:"xmlns:srw_dc,attr"`
+	XmlnsDE string   `xml:"xmlns:dc,attr"`
+	oai.DCElements
+}
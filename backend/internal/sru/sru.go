@@ -0,0 +1,295 @@
+// Package sru is an SRU 2.0 server for the catalogue. It answers
+// searchRetrieve requests, whose CQL queries are parsed by package cql and
+// run by the catalogue, with the matching books as Dublin Core or MARCXML
+// records, and explain requests with a ZeeRex record describing the
+// indexes, schemas and limits of the service. What a request asks for but
+// the service cannot do is reported as SRU diagnostics in the response.
+package sru
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"byfood-library/internal/cql"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/oai"
+	"byfood-library/internal/usecases"
+)
+
+const (
+	DefaultTitle      = "Library"
+	DefaultRecords    = 10
+	DefaultMaxRecords = 100
+	MaxRecordsLimit   = 1000
+)
+
+const (
+	supportedVersion = "2.0"
+	operationSearch  = "searchRetrieve"
+	operationExplain = "explain"
+	queryTypeCQL     = "cql"
+	// Records are XML in the response, or strings of escaped XML
+	xmlEscapingXML    = "xml"
+	xmlEscapingString = "string"
+	// packed records are in their schema; unpacked ones are not supported
+	recordPackingPacked = "packed"
+	// extensionPrefix starts the names of extension parameters, which are
+	// ignored
+	extensionPrefix = "x-"
+)
+
+// Identifiers of the record schemas
+const (
+	SchemaDublinCore = "info:srw/schema/1/dc-v1.1"
+	SchemaMARC       = "info:srw/schema/1/marcxml-v1.1"
+)
+
+// recordSchemas are the record schemas by short name and by identifier
+var recordSchemas = map[string]string{
+	"dc":             SchemaDublinCore,
+	"marcxml":        SchemaMARC,
+	SchemaDublinCore: SchemaDublinCore,
+	SchemaMARC:       SchemaMARC,
+}
+
+// parameters are the request parameters the service understands; the
+// others are unsupported, unless they are extensions
+var parameters = map[string]bool{
+	"operation":         true,
+	"version":           true,
+	"query":             true,
+	"queryType":         true,
+	"startRecord":       true,
+	"maximumRecords":    true,
+	"recordSchema":      true,
+	"recordXMLEscaping": true,
+	"recordPacking":     true,
+	// Result sets are not kept, so how long they should be is moot
+	"resultSetTTL": true,
+}
+
+// Config describes the service. Zero values use the defaults.
+type Config struct {
+	// Title is the human readable name of the database
+	Title string
+	// DefaultRecords is the number of records of a response when the
+	// request does not say
+	DefaultRecords int
+	// MaxRecords bounds the number of records of a response
+	MaxRecords int
+}
+
+func (c Config) withDefaults() Config {
+	if c.Title == "" {
+		c.Title = DefaultTitle
+	}
+	if c.MaxRecords <= 0 {
+		c.MaxRecords = DefaultMaxRecords
+	}
+	if c.MaxRecords > MaxRecordsLimit {
+		c.MaxRecords = MaxRecordsLimit
+	}
+	if c.DefaultRecords <= 0 {
+		c.DefaultRecords = DefaultRecords
+	}
+	if c.DefaultRecords > c.MaxRecords {
+		c.DefaultRecords = c.MaxRecords
+	}
+	return c
+}
+
+// Provider answers SRU requests
+type Provider struct {
+	catalog usecases.CatalogUseCase
+	config  Config
+}
+
+func NewProvider(catalog usecases.CatalogUseCase, config Config) *Provider {
+	return &Provider{
+		catalog: catalog,
+		config:  config.withDefaults(),
+	}
+}
+
+// Handle answers the request whose parameters are args, made to the
+// service at baseURL. A request with a query is a searchRetrieve request
+// and one without is an explain request, unless the operation parameter of
+// SRU 1.x says otherwise. Diagnostics are reported in the response; an
+// error is returned only when the catalogue cannot be read.
+func (p *Provider) Handle(ctx context.Context, baseURL string, args url.Values) (Response, error) {
+	operation := args.Get("operation")
+	if operation == "" {
+		operation = operationExplain
+		if args.Has("query") {
+			operation = operationSearch
+		}
+	}
+
+	if operation == operationExplain {
+		response := &ExplainResponse{XmlnsSRU: nsResponse, XmlnsDiag: nsDiagnostic}
+		if err := checkParameters(args); err != nil {
+			response.Diagnostics = &Diagnostics{Diagnostics: []Diagnostic{err.diagnostic()}}
+			return response, nil
+		}
+		response.Record = p.explainRecord(baseURL)
+		return response, nil
+	}
+
+	response := &SearchRetrieveResponse{XmlnsSRU: nsResponse, XmlnsDiag: nsDiagnostic}
+	err := p.searchRetrieve(ctx, operation, args, response)
+	var diagErr *diagnosticError
+	if errors.As(err, &diagErr) {
+		response.Diagnostics = &Diagnostics{Diagnostics: []Diagnostic{diagErr.diagnostic()}}
+		return response, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// checkParameters reports the first parameter or value the service does
+// not support
+func checkParameters(args url.Values) *diagnosticError {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	// Report the same parameter whatever the order of the map
+	sort.Strings(names)
+	for _, name := range names {
+		if !parameters[name] && !strings.HasPrefix(name, extensionPrefix) {
+			return diagnostic(DiagUnsupportedParameter, name)
+		}
+	}
+	if version := args.Get("version"); version != "" && version != supportedVersion {
+		return diagnostic(DiagUnsupportedVersion, supportedVersion)
+	}
+	if escaping := args.Get("recordXMLEscaping"); escaping != "" && escaping != xmlEscapingXML && escaping != xmlEscapingString {
+		return diagnostic(DiagUnsupportedXMLEscaping, escaping)
+	}
+	if packing := args.Get("recordPacking"); packing != "" && packing != recordPackingPacked {
+		return diagnostic(DiagUnsupportedXMLEscaping, packing)
+	}
+	return nil
+}
+
+func (p *Provider) searchRetrieve(ctx context.Context, operation string, args url.Values, response *SearchRetrieveResponse) error {
+	if operation != operationSearch {
+		return diagnostic(DiagUnsupportedOperation, operation)
+	}
+	if err := checkParameters(args); err != nil {
+		return err
+	}
+	if queryType := args.Get("queryType"); queryType != "" && queryType != queryTypeCQL {
+		return diagnostic(DiagUnsupportedValue, "queryType")
+	}
+	queryText := args.Get("query")
+	if queryText == "" {
+		return diagnostic(DiagMissingParameter, "query")
+	}
+	start, err := positiveParameter(args, "startRecord", 1, 1)
+	if err != nil {
+		return err
+	}
+	maximum, err := positiveParameter(args, "maximumRecords", p.config.DefaultRecords, 0)
+	if err != nil {
+		return err
+	}
+	if maximum > p.config.MaxRecords {
+		maximum = p.config.MaxRecords
+	}
+	schema := SchemaDublinCore
+	if name := args.Get("recordSchema"); name != "" {
+		var ok bool
+		if schema, ok = recordSchemas[name]; !ok {
+			return diagnostic(DiagUnknownSchema, name)
+		}
+	}
+	escaping := args.Get("recordXMLEscaping")
+	if escaping == "" {
+		escaping = xmlEscapingXML
+	}
+
+	query, err := cql.Parse(queryText)
+	var syntaxErr *cql.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return diagnostic(DiagQuerySyntax, syntaxErr.Message)
+	}
+	var unsupported *cql.UnsupportedError
+	if errors.As(err, &unsupported) {
+		return unsupportedDiagnostic(unsupported)
+	}
+	if err != nil {
+		return err
+	}
+
+	page, err := p.catalog.SearchCQL(ctx, query, start-1, maximum)
+	if errors.As(err, &unsupported) {
+		return unsupportedDiagnostic(unsupported)
+	}
+	if err != nil {
+		return err
+	}
+
+	response.NumberOfRecords = page.Total
+	response.ResultCountPrecision = resultCountPrecisionExact
+	if start > 1 && start > page.Total {
+		return diagnostic(DiagStartRecordOutOfRange, strconv.Itoa(start))
+	}
+	if len(page.Books) == 0 {
+		return nil
+	}
+	response.Records = &Records{}
+	for i, book := range page.Books {
+		record, err := bookRecord(book, schema, escaping)
+		if err != nil {
+			return err
+		}
+		record.Position = start + i
+		response.Records.Records = append(response.Records.Records, *record)
+	}
+	if next := start + len(page.Books); next <= page.Total {
+		response.NextRecordPosition = next
+	}
+	return nil
+}
+
+// positiveParameter reads a whole number parameter of at least min
+func positiveParameter(args url.Values, name string, fallback, min int) (int, error) {
+	value := args.Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min {
+		return 0, diagnostic(DiagUnsupportedValue, name)
+	}
+	return n, nil
+}
+
+// bookRecord renders a book in the schema, as XML or as a string of XML
+func bookRecord(book *entities.Book, schema, escaping string) (*Record, error) {
+	var data interface{}
+	if schema == SchemaMARC {
+		data = oai.MARCOf(book)
+	} else {
+		data = &DublinCore{XmlnsDC: nsDCSchema, XmlnsDE: oai.NamespaceDCElements, DCElements: oai.DCElementsOf(book)}
+	}
+	record := &Record{Schema: schema, XMLEscaping: escaping}
+	if escaping == xmlEscapingString {
+		encoded, err := xml.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		record.Data.String = string(encoded)
+		return record, nil
+	}
+	record.Data.XML = data
+	return record, nil
+}
@@ -0,0 +1,188 @@
+package sru
+
+import (
+	"context"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"byfood-library/internal/cql"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseURL = "https://library.test/sru"
+
+var (
+	bookID = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	book   = &entities.Book{
+		ID:     bookID,
+		Title:  "Clean Code",
+		Author: "Robert C. Martin",
+		Year:   2008,
+		ISBN:   "9780132350884",
+		BookMetadata: entities.BookMetadata{
+			Publisher: "Prentice Hall",
+			Subjects:  pq.StringArray{"Software"},
+		},
+		CreatedAt: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		UpdatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+)
+
+// fakeCatalog answers every CQL search with its page, or its error, and
+// remembers the searches it was asked
+type fakeCatalog struct {
+	usecases.CatalogUseCase
+	page    *entities.BookPage
+	err     error
+	queries []string
+	offsets []int
+	limits  []int
+}
+
+func (f *fakeCatalog) SearchCQL(ctx context.Context, query *cql.Query, offset, limit int) (*entities.BookPage, error) {
+	f.queries = append(f.queries, query.String())
+	f.offsets = append(f.offsets, offset)
+	f.limits = append(f.limits, limit)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.page, nil
+}
+
+func handle(t *testing.T, catalog *fakeCatalog, args url.Values) string {
+	t.Helper()
+	provider := NewProvider(catalog, Config{Title: "Test Library", DefaultRecords: 2, MaxRecords: 5})
+	response, err := provider.Handle(context.Background(), baseURL, args)
+	require.NoError(t, err)
+	data, err := xml.Marshal(response)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestHandle_SearchRetrieve(t *testing.T) {
+	t.Run("Dublin Core records", func(t *testing.T) {
+		catalog := &fakeCatalog{page: &entities.BookPage{Books: []*entities.Book{book}, Total: 3}}
+
+		body := handle(t, catalog, url.Values{"query": {`dc.title = clean`}, "startRecord": {"2"}, "maximumRecords": {"1"}})
+
+		assert.Equal(t, []string{`dc.title = "clean"`}, catalog.queries)
+		assert.Equal(t, []int{1}, catalog.offsets)
+		assert.Equal(t, []int{1}, catalog.limits)
+		assert.Contains(t, body, `<sru:searchRetrieveResponse xmlns:sru="http://docs.oasis-open.org/ns/search-ws/sruResponse"`)
+		assert.Contains(t, body, `<sru:numberOfRecords>3</sru:numberOfRecords>`)
+		assert.Contains(t, body, `<sru:recordSchema>info:srw/schema/1/dc-v1.1</sru:recordSchema><sru:recordXMLEscaping>xml</sru:recordXMLEscaping>`)
+		assert.Contains(t, body, `<sru:recordData><srw_dc:dc xmlns:srw_dc="info:srw/schema/1/dc-schema" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Clean Code</dc:title><dc:creator>Robert C. Martin</dc:creator>`)
+		assert.Contains(t, body, `<dc:identifier>urn:isbn:9780132350884</dc:identifier></srw_dc:dc></sru:recordData><sru:recordPosition>2</sru:recordPosition>`)
+		assert.Contains(t, body, `<sru:nextRecordPosition>3</sru:nextRecordPosition>`)
+		assert.Contains(t, body, `<sru:resultCountPrecision>info:srw/vocabulary/resultCountPrecision/1/exact</sru:resultCountPrecision>`)
+		assert.NotContains(t, body, `<sru:diagnostics>`)
+	})
+
+	t.Run("MARCXML records as strings", func(t *testing.T) {
+		catalog := &fakeCatalog{page: &entities.BookPage{Books: []*entities.Book{book}, Total: 1}}
+
+		body := handle(t, catalog, url.Values{"query": {"clean"}, "recordSchema": {"marcxml"}, "recordXMLEscaping": {"string"}})
+
+		assert.Contains(t, body, `<sru:recordSchema>info:srw/schema/1/marcxml-v1.1</sru:recordSchema><sru:recordXMLEscaping>string</sru:recordXMLEscaping>`)
+		assert.Contains(t, body, `<sru:recordData>&lt;marc:record xmlns:marc=&#34;http://www.loc.gov/MARC21/slim&#34;`)
+		assert.NotContains(t, body, `<sru:nextRecordPosition>`)
+	})
+
+	t.Run("defaults and limits", func(t *testing.T) {
+		catalog := &fakeCatalog{page: &entities.BookPage{Total: 0}}
+
+		body := handle(t, catalog, url.Values{"query": {"clean"}})
+		handle(t, catalog, url.Values{"query": {"clean"}, "maximumRecords": {"50"}, "x-debug": {"1"}, "version": {"2.0"}})
+
+		assert.Equal(t, []int{2, 5}, catalog.limits)
+		assert.Contains(t, body, `<sru:numberOfRecords>0</sru:numberOfRecords>`)
+		assert.NotContains(t, body, `<sru:records>`)
+	})
+
+	t.Run("catalogue failures are returned", func(t *testing.T) {
+		provider := NewProvider(&fakeCatalog{err: entities.ErrDatabaseError}, Config{})
+
+		_, err := provider.Handle(context.Background(), baseURL, url.Values{"query": {"clean"}})
+
+		assert.ErrorIs(t, err, entities.ErrDatabaseError)
+	})
+}
+
+func TestHandle_Diagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    url.Values
+		catalog *fakeCatalog
+		code    string
+		details string
+	}{
+		{"scan", url.Values{"operation": {"scan"}, "scanClause": {"dc.title"}}, nil, "4", "scan"},
+		{"version", url.Values{"query": {"a"}, "version": {"1.1"}}, nil, "5", "2.0"},
+		{"query type", url.Values{"query": {"a"}, "queryType": {"searchTerms"}}, nil, "6", "queryType"},
+		{"start record", url.Values{"query": {"a"}, "startRecord": {"0"}}, nil, "6", "startRecord"},
+		{"maximum records", url.Values{"query": {"a"}, "maximumRecords": {"many"}}, nil, "6", "maximumRecords"},
+		{"missing query", url.Values{"operation": {"searchRetrieve"}}, nil, "7", "query"},
+		{"unsupported parameter", url.Values{"query": {"a"}, "sortKeys": {"dc.title"}}, nil, "8", "sortKeys"},
+		{"syntax", url.Values{"query": {"a and"}}, nil, "10", "expected a search clause, found the end of the query"},
+		{"too many booleans", url.Values{"query": {"a" + strings.Repeat(" or a", cql.MaxBooleans+1)}}, nil, "38", "more than 64"},
+		{"unsupported index", url.Values{"query": {"dc.rights = a"}}, &fakeCatalog{err: cql.Unsupported(cql.FeatureIndex, "dc.rights")}, "16", "dc.rights"},
+		{"unsupported sort", url.Values{"query": {"a sortby dc.subject/sort.missingOmit"}}, &fakeCatalog{err: cql.Unsupported(cql.FeatureSortModifier, "sort.missingomit")}, "48", "sort.missingomit"},
+		{"start past the end", url.Values{"query": {"a"}, "startRecord": {"5"}}, &fakeCatalog{page: &entities.BookPage{Total: 4}}, "61", "5"},
+		{"unknown schema", url.Values{"query": {"a"}, "recordSchema": {"mods"}}, nil, "66", "mods"},
+		{"XML escaping", url.Values{"query": {"a"}, "recordXMLEscaping": {"json"}}, nil, "71", "json"},
+		{"record packing", url.Values{"query": {"a"}, "recordPacking": {"unpacked"}}, nil, "71", "unpacked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := tt.catalog
+			if catalog == nil {
+				catalog = &fakeCatalog{page: &entities.BookPage{}}
+			}
+
+			body := handle(t, catalog, tt.args)
+
+			assert.Contains(t, body, `<sru:diagnostics><diag:diagnostic><diag:uri>info:srw/diagnostic/1/`+tt.code+`</diag:uri><diag:details>`+tt.details+`</diag:details>`)
+			if tt.catalog == nil {
+				assert.Empty(t, catalog.queries)
+			}
+		})
+	}
+}
+
+func TestHandle_Explain(t *testing.T) {
+	t.Run("describes the service", func(t *testing.T) {
+		body := handle(t, &fakeCatalog{}, url.Values{})
+
+		assert.Contains(t, body, `<sru:explainResponse xmlns:sru="http://docs.oasis-open.org/ns/search-ws/sruResponse"`)
+		assert.Contains(t, body, `<sru:recordSchema>http://explain.z3950.org/dtd/2.0/</sru:recordSchema>`)
+		assert.Contains(t, body, `<zr:serverInfo protocol="SRU" version="2.0" transport="https"><zr:host>library.test</zr:host><zr:port>443</zr:port><zr:database>sru</zr:database></zr:serverInfo>`)
+		assert.Contains(t, body, `<zr:title lang="en" primary="true">Test Library</zr:title>`)
+		assert.Contains(t, body, `<zr:set name="dc" identifier="info:srw/cql-context-set/1/dc-v1.1"></zr:set>`)
+		assert.Contains(t, body, `<zr:index search="true" sort="true"><zr:title>year of publication</zr:title><zr:map><zr:name set="dc">date</zr:name></zr:map></zr:index>`)
+		assert.Contains(t, body, `<zr:schema identifier="info:srw/schema/1/marcxml-v1.1" name="marcxml">`)
+		assert.Contains(t, body, `<zr:default type="numberOfRecords">2</zr:default><zr:setting type="maximumRecords">5</zr:setting>`)
+	})
+
+	t.Run("by operation", func(t *testing.T) {
+		body := handle(t, &fakeCatalog{}, url.Values{"operation": {"explain"}, "version": {"2.0"}})
+
+		assert.Contains(t, body, `<sru:explainResponse`)
+		assert.NotContains(t, body, `<sru:diagnostics>`)
+	})
+
+	t.Run("unsupported parameters", func(t *testing.T) {
+		body := handle(t, &fakeCatalog{}, url.Values{"stylesheet": {"a.xsl"}})
+
+		assert.Contains(t, body, `<diag:uri>info:srw/diagnostic/1/8</diag:uri><diag:details>stylesheet</diag:details>`)
+		assert.NotContains(t, body, `<sru:record>`)
+	})
+}
@@ -0,0 +1,61 @@
+// Package storage keeps binary objects, such as cover images, behind the
+// BlobStore interface, with a local filesystem and an S3-compatible backend.
+// Keys are slash-separated paths like "covers/<tenant>/<book>/small"; they
+// are chosen by the application, never by clients.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned for keys that hold no object
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores objects by key. Put replaces any object under the key;
+// deleting a missing object is not an error.
+type BlobStore interface {
+	// Put stores size bytes read from r
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object; the caller must close its Body
+	Get(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix deletes every object whose key starts with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// Info describes a stored object
+type Info struct {
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Object is an open stored object
+type Object struct {
+	Info
+	Body io.ReadCloser
+}
+
+// checkKey rejects keys that could escape the store's root, so that a bug
+// in key construction cannot reach other files or buckets
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, "\\\x00") {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
+
+// checkPrefix accepts a key or a key followed by a slash
+func checkPrefix(prefix string) error {
+	return checkKey(strings.TrimSuffix(prefix, "/"))
+}
@@ -0,0 +1,272 @@
+package stream
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"byfood-library/internal/domain/events"
+	"github.com/google/uuid"
+)
+
+// Message is an event as streamed to clients. ID is the outbox sequence,
+// which is the same on every replica, so a client can resume on any of them.
+type Message struct {
+	ID    string
+	Event *events.Event
+	// Author and subjects of the book for created and updated events, used
+	// for filtering
+	Author   string
+	Subjects []string
+}
+
+// Filter selects the messages a subscriber receives
+type Filter func(msg *Message) bool
+
+// AuthorFilter matches events for books by any of authors, ignoring case;
+// deletions carry no author and always match. It is nil without authors.
+func AuthorFilter(authors []string) Filter {
+	var wanted []string
+	for _, author := range authors {
+		if author = strings.TrimSpace(author); author != "" {
+			wanted = append(wanted, author)
+		}
+	}
+	if len(wanted) == 0 {
+		return nil
+	}
+	return func(msg *Message) bool {
+		if msg.Author == "" {
+			return true
+		}
+		for _, author := range wanted {
+			if strings.EqualFold(author, msg.Author) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// GenreFilter matches events for books with any of genres among their
+// subjects, ignoring case; subjects are the genre facet of the catalogue
+// feeds. Deletions always match. It is nil without genres.
+func GenreFilter(genres []string) Filter {
+	var wanted []string
+	for _, genre := range genres {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			wanted = append(wanted, genre)
+		}
+	}
+	if len(wanted) == 0 {
+		return nil
+	}
+	return func(msg *Message) bool {
+		if msg.Event.Type == events.BookDeleted {
+			return true
+		}
+		for _, subject := range msg.Subjects {
+			for _, genre := range wanted {
+				if strings.EqualFold(genre, subject) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// AllOf matches the messages that every non-nil filter matches. It is nil
+// without any.
+func AllOf(filters ...Filter) Filter {
+	var active []Filter
+	for _, filter := range filters {
+		if filter != nil {
+			active = append(active, filter)
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	return func(msg *Message) bool {
+		for _, filter := range active {
+			if !filter(msg) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Subscription receives a tenant's messages on C. C is closed when the
+// subscriber falls too far behind or the hub resets; the client should
+// reconnect with its last event ID.
+type Subscription struct {
+	C        chan *Message
+	tenantID uuid.UUID
+	filter   Filter
+}
+
+// Hub fans messages out to subscribers and keeps the most recent ones for
+// replay. Messages are kept in arrival order, which is the NOTIFY order and
+// therefore the same on all replicas.
+type Hub struct {
+	mu          sync.Mutex
+	buffer      []*Message
+	size        int
+	clientQueue int
+	seen        map[uuid.UUID]bool
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewHub returns a hub replaying up to size messages; each subscriber may
+// fall clientQueue messages behind before it is disconnected
+func NewHub(size, clientQueue int) *Hub {
+	if size <= 0 {
+		size = 1000
+	}
+	if clientQueue <= 0 {
+		clientQueue = 64
+	}
+	return &Hub{
+		size:        size,
+		clientQueue: clientQueue,
+		seen:        make(map[uuid.UUID]bool),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish buffers evt and sends it to matching subscribers. Events already
+// in the buffer are dropped, since the relay delivers at least once.
+func (h *Hub) Publish(evt *events.Event) {
+	msg := &Message{ID: strconv.FormatInt(evt.Sequence, 10), Event: evt}
+	if evt.Type != events.BookDeleted {
+		var book struct {
+			Author   string   `json:"author"`
+			Subjects []string `json:"subjects"`
+		}
+		if json.Unmarshal(evt.Data, &book) == nil {
+			msg.Author = book.Author
+			msg.Subjects = book.Subjects
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.seen[evt.ID] {
+		return
+	}
+	if len(h.buffer) == h.size {
+		delete(h.seen, h.buffer[0].Event.ID)
+		h.buffer = h.buffer[1:]
+	}
+	h.buffer = append(h.buffer, msg)
+	h.seen[evt.ID] = true
+
+	for sub := range h.subscribers {
+		if !sub.matches(msg) {
+			continue
+		}
+		select {
+		case sub.C <- msg:
+		default:
+			// Too slow; it can catch up from the buffer after reconnecting
+			h.drop(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber for tenantID. With a lastEventID it
+// returns the buffered messages that followed it; reset is true if that
+// event is no longer buffered, so the client cannot resume without gaps.
+func (h *Hub) Subscribe(tenantID uuid.UUID, lastEventID string, filter Filter) (sub *Subscription, replay []*Message, reset bool) {
+	sub = &Subscription{
+		tenantID: tenantID,
+		filter:   filter,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lastEventID != "" {
+		position := -1
+		for i, msg := range h.buffer {
+			if msg.ID == lastEventID {
+				position = i
+				break
+			}
+		}
+		if position < 0 {
+			reset = true
+		} else {
+			for _, msg := range h.buffer[position+1:] {
+				if sub.matches(msg) {
+					replay = append(replay, msg)
+				}
+			}
+		}
+	}
+
+	sub.C = make(chan *Message, h.clientQueue)
+	if h.closed {
+		close(sub.C)
+		return sub, nil, false
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub, replay, reset
+}
+
+// Unsubscribe removes sub; it is safe to call after sub was dropped
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[sub]; ok {
+		h.drop(sub)
+	}
+}
+
+// Reset forgets the buffer and disconnects every subscriber. Called when
+// events may have been missed, e.g. after the listener reconnects; clients
+// that reconnect here are told to reload, since their last event is gone.
+func (h *Hub) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buffer = nil
+	h.seen = make(map[uuid.UUID]bool)
+	for sub := range h.subscribers {
+		h.drop(sub)
+	}
+}
+
+// Close disconnects every subscriber and refuses new ones, so that open
+// streams do not hold up a graceful shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subscribers {
+		h.drop(sub)
+	}
+}
+
+// Subscribers returns the number of connected subscribers
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+// drop must be called with h.mu held
+func (h *Hub) drop(sub *Subscription) {
+	delete(h.subscribers, sub)
+	close(sub.C)
+}
+
+func (s *Subscription) matches(msg *Message) bool {
+	return msg.Event.TenantID == s.tenantID && (s.filter == nil || s.filter(msg))
+}
@@ -0,0 +1,92 @@
+package stream
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"byfood-library/internal/domain/events"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// DefaultChannel is the Postgres channel carrying book events to every
+// replica's hub
+const DefaultChannel = "book_events"
+
+// NOTIFY payloads are limited to 8000 bytes; an event that does not fit is
+// sent without its data and clients fetch the book themselves
+const maxPayload = 7900
+
+// Notifier forwards relayed events to all replicas with pg_notify. It is
+// subscribed to the event bus of whichever replica is relaying.
+type Notifier struct {
+	db      *sql.DB
+	channel string
+}
+
+func NewNotifier(db *sql.DB, channel string) *Notifier {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	return &Notifier{db: db, channel: channel}
+}
+
+// HandleEvent notifies the channel of evt; the bus retries it on error
+func (n *Notifier) HandleEvent(ctx context.Context, evt *events.Event) error {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxPayload {
+		trimmed := *evt
+		trimmed.Data = nil
+		if payload, err = json.Marshal(&trimmed); err != nil {
+			return err
+		}
+	}
+	_, err = n.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, n.channel, string(payload))
+	return err
+}
+
+// Listen publishes events notified on channel to hub until ctx is done. The
+// hub is reset whenever the connection is re-established, since events sent
+// while disconnected are lost.
+func Listen(ctx context.Context, dsn, channel string, hub *Hub, logger *zap.Logger) {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Warn("Book event listener error", zap.String("channel", channel), zap.Error(err))
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		logger.Error("Failed to listen for book events", zap.String("channel", channel), zap.Error(err))
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-listener.Notify:
+			// A nil notification signals a reconnect
+			if notification == nil {
+				hub.Reset()
+				continue
+			}
+			var evt events.Event
+			if err := json.Unmarshal([]byte(notification.Extra), &evt); err != nil {
+				logger.Warn("Ignoring malformed book event notification", zap.Error(err))
+				continue
+			}
+			hub.Publish(&evt)
+		case <-time.After(90 * time.Second):
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"byfood-library/internal/domain/events"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var tenantA, tenantB = uuid.New(), uuid.New()
+
+func testEvent(tenantID uuid.UUID, sequence int64, eventType, author string) *events.Event {
+	data, _ := json.Marshal(map[string]string{"author": author})
+	return &events.Event{ID: uuid.New(), Sequence: sequence, Type: eventType, TenantID: tenantID, Data: data}
+}
+
+func ids(msgs []*Message) []string {
+	var out []string
+	for _, msg := range msgs {
+		out = append(out, msg.ID)
+	}
+	return out
+}
+
+func drain(sub *Subscription) []*Message {
+	var out []*Message
+	for {
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				return out
+			}
+			out = append(out, msg)
+		default:
+			return out
+		}
+	}
+}
+
+func TestHub_Publish(t *testing.T) {
+	hub := NewHub(10, 10)
+	sub, _, _ := hub.Subscribe(tenantA, "", nil)
+	other, _, _ := hub.Subscribe(tenantB, "", nil)
+
+	created := testEvent(tenantA, 1, events.BookCreated, "Rob Pike")
+	hub.Publish(created)
+	hub.Publish(created)
+	hub.Publish(testEvent(tenantB, 2, events.BookCreated, "Ken Thompson"))
+
+	received := drain(sub)
+	assert.Equal(t, []string{"1"}, ids(received))
+	assert.Equal(t, "Rob Pike", received[0].Author)
+	assert.Equal(t, []string{"2"}, ids(drain(other)))
+}
+
+func TestHub_Subscribe(t *testing.T) {
+	hub := NewHub(3, 10)
+	for seq := int64(1); seq <= 4; seq++ {
+		hub.Publish(testEvent(tenantA, seq, events.BookUpdated, "Author"))
+	}
+	// Arrival order counts, not sequence: 6 was delayed by a retry
+	hub.Publish(testEvent(tenantA, 6, events.BookUpdated, "Author"))
+	hub.Publish(testEvent(tenantA, 5, events.BookUpdated, "Author"))
+
+	t.Run("replays what followed the last event", func(t *testing.T) {
+		_, replay, reset := hub.Subscribe(tenantA, "4", nil)
+		assert.False(t, reset)
+		assert.Equal(t, []string{"6", "5"}, ids(replay))
+	})
+
+	t.Run("resets when the last event is no longer buffered", func(t *testing.T) {
+		_, replay, reset := hub.Subscribe(tenantA, "1", nil)
+		assert.True(t, reset)
+		assert.Empty(t, replay)
+	})
+
+	t.Run("replays only the tenant's matching events", func(t *testing.T) {
+		_, replay, _ := hub.Subscribe(tenantB, "4", nil)
+		assert.Empty(t, replay)
+
+		_, replay, _ = hub.Subscribe(tenantA, "4", func(msg *Message) bool { return msg.ID == "5" })
+		assert.Equal(t, []string{"5"}, ids(replay))
+	})
+}
+
+func TestHub_Disconnects(t *testing.T) {
+	t.Run("slow subscribers", func(t *testing.T) {
+		hub := NewHub(10, 1)
+		sub, _, _ := hub.Subscribe(tenantA, "", nil)
+
+		hub.Publish(testEvent(tenantA, 1, events.BookCreated, "A"))
+		hub.Publish(testEvent(tenantA, 2, events.BookCreated, "A"))
+
+		_, ok := <-sub.C
+		assert.True(t, ok)
+		_, ok = <-sub.C
+		assert.False(t, ok)
+		assert.Equal(t, 0, hub.Subscribers())
+		hub.Unsubscribe(sub)
+	})
+
+	t.Run("reset forgets the buffer", func(t *testing.T) {
+		hub := NewHub(10, 10)
+		hub.Publish(testEvent(tenantA, 1, events.BookCreated, "A"))
+		sub, _, _ := hub.Subscribe(tenantA, "", nil)
+
+		hub.Reset()
+
+		_, ok := <-sub.C
+		assert.False(t, ok)
+		_, _, reset := hub.Subscribe(tenantA, "1", nil)
+		assert.True(t, reset)
+	})
+
+	t.Run("close refuses new subscribers", func(t *testing.T) {
+		hub := NewHub(10, 10)
+		sub, _, _ := hub.Subscribe(tenantA, "", nil)
+
+		hub.Close()
+
+		_, ok := <-sub.C
+		assert.False(t, ok)
+		late, _, _ := hub.Subscribe(tenantA, "", nil)
+		_, ok = <-late.C
+		assert.False(t, ok)
+	})
+}
+
+func TestNotifier(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	notifier := NewNotifier(db, "")
+
+	evt := testEvent(tenantA, 1, events.BookCreated, "Rob Pike")
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(DefaultChannel, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.NoError(t, notifier.HandleEvent(context.Background(), evt))
+
+	large := testEvent(tenantA, 2, events.BookCreated, strings.Repeat("x", maxPayload))
+	trimmed := *large
+	trimmed.Data = nil
+	payload, _ := json.Marshal(&trimmed)
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(DefaultChannel, string(payload)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.NoError(t, notifier.HandleEvent(context.Background(), large))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,52 @@
+package tenancy
+
+import (
+	"context"
+	"sync/atomic"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+)
+
+type contextKey struct{}
+
+// WithTenant stores the resolved tenant in the context
+func WithTenant(ctx context.Context, tenant *entities.Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant resolved for the current request, if any
+func FromContext(ctx context.Context) (*entities.Tenant, bool) {
+	tenant, ok := ctx.Value(contextKey{}).(*entities.Tenant)
+	return tenant, ok && tenant != nil
+}
+
+// IDFromContext returns the current tenant ID or ErrTenantRequired
+func IDFromContext(ctx context.Context) (uuid.UUID, error) {
+	tenant, ok := FromContext(ctx)
+	if !ok {
+		return uuid.Nil, entities.ErrTenantRequired
+	}
+	return tenant.ID, nil
+}
+
+var bookValidationDefaults atomic.Pointer[entities.BookValidationRules]
+
+// SetBookValidationDefaults replaces the deployment's book validation rules.
+// It is safe to call while requests are served, e.g. on a config reload.
+func SetBookValidationDefaults(rules entities.BookValidationRules) {
+	bookValidationDefaults.Store(&rules)
+}
+
+// BookValidationRules returns the deployment's rules with the tenant's
+// overrides merged on top
+func BookValidationRules(ctx context.Context) entities.BookValidationRules {
+	rules := entities.DefaultBookValidationRules
+	if defaults := bookValidationDefaults.Load(); defaults != nil {
+		rules = *defaults
+	}
+	if tenant, ok := FromContext(ctx); ok && tenant.Settings.Validation != nil {
+		rules = rules.Merge(*tenant.Settings.Validation)
+	}
+	return rules
+}
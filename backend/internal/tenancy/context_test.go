@@ -0,0 +1,37 @@
+package tenancy
+
+import (
+	"context"
+	"testing"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookValidationRules(t *testing.T) {
+	t.Cleanup(func() { SetBookValidationDefaults(entities.DefaultBookValidationRules) })
+	SetBookValidationDefaults(entities.BookValidationRules{
+		MinYear: 1450,
+		MaxYear: 2030,
+		Title:   entities.TextRule{MaxLength: 120},
+	})
+
+	t.Run("deployment defaults without a tenant", func(t *testing.T) {
+		rules := BookValidationRules(context.Background())
+
+		assert.Equal(t, 1450, rules.MinYear)
+		assert.Equal(t, 120, rules.Title.MaxLength)
+	})
+
+	t.Run("tenant overrides are merged on top", func(t *testing.T) {
+		ctx := WithTenant(context.Background(), &entities.Tenant{Settings: entities.TenantSettings{
+			Validation: &entities.BookValidationRules{MaxYear: 2100},
+		}})
+
+		rules := BookValidationRules(ctx)
+
+		assert.Equal(t, 1450, rules.MinYear)
+		assert.Equal(t, 2100, rules.MaxYear)
+		assert.Equal(t, 120, rules.Title.MaxLength)
+	})
+}
@@ -0,0 +1,191 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+)
+
+// Resolution strategies, tried in the configured order
+const (
+	StrategyAPIKey    = "api_key"
+	StrategyHeader    = "header"
+	StrategySubdomain = "subdomain"
+)
+
+// Unknown identifiers are remembered for at most missTTL, so that requests
+// with a bogus tenant do not each cost a query, and at most maxMisses of
+// them are kept
+const (
+	missTTL   = 5 * time.Second
+	maxMisses = 10000
+)
+
+type ResolverConfig struct {
+	Strategies    []string
+	HeaderName    string
+	APIKeyHeader  string
+	BaseDomain    string
+	DefaultTenant string
+	CacheTTL      time.Duration
+}
+
+type cacheEntry struct {
+	tenant    *entities.Tenant
+	expiresAt time.Time
+}
+
+// Resolver maps an incoming request to a tenant using the configured
+// strategies; with no strategies every request gets the default tenant.
+// Lookups are cached for CacheTTL, and unknown tenants briefly, so that
+// resolving does not cost a query per request.
+type Resolver struct {
+	repo   repositories.TenantRepository
+	config ResolverConfig
+
+	mu     sync.RWMutex
+	cache  map[string]cacheEntry
+	misses map[string]time.Time
+	now    func() time.Time
+}
+
+func NewResolver(repo repositories.TenantRepository, config ResolverConfig) *Resolver {
+	if config.HeaderName == "" {
+		config.HeaderName = "X-Tenant-ID"
+	}
+	if config.APIKeyHeader == "" {
+		config.APIKeyHeader = "X-API-Key"
+	}
+	return &Resolver{
+		repo:   repo,
+		config: config,
+		cache:  make(map[string]cacheEntry),
+		misses: make(map[string]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Resolve returns the active tenant for the request. An explicit identifier
+// that does not match a tenant is an error; the default tenant is only used
+// when the request carries no identifier at all.
+func (r *Resolver) Resolve(ctx context.Context, req *http.Request) (*entities.Tenant, error) {
+	for _, strategy := range r.config.Strategies {
+		switch strategy {
+		case StrategyAPIKey:
+			if apiKey := req.Header.Get(r.config.APIKeyHeader); apiKey != "" {
+				return r.lookup(ctx, "key:"+entities.HashAPIKey(apiKey))
+			}
+		case StrategyHeader:
+			if slug := strings.TrimSpace(req.Header.Get(r.config.HeaderName)); slug != "" {
+				return r.lookup(ctx, "slug:"+strings.ToLower(slug))
+			}
+		case StrategySubdomain:
+			if slug := r.subdomain(req.Host); slug != "" {
+				return r.lookup(ctx, "slug:"+slug)
+			}
+		}
+	}
+
+	if r.config.DefaultTenant != "" {
+		return r.lookup(ctx, "slug:"+r.config.DefaultTenant)
+	}
+	return nil, entities.ErrTenantRequired
+}
+
+// Invalidate drops all cached lookups, e.g. after a tenant was created or
+// suspended
+func (r *Resolver) Invalidate() {
+	r.mu.Lock()
+	r.cache = make(map[string]cacheEntry)
+	r.misses = make(map[string]time.Time)
+	r.mu.Unlock()
+}
+
+func (r *Resolver) lookup(ctx context.Context, key string) (*entities.Tenant, error) {
+	r.mu.RLock()
+	entry, ok := r.cache[key]
+	missExpiresAt, missed := r.misses[key]
+	r.mu.RUnlock()
+
+	if missed && r.now().Before(missExpiresAt) {
+		return nil, entities.ErrTenantNotFound
+	}
+
+	tenant := entry.tenant
+	if !ok || r.now().After(entry.expiresAt) {
+		var err error
+		if strings.HasPrefix(key, "key:") {
+			tenant, err = r.repo.GetByAPIKeyHash(ctx, strings.TrimPrefix(key, "key:"))
+		} else {
+			tenant, err = r.repo.GetBySlug(ctx, strings.TrimPrefix(key, "slug:"))
+		}
+		if errors.Is(err, entities.ErrTenantNotFound) {
+			r.rememberMiss(key)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		r.mu.Lock()
+		r.cache[key] = cacheEntry{tenant: tenant, expiresAt: r.now().Add(r.config.CacheTTL)}
+		r.mu.Unlock()
+	}
+
+	if !tenant.IsActive() {
+		return nil, entities.ErrTenantSuspended
+	}
+	return tenant, nil
+}
+
+func (r *Resolver) rememberMiss(key string) {
+	ttl := missTTL
+	if r.config.CacheTTL < ttl {
+		ttl = r.config.CacheTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if len(r.misses) >= maxMisses {
+		for missed, expiresAt := range r.misses {
+			if now.After(expiresAt) {
+				delete(r.misses, missed)
+			}
+		}
+		// Still full of live misses: forget them rather than grow
+		if len(r.misses) >= maxMisses {
+			r.misses = make(map[string]time.Time)
+		}
+	}
+	r.misses[key] = now.Add(ttl)
+}
+
+func (r *Resolver) subdomain(host string) string {
+	if r.config.BaseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	suffix := "." + strings.ToLower(r.config.BaseDomain)
+	if !strings.HasSuffix(host, suffix) {
+		return ""
+	}
+	label := strings.TrimSuffix(host, suffix)
+	if label == "" || label == "www" || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
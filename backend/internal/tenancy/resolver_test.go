@@ -0,0 +1,198 @@
+package tenancy
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTenantRepository for testing
+type MockTenantRepository struct {
+	mock.Mock
+}
+
+func (m *MockTenantRepository) Create(ctx context.Context, tenant *entities.Tenant) (*entities.Tenant, error) {
+	args := m.Called(ctx, tenant)
+	return args.Get(0).(*entities.Tenant), args.Error(1)
+}
+
+func (m *MockTenantRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Tenant, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Tenant), args.Error(1)
+}
+
+func (m *MockTenantRepository) GetBySlug(ctx context.Context, slug string) (*entities.Tenant, error) {
+	args := m.Called(ctx, slug)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Tenant), args.Error(1)
+}
+
+func (m *MockTenantRepository) GetByAPIKeyHash(ctx context.Context, hash string) (*entities.Tenant, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Tenant), args.Error(1)
+}
+
+func (m *MockTenantRepository) GetAll(ctx context.Context) ([]*entities.Tenant, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*entities.Tenant), args.Error(1)
+}
+
+func (m *MockTenantRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status entities.TenantStatus) (*entities.Tenant, error) {
+	args := m.Called(ctx, id, status)
+	return args.Get(0).(*entities.Tenant), args.Error(1)
+}
+
+func (m *MockTenantRepository) UpdateSettings(ctx context.Context, id uuid.UUID, settings entities.TenantSettings) (*entities.Tenant, error) {
+	args := m.Called(ctx, id, settings)
+	return args.Get(0).(*entities.Tenant), args.Error(1)
+}
+
+func newTenant(slug string, status entities.TenantStatus) *entities.Tenant {
+	return &entities.Tenant{ID: uuid.New(), Slug: slug, Name: slug, Status: status}
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	allStrategies := []string{StrategyAPIKey, StrategyHeader, StrategySubdomain}
+
+	t.Run("api key takes precedence", func(t *testing.T) {
+		repo := new(MockTenantRepository)
+		tenant := newTenant("north", entities.TenantStatusActive)
+		repo.On("GetByAPIKeyHash", mock.Anything, entities.HashAPIKey("secret")).Return(tenant, nil).Once()
+
+		resolver := NewResolver(repo, ResolverConfig{Strategies: allStrategies, CacheTTL: time.Minute})
+		req := httptest.NewRequest("GET", "/api/v1/books", nil)
+		req.Header.Set("X-API-Key", "secret")
+		req.Header.Set("X-Tenant-ID", "south")
+
+		result, err := resolver.Resolve(context.Background(), req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tenant.ID, result.ID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("subdomain resolution", func(t *testing.T) {
+		repo := new(MockTenantRepository)
+		tenant := newTenant("east", entities.TenantStatusActive)
+		repo.On("GetBySlug", mock.Anything, "east").Return(tenant, nil).Once()
+
+		resolver := NewResolver(repo, ResolverConfig{Strategies: allStrategies, BaseDomain: "library.example.com"})
+		req := httptest.NewRequest("GET", "/api/v1/books", nil)
+		req.Host = "east.library.example.com:8080"
+
+		result, err := resolver.Resolve(context.Background(), req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "east", result.Slug)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("lookups are cached", func(t *testing.T) {
+		repo := new(MockTenantRepository)
+		tenant := newTenant("west", entities.TenantStatusActive)
+		repo.On("GetBySlug", mock.Anything, "west").Return(tenant, nil).Once()
+
+		resolver := NewResolver(repo, ResolverConfig{Strategies: allStrategies, CacheTTL: time.Minute})
+		req := httptest.NewRequest("GET", "/api/v1/books", nil)
+		req.Header.Set("X-Tenant-ID", "west")
+
+		for i := 0; i < 3; i++ {
+			_, err := resolver.Resolve(context.Background(), req)
+			assert.NoError(t, err)
+		}
+		repo.AssertNumberOfCalls(t, "GetBySlug", 1)
+	})
+
+	t.Run("unknown tenants are cached briefly", func(t *testing.T) {
+		repo := new(MockTenantRepository)
+		repo.On("GetBySlug", mock.Anything, "bogus").Return(nil, entities.ErrTenantNotFound)
+
+		resolver := NewResolver(repo, ResolverConfig{Strategies: allStrategies, CacheTTL: time.Minute})
+		now := time.Now()
+		resolver.now = func() time.Time { return now }
+		req := httptest.NewRequest("GET", "/api/v1/books", nil)
+		req.Header.Set("X-Tenant-ID", "bogus")
+
+		for i := 0; i < 3; i++ {
+			_, err := resolver.Resolve(context.Background(), req)
+			assert.Equal(t, entities.ErrTenantNotFound, err)
+		}
+		repo.AssertNumberOfCalls(t, "GetBySlug", 1)
+
+		now = now.Add(missTTL + time.Second)
+		_, err := resolver.Resolve(context.Background(), req)
+		assert.Equal(t, entities.ErrTenantNotFound, err)
+		repo.AssertNumberOfCalls(t, "GetBySlug", 2)
+	})
+
+	t.Run("creating a tenant forgets it was unknown", func(t *testing.T) {
+		repo := new(MockTenantRepository)
+		repo.On("GetBySlug", mock.Anything, "new").Return(nil, entities.ErrTenantNotFound).Once()
+		repo.On("GetBySlug", mock.Anything, "new").Return(newTenant("new", entities.TenantStatusActive), nil).Once()
+
+		resolver := NewResolver(repo, ResolverConfig{Strategies: allStrategies, CacheTTL: time.Minute})
+		req := httptest.NewRequest("GET", "/api/v1/books", nil)
+		req.Header.Set("X-Tenant-ID", "new")
+
+		_, err := resolver.Resolve(context.Background(), req)
+		assert.Equal(t, entities.ErrTenantNotFound, err)
+
+		resolver.Invalidate()
+		result, err := resolver.Resolve(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, "new", result.Slug)
+	})
+
+	t.Run("suspended tenant is rejected", func(t *testing.T) {
+		repo := new(MockTenantRepository)
+		repo.On("GetBySlug", mock.Anything, "closed").Return(newTenant("closed", entities.TenantStatusSuspended), nil).Once()
+
+		resolver := NewResolver(repo, ResolverConfig{Strategies: allStrategies})
+		req := httptest.NewRequest("GET", "/api/v1/books", nil)
+		req.Header.Set("X-Tenant-ID", "closed")
+
+		result, err := resolver.Resolve(context.Background(), req)
+
+		assert.Nil(t, result)
+		assert.Equal(t, entities.ErrTenantSuspended, err)
+	})
+
+	t.Run("falls back to default tenant", func(t *testing.T) {
+		repo := new(MockTenantRepository)
+		tenant := newTenant("default", entities.TenantStatusActive)
+		repo.On("GetBySlug", mock.Anything, "default").Return(tenant, nil).Once()
+
+		resolver := NewResolver(repo, ResolverConfig{DefaultTenant: "default"})
+		req := httptest.NewRequest("GET", "/api/v1/books", nil)
+		req.Header.Set("X-Tenant-ID", "ignored-when-tenancy-disabled")
+
+		result, err := resolver.Resolve(context.Background(), req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "default", result.Slug)
+	})
+
+	t.Run("no identifier and no default", func(t *testing.T) {
+		resolver := NewResolver(new(MockTenantRepository), ResolverConfig{Strategies: allStrategies})
+		req := httptest.NewRequest("GET", "/api/v1/books", nil)
+
+		result, err := resolver.Resolve(context.Background(), req)
+
+		assert.Nil(t, result)
+		assert.Equal(t, entities.ErrTenantRequired, err)
+	})
+}
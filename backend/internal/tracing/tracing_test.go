@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"bind parameters kept", "SELECT * FROM books WHERE tenant_id = $1 AND id = $2", "SELECT * FROM books WHERE tenant_id = $1 AND id = $2"},
+		{"named parameters kept", "INSERT INTO books (title) VALUES (:title)", "INSERT INTO books (title) VALUES (:title)"},
+		{"string literals stripped", "SELECT * FROM books WHERE title = 'Clean Code' AND author = 'O''Reilly'", "SELECT * FROM books WHERE title = ? AND author = ?"},
+		{"numeric literals stripped", "SELECT * FROM books WHERE year > 1999 LIMIT 10", "SELECT * FROM books WHERE year > ? LIMIT ?"},
+		{"whitespace collapsed", "SELECT id\n              FROM books", "SELECT id FROM books"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SanitizeSQL(tt.query))
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(tracetest.NewSpanRecorder()))
+	ctx, span := provider.Tracer("test").Start(context.Background(), "operation")
+	defer span.End()
+
+	core, logs := observer.New(zap.InfoLevel)
+	Logger(ctx, zap.New(core)).Info("hello")
+
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, span.SpanContext().TraceID().String(), fields["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), fields["span_id"])
+}
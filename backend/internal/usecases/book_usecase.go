@@ -4,105 +4,322 @@ import (
 	"context"
 
 	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
 	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/duplicates"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tenancy"
+	"byfood-library/internal/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
 type BookUseCase interface {
 	CreateBook(ctx context.Context, dto *entities.CreateBookDTO) (*entities.Book, error)
 	GetBookByID(ctx context.Context, id uuid.UUID) (*entities.Book, error)
+	GetBooksByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error)
 	GetAllBooks(ctx context.Context) ([]*entities.Book, error)
 	UpdateBook(ctx context.Context, id uuid.UUID, dto *entities.UpdateBookDTO) (*entities.Book, error)
 	DeleteBook(ctx context.Context, id uuid.UUID) error
+	FindDuplicates(ctx context.Context) ([]entities.DuplicateGroup, error)
+	MergeBooks(ctx context.Context, dto *entities.MergeBooksDTO) (*entities.Book, error)
 }
 
+// duplicatePairLimit bounds the candidate pairs examined for the duplicates
+// report
+const duplicatePairLimit = 1000
+
 type bookUseCase struct {
-	bookRepo repositories.BookRepository
-	logger   *zap.Logger
+	bookRepo   repositories.BookRepository
+	outbox     repositories.OutboxRepository
+	transactor repositories.Transactor
+	detector   *duplicates.Detector
+	logger     *zap.Logger
 }
 
-func NewBookUseCase(bookRepo repositories.BookRepository, logger *zap.Logger) BookUseCase {
+// NewBookUseCase returns a BookUseCase that records a domain event in outbox
+// for every change, in the same transaction as the change itself. New books
+// that detector finds to be possible duplicates are rejected unless forced;
+// a nil detector skips that check.
+func NewBookUseCase(bookRepo repositories.BookRepository, outbox repositories.OutboxRepository, transactor repositories.Transactor, detector *duplicates.Detector, logger *zap.Logger) BookUseCase {
 	return &bookUseCase{
-		bookRepo: bookRepo,
-		logger:   logger,
+		bookRepo:   bookRepo,
+		outbox:     outbox,
+		transactor: transactor,
+		detector:   detector,
+		logger:     logger,
 	}
 }
 
 func (uc *bookUseCase) CreateBook(ctx context.Context, dto *entities.CreateBookDTO) (*entities.Book, error) {
-	// Validate DTO
-	if err := dto.Validate(); err != nil {
-		uc.logger.Error("Validation failed for CreateBookDTO", zap.Error(err))
+	ctx, span := tracing.Start(ctx, "BookUseCase.CreateBook")
+	defer span.End()
+	logger := logging.FromContext(ctx, uc.logger)
+
+	// Validate DTO against the tenant's rules
+	if err := dto.ValidateWith(tenancy.BookValidationRules(ctx)); err != nil {
+		logger.Error("Validation failed for CreateBookDTO", zap.Error(err))
+		tracing.Fail(span, err)
 		return nil, err
 	}
 
 	// Convert DTO to entity
 	book := dto.ToBook()
 
-	// Create book through repository
-	createdBook, err := uc.bookRepo.Create(ctx, book)
+	if uc.detector != nil && !dto.Force {
+		candidates, err := uc.bookRepo.FindSimilar(ctx, book, uc.detector.Limit()*4)
+		if err != nil {
+			logger.Error("Failed to check for duplicate books", zap.Error(err))
+			tracing.Fail(span, err)
+			return nil, err
+		}
+		if matches := uc.detector.Candidates(book, candidates); len(matches) > 0 {
+			err := &entities.DuplicateError{Candidates: matches}
+			logger.Info("Rejected possible duplicate book", zap.Int("candidates", len(matches)))
+			tracing.Fail(span, err)
+			return nil, err
+		}
+	}
+
+	// Create book and its BookCreated event atomically
+	var createdBook *entities.Book
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		createdBook, err = uc.bookRepo.Create(ctx, book)
+		if err != nil {
+			return err
+		}
+		return uc.outbox.Append(ctx, events.NewBookCreated(createdBook))
+	})
 	if err != nil {
-		uc.logger.Error("Failed to create book", zap.Error(err))
+		logger.Error("Failed to create book", zap.Error(err))
+		tracing.Fail(span, err)
 		return nil, err
 	}
 
-	uc.logger.Info("Book created successfully", zap.String("id", createdBook.ID.String()))
+	logger.Info("Book created successfully", zap.String("id", createdBook.ID.String()))
 	return createdBook, nil
 }
 
 func (uc *bookUseCase) GetBookByID(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	ctx, span := tracing.Start(ctx, "BookUseCase.GetBookByID")
+	defer span.End()
+	span.SetAttributes(attribute.String("book.id", id.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
 	book, err := uc.bookRepo.GetByID(ctx, id)
 	if err != nil {
-		uc.logger.Error("Failed to get book by ID", zap.String("id", id.String()), zap.Error(err))
+		logger.Error("Failed to get book by ID", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
 		return nil, err
 	}
 
 	return book, nil
 }
 
+// GetBooksByIDs returns the books among ids in one query, in no particular
+// order; ids that do not exist are left out
+func (uc *bookUseCase) GetBooksByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	ctx, span := tracing.Start(ctx, "BookUseCase.GetBooksByIDs")
+	defer span.End()
+	span.SetAttributes(attribute.Int("book.count", len(ids)))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	books, err := uc.bookRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		logger.Error("Failed to get books by ID", zap.Int("count", len(ids)), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	return books, nil
+}
+
 func (uc *bookUseCase) GetAllBooks(ctx context.Context) ([]*entities.Book, error) {
+	ctx, span := tracing.Start(ctx, "BookUseCase.GetAllBooks")
+	defer span.End()
+	logger := logging.FromContext(ctx, uc.logger)
+
 	books, err := uc.bookRepo.GetAll(ctx)
 	if err != nil {
-		uc.logger.Error("Failed to get all books", zap.Error(err))
+		logger.Error("Failed to get all books", zap.Error(err))
+		tracing.Fail(span, err)
 		return nil, err
 	}
 
-	uc.logger.Info("Retrieved books successfully", zap.Int("count", len(books)))
+	logger.Info("Retrieved books successfully", zap.Int("count", len(books)))
 	return books, nil
 }
 
 func (uc *bookUseCase) UpdateBook(ctx context.Context, id uuid.UUID, dto *entities.UpdateBookDTO) (*entities.Book, error) {
-	// Validate DTO
-	if err := dto.Validate(); err != nil {
-		uc.logger.Error("Validation failed for UpdateBookDTO", zap.Error(err))
+	ctx, span := tracing.Start(ctx, "BookUseCase.UpdateBook")
+	defer span.End()
+	span.SetAttributes(attribute.String("book.id", id.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	// Validate DTO against the tenant's rules
+	if err := dto.ValidateWith(tenancy.BookValidationRules(ctx)); err != nil {
+		logger.Error("Validation failed for UpdateBookDTO", zap.Error(err))
+		tracing.Fail(span, err)
 		return nil, err
 	}
 
-	// Create book entity from DTO
-	book := &entities.Book{
-		Title:  dto.Title,
-		Author: dto.Author,
-		Year:   dto.Year,
-	}
+	// Update book and record BookUpdated atomically; the book is locked
+	// while the update is merged into it, so that the fields it leaves out
+	// keep their current values
+	var updatedBook *entities.Book
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		book, err := uc.bookRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		dto.ApplyTo(book)
 
-	// Update book through repository
-	updatedBook, err := uc.bookRepo.Update(ctx, id, book)
+		updatedBook, err = uc.bookRepo.Update(ctx, id, book)
+		if err != nil {
+			return err
+		}
+		return uc.outbox.Append(ctx, events.NewBookUpdated(updatedBook))
+	})
 	if err != nil {
-		uc.logger.Error("Failed to update book", zap.String("id", id.String()), zap.Error(err))
+		logger.Error("Failed to update book", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
 		return nil, err
 	}
 
-	uc.logger.Info("Book updated successfully", zap.String("id", updatedBook.ID.String()))
+	logger.Info("Book updated successfully", zap.String("id", updatedBook.ID.String()))
 	return updatedBook, nil
 }
 
 func (uc *bookUseCase) DeleteBook(ctx context.Context, id uuid.UUID) error {
-	err := uc.bookRepo.Delete(ctx, id)
+	ctx, span := tracing.Start(ctx, "BookUseCase.DeleteBook")
+	defer span.End()
+	span.SetAttributes(attribute.String("book.id", id.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	// Delete book and record BookDeleted atomically
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.bookRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+		tenantID, _ := tenancy.IDFromContext(ctx)
+		return uc.outbox.Append(ctx, events.NewBookDeleted(tenantID, id))
+	})
 	if err != nil {
-		uc.logger.Error("Failed to delete book", zap.String("id", id.String()), zap.Error(err))
+		logger.Error("Failed to delete book", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
 		return err
 	}
 
-	uc.logger.Info("Book deleted successfully", zap.String("id", id.String()))
+	logger.Info("Book deleted successfully", zap.String("id", id.String()))
 	return nil
-}
\ No newline at end of file
+}
+
+// FindDuplicates groups the tenant's books that appear to describe the same
+// work
+func (uc *bookUseCase) FindDuplicates(ctx context.Context) ([]entities.DuplicateGroup, error) {
+	ctx, span := tracing.Start(ctx, "BookUseCase.FindDuplicates")
+	defer span.End()
+	logger := logging.FromContext(ctx, uc.logger)
+
+	pairs, err := uc.bookRepo.FindSimilarPairs(ctx, duplicatePairLimit)
+	if err != nil {
+		logger.Error("Failed to find duplicate books", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	detector := uc.detector
+	if detector == nil {
+		detector = duplicates.NewDetector(duplicates.Config{})
+	}
+	groups := detector.Group(pairs)
+	span.SetAttributes(attribute.Int("duplicates.groups", len(groups)))
+	return groups, nil
+}
+
+// MergeBooks deletes the duplicates in favour of the survivor, recording a
+// BookDeleted event that names the survivor for each, so that consumers can
+// re-point their references. The survivor takes the ISBN and metadata it
+// lacks from the duplicates, the first listed first, and their files; other
+// tables that come to refer to books must be re-pointed here too, in the
+// same transaction.
+func (uc *bookUseCase) MergeBooks(ctx context.Context, dto *entities.MergeBooksDTO) (*entities.Book, error) {
+	ctx, span := tracing.Start(ctx, "BookUseCase.MergeBooks")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("book.id", dto.SurvivorID.String()),
+		attribute.Int("book.count", len(dto.DuplicateIDs)),
+	)
+	logger := logging.FromContext(ctx, uc.logger)
+
+	if err := dto.Validate(); err != nil {
+		logger.Error("Validation failed for MergeBooksDTO", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	var survivor *entities.Book
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		// The survivor and the duplicates are locked together, so that no
+		// concurrent update of a duplicate is lost and no duplicate is
+		// merged into two survivors; a duplicate deleted meanwhile is
+		// reported missing
+		ids := append([]uuid.UUID{dto.SurvivorID}, dto.DuplicateIDs...)
+		locked, err := uc.bookRepo.GetByIDsForUpdate(ctx, ids)
+		if err != nil {
+			return err
+		}
+		if len(locked) != len(ids) {
+			return entities.ErrBookNotFound
+		}
+
+		byID := make(map[uuid.UUID]*entities.Book, len(locked))
+		for _, book := range locked {
+			byID[book.ID] = book
+		}
+		survivor = byID[dto.SurvivorID]
+		merged := make([]*entities.Book, len(dto.DuplicateIDs))
+		filled := false
+		for i, id := range dto.DuplicateIDs {
+			merged[i] = byID[id]
+			if survivor.FillMetadata(merged[i].ISBN, merged[i].BookMetadata) {
+				filled = true
+			}
+		}
+		if filled {
+			if survivor, err = uc.bookRepo.Update(ctx, survivor.ID, survivor); err != nil {
+				return err
+			}
+			if err := uc.outbox.Append(ctx, events.NewBookUpdated(survivor)); err != nil {
+				return err
+			}
+		}
+
+		if err := uc.bookRepo.MoveFiles(ctx, dto.DuplicateIDs, survivor.ID); err != nil {
+			return err
+		}
+		for _, duplicate := range merged {
+			if err := uc.bookRepo.Delete(ctx, duplicate.ID); err != nil {
+				return err
+			}
+			if err := uc.outbox.Append(ctx, events.NewBookMerged(duplicate.TenantID, duplicate.ID, survivor.ID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to merge books", zap.String("id", dto.SurvivorID.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	logger.Info("Books merged successfully", zap.String("id", survivor.ID.String()), zap.Int("merged", len(dto.DuplicateIDs)))
+	return survivor, nil
+}
@@ -5,11 +5,19 @@ import (
 	"testing"
 	"time"
 
+	"byfood-library/internal/cql"
 	"byfood-library/internal/domain/entities"
-	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/duplicates"
+	"byfood-library/internal/tenancy"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/zap"
 )
 
@@ -31,6 +39,24 @@ func (m *MockBookRepository) GetByID(ctx context.Context, id uuid.UUID) (*entiti
 	return args.Get(0).(*entities.Book), args.Error(1)
 }
 
+func (m *MockBookRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Book), args.Error(1)
+}
+
+func (m *MockBookRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]*entities.Book), args.Error(1)
+}
+
+func (m *MockBookRepository) GetByIDsForUpdate(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]*entities.Book), args.Error(1)
+}
+
 func (m *MockBookRepository) GetAll(ctx context.Context) ([]*entities.Book, error) {
 	args := m.Called(ctx)
 	return args.Get(0).([]*entities.Book), args.Error(1)
@@ -41,15 +67,173 @@ func (m *MockBookRepository) Update(ctx context.Context, id uuid.UUID, book *ent
 	return args.Get(0).(*entities.Book), args.Error(1)
 }
 
+func (m *MockBookRepository) SetCover(ctx context.Context, id uuid.UUID, etag string) (*entities.Book, error) {
+	args := m.Called(ctx, id, etag)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Book), args.Error(1)
+}
+
 func (m *MockBookRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockBookRepository) Count(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBookRepository) FindSimilar(ctx context.Context, book *entities.Book, limit int) ([]*entities.Book, error) {
+	args := m.Called(ctx, book, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.Book), args.Error(1)
+}
+
+func (m *MockBookRepository) FindSimilarPairs(ctx context.Context, limit int) ([][2]*entities.Book, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([][2]*entities.Book), args.Error(1)
+}
+
+func (m *MockBookRepository) AddFile(ctx context.Context, file *entities.BookFile) (*entities.BookFile, error) {
+	args := m.Called(ctx, file)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookFile), args.Error(1)
+}
+
+func (m *MockBookRepository) ListFiles(ctx context.Context, bookID uuid.UUID) ([]*entities.BookFile, error) {
+	args := m.Called(ctx, bookID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.BookFile), args.Error(1)
+}
+
+func (m *MockBookRepository) Search(ctx context.Context, query entities.BookQuery) (*entities.BookPage, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookPage), args.Error(1)
+}
+
+func (m *MockBookRepository) SearchCQL(ctx context.Context, query *cql.Query, offset, limit int) (*entities.BookPage, error) {
+	args := m.Called(ctx, query, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookPage), args.Error(1)
+}
+
+func (m *MockBookRepository) FacetValues(ctx context.Context, facet entities.Facet, limit int) ([]entities.FacetValue, error) {
+	args := m.Called(ctx, facet, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.FacetValue), args.Error(1)
+}
+
+func (m *MockBookRepository) Harvest(ctx context.Context, query entities.HarvestQuery) ([]*entities.HarvestRecord, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.HarvestRecord), args.Error(1)
+}
+
+func (m *MockBookRepository) GetHarvestRecord(ctx context.Context, id uuid.UUID) (*entities.HarvestRecord, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.HarvestRecord), args.Error(1)
+}
+
+func (m *MockBookRepository) EarliestDatestamp(ctx context.Context) (time.Time, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockBookRepository) ListFilesOf(ctx context.Context, bookIDs []uuid.UUID) ([]*entities.BookFile, error) {
+	args := m.Called(ctx, bookIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.BookFile), args.Error(1)
+}
+
+func (m *MockBookRepository) GetFile(ctx context.Context, bookID, fileID uuid.UUID) (*entities.BookFile, error) {
+	args := m.Called(ctx, bookID, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookFile), args.Error(1)
+}
+
+func (m *MockBookRepository) FindFileByChecksum(ctx context.Context, checksum string) (*entities.BookFile, error) {
+	args := m.Called(ctx, checksum)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookFile), args.Error(1)
+}
+
+func (m *MockBookRepository) DeleteFile(ctx context.Context, bookID, fileID uuid.UUID) error {
+	args := m.Called(ctx, bookID, fileID)
+	return args.Error(0)
+}
+
+func (m *MockBookRepository) MoveFiles(ctx context.Context, from []uuid.UUID, to uuid.UUID) error {
+	args := m.Called(ctx, from, to)
+	return args.Error(0)
+}
+
+func (m *MockBookRepository) SetFileStorageKey(ctx context.Context, fileID uuid.UUID, key string) error {
+	args := m.Called(ctx, fileID, key)
+	return args.Error(0)
+}
+
+// recordingOutbox collects appended events; err makes Append fail
+type recordingOutbox struct {
+	events []*events.Event
+	err    error
+}
+
+func (o *recordingOutbox) Append(ctx context.Context, evts ...*events.Event) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.events = append(o.events, evts...)
+	return nil
+}
+
+func (o *recordingOutbox) Relay(ctx context.Context, limit int, deliver func(ctx context.Context, batch []*events.Event) []events.Outcome) (bool, error) {
+	return true, nil
+}
+
+func (o *recordingOutbox) DeletePublished(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return 0, nil
+}
+
+// passthroughTransactor runs fn without a database transaction
+type passthroughTransactor struct{}
+
+func (passthroughTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 func setupTest() (BookUseCase, *MockBookRepository) {
 	mockRepo := new(MockBookRepository)
 	logger := zap.NewNop()
-	useCase := NewBookUseCase(mockRepo, logger)
+	useCase := NewBookUseCase(mockRepo, &recordingOutbox{}, passthroughTransactor{}, nil, logger)
 	return useCase, mockRepo
 }
 
@@ -95,7 +279,7 @@ func TestBookUseCase_CreateBook(t *testing.T) {
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
-		assert.Equal(t, entities.ErrInvalidTitle, err)
+		assert.ErrorIs(t, err, entities.ErrInvalidTitle)
 	})
 }
 
@@ -187,6 +371,7 @@ func TestBookUseCase_UpdateBook(t *testing.T) {
 			UpdatedAt: time.Now(),
 		}
 
+		mockRepo.On("GetByIDForUpdate", mock.Anything, bookID).Return(&entities.Book{ID: bookID, Title: "Book", Author: "Author", Year: 2020}, nil)
 		mockRepo.On("Update", mock.Anything, bookID, mock.AnythingOfType("*entities.Book")).Return(updatedBook, nil)
 
 		result, err := useCase.UpdateBook(context.Background(), bookID, dto)
@@ -211,7 +396,7 @@ func TestBookUseCase_UpdateBook(t *testing.T) {
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
-		assert.Equal(t, entities.ErrInvalidTitle, err)
+		assert.ErrorIs(t, err, entities.ErrInvalidTitle)
 	})
 }
 
@@ -240,4 +425,250 @@ func TestBookUseCase_DeleteBook(t *testing.T) {
 		assert.Equal(t, entities.ErrBookNotFound, err)
 		mockRepo.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
+func TestBookUseCase_TenantValidationRules(t *testing.T) {
+	useCase, mockRepo := setupTest()
+
+	tenant := &entities.Tenant{
+		ID:     uuid.New(),
+		Status: entities.TenantStatusActive,
+		Settings: entities.TenantSettings{
+			Validation: &entities.BookValidationRules{MinYear: 1450, MaxYear: 2100},
+		},
+	}
+	ctx := tenancy.WithTenant(context.Background(), tenant)
+
+	t.Run("year allowed by tenant rules", func(t *testing.T) {
+		dto := &entities.CreateBookDTO{Title: "Future Book", Author: "Author", Year: 2050}
+
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*entities.Book")).Return(&entities.Book{
+			ID:     uuid.New(),
+			Title:  dto.Title,
+			Author: dto.Author,
+			Year:   dto.Year,
+		}, nil).Once()
+
+		result, err := useCase.CreateBook(ctx, dto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2050, result.Year)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("year rejected by tenant rules", func(t *testing.T) {
+		dto := &entities.CreateBookDTO{Title: "Old Book", Author: "Author", Year: 1200}
+
+		result, err := useCase.CreateBook(ctx, dto)
+
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, entities.ErrInvalidYear)
+	})
+}
+
+func TestBookUseCase_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	useCase, mockRepo := setupTest()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "GET /api/v1/books/:id")
+	bookID := uuid.New()
+	mockRepo.On("GetByID", mock.Anything, bookID).Return(nil, entities.ErrBookNotFound).Once()
+
+	_, err := useCase.GetBookByID(ctx, bookID)
+	parent.End()
+
+	assert.Equal(t, entities.ErrBookNotFound, err)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "BookUseCase.GetBookByID", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestBookUseCase_Events(t *testing.T) {
+	tenant := &entities.Tenant{ID: uuid.New(), Status: entities.TenantStatusActive}
+	ctx := tenancy.WithTenant(context.Background(), tenant)
+	book := &entities.Book{ID: uuid.New(), TenantID: tenant.ID, Title: "Test Book", Author: "Test Author", Year: 2020}
+
+	t.Run("each change records one event", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		outbox := &recordingOutbox{}
+		useCase := NewBookUseCase(mockRepo, outbox, passthroughTransactor{}, nil, zap.NewNop())
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(book, nil).Once()
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("Update", mock.Anything, book.ID, mock.Anything).Return(book, nil).Once()
+		mockRepo.On("Delete", mock.Anything, book.ID).Return(nil).Once()
+
+		_, err := useCase.CreateBook(ctx, &entities.CreateBookDTO{Title: book.Title, Author: book.Author, Year: book.Year})
+		assert.NoError(t, err)
+		_, err = useCase.UpdateBook(ctx, book.ID, &entities.UpdateBookDTO{Title: book.Title, Author: book.Author, Year: book.Year})
+		assert.NoError(t, err)
+		assert.NoError(t, useCase.DeleteBook(ctx, book.ID))
+
+		assert.Len(t, outbox.events, 3)
+		for i, eventType := range []string{events.BookCreated, events.BookUpdated, events.BookDeleted} {
+			assert.Equal(t, eventType, outbox.events[i].Type)
+			assert.Equal(t, book.ID, outbox.events[i].AggregateID)
+			assert.Equal(t, tenant.ID, outbox.events[i].TenantID)
+		}
+		assert.JSONEq(t, `{"id":"`+book.ID.String()+`"}`, string(outbox.events[2].Data))
+	})
+
+	t.Run("failed writes record no event", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		outbox := &recordingOutbox{}
+		useCase := NewBookUseCase(mockRepo, outbox, passthroughTransactor{}, nil, zap.NewNop())
+		mockRepo.On("Delete", mock.Anything, book.ID).Return(entities.ErrBookNotFound).Once()
+
+		assert.Equal(t, entities.ErrBookNotFound, useCase.DeleteBook(ctx, book.ID))
+		assert.Empty(t, outbox.events)
+	})
+
+	t.Run("outbox failures fail the change", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		useCase := NewBookUseCase(mockRepo, &recordingOutbox{err: entities.ErrDatabaseError}, passthroughTransactor{}, nil, zap.NewNop())
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(book, nil).Once()
+
+		result, err := useCase.CreateBook(ctx, &entities.CreateBookDTO{Title: book.Title, Author: book.Author, Year: book.Year})
+		assert.Nil(t, result)
+		assert.Equal(t, entities.ErrDatabaseError, err)
+	})
+}
+
+func TestBookUseCase_Duplicates(t *testing.T) {
+	tenant := &entities.Tenant{ID: uuid.New(), Status: entities.TenantStatusActive}
+	ctx := tenancy.WithTenant(context.Background(), tenant)
+	existing := &entities.Book{ID: uuid.New(), TenantID: tenant.ID, Title: "Clean Code", Author: "Robert Martin", Year: 2008}
+	dto := &entities.CreateBookDTO{Title: "Clean code", Author: "Robert C. Martin", Year: 2008}
+
+	t.Run("create rejects a likely duplicate", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		useCase := NewBookUseCase(mockRepo, &recordingOutbox{}, passthroughTransactor{}, duplicates.NewDetector(duplicates.Config{}), zap.NewNop())
+		mockRepo.On("FindSimilar", mock.Anything, mock.Anything, duplicates.DefaultLimit*4).Return([]*entities.Book{existing}, nil).Once()
+
+		result, err := useCase.CreateBook(ctx, dto)
+
+		assert.Nil(t, result)
+		var duplicate *entities.DuplicateError
+		if assert.ErrorAs(t, err, &duplicate) {
+			assert.Equal(t, existing, duplicate.Candidates[0].Book)
+		}
+		assert.ErrorIs(t, err, entities.ErrPossibleDuplicate)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("force skips the check", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		useCase := NewBookUseCase(mockRepo, &recordingOutbox{}, passthroughTransactor{}, duplicates.NewDetector(duplicates.Config{}), zap.NewNop())
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(existing, nil).Once()
+
+		forced := *dto
+		forced.Force = true
+		_, err := useCase.CreateBook(ctx, &forced)
+
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "FindSimilar", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("report groups similar pairs", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		useCase := NewBookUseCase(mockRepo, &recordingOutbox{}, passthroughTransactor{}, nil, zap.NewNop())
+		similar := &entities.Book{ID: uuid.New(), TenantID: tenant.ID, Title: "Clean code", Author: "Robert C. Martin", Year: 2008}
+		other := &entities.Book{ID: uuid.New(), TenantID: tenant.ID, Title: "Clean Architecture", Author: "Robert Martin", Year: 2017}
+		mockRepo.On("FindSimilarPairs", mock.Anything, mock.Anything).
+			Return([][2]*entities.Book{{existing, similar}, {existing, other}}, nil).Once()
+
+		groups, err := useCase.FindDuplicates(ctx)
+
+		assert.NoError(t, err)
+		if assert.Len(t, groups, 1) {
+			assert.ElementsMatch(t, []*entities.Book{existing, similar}, groups[0].Books)
+		}
+	})
+
+	t.Run("merge moves the files and deletes the duplicates in favour of the survivor", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		outbox := &recordingOutbox{}
+		useCase := NewBookUseCase(mockRepo, outbox, passthroughTransactor{}, nil, zap.NewNop())
+		survivor := *existing
+		survivor.Description = "Kept as entered"
+		duplicate := &entities.Book{
+			ID: uuid.New(), TenantID: tenant.ID, Title: "Clean code", Author: "Robert C. Martin", Year: 2008, ISBN: "9780132350884",
+			BookMetadata: entities.BookMetadata{Publisher: "Prentice Hall", Description: "A handbook"},
+		}
+		another := &entities.Book{
+			ID: uuid.New(), TenantID: tenant.ID, Title: "Clean Code", Author: "Martin, Robert", Year: 2008,
+			BookMetadata: entities.BookMetadata{
+				Publisher: "Pearson",
+				PageCount: 464,
+				Subjects:  pq.StringArray{"Software"},
+				CoverURL:  "https://covers.example.com/clean-code.jpg",
+			},
+		}
+		duplicateIDs := []uuid.UUID{duplicate.ID, another.ID}
+		mockRepo.On("GetByIDsForUpdate", mock.Anything, []uuid.UUID{survivor.ID, duplicate.ID, another.ID}).
+			Return([]*entities.Book{another, &survivor, duplicate}, nil).Once()
+		mockRepo.On("Update", mock.Anything, survivor.ID, mock.MatchedBy(func(book *entities.Book) bool {
+			return book.ISBN == duplicate.ISBN && assert.ObjectsAreEqual(entities.BookMetadata{
+				Publisher:   "Prentice Hall",
+				PageCount:   464,
+				Subjects:    pq.StringArray{"Software"},
+				Description: "Kept as entered",
+				CoverURL:    "https://covers.example.com/clean-code.jpg",
+			}, book.BookMetadata)
+		})).Return(&survivor, nil).Once()
+		mockRepo.On("MoveFiles", mock.Anything, duplicateIDs, survivor.ID).Return(nil).Once()
+		mockRepo.On("Delete", mock.Anything, duplicate.ID).Return(nil).Once()
+		mockRepo.On("Delete", mock.Anything, another.ID).Return(nil).Once()
+
+		result, err := useCase.MergeBooks(ctx, &entities.MergeBooksDTO{SurvivorID: survivor.ID, DuplicateIDs: duplicateIDs})
+
+		assert.NoError(t, err)
+		assert.Equal(t, survivor.ID, result.ID)
+		mockRepo.AssertExpectations(t)
+		if assert.Len(t, outbox.events, 3) {
+			assert.Equal(t, events.BookUpdated, outbox.events[0].Type)
+			assert.Equal(t, events.BookDeleted, outbox.events[1].Type)
+			assert.Equal(t, events.BookDeleted, outbox.events[2].Type)
+			assert.JSONEq(t, `{"id":"`+duplicate.ID.String()+`","merged_into":"`+survivor.ID.String()+`"}`, string(outbox.events[1].Data))
+			assert.JSONEq(t, `{"id":"`+another.ID.String()+`","merged_into":"`+survivor.ID.String()+`"}`, string(outbox.events[2].Data))
+		}
+	})
+
+	t.Run("merge leaves a complete survivor alone", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		outbox := &recordingOutbox{}
+		useCase := NewBookUseCase(mockRepo, outbox, passthroughTransactor{}, nil, zap.NewNop())
+		survivor := *existing
+		survivor.ISBN = "9780132350884"
+		duplicate := &entities.Book{ID: uuid.New(), TenantID: tenant.ID, Title: "Clean code", Author: "Robert C. Martin", Year: 2008}
+		mockRepo.On("GetByIDsForUpdate", mock.Anything, []uuid.UUID{survivor.ID, duplicate.ID}).
+			Return([]*entities.Book{&survivor, duplicate}, nil).Once()
+		mockRepo.On("MoveFiles", mock.Anything, []uuid.UUID{duplicate.ID}, survivor.ID).Return(nil).Once()
+		mockRepo.On("Delete", mock.Anything, duplicate.ID).Return(nil).Once()
+
+		_, err := useCase.MergeBooks(ctx, &entities.MergeBooksDTO{SurvivorID: survivor.ID, DuplicateIDs: []uuid.UUID{duplicate.ID}})
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		if assert.Len(t, outbox.events, 1) {
+			assert.Equal(t, events.BookDeleted, outbox.events[0].Type)
+		}
+	})
+
+	t.Run("merge fails when a duplicate is missing", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		outbox := &recordingOutbox{}
+		useCase := NewBookUseCase(mockRepo, outbox, passthroughTransactor{}, nil, zap.NewNop())
+		// Also the case of a duplicate merged elsewhere while waiting for
+		// the lock
+		missing := uuid.New()
+		mockRepo.On("GetByIDsForUpdate", mock.Anything, []uuid.UUID{existing.ID, missing}).Return([]*entities.Book{existing}, nil).Once()
+
+		result, err := useCase.MergeBooks(ctx, &entities.MergeBooksDTO{SurvivorID: existing.ID, DuplicateIDs: []uuid.UUID{missing}})
+
+		assert.Nil(t, result)
+		assert.Equal(t, entities.ErrBookNotFound, err)
+		assert.Empty(t, outbox.events)
+	})
+}
@@ -0,0 +1,119 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+
+	"byfood-library/internal/cql"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// CatalogPage is a page of the catalogue with the ebook files of its books
+type CatalogPage struct {
+	entities.BookPage
+	// Files holds the files of the page's books by book ID; it is nil when
+	// files are not offered
+	Files map[uuid.UUID][]*entities.BookFile
+}
+
+// CatalogUseCase browses the catalogue for feeds such as OPDS
+type CatalogUseCase interface {
+	Search(ctx context.Context, query entities.BookQuery) (*CatalogPage, error)
+	// SearchCQL returns the page of books a CQL query selects, or a
+	// *cql.UnsupportedError if the query uses what cannot be searched
+	SearchCQL(ctx context.Context, query *cql.Query, offset, limit int) (*entities.BookPage, error)
+	// FacetValues returns up to limit values of the facet, most common first
+	FacetValues(ctx context.Context, facet entities.Facet, limit int) ([]entities.FacetValue, error)
+}
+
+type catalogUseCase struct {
+	bookRepo repositories.BookRepository
+	files    bool
+	logger   *zap.Logger
+}
+
+// NewCatalogUseCase returns a CatalogUseCase whose pages carry the books'
+// files when files is set, i.e. when they can be downloaded
+func NewCatalogUseCase(bookRepo repositories.BookRepository, files bool, logger *zap.Logger) CatalogUseCase {
+	return &catalogUseCase{
+		bookRepo: bookRepo,
+		files:    files,
+		logger:   logger,
+	}
+}
+
+func (uc *catalogUseCase) Search(ctx context.Context, query entities.BookQuery) (*CatalogPage, error) {
+	ctx, span := tracing.Start(ctx, "CatalogUseCase.Search")
+	defer span.End()
+	span.SetAttributes(attribute.Int("catalog.offset", query.Offset), attribute.Int("catalog.limit", query.Limit))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	page, err := uc.bookRepo.Search(ctx, query)
+	if err != nil {
+		logger.Error("Failed to search the catalogue", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	result := &CatalogPage{BookPage: *page}
+	if !uc.files || len(page.Books) == 0 {
+		return result, nil
+	}
+
+	ids := make([]uuid.UUID, len(page.Books))
+	for i, book := range page.Books {
+		ids[i] = book.ID
+	}
+	files, err := uc.bookRepo.ListFilesOf(ctx, ids)
+	if err != nil {
+		logger.Error("Failed to list book files", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	result.Files = make(map[uuid.UUID][]*entities.BookFile)
+	for _, file := range files {
+		result.Files[file.BookID] = append(result.Files[file.BookID], file)
+	}
+	return result, nil
+}
+
+func (uc *catalogUseCase) SearchCQL(ctx context.Context, query *cql.Query, offset, limit int) (*entities.BookPage, error) {
+	ctx, span := tracing.Start(ctx, "CatalogUseCase.SearchCQL")
+	defer span.End()
+	span.SetAttributes(attribute.Int("catalog.offset", offset), attribute.Int("catalog.limit", limit))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	page, err := uc.bookRepo.SearchCQL(ctx, query, offset, limit)
+	var unsupported *cql.UnsupportedError
+	if errors.As(err, &unsupported) {
+		// The client asked for what the catalogue cannot search, which is
+		// reported back to it rather than a failure
+		logger.Debug("Unsupported CQL query", zap.String("feature", string(unsupported.Feature)), zap.String("value", unsupported.Value))
+		return nil, err
+	}
+	if err != nil {
+		logger.Error("Failed to search the catalogue", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return page, nil
+}
+
+func (uc *catalogUseCase) FacetValues(ctx context.Context, facet entities.Facet, limit int) ([]entities.FacetValue, error) {
+	ctx, span := tracing.Start(ctx, "CatalogUseCase.FacetValues")
+	defer span.End()
+	span.SetAttributes(attribute.String("catalog.facet", string(facet)))
+
+	values, err := uc.bookRepo.FacetValues(ctx, facet, limit)
+	if err != nil {
+		logging.FromContext(ctx, uc.logger).Error("Failed to read facet values", zap.String("facet", string(facet)), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return values, nil
+}
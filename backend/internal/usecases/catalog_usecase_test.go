@@ -0,0 +1,93 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"byfood-library/internal/cql"
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCatalogUseCase_Search(t *testing.T) {
+	query := entities.BookQuery{Search: "clean", Limit: 2}
+	first, second := &entities.Book{ID: uuid.New()}, &entities.Book{ID: uuid.New()}
+	page := &entities.BookPage{Books: []*entities.Book{first, second}, Total: 7}
+
+	t.Run("groups the files by book", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		epub := &entities.BookFile{ID: uuid.New(), BookID: first.ID, Format: "epub"}
+		pdf := &entities.BookFile{ID: uuid.New(), BookID: first.ID, Format: "pdf"}
+		mockRepo.On("Search", mock.Anything, query).Return(page, nil).Once()
+		mockRepo.On("ListFilesOf", mock.Anything, []uuid.UUID{first.ID, second.ID}).Return([]*entities.BookFile{epub, pdf}, nil).Once()
+
+		result, err := NewCatalogUseCase(mockRepo, true, zap.NewNop()).Search(context.Background(), query)
+
+		require.NoError(t, err)
+		assert.Equal(t, 7, result.Total)
+		assert.Equal(t, page.Books, result.Books)
+		assert.Equal(t, map[uuid.UUID][]*entities.BookFile{first.ID: {epub, pdf}}, result.Files)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("files are not read when they are not offered", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		mockRepo.On("Search", mock.Anything, query).Return(page, nil).Once()
+
+		result, err := NewCatalogUseCase(mockRepo, false, zap.NewNop()).Search(context.Background(), query)
+
+		require.NoError(t, err)
+		assert.Nil(t, result.Files)
+		mockRepo.AssertNotCalled(t, "ListFilesOf", mock.Anything, mock.Anything)
+	})
+
+	t.Run("repository errors are returned", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		mockRepo.On("Search", mock.Anything, query).Return(nil, entities.ErrDatabaseError).Once()
+
+		_, err := NewCatalogUseCase(mockRepo, true, zap.NewNop()).Search(context.Background(), query)
+
+		assert.ErrorIs(t, err, entities.ErrDatabaseError)
+	})
+}
+
+func TestCatalogUseCase_SearchCQL(t *testing.T) {
+	query, err := cql.Parse(`dc.title = clean`)
+	require.NoError(t, err)
+
+	t.Run("returns the page", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		page := &entities.BookPage{Books: []*entities.Book{{ID: uuid.New()}}, Total: 3}
+		mockRepo.On("SearchCQL", mock.Anything, query, 2, 1).Return(page, nil).Once()
+
+		result, err := NewCatalogUseCase(mockRepo, false, zap.NewNop()).SearchCQL(context.Background(), query, 2, 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, page, result)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unsupported queries are returned", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		mockRepo.On("SearchCQL", mock.Anything, query, 0, 10).Return(nil, cql.Unsupported(cql.FeatureIndex, "dc.rights")).Once()
+
+		_, err := NewCatalogUseCase(mockRepo, false, zap.NewNop()).SearchCQL(context.Background(), query, 0, 10)
+
+		var unsupported *cql.UnsupportedError
+		require.ErrorAs(t, err, &unsupported)
+		assert.Equal(t, cql.FeatureIndex, unsupported.Feature)
+	})
+
+	t.Run("repository errors are returned", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		mockRepo.On("SearchCQL", mock.Anything, query, 0, 10).Return(nil, entities.ErrDatabaseError).Once()
+
+		_, err := NewCatalogUseCase(mockRepo, false, zap.NewNop()).SearchCQL(context.Background(), query, 0, 10)
+
+		assert.ErrorIs(t, err, entities.ErrDatabaseError)
+	})
+}
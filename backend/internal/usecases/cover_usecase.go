@@ -0,0 +1,219 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"byfood-library/internal/covers"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/storage"
+	"byfood-library/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// Cover is an open rendition of a book's cover; the caller must close Body.
+// ETag changes whenever the cover is replaced.
+type Cover struct {
+	storage.Object
+	ETag string
+}
+
+type CoverUseCase interface {
+	// UploadCover stores data, a JPEG, PNG or WebP image, as the book's
+	// cover together with its thumbnails, replacing any previous cover
+	UploadCover(ctx context.Context, id uuid.UUID, data []byte) (*entities.Book, error)
+	// GetCover opens the rendition of the book's cover named size:
+	// covers.Original or a configured thumbnail size
+	GetCover(ctx context.Context, id uuid.UUID, size string) (*Cover, error)
+	DeleteCover(ctx context.Context, id uuid.UUID) (*entities.Book, error)
+	// HandleEvent removes the stored covers of deleted books; subscribe it
+	// to the event bus
+	HandleEvent(ctx context.Context, evt *events.Event) error
+}
+
+type coverUseCase struct {
+	bookRepo   repositories.BookRepository
+	outbox     repositories.OutboxRepository
+	transactor repositories.Transactor
+	store      storage.BlobStore
+	processor  *covers.Processor
+	logger     *zap.Logger
+}
+
+// NewCoverUseCase returns a CoverUseCase keeping renditions in store under
+// "covers/<tenant>/<book>/<etag>/<size>". A new etag for every image keeps
+// cached renditions of a replaced cover from being served as the new one.
+func NewCoverUseCase(bookRepo repositories.BookRepository, outbox repositories.OutboxRepository, transactor repositories.Transactor, store storage.BlobStore, processor *covers.Processor, logger *zap.Logger) CoverUseCase {
+	return &coverUseCase{
+		bookRepo:   bookRepo,
+		outbox:     outbox,
+		transactor: transactor,
+		store:      store,
+		processor:  processor,
+		logger:     logger,
+	}
+}
+
+// coverPrefix holds every cover version of a book
+func coverPrefix(tenantID, bookID uuid.UUID) string {
+	return fmt.Sprintf("covers/%s/%s/", tenantID, bookID)
+}
+
+func coverKey(book *entities.Book, etag, size string) string {
+	return coverPrefix(book.TenantID, book.ID) + etag + "/" + size
+}
+
+func (uc *coverUseCase) UploadCover(ctx context.Context, id uuid.UUID, data []byte) (*entities.Book, error) {
+	ctx, span := tracing.Start(ctx, "CoverUseCase.UploadCover")
+	defer span.End()
+	span.SetAttributes(attribute.String("book.id", id.String()), attribute.Int("cover.size", len(data)))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	book, err := uc.bookRepo.GetByID(ctx, id)
+	if err != nil {
+		logger.Error("Failed to get book", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	renditions, err := uc.processor.Process(data)
+	if err != nil {
+		logger.Warn("Rejected cover image", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	etag := hex.EncodeToString(sum[:])
+	if book.CoverETag == etag {
+		return book, nil
+	}
+
+	for _, rendition := range renditions {
+		if err := uc.store.Put(ctx, coverKey(book, etag, rendition.Name), bytes.NewReader(rendition.Data), int64(len(rendition.Data)), rendition.ContentType); err != nil {
+			logger.Error("Failed to store cover", zap.String("id", id.String()), zap.String("size", rendition.Name), zap.Error(err))
+			uc.removeVersion(ctx, book, etag)
+			tracing.Fail(span, err)
+			return nil, err
+		}
+	}
+
+	// The book is locked and read again inside the transaction so that the
+	// version replaced is the one current when the new one is recorded, and
+	// concurrent uploads each remove a different version
+	var previous string
+	var updated *entities.Book
+	err = uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.bookRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		previous = current.CoverETag
+		if updated, err = uc.bookRepo.SetCover(ctx, id, etag); err != nil {
+			return err
+		}
+		return uc.outbox.Append(ctx, events.NewBookUpdated(updated))
+	})
+	if err != nil {
+		logger.Error("Failed to record cover", zap.String("id", id.String()), zap.Error(err))
+		uc.removeVersion(ctx, book, etag)
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	if previous != "" && previous != etag {
+		uc.removeVersion(ctx, book, previous)
+	}
+
+	logger.Info("Cover uploaded successfully", zap.String("id", id.String()), zap.String("etag", etag))
+	return updated, nil
+}
+
+// removeVersion deletes the renditions of one cover version. Failures only
+// leave unused objects behind, which go with the book, so they are logged.
+func (uc *coverUseCase) removeVersion(ctx context.Context, book *entities.Book, etag string) {
+	if err := uc.store.DeletePrefix(ctx, coverPrefix(book.TenantID, book.ID)+etag+"/"); err != nil {
+		logging.FromContext(ctx, uc.logger).Warn("Failed to remove cover", zap.String("id", book.ID.String()), zap.String("etag", etag), zap.Error(err))
+	}
+}
+
+func (uc *coverUseCase) GetCover(ctx context.Context, id uuid.UUID, size string) (*Cover, error) {
+	ctx, span := tracing.Start(ctx, "CoverUseCase.GetCover")
+	defer span.End()
+	span.SetAttributes(attribute.String("book.id", id.String()), attribute.String("cover.rendition", size))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	if !uc.processor.HasSize(size) {
+		tracing.Fail(span, entities.ErrUnknownCoverSize)
+		return nil, entities.ErrUnknownCoverSize
+	}
+	book, err := uc.bookRepo.GetByID(ctx, id)
+	if err != nil {
+		logger.Error("Failed to get book", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	if book.CoverETag == "" {
+		return nil, entities.ErrCoverNotFound
+	}
+
+	// Thumbnail sizes added to the configuration after an upload are only
+	// rendered when the cover is uploaded again
+	object, err := uc.store.Get(ctx, coverKey(book, book.CoverETag, size))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, entities.ErrCoverNotFound
+	}
+	if err != nil {
+		logger.Error("Failed to read cover", zap.String("id", id.String()), zap.String("size", size), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return &Cover{Object: *object, ETag: book.CoverETag + "-" + size}, nil
+}
+
+func (uc *coverUseCase) DeleteCover(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	ctx, span := tracing.Start(ctx, "CoverUseCase.DeleteCover")
+	defer span.End()
+	span.SetAttributes(attribute.String("book.id", id.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	var updated *entities.Book
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.bookRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if current.CoverETag == "" {
+			return entities.ErrCoverNotFound
+		}
+		if updated, err = uc.bookRepo.SetCover(ctx, id, ""); err != nil {
+			return err
+		}
+		return uc.outbox.Append(ctx, events.NewBookUpdated(updated))
+	})
+	if err != nil {
+		logger.Error("Failed to delete cover", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	if err := uc.store.DeletePrefix(ctx, coverPrefix(updated.TenantID, id)); err != nil {
+		logger.Warn("Failed to remove cover", zap.String("id", id.String()), zap.Error(err))
+	}
+	logger.Info("Cover deleted successfully", zap.String("id", id.String()))
+	return updated, nil
+}
+
+func (uc *coverUseCase) HandleEvent(ctx context.Context, evt *events.Event) error {
+	if evt.Type != events.BookDeleted {
+		return nil
+	}
+	return uc.store.DeletePrefix(ctx, coverPrefix(evt.TenantID, evt.AggregateID))
+}
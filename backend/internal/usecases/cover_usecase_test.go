@@ -0,0 +1,216 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"byfood-library/internal/covers"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func coverPNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCoverUseCase(t *testing.T) {
+	processor := covers.NewProcessor(covers.Config{Sizes: map[string]int{"small": 64}})
+
+	setup := func(t *testing.T) (CoverUseCase, *MockBookRepository, *recordingOutbox, storage.BlobStore) {
+		store, err := storage.NewLocal(t.TempDir())
+		require.NoError(t, err)
+		mockRepo := new(MockBookRepository)
+		outbox := &recordingOutbox{}
+		return NewCoverUseCase(mockRepo, outbox, passthroughTransactor{}, store, processor, zap.NewNop()), mockRepo, outbox, store
+	}
+	newBook := func(etag string) *entities.Book {
+		return &entities.Book{ID: uuid.New(), TenantID: uuid.New(), Title: "Clean Code", Author: "Robert C. Martin", Year: 2008, CoverETag: etag}
+	}
+
+	t.Run("upload stores the renditions and records the cover", func(t *testing.T) {
+		useCase, mockRepo, outbox, _ := setup(t)
+		data := coverPNG(t, 200, 100)
+		book := newBook("")
+		updated := *book
+		updated.CoverETag = sha256Hex(data)
+		mockRepo.On("GetByID", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("SetCover", mock.Anything, book.ID, updated.CoverETag).Return(&updated, nil).Once()
+		mockRepo.On("GetByID", mock.Anything, book.ID).Return(&updated, nil)
+
+		result, err := useCase.UploadCover(context.Background(), book.ID, data)
+
+		require.NoError(t, err)
+		assert.Equal(t, updated.CoverETag, result.CoverETag)
+		require.Len(t, outbox.events, 1)
+		assert.Equal(t, events.BookUpdated, outbox.events[0].Type)
+
+		original, err := useCase.GetCover(context.Background(), book.ID, covers.Original)
+		require.NoError(t, err)
+		defer original.Body.Close()
+		stored, _ := io.ReadAll(original.Body)
+		assert.Equal(t, data, stored)
+		assert.Equal(t, "image/png", original.ContentType)
+		assert.Equal(t, updated.CoverETag+"-original", original.ETag)
+
+		small, err := useCase.GetCover(context.Background(), book.ID, "small")
+		require.NoError(t, err)
+		defer small.Body.Close()
+		thumbnail, _, err := image.Decode(small.Body)
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 64, 32), thumbnail.Bounds())
+		assert.Equal(t, "image/jpeg", small.ContentType)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("replacing a cover removes the previous one", func(t *testing.T) {
+		useCase, mockRepo, _, store := setup(t)
+		book := newBook("old")
+		oldKey := coverKey(book, "old", covers.Original)
+		require.NoError(t, store.Put(context.Background(), oldKey, strings.NewReader("old"), 3, "image/png"))
+		data := coverPNG(t, 10, 10)
+		updated := *book
+		updated.CoverETag = sha256Hex(data)
+		mockRepo.On("GetByID", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("SetCover", mock.Anything, book.ID, updated.CoverETag).Return(&updated, nil).Once()
+
+		_, err := useCase.UploadCover(context.Background(), book.ID, data)
+
+		require.NoError(t, err)
+		_, err = store.Get(context.Background(), oldKey)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("replacing removes the version current under the lock", func(t *testing.T) {
+		useCase, mockRepo, _, store := setup(t)
+		book := newBook("old")
+		// Another upload recorded "concurrent" between the first read and the lock
+		concurrent := *book
+		concurrent.CoverETag = "concurrent"
+		oldKey := coverKey(book, "old", covers.Original)
+		concurrentKey := coverKey(book, "concurrent", covers.Original)
+		require.NoError(t, store.Put(context.Background(), oldKey, strings.NewReader("old"), 3, "image/png"))
+		require.NoError(t, store.Put(context.Background(), concurrentKey, strings.NewReader("new"), 3, "image/png"))
+		data := coverPNG(t, 10, 10)
+		updated := *book
+		updated.CoverETag = sha256Hex(data)
+		mockRepo.On("GetByID", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(&concurrent, nil).Once()
+		mockRepo.On("SetCover", mock.Anything, book.ID, updated.CoverETag).Return(&updated, nil).Once()
+
+		_, err := useCase.UploadCover(context.Background(), book.ID, data)
+
+		require.NoError(t, err)
+		_, err = store.Get(context.Background(), concurrentKey)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		// The version the concurrent upload replaced is its to remove
+		_, err = store.Get(context.Background(), oldKey)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("uploading the current cover again changes nothing", func(t *testing.T) {
+		useCase, mockRepo, outbox, _ := setup(t)
+		data := coverPNG(t, 10, 10)
+		book := newBook(sha256Hex(data))
+		mockRepo.On("GetByID", mock.Anything, book.ID).Return(book, nil).Once()
+
+		result, err := useCase.UploadCover(context.Background(), book.ID, data)
+
+		require.NoError(t, err)
+		assert.Equal(t, book, result)
+		assert.Empty(t, outbox.events)
+		mockRepo.AssertNotCalled(t, "SetCover", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects images it cannot process", func(t *testing.T) {
+		useCase, mockRepo, outbox, _ := setup(t)
+		book := newBook("")
+		mockRepo.On("GetByID", mock.Anything, book.ID).Return(book, nil).Once()
+
+		_, err := useCase.UploadCover(context.Background(), book.ID, []byte("<svg></svg>"))
+
+		assert.ErrorIs(t, err, entities.ErrInvalidCoverImage)
+		assert.Empty(t, outbox.events)
+		mockRepo.AssertNotCalled(t, "SetCover", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("get reports unknown sizes and missing covers", func(t *testing.T) {
+		useCase, mockRepo, _, _ := setup(t)
+		book := newBook("")
+		mockRepo.On("GetByID", mock.Anything, book.ID).Return(book, nil).Once()
+
+		_, err := useCase.GetCover(context.Background(), book.ID, "huge")
+		assert.ErrorIs(t, err, entities.ErrUnknownCoverSize)
+
+		_, err = useCase.GetCover(context.Background(), book.ID, "small")
+		assert.ErrorIs(t, err, entities.ErrCoverNotFound)
+	})
+
+	t.Run("delete removes the cover", func(t *testing.T) {
+		useCase, mockRepo, outbox, store := setup(t)
+		book := newBook("abc")
+		key := coverKey(book, "abc", "small")
+		require.NoError(t, store.Put(context.Background(), key, strings.NewReader("jpeg"), 4, "image/jpeg"))
+		updated := *book
+		updated.CoverETag = ""
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("SetCover", mock.Anything, book.ID, "").Return(&updated, nil).Once()
+
+		result, err := useCase.DeleteCover(context.Background(), book.ID)
+
+		require.NoError(t, err)
+		assert.Empty(t, result.CoverETag)
+		assert.Len(t, outbox.events, 1)
+		_, err = store.Get(context.Background(), key)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("delete without a cover", func(t *testing.T) {
+		useCase, mockRepo, outbox, _ := setup(t)
+		book := newBook("")
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(book, nil).Once()
+
+		_, err := useCase.DeleteCover(context.Background(), book.ID)
+
+		assert.ErrorIs(t, err, entities.ErrCoverNotFound)
+		assert.Empty(t, outbox.events)
+	})
+
+	t.Run("covers of deleted books are removed", func(t *testing.T) {
+		useCase, _, _, store := setup(t)
+		book := newBook("abc")
+		key := coverKey(book, "abc", covers.Original)
+		require.NoError(t, store.Put(context.Background(), key, strings.NewReader("png"), 3, "image/png"))
+
+		require.NoError(t, useCase.HandleEvent(context.Background(), events.NewBookUpdated(book)))
+		_, err := store.Get(context.Background(), key)
+		require.NoError(t, err)
+
+		require.NoError(t, useCase.HandleEvent(context.Background(), events.NewBookDeleted(book.TenantID, book.ID)))
+		_, err = store.Get(context.Background(), key)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
@@ -0,0 +1,390 @@
+package usecases
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/ebooks"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/storage"
+	"byfood-library/internal/tenancy"
+	"byfood-library/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// maxFilenameLength bounds the stored name of an uploaded file, in runes
+const maxFilenameLength = 255
+
+// isbnMatchLimit bounds the books examined for one sharing an uploaded
+// file's ISBN
+const isbnMatchLimit = 10
+
+// EbookFile is an open ebook file; the caller must close Body
+type EbookFile struct {
+	storage.Object
+	File *entities.BookFile
+}
+
+type EbookUseCase interface {
+	// ImportFile adds an EPUB or PDF file to the library. The book it
+	// belongs to is bookID when given; otherwise it is the book sharing the
+	// file's ISBN, or a new book created from the file's metadata, subject
+	// to validation and, unless forced, duplicate detection. Metadata the
+	// book lacks is filled in from the file, and an EPUB's cover becomes
+	// the book's cover if it has none. A file already in the library is
+	// not stored again; the import reports it as a duplicate instead.
+	ImportFile(ctx context.Context, data []byte, filename string, bookID uuid.UUID, force bool) (*entities.BookFileImport, error)
+	ListFiles(ctx context.Context, bookID uuid.UUID) ([]*entities.BookFile, error)
+	OpenFile(ctx context.Context, bookID, fileID uuid.UUID) (*EbookFile, error)
+	DeleteFile(ctx context.Context, bookID, fileID uuid.UUID) error
+	// HandleEvent removes the stored files of deleted books, and moves
+	// those of merged books to the survivor; subscribe it to the event bus
+	HandleEvent(ctx context.Context, evt *events.Event) error
+}
+
+type ebookUseCase struct {
+	bookRepo     repositories.BookRepository
+	outbox       repositories.OutboxRepository
+	transactor   repositories.Transactor
+	bookUseCase  BookUseCase
+	coverUseCase CoverUseCase
+	store        storage.BlobStore
+	parser       *ebooks.Parser
+	logger       *zap.Logger
+}
+
+// NewEbookUseCase returns an EbookUseCase keeping files in store under
+// "ebooks/<tenant>/<book>/<checksum>.<format>". New books are created
+// through bookUseCase; a nil coverUseCase leaves the covers of EPUBs unused.
+func NewEbookUseCase(bookRepo repositories.BookRepository, outbox repositories.OutboxRepository, transactor repositories.Transactor, bookUseCase BookUseCase, coverUseCase CoverUseCase, store storage.BlobStore, parser *ebooks.Parser, logger *zap.Logger) EbookUseCase {
+	return &ebookUseCase{
+		bookRepo:     bookRepo,
+		outbox:       outbox,
+		transactor:   transactor,
+		bookUseCase:  bookUseCase,
+		coverUseCase: coverUseCase,
+		store:        store,
+		parser:       parser,
+		logger:       logger,
+	}
+}
+
+// ebookPrefix holds every file of a book
+func ebookPrefix(tenantID, bookID uuid.UUID) string {
+	return fmt.Sprintf("ebooks/%s/%s/", tenantID, bookID)
+}
+
+func ebookKey(tenantID, bookID uuid.UUID, checksum, format string) string {
+	return ebookPrefix(tenantID, bookID) + checksum + "." + format
+}
+
+func (uc *ebookUseCase) ImportFile(ctx context.Context, data []byte, filename string, bookID uuid.UUID, force bool) (*entities.BookFileImport, error) {
+	ctx, span := tracing.Start(ctx, "EbookUseCase.ImportFile")
+	defer span.End()
+	span.SetAttributes(attribute.Int("file.size", len(data)))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if existing, err := uc.existingFile(ctx, checksum); existing != nil || err != nil {
+		if err != nil {
+			logger.Error("Failed to check for a duplicate file", zap.Error(err))
+			tracing.Fail(span, err)
+		}
+		return existing, err
+	}
+
+	metadata, err := uc.parser.Parse(data)
+	if err != nil {
+		logger.Warn("Rejected book file", zap.String("filename", filename), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("file.format", metadata.Format))
+	dto := metadata.CreateBookDTO()
+	if dto.Title == "" {
+		dto.Title = strings.TrimSuffix(path.Base(filename), path.Ext(filename))
+	}
+	dto.Force = force
+
+	file := &entities.BookFile{
+		Format:      metadata.Format,
+		ContentType: metadata.ContentType,
+		Filename:    cleanFilename(filename, metadata.Format),
+		Size:        int64(len(data)),
+		Checksum:    checksum,
+	}
+
+	// The file is stored once its book is known, inside the transaction, so
+	// that a failure to store it leaves no book or record behind
+	result := &entities.BookFileImport{}
+	err = uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		book, created, err := uc.targetBook(ctx, bookID, metadata, &dto)
+		if err != nil {
+			return err
+		}
+		file.BookID = book.ID
+		file.StorageKey = ebookKey(book.TenantID, book.ID, checksum, metadata.Format)
+		if err := uc.store.Put(ctx, file.StorageKey, bytes.NewReader(data), file.Size, file.ContentType); err != nil {
+			return err
+		}
+		if result.File, err = uc.bookRepo.AddFile(ctx, file); err != nil {
+			return err
+		}
+		result.Book, result.Created = book, created
+		return nil
+	})
+	if errors.Is(err, entities.ErrDuplicateBookFile) {
+		// A concurrent import of the same file won; its object may be the
+		// one just stored
+		if existing, _ := uc.existingFile(ctx, checksum); existing != nil {
+			if existing.File.StorageKey != file.StorageKey {
+				uc.removeFile(ctx, file.StorageKey)
+			}
+			return existing, nil
+		}
+	}
+	if err != nil {
+		if file.StorageKey != "" {
+			uc.removeFile(ctx, file.StorageKey)
+		}
+		logger.Error("Failed to import book file", zap.String("filename", filename), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	if len(metadata.Cover) > 0 && result.Book.CoverETag == "" && uc.coverUseCase != nil {
+		// The file is imported either way; an unusable cover is only noted
+		if book, err := uc.coverUseCase.UploadCover(ctx, result.Book.ID, metadata.Cover); err != nil {
+			logger.Warn("Failed to use the file's cover", zap.String("id", result.Book.ID.String()), zap.Error(err))
+		} else {
+			result.Book = book
+		}
+	}
+
+	logger.Info("Book file imported successfully",
+		zap.String("id", result.Book.ID.String()),
+		zap.String("file_id", result.File.ID.String()),
+		zap.Bool("created", result.Created))
+	return result, nil
+}
+
+// existingFile returns the import of a file already in the library, or nil
+func (uc *ebookUseCase) existingFile(ctx context.Context, checksum string) (*entities.BookFileImport, error) {
+	file, err := uc.bookRepo.FindFileByChecksum(ctx, checksum)
+	if errors.Is(err, entities.ErrBookFileNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	book, err := uc.bookRepo.GetByID(ctx, file.BookID)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.BookFileImport{Book: book, File: file, Duplicate: true}, nil
+}
+
+// targetBook finds or creates the book a file belongs to, filling in the
+// metadata an existing book lacks, and reports whether it was created. An
+// existing book is read again locked and from the database, so that a
+// concurrent edit is not overwritten.
+func (uc *ebookUseCase) targetBook(ctx context.Context, bookID uuid.UUID, metadata *ebooks.Metadata, dto *entities.CreateBookDTO) (*entities.Book, bool, error) {
+	if bookID == uuid.Nil && metadata.ISBN != "" {
+		candidates, err := uc.bookRepo.FindSimilar(ctx, &entities.Book{Title: dto.Title, ISBN: metadata.ISBN}, isbnMatchLimit)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, candidate := range candidates {
+			if candidate.ISBN == metadata.ISBN {
+				bookID = candidate.ID
+				break
+			}
+		}
+	}
+
+	var book *entities.Book
+	if bookID != uuid.Nil {
+		found, err := uc.bookRepo.GetByIDForUpdate(ctx, bookID)
+		if err != nil {
+			return nil, false, err
+		}
+		book = found
+	}
+
+	if book == nil {
+		created, err := uc.bookUseCase.CreateBook(ctx, dto)
+		return created, err == nil, err
+	}
+	if !book.FillMetadata(metadata.ISBN, dto.BookMetadata) {
+		return book, false, nil
+	}
+	updated, err := uc.bookRepo.Update(ctx, book.ID, book)
+	if err != nil {
+		return nil, false, err
+	}
+	return updated, false, uc.outbox.Append(ctx, events.NewBookUpdated(updated))
+}
+
+// removeFile deletes a stored file. Failures only leave an unused object
+// behind, which goes with the book, so they are logged.
+func (uc *ebookUseCase) removeFile(ctx context.Context, key string) {
+	if err := uc.store.Delete(ctx, key); err != nil {
+		logging.FromContext(ctx, uc.logger).Warn("Failed to remove book file", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// cleanFilename keeps the base name of an uploaded file, without control
+// characters, ending in the extension of its format
+func cleanFilename(filename, format string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, path.Base(strings.ReplaceAll(filename, "\\", "/")))
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		name = "book"
+	}
+	extension := "." + format
+	if !strings.EqualFold(path.Ext(name), extension) {
+		name += extension
+	}
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		name = string(runes[:maxFilenameLength-len(extension)]) + extension
+	}
+	return name
+}
+
+func (uc *ebookUseCase) ListFiles(ctx context.Context, bookID uuid.UUID) ([]*entities.BookFile, error) {
+	ctx, span := tracing.Start(ctx, "EbookUseCase.ListFiles")
+	defer span.End()
+	span.SetAttributes(attribute.String("book.id", bookID.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	// An empty list is only an answer for a book that exists
+	if _, err := uc.bookRepo.GetByID(ctx, bookID); err != nil {
+		logger.Error("Failed to get book", zap.String("id", bookID.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	files, err := uc.bookRepo.ListFiles(ctx, bookID)
+	if err != nil {
+		logger.Error("Failed to list book files", zap.String("id", bookID.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return files, nil
+}
+
+func (uc *ebookUseCase) OpenFile(ctx context.Context, bookID, fileID uuid.UUID) (*EbookFile, error) {
+	ctx, span := tracing.Start(ctx, "EbookUseCase.OpenFile")
+	defer span.End()
+	span.SetAttributes(attribute.String("book.id", bookID.String()), attribute.String("file.id", fileID.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	file, err := uc.bookRepo.GetFile(ctx, bookID, fileID)
+	if err != nil {
+		logger.Error("Failed to get book file", zap.String("file_id", fileID.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	object, err := uc.store.Get(ctx, file.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, entities.ErrBookFileNotFound
+	}
+	if err != nil {
+		logger.Error("Failed to read book file", zap.String("file_id", fileID.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return &EbookFile{Object: *object, File: file}, nil
+}
+
+func (uc *ebookUseCase) DeleteFile(ctx context.Context, bookID, fileID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "EbookUseCase.DeleteFile")
+	defer span.End()
+	span.SetAttributes(attribute.String("book.id", bookID.String()), attribute.String("file.id", fileID.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	file, err := uc.bookRepo.GetFile(ctx, bookID, fileID)
+	if err == nil {
+		err = uc.bookRepo.DeleteFile(ctx, bookID, fileID)
+	}
+	if err != nil {
+		logger.Error("Failed to delete book file", zap.String("file_id", fileID.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return err
+	}
+
+	uc.removeFile(ctx, file.StorageKey)
+	logger.Info("Book file deleted successfully", zap.String("id", bookID.String()), zap.String("file_id", fileID.String()))
+	return nil
+}
+
+// HandleEvent runs once the deletion has committed, when the files of a
+// plain deletion are gone with the book and those of a merge belong to the
+// survivor. The latter are moved under the survivor's prefix, so that they
+// are removed with it in turn. Redelivery finds them moved already.
+func (uc *ebookUseCase) HandleEvent(ctx context.Context, evt *events.Event) error {
+	if evt.Type != events.BookDeleted {
+		return nil
+	}
+	var data events.BookDeletedData
+	if err := json.Unmarshal(evt.Data, &data); err != nil {
+		return err
+	}
+	if data.MergedInto != nil {
+		ctx = tenancy.WithTenant(ctx, &entities.Tenant{ID: evt.TenantID})
+		if err := uc.adoptFiles(ctx, evt.TenantID, evt.AggregateID, *data.MergedInto); err != nil {
+			return err
+		}
+	}
+	return uc.store.DeletePrefix(ctx, ebookPrefix(evt.TenantID, evt.AggregateID))
+}
+
+// adoptFiles copies the survivor's files still stored under the merged
+// book's prefix to its own, and records their new keys
+func (uc *ebookUseCase) adoptFiles(ctx context.Context, tenantID, mergedID, survivorID uuid.UUID) error {
+	files, err := uc.bookRepo.ListFiles(ctx, survivorID)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.StorageKey, ebookPrefix(tenantID, mergedID)) {
+			continue
+		}
+		key := ebookKey(tenantID, survivorID, file.Checksum, file.Format)
+		if err := uc.copyObject(ctx, file.StorageKey, key); err != nil {
+			return err
+		}
+		if err := uc.bookRepo.SetFileStorageKey(ctx, file.ID, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (uc *ebookUseCase) copyObject(ctx context.Context, from, to string) error {
+	object, err := uc.store.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	defer object.Body.Close()
+	return uc.store.Put(ctx, to, object.Body, object.Size, object.ContentType)
+}
@@ -0,0 +1,224 @@
+package usecases
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/ebooks"
+	"byfood-library/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testEPUB builds a minimal EPUB 2 whose package document has the given
+// metadata elements
+func testEPUB(t *testing.T, metadata string) []byte {
+	entries := []struct{ name, body string }{
+		{"mimetype", ebooks.ContentTypeEPUB},
+		{"META-INF/container.xml", `<container><rootfiles><rootfile full-path="content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`},
+		{"content.opf", `<package xmlns:dc="http://purl.org/dc/elements/1.1/"><metadata>` + metadata + `</metadata></package>`},
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := zw.Create(entry.name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(entry.body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestEbookUseCase(t *testing.T) {
+	tenantID := uuid.New()
+	data := testEPUB(t, `<dc:title>Clean Code</dc:title><dc:creator>Robert C. Martin</dc:creator>
+		<dc:date>2008-08-01</dc:date><dc:identifier>urn:isbn:9780132350884</dc:identifier><dc:publisher>Prentice Hall</dc:publisher>`)
+	checksum := sha256Hex(data)
+
+	setup := func(t *testing.T) (EbookUseCase, *MockBookRepository, *recordingOutbox, storage.BlobStore) {
+		store, err := storage.NewLocal(t.TempDir())
+		require.NoError(t, err)
+		mockRepo := new(MockBookRepository)
+		outbox := &recordingOutbox{}
+		bookUseCase := NewBookUseCase(mockRepo, outbox, passthroughTransactor{}, nil, zap.NewNop())
+		useCase := NewEbookUseCase(mockRepo, outbox, passthroughTransactor{}, bookUseCase, nil, store, ebooks.NewParser(ebooks.Config{}), zap.NewNop())
+		return useCase, mockRepo, outbox, store
+	}
+	addFile := func(mockRepo *MockBookRepository) {
+		created := &entities.BookFile{}
+		mockRepo.On("AddFile", mock.Anything, mock.AnythingOfType("*entities.BookFile")).Run(func(args mock.Arguments) {
+			*created = *args.Get(1).(*entities.BookFile)
+			created.ID = uuid.New()
+		}).Return(created, nil).Once()
+	}
+
+	t.Run("creates a book from the file's metadata", func(t *testing.T) {
+		useCase, mockRepo, outbox, store := setup(t)
+		book := &entities.Book{ID: uuid.New(), TenantID: tenantID, Title: "Clean Code", Author: "Robert C. Martin", Year: 2008, ISBN: "9780132350884"}
+		mockRepo.On("FindFileByChecksum", mock.Anything, checksum).Return(nil, entities.ErrBookFileNotFound).Once()
+		mockRepo.On("FindSimilar", mock.Anything, mock.Anything, isbnMatchLimit).Return([]*entities.Book{}, nil).Once()
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(b *entities.Book) bool {
+			return b.Title == "Clean Code" && b.Author == "Robert C. Martin" && b.Year == 2008 && b.Publisher == "Prentice Hall"
+		})).Return(book, nil).Once()
+		addFile(mockRepo)
+
+		result, err := useCase.ImportFile(context.Background(), data, "../clean\x00code.epub", uuid.Nil, false)
+
+		require.NoError(t, err)
+		assert.True(t, result.Created)
+		assert.False(t, result.Duplicate)
+		assert.Equal(t, book.ID, result.File.BookID)
+		assert.Equal(t, "cleancode.epub", result.File.Filename)
+		assert.Equal(t, ebooks.FormatEPUB, result.File.Format)
+		assert.Equal(t, checksum, result.File.Checksum)
+		require.Len(t, outbox.events, 1)
+		assert.Equal(t, events.BookCreated, outbox.events[0].Type)
+
+		object, err := store.Get(context.Background(), ebookKey(tenantID, book.ID, checksum, ebooks.FormatEPUB))
+		require.NoError(t, err)
+		defer object.Body.Close()
+		stored, _ := io.ReadAll(object.Body)
+		assert.Equal(t, data, stored)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("fills in the book sharing the file's ISBN", func(t *testing.T) {
+		useCase, mockRepo, outbox, _ := setup(t)
+		book := &entities.Book{ID: uuid.New(), TenantID: tenantID, Title: "Clean Code", Author: "Martin", Year: 2008, ISBN: "9780132350884"}
+		mockRepo.On("FindFileByChecksum", mock.Anything, checksum).Return(nil, entities.ErrBookFileNotFound).Once()
+		mockRepo.On("FindSimilar", mock.Anything, mock.Anything, isbnMatchLimit).Return([]*entities.Book{book}, nil).Once()
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("Update", mock.Anything, book.ID, mock.MatchedBy(func(b *entities.Book) bool {
+			return b.Publisher == "Prentice Hall" && b.Author == "Martin"
+		})).Return(book, nil).Once()
+		addFile(mockRepo)
+
+		result, err := useCase.ImportFile(context.Background(), data, "clean-code.epub", uuid.Nil, false)
+
+		require.NoError(t, err)
+		assert.False(t, result.Created)
+		assert.Equal(t, book.ID, result.Book.ID)
+		require.Len(t, outbox.events, 1)
+		assert.Equal(t, events.BookUpdated, outbox.events[0].Type)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("import keeps an edit made during it", func(t *testing.T) {
+		useCase, mockRepo, _, _ := setup(t)
+		book := &entities.Book{ID: uuid.New(), TenantID: tenantID, Title: "Clean Code", Author: "Martin", Year: 2008, ISBN: "9780132350884"}
+		edited := *book
+		edited.Author = "Robert C. Martin"
+		edited.PageCount = 464
+		mockRepo.On("FindFileByChecksum", mock.Anything, checksum).Return(nil, entities.ErrBookFileNotFound).Once()
+		mockRepo.On("FindSimilar", mock.Anything, mock.Anything, isbnMatchLimit).Return([]*entities.Book{book}, nil).Once()
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(&edited, nil).Once()
+		mockRepo.On("Update", mock.Anything, book.ID, mock.MatchedBy(func(b *entities.Book) bool {
+			return b.Author == "Robert C. Martin" && b.PageCount == 464 && b.Publisher == "Prentice Hall"
+		})).Return(&edited, nil).Once()
+		addFile(mockRepo)
+
+		_, err := useCase.ImportFile(context.Background(), data, "clean-code.epub", uuid.Nil, false)
+
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("a file already in the library is not stored again", func(t *testing.T) {
+		useCase, mockRepo, _, _ := setup(t)
+		book := &entities.Book{ID: uuid.New(), TenantID: tenantID, Title: "Clean Code"}
+		file := &entities.BookFile{ID: uuid.New(), BookID: book.ID, Checksum: checksum}
+		mockRepo.On("FindFileByChecksum", mock.Anything, checksum).Return(file, nil).Once()
+		mockRepo.On("GetByID", mock.Anything, book.ID).Return(book, nil).Once()
+
+		result, err := useCase.ImportFile(context.Background(), data, "clean-code.epub", uuid.Nil, false)
+
+		require.NoError(t, err)
+		assert.True(t, result.Duplicate)
+		assert.Equal(t, file, result.File)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("rejects other files", func(t *testing.T) {
+		useCase, mockRepo, _, _ := setup(t)
+		mockRepo.On("FindFileByChecksum", mock.Anything, mock.Anything).Return(nil, entities.ErrBookFileNotFound).Once()
+
+		_, err := useCase.ImportFile(context.Background(), []byte("plain text"), "notes.txt", uuid.Nil, false)
+
+		assert.ErrorIs(t, err, entities.ErrUnsupportedBookFile)
+	})
+
+	t.Run("a failed import removes the stored file", func(t *testing.T) {
+		useCase, mockRepo, _, store := setup(t)
+		book := &entities.Book{ID: uuid.New(), TenantID: tenantID, Title: "Clean Code", ISBN: "9780132350884", BookMetadata: entities.BookMetadata{Publisher: "Prentice Hall"}}
+		mockRepo.On("FindFileByChecksum", mock.Anything, checksum).Return(nil, entities.ErrBookFileNotFound).Once()
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("AddFile", mock.Anything, mock.Anything).Return(nil, entities.ErrDatabaseError).Once()
+
+		_, err := useCase.ImportFile(context.Background(), data, "clean-code.epub", book.ID, false)
+
+		assert.ErrorIs(t, err, entities.ErrDatabaseError)
+		_, err = store.Get(context.Background(), ebookKey(tenantID, book.ID, checksum, ebooks.FormatEPUB))
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("files of merged books move to the survivor", func(t *testing.T) {
+		useCase, mockRepo, _, store := setup(t)
+		merged, survivor := uuid.New(), uuid.New()
+		oldKey := ebookKey(tenantID, merged, checksum, ebooks.FormatPDF)
+		newKey := ebookKey(tenantID, survivor, checksum, ebooks.FormatPDF)
+		require.NoError(t, store.Put(context.Background(), oldKey, strings.NewReader("%PDF-"), 5, ebooks.ContentTypePDF))
+		file := &entities.BookFile{ID: uuid.New(), BookID: survivor, Format: ebooks.FormatPDF, Checksum: checksum, StorageKey: oldKey}
+		mockRepo.On("ListFiles", mock.Anything, survivor).Return([]*entities.BookFile{file}, nil).Once()
+		mockRepo.On("SetFileStorageKey", mock.Anything, file.ID, newKey).Return(nil).Once()
+
+		require.NoError(t, useCase.HandleEvent(context.Background(), events.NewBookMerged(tenantID, merged, survivor)))
+
+		_, err := store.Get(context.Background(), oldKey)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		object, err := store.Get(context.Background(), newKey)
+		require.NoError(t, err)
+		object.Body.Close()
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("files of deleted books are removed", func(t *testing.T) {
+		useCase, _, _, store := setup(t)
+		bookID := uuid.New()
+		key := ebookKey(tenantID, bookID, checksum, ebooks.FormatEPUB)
+		require.NoError(t, store.Put(context.Background(), key, bytes.NewReader(data), int64(len(data)), ebooks.ContentTypeEPUB))
+
+		require.NoError(t, useCase.HandleEvent(context.Background(), events.NewBookDeleted(tenantID, bookID)))
+
+		_, err := store.Get(context.Background(), key)
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+func TestCleanFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		expected string
+	}{
+		{"clean-code.pdf", "clean-code.pdf"},
+		{"clean-code.epub", "clean-code.epub.pdf"},
+		{"C:\\Users\\me\\Clean Code.PDF", "Clean Code.PDF"},
+		{"../../etc/passwd", "passwd.pdf"},
+		{"", "book.pdf"},
+		{strings.Repeat("a", 300) + ".pdf", strings.Repeat("a", 251) + ".pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			assert.Equal(t, tt.expected, cleanFilename(tt.filename, ebooks.FormatPDF))
+		})
+	}
+}
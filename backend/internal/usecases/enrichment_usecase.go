@@ -0,0 +1,131 @@
+package usecases
+
+import (
+	"context"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/enrichment"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+type EnrichmentUseCase interface {
+	// LookupBook asks the bibliographic sources about a book that is not in
+	// the library yet and returns what they know as a CreateBookDTO
+	LookupBook(ctx context.Context, query entities.MetadataQuery) (*entities.BookLookup, error)
+	// EnrichBook fills the ISBN and metadata fields a book lacks from the
+	// bibliographic sources
+	EnrichBook(ctx context.Context, id uuid.UUID) (*entities.Book, error)
+}
+
+type enrichmentUseCase struct {
+	bookRepo   repositories.BookRepository
+	outbox     repositories.OutboxRepository
+	transactor repositories.Transactor
+	provider   enrichment.MetadataProvider
+	logger     *zap.Logger
+}
+
+// NewEnrichmentUseCase returns an EnrichmentUseCase asking provider, usually
+// a cached enrichment.Chain. Enriched books are updated with a BookUpdated
+// event in outbox, as BookUseCase.UpdateBook does.
+func NewEnrichmentUseCase(bookRepo repositories.BookRepository, outbox repositories.OutboxRepository, transactor repositories.Transactor, provider enrichment.MetadataProvider, logger *zap.Logger) EnrichmentUseCase {
+	return &enrichmentUseCase{
+		bookRepo:   bookRepo,
+		outbox:     outbox,
+		transactor: transactor,
+		provider:   provider,
+		logger:     logger,
+	}
+}
+
+func (uc *enrichmentUseCase) LookupBook(ctx context.Context, query entities.MetadataQuery) (*entities.BookLookup, error) {
+	ctx, span := tracing.Start(ctx, "EnrichmentUseCase.LookupBook")
+	defer span.End()
+	logger := logging.FromContext(ctx, uc.logger)
+
+	if err := query.Validate(); err != nil {
+		logger.Error("Validation failed for MetadataQuery", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	metadata, err := uc.provider.Lookup(ctx, query)
+	if err != nil {
+		logger.Error("Failed to look up book metadata", zap.String("isbn", query.ISBN), zap.String("title", query.Title), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.StringSlice("enrichment.sources", metadata.Sources))
+	return &entities.BookLookup{
+		Book: entities.CreateBookDTO{
+			Title:        metadata.Title,
+			Author:       metadata.Author,
+			Year:         metadata.Year,
+			ISBN:         metadata.ISBN,
+			BookMetadata: metadata.BookMetadata.Sanitized(),
+		},
+		Covers:  metadata.Covers,
+		Sources: metadata.Sources,
+	}, nil
+}
+
+func (uc *enrichmentUseCase) EnrichBook(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	ctx, span := tracing.Start(ctx, "EnrichmentUseCase.EnrichBook")
+	defer span.End()
+	span.SetAttributes(attribute.String("book.id", id.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	book, err := uc.bookRepo.GetByID(ctx, id)
+	if err != nil {
+		logger.Error("Failed to get book", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	query := entities.MetadataQuery{ISBN: book.ISBN}
+	if book.ISBN == "" {
+		query = entities.MetadataQuery{Title: book.Title, Author: book.Author}
+	}
+	metadata, err := uc.provider.Lookup(ctx, query)
+	if err != nil {
+		logger.Error("Failed to look up book metadata", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.StringSlice("enrichment.sources", metadata.Sources))
+
+	// The upstream lookup happens outside the transaction; the book is read
+	// again inside it, locked and from the database, so that a concurrent
+	// edit is not overwritten
+	found := metadata.BookMetadata.Sanitized()
+	var enriched *entities.Book
+	err = uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.bookRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		enriched = current
+		if !current.FillMetadata(metadata.ISBN, found) {
+			return nil
+		}
+		if enriched, err = uc.bookRepo.Update(ctx, id, current); err != nil {
+			return err
+		}
+		return uc.outbox.Append(ctx, events.NewBookUpdated(enriched))
+	})
+	if err != nil {
+		logger.Error("Failed to enrich book", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	logger.Info("Book enriched successfully", zap.String("id", id.String()), zap.Strings("sources", metadata.Sources))
+	return enriched, nil
+}
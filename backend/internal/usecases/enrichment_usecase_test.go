@@ -0,0 +1,128 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/enrichment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestEnrichmentUseCase(t *testing.T) {
+	sources := enrichment.NewFixture([]*enrichment.Metadata{{
+		ISBN:   "9780132350884",
+		Title:  "Clean Code",
+		Author: "Robert C. Martin",
+		Year:   2008,
+		BookMetadata: entities.BookMetadata{
+			Publisher: "Prentice Hall",
+			PageCount: 464,
+			Subjects:  []string{"Software", "software", "Agile"},
+			CoverURL:  "ftp://covers.example/clean-code.jpg",
+		},
+	}})
+
+	t.Run("lookup pre-fills a new book", func(t *testing.T) {
+		useCase := NewEnrichmentUseCase(new(MockBookRepository), &recordingOutbox{}, passthroughTransactor{}, enrichment.Chain{sources}, zap.NewNop())
+
+		lookup, err := useCase.LookupBook(context.Background(), entities.MetadataQuery{ISBN: "0-13-235088-2"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Clean Code", lookup.Book.Title)
+		assert.Equal(t, 2008, lookup.Book.Year)
+		assert.Equal(t, []string{"Software", "Agile"}, []string(lookup.Book.Subjects))
+		assert.Empty(t, lookup.Book.CoverURL, "only http and https covers are kept")
+		assert.Equal(t, []string{"fixture"}, lookup.Sources)
+		assert.NoError(t, lookup.Book.Validate())
+	})
+
+	t.Run("lookup needs an isbn or a title", func(t *testing.T) {
+		useCase := NewEnrichmentUseCase(new(MockBookRepository), &recordingOutbox{}, passthroughTransactor{}, sources, zap.NewNop())
+
+		_, err := useCase.LookupBook(context.Background(), entities.MetadataQuery{Author: "Robert C. Martin"})
+		assert.ErrorIs(t, err, entities.ErrInvalidLookup)
+
+		_, err = useCase.LookupBook(context.Background(), entities.MetadataQuery{Title: "Unknown"})
+		assert.ErrorIs(t, err, entities.ErrMetadataNotFound)
+	})
+
+	t.Run("enrich fills only missing fields", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		outbox := &recordingOutbox{}
+		useCase := NewEnrichmentUseCase(mockRepo, outbox, passthroughTransactor{}, sources, zap.NewNop())
+		book := &entities.Book{
+			ID: uuid.New(), Title: "Clean code", Author: "Robert Martin", Year: 2008,
+			BookMetadata: entities.BookMetadata{Publisher: "Pearson"},
+		}
+		mockRepo.On("GetByID", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("Update", mock.Anything, book.ID, mock.MatchedBy(func(b *entities.Book) bool {
+			return b.ISBN == "9780132350884" && b.Publisher == "Pearson" && b.PageCount == 464
+		})).Return(book, nil).Once()
+
+		_, err := useCase.EnrichBook(context.Background(), book.ID)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		if assert.Len(t, outbox.events, 1) {
+			assert.Equal(t, events.BookUpdated, outbox.events[0].Type)
+		}
+	})
+
+	t.Run("enrich leaves a complete book alone", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		outbox := &recordingOutbox{}
+		useCase := NewEnrichmentUseCase(mockRepo, outbox, passthroughTransactor{}, sources, zap.NewNop())
+		book := &entities.Book{
+			ID: uuid.New(), Title: "Clean Code", Author: "Robert C. Martin", Year: 2008, ISBN: "9780132350884",
+			BookMetadata: entities.BookMetadata{Publisher: "Pearson", PageCount: 431, Subjects: []string{"Code"}},
+		}
+		mockRepo.On("GetByID", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(book, nil).Once()
+
+		result, err := useCase.EnrichBook(context.Background(), book.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, book, result)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+		assert.Empty(t, outbox.events)
+	})
+
+	t.Run("enrich keeps an edit made during the lookup", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		useCase := NewEnrichmentUseCase(mockRepo, &recordingOutbox{}, passthroughTransactor{}, sources, zap.NewNop())
+		book := &entities.Book{ID: uuid.New(), Title: "Clean code", Author: "Robert Martin", Year: 2008}
+		edited := *book
+		edited.Publisher = "Addison-Wesley"
+		mockRepo.On("GetByID", mock.Anything, book.ID).Return(book, nil).Once()
+		mockRepo.On("GetByIDForUpdate", mock.Anything, book.ID).Return(&edited, nil).Once()
+		mockRepo.On("Update", mock.Anything, book.ID, mock.MatchedBy(func(b *entities.Book) bool {
+			return b.Publisher == "Addison-Wesley" && b.PageCount == 464
+		})).Return(&edited, nil).Once()
+
+		_, err := useCase.EnrichBook(context.Background(), book.ID)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("enrich reports missing books and records", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		useCase := NewEnrichmentUseCase(mockRepo, &recordingOutbox{}, passthroughTransactor{}, sources, zap.NewNop())
+		missing := uuid.New()
+		unknown := &entities.Book{ID: uuid.New(), Title: "Unknown", Author: "Nobody", Year: 2000}
+		mockRepo.On("GetByID", mock.Anything, missing).Return(nil, entities.ErrBookNotFound).Once()
+		mockRepo.On("GetByID", mock.Anything, unknown.ID).Return(unknown, nil).Once()
+
+		_, err := useCase.EnrichBook(context.Background(), missing)
+		assert.ErrorIs(t, err, entities.ErrBookNotFound)
+
+		_, err = useCase.EnrichBook(context.Background(), unknown.ID)
+		assert.ErrorIs(t, err, entities.ErrMetadataNotFound)
+	})
+}
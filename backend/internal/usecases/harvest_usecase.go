@@ -0,0 +1,80 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// HarvestUseCase reads the catalogue for metadata harvesting, deleted books
+// included, as OAI-PMH does
+type HarvestUseCase interface {
+	Harvest(ctx context.Context, query entities.HarvestQuery) ([]*entities.HarvestRecord, error)
+	GetRecord(ctx context.Context, id uuid.UUID) (*entities.HarvestRecord, error)
+	// EarliestDatestamp is the datestamp of the oldest record, or the zero
+	// time without any
+	EarliestDatestamp(ctx context.Context) (time.Time, error)
+}
+
+type harvestUseCase struct {
+	bookRepo repositories.BookRepository
+	logger   *zap.Logger
+}
+
+func NewHarvestUseCase(bookRepo repositories.BookRepository, logger *zap.Logger) HarvestUseCase {
+	return &harvestUseCase{
+		bookRepo: bookRepo,
+		logger:   logger,
+	}
+}
+
+func (uc *harvestUseCase) Harvest(ctx context.Context, query entities.HarvestQuery) ([]*entities.HarvestRecord, error) {
+	ctx, span := tracing.Start(ctx, "HarvestUseCase.Harvest")
+	defer span.End()
+	span.SetAttributes(attribute.Int("harvest.limit", query.Limit), attribute.Bool("harvest.continued", query.After != nil))
+
+	records, err := uc.bookRepo.Harvest(ctx, query)
+	if err != nil {
+		logging.FromContext(ctx, uc.logger).Error("Failed to harvest records", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("harvest.records", len(records)))
+	return records, nil
+}
+
+func (uc *harvestUseCase) GetRecord(ctx context.Context, id uuid.UUID) (*entities.HarvestRecord, error) {
+	ctx, span := tracing.Start(ctx, "HarvestUseCase.GetRecord")
+	defer span.End()
+	span.SetAttributes(attribute.String("book.id", id.String()))
+
+	record, err := uc.bookRepo.GetHarvestRecord(ctx, id)
+	if err != nil {
+		if entities.KindOf(err) != entities.KindNotFound {
+			logging.FromContext(ctx, uc.logger).Error("Failed to get harvest record", zap.String("id", id.String()), zap.Error(err))
+			tracing.Fail(span, err)
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+func (uc *harvestUseCase) EarliestDatestamp(ctx context.Context) (time.Time, error) {
+	ctx, span := tracing.Start(ctx, "HarvestUseCase.EarliestDatestamp")
+	defer span.End()
+
+	earliest, err := uc.bookRepo.EarliestDatestamp(ctx)
+	if err != nil {
+		logging.FromContext(ctx, uc.logger).Error("Failed to read earliest datestamp", zap.Error(err))
+		tracing.Fail(span, err)
+		return time.Time{}, err
+	}
+	return earliest, nil
+}
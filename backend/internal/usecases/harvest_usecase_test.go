@@ -0,0 +1,53 @@
+package usecases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHarvestUseCase_Harvest(t *testing.T) {
+	query := entities.HarvestQuery{From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Limit: 10}
+
+	t.Run("returns books and deletions", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		records := []*entities.HarvestRecord{
+			{ID: uuid.New(), Datestamp: query.From, Book: &entities.Book{Title: "Clean Code"}},
+			{ID: uuid.New(), Datestamp: query.From.Add(time.Hour), Deleted: true},
+		}
+		mockRepo.On("Harvest", mock.Anything, query).Return(records, nil).Once()
+
+		result, err := NewHarvestUseCase(mockRepo, zap.NewNop()).Harvest(context.Background(), query)
+
+		require.NoError(t, err)
+		assert.Equal(t, records, result)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("repository errors are returned", func(t *testing.T) {
+		mockRepo := new(MockBookRepository)
+		mockRepo.On("Harvest", mock.Anything, query).Return(nil, entities.ErrDatabaseError).Once()
+
+		_, err := NewHarvestUseCase(mockRepo, zap.NewNop()).Harvest(context.Background(), query)
+
+		assert.ErrorIs(t, err, entities.ErrDatabaseError)
+	})
+}
+
+func TestHarvestUseCase_GetRecord(t *testing.T) {
+	mockRepo := new(MockBookRepository)
+	id := uuid.New()
+	mockRepo.On("GetHarvestRecord", mock.Anything, id).Return(nil, entities.ErrBookNotFound).Once()
+
+	_, err := NewHarvestUseCase(mockRepo, zap.NewNop()).GetRecord(context.Background(), id)
+
+	assert.ErrorIs(t, err, entities.ErrBookNotFound)
+	mockRepo.AssertExpectations(t)
+}
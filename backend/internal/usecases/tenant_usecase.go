@@ -0,0 +1,177 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type TenantUseCase interface {
+	CreateTenant(ctx context.Context, dto *entities.CreateTenantDTO) (*entities.TenantWithAPIKey, error)
+	GetTenantByID(ctx context.Context, id uuid.UUID) (*entities.Tenant, error)
+	GetAllTenants(ctx context.Context) ([]*entities.Tenant, error)
+	SuspendTenant(ctx context.Context, id uuid.UUID) (*entities.Tenant, error)
+	ActivateTenant(ctx context.Context, id uuid.UUID) (*entities.Tenant, error)
+	UpdateTenantSettings(ctx context.Context, id uuid.UUID, dto *entities.UpdateTenantSettingsDTO) (*entities.Tenant, error)
+}
+
+// TenantCache is implemented by components that cache tenant lookups and
+// must forget them when a tenant changes
+type TenantCache interface {
+	Invalidate()
+}
+
+type tenantUseCase struct {
+	tenantRepo repositories.TenantRepository
+	cache      TenantCache
+	logger     *zap.Logger
+}
+
+func NewTenantUseCase(tenantRepo repositories.TenantRepository, cache TenantCache, logger *zap.Logger) TenantUseCase {
+	return &tenantUseCase{
+		tenantRepo: tenantRepo,
+		cache:      cache,
+		logger:     logger,
+	}
+}
+
+func (uc *tenantUseCase) CreateTenant(ctx context.Context, dto *entities.CreateTenantDTO) (*entities.TenantWithAPIKey, error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCase.CreateTenant")
+	defer span.End()
+	logger := logging.FromContext(ctx, uc.logger)
+
+	if err := dto.Validate(); err != nil {
+		logger.Error("Validation failed for CreateTenantDTO", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	apiKey, err := generateAPIKey()
+	if err != nil {
+		logger.Error("Failed to generate tenant API key", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	tenant := dto.ToTenant()
+	tenant.APIKeyHash = entities.HashAPIKey(apiKey)
+
+	createdTenant, err := uc.tenantRepo.Create(ctx, tenant)
+	if err != nil {
+		logger.Error("Failed to create tenant", zap.String("slug", tenant.Slug), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	// The new slug and key may be cached as unknown
+	uc.cache.Invalidate()
+	logger.Info("Tenant created successfully", zap.String("id", createdTenant.ID.String()), zap.String("slug", createdTenant.Slug))
+	return &entities.TenantWithAPIKey{Tenant: createdTenant, APIKey: apiKey}, nil
+}
+
+func (uc *tenantUseCase) GetTenantByID(ctx context.Context, id uuid.UUID) (*entities.Tenant, error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCase.GetTenantByID")
+	defer span.End()
+	span.SetAttributes(attribute.String("tenant.id", id.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	tenant, err := uc.tenantRepo.GetByID(ctx, id)
+	if err != nil {
+		logger.Error("Failed to get tenant by ID", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (uc *tenantUseCase) GetAllTenants(ctx context.Context) ([]*entities.Tenant, error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCase.GetAllTenants")
+	defer span.End()
+	logger := logging.FromContext(ctx, uc.logger)
+
+	tenants, err := uc.tenantRepo.GetAll(ctx)
+	if err != nil {
+		logger.Error("Failed to get all tenants", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return tenants, nil
+}
+
+func (uc *tenantUseCase) SuspendTenant(ctx context.Context, id uuid.UUID) (*entities.Tenant, error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCase.SuspendTenant")
+	defer span.End()
+	span.SetAttributes(attribute.String("tenant.id", id.String()))
+
+	return uc.setStatus(ctx, id, entities.TenantStatusSuspended)
+}
+
+func (uc *tenantUseCase) ActivateTenant(ctx context.Context, id uuid.UUID) (*entities.Tenant, error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCase.ActivateTenant")
+	defer span.End()
+	span.SetAttributes(attribute.String("tenant.id", id.String()))
+
+	return uc.setStatus(ctx, id, entities.TenantStatusActive)
+}
+
+func (uc *tenantUseCase) UpdateTenantSettings(ctx context.Context, id uuid.UUID, dto *entities.UpdateTenantSettingsDTO) (*entities.Tenant, error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCase.UpdateTenantSettings")
+	defer span.End()
+	span.SetAttributes(attribute.String("tenant.id", id.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	if err := dto.Settings.Validate(); err != nil {
+		logger.Error("Validation failed for tenant settings", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	tenant, err := uc.tenantRepo.UpdateSettings(ctx, id, dto.Settings)
+	if err != nil {
+		logger.Error("Failed to update tenant settings", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	uc.cache.Invalidate()
+	logger.Info("Tenant settings updated", zap.String("id", id.String()))
+	return tenant, nil
+}
+
+func (uc *tenantUseCase) setStatus(ctx context.Context, id uuid.UUID, status entities.TenantStatus) (*entities.Tenant, error) {
+	span := trace.SpanFromContext(ctx)
+	logger := logging.FromContext(ctx, uc.logger)
+
+	if id == entities.DefaultTenantID && status == entities.TenantStatusSuspended {
+		tracing.Fail(span, entities.ErrDefaultTenantLocked)
+		return nil, entities.ErrDefaultTenantLocked
+	}
+
+	tenant, err := uc.tenantRepo.UpdateStatus(ctx, id, status)
+	if err != nil {
+		logger.Error("Failed to update tenant status", zap.String("id", id.String()), zap.String("status", string(status)), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	uc.cache.Invalidate()
+	logger.Info("Tenant status updated", zap.String("id", id.String()), zap.String("status", string(status)))
+	return tenant, nil
+}
+
+func generateAPIKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "bf_" + hex.EncodeToString(buf), nil
+}
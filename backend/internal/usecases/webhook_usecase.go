@@ -0,0 +1,242 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/logging"
+	"byfood-library/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+type WebhookUseCase interface {
+	CreateWebhook(ctx context.Context, dto *entities.CreateWebhookDTO) (*entities.WebhookWithSecret, error)
+	GetWebhookByID(ctx context.Context, id uuid.UUID) (*entities.Webhook, error)
+	GetAllWebhooks(ctx context.Context) ([]*entities.Webhook, error)
+	UpdateWebhook(ctx context.Context, id uuid.UUID, dto *entities.UpdateWebhookDTO) (*entities.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*entities.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, webhookID, deliveryID uuid.UUID) (*entities.WebhookDelivery, error)
+	// Redeliver queues the delivery's payload to be sent again as a new
+	// delivery, leaving the original in the log
+	Redeliver(ctx context.Context, webhookID, deliveryID uuid.UUID) (*entities.WebhookDelivery, error)
+}
+
+type webhookUseCase struct {
+	webhookRepo repositories.WebhookRepository
+	logger      *zap.Logger
+}
+
+func NewWebhookUseCase(webhookRepo repositories.WebhookRepository, logger *zap.Logger) WebhookUseCase {
+	return &webhookUseCase{
+		webhookRepo: webhookRepo,
+		logger:      logger,
+	}
+}
+
+func (uc *webhookUseCase) CreateWebhook(ctx context.Context, dto *entities.CreateWebhookDTO) (*entities.WebhookWithSecret, error) {
+	ctx, span := tracing.Start(ctx, "WebhookUseCase.CreateWebhook")
+	defer span.End()
+	logger := logging.FromContext(ctx, uc.logger)
+
+	if err := dto.Validate(); err != nil {
+		logger.Error("Validation failed for CreateWebhookDTO", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		logger.Error("Failed to generate webhook secret", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	webhook, err := uc.webhookRepo.Create(ctx, &entities.Webhook{
+		URL:        dto.URL,
+		EventTypes: dto.EventTypes,
+		Secret:     secret,
+		Active:     true,
+	})
+	if err != nil {
+		logger.Error("Failed to create webhook", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	logger.Info("Webhook created successfully", zap.String("id", webhook.ID.String()), zap.Strings("event_types", webhook.EventTypes))
+	return &entities.WebhookWithSecret{Webhook: webhook, Secret: secret}, nil
+}
+
+func (uc *webhookUseCase) GetWebhookByID(ctx context.Context, id uuid.UUID) (*entities.Webhook, error) {
+	ctx, span := tracing.Start(ctx, "WebhookUseCase.GetWebhookByID")
+	defer span.End()
+	span.SetAttributes(attribute.String("webhook.id", id.String()))
+
+	webhook, err := uc.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		logging.FromContext(ctx, uc.logger).Error("Failed to get webhook by ID", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (uc *webhookUseCase) GetAllWebhooks(ctx context.Context) ([]*entities.Webhook, error) {
+	ctx, span := tracing.Start(ctx, "WebhookUseCase.GetAllWebhooks")
+	defer span.End()
+
+	webhooks, err := uc.webhookRepo.GetAll(ctx)
+	if err != nil {
+		logging.FromContext(ctx, uc.logger).Error("Failed to get all webhooks", zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (uc *webhookUseCase) UpdateWebhook(ctx context.Context, id uuid.UUID, dto *entities.UpdateWebhookDTO) (*entities.Webhook, error) {
+	ctx, span := tracing.Start(ctx, "WebhookUseCase.UpdateWebhook")
+	defer span.End()
+	span.SetAttributes(attribute.String("webhook.id", id.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	if err := dto.Validate(); err != nil {
+		logger.Error("Validation failed for UpdateWebhookDTO", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	webhook, err := uc.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		logger.Error("Failed to get webhook for update", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	webhook.URL = dto.URL
+	webhook.EventTypes = dto.EventTypes
+	if dto.Active != nil {
+		if *dto.Active && !webhook.Active {
+			// Re-enabling starts the failure count afresh
+			webhook.ConsecutiveFailures = 0
+			webhook.DisabledAt = nil
+		}
+		webhook.Active = *dto.Active
+	}
+
+	updated, err := uc.webhookRepo.Update(ctx, webhook)
+	if err != nil {
+		logger.Error("Failed to update webhook", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	logger.Info("Webhook updated successfully", zap.String("id", id.String()), zap.Bool("active", updated.Active))
+	return updated, nil
+}
+
+func (uc *webhookUseCase) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "WebhookUseCase.DeleteWebhook")
+	defer span.End()
+	span.SetAttributes(attribute.String("webhook.id", id.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	if err := uc.webhookRepo.Delete(ctx, id); err != nil {
+		logger.Error("Failed to delete webhook", zap.String("id", id.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return err
+	}
+
+	logger.Info("Webhook deleted successfully", zap.String("id", id.String()))
+	return nil
+}
+
+func (uc *webhookUseCase) ListDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*entities.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "WebhookUseCase.ListDeliveries")
+	defer span.End()
+	span.SetAttributes(attribute.String("webhook.id", webhookID.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	// Distinguishes an unknown webhook from one without deliveries
+	if _, err := uc.webhookRepo.GetByID(ctx, webhookID); err != nil {
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	deliveries, err := uc.webhookRepo.ListDeliveries(ctx, webhookID, limit)
+	if err != nil {
+		logger.Error("Failed to list webhook deliveries", zap.String("webhook_id", webhookID.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (uc *webhookUseCase) GetDelivery(ctx context.Context, webhookID, deliveryID uuid.UUID) (*entities.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "WebhookUseCase.GetDelivery")
+	defer span.End()
+	span.SetAttributes(attribute.String("webhook.id", webhookID.String()), attribute.String("webhook.delivery_id", deliveryID.String()))
+
+	delivery, err := uc.webhookRepo.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		logging.FromContext(ctx, uc.logger).Error("Failed to get webhook delivery", zap.String("delivery_id", deliveryID.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (uc *webhookUseCase) Redeliver(ctx context.Context, webhookID, deliveryID uuid.UUID) (*entities.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "WebhookUseCase.Redeliver")
+	defer span.End()
+	span.SetAttributes(attribute.String("webhook.id", webhookID.String()), attribute.String("webhook.delivery_id", deliveryID.String()))
+	logger := logging.FromContext(ctx, uc.logger)
+
+	webhook, err := uc.webhookRepo.GetByID(ctx, webhookID)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	if !webhook.Active {
+		tracing.Fail(span, entities.ErrWebhookDisabled)
+		return nil, entities.ErrWebhookDisabled
+	}
+
+	original, err := uc.webhookRepo.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	redelivery := &entities.WebhookDelivery{
+		ID:           uuid.New(),
+		WebhookID:    webhookID,
+		TenantID:     original.TenantID,
+		EventID:      original.EventID,
+		EventType:    original.EventType,
+		Payload:      original.Payload,
+		RedeliveryOf: &original.ID,
+	}
+	if err := uc.webhookRepo.Enqueue(ctx, redelivery); err != nil {
+		logger.Error("Failed to queue webhook redelivery", zap.String("delivery_id", deliveryID.String()), zap.Error(err))
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	logger.Info("Webhook redelivery queued", zap.String("delivery_id", deliveryID.String()), zap.String("redelivery_id", redelivery.ID.String()))
+	return uc.webhookRepo.GetDelivery(ctx, webhookID, redelivery.ID)
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,286 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/domain/repositories"
+	"byfood-library/internal/messaging"
+	"byfood-library/internal/middleware"
+	"byfood-library/internal/tenancy"
+	"go.uber.org/zap"
+)
+
+// maxResponseSnippet is how much of a receiver's response body is kept in
+// the delivery log
+const maxResponseSnippet = 1024
+
+var errPrivateAddress = errors.New("webhook receiver resolves to a private or loopback address")
+
+// Config controls delivery timing, retries and when webhooks are disabled
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Timeout      time.Duration
+	// A delivery is retried after BackoffBase * 2^(attempts-1), with
+	// jitter, capped at BackoffMax, until MaxAttempts have failed
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// FailureThreshold disables a webhook after that many failed attempts
+	// in a row across its deliveries; 0 never disables
+	FailureThreshold int
+	// AllowPrivateNetworks permits receivers on loopback, private and
+	// link-local addresses, which are refused by default
+	AllowPrivateNetworks bool
+}
+
+// Dispatcher queues a delivery per subscribed webhook for each book event
+// and sends them, signed, to the receivers. Deliveries are at least once;
+// receivers can dedupe on the event id in the payload.
+type Dispatcher struct {
+	repo   repositories.WebhookRepository
+	client *http.Client
+	config Config
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewDispatcher(repo repositories.WebhookRepository, config Config, logger *zap.Logger) *Dispatcher {
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 20
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+	if config.BackoffBase <= 0 {
+		config.BackoffBase = 10 * time.Second
+	}
+	if config.BackoffMax <= 0 {
+		config.BackoffMax = time.Hour
+	}
+
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivateNetworks {
+		// Checked on the resolved address, so DNS names pointing inside the
+		// network are refused too
+		dialer.Control = refusePrivateAddresses
+	}
+	client := &http.Client{
+		Timeout: config.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: config.Timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// A redirect is reported as the receiver's answer rather than followed
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &Dispatcher{
+		repo:   repo,
+		client: client,
+		config: config,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// HandleEvent queues the event for the tenant's subscribed webhooks. It is
+// subscribed to the event bus, which retries it on error; an event queued
+// twice for a webhook is only delivered once.
+func (d *Dispatcher) HandleEvent(ctx context.Context, evt *events.Event) error {
+	ctx = tenancy.WithTenant(ctx, &entities.Tenant{ID: evt.TenantID})
+
+	webhooks, err := d.repo.ListSubscribed(ctx, evt.Type)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	deliveries := make([]*entities.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, &entities.WebhookDelivery{
+			WebhookID: webhook.ID,
+			TenantID:  evt.TenantID,
+			EventID:   evt.ID,
+			EventType: evt.Type,
+			Payload:   payload,
+		})
+	}
+	return d.repo.Enqueue(ctx, deliveries...)
+}
+
+// Run sends due deliveries every PollInterval until ctx is done, draining
+// full batches back to back
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			sent, err := d.DispatchOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				d.logger.Error("Failed to dispatch webhook deliveries", zap.Error(err))
+			}
+			if err != nil || sent < d.config.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce claims one batch of due deliveries, sends them concurrently
+// and returns how many were attempted
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	// The lease outlasts the request timeout, so a delivery is only picked
+	// up again if this dispatcher died while sending it
+	deliveries, err := d.repo.ClaimDue(ctx, d.config.BatchSize, 2*d.config.Timeout+time.Minute)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *entities.WebhookDelivery) {
+			defer wg.Done()
+			d.dispatch(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, delivery *entities.WebhookDelivery) {
+	ctx = tenancy.WithTenant(ctx, &entities.Tenant{ID: delivery.TenantID})
+	logger := d.logger.With(
+		zap.String("webhook_id", delivery.WebhookID.String()),
+		zap.String("delivery_id", delivery.ID.String()),
+		zap.String("event_type", delivery.EventType))
+
+	webhook, err := d.repo.GetByID(ctx, delivery.WebhookID)
+	if err != nil {
+		if !errors.Is(err, entities.ErrWebhookNotFound) {
+			logger.Error("Failed to load webhook for delivery", zap.Error(err))
+		}
+		return
+	}
+
+	attempt := d.Send(ctx, webhook, delivery)
+	attempts := delivery.Attempts + 1
+	succeeded := attempt.Succeeded()
+
+	var retryAt *time.Time
+	status := "succeeded"
+	if !succeeded {
+		status = "failed"
+		if attempts < d.config.MaxAttempts {
+			status = "retrying"
+			next := d.now().Add(messaging.Backoff(attempts, d.config.BackoffBase, d.config.BackoffMax))
+			retryAt = &next
+		}
+		logger.Warn("Webhook delivery failed",
+			zap.Int("attempts", attempts),
+			zap.Int("response_status", attempt.ResponseStatus),
+			zap.String("error", attempt.Error),
+			zap.Bool("will_retry", retryAt != nil))
+	}
+	middleware.RecordWebhookDelivery(delivery.EventType, status, attempt.Duration)
+
+	if err := d.repo.RecordAttempt(ctx, delivery.ID, attempt, retryAt); err != nil {
+		logger.Error("Failed to record webhook attempt", zap.Error(err))
+	}
+	disabled, err := d.repo.RecordResult(ctx, webhook.ID, succeeded, d.config.FailureThreshold)
+	if err != nil {
+		logger.Error("Failed to record webhook result", zap.Error(err))
+		return
+	}
+	if disabled {
+		middleware.RecordWebhookDisabled()
+		logger.Warn("Webhook disabled after repeated delivery failures", zap.Int("failure_threshold", d.config.FailureThreshold))
+	}
+}
+
+// Send makes one signed POST of the delivery's payload to the webhook and
+// returns what happened. It does not record the attempt.
+func (d *Dispatcher) Send(ctx context.Context, webhook *entities.Webhook, delivery *entities.WebhookDelivery) *entities.WebhookAttempt {
+	timestamp := d.now().Unix()
+	headers := entities.WebhookHeaders{
+		"Content-Type":  "application/json",
+		"User-Agent":    "byfood-library-webhooks/1.0",
+		HeaderEvent:     delivery.EventType,
+		HeaderDelivery:  delivery.ID.String(),
+		HeaderTimestamp: strconv.FormatInt(timestamp, 10),
+		HeaderSignature: Sign(webhook.Secret, timestamp, delivery.Payload),
+	}
+	attempt := &entities.WebhookAttempt{RequestHeaders: headers}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.Duration = time.Since(start)
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnippet))
+	// Drain a little more so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	attempt.Duration = time.Since(start)
+	attempt.ResponseStatus = resp.StatusCode
+	attempt.ResponseBody = string(bytes.ToValidUTF8(snippet, nil))
+	return attempt
+}
+
+func refusePrivateAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/tenancy"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryRepository keeps webhooks and deliveries in maps; ClaimDue returns
+// every pending delivery regardless of its next attempt time
+type memoryRepository struct {
+	mu         sync.Mutex
+	webhooks   map[uuid.UUID]*entities.Webhook
+	deliveries map[uuid.UUID]*entities.WebhookDelivery
+	attempts   map[uuid.UUID][]*entities.WebhookAttempt
+}
+
+func newMemoryRepository(webhooks ...*entities.Webhook) *memoryRepository {
+	repo := &memoryRepository{
+		webhooks:   make(map[uuid.UUID]*entities.Webhook),
+		deliveries: make(map[uuid.UUID]*entities.WebhookDelivery),
+		attempts:   make(map[uuid.UUID][]*entities.WebhookAttempt),
+	}
+	for _, webhook := range webhooks {
+		repo.webhooks[webhook.ID] = webhook
+	}
+	return repo
+}
+
+func (r *memoryRepository) Create(ctx context.Context, webhook *entities.Webhook) (*entities.Webhook, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetByID(ctx context.Context, id uuid.UUID) (*entities.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tenantID, _ := tenancy.IDFromContext(ctx)
+	webhook, ok := r.webhooks[id]
+	if !ok || webhook.TenantID != tenantID {
+		return nil, entities.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+func (r *memoryRepository) GetAll(ctx context.Context) ([]*entities.Webhook, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, webhook *entities.Webhook) (*entities.Webhook, error) {
+	return webhook, nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (r *memoryRepository) ListSubscribed(ctx context.Context, eventType string) ([]*entities.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tenantID, _ := tenancy.IDFromContext(ctx)
+	var subscribed []*entities.Webhook
+	for _, webhook := range r.webhooks {
+		if webhook.TenantID == tenantID && webhook.Active && webhook.Subscribes(eventType) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	return subscribed, nil
+}
+
+func (r *memoryRepository) Enqueue(ctx context.Context, deliveries ...*entities.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, delivery := range deliveries {
+		if delivery.ID == uuid.Nil {
+			delivery.ID = uuid.New()
+		}
+		delivery.Status = entities.WebhookDeliveryPending
+		r.deliveries[delivery.ID] = delivery
+	}
+	return nil
+}
+
+func (r *memoryRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*entities.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetDelivery(ctx context.Context, webhookID, id uuid.UUID) (*entities.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*entities.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []*entities.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == entities.WebhookDeliveryPending && r.webhooks[delivery.WebhookID].Active && len(due) < limit {
+			claimed := *delivery
+			due = append(due, &claimed)
+		}
+	}
+	return due, nil
+}
+
+func (r *memoryRepository) RecordAttempt(ctx context.Context, id uuid.UUID, attempt *entities.WebhookAttempt, retryAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery := r.deliveries[id]
+	delivery.Attempts++
+	delivery.NextAttemptAt = retryAt
+	switch {
+	case retryAt != nil:
+		delivery.Status = entities.WebhookDeliveryPending
+	case attempt.Succeeded():
+		delivery.Status = entities.WebhookDeliverySucceeded
+	default:
+		delivery.Status = entities.WebhookDeliveryFailed
+	}
+	r.attempts[id] = append(r.attempts[id], attempt)
+	return nil
+}
+
+func (r *memoryRepository) RecordResult(ctx context.Context, webhookID uuid.UUID, succeeded bool, disableAfter int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook := r.webhooks[webhookID]
+	if succeeded {
+		webhook.ConsecutiveFailures = 0
+		return false, nil
+	}
+	webhook.ConsecutiveFailures++
+	if disableAfter > 0 && webhook.ConsecutiveFailures == disableAfter {
+		webhook.Active = false
+		return true, nil
+	}
+	return false, nil
+}
+
+// receiver is an httptest server that verifies signatures and answers
+// with the next status from statuses, repeating the last one
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	errors   []error
+}
+
+func newReceiver(t *testing.T, secret string, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.bodies = append(r.bodies, body)
+		r.errors = append(r.errors, Verify(secret, req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderSignature), body, 5*time.Minute, time.Now()))
+
+		status := r.statuses[0]
+		if len(r.statuses) > 1 {
+			r.statuses = r.statuses[1:]
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"received":true}`))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func testWebhook(url string) *entities.Webhook {
+	return &entities.Webhook{
+		ID:         uuid.New(),
+		TenantID:   uuid.New(),
+		URL:        url,
+		EventTypes: []string{events.BookCreated, events.BookDeleted},
+		Secret:     "whsec_test",
+		Active:     true,
+	}
+}
+
+func testDispatcher(repo *memoryRepository, config Config) *Dispatcher {
+	config.AllowPrivateNetworks = true
+	config.BackoffBase = time.Second
+	return NewDispatcher(repo, config, zap.NewNop())
+}
+
+func queueEvent(t *testing.T, d *Dispatcher, tenantID uuid.UUID, eventType string) {
+	evt := &events.Event{ID: uuid.New(), Type: eventType, AggregateType: events.AggregateBook, AggregateID: uuid.New(), TenantID: tenantID, Data: json.RawMessage(`{}`)}
+	assert.NoError(t, d.HandleEvent(context.Background(), evt))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"1"}`)
+	signature := Sign("secret", now.Unix(), body)
+
+	assert.Equal(t, "sha256=", signature[:7])
+	assert.NoError(t, Verify("secret", "1700000000", signature, body, time.Minute, now))
+	assert.Equal(t, ErrInvalidSignature, Verify("other", "1700000000", signature, body, time.Minute, now))
+	assert.Equal(t, ErrInvalidSignature, Verify("secret", "1700000000", signature, []byte(`{"id":"2"}`), time.Minute, now))
+	assert.Equal(t, ErrInvalidSignature, Verify("secret", "1700000001", signature, body, time.Minute, now))
+	assert.Equal(t, ErrStaleTimestamp, Verify("secret", "1700000000", signature, body, time.Minute, now.Add(2*time.Minute)))
+}
+
+func TestDispatcher_HandleEvent(t *testing.T) {
+	subscribed := testWebhook("http://example.com/hook")
+	other := testWebhook("http://example.com/other")
+	other.TenantID = subscribed.TenantID
+	other.EventTypes = []string{events.BookUpdated}
+	repo := newMemoryRepository(subscribed, other)
+	d := testDispatcher(repo, Config{})
+
+	queueEvent(t, d, subscribed.TenantID, events.BookCreated)
+	queueEvent(t, d, uuid.New(), events.BookCreated)
+
+	assert.Len(t, repo.deliveries, 1)
+	for _, delivery := range repo.deliveries {
+		assert.Equal(t, subscribed.ID, delivery.WebhookID)
+		assert.Equal(t, subscribed.TenantID, delivery.TenantID)
+		var evt events.Event
+		assert.NoError(t, json.Unmarshal(delivery.Payload, &evt))
+		assert.Equal(t, delivery.EventID, evt.ID)
+	}
+}
+
+func TestDispatcher_DispatchOnce(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sends signed deliveries", func(t *testing.T) {
+		hook := newReceiver(t, "whsec_test", http.StatusOK)
+		webhook := testWebhook(hook.URL)
+		repo := newMemoryRepository(webhook)
+		d := testDispatcher(repo, Config{})
+		queueEvent(t, d, webhook.TenantID, events.BookCreated)
+
+		sent, err := d.DispatchOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sent)
+		assert.Equal(t, []error{nil}, hook.errors)
+		for id, delivery := range repo.deliveries {
+			assert.Equal(t, entities.WebhookDeliverySucceeded, delivery.Status)
+			attempt := repo.attempts[id][0]
+			assert.Equal(t, http.StatusOK, attempt.ResponseStatus)
+			assert.Equal(t, `{"received":true}`, attempt.ResponseBody)
+			assert.Equal(t, events.BookCreated, attempt.RequestHeaders[HeaderEvent])
+			assert.Equal(t, id.String(), attempt.RequestHeaders[HeaderDelivery])
+			assert.Equal(t, delivery.Payload, json.RawMessage(hook.bodies[0]))
+		}
+	})
+
+	t.Run("retries with backoff until the attempts run out", func(t *testing.T) {
+		hook := newReceiver(t, "whsec_test", http.StatusInternalServerError)
+		webhook := testWebhook(hook.URL)
+		repo := newMemoryRepository(webhook)
+		d := testDispatcher(repo, Config{MaxAttempts: 3})
+		queueEvent(t, d, webhook.TenantID, events.BookCreated)
+
+		before := time.Now()
+		d.DispatchOnce(ctx)
+		for _, delivery := range repo.deliveries {
+			assert.Equal(t, entities.WebhookDeliveryPending, delivery.Status)
+			assert.WithinDuration(t, before.Add(time.Second), *delivery.NextAttemptAt, 300*time.Millisecond)
+		}
+
+		d.DispatchOnce(ctx)
+		d.DispatchOnce(ctx)
+		sent, _ := d.DispatchOnce(ctx)
+
+		assert.Equal(t, 0, sent)
+		assert.Len(t, hook.bodies, 3)
+		for _, delivery := range repo.deliveries {
+			assert.Equal(t, entities.WebhookDeliveryFailed, delivery.Status)
+			assert.Nil(t, delivery.NextAttemptAt)
+		}
+	})
+
+	t.Run("disables the webhook after repeated failures", func(t *testing.T) {
+		hook := newReceiver(t, "whsec_test", http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+		webhook := testWebhook(hook.URL)
+		repo := newMemoryRepository(webhook)
+		d := testDispatcher(repo, Config{FailureThreshold: 2})
+		queueEvent(t, d, webhook.TenantID, events.BookCreated)
+
+		d.DispatchOnce(ctx)
+		assert.True(t, webhook.Active)
+		d.DispatchOnce(ctx)
+		assert.False(t, webhook.Active)
+
+		sent, _ := d.DispatchOnce(ctx)
+		assert.Equal(t, 0, sent)
+		assert.Len(t, hook.bodies, 2)
+	})
+
+	t.Run("a success resets the failure count", func(t *testing.T) {
+		hook := newReceiver(t, "whsec_test", http.StatusBadGateway, http.StatusOK, http.StatusBadGateway)
+		webhook := testWebhook(hook.URL)
+		repo := newMemoryRepository(webhook)
+		d := testDispatcher(repo, Config{FailureThreshold: 2})
+		queueEvent(t, d, webhook.TenantID, events.BookCreated)
+		d.DispatchOnce(ctx)
+		d.DispatchOnce(ctx)
+		queueEvent(t, d, webhook.TenantID, events.BookDeleted)
+		d.DispatchOnce(ctx)
+
+		assert.True(t, webhook.Active)
+		assert.Equal(t, 1, webhook.ConsecutiveFailures)
+	})
+}
+
+func TestDispatcher_Send(t *testing.T) {
+	ctx := context.Background()
+	delivery := &entities.WebhookDelivery{ID: uuid.New(), EventType: events.BookCreated, Payload: json.RawMessage(`{}`)}
+
+	t.Run("refuses private addresses by default", func(t *testing.T) {
+		hook := newReceiver(t, "whsec_test", http.StatusOK)
+		d := NewDispatcher(newMemoryRepository(), Config{}, zap.NewNop())
+
+		attempt := d.Send(ctx, testWebhook(hook.URL), delivery)
+
+		assert.False(t, attempt.Succeeded())
+		assert.Contains(t, attempt.Error, errPrivateAddress.Error())
+		assert.Empty(t, hook.bodies)
+	})
+
+	t.Run("does not follow redirects", func(t *testing.T) {
+		hook := newReceiver(t, "whsec_test", http.StatusOK)
+		redirect := httptest.NewServer(http.RedirectHandler(hook.URL, http.StatusFound))
+		defer redirect.Close()
+		d := testDispatcher(newMemoryRepository(), Config{})
+
+		attempt := d.Send(ctx, testWebhook(redirect.URL), delivery)
+
+		assert.False(t, attempt.Succeeded())
+		assert.Equal(t, http.StatusFound, attempt.ResponseStatus)
+		assert.Empty(t, hook.bodies)
+	})
+
+	t.Run("times out slow receivers", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer slow.Close()
+		d := testDispatcher(newMemoryRepository(), Config{Timeout: 50 * time.Millisecond})
+
+		attempt := d.Send(ctx, testWebhook(slow.URL), delivery)
+
+		assert.False(t, attempt.Succeeded())
+		assert.NotEmpty(t, attempt.Error)
+		assert.Zero(t, attempt.ResponseStatus)
+	})
+}
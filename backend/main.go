@@ -1,19 +1,51 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"byfood-library/internal/cache"
 	"byfood-library/internal/config"
+	"byfood-library/internal/covers"
+	grpcservices "byfood-library/internal/delivery/grpc/services"
 	"byfood-library/internal/delivery/http/handlers"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/duplicates"
+	"byfood-library/internal/ebooks"
+	"byfood-library/internal/enrichment"
+	"byfood-library/internal/gql"
+	"byfood-library/internal/health"
 	"byfood-library/internal/infrastructure/database"
+	"byfood-library/internal/messaging"
+	appmiddleware "byfood-library/internal/middleware"
+	"byfood-library/internal/oai"
+	"byfood-library/internal/opds"
 	"byfood-library/internal/repositories"
 	"byfood-library/internal/routes"
+	"byfood-library/internal/sru"
+	"byfood-library/internal/storage"
+	"byfood-library/internal/stream"
+	"byfood-library/internal/tenancy"
+	"byfood-library/internal/tracing"
 	"byfood-library/internal/usecases"
+	"byfood-library/internal/webhooks"
 	_ "byfood-library/docs"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
 )
 
 // @title Book Library API
@@ -22,54 +54,487 @@ import (
 // @host localhost:8080
 // @BasePath /
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:]))
+	}
+
+	configPath := flag.String("config", os.Getenv("BYFOOD_CONFIG"), "path to the YAML configuration file")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(*configPath)
 	if err != nil {
-		log.Fatal("Failed to load configuration:", err)
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Initialize logger
-	var logger *zap.Logger
+	// Initialize logger; the level can be changed by a configuration reload
+	logLevel := zap.NewAtomicLevel()
+	if err := logLevel.UnmarshalText([]byte(cfg.Logging.Level)); err != nil {
+		log.Fatalf("Invalid log level: %v", err)
+	}
+	loggerConfig := zap.NewDevelopmentConfig()
 	if cfg.Logging.Environment == "production" {
-		logger, _ = zap.NewProduction()
-	} else {
-		logger, _ = zap.NewDevelopment()
+		loggerConfig = zap.NewProductionConfig()
+	}
+	loggerConfig.Level = logLevel
+	logger, err := loggerConfig.Build()
+	if err != nil {
+		log.Fatalf("Failed to build logger: %v", err)
 	}
 	defer logger.Sync()
 
+	// Live configuration reloads on SIGHUP or file change
+	configManager, err := config.NewManager(*configPath, cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to watch configuration", zap.Error(err))
+	}
+	configManager.OnReload("logging", func(cfg *config.Config) error {
+		return logLevel.UnmarshalText([]byte(cfg.Logging.Level))
+	})
+
+	// Tracing is set up before anything that creates spans
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Enabled:     cfg.Tracing.Enabled,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+
 	// Initialize database connection
 	db, err := database.InitDBWithConfig(cfg.Database.GetConnectionString())
 	if err != nil {
 		logger.Fatal("Failed to connect to database", zap.Error(err))
 	}
-	defer db.Close()
+	if cfg.Database.MaxConnections > 0 {
+		db.SetMaxOpenConns(cfg.Database.MaxConnections)
+	}
+	if cfg.Database.MaxIdle > 0 {
+		db.SetMaxIdleConns(cfg.Database.MaxIdle)
+	}
+	if cfg.Database.AutoMigrate {
+		applied, err := database.Migrate(context.Background(), db)
+		if err != nil {
+			logger.Fatal("Failed to migrate database", zap.Error(err))
+		}
+		logger.Info("Database schema migrated", zap.Ints("applied", applied), zap.Int("version", database.SchemaVersion))
+	}
+
+	// Background workers share a context that is cancelled on shutdown
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	runWorker := func(name string, run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+			logger.Info("Background worker stopped", zap.String("worker", name))
+		}()
+	}
 
 	// Initialize Clean Architecture layers
-	bookRepo := repositories.NewPostgresBookRepository(db,zap.L())
-	bookUseCase := usecases.NewBookUseCase(bookRepo, logger)
+	postgresBookRepo := repositories.NewPostgresBookRepository(db, logger)
+	bookRepo := repositories.NewInstrumentedBookRepository(postgresBookRepo)
+	var redisCache *cache.Redis
+	if cfg.Cache.Enabled {
+		// Cache hits bypass the instrumented repository, so database metrics
+		// only count queries that reach Postgres
+		cacheOptions := cache.Options{
+			Broadcaster: cache.NewPostgresBroadcaster(db.DB, cfg.Cache.InvalidationChannel),
+			OnLookup: func(tier string, hit bool) {
+				appmiddleware.RecordCacheLookup("books", tier, hit)
+			},
+			OnError: func(op string, err error) {
+				appmiddleware.RecordCacheError("books", op)
+				logger.Warn("Book cache operation failed", zap.String("operation", op), zap.Error(err))
+			},
+		}
+		if cfg.Cache.RedisURL != "" {
+			redisCache, err = cache.NewRedisFromURL(cfg.Cache.RedisURL, cfg.Cache.RedisPrefix)
+			if err != nil {
+				logger.Fatal("Failed to configure Redis cache", zap.Error(err))
+			}
+			cacheOptions.Remote = redisCache
+		}
+		bookCache := cache.New(cache.NewLRU(cfg.Cache.Size), cacheOptions)
+		bookRepo = repositories.NewCachingBookRepository(bookRepo, bookCache, repositories.BookCacheConfig{
+			TTL:     cfg.Cache.TTL,
+			ListTTL: cfg.Cache.ListTTL,
+		}, logger)
+		runWorker("cache-invalidation", func(ctx context.Context) {
+			cache.Listen(ctx, cfg.Database.GetConnectionString(), cfg.Cache.InvalidationChannel, bookCache, logger)
+		})
+	}
+	tenantRepo := repositories.NewPostgresTenantRepository(db, logger)
+	idempotencyRepo := repositories.NewPostgresIdempotencyRepository(db, logger)
+	outboxRepo := repositories.NewPostgresOutboxRepository(db, logger)
+	transactor := repositories.NewPostgresTransactor(db, logger)
+
+	// Tenant resolution; with tenancy disabled every request uses the default tenant
+	resolverConfig := tenancy.ResolverConfig{
+		HeaderName:    cfg.Tenancy.HeaderName,
+		APIKeyHeader:  cfg.Tenancy.APIKeyHeader,
+		BaseDomain:    cfg.Tenancy.BaseDomain,
+		DefaultTenant: cfg.Tenancy.DefaultTenant,
+		CacheTTL:      cfg.Tenancy.CacheTTL,
+	}
+	if cfg.Tenancy.Enabled {
+		resolverConfig.Strategies = cfg.Tenancy.Strategies
+	}
+	if resolverConfig.DefaultTenant == "" && !cfg.Tenancy.Enabled {
+		resolverConfig.DefaultTenant = "default"
+	}
+	tenantResolver := tenancy.NewResolver(tenantRepo, resolverConfig)
+
+	// Book validation rules; tenants override them through their settings
+	tenancy.SetBookValidationDefaults(bookValidationRules(cfg.Validation.Books))
+	configManager.OnReload("book-validation", func(cfg *config.Config) error {
+		tenancy.SetBookValidationDefaults(bookValidationRules(cfg.Validation.Books))
+		return nil
+	})
+
+	// Possible duplicates are rejected on create unless forced
+	var duplicateDetector *duplicates.Detector
+	if cfg.Duplicates.Enabled {
+		duplicateDetector = duplicates.NewDetector(duplicates.Config{
+			Threshold: cfg.Duplicates.Threshold,
+			Limit:     cfg.Duplicates.Limit,
+		})
+	}
+
+	bookUseCase := usecases.NewBookUseCase(bookRepo, outboxRepo, transactor, duplicateDetector, logger)
+	tenantUseCase := usecases.NewTenantUseCase(tenantRepo, tenantResolver, logger)
 	bookHandler := handlers.NewBookHandler(bookUseCase, logger)
 	urlHandler := handlers.NewURLHandler(logger)
+	tenantHandler := handlers.NewTenantHandler(tenantUseCase, logger)
+
+	// Dependency checks behind /readyz; results are cached between probes
+	healthRegistry := health.NewRegistry(health.Config{
+		CacheTTL: cfg.Health.CacheTTL,
+		Timeout:  cfg.Health.CheckTimeout,
+	})
+	healthRegistry.Register(health.DatabasePing(db), true)
+	healthRegistry.Register(health.PoolSaturation(db, cfg.Health.PoolSaturation), false)
+	healthRegistry.Register(health.Migrations(db, database.SchemaVersion), true)
+	if redisCache != nil {
+		// Reads fall back to Postgres, so Redis only degrades readiness
+		healthRegistry.Register(health.CheckFunc{
+			CheckName: "redis",
+			Fn: func(ctx context.Context) (map[string]interface{}, error) {
+				if err := redisCache.Ping(ctx); err != nil {
+					return nil, health.Degraded(err)
+				}
+				return nil, nil
+			},
+		}, false)
+	}
+	healthHandler := handlers.NewHealthHandler(healthRegistry, logger)
+	configHandler := handlers.NewConfigHandler(configManager, logger)
 
 	// Initialize Echo server
 	e := echo.New()
 
-	// Global middleware
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
+	// Server timeouts protect against slow clients holding connections
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout
+	e.Server.ReadHeaderTimeout = cfg.Server.ReadHeaderTimeout
+	e.Server.WriteTimeout = cfg.Server.WriteTimeout
+	e.Server.IdleTimeout = cfg.Server.IdleTimeout
 
-	// CORS middleware
-	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: cfg.CORS.AllowedOrigins,
-		AllowMethods: cfg.CORS.AllowedMethods,
-		AllowHeaders: cfg.CORS.AllowedHeaders,
+	// Global middleware. The request ID comes first so that spans and the
+	// request-scoped logger carry it; tracing, access logs and metrics sit
+	// outside Recover so panics count as 500s.
+	accessLogSkip := []string{"/livez", "/readyz", "/health"}
+	if cfg.Metrics.Path != "" {
+		accessLogSkip = append(accessLogSkip, cfg.Metrics.Path)
+	}
+	e.HTTPErrorHandler = appmiddleware.NewErrorHandler(logger).CustomHTTPErrorHandler
+	e.Use(appmiddleware.DefaultMiddleware())
+	e.Use(appmiddleware.Tracing())
+	e.Use(appmiddleware.RequestLogger(logger, accessLogSkip...))
+	if cfg.Metrics.Enabled {
+		e.Use(appmiddleware.PrometheusMetrics())
+	}
+	e.Use(middleware.Recover())
+	// The book stream stays open, so it has no deadline unless one is configured
+	routeTimeouts := map[string]time.Duration{"GET /api/v1/books/stream": 0}
+	for route, timeout := range cfg.Server.RouteTimeouts {
+		routeTimeouts[route] = timeout
+	}
+	e.Use(appmiddleware.RequestTimeout(appmiddleware.TimeoutConfig{
+		Default: cfg.Server.RequestTimeout,
+		Routes:  routeTimeouts,
 	}))
 
+	// Tenant-aware CORS and rate limiting; tenants may override these defaults
+	skipPrefixes := []string{"/health", "/livez", "/readyz", "/admin", "/docs"}
+	if cfg.Metrics.Enabled && cfg.Metrics.Path != "" {
+		skipPrefixes = append(skipPrefixes, cfg.Metrics.Path)
+	}
+	if cfg.API.SwaggerPath != "" {
+		skipPrefixes = append(skipPrefixes, cfg.API.SwaggerPath)
+	}
+	apiKeyHeader := ""
+	if cfg.Tenancy.Enabled {
+		apiKeyHeader = cfg.Tenancy.APIKeyHeader
+		if apiKeyHeader == "" {
+			apiKeyHeader = "X-API-Key"
+		}
+	}
+	tenantMiddleware := appmiddleware.NewTenantMiddleware(tenantResolver, appmiddleware.TenantConfig{
+		CORS: middleware.CORSConfig{
+			AllowOrigins: cfg.CORS.AllowedOrigins,
+			AllowMethods: cfg.CORS.AllowedMethods,
+			AllowHeaders: cfg.CORS.AllowedHeaders,
+		},
+		EnableRateLimit: cfg.RateLimit.Enabled,
+		RateLimitRPS:    cfg.RateLimit.RPS,
+		RateLimitBurst:  cfg.RateLimit.Burst,
+		SkipPrefixes:    skipPrefixes,
+		APIKeyHeader:    apiKeyHeader,
+	}, logger)
+
+	configManager.OnReload("tenant-defaults", func(cfg *config.Config) error {
+		tenantMiddleware.UpdateDefaults(middleware.CORSConfig{
+			AllowOrigins: cfg.CORS.AllowedOrigins,
+			AllowMethods: cfg.CORS.AllowedMethods,
+			AllowHeaders: cfg.CORS.AllowedHeaders,
+		}, cfg.RateLimit.Enabled, cfg.RateLimit.RPS, cfg.RateLimit.Burst)
+		return nil
+	})
+	runWorker("config-watch", configManager.Watch)
+
+	// Idempotency-Key support with periodic purge of expired keys; ebook
+	// imports are the one body allowed past max_body_size
+	idempotencyBodyLimits := map[string]int64{}
+	if cfg.Ebooks.Enabled {
+		ebookMaxSize := cfg.Ebooks.MaxSize
+		if ebookMaxSize <= 0 {
+			ebookMaxSize = ebooks.DefaultMaxSize
+		}
+		idempotencyBodyLimits["POST /api/v1/books/files"] = ebookMaxSize
+	}
+	idempotencyMiddleware := appmiddleware.NewIdempotencyMiddleware(idempotencyRepo, appmiddleware.IdempotencyConfig{
+		TTL:             cfg.Idempotency.TTL,
+		LockTTL:         cfg.Idempotency.LockTTL,
+		CleanupInterval: cfg.Idempotency.CleanupInterval,
+		MaxBodySize:     cfg.Idempotency.MaxBodySize,
+		BodyLimits:      idempotencyBodyLimits,
+	}, logger)
+	runWorker("idempotency-cleanup", idempotencyMiddleware.RunCleanup)
+
+	// Book events are relayed from the outbox to the configured sinks; the
+	// in-process bus lets other components react to committed changes
+	eventBus := messaging.NewBus()
+	var eventSinks []messaging.Sink
+	var closeSinks []func()
+	for _, name := range cfg.Events.Sinks {
+		switch name {
+		case "bus":
+			eventSinks = append(eventSinks, eventBus)
+		case "nats":
+			natsSink, closeNATS, err := messaging.ConnectNATS(cfg.Events.NATS.URL, cfg.Events.NATS.SubjectPrefix)
+			if err != nil {
+				logger.Fatal("Failed to connect to NATS", zap.Error(err))
+			}
+			closeSinks = append(closeSinks, closeNATS)
+			eventSinks = append(eventSinks, natsSink)
+		case "kafka":
+			kafkaWriter := messaging.NewKafkaWriter(cfg.Events.Kafka.Brokers, cfg.Events.Kafka.Topic)
+			closeSinks = append(closeSinks, func() { kafkaWriter.Close() })
+			eventSinks = append(eventSinks, messaging.NewKafkaSink(kafkaWriter))
+		}
+	}
+	relay := messaging.NewRelay(outboxRepo, eventSinks, messaging.RelayConfig{
+		Interval:       cfg.Events.RelayInterval,
+		BatchSize:      cfg.Events.BatchSize,
+		BackoffBase:    cfg.Events.BackoffBase,
+		BackoffMax:     cfg.Events.BackoffMax,
+		PublishTimeout: cfg.Events.PublishTimeout,
+		Retention:      cfg.Events.Retention,
+	}, logger)
+	runWorker("outbox-relay", relay.Run)
+
+	// Webhook deliveries are queued from the bus and sent by the dispatcher
+	var webhookHandler handlers.WebhookHandlerInterface
+	if cfg.Webhooks.Enabled {
+		webhookRepo := repositories.NewPostgresWebhookRepository(db, logger)
+		dispatcher := webhooks.NewDispatcher(webhookRepo, webhooks.Config{
+			PollInterval:         cfg.Webhooks.PollInterval,
+			BatchSize:            cfg.Webhooks.BatchSize,
+			Timeout:              cfg.Webhooks.Timeout,
+			MaxAttempts:          cfg.Webhooks.MaxAttempts,
+			BackoffBase:          cfg.Webhooks.BackoffBase,
+			BackoffMax:           cfg.Webhooks.BackoffMax,
+			FailureThreshold:     cfg.Webhooks.FailureThreshold,
+			AllowPrivateNetworks: cfg.Webhooks.AllowPrivateNetworks,
+		}, logger)
+		eventBus.Subscribe(dispatcher.HandleEvent)
+		runWorker("webhook-dispatcher", dispatcher.Run)
+		webhookHandler = handlers.NewWebhookHandler(usecases.NewWebhookUseCase(webhookRepo, logger), logger)
+	}
+
+	// Live book stream: the relaying replica forwards events over NOTIFY and
+	// every replica fans them out to its SSE clients
+	var streamHandler handlers.StreamHandlerInterface
+	var bookHub *stream.Hub
+	if cfg.Stream.Enabled {
+		bookHub = stream.NewHub(cfg.Stream.ReplaySize, cfg.Stream.ClientQueue)
+		eventBus.Subscribe(stream.NewNotifier(db.DB, cfg.Stream.Channel).HandleEvent)
+		runWorker("book-stream", func(ctx context.Context) {
+			stream.Listen(ctx, cfg.Database.GetConnectionString(), cfg.Stream.Channel, bookHub, logger)
+		})
+		// Open streams would otherwise hold up draining until the grace period ends
+		e.Server.RegisterOnShutdown(bookHub.Close)
+		streamHandler = handlers.NewStreamHandler(bookHub, cfg.Stream.Heartbeat, logger)
+	}
+
+	// GraphQL resolves through the same book use case as the REST handlers
+	var graphQLHandler handlers.GraphQLHandlerInterface
+	if cfg.GraphQL.Enabled {
+		var allowedQueries map[string]string
+		if cfg.GraphQL.PersistedQueries == gql.PersistedQueriesOnly {
+			allowedQueries, err = gql.LoadPersistedQueries(cfg.GraphQL.PersistedQueriesFile)
+			if err != nil {
+				logger.Fatal("Failed to load persisted GraphQL queries", zap.Error(err))
+			}
+		}
+		graphQLServer, err := gql.NewServer(bookUseCase, gql.Config{
+			MaxDepth:         cfg.GraphQL.MaxDepth,
+			MaxComplexity:    cfg.GraphQL.MaxComplexity,
+			ListCost:         cfg.GraphQL.ListCost,
+			PersistedQueries: cfg.GraphQL.PersistedQueries,
+			AllowedQueries:   allowedQueries,
+			CacheSize:        cfg.GraphQL.PersistedQueriesCacheSize,
+		}, logger)
+		if err != nil {
+			logger.Fatal("Failed to build GraphQL schema", zap.Error(err))
+		}
+		graphQLHandler = handlers.NewGraphQLHandler(graphQLServer, logger)
+	}
+
+	// Book lookups in Open Library, Google Books or a local fixture
+	var enrichmentHandler handlers.EnrichmentHandlerInterface
+	if cfg.Enrichment.Enabled {
+		provider, err := metadataProvider(cfg.Enrichment, logger)
+		if err != nil {
+			logger.Fatal("Failed to configure metadata enrichment", zap.Error(err))
+		}
+		enrichmentUseCase := usecases.NewEnrichmentUseCase(bookRepo, outboxRepo, transactor, provider, logger)
+		enrichmentHandler = handlers.NewEnrichmentHandler(enrichmentUseCase, logger)
+	}
+
+	// Cover images and their thumbnails in local or S3-compatible storage;
+	// covers of deleted books are removed when the bus sees the deletion
+	var coverUseCase usecases.CoverUseCase
+	var coverHandler handlers.CoverHandlerInterface
+	if cfg.Covers.Enabled {
+		coverStore, err := blobStore(cfg.Covers.Storage)
+		if err != nil {
+			logger.Fatal("Failed to configure cover storage", zap.Error(err))
+		}
+		coverUseCase = usecases.NewCoverUseCase(bookRepo, outboxRepo, transactor, coverStore, covers.NewProcessor(covers.Config{
+			MaxSize:   cfg.Covers.MaxSize,
+			MaxPixels: cfg.Covers.MaxPixels,
+			Sizes:     cfg.Covers.Sizes,
+			Quality:   cfg.Covers.Quality,
+		}), logger)
+		eventBus.Subscribe(coverUseCase.HandleEvent)
+		coverHandler = handlers.NewCoverHandler(coverUseCase, cfg.Covers.MaxSize, cfg.Covers.CacheMaxAge, logger)
+	}
+
+	// EPUB and PDF files, imported with the metadata they carry; files of
+	// deleted books are removed, and those of merged books moved, when the
+	// bus sees the deletion
+	var ebookHandler handlers.EbookHandlerInterface
+	if cfg.Ebooks.Enabled {
+		ebookStore, err := blobStore(cfg.Ebooks.Storage)
+		if err != nil {
+			logger.Fatal("Failed to configure ebook storage", zap.Error(err))
+		}
+		ebookUseCase := usecases.NewEbookUseCase(bookRepo, outboxRepo, transactor, bookUseCase, coverUseCase, ebookStore, ebooks.NewParser(ebooks.Config{
+			MaxSize:         cfg.Ebooks.MaxSize,
+			MaxEntries:      cfg.Ebooks.MaxEntries,
+			MaxUncompressed: cfg.Ebooks.MaxUncompressed,
+		}), logger)
+		eventBus.Subscribe(ebookUseCase.HandleEvent)
+		ebookHandler = handlers.NewEbookHandler(ebookUseCase, cfg.Ebooks.MaxSize, logger)
+	}
+
+	// OPDS feeds for e-reader apps, linking the ebook files and covers
+	// served when those are enabled
+	var opdsHandler handlers.OPDSHandlerInterface
+	if cfg.OPDS.Enabled {
+		catalogUseCase := usecases.NewCatalogUseCase(bookRepo, cfg.Ebooks.Enabled, logger)
+		opdsHandler = handlers.NewOPDSHandler(catalogUseCase, opds.Config{
+			Title:      cfg.OPDS.Title,
+			PageSize:   cfg.OPDS.PageSize,
+			FacetLimit: cfg.OPDS.FacetLimit,
+			Covers:     cfg.Covers.Enabled,
+			Thumbnail:  cfg.OPDS.Thumbnail,
+		}, logger)
+	}
+
+	// OAI-PMH for metadata harvesters, deleted books included
+	var oaiHandler handlers.OAIHandlerInterface
+	if cfg.OAI.Enabled {
+		harvestUseCase := usecases.NewHarvestUseCase(bookRepo, logger)
+		oaiHandler = handlers.NewOAIHandler(oai.NewProvider(harvestUseCase, oai.Config{
+			RepositoryName:       cfg.OAI.RepositoryName,
+			RepositoryIdentifier: cfg.OAI.RepositoryIdentifier,
+			AdminEmails:          cfg.OAI.AdminEmails,
+			PageSize:             cfg.OAI.PageSize,
+		}), logger)
+	}
+
+	// SRU for library search clients, with CQL queries
+	var sruHandler handlers.SRUHandlerInterface
+	if cfg.SRU.Enabled {
+		catalogUseCase := usecases.NewCatalogUseCase(bookRepo, false, logger)
+		sruHandler = handlers.NewSRUHandler(sru.NewProvider(catalogUseCase, sru.Config{
+			Title:          cfg.SRU.Title,
+			DefaultRecords: cfg.SRU.DefaultRecords,
+			MaxRecords:     cfg.SRU.MaxRecords,
+		}), logger)
+	}
+
+	// Connection pool gauges and a periodic books_total recount
+	if cfg.Metrics.Enabled {
+		if err := appmiddleware.RegisterDatabaseMetrics(db.DB, cfg.Database.Name); err != nil {
+			logger.Error("Failed to register database metrics", zap.Error(err))
+		}
+		runWorker("book-metrics", func(ctx context.Context) {
+			appmiddleware.RunBookMetrics(ctx, tenantRepo, postgresBookRepo, cfg.Metrics.BookCountInterval, logger)
+		})
+	}
+
 	// Setup routes with handlers
 	handlers := &routes.Handlers{
-		BookHandler: bookHandler,
-		URLHandler:  urlHandler,
+		BookHandler:       bookHandler,
+		URLHandler:        urlHandler,
+		TenantHandler:     tenantHandler,
+		HealthHandler:     healthHandler,
+		ConfigHandler:     configHandler,
+		WebhookHandler:    webhookHandler,
+		StreamHandler:     streamHandler,
+		GraphQLHandler:    graphQLHandler,
+		EnrichmentHandler: enrichmentHandler,
+		CoverHandler:      coverHandler,
+		EbookHandler:      ebookHandler,
+		OPDSHandler:       opdsHandler,
+		OAIHandler:        oaiHandler,
+		SRUHandler:        sruHandler,
 	}
-	routes.SetupRoutes(e, cfg, handlers)
+	routes.SetupRoutes(e, cfg, handlers, &routes.Middleware{
+		Tenant:      tenantMiddleware,
+		Idempotency: idempotencyMiddleware,
+	}, logger)
 
 	// Start server
 	address := cfg.Server.Host + ":" + cfg.Server.Port
@@ -78,7 +543,288 @@ func main() {
 		zap.String("environment", cfg.Logging.Environment),
 		zap.String("framework", "echo"))
 
-	if err := e.Start(address); err != nil {
-		logger.Fatal("Server failed to start", zap.Error(err))
+	serverErr := make(chan error, 2)
+	var serving sync.WaitGroup
+	serving.Add(1)
+	go func() {
+		defer serving.Done()
+		if err := e.Start(address); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	// BookService over gRPC, on its own port and sharing the use cases,
+	// tenant resolution and book stream with the HTTP API
+	var grpcServer *grpc.Server
+	var grpcHealth *grpchealth.Server
+	if cfg.GRPC.Enabled {
+		grpcServer, grpcHealth = routes.SetupGRPC(cfg, &routes.GRPCServices{
+			BookService: grpcservices.NewBookService(bookUseCase, usecases.NewCatalogUseCase(bookRepo, false, logger), bookHub, logger),
+		}, tenantResolver, apiKeyHeader, logger)
+
+		grpcPort := cfg.GRPC.Port
+		if grpcPort == "" {
+			grpcPort = "9090"
+		}
+		grpcAddress := cfg.Server.Host + ":" + grpcPort
+		listener, err := net.Listen("tcp", grpcAddress)
+		if err != nil {
+			logger.Fatal("Failed to listen for gRPC", zap.String("address", grpcAddress), zap.Error(err))
+		}
+		logger.Info("gRPC server starting", zap.String("address", grpcAddress))
+		serving.Add(1)
+		go func() {
+			defer serving.Done()
+			if err := grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				serverErr <- err
+			}
+		}()
+	}
+	go func() {
+		serving.Wait()
+		close(serverErr)
+	}()
+
+	// Wait for a termination signal or a startup failure
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	exitCode := 0
+	select {
+	case <-signalCtx.Done():
+		logger.Info("Shutdown signal received, draining connections",
+			zap.Duration("grace_period", cfg.Server.ShutdownTimeout))
+	case err := <-serverErr:
+		logger.Error("Server failed to start", zap.Error(err))
+		exitCode = 1
+	}
+	stopSignals()
+
+	// Fail readiness first so load balancers stop sending new traffic
+	healthRegistry.SetShuttingDown()
+	if exitCode == 0 && cfg.Health.ShutdownDelay > 0 {
+		logger.Info("Readiness disabled, waiting before draining",
+			zap.Duration("delay", cfg.Health.ShutdownDelay))
+		time.Sleep(cfg.Health.ShutdownDelay)
+	}
+
+	// Stop accepting connections and wait for in-flight requests
+	shutdownTimeout := cfg.Server.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 20 * time.Second
+	}
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+
+	var grpcStopped chan struct{}
+	if grpcServer != nil {
+		grpcHealth.Shutdown()
+		grpcStopped = make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+	}
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server did not drain within grace period", zap.Error(err))
+		exitCode = 1
+	}
+	if grpcServer != nil {
+		select {
+		case <-grpcStopped:
+		case <-shutdownCtx.Done():
+			logger.Error("gRPC server did not drain within grace period")
+			grpcServer.Stop()
+			exitCode = 1
+		}
+	}
+
+	// Stop background workers, then close the database last
+	stopWorkers()
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		logger.Warn("Background workers did not stop within grace period")
+	}
+
+	for _, closeSink := range closeSinks {
+		closeSink()
+	}
+	if redisCache != nil {
+		if err := redisCache.Close(); err != nil {
+			logger.Error("Failed to close Redis", zap.Error(err))
+		}
+	}
+	if err := db.Close(); err != nil {
+		logger.Error("Failed to close database", zap.Error(err))
+		exitCode = 1
+	}
+
+	// Flush spans still buffered in the exporter
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("Failed to flush traces", zap.Error(err))
+	}
+
+	logger.Info("Server stopped")
+	logger.Sync()
+	os.Exit(exitCode)
+}
+
+// bookValidationRules converts the configured rules, keeping the built-in
+// defaults for anything left unset
+func bookValidationRules(books config.BookValidationConfig) entities.BookValidationRules {
+	text := func(rule config.TextRuleConfig) entities.TextRule {
+		return entities.TextRule{
+			Required:       rule.Required,
+			MinLength:      rule.MinLength,
+			MaxLength:      rule.MaxLength,
+			Pattern:        rule.Pattern,
+			ForbiddenWords: rule.ForbiddenWords,
+		}
+	}
+	return entities.DefaultBookValidationRules.Merge(entities.BookValidationRules{
+		MinYear:       books.MinYear,
+		MaxYear:       books.MaxYear,
+		MinYearOffset: books.MinYearOffset,
+		MaxYearOffset: books.MaxYearOffset,
+		Title:         text(books.Title),
+		Author:        text(books.Author),
+	})
+}
+
+// metadataProvider chains the configured bibliographic sources in fallback
+// order, each rate limited as configured, behind a cache of their answers
+func metadataProvider(cfg config.EnrichmentConfig, logger *zap.Logger) (enrichment.MetadataProvider, error) {
+	client := enrichment.NewClient(cfg.Timeout, cfg.UserAgent)
+	limited := func(provider enrichment.MetadataProvider, limits config.EnrichmentProviderConfig) enrichment.MetadataProvider {
+		if limits.RateLimit > 0 {
+			provider = enrichment.NewRateLimited(provider, limits.RateLimit, limits.Burst)
+		}
+		return enrichment.NewInstrumented(provider)
+	}
+
+	var chain enrichment.Chain
+	for _, name := range cfg.Providers {
+		switch name {
+		case "openlibrary":
+			chain = append(chain, limited(enrichment.NewOpenLibrary(client, cfg.OpenLibrary.BaseURL), cfg.OpenLibrary))
+		case "googlebooks":
+			chain = append(chain, limited(enrichment.NewGoogleBooks(client, cfg.GoogleBooks.BaseURL, cfg.GoogleBooks.APIKey), cfg.GoogleBooks))
+		case "fixture":
+			fixture, err := enrichment.LoadFixture(cfg.FixturePath)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, enrichment.NewInstrumented(fixture))
+		}
+	}
+	if cfg.CacheSize == 0 {
+		return chain, nil
+	}
+
+	metadataCache := cache.New(cache.NewLRU(cfg.CacheSize), cache.Options{
+		OnLookup: func(tier string, hit bool) {
+			appmiddleware.RecordCacheLookup("enrichment", tier, hit)
+		},
+		OnError: func(op string, err error) {
+			appmiddleware.RecordCacheError("enrichment", op)
+			logger.Warn("Enrichment cache operation failed", zap.String("operation", op), zap.Error(err))
+		},
+	})
+	return enrichment.NewCached(chain, metadataCache, cfg.CacheTTL), nil
+}
+
+// blobStore opens the configured blob storage backend
+func blobStore(cfg config.BlobStorageConfig) (storage.BlobStore, error) {
+	if cfg.Backend == "local" {
+		return storage.NewLocal(cfg.Path)
+	}
+
+	s3, err := storage.NewS3(storage.S3Config{
+		Endpoint:  cfg.S3.Endpoint,
+		Region:    cfg.S3.Region,
+		Bucket:    cfg.S3.Bucket,
+		AccessKey: cfg.S3.AccessKey,
+		SecretKey: cfg.S3.SecretKey,
+		PathStyle: cfg.S3.PathStyle,
+		Timeout:   cfg.S3.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.S3.CreateBucket {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s3.EnsureBucket(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return s3, nil
+}
+
+// runConfigCommand implements "config print [--config path] [--redacted]",
+// which shows the effective configuration after environment overrides
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: config print [--config path] [--redacted=true|false]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config print", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv("BYFOOD_CONFIG"), "path to the YAML configuration file")
+	redacted := flags.Bool("redacted", true, "mask secrets such as passwords and API keys")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Read(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := config.Print(os.Stdout, cfg, *redacted); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func runMigrateCommand(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv("BYFOOD_CONFIG"), "path to the YAML configuration file")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	db, err := database.InitDBWithConfig(cfg.Database.GetConnectionString())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer db.Close()
+
+	applied, err := database.Migrate(context.Background(), db)
+	for _, version := range applied {
+		fmt.Printf("applied migration %d\n", version)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
-}
\ No newline at end of file
+	fmt.Printf("schema at version %d\n", database.SchemaVersion)
+	return 0
+}
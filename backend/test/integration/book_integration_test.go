@@ -1,3 +1,8 @@
+//go:build integration
+
+// Integration tests need a Postgres instance; run them with
+// go test -tags integration ./test/integration
+
 package integration
 
 import (
@@ -9,12 +14,15 @@ import (
 
 	"byfood-library/internal/config"
 	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/duplicates"
+	"byfood-library/internal/infrastructure/database"
+	domain_repositories "byfood-library/internal/domain/repositories"
 	"byfood-library/internal/repositories"
+	"byfood-library/internal/tenancy"
 	"byfood-library/internal/usecases"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/zap"
 )
@@ -23,7 +31,7 @@ type BookIntegrationTestSuite struct {
 	suite.Suite
 	db        *sqlx.DB
 	bookUC    usecases.BookUseCase
-	bookRepo  repositories.BookRepository
+	bookRepo  domain_repositories.BookRepository
 	logger    *zap.Logger
 }
 
@@ -38,7 +46,7 @@ func (s *BookIntegrationTestSuite) SetupSuite() {
 	s.logger, _ = zap.NewDevelopment()
 
 	// Connect to test database
-	s.db, err = sqlx.Connect("postgres", cfg.Database.URL)
+	s.db, err = sqlx.Connect("postgres", cfg.Database.GetConnectionString())
 	if err != nil {
 		log.Fatal("Failed to connect to test database:", err)
 	}
@@ -48,7 +56,11 @@ func (s *BookIntegrationTestSuite) SetupSuite() {
 
 	// Initialize repositories and use cases
 	s.bookRepo = repositories.NewPostgresBookRepository(s.db, s.logger)
-	s.bookUC = usecases.NewBookUseCase(s.bookRepo, s.logger)
+	s.bookUC = usecases.NewBookUseCase(s.bookRepo,
+		repositories.NewPostgresOutboxRepository(s.db, s.logger),
+		repositories.NewPostgresTransactor(s.db, s.logger),
+		duplicates.NewDetector(duplicates.Config{}),
+		s.logger)
 }
 
 func (s *BookIntegrationTestSuite) TearDownSuite() {
@@ -56,29 +68,32 @@ func (s *BookIntegrationTestSuite) TearDownSuite() {
 }
 
 func (s *BookIntegrationTestSuite) SetupTest() {
-	// Clean database before each test
-	s.db.Exec("DELETE FROM books")
+	// Clean database before each test; TRUNCATE skips the row level
+	// security policies and the tombstone trigger
+	_, err := s.db.Exec("TRUNCATE books, book_tombstones, outbox CASCADE")
+	s.Require().NoError(err)
 }
 
-func (s *BookIntegrationTestSuite) runMigrations() {
-	migrations := []string{
-		`CREATE EXTENSION IF NOT EXISTS "uuid-ossp";`,
-		`CREATE TABLE IF NOT EXISTS books (
-			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-			title VARCHAR(255) NOT NULL,
-			author VARCHAR(255) NOT NULL,
-			year INTEGER NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);`,
-	}
+// ctx scopes use case calls to the default tenant seeded by the migrations
+func (s *BookIntegrationTestSuite) ctx() context.Context {
+	return tenancy.WithTenant(context.Background(), &entities.Tenant{
+		ID:     entities.DefaultTenantID,
+		Slug:   "default",
+		Status: entities.TenantStatusActive,
+	})
+}
 
-	for _, migration := range migrations {
-		_, err := s.db.Exec(migration)
-		if err != nil {
-			log.Fatal("Migration failed:", err)
+// runMigrations recreates the schema from the migrations the backend
+// applies, so the suite runs against the real schema
+func (s *BookIntegrationTestSuite) runMigrations() {
+	for _, statement := range []string{`DROP SCHEMA public CASCADE`, `CREATE SCHEMA public`} {
+		if _, err := s.db.Exec(statement); err != nil {
+			log.Fatal("Failed to reset schema:", err)
 		}
 	}
+	if _, err := database.Migrate(context.Background(), s.db); err != nil {
+		log.Fatal("Migration failed:", err)
+	}
 }
 
 func (s *BookIntegrationTestSuite) TestCreateBook() {
@@ -88,7 +103,7 @@ func (s *BookIntegrationTestSuite) TestCreateBook() {
 		Year:   2023,
 	}
 
-	book, err := s.bookUC.CreateBook(context.Background(), dto)
+	book, err := s.bookUC.CreateBook(s.ctx(), dto)
 
 	s.NoError(err)
 	s.NotNil(book)
@@ -104,6 +119,12 @@ func (s *BookIntegrationTestSuite) TestCreateBook() {
 	err = s.db.Get(&count, "SELECT COUNT(*) FROM books WHERE id = $1", book.ID)
 	s.NoError(err)
 	s.Equal(1, count)
+
+	// The BookCreated event committed with the book
+	var eventType string
+	err = s.db.Get(&eventType, "SELECT event_type FROM outbox WHERE aggregate_id = $1", book.ID)
+	s.NoError(err)
+	s.Equal("book.created", eventType)
 }
 
 func (s *BookIntegrationTestSuite) TestGetBookByID() {
@@ -114,11 +135,11 @@ func (s *BookIntegrationTestSuite) TestGetBookByID() {
 		Year:   2023,
 	}
 
-	createdBook, err := s.bookUC.CreateBook(context.Background(), dto)
+	createdBook, err := s.bookUC.CreateBook(s.ctx(), dto)
 	s.NoError(err)
 
 	// Now get it by ID
-	retrievedBook, err := s.bookUC.GetBookByID(context.Background(), createdBook.ID)
+	retrievedBook, err := s.bookUC.GetBookByID(s.ctx(), createdBook.ID)
 
 	s.NoError(err)
 	s.NotNil(retrievedBook)
@@ -131,7 +152,7 @@ func (s *BookIntegrationTestSuite) TestGetBookByID() {
 func (s *BookIntegrationTestSuite) TestGetBookByID_NotFound() {
 	nonExistentID := uuid.New()
 
-	book, err := s.bookUC.GetBookByID(context.Background(), nonExistentID)
+	book, err := s.bookUC.GetBookByID(s.ctx(), nonExistentID)
 
 	s.Error(err)
 	s.Nil(book)
@@ -148,13 +169,13 @@ func (s *BookIntegrationTestSuite) TestGetAllBooks() {
 
 	var createdBooks []*entities.Book
 	for _, dto := range books {
-		book, err := s.bookUC.CreateBook(context.Background(), dto)
+		book, err := s.bookUC.CreateBook(s.ctx(), dto)
 		s.NoError(err)
 		createdBooks = append(createdBooks, book)
 	}
 
 	// Get all books
-	allBooks, err := s.bookUC.GetAllBooks(context.Background())
+	allBooks, err := s.bookUC.GetAllBooks(s.ctx())
 
 	s.NoError(err)
 	s.NotNil(allBooks)
@@ -173,7 +194,7 @@ func (s *BookIntegrationTestSuite) TestUpdateBook() {
 		Year:   2020,
 	}
 
-	createdBook, err := s.bookUC.CreateBook(context.Background(), dto)
+	createdBook, err := s.bookUC.CreateBook(s.ctx(), dto)
 	s.NoError(err)
 
 	// Wait a bit to ensure updated_at is different
@@ -186,7 +207,7 @@ func (s *BookIntegrationTestSuite) TestUpdateBook() {
 		Year:   2023,
 	}
 
-	updatedBook, err := s.bookUC.UpdateBook(context.Background(), createdBook.ID, updateDTO)
+	updatedBook, err := s.bookUC.UpdateBook(s.ctx(), createdBook.ID, updateDTO)
 
 	s.NoError(err)
 	s.NotNil(updatedBook)
@@ -198,6 +219,36 @@ func (s *BookIntegrationTestSuite) TestUpdateBook() {
 	s.True(updatedBook.UpdatedAt.After(createdBook.UpdatedAt))
 }
 
+func (s *BookIntegrationTestSuite) TestUpdateBook_KeepsFieldsLeftOut() {
+	createdBook, err := s.bookUC.CreateBook(s.ctx(), &entities.CreateBookDTO{
+		Title:  "Clean Code",
+		Author: "Robert C. Martin",
+		Year:   2008,
+		ISBN:   "9780132350884",
+		BookMetadata: entities.BookMetadata{
+			Publisher: "Prentice Hall",
+			PageCount: 464,
+			Subjects:  []string{"Software"},
+		},
+	})
+	s.Require().NoError(err)
+
+	_, err = s.bookUC.UpdateBook(s.ctx(), createdBook.ID, &entities.UpdateBookDTO{
+		Title:  "Clean Code, 2nd Edition",
+		Author: "Robert C. Martin",
+		Year:   2008,
+	})
+	s.Require().NoError(err)
+
+	stored, err := s.bookRepo.GetByID(s.ctx(), createdBook.ID)
+	s.Require().NoError(err)
+	s.Equal("Clean Code, 2nd Edition", stored.Title)
+	s.Equal("9780132350884", stored.ISBN)
+	s.Equal("Prentice Hall", stored.Publisher)
+	s.Equal(464, stored.PageCount)
+	s.Equal([]string{"Software"}, []string(stored.Subjects))
+}
+
 func (s *BookIntegrationTestSuite) TestUpdateBook_NotFound() {
 	nonExistentID := uuid.New()
 	updateDTO := &entities.UpdateBookDTO{
@@ -206,7 +257,7 @@ func (s *BookIntegrationTestSuite) TestUpdateBook_NotFound() {
 		Year:   2023,
 	}
 
-	updatedBook, err := s.bookUC.UpdateBook(context.Background(), nonExistentID, updateDTO)
+	updatedBook, err := s.bookUC.UpdateBook(s.ctx(), nonExistentID, updateDTO)
 
 	s.Error(err)
 	s.Nil(updatedBook)
@@ -221,16 +272,16 @@ func (s *BookIntegrationTestSuite) TestDeleteBook() {
 		Year:   2023,
 	}
 
-	createdBook, err := s.bookUC.CreateBook(context.Background(), dto)
+	createdBook, err := s.bookUC.CreateBook(s.ctx(), dto)
 	s.NoError(err)
 
 	// Delete the book
-	err = s.bookUC.DeleteBook(context.Background(), createdBook.ID)
+	err = s.bookUC.DeleteBook(s.ctx(), createdBook.ID)
 
 	s.NoError(err)
 
 	// Verify it's deleted
-	_, err = s.bookUC.GetBookByID(context.Background(), createdBook.ID)
+	_, err = s.bookUC.GetBookByID(s.ctx(), createdBook.ID)
 	s.Error(err)
 	s.Equal(entities.ErrBookNotFound, err)
 
@@ -244,7 +295,7 @@ func (s *BookIntegrationTestSuite) TestDeleteBook() {
 func (s *BookIntegrationTestSuite) TestDeleteBook_NotFound() {
 	nonExistentID := uuid.New()
 
-	err := s.bookUC.DeleteBook(context.Background(), nonExistentID)
+	err := s.bookUC.DeleteBook(s.ctx(), nonExistentID)
 
 	s.Error(err)
 	s.Equal(entities.ErrBookNotFound, err)
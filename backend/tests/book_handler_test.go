@@ -6,12 +6,17 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"byfood-library/internal/delivery/http/handlers"
 	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/problem"
+	"byfood-library/internal/tenancy"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
@@ -38,6 +43,14 @@ func (m *MockBookUseCase) GetBookByID(ctx context.Context, id uuid.UUID) (*entit
 	return args.Get(0).(*entities.Book), args.Error(1)
 }
 
+func (m *MockBookUseCase) GetBooksByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.Book), args.Error(1)
+}
+
 func (m *MockBookUseCase) GetAllBooks(ctx context.Context) ([]*entities.Book, error) {
 	args := m.Called(ctx)
 	if args.Get(0) == nil {
@@ -59,6 +72,22 @@ func (m *MockBookUseCase) DeleteBook(ctx context.Context, id uuid.UUID) error {
 	return args.Error(0)
 }
 
+func (m *MockBookUseCase) FindDuplicates(ctx context.Context) ([]entities.DuplicateGroup, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.DuplicateGroup), args.Error(1)
+}
+
+func (m *MockBookUseCase) MergeBooks(ctx context.Context, dto *entities.MergeBooksDTO) (*entities.Book, error) {
+	args := m.Called(ctx, dto)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Book), args.Error(1)
+}
+
 func setupBookHandler() (*MockBookUseCase, handlers.BookHandlerInterface) {
 	mockUseCase := new(MockBookUseCase)
 	logger, _ := zap.NewDevelopment()
@@ -109,10 +138,13 @@ func TestBookHandler_GetBooks(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 
-		var errorResp handlers.ErrorResponse
+		assert.Equal(t, problem.ContentType, rec.Header().Get(echo.HeaderContentType))
+		var errorResp problem.Problem
 		err = json.Unmarshal(rec.Body.Bytes(), &errorResp)
 		assert.NoError(t, err)
-		assert.Equal(t, "Failed to retrieve books", errorResp.Error)
+		assert.Equal(t, problem.TypeInternal, errorResp.Type)
+		assert.Equal(t, http.StatusInternalServerError, errorResp.Status)
+		assert.NotContains(t, errorResp.Detail, entities.ErrDatabaseError.Error())
 
 		mockUseCase.AssertExpectations(t)
 	})
@@ -166,10 +198,11 @@ func TestBookHandler_GetBook(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 
-		var errorResp handlers.ErrorResponse
+		var errorResp problem.Problem
 		err = json.Unmarshal(rec.Body.Bytes(), &errorResp)
 		assert.NoError(t, err)
-		assert.Equal(t, "Invalid UUID format", errorResp.Error)
+		assert.Equal(t, problem.TypeBadRequest, errorResp.Type)
+		assert.Equal(t, entities.ErrInvalidUUID.Error(), errorResp.Detail)
 
 		mockUseCase.AssertExpectations(t)
 	})
@@ -191,15 +224,149 @@ func TestBookHandler_GetBook(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusNotFound, rec.Code)
 
-		var errorResp handlers.ErrorResponse
+		var errorResp problem.Problem
 		err = json.Unmarshal(rec.Body.Bytes(), &errorResp)
 		assert.NoError(t, err)
-		assert.Equal(t, "Book not found", errorResp.Error)
+		assert.Equal(t, problem.TypeNotFound, errorResp.Type)
+		assert.Equal(t, http.StatusNotFound, errorResp.Status)
 
 		mockUseCase.AssertExpectations(t)
 	})
 }
 
+func TestBookHandler_GetBook_Representations(t *testing.T) {
+	mockUseCase, handler := setupBookHandler()
+	book := &entities.Book{
+		ID:     uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+		Title:  "=Clean Code",
+		Author: "Robert C. Martin",
+		Year:   2008,
+		ISBN:   "9780132350884",
+		BookMetadata: entities.BookMetadata{
+			Subjects: pq.StringArray{"Software", "Agile"},
+		},
+		CreatedAt: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		UpdatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	mockUseCase.On("GetBookByID", mock.Anything, book.ID).Return(book, nil)
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/books/"+book.ID.String(), nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(book.ID.String())
+
+		assert.NoError(t, handler.GetBook(c))
+		return rec
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		rec := get("*/*")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "Accept", rec.Header().Get(echo.HeaderVary))
+		var result entities.Book
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, book.Title, result.Title)
+	})
+
+	t.Run("JSON-LD", func(t *testing.T) {
+		rec := get("application/ld+json")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/ld+json", rec.Header().Get(echo.HeaderContentType))
+		var result map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, "Book", result["@type"])
+		assert.Equal(t, map[string]interface{}{"@type": "Person", "name": "Robert C. Martin"}, result["author"])
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		rec := get("text/csv")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/csv; charset=utf-8; header=present", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "id,title,author,year,isbn,publisher,page_count,subjects,description,cover_url,created_at,updated_at\n"+
+			"11111111-1111-1111-1111-111111111111,'=Clean Code,Robert C. Martin,2008,9780132350884,,,Software; Agile,,,2024-05-06T07:08:09Z,2025-01-02T03:04:05Z\n",
+			rec.Body.String())
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		rec := get("application/xml")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/xml; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+		assert.Contains(t, rec.Body.String(), `<book id="11111111-1111-1111-1111-111111111111"><title>=Clean Code</title>`)
+		assert.Contains(t, rec.Body.String(), `<subjects><subject>Software</subject><subject>Agile</subject></subjects>`)
+	})
+
+	t.Run("Turtle", func(t *testing.T) {
+		rec := get("text/turtle")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/turtle; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+		assert.Contains(t, rec.Body.String(), `<urn:uuid:11111111-1111-1111-1111-111111111111> a schema:Book`)
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		rec := get("image/png")
+
+		assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+		assert.Equal(t, problem.ContentType, rec.Header().Get(echo.HeaderContentType))
+	})
+}
+
+func TestBookHandler_GetBooks_Representations(t *testing.T) {
+	mockUseCase, handler := setupBookHandler()
+	books := []*entities.Book{
+		{ID: uuid.New(), Title: "Book 1", Author: "Author 1", Year: 2020},
+		{ID: uuid.New(), Title: "Book 2", Author: "Author 2", Year: 2021},
+	}
+	mockUseCase.On("GetAllBooks", mock.Anything).Return(books, nil)
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/books", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+
+		assert.NoError(t, handler.GetBooks(e.NewContext(req, rec)))
+		return rec
+	}
+
+	t.Run("JSON-LD item list", func(t *testing.T) {
+		rec := get("application/ld+json")
+
+		var result map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, "ItemList", result["@type"])
+		assert.Equal(t, float64(2), result["numberOfItems"])
+	})
+
+	t.Run("CSV row per book", func(t *testing.T) {
+		rec := get("text/csv")
+
+		assert.Equal(t, 3, strings.Count(rec.Body.String(), "\n"))
+	})
+
+	t.Run("XML list", func(t *testing.T) {
+		rec := get("application/xml;q=0.9, application/json;q=0.1")
+
+		assert.Equal(t, "application/xml; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+		assert.Contains(t, rec.Body.String(), "<books><book id=")
+	})
+
+	t.Run("Turtle", func(t *testing.T) {
+		rec := get("text/turtle")
+
+		assert.Equal(t, 2, strings.Count(rec.Body.String(), "a schema:Book"))
+	})
+}
+
 func TestBookHandler_CreateBook(t *testing.T) {
 	mockUseCase, handler := setupBookHandler()
 
@@ -252,10 +419,10 @@ func TestBookHandler_CreateBook(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 
-		var errorResp handlers.ErrorResponse
+		var errorResp problem.Problem
 		err = json.Unmarshal(rec.Body.Bytes(), &errorResp)
 		assert.NoError(t, err)
-		assert.Equal(t, "Invalid request body", errorResp.Error)
+		assert.Equal(t, problem.TypeBadRequest, errorResp.Type)
 
 		mockUseCase.AssertExpectations(t)
 	})
@@ -264,10 +431,10 @@ func TestBookHandler_CreateBook(t *testing.T) {
 		dto := entities.CreateBookDTO{
 			Title:  "", // Invalid
 			Author: "Alan Donovan",
-			Year:   2015,
+			Year:   999, // Invalid
 		}
 
-		mockUseCase.On("CreateBook", mock.Anything, &dto).Return(nil, entities.ErrInvalidTitle).Once()
+		mockUseCase.On("CreateBook", mock.Anything, &dto).Return(nil, dto.Validate()).Once()
 
 		reqBody, _ := json.Marshal(dto)
 		e := echo.New()
@@ -281,10 +448,132 @@ func TestBookHandler_CreateBook(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 
-		var errorResp handlers.ErrorResponse
+		var errorResp problem.Problem
+		err = json.Unmarshal(rec.Body.Bytes(), &errorResp)
+		assert.NoError(t, err)
+		assert.Equal(t, problem.TypeValidation, errorResp.Type)
+		if assert.Len(t, errorResp.Errors, 2) {
+			assert.Equal(t, "/title", errorResp.Errors[0].Pointer)
+			assert.Equal(t, "/year", errorResp.Errors[1].Pointer)
+		}
+
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("possible duplicate", func(t *testing.T) {
+		dto := entities.CreateBookDTO{Title: "Clean code", Author: "Robert C. Martin", Year: 2008}
+		existing := &entities.Book{ID: uuid.New(), Title: "Clean Code", Author: "Robert Martin", Year: 2008}
+		duplicateErr := &entities.DuplicateError{Candidates: []entities.DuplicateCandidate{
+			{Book: existing, Score: 0.93, Reason: entities.DuplicateReasonSimilar},
+		}}
+
+		mockUseCase.On("CreateBook", mock.Anything, &dto).Return(nil, duplicateErr).Once()
+
+		reqBody, _ := json.Marshal(dto)
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/books", bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.CreateBook(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		var errorResp problem.Problem
 		err = json.Unmarshal(rec.Body.Bytes(), &errorResp)
 		assert.NoError(t, err)
-		assert.Equal(t, "Validation failed", errorResp.Error)
+		assert.Equal(t, problem.TypeDuplicate, errorResp.Type)
+		if assert.Len(t, errorResp.Duplicates, 1) {
+			assert.Equal(t, existing.ID, errorResp.Duplicates[0].Book.ID)
+		}
+
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("force overrides the duplicate check", func(t *testing.T) {
+		dto := entities.CreateBookDTO{Title: "Clean code", Author: "Robert C. Martin", Year: 2008}
+		forced := dto
+		forced.Force = true
+
+		mockUseCase.On("CreateBook", mock.Anything, &forced).Return(&entities.Book{ID: uuid.New()}, nil).Once()
+
+		reqBody, _ := json.Marshal(dto)
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/books?force=true", bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.CreateBook(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("invalid force", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/books?force=maybe", bytes.NewReader([]byte(`{"title":"Dune"}`)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.CreateBook(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUseCase.AssertExpectations(t)
+	})
+}
+
+func TestBookHandler_MergeBooks(t *testing.T) {
+	mockUseCase, handler := setupBookHandler()
+
+	t.Run("successful merge", func(t *testing.T) {
+		dto := entities.MergeBooksDTO{SurvivorID: uuid.New(), DuplicateIDs: []uuid.UUID{uuid.New()}}
+		survivor := &entities.Book{ID: dto.SurvivorID, Title: "Clean Code", Author: "Robert Martin", Year: 2008}
+
+		mockUseCase.On("MergeBooks", mock.Anything, &dto).Return(survivor, nil).Once()
+
+		reqBody, _ := json.Marshal(dto)
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/books/merge", bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.MergeBooks(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var result entities.Book
+		err = json.Unmarshal(rec.Body.Bytes(), &result)
+		assert.NoError(t, err)
+		assert.Equal(t, survivor.ID, result.ID)
+
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("missing book", func(t *testing.T) {
+		dto := entities.MergeBooksDTO{SurvivorID: uuid.New(), DuplicateIDs: []uuid.UUID{uuid.New()}}
+
+		mockUseCase.On("MergeBooks", mock.Anything, &dto).Return(nil, entities.ErrBookNotFound).Once()
+
+		reqBody, _ := json.Marshal(dto)
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/books/merge", bytes.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.MergeBooks(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
 
 		mockUseCase.AssertExpectations(t)
 	})
@@ -331,6 +620,29 @@ func TestBookHandler_UpdateBook(t *testing.T) {
 
 		mockUseCase.AssertExpectations(t)
 	})
+
+	t.Run("fields left out are not sent as empty", func(t *testing.T) {
+		bookID := uuid.New()
+		leftOut := mock.MatchedBy(func(dto *entities.UpdateBookDTO) bool {
+			return dto.ISBN == nil && dto.PageCount == nil && dto.Subjects == nil && dto.Description == nil &&
+				dto.CoverURL == nil && dto.Publisher != nil && *dto.Publisher == ""
+		})
+		mockUseCase.On("UpdateBook", mock.Anything, bookID, leftOut).Return(&entities.Book{ID: bookID}, nil).Once()
+
+		// The frontend sends only the fields of its form
+		reqBody := `{"title":"Clean Code","author":"Robert C. Martin","year":2008,"publisher":""}`
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/books/"+bookID.String(), strings.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(bookID.String())
+
+		assert.NoError(t, handler.UpdateBook(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockUseCase.AssertExpectations(t)
+	})
 }
 
 func TestBookHandler_DeleteBook(t *testing.T) {
@@ -360,4 +672,58 @@ func TestBookHandler_DeleteBook(t *testing.T) {
 
 		mockUseCase.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
+func TestBookHandler_GetBookSchema(t *testing.T) {
+	_, handler := setupBookHandler()
+
+	t.Run("default rules", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/books/schema", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.GetBookSchema(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"title": "Book",
+			"type": "object",
+			"required": ["year", "title", "author"],
+			"properties": {
+				"title": {"type": "string", "minLength": 1, "maxLength": 255},
+				"author": {"type": "string", "minLength": 1, "maxLength": 255},
+				"year": {"type": "integer", "minimum": 1000, "maximum": 2034}
+			}
+		}`, rec.Body.String())
+	})
+
+	t.Run("tenant overrides", func(t *testing.T) {
+		optional := false
+		tenant := &entities.Tenant{ID: uuid.New(), Settings: entities.TenantSettings{
+			Validation: &entities.BookValidationRules{
+				MinYear: 1450,
+				Title:   entities.TextRule{MaxLength: 100, Pattern: `^\S`, ForbiddenWords: []string{"draft"}},
+				Author:  entities.TextRule{Required: &optional},
+			},
+		}}
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/books/schema", nil)
+		req = req.WithContext(tenancy.WithTenant(req.Context(), tenant))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := handler.GetBookSchema(c)
+
+		assert.NoError(t, err)
+		var schema handlers.JSONSchema
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &schema))
+		assert.Equal(t, []string{"year", "title"}, schema.Required)
+		assert.Equal(t, 100, *schema.Properties["title"].MaxLength)
+		assert.Equal(t, `^\S`, schema.Properties["title"].Pattern)
+		assert.Equal(t, []string{"draft"}, schema.Properties["title"].ForbiddenWords)
+		assert.Nil(t, schema.Properties["author"].MinLength)
+		assert.Equal(t, 1450, *schema.Properties["year"].Minimum)
+	})
+}
@@ -2,17 +2,63 @@ package tests
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
 	"testing"
+	"time"
 
+	"byfood-library/internal/cql"
 	"byfood-library/internal/domain/entities"
 	domain_repositories "byfood-library/internal/domain/repositories"
 	"byfood-library/internal/repositories"
+	"byfood-library/internal/tenancy"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
 )
 
+var testTenant = &entities.Tenant{ID: uuid.New(), Slug: "test", Status: entities.TenantStatusActive}
+
+func testTime(value string) time.Time {
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
+
+// bookRowColumns are the columns the repository scans into a book
+var bookRowColumns = []string{"id", "tenant_id", "title", "author", "year", "isbn", "publisher", "page_count", "subjects", "description", "cover_url", "cover_etag", "created_at", "updated_at"}
+
+// pairColumns are bookRowColumns for both sides of a self-join
+func pairColumns() []string {
+	var columns []string
+	for _, alias := range []string{"a", "b"} {
+		for _, column := range bookRowColumns {
+			columns = append(columns, alias+"."+column)
+		}
+	}
+	return columns
+}
+
+func tenantContext() context.Context {
+	return tenancy.WithTenant(context.Background(), testTenant)
+}
+
+// expectTenantTx expects the transaction preamble that binds the tenant for RLS
+func expectTenantTx(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT set_config\('app.tenant_id', \$1, true\)`).
+		WithArgs(testTenant.ID.String()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
 func setupRepositoryTest() (*sqlx.DB, sqlmock.Sqlmock, domain_repositories.BookRepository) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -21,7 +67,7 @@ func setupRepositoryTest() (*sqlx.DB, sqlmock.Sqlmock, domain_repositories.BookR
 
 	// Wrap with sqlx.NewDb for sqlx compatibility
 	sqlxDB := sqlx.NewDb(db, "postgres")
-	repo := repositories.NewPostgresBookRepository(sqlxDB)
+	repo := repositories.NewPostgresBookRepository(sqlxDB, zap.NewNop())
 
 	return sqlxDB, mock, repo
 }
@@ -35,17 +81,24 @@ func TestPostgresBookRepository_Create(t *testing.T) {
 			Title:  "The Go Programming Language",
 			Author: "Alan Donovan",
 			Year:   2015,
+			BookMetadata: entities.BookMetadata{
+				Publisher: "Addison-Wesley",
+				PageCount: 380,
+				Subjects:  []string{"Go", "Programming"},
+			},
 		}
 
 		expectedID := uuid.New()
-		rows := sqlmock.NewRows([]string{"id", "title", "author", "year", "created_at", "updated_at"}).
-			AddRow(expectedID, "The Go Programming Language", "Alan Donovan", 2015, "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z")
+		rows := sqlmock.NewRows(bookRowColumns).
+			AddRow(expectedID, testTenant.ID, "The Go Programming Language", "Alan Donovan", 2015, "", "Addison-Wesley", 380, "{Go,Programming}", "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-01T00:00:00Z"))
 
-		mock.ExpectQuery(`INSERT INTO books \(title, author, year\) VALUES \(\$1, \$2, \$3\)`).
-			WithArgs("The Go Programming Language", "Alan Donovan", 2015).
+		expectTenantTx(mock)
+		mock.ExpectQuery(`INSERT INTO books \(tenant_id, title, author, year, isbn, publisher, page_count, subjects, description, cover_url\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10\)`).
+			WithArgs(testTenant.ID, "The Go Programming Language", "Alan Donovan", 2015, "", "Addison-Wesley", 380, `{"Go","Programming"}`, "", "").
 			WillReturnRows(rows)
+		mock.ExpectCommit()
 
-		result, err := repo.Create(context.Background(), book)
+		result, err := repo.Create(tenantContext(), book)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
@@ -53,6 +106,7 @@ func TestPostgresBookRepository_Create(t *testing.T) {
 		assert.Equal(t, "The Go Programming Language", result.Title)
 		assert.Equal(t, "Alan Donovan", result.Author)
 		assert.Equal(t, 2015, result.Year)
+		assert.Equal(t, []string{"Go", "Programming"}, []string(result.Subjects))
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -64,11 +118,13 @@ func TestPostgresBookRepository_Create(t *testing.T) {
 			Year:   2015,
 		}
 
-		mock.ExpectQuery(`INSERT INTO books \(title, author, year\) VALUES \(\$1, \$2, \$3\)`).
-			WithArgs("The Go Programming Language", "Alan Donovan", 2015).
+		expectTenantTx(mock)
+		mock.ExpectQuery(`INSERT INTO books \(tenant_id, title, author, year, isbn, publisher, page_count, subjects, description, cover_url\)\s+VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9, \$10\)`).
+			WithArgs(testTenant.ID, "The Go Programming Language", "Alan Donovan", 2015, "", "", 0, nil, "", "").
 			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
 
-		result, err := repo.Create(context.Background(), book)
+		result, err := repo.Create(tenantContext(), book)
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
@@ -84,14 +140,16 @@ func TestPostgresBookRepository_GetByID(t *testing.T) {
 
 	t.Run("successful retrieval", func(t *testing.T) {
 		bookID := uuid.New()
-		rows := sqlmock.NewRows([]string{"id", "title", "author", "year", "created_at", "updated_at"}).
-			AddRow(bookID, "The Go Programming Language", "Alan Donovan", 2015, "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z")
+		rows := sqlmock.NewRows(bookRowColumns).
+			AddRow(bookID, testTenant.ID, "The Go Programming Language", "Alan Donovan", 2015, "", "", 0, nil, "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-01T00:00:00Z"))
 
-		mock.ExpectQuery(`SELECT id, title, author, year, created_at, updated_at FROM books WHERE id = \$1`).
-			WithArgs(bookID).
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT id, tenant_id, title, author, year, isbn, publisher, page_count, subjects, description, cover_url, cover_etag, created_at, updated_at FROM books WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs(testTenant.ID, bookID).
 			WillReturnRows(rows)
+		mock.ExpectCommit()
 
-		result, err := repo.GetByID(context.Background(), bookID)
+		result, err := repo.GetByID(tenantContext(), bookID)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
@@ -106,11 +164,13 @@ func TestPostgresBookRepository_GetByID(t *testing.T) {
 	t.Run("book not found", func(t *testing.T) {
 		bookID := uuid.New()
 
-		mock.ExpectQuery(`SELECT id, title, author, year, created_at, updated_at FROM books WHERE id = \$1`).
-			WithArgs(bookID).
-			WillReturnError(sqlmock.ErrCancelled)
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT id, tenant_id, title, author, year, isbn, publisher, page_count, subjects, description, cover_url, cover_etag, created_at, updated_at FROM books WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs(testTenant.ID, bookID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
 
-		result, err := repo.GetByID(context.Background(), bookID)
+		result, err := repo.GetByID(tenantContext(), bookID)
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
@@ -120,6 +180,46 @@ func TestPostgresBookRepository_GetByID(t *testing.T) {
 	})
 }
 
+func TestPostgresBookRepository_GetByIDForUpdate(t *testing.T) {
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+
+	t.Run("locks the row", func(t *testing.T) {
+		bookID := uuid.New()
+		rows := sqlmock.NewRows(bookRowColumns).
+			AddRow(bookID, testTenant.ID, "The Go Programming Language", "Alan Donovan", 2015, "9780134190440", "", 0, nil, "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-01T00:00:00Z"))
+
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT id, tenant_id, title, .* FROM books WHERE tenant_id = \$1 AND id = \$2 FOR UPDATE`).
+			WithArgs(testTenant.ID, bookID).
+			WillReturnRows(rows)
+		mock.ExpectCommit()
+
+		result, err := repo.GetByIDForUpdate(tenantContext(), bookID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, bookID, result.ID)
+		assert.Equal(t, "9780134190440", result.ISBN)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("book not found", func(t *testing.T) {
+		bookID := uuid.New()
+
+		expectTenantTx(mock)
+		mock.ExpectQuery(`FOR UPDATE`).
+			WithArgs(testTenant.ID, bookID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		result, err := repo.GetByIDForUpdate(tenantContext(), bookID)
+
+		assert.Equal(t, entities.ErrBookNotFound, err)
+		assert.Nil(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestPostgresBookRepository_GetAll(t *testing.T) {
 	db, mock, repo := setupRepositoryTest()
 	defer db.Close()
@@ -127,14 +227,17 @@ func TestPostgresBookRepository_GetAll(t *testing.T) {
 	t.Run("successful retrieval", func(t *testing.T) {
 		bookID1 := uuid.New()
 		bookID2 := uuid.New()
-		rows := sqlmock.NewRows([]string{"id", "title", "author", "year", "created_at", "updated_at"}).
-			AddRow(bookID1, "Book 1", "Author 1", 2020, "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z").
-			AddRow(bookID2, "Book 2", "Author 2", 2021, "2024-01-02T00:00:00Z", "2024-01-02T00:00:00Z")
+		rows := sqlmock.NewRows(bookRowColumns).
+			AddRow(bookID1, testTenant.ID, "Book 1", "Author 1", 2020, "", "", 0, nil, "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-01T00:00:00Z")).
+			AddRow(bookID2, testTenant.ID, "Book 2", "Author 2", 2021, "", "", 0, nil, "", "", "", testTime("2024-01-02T00:00:00Z"), testTime("2024-01-02T00:00:00Z"))
 
-		mock.ExpectQuery(`SELECT id, title, author, year, created_at, updated_at FROM books ORDER BY created_at DESC`).
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT id, tenant_id, title, author, year, isbn, publisher, page_count, subjects, description, cover_url, cover_etag, created_at, updated_at FROM books WHERE tenant_id = \$1 ORDER BY created_at DESC`).
+			WithArgs(testTenant.ID).
 			WillReturnRows(rows)
+		mock.ExpectCommit()
 
-		result, err := repo.GetAll(context.Background())
+		result, err := repo.GetAll(tenantContext())
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
@@ -148,10 +251,13 @@ func TestPostgresBookRepository_GetAll(t *testing.T) {
 	})
 
 	t.Run("database error", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT id, title, author, year, created_at, updated_at FROM books ORDER BY created_at DESC`).
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT id, tenant_id, title, author, year, isbn, publisher, page_count, subjects, description, cover_url, cover_etag, created_at, updated_at FROM books WHERE tenant_id = \$1 ORDER BY created_at DESC`).
+			WithArgs(testTenant.ID).
 			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
 
-		result, err := repo.GetAll(context.Background())
+		result, err := repo.GetAll(tenantContext())
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
@@ -161,6 +267,52 @@ func TestPostgresBookRepository_GetAll(t *testing.T) {
 	})
 }
 
+func TestPostgresBookRepository_GetByIDs(t *testing.T) {
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+
+	bookID := uuid.New()
+	missingID := uuid.New()
+	rows := sqlmock.NewRows(bookRowColumns).
+		AddRow(bookID, testTenant.ID, "Book 1", "Author 1", 2020, "", "", 0, nil, "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-01T00:00:00Z"))
+
+	expectTenantTx(mock)
+	mock.ExpectQuery(`SELECT id, tenant_id, title, author, year, isbn, publisher, page_count, subjects, description, cover_url, cover_etag, created_at, updated_at FROM books WHERE tenant_id = \$1 AND id = ANY\(\$2::uuid\[\]\)`).
+		WithArgs(testTenant.ID, "{\""+bookID.String()+"\",\""+missingID.String()+"\"}").
+		WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	result, err := repo.GetByIDs(tenantContext(), []uuid.UUID{bookID, missingID})
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, bookID, result[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresBookRepository_GetByIDsForUpdate(t *testing.T) {
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+
+	firstID := uuid.New()
+	secondID := uuid.New()
+	rows := sqlmock.NewRows(bookRowColumns).
+		AddRow(firstID, testTenant.ID, "Book 1", "Author 1", 2020, "", "", 0, nil, "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-01T00:00:00Z")).
+		AddRow(secondID, testTenant.ID, "Book 2", "Author 2", 2021, "", "", 0, nil, "", "", "", testTime("2024-01-02T00:00:00Z"), testTime("2024-01-02T00:00:00Z"))
+
+	expectTenantTx(mock)
+	mock.ExpectQuery(`FROM books WHERE tenant_id = \$1 AND id = ANY\(\$2::uuid\[\]\) ORDER BY id FOR UPDATE`).
+		WithArgs(testTenant.ID, "{\""+secondID.String()+"\",\""+firstID.String()+"\"}").
+		WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	result, err := repo.GetByIDsForUpdate(tenantContext(), []uuid.UUID{secondID, firstID})
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestPostgresBookRepository_Update(t *testing.T) {
 	db, mock, repo := setupRepositoryTest()
 	defer db.Close()
@@ -173,14 +325,16 @@ func TestPostgresBookRepository_Update(t *testing.T) {
 			Year:   2022,
 		}
 
-		rows := sqlmock.NewRows([]string{"id", "title", "author", "year", "created_at", "updated_at"}).
-			AddRow(bookID, "Updated Title", "Updated Author", 2022, "2024-01-01T00:00:00Z", "2024-01-01T01:00:00Z")
+		rows := sqlmock.NewRows(bookRowColumns).
+			AddRow(bookID, testTenant.ID, "Updated Title", "Updated Author", 2022, "", "", 0, nil, "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-01T01:00:00Z"))
 
-		mock.ExpectQuery(`UPDATE books SET title = \$1, author = \$2, year = \$3 WHERE id = \$4`).
-			WithArgs("Updated Title", "Updated Author", 2022, bookID).
+		expectTenantTx(mock)
+		mock.ExpectQuery(`UPDATE books SET title = \$1, author = \$2, year = \$3, isbn = \$4, publisher = \$5,\s+page_count = \$6, subjects = \$7, description = \$8, cover_url = \$9\s+WHERE tenant_id = \$10 AND id = \$11`).
+			WithArgs("Updated Title", "Updated Author", 2022, "", "", 0, nil, "", "", testTenant.ID, bookID).
 			WillReturnRows(rows)
+		mock.ExpectCommit()
 
-		result, err := repo.Update(context.Background(), bookID, book)
+		result, err := repo.Update(tenantContext(), bookID, book)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
@@ -200,13 +354,15 @@ func TestPostgresBookRepository_Update(t *testing.T) {
 			Year:   2022,
 		}
 
-		rows := sqlmock.NewRows([]string{"id", "title", "author", "year", "created_at", "updated_at"})
+		rows := sqlmock.NewRows(bookRowColumns)
 
-		mock.ExpectQuery(`UPDATE books SET title = \$1, author = \$2, year = \$3 WHERE id = \$4`).
-			WithArgs("Updated Title", "Updated Author", 2022, bookID).
+		expectTenantTx(mock)
+		mock.ExpectQuery(`UPDATE books SET title = \$1, author = \$2, year = \$3, isbn = \$4, publisher = \$5,\s+page_count = \$6, subjects = \$7, description = \$8, cover_url = \$9\s+WHERE tenant_id = \$10 AND id = \$11`).
+			WithArgs("Updated Title", "Updated Author", 2022, "", "", 0, nil, "", "", testTenant.ID, bookID).
 			WillReturnRows(rows)
+		mock.ExpectRollback()
 
-		result, err := repo.Update(context.Background(), bookID, book)
+		result, err := repo.Update(tenantContext(), bookID, book)
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
@@ -216,6 +372,129 @@ func TestPostgresBookRepository_Update(t *testing.T) {
 	})
 }
 
+func TestPostgresBookRepository_SetCover(t *testing.T) {
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+
+	t.Run("successful update", func(t *testing.T) {
+		bookID := uuid.New()
+		rows := sqlmock.NewRows(bookRowColumns).
+			AddRow(bookID, testTenant.ID, "Book 1", "Author 1", 2020, "", "", 0, nil, "", "", "9f2c", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-01T01:00:00Z"))
+
+		expectTenantTx(mock)
+		mock.ExpectQuery(`UPDATE books SET cover_etag = \$3 WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs(testTenant.ID, bookID, "9f2c").
+			WillReturnRows(rows)
+		mock.ExpectCommit()
+
+		result, err := repo.SetCover(tenantContext(), bookID, "9f2c")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "9f2c", result.CoverETag)
+		assert.Equal(t, "Book 1", result.Title)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("book not found", func(t *testing.T) {
+		bookID := uuid.New()
+
+		expectTenantTx(mock)
+		mock.ExpectQuery(`UPDATE books SET cover_etag = \$3`).
+			WithArgs(testTenant.ID, bookID, "").
+			WillReturnRows(sqlmock.NewRows(bookRowColumns))
+		mock.ExpectRollback()
+
+		result, err := repo.SetCover(tenantContext(), bookID, "")
+
+		assert.ErrorIs(t, err, entities.ErrBookNotFound)
+		assert.Nil(t, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// bookFileRowColumns are the columns the repository scans into a book file
+var bookFileRowColumns = []string{"id", "tenant_id", "book_id", "format", "content_type", "filename", "size", "checksum", "storage_key", "created_at"}
+
+func TestPostgresBookRepository_Files(t *testing.T) {
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+	bookID := uuid.New()
+	checksum := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+	t.Run("add a file", func(t *testing.T) {
+		file := &entities.BookFile{BookID: bookID, Format: "epub", ContentType: "application/epub+zip", Filename: "clean-code.epub", Size: 3, Checksum: checksum, StorageKey: "ebooks/key"}
+
+		expectTenantTx(mock)
+		mock.ExpectQuery(`INSERT INTO book_files`).
+			WithArgs(sqlmock.AnyArg(), testTenant.ID, bookID, "epub", "application/epub+zip", "clean-code.epub", int64(3), checksum, "ebooks/key").
+			WillReturnRows(sqlmock.NewRows(bookFileRowColumns).
+				AddRow(uuid.New(), testTenant.ID, bookID, "epub", "application/epub+zip", "clean-code.epub", 3, checksum, "ebooks/key", testTime("2024-01-01T00:00:00Z")))
+		mock.ExpectCommit()
+
+		result, err := repo.AddFile(tenantContext(), file)
+
+		assert.NoError(t, err)
+		assert.Equal(t, checksum, result.Checksum)
+		assert.Equal(t, "ebooks/key", result.StorageKey)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a checksum the tenant already has", func(t *testing.T) {
+		expectTenantTx(mock)
+		mock.ExpectQuery(`INSERT INTO book_files`).
+			WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		_, err := repo.AddFile(tenantContext(), &entities.BookFile{BookID: bookID, Checksum: checksum})
+
+		assert.ErrorIs(t, err, entities.ErrDuplicateBookFile)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("find by checksum", func(t *testing.T) {
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT .+ FROM book_files WHERE tenant_id = \$1 AND checksum = \$2`).
+			WithArgs(testTenant.ID, checksum).
+			WillReturnRows(sqlmock.NewRows(bookFileRowColumns))
+		mock.ExpectRollback()
+
+		_, err := repo.FindFileByChecksum(tenantContext(), checksum)
+
+		assert.ErrorIs(t, err, entities.ErrBookFileNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("move files to a survivor", func(t *testing.T) {
+		duplicate, survivor := uuid.New(), uuid.New()
+
+		expectTenantTx(mock)
+		mock.ExpectExec(`UPDATE book_files SET book_id = \$3 WHERE tenant_id = \$1 AND book_id = ANY\(\$2::uuid\[\]\)`).
+			WithArgs(testTenant.ID, pq.StringArray{duplicate.String()}, survivor).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := repo.MoveFiles(tenantContext(), []uuid.UUID{duplicate}, survivor)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete a missing file", func(t *testing.T) {
+		fileID := uuid.New()
+
+		expectTenantTx(mock)
+		mock.ExpectExec(`DELETE FROM book_files WHERE tenant_id = \$1 AND book_id = \$2 AND id = \$3`).
+			WithArgs(testTenant.ID, bookID, fileID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.DeleteFile(tenantContext(), bookID, fileID)
+
+		assert.ErrorIs(t, err, entities.ErrBookFileNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestPostgresBookRepository_Delete(t *testing.T) {
 	db, mock, repo := setupRepositoryTest()
 	defer db.Close()
@@ -223,11 +502,13 @@ func TestPostgresBookRepository_Delete(t *testing.T) {
 	t.Run("successful deletion", func(t *testing.T) {
 		bookID := uuid.New()
 
-		mock.ExpectExec(`DELETE FROM books WHERE id = \$1`).
-			WithArgs(bookID).
+		expectTenantTx(mock)
+		mock.ExpectExec(`DELETE FROM books WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs(testTenant.ID, bookID).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
 
-		err := repo.Delete(context.Background(), bookID)
+		err := repo.Delete(tenantContext(), bookID)
 
 		assert.NoError(t, err)
 
@@ -237,11 +518,13 @@ func TestPostgresBookRepository_Delete(t *testing.T) {
 	t.Run("book not found", func(t *testing.T) {
 		bookID := uuid.New()
 
-		mock.ExpectExec(`DELETE FROM books WHERE id = \$1`).
-			WithArgs(bookID).
+		expectTenantTx(mock)
+		mock.ExpectExec(`DELETE FROM books WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs(testTenant.ID, bookID).
 			WillReturnResult(sqlmock.NewResult(0, 0)) // No rows affected
+		mock.ExpectRollback()
 
-		err := repo.Delete(context.Background(), bookID)
+		err := repo.Delete(tenantContext(), bookID)
 
 		assert.Error(t, err)
 		assert.Equal(t, entities.ErrBookNotFound, err)
@@ -252,15 +535,374 @@ func TestPostgresBookRepository_Delete(t *testing.T) {
 	t.Run("database error", func(t *testing.T) {
 		bookID := uuid.New()
 
-		mock.ExpectExec(`DELETE FROM books WHERE id = \$1`).
-			WithArgs(bookID).
+		expectTenantTx(mock)
+		mock.ExpectExec(`DELETE FROM books WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs(testTenant.ID, bookID).
 			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
 
-		err := repo.Delete(context.Background(), bookID)
+		err := repo.Delete(tenantContext(), bookID)
 
 		assert.Error(t, err)
 		assert.Equal(t, entities.ErrDatabaseError, err)
 
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
-}
\ No newline at end of file
+}
+func TestPostgresBookRepository_Count(t *testing.T) {
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+
+	expectTenantTx(mock)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM books WHERE tenant_id = \$1`).
+		WithArgs(testTenant.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectCommit()
+
+	count, err := repo.Count(tenantContext())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresBookRepository_Search(t *testing.T) {
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+
+	t.Run("filters by every word, author and subject", func(t *testing.T) {
+		bookID := uuid.New()
+		query := entities.BookQuery{Search: "Clean 100%", Author: "Robert C. Martin", Subject: "Software", Offset: 10, Limit: 5}
+		conditions := `tenant_id = \$1 AND \(lower\(title\) LIKE \$2 OR lower\(author\) LIKE \$2\) AND \(lower\(title\) LIKE \$3 OR lower\(author\) LIKE \$3\) AND author = \$4 AND subjects @> \$5`
+
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM books WHERE `+conditions+`$`).
+			WithArgs(testTenant.ID, "%clean%", `%100\%%`, "Robert C. Martin", `{"Software"}`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+		mock.ExpectQuery(`SELECT .+ FROM books WHERE `+conditions+` ORDER BY created_at DESC, id DESC LIMIT \$6 OFFSET \$7`).
+			WithArgs(testTenant.ID, "%clean%", `%100\%%`, "Robert C. Martin", `{"Software"}`, 5, 10).
+			WillReturnRows(sqlmock.NewRows(bookRowColumns).
+				AddRow(bookID, testTenant.ID, "Clean Code 100%", "Robert C. Martin", 2008, "", "", 0, "{Software}", "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-01T00:00:00Z")))
+		mock.ExpectCommit()
+
+		page, err := repo.Search(tenantContext(), query)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 11, page.Total)
+		assert.Len(t, page.Books, 1)
+		assert.Equal(t, bookID, page.Books[0].ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("seeks past the cursor and counts every match", func(t *testing.T) {
+		after := &entities.BookCursor{CreatedAt: testTime("2024-01-02T00:00:00Z"), ID: uuid.New()}
+		query := entities.BookQuery{Author: "Robert C. Martin", After: after, Limit: 3}
+
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM books WHERE tenant_id = \$1 AND author = \$2$`).
+			WithArgs(testTenant.ID, "Robert C. Martin").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(40))
+		mock.ExpectQuery(`SELECT .+ FROM books WHERE tenant_id = \$1 AND author = \$2 AND \(created_at, id\) < \(\$3, \$4\) ORDER BY created_at DESC, id DESC LIMIT \$5 OFFSET \$6`).
+			WithArgs(testTenant.ID, "Robert C. Martin", after.CreatedAt, after.ID, 3, 0).
+			WillReturnRows(sqlmock.NewRows(bookRowColumns))
+		mock.ExpectCommit()
+
+		page, err := repo.Search(tenantContext(), query)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 40, page.Total)
+		assert.Empty(t, page.Books)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("facet values", func(t *testing.T) {
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT subject AS value, COUNT\(\*\) AS count FROM books, unnest\(subjects\) AS subject`).
+			WithArgs(testTenant.ID, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).AddRow("Software", 4).AddRow("Fiction", 2))
+		mock.ExpectCommit()
+
+		values, err := repo.FacetValues(tenantContext(), entities.FacetSubject, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []entities.FacetValue{{Value: "Software", Count: 4}, {Value: "Fiction", Count: 2}}, values)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresBookRepository_SearchCQL(t *testing.T) {
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+
+	translations := []struct {
+		name       string
+		query      string
+		conditions string
+		order      string
+		args       []driver.Value
+	}{
+		{
+			"server choice phrase",
+			`"Clean  Code"`,
+			`((lower(title) LIKE $2 OR lower(author) LIKE $2))`,
+			"created_at DESC, id DESC",
+			[]driver.Value{"%clean code%"},
+		},
+		{
+			"any word with masks and escapes",
+			`dc.title any "clean* c?de 100\% x\*"`,
+			`((lower(title) LIKE $2) OR (lower(title) LIKE $3) OR (lower(title) LIKE $4) OR (lower(title) LIKE $5))`,
+			"created_at DESC, id DESC",
+			[]driver.Value{"%clean%%", "%c_de%", `%100\%%`, "%x*%"},
+		},
+		{
+			"anchored, case-sensitive and unmasked",
+			`dc.creator =/respectCase/unmasked "^Martin*"`,
+			`((author LIKE $2))`,
+			"created_at DESC, id DESC",
+			[]driver.Value{"Martin*%"},
+		},
+		{
+			"exact, not equal and subjects",
+			`dc.publisher exact "Prentice Hall" not dc.subject <> fiction`,
+			`(((lower(publisher) LIKE $2)) AND NOT NOT (EXISTS (SELECT 1 FROM unnest(subjects) AS subject WHERE lower(subject) LIKE $3)))`,
+			"created_at DESC, id DESC",
+			[]driver.Value{"prentice hall", "fiction"},
+		},
+		{
+			"years and booleans",
+			`dc.date within "1990 2000" or (dc.date >= 2010 and dc.date <> 2015)`,
+			`(year BETWEEN $2 AND $3 OR (year >= $4 AND year <> $5))`,
+			"created_at DESC, id DESC",
+			[]driver.Value{1990, 2000, 2010, 2015},
+		},
+		{
+			"identifiers",
+			`dc.identifier = "urn:isbn:978-0-13-235088-4" or rec.identifier = 6ba7b810-9dad-11d1-80b4-00c04fd430c8`,
+			`(isbn = $2 OR id = $3)`,
+			"created_at DESC, id DESC",
+			[]driver.Value{"9780132350884", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		},
+		{
+			"proximity",
+			`dc.title = clean prox/unit=word/distance<=2/ordered dc.title = "code*"`,
+			`(to_tsvector('simple', title) @@ to_tsquery('simple', $2))`,
+			"created_at DESC, id DESC",
+			[]driver.Value{`('clean') <1> ('code':*) | ('clean') <2> ('code':*)`},
+		},
+		{
+			"prefix assignment and sort keys",
+			`> x = "info:srw/cql-context-set/1/dc-v1.1" x.title = code sortBy dc.date/sort.descending title`,
+			`((lower(title) LIKE $2))`,
+			"year DESC, lower(title) ASC, id",
+			[]driver.Value{"%code%"},
+		},
+	}
+
+	for _, tt := range translations {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := cql.Parse(tt.query)
+			assert.NoError(t, err)
+			conditions := regexp.QuoteMeta("tenant_id = $1 AND " + tt.conditions)
+			n := len(tt.args) + 1
+
+			expectTenantTx(mock)
+			mock.ExpectQuery(`SELECT COUNT\(\*\) FROM books WHERE ` + conditions + `$`).
+				WithArgs(append([]driver.Value{testTenant.ID}, tt.args...)...).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(`SELECT .+ FROM books WHERE ` + conditions + regexp.QuoteMeta(fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", tt.order, n+1, n+2)) + `$`).
+				WithArgs(append(append([]driver.Value{testTenant.ID}, tt.args...), 10, 20)...).
+				WillReturnRows(sqlmock.NewRows(bookRowColumns))
+			mock.ExpectCommit()
+
+			page, err := repo.SearchCQL(tenantContext(), query, 20, 10)
+
+			assert.NoError(t, err)
+			assert.Equal(t, 0, page.Total)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	unsupported := []struct {
+		query   string
+		feature cql.Feature
+	}{
+		{`> x = "urn:other" x.title = a`, cql.FeatureContextSet},
+		{`bib.title = a`, cql.FeatureContextSet},
+		{`dc.rights = a`, cql.FeatureIndex},
+		{`dc.title < a`, cql.FeatureRelationForIndex},
+		{`dc.date any 2000`, cql.FeatureRelationForIndex},
+		{`dc.title fuzzy a`, cql.FeatureRelation},
+		{`dc.title =/stem a`, cql.FeatureRelationModifier},
+		{`dc.date =/respectCase 2000`, cql.FeatureRelationModifier},
+		{`dc.date = "the nineties"`, cql.FeatureTerm},
+		{`dc.identifier = "not an isbn"`, cql.FeatureTerm},
+		{`dc.title = ""`, cql.FeatureEmptyTerm},
+		{`dc.title = "a^b"`, cql.FeatureAnchoring},
+		{`a and/rel.algorithm=cql b`, cql.FeatureBooleanModifier},
+		{`dc.title = a prox dc.creator = b`, cql.FeatureProximity},
+		{`(a or b) prox c`, cql.FeatureProximity},
+		{`a prox/distance>2 b`, cql.FeatureProximityRelation},
+		{`a prox/distance=11 b`, cql.FeatureProximityDistance},
+		{`a prox/unit=sentence b`, cql.FeatureProximityUnit},
+		{`a sortby dc.description`, cql.FeatureSortIndex},
+		{`a sortby dc.title/sort.missingOmit`, cql.FeatureSortModifier},
+	}
+
+	for _, tt := range unsupported {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := cql.Parse(tt.query)
+			assert.NoError(t, err)
+
+			_, err = repo.SearchCQL(tenantContext(), query, 0, 10)
+
+			var unsupportedErr *cql.UnsupportedError
+			if assert.ErrorAs(t, err, &unsupportedErr) {
+				assert.Equal(t, tt.feature, unsupportedErr.Feature)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresBookRepository_Harvest(t *testing.T) {
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+
+	t.Run("merges books and tombstones past the cursor", func(t *testing.T) {
+		bookID, goneID, laterID := uuid.New(), uuid.New(), uuid.New()
+		from := testTime("2024-01-01T00:00:00Z")
+		before := testTime("2024-02-01T00:00:00Z")
+		after := &entities.HarvestCursor{Datestamp: testTime("2024-01-05T00:00:00Z"), ID: uuid.New()}
+		query := entities.HarvestQuery{From: from, Before: before, After: after, Limit: 2}
+
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT .+ FROM books WHERE tenant_id = \$1 AND updated_at >= \$2 AND updated_at < \$3 AND \(updated_at, id\) > \(\$4, \$5\) ORDER BY updated_at, id LIMIT 2`).
+			WithArgs(testTenant.ID, from, before, after.Datestamp, after.ID).
+			WillReturnRows(sqlmock.NewRows(bookRowColumns).
+				AddRow(bookID, testTenant.ID, "Clean Code", "Robert C. Martin", 2008, "", "", 0, nil, "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-06T00:00:00Z")).
+				AddRow(laterID, testTenant.ID, "Refactoring", "Martin Fowler", 1999, "", "", 0, nil, "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-09T00:00:00Z")))
+		mock.ExpectQuery(`SELECT book_id, deleted_at FROM book_tombstones WHERE tenant_id = \$1 AND deleted_at >= \$2 AND deleted_at < \$3 AND \(deleted_at, book_id\) > \(\$4, \$5\) ORDER BY deleted_at, book_id LIMIT 2`).
+			WithArgs(testTenant.ID, from, before, after.Datestamp, after.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"book_id", "deleted_at"}).AddRow(goneID, testTime("2024-01-07T00:00:00Z")))
+		mock.ExpectCommit()
+
+		records, err := repo.Harvest(tenantContext(), query)
+
+		assert.NoError(t, err)
+		if assert.Len(t, records, 2) {
+			assert.Equal(t, bookID, records[0].ID)
+			assert.Equal(t, "Clean Code", records[0].Book.Title)
+			assert.Equal(t, &entities.HarvestRecord{ID: goneID, Datestamp: testTime("2024-01-07T00:00:00Z"), Deleted: true}, records[1])
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("deleted record", func(t *testing.T) {
+		goneID := uuid.New()
+
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT .+ FROM books WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs(testTenant.ID, goneID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(`SELECT book_id, deleted_at FROM book_tombstones WHERE tenant_id = \$1 AND book_id = \$2`).
+			WithArgs(testTenant.ID, goneID).
+			WillReturnRows(sqlmock.NewRows([]string{"book_id", "deleted_at"}).AddRow(goneID, testTime("2024-01-07T00:00:00Z")))
+		mock.ExpectCommit()
+
+		record, err := repo.GetHarvestRecord(tenantContext(), goneID)
+
+		assert.NoError(t, err)
+		assert.True(t, record.Deleted)
+		assert.Nil(t, record.Book)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown record", func(t *testing.T) {
+		id := uuid.New()
+
+		expectTenantTx(mock)
+		mock.ExpectQuery(`SELECT .+ FROM books WHERE tenant_id = \$1 AND id = \$2`).
+			WithArgs(testTenant.ID, id).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(`SELECT book_id, deleted_at FROM book_tombstones`).
+			WithArgs(testTenant.ID, id).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := repo.GetHarvestRecord(tenantContext(), id)
+
+		assert.ErrorIs(t, err, entities.ErrBookNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresBookRepository_FindSimilar(t *testing.T) {
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+
+	book := &entities.Book{ID: uuid.New(), Title: "Clean code", Author: "Robert C. Martin", Year: 2008, ISBN: "9780132350884"}
+	similarID := uuid.New()
+	rows := sqlmock.NewRows(bookRowColumns).
+		AddRow(similarID, testTenant.ID, "Clean Code", "Robert Martin", 2008, "", "", 0, nil, "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-01T00:00:00Z"))
+
+	expectTenantTx(mock)
+	mock.ExpectQuery(`SELECT id, tenant_id, title, author, year, isbn, publisher, page_count, subjects, description, cover_url, cover_etag, created_at, updated_at FROM books\s+WHERE tenant_id = \$1 AND id <> \$2 AND \(\(isbn <> '' AND isbn = \$3\) OR lower\(title\) % lower\(\$4\)\)`).
+		WithArgs(testTenant.ID, book.ID, "9780132350884", "Clean code", 20).
+		WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	result, err := repo.FindSimilar(tenantContext(), book, 20)
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, similarID, result[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresBookRepository_FindSimilarPairs(t *testing.T) {
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+
+	firstID, secondID := uuid.New(), uuid.New()
+	rows := sqlmock.NewRows(pairColumns()).AddRow(
+		firstID, testTenant.ID, "Clean Code", "Robert Martin", 2008, "", "", 0, nil, "", "", "", testTime("2024-01-01T00:00:00Z"), testTime("2024-01-01T00:00:00Z"),
+		secondID, testTenant.ID, "Clean code", "Robert C. Martin", 2008, "", "", 0, nil, "", "", "", testTime("2024-01-02T00:00:00Z"), testTime("2024-01-02T00:00:00Z"),
+	)
+
+	expectTenantTx(mock)
+	mock.ExpectQuery(`FROM books a JOIN books b ON b.tenant_id = a.tenant_id`).
+		WithArgs(testTenant.ID, 100).
+		WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	pairs, err := repo.FindSimilarPairs(tenantContext(), 100)
+
+	assert.NoError(t, err)
+	assert.Len(t, pairs, 1)
+	assert.Equal(t, firstID, pairs[0][0].ID)
+	assert.Equal(t, "Robert C. Martin", pairs[0][1].Author)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresBookRepository_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	db, mock, repo := setupRepositoryTest()
+	defer db.Close()
+
+	bookID := uuid.New()
+	expectTenantTx(mock)
+	mock.ExpectExec(`DELETE FROM books WHERE tenant_id = \$1 AND id = \$2`).
+		WithArgs(testTenant.ID, bookID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.Delete(tenantContext(), bookID)
+
+	assert.NoError(t, err)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "DELETE books", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.query.text", "DELETE FROM books WHERE tenant_id = $1 AND id = $2"))
+}
@@ -3,10 +3,14 @@ package tests
 import (
 	"context"
 	"testing"
+	"time"
 
+	"byfood-library/internal/cql"
 	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
 	"byfood-library/internal/usecases"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
@@ -33,6 +37,30 @@ func (m *MockBookRepository) GetByID(ctx context.Context, id uuid.UUID) (*entiti
 	return args.Get(0).(*entities.Book), args.Error(1)
 }
 
+func (m *MockBookRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Book), args.Error(1)
+}
+
+func (m *MockBookRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.Book), args.Error(1)
+}
+
+func (m *MockBookRepository) GetByIDsForUpdate(ctx context.Context, ids []uuid.UUID) ([]*entities.Book, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.Book), args.Error(1)
+}
+
 func (m *MockBookRepository) GetAll(ctx context.Context) ([]*entities.Book, error) {
 	args := m.Called(ctx)
 	if args.Get(0) == nil {
@@ -49,15 +77,173 @@ func (m *MockBookRepository) Update(ctx context.Context, id uuid.UUID, book *ent
 	return args.Get(0).(*entities.Book), args.Error(1)
 }
 
+func (m *MockBookRepository) SetCover(ctx context.Context, id uuid.UUID, etag string) (*entities.Book, error) {
+	args := m.Called(ctx, id, etag)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Book), args.Error(1)
+}
+
 func (m *MockBookRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockBookRepository) Count(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBookRepository) FindSimilar(ctx context.Context, book *entities.Book, limit int) ([]*entities.Book, error) {
+	args := m.Called(ctx, book, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.Book), args.Error(1)
+}
+
+func (m *MockBookRepository) FindSimilarPairs(ctx context.Context, limit int) ([][2]*entities.Book, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([][2]*entities.Book), args.Error(1)
+}
+
+func (m *MockBookRepository) AddFile(ctx context.Context, file *entities.BookFile) (*entities.BookFile, error) {
+	args := m.Called(ctx, file)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookFile), args.Error(1)
+}
+
+func (m *MockBookRepository) ListFiles(ctx context.Context, bookID uuid.UUID) ([]*entities.BookFile, error) {
+	args := m.Called(ctx, bookID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.BookFile), args.Error(1)
+}
+
+func (m *MockBookRepository) Search(ctx context.Context, query entities.BookQuery) (*entities.BookPage, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookPage), args.Error(1)
+}
+
+func (m *MockBookRepository) SearchCQL(ctx context.Context, query *cql.Query, offset, limit int) (*entities.BookPage, error) {
+	args := m.Called(ctx, query, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookPage), args.Error(1)
+}
+
+func (m *MockBookRepository) FacetValues(ctx context.Context, facet entities.Facet, limit int) ([]entities.FacetValue, error) {
+	args := m.Called(ctx, facet, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.FacetValue), args.Error(1)
+}
+
+func (m *MockBookRepository) Harvest(ctx context.Context, query entities.HarvestQuery) ([]*entities.HarvestRecord, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.HarvestRecord), args.Error(1)
+}
+
+func (m *MockBookRepository) GetHarvestRecord(ctx context.Context, id uuid.UUID) (*entities.HarvestRecord, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.HarvestRecord), args.Error(1)
+}
+
+func (m *MockBookRepository) EarliestDatestamp(ctx context.Context) (time.Time, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockBookRepository) ListFilesOf(ctx context.Context, bookIDs []uuid.UUID) ([]*entities.BookFile, error) {
+	args := m.Called(ctx, bookIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.BookFile), args.Error(1)
+}
+
+func (m *MockBookRepository) GetFile(ctx context.Context, bookID, fileID uuid.UUID) (*entities.BookFile, error) {
+	args := m.Called(ctx, bookID, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookFile), args.Error(1)
+}
+
+func (m *MockBookRepository) FindFileByChecksum(ctx context.Context, checksum string) (*entities.BookFile, error) {
+	args := m.Called(ctx, checksum)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookFile), args.Error(1)
+}
+
+func (m *MockBookRepository) DeleteFile(ctx context.Context, bookID, fileID uuid.UUID) error {
+	args := m.Called(ctx, bookID, fileID)
+	return args.Error(0)
+}
+
+func (m *MockBookRepository) MoveFiles(ctx context.Context, from []uuid.UUID, to uuid.UUID) error {
+	args := m.Called(ctx, from, to)
+	return args.Error(0)
+}
+
+func (m *MockBookRepository) SetFileStorageKey(ctx context.Context, fileID uuid.UUID, key string) error {
+	args := m.Called(ctx, fileID, key)
+	return args.Error(0)
+}
+
+// recordingOutbox collects appended events; err makes Append fail
+type recordingOutbox struct {
+	events []*events.Event
+	err    error
+}
+
+func (o *recordingOutbox) Append(ctx context.Context, evts ...*events.Event) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.events = append(o.events, evts...)
+	return nil
+}
+
+func (o *recordingOutbox) Relay(ctx context.Context, limit int, deliver func(ctx context.Context, batch []*events.Event) []events.Outcome) (bool, error) {
+	return true, nil
+}
+
+func (o *recordingOutbox) DeletePublished(ctx context.Context, olderThan time.Duration) (int64, error) {
+	return 0, nil
+}
+
+// passthroughTransactor runs fn without a database transaction
+type passthroughTransactor struct{}
+
+func (passthroughTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 func setupBookUseCase() (*MockBookRepository, usecases.BookUseCase) {
 	mockRepo := new(MockBookRepository)
 	logger, _ := zap.NewDevelopment()
-	useCase := usecases.NewBookUseCase(mockRepo, logger)
+	useCase := usecases.NewBookUseCase(mockRepo, &recordingOutbox{}, passthroughTransactor{}, nil, logger)
 	return mockRepo, useCase
 }
 
@@ -102,7 +288,7 @@ func TestBookUseCase_CreateBook(t *testing.T) {
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
-		assert.Equal(t, entities.ErrInvalidTitle, err)
+		assert.ErrorIs(t, err, entities.ErrInvalidTitle)
 
 		// Repository should not be called for validation errors
 		mockRepo.AssertExpectations(t)
@@ -127,6 +313,33 @@ func TestBookUseCase_CreateBook(t *testing.T) {
 	})
 }
 
+func TestBookUseCase_GetBooksByIDs(t *testing.T) {
+	mockRepo, useCase := setupBookUseCase()
+
+	t.Run("loads the batch in one call", func(t *testing.T) {
+		ids := []uuid.UUID{uuid.New(), uuid.New()}
+		expectedBooks := []*entities.Book{{ID: ids[1], Title: "Book 2", Author: "Author 2", Year: 2021}}
+
+		mockRepo.On("GetByIDs", mock.Anything, ids).Return(expectedBooks, nil).Once()
+
+		result, err := useCase.GetBooksByIDs(context.Background(), ids)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expectedBooks, result)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("skips the repository for an empty batch", func(t *testing.T) {
+		mockRepo, useCase := setupBookUseCase()
+
+		result, err := useCase.GetBooksByIDs(context.Background(), nil)
+
+		assert.NoError(t, err)
+		assert.Nil(t, result)
+		mockRepo.AssertNotCalled(t, "GetByIDs", mock.Anything, mock.Anything)
+	})
+}
+
 func TestBookUseCase_GetAllBooks(t *testing.T) {
 	mockRepo, useCase := setupBookUseCase()
 
@@ -219,6 +432,7 @@ func TestBookUseCase_UpdateBook(t *testing.T) {
 			Year:   2022,
 		}
 
+		mockRepo.On("GetByIDForUpdate", mock.Anything, bookID).Return(&entities.Book{ID: bookID, Title: "Title", Author: "Author", Year: 2020}, nil).Once()
 		mockRepo.On("Update", mock.Anything, bookID, mock.AnythingOfType("*entities.Book")).Return(expectedBook, nil).Once()
 
 		result, err := useCase.UpdateBook(context.Background(), bookID, dto)
@@ -232,6 +446,87 @@ func TestBookUseCase_UpdateBook(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("ISBN left out keeps the stored one", func(t *testing.T) {
+		bookID := uuid.New()
+		stored := &entities.Book{ID: bookID, Title: "Clean Code", Author: "Robert Martin", Year: 2008, ISBN: "9780132350884"}
+		dto := &entities.UpdateBookDTO{Title: "Clean Code", Author: "Robert C. Martin", Year: 2008}
+
+		mockRepo.On("GetByIDForUpdate", mock.Anything, bookID).Return(stored, nil).Once()
+		mockRepo.On("Update", mock.Anything, bookID, mock.MatchedBy(func(book *entities.Book) bool {
+			return book.Author == "Robert C. Martin" && book.ISBN == "9780132350884"
+		})).Return(stored, nil).Once()
+
+		_, err := useCase.UpdateBook(context.Background(), bookID, dto)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("ISBN sent is normalized, and empty clears it", func(t *testing.T) {
+		for isbn, want := range map[string]string{"0-13-235088-2": "9780132350884", "": ""} {
+			bookID := uuid.New()
+			stored := &entities.Book{ID: bookID, Title: "Clean Code", Author: "Robert Martin", Year: 2008, ISBN: "9780134190440"}
+			isbn := isbn
+			dto := &entities.UpdateBookDTO{Title: "Clean Code", Author: "Robert Martin", Year: 2008, ISBN: &isbn}
+
+			mockRepo.On("GetByIDForUpdate", mock.Anything, bookID).Return(stored, nil).Once()
+			mockRepo.On("Update", mock.Anything, bookID, mock.MatchedBy(func(book *entities.Book) bool {
+				return book.ISBN == want
+			})).Return(stored, nil).Once()
+
+			_, err := useCase.UpdateBook(context.Background(), bookID, dto)
+
+			assert.NoError(t, err)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("metadata left out keeps the stored metadata", func(t *testing.T) {
+		bookID := uuid.New()
+		metadata := entities.BookMetadata{
+			Publisher:   "Prentice Hall",
+			PageCount:   464,
+			Subjects:    pq.StringArray{"Software"},
+			Description: "A handbook of agile software craftsmanship",
+			CoverURL:    "https://covers.example.com/clean-code.jpg",
+		}
+		stored := &entities.Book{ID: bookID, Title: "Clean Code", Author: "Robert Martin", Year: 2008, BookMetadata: metadata}
+		description := "  "
+		dto := &entities.UpdateBookDTO{
+			Title:  "Clean Code",
+			Author: "Robert C. Martin",
+			Year:   2008,
+			BookMetadataUpdate: entities.BookMetadataUpdate{
+				Description: &description,
+			},
+		}
+
+		want := metadata
+		want.Description = ""
+		mockRepo.On("GetByIDForUpdate", mock.Anything, bookID).Return(stored, nil).Once()
+		mockRepo.On("Update", mock.Anything, bookID, mock.MatchedBy(func(book *entities.Book) bool {
+			return assert.ObjectsAreEqual(want, book.BookMetadata)
+		})).Return(stored, nil).Once()
+
+		_, err := useCase.UpdateBook(context.Background(), bookID, dto)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("book not found", func(t *testing.T) {
+		bookID := uuid.New()
+		dto := &entities.UpdateBookDTO{Title: "Title", Author: "Author", Year: 2020}
+
+		mockRepo.On("GetByIDForUpdate", mock.Anything, bookID).Return(nil, entities.ErrBookNotFound).Once()
+
+		result, err := useCase.UpdateBook(context.Background(), bookID, dto)
+
+		assert.ErrorIs(t, err, entities.ErrBookNotFound)
+		assert.Nil(t, result)
+		mockRepo.AssertExpectations(t)
+	})
+
 	t.Run("validation error", func(t *testing.T) {
 		bookID := uuid.New()
 		dto := &entities.UpdateBookDTO{
@@ -244,7 +539,7 @@ func TestBookUseCase_UpdateBook(t *testing.T) {
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
-		assert.Equal(t, entities.ErrInvalidTitle, err)
+		assert.ErrorIs(t, err, entities.ErrInvalidTitle)
 
 		// Repository should not be called for validation errors
 		mockRepo.AssertExpectations(t)
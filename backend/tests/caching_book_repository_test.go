@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"byfood-library/internal/cache"
+	"byfood-library/internal/domain/entities"
+	domain_repositories "byfood-library/internal/domain/repositories"
+	"byfood-library/internal/repositories"
+	"byfood-library/internal/tenancy"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func setupCachingRepository(remote cache.Store) (*MockBookRepository, domain_repositories.BookRepository) {
+	mockRepo := new(MockBookRepository)
+	bookCache := cache.New(cache.NewLRU(100), cache.Options{Remote: remote})
+	repo := repositories.NewCachingBookRepository(mockRepo, bookCache, repositories.BookCacheConfig{TTL: time.Minute}, zap.NewNop())
+	return mockRepo, repo
+}
+
+func TestCachingBookRepository(t *testing.T) {
+	ctx := tenantContext()
+	bookID := uuid.New()
+	book := &entities.Book{
+		ID:        bookID,
+		TenantID:  testTenant.ID,
+		Title:     "The Go Programming Language",
+		Author:    "Alan Donovan",
+		Year:      2015,
+		CreatedAt: testTime("2024-01-01T00:00:00Z"),
+		UpdatedAt: testTime("2024-01-01T00:00:00Z"),
+	}
+
+	t.Run("GetByID is served from the cache after the first call", func(t *testing.T) {
+		mockRepo, repo := setupCachingRepository(nil)
+		mockRepo.On("GetByID", mock.Anything, bookID).Return(book, nil).Once()
+
+		for i := 0; i < 3; i++ {
+			got, err := repo.GetByID(ctx, bookID)
+			assert.NoError(t, err)
+			assert.Equal(t, book, got)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("cached books are not shared between tenants", func(t *testing.T) {
+		mockRepo, repo := setupCachingRepository(nil)
+		otherCtx := tenancy.WithTenant(context.Background(), &entities.Tenant{ID: uuid.New(), Slug: "other"})
+		mockRepo.On("GetAll", mock.Anything).Return([]*entities.Book{book}, nil).Once()
+		mockRepo.On("GetAll", mock.Anything).Return([]*entities.Book{}, nil).Once()
+
+		books, err := repo.GetAll(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, books, 1)
+
+		books, err = repo.GetAll(otherCtx)
+		assert.NoError(t, err)
+		assert.Empty(t, books)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("not found is passed through and not cached", func(t *testing.T) {
+		mockRepo, repo := setupCachingRepository(nil)
+		mockRepo.On("GetByID", mock.Anything, bookID).Return(nil, entities.ErrBookNotFound).Twice()
+
+		for i := 0; i < 2; i++ {
+			_, err := repo.GetByID(ctx, bookID)
+			assert.Equal(t, entities.ErrBookNotFound, err)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("writes invalidate the book and the list", func(t *testing.T) {
+		mockRepo, repo := setupCachingRepository(nil)
+		updated := *book
+		updated.Title = "Updated"
+		mockRepo.On("GetByID", mock.Anything, bookID).Return(book, nil).Once()
+		mockRepo.On("GetAll", mock.Anything).Return([]*entities.Book{book}, nil).Once()
+		mockRepo.On("Update", mock.Anything, bookID, mock.Anything).Return(&updated, nil).Once()
+		mockRepo.On("GetByID", mock.Anything, bookID).Return(&updated, nil).Once()
+		mockRepo.On("GetAll", mock.Anything).Return([]*entities.Book{&updated}, nil).Once()
+
+		repo.GetByID(ctx, bookID)
+		repo.GetAll(ctx)
+		_, err := repo.Update(ctx, bookID, &updated)
+		assert.NoError(t, err)
+
+		got, err := repo.GetByID(ctx, bookID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Updated", got.Title)
+		books, err := repo.GetAll(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "Updated", books[0].Title)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("a shared remote tier serves other replicas", func(t *testing.T) {
+		// An in-memory store stands in for Redis
+		remote := cache.NewLRU(100)
+		firstRepo, first := setupCachingRepository(remote)
+		secondRepo, second := setupCachingRepository(remote)
+		firstRepo.On("GetByID", mock.Anything, bookID).Return(book, nil).Once()
+
+		_, err := first.GetByID(ctx, bookID)
+		assert.NoError(t, err)
+		got, err := second.GetByID(ctx, bookID)
+		assert.NoError(t, err)
+		assert.Equal(t, book, got)
+		firstRepo.AssertExpectations(t)
+		secondRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"byfood-library/internal/delivery/http/handlers"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/storage"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// MockCoverUseCase for testing
+type MockCoverUseCase struct {
+	mock.Mock
+}
+
+func (m *MockCoverUseCase) UploadCover(ctx context.Context, id uuid.UUID, data []byte) (*entities.Book, error) {
+	args := m.Called(ctx, id, data)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Book), args.Error(1)
+}
+
+func (m *MockCoverUseCase) GetCover(ctx context.Context, id uuid.UUID, size string) (*usecases.Cover, error) {
+	args := m.Called(ctx, id, size)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*usecases.Cover), args.Error(1)
+}
+
+func (m *MockCoverUseCase) DeleteCover(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Book), args.Error(1)
+}
+
+func (m *MockCoverUseCase) HandleEvent(ctx context.Context, evt *events.Event) error {
+	return m.Called(ctx, evt).Error(0)
+}
+
+func setupCoverHandler() (*MockCoverUseCase, handlers.CoverHandlerInterface) {
+	mockUseCase := new(MockCoverUseCase)
+	return mockUseCase, handlers.NewCoverHandler(mockUseCase, 16, time.Hour, zap.NewNop())
+}
+
+func coverContext(method, id, target string, body io.Reader) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, body)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	return c, rec
+}
+
+func TestCoverHandler_UploadCover(t *testing.T) {
+	t.Run("returns the book with its new cover", func(t *testing.T) {
+		mockUseCase, handler := setupCoverHandler()
+		book := &entities.Book{ID: uuid.New(), Title: "Clean Code", CoverETag: "abc"}
+		mockUseCase.On("UploadCover", mock.Anything, book.ID, []byte("image")).Return(book, nil).Once()
+		c, rec := coverContext(http.MethodPut, book.ID.String(), "/books/"+book.ID.String()+"/cover", strings.NewReader("image"))
+
+		err := handler.UploadCover(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"cover_etag":"abc"`)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		mockUseCase, handler := setupCoverHandler()
+		id := uuid.New()
+		c, rec := coverContext(http.MethodPut, id.String(), "/books/"+id.String()+"/cover", strings.NewReader(strings.Repeat("x", 17)))
+
+		err := handler.UploadCover(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		mockUseCase.AssertNotCalled(t, "UploadCover", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("not an image", func(t *testing.T) {
+		mockUseCase, handler := setupCoverHandler()
+		id := uuid.New()
+		mockUseCase.On("UploadCover", mock.Anything, id, []byte("text")).Return(nil, entities.ErrInvalidCoverImage).Once()
+		c, rec := coverContext(http.MethodPut, id.String(), "/books/"+id.String()+"/cover", strings.NewReader("text"))
+
+		err := handler.UploadCover(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUseCase.AssertExpectations(t)
+	})
+}
+
+func TestCoverHandler_GetCover(t *testing.T) {
+	newCover := func() *usecases.Cover {
+		return &usecases.Cover{
+			Object: storage.Object{
+				Info: storage.Info{Size: 4, ContentType: "image/jpeg", ModTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+				Body: io.NopCloser(strings.NewReader("jpeg")),
+			},
+			ETag: "abc-small",
+		}
+	}
+
+	t.Run("streams the rendition with caching headers", func(t *testing.T) {
+		mockUseCase, handler := setupCoverHandler()
+		id := uuid.New()
+		mockUseCase.On("GetCover", mock.Anything, id, "small").Return(newCover(), nil).Once()
+		c, rec := coverContext(http.MethodGet, id.String(), "/books/"+id.String()+"/cover?size=small", nil)
+
+		err := handler.GetCover(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "jpeg", rec.Body.String())
+		assert.Equal(t, "image/jpeg", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, `"abc-small"`, rec.Header().Get("ETag"))
+		assert.Equal(t, "private, max-age=3600", rec.Header().Get("Cache-Control"))
+		assert.Equal(t, "Tue, 02 Jan 2024 03:04:05 GMT", rec.Header().Get("Last-Modified"))
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		mockUseCase, handler := setupCoverHandler()
+		id := uuid.New()
+		mockUseCase.On("GetCover", mock.Anything, id, "original").Return(newCover(), nil).Once()
+		c, rec := coverContext(http.MethodGet, id.String(), "/books/"+id.String()+"/cover", nil)
+		c.Request().Header.Set("If-None-Match", `"other", W/"abc-small"`)
+
+		err := handler.GetCover(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("no cover", func(t *testing.T) {
+		mockUseCase, handler := setupCoverHandler()
+		id := uuid.New()
+		mockUseCase.On("GetCover", mock.Anything, id, "original").Return(nil, entities.ErrCoverNotFound).Once()
+		c, rec := coverContext(http.MethodGet, id.String(), "/books/"+id.String()+"/cover", nil)
+
+		err := handler.GetCover(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockUseCase.AssertExpectations(t)
+	})
+}
+
+func TestCoverHandler_DeleteCover(t *testing.T) {
+	t.Run("returns the book without a cover", func(t *testing.T) {
+		mockUseCase, handler := setupCoverHandler()
+		book := &entities.Book{ID: uuid.New(), Title: "Clean Code"}
+		mockUseCase.On("DeleteCover", mock.Anything, book.ID).Return(book, nil).Once()
+		c, rec := coverContext(http.MethodDelete, book.ID.String(), "/books/"+book.ID.String()+"/cover", nil)
+
+		err := handler.DeleteCover(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "cover_etag")
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("invalid UUID", func(t *testing.T) {
+		mockUseCase, handler := setupCoverHandler()
+		c, rec := coverContext(http.MethodDelete, "nope", "/books/nope/cover", nil)
+
+		err := handler.DeleteCover(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUseCase.AssertNotCalled(t, "DeleteCover", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"byfood-library/internal/delivery/http/handlers"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/storage"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// MockEbookUseCase for testing
+type MockEbookUseCase struct {
+	mock.Mock
+}
+
+func (m *MockEbookUseCase) ImportFile(ctx context.Context, data []byte, filename string, bookID uuid.UUID, force bool) (*entities.BookFileImport, error) {
+	args := m.Called(ctx, data, filename, bookID, force)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookFileImport), args.Error(1)
+}
+
+func (m *MockEbookUseCase) ListFiles(ctx context.Context, bookID uuid.UUID) ([]*entities.BookFile, error) {
+	args := m.Called(ctx, bookID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.BookFile), args.Error(1)
+}
+
+func (m *MockEbookUseCase) OpenFile(ctx context.Context, bookID, fileID uuid.UUID) (*usecases.EbookFile, error) {
+	args := m.Called(ctx, bookID, fileID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*usecases.EbookFile), args.Error(1)
+}
+
+func (m *MockEbookUseCase) DeleteFile(ctx context.Context, bookID, fileID uuid.UUID) error {
+	return m.Called(ctx, bookID, fileID).Error(0)
+}
+
+func (m *MockEbookUseCase) HandleEvent(ctx context.Context, evt *events.Event) error {
+	return m.Called(ctx, evt).Error(0)
+}
+
+func setupEbookHandler() (*MockEbookUseCase, handlers.EbookHandlerInterface) {
+	mockUseCase := new(MockEbookUseCase)
+	return mockUseCase, handlers.NewEbookHandler(mockUseCase, 16, zap.NewNop())
+}
+
+func ebookContext(method, target string, body io.Reader, params ...string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, body)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames([]string{"id", "fileId"}[:len(params)]...)
+	c.SetParamValues(params...)
+	return c, rec
+}
+
+func TestEbookHandler_ImportFile(t *testing.T) {
+	t.Run("a new book is created", func(t *testing.T) {
+		mockUseCase, handler := setupEbookHandler()
+		result := &entities.BookFileImport{
+			Book:    &entities.Book{ID: uuid.New(), Title: "Clean Code"},
+			File:    &entities.BookFile{ID: uuid.New(), Filename: "clean-code.epub", Checksum: "abc"},
+			Created: true,
+		}
+		mockUseCase.On("ImportFile", mock.Anything, []byte("PK\x03\x04"), "clean-code.epub", uuid.Nil, true).Return(result, nil).Once()
+		c, rec := ebookContext(http.MethodPost, "/books/files?force=true", strings.NewReader("PK\x03\x04"))
+		c.Request().Header.Set("Content-Disposition", `attachment; filename="clean-code.epub"`)
+
+		err := handler.ImportFile(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"created":true`)
+		assert.Contains(t, rec.Body.String(), `"checksum":"abc"`)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("a file for an existing book", func(t *testing.T) {
+		mockUseCase, handler := setupEbookHandler()
+		bookID := uuid.New()
+		result := &entities.BookFileImport{Book: &entities.Book{ID: bookID}, File: &entities.BookFile{ID: uuid.New()}}
+		mockUseCase.On("ImportFile", mock.Anything, []byte("%PDF-1.7"), "", bookID, false).Return(result, nil).Once()
+		c, rec := ebookContext(http.MethodPost, "/books/files?book_id="+bookID.String(), strings.NewReader("%PDF-1.7"))
+
+		err := handler.ImportFile(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		mockUseCase, handler := setupEbookHandler()
+		c, rec := ebookContext(http.MethodPost, "/books/files", strings.NewReader(strings.Repeat("x", 17)))
+
+		err := handler.ImportFile(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		mockUseCase.AssertNotCalled(t, "ImportFile", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		mockUseCase, handler := setupEbookHandler()
+		mockUseCase.On("ImportFile", mock.Anything, []byte("PK\x03\x04"), "", uuid.Nil, false).Return(nil, entities.ErrMalformedBookFile).Once()
+		c, rec := ebookContext(http.MethodPost, "/books/files", strings.NewReader("PK\x03\x04"))
+
+		err := handler.ImportFile(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("invalid book_id", func(t *testing.T) {
+		_, handler := setupEbookHandler()
+		c, rec := ebookContext(http.MethodPost, "/books/files?book_id=nope", strings.NewReader("PK"))
+
+		err := handler.ImportFile(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestEbookHandler_ListFiles(t *testing.T) {
+	t.Run("lists the book's files", func(t *testing.T) {
+		mockUseCase, handler := setupEbookHandler()
+		bookID := uuid.New()
+		files := []*entities.BookFile{{ID: uuid.New(), BookID: bookID, Format: "epub"}}
+		mockUseCase.On("ListFiles", mock.Anything, bookID).Return(files, nil).Once()
+		c, rec := ebookContext(http.MethodGet, "/books/"+bookID.String()+"/files", nil, bookID.String())
+
+		err := handler.ListFiles(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"format":"epub"`)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("missing book", func(t *testing.T) {
+		mockUseCase, handler := setupEbookHandler()
+		bookID := uuid.New()
+		mockUseCase.On("ListFiles", mock.Anything, bookID).Return(nil, entities.ErrBookNotFound).Once()
+		c, rec := ebookContext(http.MethodGet, "/books/"+bookID.String()+"/files", nil, bookID.String())
+
+		err := handler.ListFiles(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestEbookHandler_DownloadFile(t *testing.T) {
+	bookID, fileID := uuid.New(), uuid.New()
+	newFile := func() *usecases.EbookFile {
+		return &usecases.EbookFile{
+			Object: storage.Object{Body: io.NopCloser(strings.NewReader("%PDF-"))},
+			File:   &entities.BookFile{ID: fileID, BookID: bookID, ContentType: "application/pdf", Filename: "Clean Code.pdf", Size: 5, Checksum: "abc"},
+		}
+	}
+
+	t.Run("streams the file as an attachment", func(t *testing.T) {
+		mockUseCase, handler := setupEbookHandler()
+		mockUseCase.On("OpenFile", mock.Anything, bookID, fileID).Return(newFile(), nil).Once()
+		c, rec := ebookContext(http.MethodGet, "/books/"+bookID.String()+"/files/"+fileID.String(), nil, bookID.String(), fileID.String())
+
+		err := handler.DownloadFile(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "%PDF-", rec.Body.String())
+		assert.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="Clean Code.pdf"`, rec.Header().Get("Content-Disposition"))
+		assert.Equal(t, `"abc"`, rec.Header().Get("ETag"))
+		assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		mockUseCase, handler := setupEbookHandler()
+		mockUseCase.On("OpenFile", mock.Anything, bookID, fileID).Return(newFile(), nil).Once()
+		c, rec := ebookContext(http.MethodGet, "/books/"+bookID.String()+"/files/"+fileID.String(), nil, bookID.String(), fileID.String())
+		c.Request().Header.Set("If-None-Match", `"abc"`)
+
+		err := handler.DownloadFile(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+	})
+
+	t.Run("invalid file UUID", func(t *testing.T) {
+		_, handler := setupEbookHandler()
+		c, rec := ebookContext(http.MethodGet, "/books/"+bookID.String()+"/files/nope", nil, bookID.String(), "nope")
+
+		err := handler.DownloadFile(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestEbookHandler_DeleteFile(t *testing.T) {
+	t.Run("deletes the file", func(t *testing.T) {
+		mockUseCase, handler := setupEbookHandler()
+		bookID, fileID := uuid.New(), uuid.New()
+		mockUseCase.On("DeleteFile", mock.Anything, bookID, fileID).Return(nil).Once()
+		c, rec := ebookContext(http.MethodDelete, "/books/"+bookID.String()+"/files/"+fileID.String(), nil, bookID.String(), fileID.String())
+
+		err := handler.DeleteFile(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		mockUseCase, handler := setupEbookHandler()
+		bookID, fileID := uuid.New(), uuid.New()
+		mockUseCase.On("DeleteFile", mock.Anything, bookID, fileID).Return(entities.ErrBookFileNotFound).Once()
+		c, rec := ebookContext(http.MethodDelete, "/books/"+bookID.String()+"/files/"+fileID.String(), nil, bookID.String(), fileID.String())
+
+		err := handler.DeleteFile(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
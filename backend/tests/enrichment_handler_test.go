@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"byfood-library/internal/delivery/http/handlers"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/problem"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// MockEnrichmentUseCase for testing
+type MockEnrichmentUseCase struct {
+	mock.Mock
+}
+
+func (m *MockEnrichmentUseCase) LookupBook(ctx context.Context, query entities.MetadataQuery) (*entities.BookLookup, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookLookup), args.Error(1)
+}
+
+func (m *MockEnrichmentUseCase) EnrichBook(ctx context.Context, id uuid.UUID) (*entities.Book, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Book), args.Error(1)
+}
+
+func setupEnrichmentHandler() (*MockEnrichmentUseCase, handlers.EnrichmentHandlerInterface) {
+	mockUseCase := new(MockEnrichmentUseCase)
+	return mockUseCase, handlers.NewEnrichmentHandler(mockUseCase, zap.NewNop())
+}
+
+func TestEnrichmentHandler_LookupBook(t *testing.T) {
+	mockUseCase, handler := setupEnrichmentHandler()
+
+	t.Run("pre-fills a create body", func(t *testing.T) {
+		query := entities.MetadataQuery{Title: "Clean Code", Author: "Robert Martin"}
+		lookup := &entities.BookLookup{
+			Book: entities.CreateBookDTO{
+				Title: "Clean Code", Author: "Robert C. Martin", Year: 2008, ISBN: "9780132350884",
+				BookMetadata: entities.BookMetadata{Publisher: "Prentice Hall", Subjects: []string{"Software"}},
+			},
+			Sources: []string{"openlibrary"},
+		}
+		mockUseCase.On("LookupBook", mock.Anything, query).Return(lookup, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/books/lookup?title=Clean+Code&author=Robert+Martin", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := handler.LookupBook(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"book": {"title": "Clean Code", "author": "Robert C. Martin", "year": 2008, "isbn": "9780132350884",
+				"publisher": "Prentice Hall", "subjects": ["Software"]},
+			"sources": ["openlibrary"]
+		}`, rec.Body.String())
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("sources unavailable", func(t *testing.T) {
+		query := entities.MetadataQuery{ISBN: "9780132350884"}
+		mockUseCase.On("LookupBook", mock.Anything, query).Return(nil, entities.ErrMetadataUnavailable).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/books/lookup?isbn=9780132350884", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := handler.LookupBook(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+		var errorResp problem.Problem
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResp))
+		assert.Equal(t, problem.TypeUnavailable, errorResp.Type)
+		mockUseCase.AssertExpectations(t)
+	})
+}
+
+func TestEnrichmentHandler_EnrichBook(t *testing.T) {
+	mockUseCase, handler := setupEnrichmentHandler()
+
+	t.Run("returns the enriched book", func(t *testing.T) {
+		book := &entities.Book{ID: uuid.New(), Title: "Clean Code", Author: "Robert Martin", Year: 2008,
+			BookMetadata: entities.BookMetadata{PageCount: 464}}
+		mockUseCase.On("EnrichBook", mock.Anything, book.ID).Return(book, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/books/"+book.ID.String()+"/enrich", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(book.ID.String())
+
+		err := handler.EnrichBook(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var result entities.Book
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.Equal(t, 464, result.PageCount)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("no record of the book", func(t *testing.T) {
+		id := uuid.New()
+		mockUseCase.On("EnrichBook", mock.Anything, id).Return(nil, entities.ErrMetadataNotFound).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/books/"+id.String()+"/enrich", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id.String())
+
+		err := handler.EnrichBook(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("invalid UUID", func(t *testing.T) {
+		mockUseCase, handler := setupEnrichmentHandler()
+		req := httptest.NewRequest(http.MethodPost, "/books/nope/enrich", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("nope")
+
+		err := handler.EnrichBook(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUseCase.AssertNotCalled(t, "EnrichBook", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"byfood-library/internal/delivery/http/handlers"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/gql"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type graphQLResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func setupGraphQLHandler(t *testing.T) (*MockBookUseCase, handlers.GraphQLHandlerInterface) {
+	mockUseCase := new(MockBookUseCase)
+	server, err := gql.NewServer(mockUseCase, gql.Config{PersistedQueries: gql.PersistedQueriesAuto}, zap.NewNop())
+	assert.NoError(t, err)
+	return mockUseCase, handlers.NewGraphQLHandler(server, zap.NewNop())
+}
+
+func serveGraphQL(t *testing.T, handler handlers.GraphQLHandlerInterface, req *http.Request) (int, graphQLResponse) {
+	rec := httptest.NewRecorder()
+	assert.NoError(t, handler.Query(echo.New().NewContext(req, rec)))
+
+	var response graphQLResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return rec.Code, response
+}
+
+func TestGraphQLHandler_Query(t *testing.T) {
+	mockUseCase, handler := setupGraphQLHandler(t)
+	first := &entities.Book{ID: uuid.New(), Title: "Book 1", Author: "Author 1", Year: 2020}
+	second := &entities.Book{ID: uuid.New(), Title: "Book 2", Author: "Author 2", Year: 2021}
+
+	t.Run("batches lookups in a POST", func(t *testing.T) {
+		// graphql-go resolves the fields in no fixed order, so the batch may
+		// list the IDs either way round
+		batch := mock.MatchedBy(func(ids []uuid.UUID) bool {
+			return len(ids) == 2 && ids[0] != ids[1] &&
+				(ids[0] == first.ID || ids[0] == second.ID) && (ids[1] == first.ID || ids[1] == second.ID)
+		})
+		mockUseCase.On("GetBooksByIDs", mock.Anything, batch).
+			Return([]*entities.Book{second, first}, nil).Once()
+
+		body, _ := json.Marshal(map[string]interface{}{
+			"query":     `query Pair($a: ID!, $b: ID!) { a: book(id: $a) { title } b: book(id: $b) { year } }`,
+			"variables": map[string]string{"a": first.ID.String(), "b": second.ID.String()},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		status, response := serveGraphQL(t, handler, req)
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.Empty(t, response.Errors)
+		assert.JSONEq(t, `{"title":"Book 1"}`, string(response.Data["a"]))
+		assert.JSONEq(t, `{"year":2021}`, string(response.Data["b"]))
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("serves persisted queries over GET", func(t *testing.T) {
+		query := `{ books { title } }`
+		extensions := `{"persistedQuery":{"version":1,"sha256Hash":"` + gql.HashQuery(query) + `"}}`
+		get := func(params url.Values) *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/graphql?"+params.Encode(), nil)
+		}
+
+		status, response := serveGraphQL(t, handler, get(url.Values{"extensions": {extensions}}))
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, gql.CodePersistedQueryNotFound, response.Errors[0].Extensions["code"])
+
+		mockUseCase.On("GetAllBooks", mock.Anything).Return([]*entities.Book{first}, nil).Twice()
+		status, _ = serveGraphQL(t, handler, get(url.Values{"query": {query}, "extensions": {extensions}}))
+		assert.Equal(t, http.StatusOK, status)
+
+		status, response = serveGraphQL(t, handler, get(url.Values{"extensions": {extensions}}))
+		assert.Equal(t, http.StatusOK, status)
+		assert.JSONEq(t, `[{"title":"Book 1"}]`, string(response.Data["books"]))
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("refuses mutations over GET", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/graphql?"+url.Values{
+			"query": {`mutation { deleteBook(id: "` + first.ID.String() + `") }`},
+		}.Encode(), nil)
+
+		status, response := serveGraphQL(t, handler, req)
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, gql.CodeBadRequest, response.Errors[0].Extensions["code"])
+		mockUseCase.AssertNotCalled(t, "DeleteBook", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports use case errors", func(t *testing.T) {
+		mockUseCase.On("DeleteBook", mock.Anything, first.ID).Return(entities.ErrBookNotFound).Once()
+		body := `{"query":"mutation { deleteBook(id: \"` + first.ID.String() + `\") }"}`
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+
+		status, response := serveGraphQL(t, handler, req)
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, gql.CodeNotFound, response.Errors[0].Extensions["code"])
+		mockUseCase.AssertExpectations(t)
+	})
+}
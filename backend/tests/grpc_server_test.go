@@ -0,0 +1,219 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"byfood-library/internal/config"
+	grpcservices "byfood-library/internal/delivery/grpc/services"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/routes"
+	"byfood-library/internal/stream"
+	"byfood-library/internal/usecases"
+	bookv1 "byfood-library/proto/book/v1"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// apiKeyResolver knows testTenant by a single API key
+type apiKeyResolver struct{}
+
+func (apiKeyResolver) Resolve(ctx context.Context, req *http.Request) (*entities.Tenant, error) {
+	if req.Header.Get("X-API-Key") != "secret" {
+		return nil, entities.ErrTenantNotFound
+	}
+	return testTenant, nil
+}
+
+func setupGRPCServer(t *testing.T, hub *stream.Hub) (*MockBookUseCase, *MockCatalogUseCase, *grpc.ClientConn) {
+	mockUseCase := new(MockBookUseCase)
+	mockCatalog := new(MockCatalogUseCase)
+	server, _ := routes.SetupGRPC(&config.Config{}, &routes.GRPCServices{
+		BookService: grpcservices.NewBookService(mockUseCase, mockCatalog, hub, zap.NewNop()),
+	}, apiKeyResolver{}, "X-API-Key", zap.NewNop())
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return mockUseCase, mockCatalog, conn
+}
+
+func authorized() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret")
+}
+
+func TestGRPCServer_BookService(t *testing.T) {
+	mockUseCase, mockCatalog, conn := setupGRPCServer(t, nil)
+	client := bookv1.NewBookServiceClient(conn)
+	now := time.Now()
+	books := []*entities.Book{
+		{ID: uuid.New(), Title: "Book 1", Author: "Author 1", Year: 2020, CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: uuid.New(), Title: "Book 2", Author: "Author 2", Year: 2021, CreatedAt: now},
+		{ID: uuid.New(), Title: "Book 3", Author: "Author 3", Year: 2022, CreatedAt: now.Add(-time.Hour)},
+	}
+
+	t.Run("rejects calls without a tenant", func(t *testing.T) {
+		_, err := client.GetBook(context.Background(), &bookv1.GetBookRequest{Id: books[0].ID.String()})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		mockUseCase.AssertNotCalled(t, "GetBookByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("lets health checks through without a tenant", func(t *testing.T) {
+		resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{
+			Service: bookv1.BookService_ServiceDesc.ServiceName,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	})
+
+	t.Run("maps domain errors to codes", func(t *testing.T) {
+		mockUseCase.On("GetBookByID", mock.Anything, books[0].ID).Return(nil, entities.ErrBookNotFound).Once()
+		mockUseCase.On("CreateBook", mock.Anything, &entities.CreateBookDTO{Title: "", Author: "A", Year: 2000}).
+			Return(nil, entities.ErrInvalidTitle).Once()
+
+		_, err := client.GetBook(authorized(), &bookv1.GetBookRequest{Id: books[0].ID.String()})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = client.GetBook(authorized(), &bookv1.GetBookRequest{Id: "not-a-uuid"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.CreateBook(authorized(), &bookv1.CreateBookRequest{Author: "A", Year: 2000})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("carries book metadata", func(t *testing.T) {
+		metadata := entities.BookMetadata{Publisher: "Addison-Wesley", PageCount: 380, Subjects: []string{"Go"}}
+		created := &entities.Book{ID: uuid.New(), Title: "Book", Author: "A", Year: 2015, BookMetadata: metadata}
+		mockUseCase.On("CreateBook", mock.Anything, &entities.CreateBookDTO{Title: "Book", Author: "A", Year: 2015, BookMetadata: metadata}).
+			Return(created, nil).Once()
+
+		book, err := client.CreateBook(authorized(), &bookv1.CreateBookRequest{
+			Title: "Book", Author: "A", Year: 2015,
+			Metadata: &bookv1.BookMetadata{Publisher: "Addison-Wesley", PageCount: 380, Subjects: []string{"Go"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Addison-Wesley", book.Metadata.Publisher)
+		assert.Equal(t, int32(380), book.Metadata.PageCount)
+		assert.Equal(t, []string{"Go"}, book.Metadata.Subjects)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("pages through books newest first", func(t *testing.T) {
+		newest, middle, oldest := books[1], books[2], books[0]
+		mockCatalog.On("Search", mock.Anything, entities.BookQuery{Limit: 3}).
+			Return(&usecases.CatalogPage{BookPage: entities.BookPage{Books: []*entities.Book{newest, middle, oldest}, Total: 3}}, nil).Once()
+		mockCatalog.On("Search", mock.Anything, mock.MatchedBy(func(query entities.BookQuery) bool {
+			return query.Limit == 3 && query.Offset == 0 && query.After != nil &&
+				query.After.ID == middle.ID && query.After.CreatedAt.Equal(middle.CreatedAt)
+		})).Return(&usecases.CatalogPage{BookPage: entities.BookPage{Books: []*entities.Book{oldest}, Total: 3}}, nil).Once()
+
+		first, err := client.ListBooks(authorized(), &bookv1.ListBooksRequest{PageSize: 2})
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), first.TotalSize)
+		require.Len(t, first.Books, 2)
+		assert.Equal(t, "Book 2", first.Books[0].Title)
+		assert.Equal(t, "Book 3", first.Books[1].Title)
+		assert.NotEmpty(t, first.NextPageToken)
+
+		second, err := client.ListBooks(authorized(), &bookv1.ListBooksRequest{PageSize: 2, PageToken: first.NextPageToken})
+		require.NoError(t, err)
+		require.Len(t, second.Books, 1)
+		assert.Equal(t, "Book 1", second.Books[0].Title)
+		assert.Empty(t, second.NextPageToken)
+
+		_, err = client.ListBooks(authorized(), &bookv1.ListBooksRequest{PageToken: "!"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		mockCatalog.AssertExpectations(t)
+	})
+
+	t.Run("reports a disabled book stream", func(t *testing.T) {
+		watch, err := client.WatchBooks(authorized(), &bookv1.WatchBooksRequest{})
+		require.NoError(t, err)
+
+		_, err = watch.Recv()
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+}
+
+func TestGRPCServer_WatchBooks(t *testing.T) {
+	hub := stream.NewHub(10, 10)
+	_, _, conn := setupGRPCServer(t, hub)
+	client := bookv1.NewBookServiceClient(conn)
+	hub.Publish(bookEvent(1, events.BookCreated, "Rob Pike"))
+	hub.Publish(bookEvent(2, events.BookUpdated, "Ken Thompson"))
+
+	ctx, cancel := context.WithTimeout(authorized(), 5*time.Second)
+	defer cancel()
+	watch, err := client.WatchBooks(ctx, &bookv1.WatchBooksRequest{LastEventId: "1", Authors: []string{"ken thompson"}})
+	require.NoError(t, err)
+
+	replayed, err := watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "2", replayed.Id)
+	assert.Equal(t, bookv1.BookEvent_TYPE_UPDATED, replayed.Type)
+	assert.Equal(t, "Ken Thompson", replayed.Book.Author)
+
+	require.Eventually(t, func() bool { return hub.Subscribers() == 1 }, time.Second, 5*time.Millisecond)
+	hub.Publish(bookEvent(3, events.BookCreated, "Rob Pike"))
+	deleted := bookEvent(4, events.BookDeleted, "")
+	hub.Publish(deleted)
+
+	live, err := watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, bookv1.BookEvent_TYPE_DELETED, live.Type)
+	assert.Equal(t, deleted.AggregateID.String(), live.BookId)
+	assert.Nil(t, live.Book)
+
+	hub.Close()
+	_, err = watch.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestGRPCServer_WatchBooksByGenre(t *testing.T) {
+	hub := stream.NewHub(10, 10)
+	_, _, conn := setupGRPCServer(t, hub)
+	client := bookv1.NewBookServiceClient(conn)
+	hub.Publish(bookEvent(1, events.BookCreated, "Rob Pike", "Programming"))
+	hub.Publish(bookEvent(2, events.BookCreated, "Rob Pike", "Poetry"))
+	hub.Publish(bookEvent(3, events.BookCreated, "Ken Thompson", "Poetry"))
+
+	ctx, cancel := context.WithTimeout(authorized(), 5*time.Second)
+	defer cancel()
+	watch, err := client.WatchBooks(ctx, &bookv1.WatchBooksRequest{LastEventId: "1", Authors: []string{"rob pike"}, Genres: []string{"poetry"}})
+	require.NoError(t, err)
+
+	replayed, err := watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "2", replayed.Id)
+	assert.Equal(t, []string{"Poetry"}, replayed.Book.Metadata.Subjects)
+
+	hub.Close()
+	_, err = watch.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/repositories"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPostgresIdempotencyRepository_ExpiredTakeover(t *testing.T) {
+	db, mock, _ := setupRepositoryTest()
+	defer db.Close()
+	repo := repositories.NewPostgresIdempotencyRepository(db, zap.NewNop())
+	columns := []string{"tenant_id", "idempotency_key", "method", "path", "request_hash", "lock_id", "status",
+		"response_status", "response_headers", "response_body", "created_at", "expires_at"}
+
+	tenantID := uuid.New()
+	stale, current := uuid.New(), uuid.New()
+	record := &entities.IdempotencyRecord{
+		TenantID: tenantID, Key: "key-1", Method: http.MethodPost, Path: "/api/v1/books",
+		RequestHash: "hash", LockID: current, ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	t.Run("acquire takes over an expired claim with its own lock id", func(t *testing.T) {
+		mock.ExpectQuery(`INSERT INTO idempotency_keys .* lock_id = EXCLUDED.lock_id.* WHERE idempotency_keys.expires_at < CURRENT_TIMESTAMP`).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(tenantID, "key-1", http.MethodPost, "/api/v1/books", "hash", current,
+				"in_flight", nil, nil, nil, time.Now(), record.ExpiresAt))
+
+		acquired, ok, err := repo.Acquire(context.Background(), record)
+
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, current, acquired.LockID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("the previous owner can no longer complete the key", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE idempotency_keys .* WHERE tenant_id = \$1 AND idempotency_key = \$2 AND status = 'in_flight' AND lock_id = \$3`).
+			WithArgs(tenantID, "key-1", stale, http.StatusCreated, sqlmock.AnyArg(), []byte(`{}`), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Complete(context.Background(), tenantID, "key-1", stale, http.StatusCreated, http.Header{}, []byte(`{}`), time.Now().Add(time.Hour))
+
+		assert.ErrorIs(t, err, entities.ErrIdempotencyKeyLost)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("the previous owner can no longer release the key", func(t *testing.T) {
+		mock.ExpectExec(`DELETE FROM idempotency_keys WHERE tenant_id = \$1 AND idempotency_key = \$2 AND status = 'in_flight' AND lock_id = \$3`).
+			WithArgs(tenantID, "key-1", stale).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Release(context.Background(), tenantID, "key-1", stale)
+
+		assert.ErrorIs(t, err, entities.ErrIdempotencyKeyLost)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("the new owner completes it", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE idempotency_keys`).
+			WithArgs(tenantID, "key-1", current, http.StatusCreated, sqlmock.AnyArg(), []byte(`{}`), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Complete(context.Background(), tenantID, "key-1", current, http.StatusCreated, http.Header{}, []byte(`{}`), time.Now().Add(time.Hour))
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
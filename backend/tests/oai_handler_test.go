@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"byfood-library/internal/delivery/http/handlers"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/oai"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// MockHarvestUseCase for testing
+type MockHarvestUseCase struct {
+	mock.Mock
+}
+
+func (m *MockHarvestUseCase) Harvest(ctx context.Context, query entities.HarvestQuery) ([]*entities.HarvestRecord, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.HarvestRecord), args.Error(1)
+}
+
+func (m *MockHarvestUseCase) GetRecord(ctx context.Context, id uuid.UUID) (*entities.HarvestRecord, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.HarvestRecord), args.Error(1)
+}
+
+func (m *MockHarvestUseCase) EarliestDatestamp(ctx context.Context) (time.Time, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func setupOAIHandler() (*MockHarvestUseCase, handlers.OAIHandlerInterface) {
+	mockUseCase := new(MockHarvestUseCase)
+	provider := oai.NewProvider(mockUseCase, oai.Config{RepositoryName: "Test Library", AdminEmails: []string{"admin@library.test"}})
+	return mockUseCase, handlers.NewOAIHandler(provider, zap.NewNop())
+}
+
+func TestOAIHandler_Handle(t *testing.T) {
+	t.Run("GET", func(t *testing.T) {
+		mockUseCase, handler := setupOAIHandler()
+		mockUseCase.On("EarliestDatestamp", mock.Anything).Return(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), nil).Once()
+		req := httptest.NewRequest(http.MethodGet, "/oai?verb=Identify", nil)
+		req.Host = "library.test"
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := handler.Handle(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, oai.ContentType, rec.Header().Get("Content-Type"))
+		body := rec.Body.String()
+		assert.Contains(t, body, `<?xml version="1.0" encoding="UTF-8"?>`)
+		assert.Contains(t, body, `<request verb="Identify">http://library.test/oai</request>`)
+		assert.Contains(t, body, `<earliestDatestamp>2024-01-02T03:04:05Z</earliestDatestamp>`)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("POST", func(t *testing.T) {
+		id := uuid.New()
+		mockUseCase, handler := setupOAIHandler()
+		mockUseCase.On("GetRecord", mock.Anything, id).Return(nil, entities.ErrBookNotFound).Once()
+		form := url.Values{"verb": {"GetRecord"}, "metadataPrefix": {"oai_dc"}, "identifier": {"oai:library.test:" + id.String()}}
+		req := httptest.NewRequest(http.MethodPost, "/oai", strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		req.Host = "library.test"
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := handler.Handle(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `<error code="idDoesNotExist">`)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("catalogue failure", func(t *testing.T) {
+		mockUseCase, handler := setupOAIHandler()
+		mockUseCase.On("Harvest", mock.Anything, mock.Anything).Return(nil, entities.ErrDatabaseError).Once()
+		req := httptest.NewRequest(http.MethodGet, "/oai?verb=ListRecords&metadataPrefix=marcxml", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := handler.Handle(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
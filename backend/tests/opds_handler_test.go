@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"byfood-library/internal/cql"
+	"byfood-library/internal/delivery/http/handlers"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/opds"
+	"byfood-library/internal/usecases"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// MockCatalogUseCase for testing
+type MockCatalogUseCase struct {
+	mock.Mock
+}
+
+func (m *MockCatalogUseCase) Search(ctx context.Context, query entities.BookQuery) (*usecases.CatalogPage, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*usecases.CatalogPage), args.Error(1)
+}
+
+func (m *MockCatalogUseCase) SearchCQL(ctx context.Context, query *cql.Query, offset, limit int) (*entities.BookPage, error) {
+	args := m.Called(ctx, query, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.BookPage), args.Error(1)
+}
+
+func (m *MockCatalogUseCase) FacetValues(ctx context.Context, facet entities.Facet, limit int) ([]entities.FacetValue, error) {
+	args := m.Called(ctx, facet, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entities.FacetValue), args.Error(1)
+}
+
+func setupOPDSHandler() (*MockCatalogUseCase, handlers.OPDSHandlerInterface) {
+	mockUseCase := new(MockCatalogUseCase)
+	return mockUseCase, handlers.NewOPDSHandler(mockUseCase, opds.Config{Title: "Test Library", PageSize: 10, FacetLimit: 5}, zap.NewNop())
+}
+
+// opdsContext routes target as if it matched the route path
+func opdsContext(path, target string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Host = "library.test"
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetPath(path)
+	return c, rec
+}
+
+func TestOPDSHandler_Navigation(t *testing.T) {
+	t.Run("OPDS 1.2", func(t *testing.T) {
+		_, handler := setupOPDSHandler()
+		c, rec := opdsContext("/opds", "/opds")
+
+		err := handler.Navigation(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, opds.AtomNavigation, rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), `<?xml version="1.0" encoding="UTF-8"?>`)
+		assert.Contains(t, rec.Body.String(), `<link rel="search" href="http://library.test/opds/opensearch.xml"`)
+	})
+
+	t.Run("OPDS 2.0", func(t *testing.T) {
+		_, handler := setupOPDSHandler()
+		c, rec := opdsContext("/opds/v2", "/opds/v2")
+
+		err := handler.Navigation(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, opds.JSONFeed, rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), `"href":"http://library.test/opds/v2/authors"`)
+	})
+}
+
+func TestOPDSHandler_Books(t *testing.T) {
+	book := &entities.Book{ID: uuid.New(), Title: "Clean Code", Author: "Robert C. Martin"}
+	file := &entities.BookFile{ID: uuid.New(), BookID: book.ID, ContentType: "application/pdf", Filename: "clean-code.pdf", Size: 5}
+	page := &usecases.CatalogPage{
+		BookPage: entities.BookPage{Books: []*entities.Book{book}, Total: 21},
+		Files:    map[uuid.UUID][]*entities.BookFile{book.ID: {file}},
+	}
+	expectFacets := func(mockUseCase *MockCatalogUseCase) {
+		mockUseCase.On("FacetValues", mock.Anything, entities.FacetAuthor, 5).Return([]entities.FacetValue{{Value: "Robert C. Martin", Count: 3}}, nil).Once()
+		mockUseCase.On("FacetValues", mock.Anything, entities.FacetSubject, 5).Return([]entities.FacetValue(nil), nil).Once()
+	}
+
+	t.Run("a page of search results", func(t *testing.T) {
+		mockUseCase, handler := setupOPDSHandler()
+		mockUseCase.On("Search", mock.Anything, entities.BookQuery{Search: "clean", Subject: "Software", Offset: 10, Limit: 10}).Return(page, nil).Once()
+		expectFacets(mockUseCase)
+		c, rec := opdsContext("/opds/books", "/opds/books?q=clean&subject=Software&page=2")
+
+		err := handler.Books(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, opds.AtomAcquisition, rec.Header().Get("Content-Type"))
+		body := rec.Body.String()
+		assert.Contains(t, body, `<opensearch:totalResults>21</opensearch:totalResults>`)
+		assert.Contains(t, body, `<link rel="next" href="http://library.test/opds/books?page=3&amp;q=clean&amp;subject=Software"`)
+		assert.Contains(t, body, `<link rel="http://opds-spec.org/acquisition" href="http://library.test/api/v1/books/`+book.ID.String()+`/files/`+file.ID.String()+`" type="application/pdf" title="clean-code.pdf" length="5">`)
+		assert.Contains(t, body, `opds:facetGroup="Author" thr:count="3"`)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("OPDS 2.0", func(t *testing.T) {
+		mockUseCase, handler := setupOPDSHandler()
+		mockUseCase.On("Search", mock.Anything, entities.BookQuery{Limit: 10}).Return(page, nil).Once()
+		expectFacets(mockUseCase)
+		c, rec := opdsContext("/opds/v2/books", "/opds/v2/books")
+
+		err := handler.Books(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, opds.JSONFeed, rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), `"numberOfItems":21`)
+		assert.Contains(t, rec.Body.String(), `"rel":"http://opds-spec.org/acquisition"`)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("invalid page", func(t *testing.T) {
+		mockUseCase, handler := setupOPDSHandler()
+		c, rec := opdsContext("/opds/books", "/opds/books?page=0")
+
+		err := handler.Books(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUseCase.AssertNotCalled(t, "Search", mock.Anything, mock.Anything)
+	})
+
+	t.Run("search failure", func(t *testing.T) {
+		mockUseCase, handler := setupOPDSHandler()
+		mockUseCase.On("Search", mock.Anything, mock.Anything).Return(nil, entities.ErrDatabaseError).Once()
+		c, rec := opdsContext("/opds/books", "/opds/books")
+
+		err := handler.Books(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestOPDSHandler_Browse(t *testing.T) {
+	mockUseCase, handler := setupOPDSHandler()
+	mockUseCase.On("FacetValues", mock.Anything, entities.FacetSubject, opds.BrowseLimit).Return([]entities.FacetValue{{Value: "Science Fiction", Count: 2}}, nil).Once()
+	c, rec := opdsContext("/opds/subjects", "/opds/subjects")
+
+	err := handler.Subjects(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<link rel="subsection" href="http://library.test/opds/books?subject=Science+Fiction" type="`+opds.AtomAcquisition+`" thr:count="2">`)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestOPDSHandler_OpenSearch(t *testing.T) {
+	_, handler := setupOPDSHandler()
+	c, rec := opdsContext("/opds/opensearch.xml", "/opds/opensearch.xml")
+
+	err := handler.OpenSearch(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, opds.OpenSearchType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `<ShortName>Test Library</ShortName>`)
+	assert.Contains(t, rec.Body.String(), `template="http://library.test/opds/books?q={searchTerms}&amp;page={startPage?}"`)
+}
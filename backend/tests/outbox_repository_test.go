@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/repositories"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestPostgresTransactor_BookAndEventShareTransaction(t *testing.T) {
+	db, mock, bookRepo := setupRepositoryTest()
+	defer db.Close()
+	outbox := repositories.NewPostgresOutboxRepository(db, zap.NewNop())
+	transactor := repositories.NewPostgresTransactor(db, zap.NewNop())
+
+	bookID := uuid.New()
+	t.Run("commits both", func(t *testing.T) {
+		expectTenantTx(mock)
+		mock.ExpectQuery(`INSERT INTO books`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "title", "author", "year", "created_at", "updated_at"}).
+				AddRow(bookID, testTenant.ID, "Title", "Author", 2020, time.Now(), time.Now()))
+		mock.ExpectExec(`INSERT INTO outbox`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := transactor.WithinTx(tenantContext(), func(ctx context.Context) error {
+			book, err := bookRepo.Create(ctx, &entities.Book{Title: "Title", Author: "Author", Year: 2020})
+			if err != nil {
+				return err
+			}
+			return outbox.Append(ctx, events.NewBookCreated(book))
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back the book when the event cannot be stored", func(t *testing.T) {
+		expectTenantTx(mock)
+		mock.ExpectExec(`DELETE FROM books`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO outbox`).WillReturnError(errors.New("disk full"))
+		mock.ExpectRollback()
+
+		err := transactor.WithinTx(tenantContext(), func(ctx context.Context) error {
+			if err := bookRepo.Delete(ctx, bookID); err != nil {
+				return err
+			}
+			return outbox.Append(ctx, events.NewBookDeleted(testTenant.ID, bookID))
+		})
+
+		assert.Equal(t, entities.ErrDatabaseError, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresOutboxRepository_Relay(t *testing.T) {
+	db, mock, _ := setupRepositoryTest()
+	defer db.Close()
+	outbox := repositories.NewPostgresOutboxRepository(db, zap.NewNop())
+	columns := []string{"id", "sequence", "tenant_id", "aggregate_type", "aggregate_id", "event_type", "payload", "occurred_at", "attempts"}
+
+	t.Run("skips the batch when another relay holds the lock", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+		mock.ExpectRollback()
+
+		ok, err := outbox.Relay(context.Background(), 10, func(ctx context.Context, batch []*events.Event) []events.Outcome {
+			t.Fatal("deliver should not be called")
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("records delivery outcomes", func(t *testing.T) {
+		published, failed := uuid.New(), uuid.New()
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(`SELECT id, sequence, .* FROM outbox o\s+WHERE o.published_at IS NULL`).
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(published, 1, testTenant.ID, "book", uuid.New(), events.BookCreated, []byte(`{}`), time.Now(), 0).
+				AddRow(failed, 2, testTenant.ID, "book", uuid.New(), events.BookUpdated, []byte(`{}`), time.Now(), 3))
+		mock.ExpectExec(`UPDATE outbox SET published_at = CURRENT_TIMESTAMP`).
+			WithArgs(published).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE outbox SET attempts = attempts \+ 1, last_error = \$2`).
+			WithArgs(failed, "timeout", int64(8000)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		ok, err := outbox.Relay(context.Background(), 10, func(ctx context.Context, batch []*events.Event) []events.Outcome {
+			assert.Len(t, batch, 2)
+			assert.Equal(t, 3, batch[1].Attempts)
+			return []events.Outcome{
+				{EventID: batch[0].ID, Published: true},
+				{EventID: batch[1].ID, Error: "timeout", RetryAfter: 8 * time.Second},
+			}
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
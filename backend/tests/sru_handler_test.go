@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"byfood-library/internal/delivery/http/handlers"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/sru"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func setupSRUHandler() (*MockCatalogUseCase, handlers.SRUHandlerInterface) {
+	mockUseCase := new(MockCatalogUseCase)
+	provider := sru.NewProvider(mockUseCase, sru.Config{Title: "Test Library"})
+	return mockUseCase, handlers.NewSRUHandler(provider, zap.NewNop())
+}
+
+func TestSRUHandler_Handle(t *testing.T) {
+	t.Run("GET", func(t *testing.T) {
+		mockUseCase, handler := setupSRUHandler()
+		book := &entities.Book{ID: uuid.New(), Title: "Clean Code", Author: "Robert C. Martin"}
+		mockUseCase.On("SearchCQL", mock.Anything, mock.Anything, 0, 10).
+			Return(&entities.BookPage{Books: []*entities.Book{book}, Total: 1}, nil).Once()
+		req := httptest.NewRequest(http.MethodGet, "/sru?query="+url.QueryEscape(`dc.creator = martin`), nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := handler.Handle(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, sru.ContentType, rec.Header().Get("Content-Type"))
+		body := rec.Body.String()
+		assert.Contains(t, body, `<?xml version="1.0" encoding="UTF-8"?>`)
+		assert.Contains(t, body, `<sru:numberOfRecords>1</sru:numberOfRecords>`)
+		assert.Contains(t, body, `<dc:title>Clean Code</dc:title>`)
+		mockUseCase.AssertExpectations(t)
+	})
+
+	t.Run("POST explain", func(t *testing.T) {
+		_, handler := setupSRUHandler()
+		form := url.Values{"operation": {"explain"}, "version": {"2.0"}}
+		req := httptest.NewRequest(http.MethodPost, "/sru", strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		req.Host = "library.test"
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := handler.Handle(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `<zr:host>library.test</zr:host>`)
+	})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		mockUseCase, handler := setupSRUHandler()
+		req := httptest.NewRequest(http.MethodGet, "/sru?query="+url.QueryEscape(`dc.title = (`), nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := handler.Handle(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `<diag:uri>info:srw/diagnostic/1/10</diag:uri>`)
+		mockUseCase.AssertNotCalled(t, "SearchCQL", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("catalogue failure", func(t *testing.T) {
+		mockUseCase, handler := setupSRUHandler()
+		mockUseCase.On("SearchCQL", mock.Anything, mock.Anything, 0, 10).Return(nil, entities.ErrDatabaseError).Once()
+		req := httptest.NewRequest(http.MethodGet, "/sru?query=clean", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+
+		err := handler.Handle(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"byfood-library/internal/delivery/http/handlers"
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/domain/events"
+	"byfood-library/internal/stream"
+	"byfood-library/internal/tenancy"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func setupStreamServer(t *testing.T, hub *stream.Hub) *httptest.Server {
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.SetRequest(c.Request().WithContext(tenancy.WithTenant(c.Request().Context(), testTenant)))
+			return next(c)
+		}
+	})
+	e.GET("/api/v1/books/stream", handlers.NewStreamHandler(hub, 20*time.Millisecond, zap.NewNop()).StreamBooks)
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// readFrames parses the next n SSE frames; comments are reported under
+// "comment"
+func readFrames(scanner *bufio.Scanner, n int) []map[string]string {
+	var out []map[string]string
+	current := map[string]string{}
+	for len(out) < n && scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(current) > 0 {
+				out = append(out, current)
+			}
+			current = map[string]string{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			current["comment"] = strings.TrimSpace(line[1:])
+			continue
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		current[field] = value
+	}
+	return out
+}
+
+// nextFrame skips frames until one has field
+func nextFrame(scanner *bufio.Scanner, field string) map[string]string {
+	for {
+		frames := readFrames(scanner, 1)
+		if len(frames) == 0 {
+			return nil
+		}
+		if _, ok := frames[0][field]; ok {
+			return frames[0]
+		}
+	}
+}
+
+func bookEvent(sequence int64, eventType, author string, subjects ...string) *events.Event {
+	book := &entities.Book{ID: uuid.New(), TenantID: testTenant.ID, Title: "Title", Author: author, Year: 2020}
+	book.Subjects = subjects
+	evt := events.NewBookCreated(book)
+	evt.Type = eventType
+	evt.Sequence = sequence
+	return evt
+}
+
+func TestStreamHandler_StreamBooks(t *testing.T) {
+	hub := stream.NewHub(100, 10)
+	server := setupStreamServer(t, hub)
+	hub.Publish(bookEvent(1, events.BookCreated, "Rob Pike"))
+	hub.Publish(bookEvent(2, events.BookUpdated, "Ken Thompson"))
+
+	t.Run("resumes after Last-Event-ID and streams live events", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/v1/books/stream?author=rob+pike", nil)
+		req.Header.Set("Last-Event-ID", "1")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		scanner := bufio.NewScanner(resp.Body)
+
+		assert.Equal(t, "3000", readFrames(scanner, 1)[0]["retry"])
+
+		other := bookEvent(3, events.BookCreated, "Someone Else")
+		other.TenantID = uuid.New()
+		hub.Publish(other)
+		hub.Publish(bookEvent(4, events.BookCreated, "Ken Thompson"))
+		live := bookEvent(5, events.BookUpdated, "Rob Pike")
+		hub.Publish(live)
+
+		received := nextFrame(scanner, "event")
+		assert.Equal(t, "5", received["id"])
+		assert.Equal(t, events.BookUpdated, received["event"])
+		var decoded events.Event
+		assert.NoError(t, json.Unmarshal([]byte(received["data"]), &decoded))
+		assert.Equal(t, live.ID, decoded.ID)
+
+		assert.Equal(t, "heartbeat", nextFrame(scanner, "comment")["comment"])
+	})
+
+	t.Run("asks the client to reload when it cannot resume", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/v1/books/stream?last_event_id=999")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		received := readFrames(bufio.NewScanner(resp.Body), 2)
+		assert.Equal(t, "reset", received[1]["event"])
+	})
+
+	t.Run("filters by genre against the subjects", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/v1/books/stream?genre=Programming&genre=poetry")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		scanner := bufio.NewScanner(resp.Body)
+		readFrames(scanner, 1)
+
+		hub.Publish(bookEvent(6, events.BookCreated, "Rob Pike", "Fiction"))
+		hub.Publish(bookEvent(7, events.BookCreated, "Rob Pike", "Software", "programming"))
+
+		received := nextFrame(scanner, "event")
+		assert.Equal(t, "7", received["id"])
+	})
+
+	t.Run("ends open streams when the hub closes", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/api/v1/books/stream")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		readFrames(scanner, 1)
+
+		hub.Close()
+
+		for scanner.Scan() {
+		}
+		assert.NoError(t, scanner.Err())
+	})
+}
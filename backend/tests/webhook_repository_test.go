@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"byfood-library/internal/domain/entities"
+	"byfood-library/internal/repositories"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestPostgresWebhookRepository(t *testing.T) {
+	db, mock, _ := setupRepositoryTest()
+	defer db.Close()
+	repo := repositories.NewPostgresWebhookRepository(db, zap.NewNop())
+	webhookID := uuid.New()
+
+	t.Run("webhooks are scoped to the tenant", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM webhooks WHERE id = \$1 AND tenant_id = \$2`).
+			WithArgs(webhookID, testTenant.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := repo.GetByID(tenantContext(), webhookID)
+		assert.Equal(t, entities.ErrWebhookNotFound, err)
+
+		_, err = repo.GetByID(context.Background(), webhookID)
+		assert.Equal(t, entities.ErrTenantRequired, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("an event is queued once per webhook", func(t *testing.T) {
+		mock.ExpectExec(`INSERT INTO webhook_deliveries .* ON CONFLICT \(webhook_id, event_id\) WHERE redelivery_of IS NULL DO NOTHING`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		delivery := &entities.WebhookDelivery{WebhookID: webhookID, EventID: uuid.New(), EventType: "book.created", Payload: []byte(`{}`)}
+		err := repo.Enqueue(tenantContext(), delivery)
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, uuid.Nil, delivery.ID)
+		assert.Equal(t, testTenant.ID, delivery.TenantID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("claims due deliveries of active webhooks", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE webhook_deliveries\s+SET next_attempt_at = CURRENT_TIMESTAMP \+ \$2 .*w.active\s+ORDER BY d.next_attempt_at\s+LIMIT \$1\s+FOR UPDATE OF d SKIP LOCKED\)`).
+			WithArgs(20, int64(80000)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "tenant_id", "event_id", "event_type", "payload", "status", "attempts"}).
+				AddRow(uuid.New(), webhookID, testTenant.ID, uuid.New(), "book.created", []byte(`{}`), "pending", 2))
+
+		deliveries, err := repo.ClaimDue(context.Background(), 20, 80*time.Second)
+
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+		assert.Equal(t, 2, deliveries[0].Attempts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("records a final attempt", func(t *testing.T) {
+		deliveryID := uuid.New()
+		mock.ExpectExec(`UPDATE webhook_deliveries\s+SET attempts = attempts \+ 1, status = \$2`).
+			WithArgs(deliveryID, entities.WebhookDeliverySucceeded, nil, sqlmock.AnyArg(), 204, "", nil, int64(12), true).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.RecordAttempt(context.Background(), deliveryID, &entities.WebhookAttempt{
+			RequestHeaders: entities.WebhookHeaders{"X-Byfood-Event": "book.created"},
+			ResponseStatus: 204,
+			Duration:       12 * time.Millisecond,
+		}, nil)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reports when a failure disables the webhook", func(t *testing.T) {
+		mock.ExpectQuery(`UPDATE webhooks\s+SET consecutive_failures = consecutive_failures \+ 1`).
+			WithArgs(webhookID, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"disabled"}).AddRow(true))
+
+		disabled, err := repo.RecordResult(context.Background(), webhookID, false, 5)
+
+		assert.NoError(t, err)
+		assert.True(t, disabled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a success resets the failure count", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE webhooks SET consecutive_failures = 0 WHERE id = \$1`).
+			WithArgs(webhookID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		disabled, err := repo.RecordResult(context.Background(), webhookID, true, 5)
+
+		assert.NoError(t, err)
+		assert.False(t, disabled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}